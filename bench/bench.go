@@ -0,0 +1,359 @@
+package bench
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yomorun/yomo/connector"
+	"github.com/yomorun/yomo/core/rx"
+	"github.com/yomorun/yomo/internal/core"
+	"github.com/yomorun/yomo/logger"
+	"github.com/yomorun/yomo/source"
+	"github.com/yomorun/yomo/streamfunction"
+	"github.com/yomorun/yomo/zipper"
+)
+
+const (
+	// DefaultName is the default name of the meter stream function.
+	DefaultName = "yomo-bench"
+	// DefaultTag is the default data tag of the frames.
+	DefaultTag byte = 0x33
+	// DefaultSize is the default size in bytes of the frames.
+	DefaultSize = 1024
+	// DefaultDuration is the default duration of the benchmark.
+	DefaultDuration = 10 * time.Second
+
+	// probeInterval is the interval of the probes written until the meter observes one.
+	probeInterval = 100 * time.Millisecond
+	// probeTimeout is how long the probes are written before the pipeline is considered broken.
+	probeTimeout = 10 * time.Second
+	// drainTimeout is how long the frames in flight are awaited after the last one is written, the frames which
+	// aren't observed in it are lost.
+	drainTimeout = 3 * time.Second
+	// pollInterval is the interval of checking the pace of the sources and the frames observed.
+	pollInterval = time.Millisecond
+)
+
+// Config represents the config of the benchmark.
+type Config struct {
+	// Zipper is the address of the remote YoMo-Zipper whose workflow is benchmarked, the meter must be the last
+	// stream function of the workflow. An in-process YoMo-Zipper is run if it's empty.
+	Zipper string `yaml:"zipper,omitempty"`
+	// Port is the port of the in-process YoMo-Zipper, a free port is chosen if it's 0.
+	Port int `yaml:"port,omitempty"`
+	// Token is the token of YoMo-Zipper.
+	Token string `yaml:"token,omitempty"`
+	// Name is the name of the meter stream function, the default is DefaultName.
+	Name string `yaml:"name,omitempty"`
+	// Tag is the data tag of the frames, the default is DefaultTag.
+	Tag byte `yaml:"tag,omitempty"`
+	// Size is the size in bytes of the carriage of frames, the default is DefaultSize.
+	Size int `yaml:"size,omitempty"`
+	// Rate is the frames per second written by all the sources, the frames are written as fast as possible if
+	// it's 0.
+	Rate int `yaml:"rate,omitempty"`
+	// Duration is how long the frames are written, the default is DefaultDuration.
+	Duration time.Duration `yaml:"duration,omitempty"`
+	// Sources is the count of sources which share the rate, the default is 1.
+	Sources int `yaml:"sources,omitempty"`
+	// Stages is the count of pass-through stream functions before the meter, they're run by the in-process
+	// YoMo-Zipper only.
+	Stages int `yaml:"stages,omitempty"`
+}
+
+// Result is the result of the benchmark, the latencies are in nanoseconds in JSON.
+type Result struct {
+	// Sent is the count of frames written by the sources.
+	Sent uint64 `json:"sent"`
+	// Failed is the count of frames which failed to be written.
+	Failed uint64 `json:"failed"`
+	// Received is the count of frames observed by the meter.
+	Received uint64 `json:"received"`
+	// Lost is the count of frames written but not observed by the meter in time.
+	Lost uint64 `json:"lost"`
+	// Elapsed is from the first frame written to the last frame observed.
+	Elapsed time.Duration `json:"elapsed"`
+	// Throughput is the frames per second observed by the meter.
+	Throughput float64 `json:"throughput"`
+	// Bandwidth is the bytes per second observed by the meter.
+	Bandwidth float64 `json:"bandwidth"`
+	// P50 is the median of the end-to-end latency.
+	P50 time.Duration `json:"p50"`
+	// P95 is the 95th percentile of the end-to-end latency.
+	P95 time.Duration `json:"p95"`
+	// P99 is the 99th percentile of the end-to-end latency.
+	P99 time.Duration `json:"p99"`
+	// Max is the max end-to-end latency.
+	Max time.Duration `json:"max"`
+}
+
+// LossRate returns the ratio of the frames lost to the frames sent.
+func (r *Result) LossRate() float64 {
+	if r.Sent == 0 {
+		return 0
+	}
+	return float64(r.Lost) / float64(r.Sent)
+}
+
+// Bench runs the benchmark of the pipeline.
+type Bench struct {
+	conf    Config
+	closers []func() error // closers close the in-process YoMo-Zipper and its stages in reverse order.
+}
+
+// New creates the benchmark, the defaults are applied to the config.
+func New(conf Config) (*Bench, error) {
+	if conf.Size < 0 {
+		return nil, errors.New("bench: the size must not be negative")
+	}
+	if conf.Size > core.DefaultMaxFrameSize {
+		return nil, fmt.Errorf("bench: the size must not exceed %d bytes", core.DefaultMaxFrameSize)
+	}
+	if conf.Rate < 0 {
+		return nil, errors.New("bench: the rate must not be negative")
+	}
+	if conf.Duration < 0 {
+		return nil, errors.New("bench: the duration must not be negative")
+	}
+	if conf.Sources < 0 {
+		return nil, errors.New("bench: the count of sources must not be negative")
+	}
+	if conf.Stages < 0 {
+		return nil, errors.New("bench: the count of stages must not be negative")
+	}
+	if conf.Zipper != "" && conf.Stages > 0 {
+		return nil, errors.New("bench: the stages are run by the in-process YoMo-Zipper only")
+	}
+	if conf.Name == "" {
+		conf.Name = DefaultName
+	}
+	if conf.Tag == 0 {
+		conf.Tag = DefaultTag
+	}
+	if conf.Size == 0 {
+		conf.Size = DefaultSize
+	}
+	if conf.Duration == 0 {
+		conf.Duration = DefaultDuration
+	}
+	if conf.Sources == 0 {
+		conf.Sources = 1
+	}
+	return &Bench{conf: conf}, nil
+}
+
+// Config returns the config of the benchmark with the defaults.
+func (b *Bench) Config() Config {
+	return b.conf
+}
+
+// Run runs the benchmark until the duration elapses or ctx is done, the frames are written after the meter observes
+// a probe, so the time of connecting isn't measured.
+func (b *Bench) Run(ctx context.Context) (*Result, error) {
+	host, port, err := b.pipeline()
+	if err != nil {
+		return nil, err
+	}
+	defer b.close()
+
+	meter := NewMeter("bench-")
+	sfn, err := streamfunction.New(b.conf.Name, streamfunction.WithToken(b.conf.Token)).Connect(host, port)
+	if err != nil {
+		return nil, err
+	}
+	defer sfn.Close()
+	if err := sfn.Subscribe(b.conf.Tag); err != nil {
+		return nil, err
+	}
+	go sfn.Pipe(meter.Handler())
+
+	sources := make([]source.Client, b.conf.Sources)
+	for i := range sources {
+		src, err := source.New(fmt.Sprintf("%s-source-%d", b.conf.Name, i), source.WithToken(b.conf.Token)).Connect(host, port)
+		if err != nil {
+			return nil, err
+		}
+		defer src.Close()
+		sources[i] = src
+	}
+
+	if err := b.probe(ctx, sources[0], meter); err != nil {
+		return nil, err
+	}
+	logger.Printf("✅ Benchmarking the frames of %d bytes at %s for %v", b.conf.Size, b.rate(), b.conf.Duration)
+
+	payload := make([]byte, b.conf.Size)
+	rand.Read(payload)
+	var sent, failed uint64
+	start := time.Now()
+	genCtx, cancel := context.WithTimeout(ctx, b.conf.Duration)
+	defer cancel()
+	var wg sync.WaitGroup
+	for i, src := range sources {
+		wg.Add(1)
+		go func(i int, src source.Client) {
+			defer wg.Done()
+			b.generate(genCtx, i, src, payload, &sent, &failed)
+		}(i, src)
+	}
+	wg.Wait()
+	end := time.Now()
+
+	// the frames in flight are awaited until they're all observed or no more frame is observed in drainTimeout.
+	for meter.Count() < atomic.LoadUint64(&sent) && ctx.Err() == nil {
+		last := meter.Last()
+		if last.Before(end) {
+			last = end
+		}
+		if time.Since(last) > drainTimeout {
+			break
+		}
+		time.Sleep(pollInterval)
+	}
+
+	result := &Result{Sent: sent, Failed: failed, Received: meter.Count()}
+	if result.Received < result.Sent {
+		result.Lost = result.Sent - result.Received
+	}
+	if last := meter.Last(); !last.IsZero() {
+		result.Elapsed = last.Sub(start)
+		result.Throughput = float64(result.Received) / result.Elapsed.Seconds()
+		result.Bandwidth = float64(meter.Bytes()) / result.Elapsed.Seconds()
+	}
+	ps := meter.Percentiles(50, 95, 99, 100)
+	result.P50, result.P95, result.P99, result.Max = ps[0], ps[1], ps[2], ps[3]
+	return result, nil
+}
+
+// pipeline returns the address of YoMo-Zipper, the in-process YoMo-Zipper and its stages are run if the remote one
+// isn't set.
+func (b *Bench) pipeline() (string, int, error) {
+	if b.conf.Zipper != "" {
+		host, p, err := net.SplitHostPort(b.conf.Zipper)
+		if err != nil {
+			return "", 0, fmt.Errorf("bench: the address of YoMo-Zipper is invalid: %w", err)
+		}
+		port, err := strconv.Atoi(p)
+		if err != nil {
+			return "", 0, fmt.Errorf("bench: the port of YoMo-Zipper is invalid: %s", p)
+		}
+		return host, port, nil
+	}
+
+	port := b.conf.Port
+	if port == 0 {
+		var err error
+		if port, err = freePort(); err != nil {
+			return "", 0, err
+		}
+	}
+	conf := &zipper.WorkflowConfig{Name: b.conf.Name, Host: "localhost", Port: port}
+	for i := 0; i < b.conf.Stages; i++ {
+		conf.Functions = append(conf.Functions, zipper.App{Name: b.stageName(i), Tags: []byte{b.conf.Tag}})
+	}
+	conf.Functions = append(conf.Functions, zipper.App{Name: b.conf.Name, Tags: []byte{b.conf.Tag}})
+	z := zipper.New(conf)
+	go func() {
+		if err := z.Serve(fmt.Sprintf("%s:%d", conf.Host, conf.Port)); err != nil {
+			logger.Error("[bench] serve YoMo-Zipper failed.", "err", err)
+		}
+	}()
+	b.closers = append(b.closers, z.Close)
+
+	for i := 0; i < b.conf.Stages; i++ {
+		stage, err := streamfunction.New(b.stageName(i), streamfunction.WithToken(b.conf.Token)).Connect(conf.Host, conf.Port)
+		if err != nil {
+			b.close()
+			return "", 0, err
+		}
+		go stage.Pipe(passThrough(b.conf.Tag))
+		b.closers = append(b.closers, stage.Close)
+	}
+	return conf.Host, conf.Port, nil
+}
+
+// close closes the in-process YoMo-Zipper and its stages.
+func (b *Bench) close() {
+	for i := len(b.closers) - 1; i >= 0; i-- {
+		b.closers[i]()
+	}
+	b.closers = nil
+}
+
+// stageName returns the name of the i-th stage.
+func (b *Bench) stageName(i int) string {
+	return fmt.Sprintf("%s-stage-%d", b.conf.Name, i+1)
+}
+
+// rate returns the rate of the frames in words.
+func (b *Bench) rate() string {
+	if b.conf.Rate == 0 {
+		return "the max rate"
+	}
+	return fmt.Sprintf("%d frames/s", b.conf.Rate)
+}
+
+// probe writes the probes until the meter observes one, so the pipeline is ready before the frames are measured.
+func (b *Bench) probe(ctx context.Context, w connector.Writer, meter *Meter) error {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	for i := 0; meter.Ignored() == 0; i++ {
+		if _, err := connector.WriteWithTransaction(w, "probe-"+strconv.Itoa(i), []byte("probe"), b.conf.Tag); err != nil {
+			logger.Debug("[bench] write the probe failed.", "err", err)
+		}
+		select {
+		case <-time.After(probeInterval):
+		case <-ctx.Done():
+			return fmt.Errorf("bench: the meter %s doesn't observe the frames of tag 0x%02x, it must be the last stream function of the workflow", b.conf.Name, b.conf.Tag)
+		}
+	}
+	return nil
+}
+
+// generate writes the frames by the i-th source at its share of the rate until ctx is done, the frames which are
+// behind the pace are written at once.
+func (b *Bench) generate(ctx context.Context, i int, w connector.Writer, payload []byte, sent *uint64, failed *uint64) {
+	rate := float64(b.conf.Rate) / float64(b.conf.Sources)
+	start := time.Now()
+	for n := 0; ctx.Err() == nil; {
+		if rate > 0 && float64(n) >= time.Since(start).Seconds()*rate {
+			time.Sleep(pollInterval)
+			continue
+		}
+		// the transaction IDs are unique, so the frames aren't deduplicated by the stream functions.
+		tid := fmt.Sprintf("bench-%d-%d", i, n)
+		if _, err := connector.WriteWithTransaction(w, tid, payload, b.conf.Tag); err != nil {
+			atomic.AddUint64(failed, 1)
+			logger.Debug("[bench] write the frame failed.", "err", err)
+		} else {
+			atomic.AddUint64(sent, 1)
+		}
+		n++
+	}
+}
+
+// passThrough returns the handler of the stages which respond the raw carriages with the tag.
+func passThrough(tag byte) func(rxstream rx.Stream) rx.Stream {
+	return func(rxstream rx.Stream) rx.Stream {
+		return rxstream.RawBytes().Map(func(_ context.Context, i interface{}) (interface{}, error) {
+			return rx.TaggedData{Tag: tag, Data: i.([]byte)}, nil
+		})
+	}
+}
+
+// freePort returns a free UDP port of localhost.
+func freePort() (int, error) {
+	conn, err := net.ListenPacket("udp", "localhost:0")
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).Port, nil
+}
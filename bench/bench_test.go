@@ -0,0 +1,57 @@
+package bench
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBench(t *testing.T) {
+	b, err := New(Config{Name: "bench-test", Size: 256, Rate: 200, Duration: time.Second, Sources: 2, Stages: 2})
+	assert.NoError(t, err)
+	result, err := b.Run(context.Background())
+	assert.NoError(t, err)
+
+	// the frames of the rate are written in the duration and all of them pass through the stages.
+	assert.InDelta(t, 200, result.Sent, 20)
+	assert.Equal(t, uint64(0), result.Failed)
+	assert.Equal(t, result.Sent, result.Received)
+	assert.Equal(t, uint64(0), result.Lost)
+	assert.Zero(t, result.LossRate())
+	assert.InDelta(t, 200, result.Throughput, 40)
+	assert.InDelta(t, 200*256, result.Bandwidth, 40*256)
+	assert.True(t, result.P50 > 0)
+	assert.True(t, result.P50 <= result.P95 && result.P95 <= result.P99 && result.P99 <= result.Max)
+}
+
+func TestNew(t *testing.T) {
+	b, err := New(Config{})
+	assert.NoError(t, err)
+	assert.Equal(t, Config{Name: DefaultName, Tag: DefaultTag, Size: DefaultSize, Duration: DefaultDuration, Sources: 1}, b.Config())
+
+	_, err = New(Config{Rate: -1})
+	assert.EqualError(t, err, "bench: the rate must not be negative")
+	_, err = New(Config{Size: 65 << 20})
+	assert.Error(t, err)
+	_, err = New(Config{Zipper: "localhost:9000", Stages: 1})
+	assert.EqualError(t, err, "bench: the stages are run by the in-process YoMo-Zipper only")
+}
+
+func TestProbeTimeout(t *testing.T) {
+	b, err := New(Config{Name: "bench-unreachable"})
+	assert.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	// the writes are lost, so the meter never observes the probes.
+	err = b.probe(ctx, discard{}, NewMeter("bench-"))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "it must be the last stream function of the workflow")
+}
+
+type discard struct{}
+
+func (discard) WriteWithTags(data []byte, tags ...byte) (int, error) {
+	return len(data), nil
+}
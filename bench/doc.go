@@ -0,0 +1,12 @@
+// Package bench is the load generator and the benchmark harness of YoMo, it drives the frames of a size at a rate
+// through a pipeline and reports the percentiles of end-to-end latency and the throughput, so the changes of the
+// transport and the dispatcher are regression tested for performance.
+//
+// The sources write the frames to YoMo-Zipper, the frames pass through the stages of the pipeline and are observed
+// by the meter, a stream function which measures the latency of each frame from the time when it was issued by its
+// source. The pipeline is an in-process YoMo-Zipper with the pass-through stream functions as the stages by default,
+// or the workflow of a remote YoMo-Zipper which has the meter as its last stream function.
+//
+// The frames are written as fast as possible if the rate is 0, the throughput is then the max sustainable throughput
+// of the pipeline.
+package bench
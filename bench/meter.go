@@ -0,0 +1,125 @@
+package bench
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yomorun/yomo/core/rx"
+	"github.com/yomorun/yomo/streamfunction"
+)
+
+// Meter measures the end-to-end latency of the frames observed by the stream function, the latencies are kept in
+// memory until the meter is reset.
+type Meter struct {
+	prefix    string
+	mutex     sync.Mutex
+	latencies []time.Duration
+	bytes     uint64
+	last      time.Time
+	ignored   uint64
+}
+
+// NewMeter creates the meter of the frames whose transaction IDs start with prefix, the other frames are ignored,
+// e.g. the probes which are written before the benchmark.
+func NewMeter(prefix string) *Meter {
+	return &Meter{prefix: prefix}
+}
+
+// Observe records the latency of a frame of size bytes.
+func (m *Meter) Observe(latency time.Duration, size int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.latencies = append(m.latencies, latency)
+	m.bytes += uint64(size)
+	m.last = time.Now()
+}
+
+// Handler returns the handler of rx.Stream which measures the raw carriages observed by the stream function, the
+// latency is from the time when the frame was issued by its source, e.g. sfn.Pipe(meter.Handler()).
+func (m *Meter) Handler() func(rxstream rx.Stream) rx.Stream {
+	return func(rxstream rx.Stream) rx.Stream {
+		return rxstream.RawBytes().FlatMapSlice(func(ctx context.Context, i interface{}) ([]interface{}, error) {
+			if strings.HasPrefix(streamfunction.TransactionID(ctx), m.prefix) {
+				m.Observe(streamfunction.Latency(ctx), len(i.([]byte)))
+			} else {
+				m.ignore()
+			}
+			return nil, nil
+		})
+	}
+}
+
+// ignore counts a frame which isn't measured.
+func (m *Meter) ignore() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.ignored++
+}
+
+// Ignored returns the count of frames ignored by the prefix of transaction IDs.
+func (m *Meter) Ignored() uint64 {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.ignored
+}
+
+// Count returns the count of frames observed.
+func (m *Meter) Count() uint64 {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return uint64(len(m.latencies))
+}
+
+// Bytes returns the bytes of the frames observed.
+func (m *Meter) Bytes() uint64 {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.bytes
+}
+
+// Last returns when the last frame was observed, it's zero if no frame is observed.
+func (m *Meter) Last() time.Time {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.last
+}
+
+// Percentiles returns the latencies at the percentiles in (0, 100] by the nearest rank, they're zero if no frame is
+// observed, e.g. Percentiles(50, 99) returns p50 and p99.
+func (m *Meter) Percentiles(ps ...float64) []time.Duration {
+	m.mutex.Lock()
+	sorted := make([]time.Duration, len(m.latencies))
+	copy(sorted, m.latencies)
+	m.mutex.Unlock()
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	results := make([]time.Duration, len(ps))
+	if len(sorted) == 0 {
+		return results
+	}
+	for i, p := range ps {
+		rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+		if rank < 1 {
+			rank = 1
+		}
+		if rank > len(sorted) {
+			rank = len(sorted)
+		}
+		results[i] = sorted[rank-1]
+	}
+	return results
+}
+
+// Reset drops the latencies observed and the count of frames ignored.
+func (m *Meter) Reset() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.latencies = nil
+	m.bytes = 0
+	m.last = time.Time{}
+	m.ignored = 0
+}
@@ -0,0 +1,31 @@
+package bench
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMeter(t *testing.T) {
+	m := NewMeter("bench-")
+	assert.Equal(t, []time.Duration{0, 0}, m.Percentiles(50, 99))
+	assert.True(t, m.Last().IsZero())
+
+	for i := 100; i >= 1; i-- {
+		m.Observe(time.Duration(i)*time.Millisecond, 10)
+	}
+	assert.Equal(t, uint64(100), m.Count())
+	assert.Equal(t, uint64(1000), m.Bytes())
+	assert.False(t, m.Last().IsZero())
+	assert.Equal(t, []time.Duration{
+		time.Millisecond, 50 * time.Millisecond, 95 * time.Millisecond, 99 * time.Millisecond, 100 * time.Millisecond,
+	}, m.Percentiles(0, 50, 95, 99, 100))
+
+	m.ignore()
+	assert.Equal(t, uint64(1), m.Ignored())
+	m.Reset()
+	assert.Equal(t, uint64(0), m.Count())
+	assert.Equal(t, uint64(0), m.Ignored())
+	assert.Equal(t, []time.Duration{0}, m.Percentiles(99))
+}
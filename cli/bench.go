@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/yomorun/yomo/bench"
+)
+
+func benchCommand(args []string, stdout io.Writer) error {
+	flags := newFlagSet("bench", "", stdout)
+	addr := flags.String("zipper", "", "the address of YoMo-Zipper whose workflow is benchmarked, an in-process YoMo-Zipper is run if it's empty")
+	name := flags.String("n", bench.DefaultName, "the name of stream function which measures the latency, it must be the last one of the workflow")
+	token := flags.String("token", os.Getenv("YOMO_TOKEN"), "the token of YoMo-Zipper, the default is $YOMO_TOKEN")
+	tag := flags.Uint("tag", uint(bench.DefaultTag), "the data tag of the frames")
+	size := flags.Int("size", bench.DefaultSize, "the size in bytes of the frames")
+	rate := flags.Int("rate", 0, "the frames per second, the frames are written as fast as possible to find the max sustainable throughput if it's 0")
+	duration := flags.Duration("d", bench.DefaultDuration, "how long the frames are written")
+	sources := flags.Int("sources", 1, "the count of sources which share the rate")
+	stages := flags.Int("stages", 1, "the count of pass-through stream functions before the meter in the in-process YoMo-Zipper")
+	asJSON := flags.Bool("json", false, "print the result in JSON, the latencies are in nanoseconds")
+	maxP99 := flags.Duration("max-p99", 0, "fail if the p99 latency exceeds it, e.g. in the regression tests")
+	minThroughput := flags.Float64("min-throughput", 0, "fail if the frames per second observed are below it, e.g. in the regression tests")
+	if err := parse(flags, args); err != nil {
+		return err
+	}
+	if *tag > 0xff {
+		return errors.New("the data tag must be in the range [0, 255]")
+	}
+	conf := bench.Config{Zipper: *addr, Token: *token, Name: *name, Tag: byte(*tag), Size: *size, Rate: *rate, Duration: *duration, Sources: *sources}
+	if conf.Zipper == "" {
+		conf.Stages = *stages
+	}
+	b, err := bench.New(conf)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	result, err := b.Run(ctx)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		if err := json.NewEncoder(stdout).Encode(result); err != nil {
+			return err
+		}
+	} else {
+		printResult(result, stdout)
+	}
+
+	if *maxP99 > 0 && result.P99 > *maxP99 {
+		return fmt.Errorf("the p99 latency %v exceeds %v", result.P99, *maxP99)
+	}
+	if *minThroughput > 0 && result.Throughput < *minThroughput {
+		return fmt.Errorf("the throughput %.1f frames/s is below %.1f frames/s", result.Throughput, *minThroughput)
+	}
+	return nil
+}
+
+// printResult prints the result of benchmark.
+func printResult(r *bench.Result, stdout io.Writer) {
+	fmt.Fprintf(stdout, "ℹ️   Sent %d frames, %d failed, received %d frames, lost %d (%.2f%%)\n", r.Sent, r.Failed, r.Received, r.Lost, r.LossRate()*100)
+	fmt.Fprintf(stdout, "ℹ️   Throughput: %.1f frames/s, %.2f MB/s\n", r.Throughput, r.Bandwidth/(1<<20))
+	fmt.Fprintf(stdout, "ℹ️   Latency: p50 %v, p95 %v, p99 %v, max %v\n",
+		r.P50.Round(time.Microsecond), r.P95.Round(time.Microsecond), r.P99.Round(time.Microsecond), r.Max.Round(time.Microsecond))
+}
@@ -0,0 +1,27 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/bench"
+)
+
+func TestBench(t *testing.T) {
+	var stdout bytes.Buffer
+	assert.NoError(t, benchCommand([]string{"-n", "cli-bench", "-size", "64", "-rate", "100", "-d", "500ms", "-json"}, &stdout))
+	var result bench.Result
+	assert.NoError(t, json.Unmarshal(stdout.Bytes(), &result))
+	assert.True(t, result.Sent > 0)
+	assert.Equal(t, result.Sent, result.Received)
+
+	stdout.Reset()
+	err := benchCommand([]string{"-n", "cli-bench-slo", "-rate", "100", "-d", "200ms", "-max-p99", "1ns"}, &stdout)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "the p99 latency")
+	assert.Contains(t, stdout.String(), "Latency: p50")
+
+	assert.EqualError(t, benchCommand([]string{"-rate", "-1"}, &stdout), "bench: the rate must not be negative")
+}
@@ -0,0 +1,98 @@
+// Package cli is the yomo command, it scaffolds the stream functions, runs them with a local YoMo-Zipper while
+// they're developed, runs YoMo-Zipper from the workflow config, queries the status of YoMo-Zipper, records the
+// traffic of YoMo-Zipper to replay it, and benchmarks the pipelines.
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// command is a sub-command of yomo.
+type command struct {
+	usage string
+	run   func(args []string, stdout io.Writer) error
+}
+
+// commands are the sub-commands by name, they're registered in init since they print their own usage.
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"init":   {usage: "Scaffold a stream function project", run: initCommand},
+		"dev":    {usage: "Run a local YoMo-Zipper and the stream function, which is rebuilt when its code changes", run: devCommand},
+		"run":    {usage: "Run YoMo-Zipper from the workflow config", run: runCommand},
+		"status": {usage: "Query the status of YoMo-Zipper by the admin API", run: statusCommand},
+		"record": {usage: "Record the data of a tag in YoMo-Zipper to a file", run: recordCommand},
+		"replay": {usage: "Replay the data recorded in a file to YoMo-Zipper", run: replayCommand},
+		"bench":  {usage: "Benchmark the end-to-end latency and the throughput of a pipeline", run: benchCommand},
+	}
+}
+
+// Run runs the yomo command with the arguments without the program name, it returns the exit code.
+func Run(args []string, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		usage(stdout)
+		return 0
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "❌ Unknown command %q\n\n", args[0])
+		usage(stderr)
+		return 2
+	}
+
+	err := cmd.run(args[1:], stdout)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if errors.Is(err, errUsage) {
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "❌ %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// errUsage is returned by the commands when the arguments are invalid, the usage is printed by the flags.
+var errUsage = errors.New("invalid arguments")
+
+// newFlagSet creates the flags of command, the errors of parsing are reported to stdout with the usage.
+func newFlagSet(name string, args string, stdout io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stdout)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: yomo %s [flags] %s\n\n%s.\n\nFlags:\n", name, args, commands[name].usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse parses the arguments, the invalid arguments are reported as errUsage.
+func parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+	return nil
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: yomo <command> [flags]\n\nCommands:\n")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-8s %s\n", name, commands[name].usage)
+	}
+	fmt.Fprintf(w, "\nRun 'yomo <command> -h' for the flags of command.\n")
+}
@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/zipper"
+)
+
+func TestRun(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 0, Run(nil, &stdout, &stderr))
+	assert.Contains(t, stdout.String(), "Usage: yomo <command> [flags]")
+
+	assert.Equal(t, 2, Run([]string{"deploy"}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), `Unknown command "deploy"`)
+
+	stdout.Reset()
+	assert.Equal(t, 2, Run([]string{"init"}, &stdout, &stderr))
+	assert.Contains(t, stdout.String(), "Usage: yomo init [flags] <name>")
+	assert.Equal(t, 0, Run([]string{"run", "-h"}, &stdout, &stderr))
+
+	stderr.Reset()
+	assert.Equal(t, 1, Run([]string{"run", "-c", "workflow.json"}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "The extension of workflow config is incorrect")
+}
+
+func TestScaffold(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "noise")
+	assert.NoError(t, scaffold(dir, project{Name: "noise", Observe: "0x10", Respond: "0x11"}))
+
+	// the scaffold is valid Go code.
+	for _, file := range []string{"app.go", "main.go"} {
+		_, err := parser.ParseFile(token.NewFileSet(), filepath.Join(dir, file), nil, 0)
+		assert.NoError(t, err, file)
+	}
+	conf, err := zipper.ParseConfig(filepath.Join(dir, "workflow.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, "noise", conf.Functions[0].Name)
+
+	// the existing files are not overwritten.
+	assert.EqualError(t, scaffold(dir, project{Name: "noise"}), "the file "+filepath.Join(dir, "app.go")+" already exists")
+	assert.Error(t, scaffold(filepath.Join(t.TempDir(), "no ise"), project{Name: "no ise"}))
+}
+
+func TestDevConfig(t *testing.T) {
+	dir := t.TempDir()
+	conf, err := devConfig(dir, "", "noise", time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, []zipper.App{{Name: "noise"}}, conf.Functions)
+	assert.Nil(t, conf.Reload)
+
+	// the workflow config in dir is reloaded when it changes.
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "workflow.yaml"), []byte("name: dev\nhost: localhost\nport: 9000\nfunctions:\n  - name: a\n  - name: noise\n"), 0644))
+	conf, err = devConfig(dir, "", "noise", time.Second)
+	assert.NoError(t, err)
+	assert.Len(t, conf.Functions, 2)
+	assert.Equal(t, &zipper.ReloadConfig{WatchInterval: time.Second}, conf.Reload)
+}
+
+func TestLastModified(t *testing.T) {
+	dir := t.TempDir()
+	assert.True(t, lastModified(dir).IsZero())
+
+	app := filepath.Join(dir, "app.go")
+	assert.NoError(t, ioutil.WriteFile(app, []byte("package main\n"), 0644))
+	modTime := time.Now().Add(-time.Hour)
+	assert.NoError(t, os.Chtimes(app, modTime, modTime))
+	assert.Equal(t, modTime.Unix(), lastModified(dir).Unix())
+
+	// the built binary and the other files don't trigger the rebuild.
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, ".yomo"), 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, ".yomo", "main.go"), []byte("package main\n"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte("# noise\n"), 0644))
+	assert.Equal(t, modTime.Unix(), lastModified(dir).Unix())
+}
+
+func TestStatus(t *testing.T) {
+	responses := map[string]interface{}{
+		"/stats":       zipper.Stats{Name: "zipper", Uptime: 90, Connections: 2, FramesIn: 10, FramesOut: 9},
+		"/stages":      []zipper.StageInfo{{Name: "noise", Instances: 1, Backlog: 3, Latency: 0.002, Slow: true, Bottleneck: true}, {Name: "echo", Wasm: true}},
+		"/connections": []zipper.ConnectionInfo{{Name: "noise", Type: "Stream Function", RemoteAddr: "10.0.0.1:5000", Uptime: 60, Frames: 7}},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "unauthorized"})
+			return
+		}
+		json.NewEncoder(w).Encode(responses[r.URL.Path])
+	}))
+	defer server.Close()
+
+	var stdout bytes.Buffer
+	assert.NoError(t, statusCommand([]string{"-admin", server.URL, "-token", "secret"}, &stdout))
+	out := stdout.String()
+	assert.Contains(t, out, "YoMo-Zipper zipper, uptime: 1m30s, connections: 2, frames in: 10, frames out: 9")
+	assert.Regexp(t, `noise\s+remote\s+1\s+3\s+2ms\s+bottleneck`, out)
+	assert.Regexp(t, `echo\s+wasm\s+0\s+0\s+0s`, out)
+	assert.Regexp(t, `noise\s+Stream Function\s+10.0.0.1:5000\s+1m0s\s+7`, out)
+
+	err := statusCommand([]string{"-admin", server.URL, "-token", "other"}, &stdout)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "responds 401: unauthorized")
+}
@@ -0,0 +1,246 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/yomorun/yomo/streamfunction"
+	"github.com/yomorun/yomo/zipper"
+)
+
+func devCommand(args []string, stdout io.Writer) error {
+	flags := newFlagSet("dev", "[dir]", stdout)
+	config := flags.String("c", "", "the path of workflow config of the local YoMo-Zipper, the default is workflow.yaml in dir")
+	name := flags.String("n", "", "the name of stream function, the default is the name of dir")
+	interval := flags.Duration("watch", time.Second, "the interval of checking the changes of code")
+	hot := flags.Bool("hot", true, "keep the stream function registered in YoMo-Zipper while it's rebuilt and restarted")
+	if err := parse(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return errUsage
+	}
+	dir := "."
+	if flags.NArg() == 1 {
+		dir = flags.Arg(0)
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	if *name == "" {
+		*name = filepath.Base(abs)
+	}
+
+	conf, err := devConfig(abs, *config, *name, *interval)
+	if err != nil {
+		return err
+	}
+	printWorkflow(conf, stdout)
+	z, errs := serve(conf)
+	fmt.Fprintf(stdout, "ℹ️   Running YoMo-Zipper on %s:%d...\n", conf.Host, conf.Port)
+
+	fn := &devFunc{
+		dir:    abs,
+		name:   *name,
+		addr:   fmt.Sprintf("%s:%d", conf.Host, conf.Port),
+		binary: filepath.Join(abs, ".yomo", *name),
+		stdout: stdout,
+	}
+	if runtime.GOOS == "windows" {
+		fn.binary += ".exe"
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	if *hot {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			cancel()
+			return err
+		}
+		fn.relay = listener.Addr().String()
+		go relay(ctx, *name, conf, listener, stdout)
+	}
+	stopped := make(chan struct{})
+	go func() {
+		fn.watch(ctx, *interval)
+		close(stopped)
+	}()
+
+	err = wait(z, errs)
+	cancel()
+	<-stopped
+	return err
+}
+
+// relay connects the stream function to YoMo-Zipper once, and relays the data to its processes which connect to the
+// listener, so the stream function isn't re-registered when it's restarted. It stops when ctx is done.
+func relay(ctx context.Context, name string, conf *zipper.WorkflowConfig, listener net.Listener, stdout io.Writer) {
+	defer listener.Close()
+	sfn := streamfunction.New(name, streamfunction.WithToken(os.Getenv("YOMO_TOKEN")))
+	for {
+		// YoMo-Zipper is started in background, it may not be ready yet.
+		cli, err := sfn.Connect(conf.Host, conf.Port)
+		if err == nil {
+			defer cli.Close()
+			go func() {
+				<-ctx.Done()
+				listener.Close()
+			}()
+			cli.Relay(listener)
+			return
+		}
+		if errors.Is(err, streamfunction.ErrUnauthenticated) || errors.Is(err, streamfunction.ErrUnauthorized) {
+			fmt.Fprintf(stdout, "❌  Connect the YoMo Stream Function to YoMo-Zipper failure: %v\n", err)
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// devConfig returns the workflow config of the local YoMo-Zipper, it's loaded from the file and reloaded when the file
+// changes, or it's the workflow of the stream function if there's no file.
+func devConfig(dir string, config string, name string, interval time.Duration) (*zipper.WorkflowConfig, error) {
+	if config == "" {
+		config = filepath.Join(dir, "workflow.yaml")
+		if _, err := os.Stat(config); os.IsNotExist(err) {
+			return &zipper.WorkflowConfig{
+				Name:     "dev",
+				Host:     "localhost",
+				Port:     9000,
+				Workflow: zipper.Workflow{Functions: []zipper.App{{Name: name}}},
+			}, nil
+		}
+	}
+
+	conf, err := zipper.ParseConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	if conf.Reload == nil {
+		conf.Reload = &zipper.ReloadConfig{WatchInterval: interval}
+	}
+	return conf, nil
+}
+
+// devFunc builds and runs the stream function in dir, it's rebuilt and restarted when its code changes. The data
+// received while it's restarted is held by the relay if it's connected to the relay.
+type devFunc struct {
+	dir    string
+	name   string
+	addr   string
+	binary string
+	relay  string // relay is the address which the stream function connects to instead of YoMo-Zipper if not empty.
+	stdout io.Writer
+	cmd    *exec.Cmd
+	exited chan struct{}
+}
+
+// watch builds and runs the stream function, and checks the changes of code at interval until ctx is done, then
+// the stream function is stopped.
+func (f *devFunc) watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	defer f.stop()
+
+	var modTime time.Time
+	for {
+		if m := lastModified(f.dir); m.After(modTime) {
+			modTime = m
+			f.restart()
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// restart rebuilds the stream function and restarts it, the running one is kept if the build fails.
+func (f *devFunc) restart() {
+	fmt.Fprintln(f.stdout, "⌛  YoMo Stream Function building...")
+	if err := build(f.dir, f.binary, f.stdout); err != nil {
+		fmt.Fprintf(f.stdout, "❌  Build the YoMo Stream Function failure: %v\n", err)
+		return
+	}
+	fmt.Fprintln(f.stdout, "✅  Success! YoMo Stream Function build.")
+
+	f.stop()
+	cmd := exec.Command(f.binary)
+	cmd.Dir = f.dir
+	cmd.Env = append(os.Environ(), "YOMO_ZIPPER_ADDR="+f.addr, "YOMO_SFN_NAME="+f.name)
+	if f.relay != "" {
+		cmd.Env = append(cmd.Env, streamfunction.RelayAddrEnv+"="+f.relay)
+	}
+	cmd.Stdout = f.stdout
+	cmd.Stderr = f.stdout
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintf(f.stdout, "❌  Run the YoMo Stream Function failure: %v\n", err)
+		return
+	}
+	f.cmd, f.exited = cmd, make(chan struct{})
+	go func(exited chan struct{}) {
+		cmd.Wait()
+		close(exited)
+	}(f.exited)
+	fmt.Fprintf(f.stdout, "ℹ️   YoMo Stream Function %s is running...\n", f.name)
+}
+
+// stop kills the running stream function.
+func (f *devFunc) stop() {
+	if f.cmd == nil {
+		return
+	}
+	f.cmd.Process.Kill()
+	<-f.exited
+	f.cmd = nil
+}
+
+// build builds the Go package in dir to the binary.
+func build(dir string, binary string, stdout io.Writer) error {
+	cmd := exec.Command("go", "build", "-o", binary, ".")
+	cmd.Dir = dir
+	cmd.Stdout = stdout
+	cmd.Stderr = stdout
+	return cmd.Run()
+}
+
+// lastModified returns the latest modification time of the Go files and the module files in dir, the hidden
+// directories, e.g. .yomo where the binary is built, and the vendor directory are skipped.
+func lastModified(dir string) time.Time {
+	var modTime time.Time
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path != dir && (strings.HasPrefix(d.Name(), ".") || d.Name() == "vendor") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(d.Name(), ".go") && d.Name() != "go.mod" && d.Name() != "go.sum" {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+		return nil
+	})
+	return modTime
+}
@@ -0,0 +1,131 @@
+package cli
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"text/template"
+)
+
+//go:embed templates
+var templates embed.FS
+
+// project is the data of templates.
+type project struct {
+	Name    string
+	Observe string
+	Respond string
+}
+
+// validName is the pattern of the names of stream functions, they're used as the directories and in the configs.
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+func initCommand(args []string, stdout io.Writer) error {
+	flags := newFlagSet("init", "<name>", stdout)
+	observe := flags.Uint("observe", 0x10, "the data tag which the stream function observes")
+	respond := flags.Uint("respond", 0x11, "the data tag of the responses of stream function")
+	if err := parse(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errUsage
+	}
+	if *observe > 0xff || *respond > 0xff {
+		return errors.New("the data tags must be in the range [0, 255]")
+	}
+
+	name := flags.Arg(0)
+	fmt.Fprintln(stdout, "⌛  Initializing the Stream Function...")
+	p := project{Name: filepath.Base(name), Observe: fmt.Sprintf("0x%02x", *observe), Respond: fmt.Sprintf("0x%02x", *respond)}
+	if err := scaffold(name, p); err != nil {
+		return err
+	}
+	if !hasModule(name) {
+		if err := initModule(name, p.Name, stdout); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintln(stdout, "✅  Congratulations! You have initialized the stream function successfully.")
+	fmt.Fprintln(stdout, "ℹ️   You can enjoy the YoMo Stream Function via the command:")
+	fmt.Fprintf(stdout, "ℹ️   \tDEV: \tyomo dev %s\n", name)
+	fmt.Fprintf(stdout, "ℹ️   \tPROD: \tFirst run YoMo-Zipper, eg: yomo run -c %s\n", filepath.Join(name, "workflow.yaml"))
+	fmt.Fprintf(stdout, "\t\tSecond: cd %s && go run .\n", name)
+	return nil
+}
+
+// scaffold creates the directory of stream function, it has the Handler in app.go, the main function which connects
+// it to YoMo-Zipper in main.go, and the workflow config of the local YoMo-Zipper. The existing files are not
+// overwritten.
+func scaffold(dir string, p project) error {
+	if !validName.MatchString(p.Name) {
+		return fmt.Errorf("the name %q of stream function must be letters, digits, '_', '.' or '-'", p.Name)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, file := range []string{"app.go", "main.go", "workflow.yaml"} {
+		if err := render(filepath.Join(dir, file), file+".tmpl", p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// render writes the template to the file, it fails if the file exists.
+func render(file string, name string, p project) error {
+	t, err := template.ParseFS(templates, "templates/"+name)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if os.IsExist(err) {
+		return fmt.Errorf("the file %s already exists", file)
+	}
+	if err != nil {
+		return err
+	}
+	if err := t.Execute(f, p); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// hasModule indicates if the directory is in a Go module.
+func hasModule(dir string) bool {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(abs, "go.mod")); err == nil {
+			return true
+		}
+		parent := filepath.Dir(abs)
+		if parent == abs {
+			return false
+		}
+		abs = parent
+	}
+}
+
+// initModule creates the Go module of stream function which requires YoMo.
+func initModule(dir string, name string, stdout io.Writer) error {
+	for _, args := range [][]string{{"mod", "init", name}, {"get", "github.com/yomorun/yomo"}, {"mod", "tidy"}} {
+		cmd := exec.Command("go", args...)
+		cmd.Dir = dir
+		cmd.Stdout = stdout
+		cmd.Stderr = stdout
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("go %s failed: %w", args[0]+" "+args[1], err)
+		}
+	}
+	return nil
+}
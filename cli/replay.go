@@ -0,0 +1,162 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/yomorun/yomo/connector/replay"
+	"github.com/yomorun/yomo/source"
+	"github.com/yomorun/yomo/streamfunction"
+)
+
+// lingerTimeout is how long the source is kept after the data is replayed, so the data buffered in the QUIC stream
+// is sent before the session is closed.
+const lingerTimeout = time.Second
+
+func recordCommand(args []string, stdout io.Writer) error {
+	flags := newFlagSet("record", "<file>", stdout)
+	addr := flags.String("zipper", "localhost:9000", "the address of YoMo-Zipper")
+	name := flags.String("n", "yomo-record", "the name of stream function which records the data, it must be in the workflow")
+	token := flags.String("token", os.Getenv("YOMO_TOKEN"), "the token of YoMo-Zipper, the default is $YOMO_TOKEN")
+	tag := flags.Uint("tag", 0x10, "the data tag to record")
+	pass := flags.Bool("pass", true, "pass the data recorded to the next stream functions")
+	if err := parse(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errUsage
+	}
+	if *tag > 0xff {
+		return errors.New("the data tag must be in the range [0, 255]")
+	}
+	host, port, err := splitAddr(*addr)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return record(ctx, flags.Arg(0), host, port, *name, *token, byte(*tag), *pass, stdout)
+}
+
+// record records the data of the tag to the file by the stream function until ctx is done.
+func record(ctx context.Context, path string, host string, port int, name string, token string, tag byte, pass bool, stdout io.Writer) error {
+	recorder, err := replay.NewRecorder(replay.RecorderConfig{Path: path, PassThrough: pass})
+	if err != nil {
+		return err
+	}
+	defer recorder.Close()
+
+	sfn, err := streamfunction.New(name, streamfunction.WithToken(token)).Connect(host, port)
+	if err != nil {
+		return err
+	}
+	defer sfn.Close()
+
+	// the raw carriages of the tag are recorded, so they're replayed as they were written by the sources.
+	if err := sfn.Subscribe(tag); err != nil {
+		return err
+	}
+	go sfn.Pipe(recorder.RawHandler(tag))
+	fmt.Fprintf(stdout, "ℹ️   Recording the data of tag 0x%02x to %s...\n", tag, path)
+	<-ctx.Done()
+	return nil
+}
+
+func replayCommand(args []string, stdout io.Writer) error {
+	flags := newFlagSet("replay", "<file>", stdout)
+	addr := flags.String("zipper", "localhost:9000", "the address of YoMo-Zipper")
+	name := flags.String("n", "yomo-replay", "the name of source which replays the data")
+	token := flags.String("token", os.Getenv("YOMO_TOKEN"), "the token of YoMo-Zipper, the default is $YOMO_TOKEN")
+	speed := flags.Float64("speed", 1, "the multiple of the pace which the data was recorded at, the data is replayed as fast as possible if it's 0")
+	loops := flags.Int("loops", 1, "the count of times the file is replayed, it's replayed until interrupted if it's negative")
+	tags := flags.String("map", "", "map the recorded data tags to the tags which the data is replayed with, e.g. 0x33=0x40,0x34=0x41")
+	tid := flags.Bool("tid", false, "replay the data with the recorded transaction IDs")
+	if err := parse(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errUsage
+	}
+	host, port, err := splitAddr(*addr)
+	if err != nil {
+		return err
+	}
+	conf := replay.ReplayerConfig{Path: flags.Arg(0), Speed: *speed, Unpaced: *speed == 0, Loops: *loops, TransactionIDs: *tid}
+	if conf.Tags, err = parseTagMap(*tags); err != nil {
+		return err
+	}
+	if conf.Loops == 0 {
+		return errors.New("the loops must not be 0")
+	}
+	replayer, err := replay.NewReplayer(conf)
+	if err != nil {
+		return err
+	}
+
+	src, err := source.New(*name, source.WithToken(*token)).Connect(host, port)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := replayer.Run(ctx, src); err != nil {
+		return err
+	}
+	select {
+	case <-time.After(lingerTimeout):
+	case <-ctx.Done():
+	}
+	fmt.Fprintf(stdout, "✅  Replayed the data of %s\n", conf.Path)
+	return nil
+}
+
+// splitAddr splits the address of YoMo-Zipper into the host and the port.
+func splitAddr(addr string) (string, int, error) {
+	host, p, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", 0, fmt.Errorf("the address of YoMo-Zipper is invalid: %w", err)
+	}
+	port, err := strconv.Atoi(p)
+	if err != nil {
+		return "", 0, fmt.Errorf("the port of YoMo-Zipper is invalid: %s", p)
+	}
+	return host, port, nil
+}
+
+// parseTagMap parses the mapping of data tags, e.g. "0x33=0x40,0x34=0x41".
+func parseTagMap(s string) (map[byte]byte, error) {
+	if s == "" {
+		return nil, nil
+	}
+	tags := make(map[byte]byte)
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("the mapping of data tags %q is invalid", pair)
+		}
+		from, err := strconv.ParseUint(strings.TrimSpace(kv[0]), 0, 8)
+		if err != nil {
+			return nil, fmt.Errorf("the data tag %q is invalid", kv[0])
+		}
+		to, err := strconv.ParseUint(strings.TrimSpace(kv[1]), 0, 8)
+		if err != nil {
+			return nil, fmt.Errorf("the data tag %q is invalid", kv[1])
+		}
+		tags[byte(from)] = byte(to)
+	}
+	return tags, nil
+}
@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/connector/replay"
+	"github.com/yomorun/yomo/zipper"
+)
+
+func TestRecordReplay(t *testing.T) {
+	conf := &zipper.WorkflowConfig{Name: "zipper", Host: "localhost", Port: 9041, Workflow: zipper.Workflow{
+		Functions: []zipper.App{{Name: "cli-record"}},
+	}}
+	z, _ := serve(conf)
+	defer z.Close()
+
+	dir := t.TempDir()
+	recorded := filepath.Join(dir, "recorded.ndjson")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	var stdout bytes.Buffer
+	go func() {
+		done <- record(ctx, recorded, "localhost", 9041, "cli-record", "", 0x33, false, &stdout)
+	}()
+	time.Sleep(500 * time.Millisecond)
+
+	traffic := filepath.Join(dir, "traffic.ndjson")
+	assert.NoError(t, ioutil.WriteFile(traffic, []byte(`{"time":"2026-10-16T13:00:00Z","tag":51,"data":"eW8="}
+{"time":"2026-10-16T13:00:00.05Z","tag":52,"data":"bW8="}
+`), 0o644))
+	var out bytes.Buffer
+	assert.NoError(t, replayCommand([]string{"-zipper", "localhost:9041", "-map", "0x34=0x33", traffic}, &out))
+	assert.Contains(t, out.String(), "Replayed the data of "+traffic)
+
+	// both data is recorded by the tag 0x33.
+	assert.Eventually(t, func() bool {
+		buf, _ := ioutil.ReadFile(recorded)
+		return bytes.Count(buf, []byte("\n")) == 2
+	}, 3*time.Second, 50*time.Millisecond)
+	cancel()
+	assert.NoError(t, <-done)
+
+	r, err := replay.NewReplayer(replay.ReplayerConfig{Path: recorded, Unpaced: true})
+	assert.NoError(t, err)
+	w := &dataWriter{}
+	assert.NoError(t, r.Run(context.Background(), w))
+	assert.ElementsMatch(t, []string{"yo", "mo"}, w.data)
+	assert.Equal(t, []byte{0x33, 0x33}, w.tags)
+}
+
+func TestParseTagMap(t *testing.T) {
+	tags, err := parseTagMap("0x33=0x40, 52=65")
+	assert.NoError(t, err)
+	assert.Equal(t, map[byte]byte{0x33: 0x40, 0x34: 0x41}, tags)
+	_, err = parseTagMap("0x33")
+	assert.EqualError(t, err, `the mapping of data tags "0x33" is invalid`)
+	_, err = parseTagMap("0x33=0x100")
+	assert.EqualError(t, err, `the data tag "0x100" is invalid`)
+
+	_, _, err = splitAddr("localhost")
+	assert.Error(t, err)
+}
+
+type dataWriter struct {
+	data []string
+	tags []byte
+}
+
+func (w *dataWriter) WriteWithTags(data []byte, tags ...byte) (int, error) {
+	w.data = append(w.data, string(data))
+	w.tags = append(w.tags, tags...)
+	return len(data), nil
+}
@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/yomorun/yomo/zipper"
+)
+
+func runCommand(args []string, stdout io.Writer) error {
+	flags := newFlagSet("run", "", stdout)
+	config := flags.String("c", "workflow.yaml", "the path of workflow config")
+	if err := parse(flags, args); err != nil {
+		return err
+	}
+
+	conf, err := zipper.ParseConfig(*config)
+	if err != nil {
+		return err
+	}
+	printWorkflow(conf, stdout)
+
+	z, errs := serve(conf)
+	fmt.Fprintf(stdout, "ℹ️   Running YoMo-Zipper on %s:%d...\n", conf.Host, conf.Port)
+	return wait(z, errs)
+}
+
+// printWorkflow prints the stream functions of workflow.
+func printWorkflow(conf *zipper.WorkflowConfig, stdout io.Writer) {
+	fmt.Fprintf(stdout, "ℹ️   Found %d stream functions in YoMo-Zipper config\n", len(conf.Functions))
+	for i, app := range conf.Functions {
+		fmt.Fprintf(stdout, "ℹ️   Stream Function %d: %s\n", i+1, app.Name)
+	}
+}
+
+// serve runs YoMo-Zipper in background, the error of serving is sent to the channel.
+func serve(conf *zipper.WorkflowConfig) (zipper.Zipper, chan error) {
+	z := zipper.New(conf)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- z.Serve(fmt.Sprintf("%s:%d", conf.Host, conf.Port))
+	}()
+	return z, errs
+}
+
+// wait waits until YoMo-Zipper fails or the process is interrupted, YoMo-Zipper is closed when it's interrupted.
+func wait(z zipper.Zipper, errs chan error) error {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sig)
+
+	select {
+	case err := <-errs:
+		return err
+	case <-sig:
+		return z.Close()
+	}
+}
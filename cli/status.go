@@ -0,0 +1,127 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/yomorun/yomo/zipper"
+)
+
+func statusCommand(args []string, stdout io.Writer) error {
+	flags := newFlagSet("status", "", stdout)
+	config := flags.String("c", "", "the path of workflow config, the address and the token of admin API are read from it")
+	admin := flags.String("admin", "localhost:9001", "the address of admin API")
+	token := flags.String("token", os.Getenv("YOMO_ADMIN_TOKEN"), "the token of admin API, the default is $YOMO_ADMIN_TOKEN")
+	if err := parse(flags, args); err != nil {
+		return err
+	}
+	if *config != "" {
+		conf, err := zipper.ParseConfig(*config)
+		if err != nil {
+			return err
+		}
+		if conf.Admin == "" {
+			return errors.New("the admin API is not enabled in the workflow config")
+		}
+		*admin, *token = conf.Admin, conf.AdminToken
+	}
+
+	c := &adminClient{url: adminURL(*admin), token: *token, http: &http.Client{Timeout: 5 * time.Second}}
+	var stats zipper.Stats
+	var stages []zipper.StageInfo
+	var conns []zipper.ConnectionInfo
+	for path, v := range map[string]interface{}{"/stats": &stats, "/stages": &stages, "/connections": &conns} {
+		if err := c.get(path, v); err != nil {
+			return err
+		}
+	}
+	printStatus(stdout, stats, stages, conns)
+	return nil
+}
+
+// adminURL returns the base URL of admin API, the scheme is http if the address doesn't have one.
+func adminURL(addr string) string {
+	if strings.HasPrefix(addr, "http://") || strings.HasPrefix(addr, "https://") {
+		return strings.TrimSuffix(addr, "/")
+	}
+	return "http://" + addr
+}
+
+// adminClient requests the admin API of YoMo-Zipper.
+type adminClient struct {
+	url   string
+	token string
+	http  *http.Client
+}
+
+// get decodes the JSON response of the path to v.
+func (c *adminClient) get(path string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, c.url+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	res, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		var failure struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(res.Body).Decode(&failure)
+		return fmt.Errorf("the admin API %s responds %d: %s", path, res.StatusCode, failure.Error)
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+// printStatus prints the statistics, the stages and the connections of YoMo-Zipper.
+func printStatus(w io.Writer, stats zipper.Stats, stages []zipper.StageInfo, conns []zipper.ConnectionInfo) {
+	uptime := time.Duration(stats.Uptime) * time.Second
+	fmt.Fprintf(w, "YoMo-Zipper %s, uptime: %s, connections: %d, frames in: %d, frames out: %d\n\n",
+		stats.Name, uptime, stats.Connections, stats.FramesIn, stats.FramesOut)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STAGE\tKIND\tINSTANCES\tBACKLOG\tLATENCY\tSLOW")
+	for _, stage := range stages {
+		kind := "remote"
+		switch {
+		case stage.Local:
+			kind = "local"
+		case stage.Serverless:
+			kind = "serverless"
+		case stage.Wasm:
+			kind = "wasm"
+		}
+		slow := ""
+		if stage.Bottleneck {
+			slow = "bottleneck"
+		} else if stage.Slow {
+			slow = "slow"
+		}
+		latency := time.Duration(stage.Latency * float64(time.Second)).Round(time.Microsecond)
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%s\t%s\n", stage.Name, kind, stage.Instances, stage.Backlog, latency, slow)
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CLIENT\tTYPE\tREMOTE ADDR\tUPTIME\tFRAMES")
+	for _, c := range conns {
+		uptime := (time.Duration(c.Uptime) * time.Second).String()
+		if c.Departed {
+			uptime += " (departed)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\n", c.Name, c.Type, c.RemoteAddr, uptime, c.Frames)
+	}
+	tw.Flush()
+}
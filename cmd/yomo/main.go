@@ -0,0 +1,13 @@
+// The yomo command scaffolds the stream functions, runs them with a local YoMo-Zipper while they're developed,
+// runs YoMo-Zipper from the workflow config, and queries the status of YoMo-Zipper.
+package main
+
+import (
+	"os"
+
+	"github.com/yomorun/yomo/cli"
+)
+
+func main() {
+	os.Exit(cli.Run(os.Args[1:], os.Stdout, os.Stderr))
+}
@@ -0,0 +1,9 @@
+// Package cloudevents maps the data of YoMo to the CloudEvents 1.0, so the pipelines interoperate with Knative,
+// EventBridge and the other event brokers. The data tag is mapped to the type of event and the transaction ID to
+// its ID by a Mapping.
+//
+// The Source accepts the events delivered by HTTP in the binary, structured and batched modes, and writes them to
+// YoMo-Zipper with the data tags of their types. The Sink delivers the data of a stream function as the events by
+// HTTP in the binary or structured mode. The Kafka connectors wrap and unwrap the records in the structured mode by
+// the same Mapping, the binary mode isn't supported since the records of Kafka REST Proxy v2 have no headers.
+package cloudevents
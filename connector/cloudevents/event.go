@@ -0,0 +1,251 @@
+package cloudevents
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// SpecVersion is the version of CloudEvents specification.
+	SpecVersion = "1.0"
+	// ContentType is the content type of the events in the structured mode.
+	ContentType = "application/cloudevents+json"
+	// ContentTypeBatch is the content type of the events in the batched mode.
+	ContentTypeBatch = "application/cloudevents-batch+json"
+	// DefaultSource is the default source of the events.
+	DefaultSource = "yomo"
+	// DefaultTypePrefix is the default prefix of the types of the events whose data tags aren't mapped, the type is
+	// the prefix followed by the tag in decimal, e.g. "run.yomo.tag.51".
+	DefaultTypePrefix = "run.yomo.tag."
+)
+
+// the attributes of the specification, the others are the extensions.
+var attributes = map[string]bool{
+	"id": true, "source": true, "specversion": true, "type": true, "subject": true, "time": true,
+	"datacontenttype": true, "dataschema": true, "data": true, "data_base64": true,
+}
+
+// Event is a CloudEvent.
+type Event struct {
+	ID              string
+	Source          string
+	SpecVersion     string
+	Type            string
+	Subject         string
+	Time            time.Time
+	DataContentType string
+	DataSchema      string
+	Data            []byte
+	// Extensions are the extension attributes in their string forms, e.g. "traceparent".
+	Extensions map[string]string
+}
+
+// Validate checks the required attributes of event.
+func (e *Event) Validate() error {
+	switch {
+	case e.SpecVersion != SpecVersion:
+		return fmt.Errorf("cloudevents: unsupported specversion %q", e.SpecVersion)
+	case e.ID == "":
+		return errors.New("cloudevents: the id is required")
+	case e.Source == "":
+		return errors.New("cloudevents: the source is required")
+	case e.Type == "":
+		return errors.New("cloudevents: the type is required")
+	}
+	return nil
+}
+
+// isJSON reports whether the data of content type is JSON, the data without content type is JSON too.
+func isJSON(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || mediaType == "text/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// MarshalJSON encodes the event in the JSON format of the structured mode, the data is embedded if it's JSON,
+// or it's encoded in base64 as data_base64.
+func (e *Event) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{}, len(e.Extensions)+9)
+	for name, value := range e.Extensions {
+		m[name] = value
+	}
+	m["id"] = e.ID
+	m["source"] = e.Source
+	m["specversion"] = e.SpecVersion
+	m["type"] = e.Type
+	if e.Subject != "" {
+		m["subject"] = e.Subject
+	}
+	if !e.Time.IsZero() {
+		m["time"] = e.Time.UTC().Format(time.RFC3339Nano)
+	}
+	if e.DataContentType != "" {
+		m["datacontenttype"] = e.DataContentType
+	}
+	if e.DataSchema != "" {
+		m["dataschema"] = e.DataSchema
+	}
+	if e.Data != nil {
+		if isJSON(e.DataContentType) && json.Valid(e.Data) {
+			m["data"] = json.RawMessage(e.Data)
+		} else {
+			m["data_base64"] = e.Data
+		}
+	}
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON decodes the event in the JSON format of the structured mode. The data is its JSON text if the
+// content type is JSON, or the content of string otherwise, e.g. "text/plain".
+func (e *Event) UnmarshalJSON(buf []byte) error {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(buf, &m); err != nil {
+		return err
+	}
+
+	*e = Event{}
+	str := func(name string) (string, error) {
+		raw, ok := m[name]
+		if !ok || string(raw) == "null" {
+			return "", nil
+		}
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return "", fmt.Errorf("cloudevents: the attribute %s isn't a string", name)
+		}
+		return s, nil
+	}
+	var err error
+	for name, field := range map[string]*string{
+		"id": &e.ID, "source": &e.Source, "specversion": &e.SpecVersion, "type": &e.Type, "subject": &e.Subject,
+		"datacontenttype": &e.DataContentType, "dataschema": &e.DataSchema,
+	} {
+		if *field, err = str(name); err != nil {
+			return err
+		}
+	}
+	t, err := str("time")
+	if err != nil {
+		return err
+	}
+	if t != "" {
+		if e.Time, err = time.Parse(time.RFC3339Nano, t); err != nil {
+			return fmt.Errorf("cloudevents: invalid time %q", t)
+		}
+	}
+
+	if raw, ok := m["data_base64"]; ok && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &e.Data); err != nil {
+			return errors.New("cloudevents: invalid data_base64")
+		}
+	} else if raw, ok := m["data"]; ok && string(raw) != "null" {
+		var s string
+		if !isJSON(e.DataContentType) && json.Unmarshal(raw, &s) == nil {
+			e.Data = []byte(s)
+		} else {
+			e.Data = raw
+		}
+	}
+
+	for name, raw := range m {
+		if attributes[name] {
+			continue
+		}
+		if e.Extensions == nil {
+			e.Extensions = make(map[string]string)
+		}
+		// the extensions of the other types are kept in their JSON texts, e.g. the integers and the booleans.
+		var s string
+		if json.Unmarshal(raw, &s) == nil {
+			e.Extensions[name] = s
+		} else {
+			e.Extensions[name] = string(raw)
+		}
+	}
+	return nil
+}
+
+// Mapping maps the data tags to the types of events, and the transaction IDs to their IDs.
+type Mapping struct {
+	// Source is the source of the events, the default is "yomo".
+	Source string `yaml:"source,omitempty"`
+	// Types maps the types of events to the data tags, e.g. "com.example.noise": 0x33. The data tags which aren't
+	// mapped are typed by TypePrefix, the incoming events of the other types are rejected.
+	Types map[string]byte `yaml:"types,omitempty"`
+	// TypePrefix is the prefix of the types of the data tags which aren't mapped, the type is the prefix followed
+	// by the tag in decimal, the default is "run.yomo.tag.".
+	TypePrefix string `yaml:"type_prefix,omitempty"`
+	// DataContentType is the content type of the data of events, e.g. "application/json".
+	DataContentType string `yaml:"data_content_type,omitempty"`
+}
+
+// Type returns the type of events with the data tag, the least type is returned if several types are mapped to it.
+func (m *Mapping) Type(tag byte) string {
+	mapped := ""
+	for typ, t := range m.Types {
+		if t == tag && (mapped == "" || typ < mapped) {
+			mapped = typ
+		}
+	}
+	if mapped != "" {
+		return mapped
+	}
+	return m.typePrefix() + strconv.Itoa(int(tag))
+}
+
+// Tag returns the data tag of events with the type, ok is false if the type isn't mapped.
+func (m *Mapping) Tag(typ string) (tag byte, ok bool) {
+	if tag, ok := m.Types[typ]; ok {
+		return tag, true
+	}
+	if !strings.HasPrefix(typ, m.typePrefix()) {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(strings.TrimPrefix(typ, m.typePrefix()), 10, 8)
+	if err != nil {
+		return 0, false
+	}
+	return byte(n), true
+}
+
+func (m *Mapping) typePrefix() string {
+	if m.TypePrefix == "" {
+		return DefaultTypePrefix
+	}
+	return m.TypePrefix
+}
+
+// NewEvent returns the event of data with the tag and the transaction ID, which was issued at t. The ID of event is
+// generated if tid is empty.
+func (m *Mapping) NewEvent(tag byte, tid string, data []byte, t time.Time) *Event {
+	source := m.Source
+	if source == "" {
+		source = DefaultSource
+	}
+	if tid == "" {
+		id := make([]byte, 16)
+		rand.Read(id)
+		tid = hex.EncodeToString(id)
+	}
+	return &Event{
+		ID:              tid,
+		Source:          source,
+		SpecVersion:     SpecVersion,
+		Type:            m.Type(tag),
+		Time:            t,
+		DataContentType: m.DataContentType,
+		Data:            data,
+	}
+}
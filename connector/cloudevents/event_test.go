@@ -0,0 +1,70 @@
+package cloudevents
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventJSON(t *testing.T) {
+	e := &Event{
+		ID:              "e-1",
+		Source:          "/sensors/1",
+		SpecVersion:     SpecVersion,
+		Type:            "com.example.noise",
+		Time:            time.Date(2021, 8, 1, 12, 0, 0, 0, time.UTC),
+		DataContentType: "application/json",
+		Data:            []byte(`{"noise":42}`),
+		Extensions:      map[string]string{"traceparent": "00-abc-def-01"},
+	}
+	buf, err := json.Marshal(e)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"id":"e-1","source":"/sensors/1","specversion":"1.0","type":"com.example.noise",
+		"time":"2021-08-01T12:00:00Z","datacontenttype":"application/json","data":{"noise":42},
+		"traceparent":"00-abc-def-01"}`, string(buf))
+	decoded := new(Event)
+	assert.NoError(t, json.Unmarshal(buf, decoded))
+	assert.Equal(t, e, decoded)
+
+	// the binary data is encoded in base64.
+	e = &Event{ID: "e-2", Source: "s", SpecVersion: SpecVersion, Type: "t", DataContentType: "application/octet-stream", Data: []byte{0, 1}}
+	buf, err = json.Marshal(e)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"id":"e-2","source":"s","specversion":"1.0","type":"t","datacontenttype":"application/octet-stream","data_base64":"AAE="}`, string(buf))
+	decoded = new(Event)
+	assert.NoError(t, json.Unmarshal(buf, decoded))
+	assert.Equal(t, e, decoded)
+
+	// the string data of the other content types is its content, the other extensions are in their JSON texts.
+	assert.NoError(t, json.Unmarshal([]byte(`{"id":"e-3","source":"s","specversion":"1.0","type":"t","datacontenttype":"text/plain","data":"hi","seq":7}`), decoded))
+	assert.Equal(t, "hi", string(decoded.Data))
+	assert.Equal(t, map[string]string{"seq": "7"}, decoded.Extensions)
+	assert.NoError(t, decoded.Validate())
+
+	assert.Error(t, json.Unmarshal([]byte(`{"id":1}`), decoded))
+	assert.Error(t, (&Event{ID: "e", Source: "s", Type: "t", SpecVersion: "0.3"}).Validate())
+	assert.Error(t, (&Event{Source: "s", Type: "t", SpecVersion: SpecVersion}).Validate())
+}
+
+func TestMapping(t *testing.T) {
+	m := &Mapping{Types: map[string]byte{"com.example.noise": 0x33, "com.example.sound": 0x33}}
+	assert.Equal(t, "com.example.noise", m.Type(0x33))
+	assert.Equal(t, "run.yomo.tag.52", m.Type(0x34))
+
+	for typ, want := range map[string]byte{"com.example.sound": 0x33, "run.yomo.tag.52": 0x34} {
+		tag, ok := m.Tag(typ)
+		assert.True(t, ok, typ)
+		assert.Equal(t, want, tag, typ)
+	}
+	for _, typ := range []string{"com.example.other", "run.yomo.tag.256", "run.yomo.tag.x"} {
+		_, ok := m.Tag(typ)
+		assert.False(t, ok, typ)
+	}
+
+	e := m.NewEvent(0x33, "", []byte("data"), time.Now())
+	assert.Len(t, e.ID, 32)
+	assert.Equal(t, DefaultSource, e.Source)
+	assert.NoError(t, e.Validate())
+}
@@ -0,0 +1,135 @@
+package cloudevents
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// headerPrefix is the prefix of the headers of attributes in the binary mode.
+const headerPrefix = "Ce-"
+
+// EncodeHTTP sets the headers of the event in h and returns the body of HTTP message, the event is encoded in the
+// structured mode if structured is true, or in the binary mode.
+func EncodeHTTP(h http.Header, e *Event, structured bool) ([]byte, error) {
+	if structured {
+		h.Set("Content-Type", ContentType)
+		return json.Marshal(e)
+	}
+
+	attrs := map[string]string{
+		"id": e.ID, "source": e.Source, "specversion": e.SpecVersion, "type": e.Type, "subject": e.Subject,
+		"dataschema": e.DataSchema,
+	}
+	if !e.Time.IsZero() {
+		attrs["time"] = e.Time.UTC().Format(time.RFC3339Nano)
+	}
+	for name, value := range e.Extensions {
+		attrs[name] = value
+	}
+	for name, value := range attrs {
+		if value != "" {
+			h.Set(headerPrefix+name, encodeHeader(value))
+		}
+	}
+	if e.DataContentType != "" {
+		h.Set("Content-Type", e.DataContentType)
+	}
+	return e.Data, nil
+}
+
+// DecodeHTTP decodes the events of HTTP request in the binary, structured or batched mode, the body is read
+// entirely, so it should be limited by http.MaxBytesReader.
+func DecodeHTTP(r *http.Request) ([]*Event, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case ContentType:
+		e := new(Event)
+		if err := json.Unmarshal(body, e); err != nil {
+			return nil, fmt.Errorf("cloudevents: malformed event: %v", err)
+		}
+		return []*Event{e}, e.Validate()
+	case ContentTypeBatch:
+		var events []*Event
+		if err := json.Unmarshal(body, &events); err != nil {
+			return nil, fmt.Errorf("cloudevents: malformed batch: %v", err)
+		}
+		for _, e := range events {
+			if err := e.Validate(); err != nil {
+				return nil, err
+			}
+		}
+		return events, nil
+	}
+
+	if r.Header.Get(headerPrefix+"Specversion") == "" {
+		return nil, errors.New("cloudevents: the request isn't an event")
+	}
+	e := &Event{DataContentType: r.Header.Get("Content-Type"), Data: body}
+	for name, values := range r.Header {
+		if !strings.HasPrefix(name, headerPrefix) || len(values) == 0 {
+			continue
+		}
+		value, err := decodeHeader(values[0])
+		if err != nil {
+			return nil, fmt.Errorf("cloudevents: malformed header %s", name)
+		}
+		switch attr := strings.ToLower(strings.TrimPrefix(name, headerPrefix)); attr {
+		case "id":
+			e.ID = value
+		case "source":
+			e.Source = value
+		case "specversion":
+			e.SpecVersion = value
+		case "type":
+			e.Type = value
+		case "subject":
+			e.Subject = value
+		case "dataschema":
+			e.DataSchema = value
+		case "time":
+			if e.Time, err = time.Parse(time.RFC3339Nano, value); err != nil {
+				return nil, fmt.Errorf("cloudevents: invalid time %q", value)
+			}
+		default:
+			if e.Extensions == nil {
+				e.Extensions = make(map[string]string)
+			}
+			e.Extensions[attr] = value
+		}
+	}
+	return []*Event{e}, e.Validate()
+}
+
+// encodeHeader percent-encodes the space, the double quote, the percent sign and the characters outside the
+// printable ASCII in the header value, as the HTTP binding requires.
+func encodeHeader(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c <= ' ' || c >= 0x7f || c == '"' || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+func decodeHeader(value string) (string, error) {
+	if !strings.Contains(value, "%") {
+		return value, nil
+	}
+	return url.PathUnescape(value)
+}
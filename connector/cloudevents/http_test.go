@@ -0,0 +1,55 @@
+package cloudevents
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTP(t *testing.T) {
+	e := &Event{
+		ID:              "e-1",
+		Source:          "/sensors/1",
+		SpecVersion:     SpecVersion,
+		Type:            "com.example.noise",
+		Subject:         "a \"quoted\" 100% café",
+		DataContentType: "application/json",
+		Data:            []byte(`{"noise":42}`),
+		Extensions:      map[string]string{"partitionkey": "d-1"},
+	}
+	for _, structured := range []bool{false, true} {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		body, err := EncodeHTTP(req.Header, e, structured)
+		assert.NoError(t, err)
+		if !structured {
+			assert.Equal(t, "e-1", req.Header.Get("Ce-Id"))
+			assert.Equal(t, "a%20%22quoted%22%20100%25%20caf%C3%A9", req.Header.Get("Ce-Subject"))
+			assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		events, err := DecodeHTTP(req)
+		assert.NoError(t, err)
+		assert.Equal(t, []*Event{e}, events)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`[
+		{"id":"e-1","source":"s","specversion":"1.0","type":"t"},
+		{"id":"e-2","source":"s","specversion":"1.0","type":"t","data":"hi"}
+	]`))
+	req.Header.Set("Content-Type", ContentTypeBatch)
+	events, err := DecodeHTTP(req)
+	assert.NoError(t, err)
+	assert.Len(t, events, 2)
+	assert.Equal(t, `"hi"`, string(events[1].Data))
+
+	// the request without the attributes isn't an event.
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("data"))
+	_, err = DecodeHTTP(req)
+	assert.Error(t, err)
+}
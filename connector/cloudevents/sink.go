@@ -0,0 +1,121 @@
+package cloudevents
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/yomorun/yomo/connector"
+	"github.com/yomorun/yomo/streamfunction"
+)
+
+// the modes of the HTTP messages of events.
+const (
+	// ModeBinary carries the attributes in the headers and the data in the body.
+	ModeBinary = "binary"
+	// ModeStructured carries the event in the JSON body.
+	ModeStructured = "structured"
+)
+
+// SinkConfig represents the config of CloudEvents sink.
+type SinkConfig struct {
+	// URL is the URL which the events are posted to, e.g. the Knative broker or the EventBridge API destination.
+	URL string `yaml:"url"`
+	// Headers are sent with each request, such as "Authorization".
+	Headers map[string]string `yaml:"headers,omitempty"`
+	// Mode is the mode of the HTTP messages, it's "binary" or "structured", the default is "binary".
+	Mode string `yaml:"mode,omitempty"`
+	// MaxRetries is the count of retries when the delivery fails, the default is 5,
+	// it's retried until the handler is cancelled if it's negative.
+	MaxRetries int `yaml:"max_retries,omitempty"`
+	// PassThrough responds the data after it's delivered, so the next stream functions receive it too.
+	PassThrough bool `yaml:"pass_through,omitempty"`
+	// Mapping maps the data tags to the types of events.
+	Mapping `yaml:",inline"`
+}
+
+// Sink delivers the data of stream function as the events by HTTP, the type of event is mapped from its data tag
+// and its ID is the transaction ID.
+type Sink struct {
+	conf   SinkConfig
+	client *http.Client
+}
+
+// NewSink creates a CloudEvents sink.
+func NewSink(conf SinkConfig) (*Sink, error) {
+	if conf.URL == "" {
+		return nil, errors.New("cloudevents: the URL is required")
+	}
+	switch conf.Mode {
+	case "":
+		conf.Mode = ModeBinary
+	case ModeBinary, ModeStructured:
+	default:
+		return nil, fmt.Errorf("cloudevents: unknown mode %q", conf.Mode)
+	}
+	if conf.MaxRetries == 0 {
+		conf.MaxRetries = connector.DefaultMaxRetries
+	}
+	return &Sink{conf: conf, client: &http.Client{}}, nil
+}
+
+// Publish delivers the data with the tag as an event, it returns after the receiver accepted it. The time of
+// event is taken from ctx of the handler.
+func (s *Sink) Publish(ctx context.Context, tag byte, tid string, data []byte) error {
+	t, ok := streamfunction.Timestamp(ctx)
+	if !ok {
+		t = time.Now()
+	}
+	e := s.conf.NewEvent(tag, tid, data, t)
+	header := make(http.Header)
+	body, err := EncodeHTTP(header, e, s.conf.Mode == ModeStructured)
+	if err != nil {
+		return err
+	}
+	for k, v := range s.conf.Headers {
+		header.Set(k, v)
+	}
+
+	return connector.Retry(ctx, s.conf.MaxRetries, func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.conf.URL, bytes.NewReader(body))
+		if err != nil {
+			return backoff.Permanent(err)
+		}
+		req.Header = header.Clone()
+		res, err := s.client.Do(req)
+		if err != nil {
+			return err
+		}
+		io.Copy(ioutil.Discard, res.Body)
+		res.Body.Close()
+		if res.StatusCode/100 == 2 {
+			return nil
+		}
+		err = fmt.Errorf("cloudevents: status %d of delivering the event %s", res.StatusCode, e.ID)
+		// the client errors except the timeout and the throttling are permanent.
+		if res.StatusCode < http.StatusInternalServerError && res.StatusCode != http.StatusRequestTimeout && res.StatusCode != http.StatusTooManyRequests {
+			return backoff.Permanent(err)
+		}
+		return err
+	})
+}
+
+// Handler returns the handler of stream function which delivers the data observed by the tag, e.g.
+// sfn.PipeFunc(0x33, 0x34, sink.Handler(0x33)).
+func (s *Sink) Handler(tag byte) streamfunction.Handler {
+	return func(ctx context.Context, payload []byte) ([]byte, error) {
+		if err := s.Publish(ctx, tag, streamfunction.TransactionID(ctx), payload); err != nil {
+			return nil, err
+		}
+		if s.conf.PassThrough {
+			return payload, nil
+		}
+		return nil, nil
+	}
+}
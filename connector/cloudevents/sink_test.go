@@ -0,0 +1,76 @@
+package cloudevents
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSink(t *testing.T) {
+	var failures int32 = 1
+	var received []*Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		// the first delivery fails, it's retried.
+		if atomic.AddInt32(&failures, -1) >= 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		events, err := DecodeHTTP(r)
+		assert.NoError(t, err)
+		received = append(received, events...)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	for _, mode := range []string{ModeBinary, ModeStructured} {
+		received = nil
+		sink, err := NewSink(SinkConfig{
+			URL:     server.URL,
+			Headers: map[string]string{"Authorization": "Bearer secret"},
+			Mode:    mode,
+			Mapping: Mapping{Source: "/noise", Types: map[string]byte{"com.example.noise": 0x33}, DataContentType: "application/json"},
+		})
+		assert.NoError(t, err)
+
+		res, err := sink.Handler(0x33)(context.Background(), []byte(`{"noise":42}`))
+		assert.NoError(t, err)
+		assert.Nil(t, res)
+		if assert.Len(t, received, 1, mode) {
+			e := received[0]
+			assert.Equal(t, "/noise", e.Source)
+			assert.Equal(t, "com.example.noise", e.Type)
+			assert.Equal(t, "application/json", e.DataContentType)
+			assert.JSONEq(t, `{"noise":42}`, string(e.Data))
+			assert.False(t, e.Time.IsZero())
+			assert.NotEmpty(t, e.ID)
+		}
+	}
+}
+
+func TestSinkRejected(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	sink, err := NewSink(SinkConfig{URL: server.URL, PassThrough: true})
+	assert.NoError(t, err)
+	// the client errors are not retried.
+	err = sink.Publish(context.Background(), 0x33, "tid-1", []byte("data"))
+	assert.EqualError(t, err, "cloudevents: status 400 of delivering the event tid-1")
+	assert.Equal(t, 1, calls)
+
+	_, err = NewSink(SinkConfig{})
+	assert.Error(t, err)
+	_, err = NewSink(SinkConfig{URL: server.URL, Mode: "batched"})
+	assert.Error(t, err)
+}
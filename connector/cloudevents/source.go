@@ -0,0 +1,127 @@
+package cloudevents
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/yomorun/yomo/connector"
+	"github.com/yomorun/yomo/logger"
+)
+
+const (
+	// DefaultMaxBodySize is the default max size in bytes of the requests.
+	DefaultMaxBodySize = 1 << 20
+	// shutdownTimeout is the timeout of the requests in flight when the source stops.
+	shutdownTimeout = 5 * time.Second
+)
+
+// SourceConfig represents the config of CloudEvents source.
+type SourceConfig struct {
+	// Addr is the address which the source listens on, e.g. ":8080".
+	Addr string `yaml:"addr"`
+	// Path is the path of the requests, the default is "/".
+	Path string `yaml:"path,omitempty"`
+	// MaxBodySize is the max size in bytes of the requests, the default is 1MB.
+	MaxBodySize int64 `yaml:"max_body_size,omitempty"`
+	// Mapping maps the types of events to the data tags.
+	Mapping `yaml:",inline"`
+}
+
+// Source ingests the events delivered by HTTP into YoMo-Zipper, the data of event is written with the data tag of
+// its type and with its ID as the transaction ID. The events are acknowledged after they're written, so the
+// senders retry the failed deliveries.
+type Source struct {
+	conf SourceConfig
+}
+
+// NewSource creates a CloudEvents source.
+func NewSource(conf SourceConfig) (*Source, error) {
+	if conf.Addr == "" {
+		return nil, errors.New("cloudevents: the address is required")
+	}
+	if conf.Path == "" {
+		conf.Path = "/"
+	}
+	if conf.MaxBodySize <= 0 {
+		conf.MaxBodySize = DefaultMaxBodySize
+	}
+	return &Source{conf: conf}, nil
+}
+
+// Run serves the events and writes them to w until ctx is done, e.g. the YoMo-Source client. It returns nil when
+// ctx is done, or the error if the address can't be listened.
+func (s *Source) Run(ctx context.Context, w connector.Writer) error {
+	ln, err := net.Listen("tcp", s.conf.Addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle(s.conf.Path, s.Handler(w))
+	server := &http.Server{Handler: mux}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	logger.Printf("✅ Receiving the CloudEvents on %s%s", ln.Addr(), s.conf.Path)
+	if err := server.Serve(ln); err != http.ErrServerClosed {
+		return err
+	}
+	<-done
+	return nil
+}
+
+// Handler returns the http.Handler which writes the events to w, so it's mounted on an existing HTTP server.
+func (s *Source) Handler(w connector.Writer) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+		case http.MethodOptions:
+			// the abuse protection of the HTTP webhooks, the deliveries of any origin are allowed.
+			if origin := r.Header.Get("WebHook-Request-Origin"); origin != "" {
+				rw.Header().Set("WebHook-Allowed-Origin", origin)
+			}
+			rw.Header().Set("Allow", "POST")
+			rw.WriteHeader(http.StatusOK)
+			return
+		default:
+			rw.Header().Set("Allow", "POST, OPTIONS")
+			http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(rw, r.Body, s.conf.MaxBodySize)
+		events, err := DecodeHTTP(r)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		tags := make([]byte, len(events))
+		for i, e := range events {
+			tag, ok := s.conf.Tag(e.Type)
+			if !ok {
+				http.Error(rw, "cloudevents: the type "+e.Type+" isn't mapped to a data tag", http.StatusBadRequest)
+				return
+			}
+			tags[i] = tag
+		}
+
+		for i, e := range events {
+			if _, err := connector.WriteWithTransaction(w, e.ID, e.Data, tags[i]); err != nil {
+				logger.Error("[cloudevents] write the event to YoMo-Zipper failed.", "id", e.ID, "type", e.Type, "err", err)
+				// the sender retries the delivery later.
+				http.Error(rw, "the event can't be written", http.StatusServiceUnavailable)
+				return
+			}
+		}
+		rw.WriteHeader(http.StatusAccepted)
+	})
+}
@@ -0,0 +1,128 @@
+package cloudevents
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeWriter is the YoMo-Source client which writes the data with the transaction IDs.
+type fakeWriter struct {
+	mutex sync.Mutex
+	fail  bool
+	data  []string
+	tids  []string
+	tags  []byte
+}
+
+func (w *fakeWriter) WriteWithTags(data []byte, tags ...byte) (int, error) {
+	return w.WriteWithTransaction("generated", data, tags...)
+}
+
+func (w *fakeWriter) WriteWithTransaction(tid string, data []byte, tags ...byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.fail {
+		return 0, errors.New("disconnected")
+	}
+	w.data = append(w.data, string(data))
+	w.tids = append(w.tids, tid)
+	w.tags = append(w.tags, tags...)
+	return len(data), nil
+}
+
+func TestSource(t *testing.T) {
+	source, err := NewSource(SourceConfig{Addr: ":0", Mapping: Mapping{Types: map[string]byte{"com.example.noise": 0x33}}})
+	assert.NoError(t, err)
+	w := &fakeWriter{}
+	server := httptest.NewServer(source.Handler(w))
+	defer server.Close()
+
+	post := func(contentType string, headers map[string]string, body string) int {
+		req, err := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(body))
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", contentType)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		res, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	// the binary mode.
+	assert.Equal(t, http.StatusAccepted, post("application/json", map[string]string{
+		"Ce-Id": "e-1", "Ce-Source": "/sensors/1", "Ce-Specversion": "1.0", "Ce-Type": "com.example.noise",
+	}, `{"noise":42}`))
+	// the batched mode, the types which aren't mapped are typed by the prefix.
+	assert.Equal(t, http.StatusAccepted, post(ContentTypeBatch, nil, `[
+		{"id":"e-2","source":"s","specversion":"1.0","type":"com.example.noise","data":{"noise":43}},
+		{"id":"e-3","source":"s","specversion":"1.0","type":"run.yomo.tag.52","data_base64":"AAE="}
+	]`))
+	assert.Equal(t, []string{`{"noise":42}`, `{"noise":43}`, "\x00\x01"}, w.data)
+	assert.Equal(t, []string{"e-1", "e-2", "e-3"}, w.tids)
+	assert.Equal(t, []byte{0x33, 0x33, 0x34}, w.tags)
+
+	// the events of other types are rejected, and nothing of the batch is written.
+	assert.Equal(t, http.StatusBadRequest, post(ContentTypeBatch, nil, `[
+		{"id":"e-4","source":"s","specversion":"1.0","type":"com.example.noise"},
+		{"id":"e-5","source":"s","specversion":"1.0","type":"com.example.other"}
+	]`))
+	assert.Equal(t, http.StatusBadRequest, post(ContentType, nil, `{"id":"e-6"}`))
+	assert.Len(t, w.tids, 3)
+
+	// the sender retries the events which can't be written.
+	w.fail = true
+	assert.Equal(t, http.StatusServiceUnavailable, post(ContentType, nil, `{"id":"e-7","source":"s","specversion":"1.0","type":"com.example.noise"}`))
+
+	// the abuse protection of the webhooks.
+	req, err := http.NewRequest(http.MethodOptions, server.URL, nil)
+	assert.NoError(t, err)
+	req.Header.Set("WebHook-Request-Origin", "eventgrid.azure.net")
+	res, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "eventgrid.azure.net", res.Header.Get("WebHook-Allowed-Origin"))
+}
+
+func TestSourceRun(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := ln.Addr().String()
+	ln.Close()
+
+	source, err := NewSource(SourceConfig{Addr: addr, Path: "/events"})
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- source.Run(ctx, &fakeWriter{})
+	}()
+
+	assert.Eventually(t, func() bool {
+		res, err := http.Get("http://" + addr + "/events")
+		if err != nil {
+			return false
+		}
+		res.Body.Close()
+		return res.StatusCode == http.StatusMethodNotAllowed
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the source isn't stopped")
+	}
+}
@@ -0,0 +1,55 @@
+package connector
+
+import (
+	"context"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+)
+
+const (
+	// DefaultMaxRetries is the default count of retries of delivering the data to the external system.
+	DefaultMaxRetries = 5
+	// retryInitial is the initial interval of retries.
+	retryInitial = 100 * time.Millisecond
+	// retryMax is the max interval of retries.
+	retryMax = 10 * time.Second
+)
+
+// Writer writes the data observed by the data tags to YoMo-Zipper, it's implemented by the YoMo-Source client.
+type Writer interface {
+	WriteWithTags(data []byte, tags ...byte) (int, error)
+}
+
+// TransactionWriter is the Writer which writes the data with the transaction ID assigned by the caller, it's
+// implemented by the YoMo-Source client, so the IDs of the bridged events are kept in YoMo.
+type TransactionWriter interface {
+	Writer
+	WriteWithTransaction(tid string, data []byte, tags ...byte) (int, error)
+}
+
+// WriteWithTransaction writes the data with the transaction ID if w is a TransactionWriter and tid isn't empty,
+// or the transaction ID is generated by w.
+func WriteWithTransaction(w Writer, tid string, data []byte, tags ...byte) (int, error) {
+	if tw, ok := w.(TransactionWriter); ok && tid != "" {
+		return tw.WriteWithTransaction(tid, data, tags...)
+	}
+	return w.WriteWithTags(data, tags...)
+}
+
+// Retry calls fn until it succeeds or ctx is done, it's retried at most maxRetries times with the jittered
+// exponential backoff, or until ctx is done if maxRetries is negative. The last error of fn is returned, or the
+// error of ctx if it's done while waiting. fn stops the retries by returning the error wrapped by backoff.Permanent.
+func Retry(ctx context.Context, maxRetries int, fn func() error) error {
+	eb := backoff.NewExponentialBackOff()
+	eb.InitialInterval = retryInitial
+	eb.MaxInterval = retryMax
+	eb.MaxElapsedTime = 0
+
+	var b backoff.BackOff = eb
+	if maxRetries >= 0 {
+		b = backoff.WithMaxRetries(b, uint64(maxRetries))
+	}
+
+	return backoff.Retry(fn, backoff.WithContext(b, ctx))
+}
@@ -0,0 +1,50 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetry(t *testing.T) {
+	calls := 0
+	err := Retry(context.Background(), 2, func() error {
+		calls++
+		return errors.New("unavailable")
+	})
+	assert.EqualError(t, err, "unavailable")
+	assert.Equal(t, 3, calls)
+
+	calls = 0
+	err = Retry(context.Background(), -1, func() error {
+		calls++
+		if calls < 3 {
+			return errors.New("unavailable")
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+
+	// the retries stop when ctx is done.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = Retry(ctx, -1, func() error {
+		return errors.New("unavailable")
+	})
+	assert.Error(t, err)
+}
+
+func TestRetryPermanent(t *testing.T) {
+	calls := 0
+	err := Retry(context.Background(), -1, func() error {
+		calls++
+		return backoff.Permanent(errors.New("bad request"))
+	})
+	assert.EqualError(t, err, "bad request")
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,4 @@
+// Package connector provides the fundamentals of the connectors which bridge YoMo to the external data platforms,
+// e.g. Kafka. The sink connectors run as stream functions which publish the data to the external systems, and the
+// source connectors ingest the data of the external systems into YoMo-Zipper by the YoMo-Source client.
+package connector
@@ -0,0 +1,241 @@
+package grpcbridge
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yomorun/yomo/logger"
+	"github.com/yomorun/yomo/streamfunction"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// DefaultTimeout is the default timeout of the server processing a data.
+const DefaultTimeout = 10 * time.Second
+
+// errClosed is the error of the data in flight when the adapter is closed.
+var errClosed = errors.New("grpcbridge: the adapter is closed")
+
+// processDesc is the description of the stream Process.
+var processDesc = &grpc.StreamDesc{StreamName: "Process", ServerStreams: true, ClientStreams: true}
+
+// Config represents the config of gRPC adapter.
+type Config struct {
+	// Target is the address of gRPC server in the name syntax of gRPC, e.g. "localhost:9000", "dns:///sfn:9000".
+	Target string `yaml:"target"`
+	// TLS connects to the server by TLS with the system roots, it's insecure if it's false.
+	TLS bool `yaml:"tls,omitempty"`
+	// Timeout is the timeout of the server processing a data, the default is 10s.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+// Adapter calls the stream function implemented as a gRPC server. The data is sent through a bidirectional stream
+// of Process, which is kept open and reopened when it's broken.
+type Adapter struct {
+	conf   Config
+	conn   *grpc.ClientConn
+	nextID uint64
+	mutex  sync.Mutex
+	stream *processStream
+	closed bool
+}
+
+// NewAdapter creates a gRPC adapter, the opts are appended to the dial options, e.g. the credentials.
+// The server is connected lazily, so it may start after the adapter.
+func NewAdapter(conf Config, opts ...grpc.DialOption) (*Adapter, error) {
+	if conf.Target == "" {
+		return nil, errors.New("grpcbridge: the target is required")
+	}
+	if conf.Timeout <= 0 {
+		conf.Timeout = DefaultTimeout
+	}
+
+	dialOpts := []grpc.DialOption{grpc.WithInsecure()}
+	if conf.TLS {
+		dialOpts = []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewClientTLSFromCert(nil, ""))}
+	}
+	conn, err := grpc.Dial(conf.Target, append(dialOpts, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	return &Adapter{conf: conf, conn: conn}, nil
+}
+
+// Process sends the data to the server and returns its response, the ID of data is assigned by the adapter.
+func (a *Adapter) Process(ctx context.Context, data *DataFrame) (*DataFrame, error) {
+	s, err := a.open()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, a.conf.Timeout)
+	defer cancel()
+
+	req := *data
+	req.ID = atomic.AddUint64(&a.nextID, 1)
+	ch := s.register(req.ID)
+	defer s.unregister(req.ID)
+	if err := s.send(&req); err != nil {
+		s.fail(err)
+		return nil, err
+	}
+
+	select {
+	case res := <-ch:
+		return res, nil
+	case <-s.done:
+		return nil, s.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Handler returns the handler of stream function which processes the data observed by the tag on the server, e.g.
+// sfn.PipeFunc(0x33, 0x34, adapter.Handler(0x33)). The error of response fails the handler.
+func (a *Adapter) Handler(tag byte) streamfunction.Handler {
+	return func(ctx context.Context, payload []byte) ([]byte, error) {
+		data := &DataFrame{
+			Tag:           uint32(tag),
+			TransactionID: streamfunction.TransactionID(ctx),
+			Payload:       payload,
+			Hops:          streamfunction.Hops(ctx),
+		}
+		if timestamp, ok := streamfunction.Timestamp(ctx); ok {
+			data.Timestamp = timestamp.UnixNano()
+		}
+
+		res, err := a.Process(ctx, data)
+		if err != nil {
+			return nil, err
+		}
+		if res.Error != "" {
+			return nil, errors.New(res.Error)
+		}
+		if len(res.Payload) == 0 {
+			return nil, nil
+		}
+		return res.Payload, nil
+	}
+}
+
+// Close closes the stream and the connection to the server, the data in flight fail.
+func (a *Adapter) Close() error {
+	a.mutex.Lock()
+	a.closed = true
+	s := a.stream
+	a.mutex.Unlock()
+
+	if s != nil {
+		s.fail(errClosed)
+	}
+	return a.conn.Close()
+}
+
+// open returns the stream of Process, it's reopened if it's broken.
+func (a *Adapter) open() (*processStream, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.closed {
+		return nil, errClosed
+	}
+	if a.stream != nil && !a.stream.broken() {
+		return a.stream, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cs, err := a.conn.NewStream(ctx, processDesc, processMethod, grpc.ForceCodec(codec{}))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	s := &processStream{
+		cs:      cs,
+		cancel:  cancel,
+		pending: make(map[uint64]chan *DataFrame),
+		done:    make(chan struct{}),
+	}
+	go s.recvLoop(a.conf.Target)
+	a.stream = s
+	return s, nil
+}
+
+// processStream is an open stream of Process, the responses are dispatched to the pending data by their IDs.
+type processStream struct {
+	cs       grpc.ClientStream
+	cancel   context.CancelFunc
+	sendLock sync.Mutex
+	mutex    sync.Mutex
+	pending  map[uint64]chan *DataFrame
+	failOnce sync.Once
+	err      error
+	done     chan struct{}
+}
+
+func (s *processStream) register(id uint64) chan *DataFrame {
+	ch := make(chan *DataFrame, 1)
+	s.mutex.Lock()
+	s.pending[id] = ch
+	s.mutex.Unlock()
+	return ch
+}
+
+func (s *processStream) unregister(id uint64) {
+	s.mutex.Lock()
+	delete(s.pending, id)
+	s.mutex.Unlock()
+}
+
+// send sends the data, it's safe for concurrent use.
+func (s *processStream) send(m *DataFrame) error {
+	s.sendLock.Lock()
+	defer s.sendLock.Unlock()
+	return s.cs.SendMsg(m)
+}
+
+// recvLoop receives the responses until the stream is broken.
+func (s *processStream) recvLoop(target string) {
+	for {
+		m := new(DataFrame)
+		if err := s.cs.RecvMsg(m); err != nil {
+			if !s.broken() {
+				logger.Error("[grpcbridge] the stream is broken, will reopen.", "target", target, "err", err)
+			}
+			s.fail(err)
+			return
+		}
+
+		s.mutex.Lock()
+		ch, ok := s.pending[m.ID]
+		s.mutex.Unlock()
+		if !ok {
+			logger.Debug("[grpcbridge] the response of unknown data is discarded.", "target", target, "id", m.ID)
+			continue
+		}
+		select {
+		case ch <- m:
+		default:
+			// the server responded the data more than once.
+		}
+	}
+}
+
+// fail breaks the stream with the error, the pending data fail.
+func (s *processStream) fail(err error) {
+	s.failOnce.Do(func() {
+		s.err = err
+		close(s.done)
+		s.cancel()
+	})
+}
+
+func (s *processStream) broken() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,111 @@
+package grpcbridge
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// upperServer responds the data in upper case, and it ends the stream on "quit".
+type upperServer struct {
+	streams int32
+}
+
+func (s *upperServer) Process(stream ProcessServer) error {
+	atomic.AddInt32(&s.streams, 1)
+	for {
+		m, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch string(m.Payload) {
+		case "quit":
+			return errors.New("quit")
+		case "fail":
+			m.Error = "failed"
+		case "drop":
+			m.Payload = nil
+		default:
+			m.Payload = bytes.ToUpper(m.Payload)
+		}
+		m.TransactionID = ""
+		if err := stream.Send(m); err != nil {
+			return err
+		}
+	}
+}
+
+func TestAdapter(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	server := grpc.NewServer(ServerCodec())
+	srv := &upperServer{}
+	RegisterStreamFunctionServer(server, srv)
+	go server.Serve(ln)
+	defer server.Stop()
+
+	adapter, err := NewAdapter(Config{Target: ln.Addr().String()})
+	assert.NoError(t, err)
+	handler := adapter.Handler(0x33)
+
+	out, err := handler(context.Background(), []byte("hello"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("HELLO"), out)
+
+	out, err = handler(context.Background(), []byte("drop"))
+	assert.NoError(t, err)
+	assert.Nil(t, out)
+
+	_, err = handler(context.Background(), []byte("fail"))
+	assert.EqualError(t, err, "failed")
+
+	res, err := adapter.Process(context.Background(), &DataFrame{Tag: 0x34, Payload: []byte("x"), Hops: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(0x34), res.Tag)
+	assert.Equal(t, uint32(2), res.Hops)
+	assert.Equal(t, uint64(4), res.ID)
+
+	// the broken stream is reopened.
+	_, err = handler(context.Background(), []byte("quit"))
+	assert.Error(t, err)
+	out, err = handler(context.Background(), []byte("again"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("AGAIN"), out)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&srv.streams))
+
+	assert.NoError(t, adapter.Close())
+	_, err = handler(context.Background(), []byte("closed"))
+	assert.ErrorIs(t, err, errClosed)
+}
+
+func TestNewAdapter(t *testing.T) {
+	_, err := NewAdapter(Config{})
+	assert.Error(t, err)
+}
+
+func TestDataFrame(t *testing.T) {
+	m := DataFrame{ID: 1, Tag: 0x33, TransactionID: "tid", Payload: []byte("data"), Timestamp: -1, Hops: 2, Error: "err"}
+	buf := m.Marshal()
+
+	// the unknown fields are skipped.
+	buf = protowire.AppendTag(buf, 99, protowire.Fixed32Type)
+	buf = protowire.AppendFixed32(buf, 7)
+
+	var got DataFrame
+	assert.NoError(t, got.Unmarshal(buf))
+	assert.Equal(t, m, got)
+
+	assert.Empty(t, (&DataFrame{}).Marshal())
+	assert.Error(t, got.Unmarshal([]byte{0x0a}))
+}
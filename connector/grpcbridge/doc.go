@@ -0,0 +1,8 @@
+// Package grpcbridge runs the stream functions implemented as gRPC servers, so they're written in any language with
+// the standard gRPC toolchains. The service is defined in stream.proto, the server implements the bidirectional
+// stream Process, and the Adapter connects to YoMo-Zipper as the stream function, which translates the data to the
+// DataFrame messages of the server and its responses back.
+//
+// The messages are encoded in the protobuf wire format of stream.proto by hand, so no generated code is linked into
+// YoMo, the Go servers register the service by RegisterStreamFunctionServer.
+package grpcbridge
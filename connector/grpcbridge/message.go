@@ -0,0 +1,203 @@
+package grpcbridge
+
+import (
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// the names of service and method in stream.proto.
+const (
+	serviceName   = "yomo.stream.v1.StreamFunction"
+	processMethod = "/" + serviceName + "/Process"
+)
+
+// the field numbers of DataFrame in stream.proto.
+const (
+	fieldID            protowire.Number = 1
+	fieldTag           protowire.Number = 2
+	fieldTransactionID protowire.Number = 3
+	fieldPayload       protowire.Number = 4
+	fieldTimestamp     protowire.Number = 5
+	fieldHops          protowire.Number = 6
+	fieldError         protowire.Number = 7
+)
+
+// DataFrame is the message of stream.proto, the data observed by the stream function or the response to it.
+type DataFrame struct {
+	// ID correlates the response to the data, the response has the ID of its data.
+	ID uint64
+	// Tag is the data tag of the data.
+	Tag uint32
+	// TransactionID is the transaction ID of the data through the pipeline.
+	TransactionID string
+	// Payload is the data, or the result in the response, nothing is sent to the next stream functions if it's empty.
+	Payload []byte
+	// Timestamp is the time in unix nanoseconds when the source created the data, it's 0 if it's not stamped.
+	Timestamp int64
+	// Hops is the count of YoMo-Zippers which the data passed through.
+	Hops uint32
+	// Error fails the processing of the data in the response.
+	Error string
+}
+
+// Marshal encodes the message in the protobuf wire format, the fields of zero values are omitted.
+func (m *DataFrame) Marshal() []byte {
+	var buf []byte
+	if m.ID != 0 {
+		buf = protowire.AppendTag(buf, fieldID, protowire.VarintType)
+		buf = protowire.AppendVarint(buf, m.ID)
+	}
+	if m.Tag != 0 {
+		buf = protowire.AppendTag(buf, fieldTag, protowire.VarintType)
+		buf = protowire.AppendVarint(buf, uint64(m.Tag))
+	}
+	if m.TransactionID != "" {
+		buf = protowire.AppendTag(buf, fieldTransactionID, protowire.BytesType)
+		buf = protowire.AppendString(buf, m.TransactionID)
+	}
+	if len(m.Payload) > 0 {
+		buf = protowire.AppendTag(buf, fieldPayload, protowire.BytesType)
+		buf = protowire.AppendBytes(buf, m.Payload)
+	}
+	if m.Timestamp != 0 {
+		buf = protowire.AppendTag(buf, fieldTimestamp, protowire.VarintType)
+		buf = protowire.AppendVarint(buf, uint64(m.Timestamp))
+	}
+	if m.Hops != 0 {
+		buf = protowire.AppendTag(buf, fieldHops, protowire.VarintType)
+		buf = protowire.AppendVarint(buf, uint64(m.Hops))
+	}
+	if m.Error != "" {
+		buf = protowire.AppendTag(buf, fieldError, protowire.BytesType)
+		buf = protowire.AppendString(buf, m.Error)
+	}
+	return buf
+}
+
+// Unmarshal decodes the message in the protobuf wire format, the unknown fields are skipped.
+func (m *DataFrame) Unmarshal(buf []byte) error {
+	*m = DataFrame{}
+	for len(buf) > 0 {
+		num, typ, n := protowire.ConsumeTag(buf)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		buf = buf[n:]
+
+		var v uint64
+		var b []byte
+		switch typ {
+		case protowire.VarintType:
+			v, n = protowire.ConsumeVarint(buf)
+		case protowire.BytesType:
+			b, n = protowire.ConsumeBytes(buf)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, buf)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		buf = buf[n:]
+
+		switch {
+		case num == fieldID && typ == protowire.VarintType:
+			m.ID = v
+		case num == fieldTag && typ == protowire.VarintType:
+			m.Tag = uint32(v)
+		case num == fieldTransactionID && typ == protowire.BytesType:
+			m.TransactionID = string(b)
+		case num == fieldPayload && typ == protowire.BytesType:
+			m.Payload = append([]byte(nil), b...)
+		case num == fieldTimestamp && typ == protowire.VarintType:
+			m.Timestamp = int64(v)
+		case num == fieldHops && typ == protowire.VarintType:
+			m.Hops = uint32(v)
+		case num == fieldError && typ == protowire.BytesType:
+			m.Error = string(b)
+		}
+	}
+	return nil
+}
+
+// codec encodes DataFrame, and delegates the other messages to the protobuf codec of gRPC, so it's safe to be
+// forced on a server of other services. Its name is "proto", the peers see the standard content type of gRPC.
+type codec struct{}
+
+func (codec) Marshal(v interface{}) ([]byte, error) {
+	if m, ok := v.(*DataFrame); ok {
+		return m.Marshal(), nil
+	}
+	if c := encoding.GetCodec("proto"); c != nil {
+		return c.Marshal(v)
+	}
+	return nil, fmt.Errorf("grpcbridge: unknown message %T", v)
+}
+
+func (codec) Unmarshal(data []byte, v interface{}) error {
+	if m, ok := v.(*DataFrame); ok {
+		return m.Unmarshal(data)
+	}
+	if c := encoding.GetCodec("proto"); c != nil {
+		return c.Unmarshal(data, v)
+	}
+	return fmt.Errorf("grpcbridge: unknown message %T", v)
+}
+
+func (codec) Name() string {
+	return "proto"
+}
+
+// ProcessServer is the server side of the stream Process.
+type ProcessServer interface {
+	Send(*DataFrame) error
+	Recv() (*DataFrame, error)
+	grpc.ServerStream
+}
+
+// StreamFunctionServer is the server of StreamFunction in Go.
+type StreamFunctionServer interface {
+	Process(ProcessServer) error
+}
+
+// RegisterStreamFunctionServer registers the server of StreamFunction on s, s must be created with the option
+// ServerCodec to encode DataFrame.
+func RegisterStreamFunctionServer(s *grpc.Server, srv StreamFunctionServer) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: serviceName,
+		HandlerType: (*StreamFunctionServer)(nil),
+		Streams: []grpc.StreamDesc{{
+			StreamName:    "Process",
+			ServerStreams: true,
+			ClientStreams: true,
+			Handler: func(srv interface{}, stream grpc.ServerStream) error {
+				return srv.(StreamFunctionServer).Process(&processServer{stream})
+			},
+		}},
+		Metadata: "stream.proto",
+	}, srv)
+}
+
+// ServerCodec is the option of gRPC server to encode DataFrame, the messages of the other services are encoded by
+// the protobuf codec as usual.
+func ServerCodec() grpc.ServerOption {
+	return grpc.ForceServerCodec(codec{})
+}
+
+type processServer struct {
+	grpc.ServerStream
+}
+
+func (s *processServer) Send(m *DataFrame) error {
+	return s.ServerStream.SendMsg(m)
+}
+
+func (s *processServer) Recv() (*DataFrame, error) {
+	m := new(DataFrame)
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,9 @@
+// Package kafka bridges YoMo to Kafka by the Kafka REST Proxy v2 API, e.g. the Confluent REST Proxy or the Redpanda
+// HTTP Proxy, so no Kafka client is linked into the process.
+//
+// The Sink publishes the data of a stream function to the topics mapped from its data tags, it's acknowledged after
+// Kafka accepted it, and the failed publishes are retried, so the delivery is at least once.
+//
+// The Source ingests the records of topics into YoMo-Zipper with the data tags mapped from the topics, the offsets
+// are committed after the records are written to YoMo-Zipper, so the delivery is at least once.
+package kafka
@@ -0,0 +1,91 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/cenkalti/backoff/v4"
+)
+
+// the content types of Kafka REST Proxy v2 API.
+const (
+	contentTypeJSON   = "application/vnd.kafka.v2+json"
+	contentTypeBinary = "application/vnd.kafka.binary.v2+json"
+)
+
+// proxyError is the error responded by Kafka REST Proxy.
+type proxyError struct {
+	Status    int    `json:"-"`
+	ErrorCode int    `json:"error_code"`
+	Message   string `json:"message"`
+}
+
+func (e *proxyError) Error() string {
+	return fmt.Sprintf("kafka rest proxy: status %d, error code %d: %s", e.Status, e.ErrorCode, e.Message)
+}
+
+// proxy is the client of Kafka REST Proxy.
+type proxy struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func newProxy(url string, headers map[string]string) *proxy {
+	return &proxy{
+		url:     strings.TrimSuffix(url, "/"),
+		headers: headers,
+		client:  &http.Client{},
+	}
+}
+
+// do sends the request with the JSON body in and decodes the JSON response into out, both of them can be nil.
+// The client errors except timeout and throttling are permanent, they're not retried.
+func (p *proxy) do(ctx context.Context, method, path, contentType, accept string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		buf, err := json.Marshal(in)
+		if err != nil {
+			return backoff.Permanent(err)
+		}
+		body = bytes.NewReader(buf)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, p.url+path, body)
+	if err != nil {
+		return backoff.Permanent(err)
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", accept)
+	for k, v := range p.headers {
+		req.Header.Set(k, v)
+	}
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= http.StatusBadRequest {
+		e := &proxyError{Status: res.StatusCode}
+		if err := json.NewDecoder(res.Body).Decode(e); err != nil {
+			e.Message = http.StatusText(res.StatusCode)
+		}
+		if res.StatusCode < http.StatusInternalServerError && res.StatusCode != http.StatusRequestTimeout && res.StatusCode != http.StatusTooManyRequests {
+			return backoff.Permanent(e)
+		}
+		return e
+	}
+	if out == nil || res.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
@@ -0,0 +1,173 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yomorun/yomo/connector"
+	"github.com/yomorun/yomo/connector/cloudevents"
+	"github.com/yomorun/yomo/streamfunction"
+)
+
+// the partition keys of the published records.
+const (
+	// KeyNone publishes the records without key, they're spread over the partitions.
+	KeyNone = ""
+	// KeyTransactionID keys the records by the transaction ID of data.
+	KeyTransactionID = "tid"
+	// KeyTag keys the records by the data tag in decimal.
+	KeyTag = "tag"
+	// KeyFieldPrefix keys the records by a field of the JSON object of data, e.g. "json:device_id".
+	KeyFieldPrefix = "json:"
+)
+
+// SinkConfig represents the config of Kafka sink.
+type SinkConfig struct {
+	// URL is the base URL of Kafka REST Proxy, e.g. "http://localhost:8082".
+	URL string `yaml:"url"`
+	// Headers are sent with each request to the proxy, such as "Authorization".
+	Headers map[string]string `yaml:"headers,omitempty"`
+	// Topic is the topic which the data is published to if its tag isn't in Topics.
+	Topic string `yaml:"topic,omitempty"`
+	// Topics maps the data tags to the topics.
+	Topics map[byte]string `yaml:"topics,omitempty"`
+	// Key selects the partition key of records, it's one of "", "tid", "tag" and "json:<field>",
+	// the records with the same key are published to the same partition in order.
+	Key string `yaml:"key,omitempty"`
+	// MaxRetries is the count of retries when the publish fails, the default is 5,
+	// it's retried until the handler is cancelled if it's negative.
+	MaxRetries int `yaml:"max_retries,omitempty"`
+	// PassThrough responds the data after it's published, so the next stream functions receive it too.
+	PassThrough bool `yaml:"pass_through,omitempty"`
+	// CloudEvents wraps the data in the CloudEvents of the structured mode, its type is mapped from the data tag
+	// and its ID is the transaction ID. The partition key is still selected from the data.
+	CloudEvents *cloudevents.Mapping `yaml:"cloudevents,omitempty"`
+}
+
+// Sink publishes the data of stream function to Kafka.
+type Sink struct {
+	conf  SinkConfig
+	proxy *proxy
+}
+
+// NewSink creates a Kafka sink.
+func NewSink(conf SinkConfig) (*Sink, error) {
+	if conf.URL == "" {
+		return nil, errors.New("kafka: the URL of REST proxy is required")
+	}
+	switch {
+	case conf.Key == KeyNone, conf.Key == KeyTransactionID, conf.Key == KeyTag:
+	case strings.HasPrefix(conf.Key, KeyFieldPrefix) && len(conf.Key) > len(KeyFieldPrefix):
+	default:
+		return nil, fmt.Errorf("kafka: unknown partition key %q", conf.Key)
+	}
+	if conf.MaxRetries == 0 {
+		conf.MaxRetries = connector.DefaultMaxRetries
+	}
+	return &Sink{conf: conf, proxy: newProxy(conf.URL, conf.Headers)}, nil
+}
+
+// produceRecord is a record in the request of produce.
+type produceRecord struct {
+	Key   []byte `json:"key,omitempty"`
+	Value []byte `json:"value"`
+}
+
+// produceOffset is the result of a record in the response of produce.
+type produceOffset struct {
+	Partition int     `json:"partition"`
+	Offset    int64   `json:"offset"`
+	ErrorCode *int    `json:"error_code"`
+	Error     *string `json:"error"`
+}
+
+// Publish publishes the data with the tag to its topic, it returns after Kafka accepted the record.
+func (s *Sink) Publish(ctx context.Context, tag byte, tid string, data []byte) error {
+	topic, ok := s.conf.Topics[tag]
+	if !ok {
+		topic = s.conf.Topic
+	}
+	if topic == "" {
+		return fmt.Errorf("kafka: no topic for the tag %#x", tag)
+	}
+	key, err := s.key(tag, tid, data)
+	if err != nil {
+		return err
+	}
+	value := data
+	if s.conf.CloudEvents != nil {
+		t, ok := streamfunction.Timestamp(ctx)
+		if !ok {
+			t = time.Now()
+		}
+		if value, err = json.Marshal(s.conf.CloudEvents.NewEvent(tag, tid, data, t)); err != nil {
+			return err
+		}
+	}
+
+	req := map[string][]produceRecord{"records": {{Key: key, Value: value}}}
+	path := "/topics/" + url.PathEscape(topic)
+	return connector.Retry(ctx, s.conf.MaxRetries, func() error {
+		var res struct {
+			Offsets []produceOffset `json:"offsets"`
+		}
+		if err := s.proxy.do(ctx, http.MethodPost, path, contentTypeBinary, contentTypeJSON, req, &res); err != nil {
+			return err
+		}
+		for _, offset := range res.Offsets {
+			if offset.Error != nil {
+				return fmt.Errorf("kafka: publish to %s failed: %s", topic, *offset.Error)
+			}
+		}
+		return nil
+	})
+}
+
+// key returns the partition key of the data.
+func (s *Sink) key(tag byte, tid string, data []byte) ([]byte, error) {
+	switch s.conf.Key {
+	case KeyNone:
+		return nil, nil
+	case KeyTransactionID:
+		return []byte(tid), nil
+	case KeyTag:
+		return []byte(strconv.Itoa(int(tag))), nil
+	}
+
+	field := strings.TrimPrefix(s.conf.Key, KeyFieldPrefix)
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, fmt.Errorf("kafka: the data isn't a JSON object for the partition key: %v", err)
+	}
+	v, ok := obj[field]
+	if !ok {
+		return nil, fmt.Errorf("kafka: the field %s of partition key isn't found", field)
+	}
+	// the string is keyed by its content, the other values are keyed by their JSON text.
+	var str string
+	if err := json.Unmarshal(v, &str); err == nil {
+		return []byte(str), nil
+	}
+	return v, nil
+}
+
+// Handler returns the handler of stream function which publishes the data observed by the tag, e.g.
+// sfn.PipeFunc(0x33, 0x34, sink.Handler(0x33)).
+func (s *Sink) Handler(tag byte) streamfunction.Handler {
+	return func(ctx context.Context, payload []byte) ([]byte, error) {
+		if err := s.Publish(ctx, tag, streamfunction.TransactionID(ctx), payload); err != nil {
+			return nil, err
+		}
+		if s.conf.PassThrough {
+			return payload, nil
+		}
+		return nil, nil
+	}
+}
@@ -0,0 +1,110 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/connector/cloudevents"
+)
+
+func TestSink(t *testing.T) {
+	var failures int32 = 1
+	var topic string
+	var records []produceRecord
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, contentTypeBinary, r.Header.Get("Content-Type"))
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		// the first publish fails, it's retried.
+		if atomic.AddInt32(&failures, -1) >= 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		topic = r.URL.Path
+		var req struct {
+			Records []produceRecord `json:"records"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		records = append(records, req.Records...)
+		w.Write([]byte(`{"offsets":[{"partition":0,"offset":1,"error_code":null,"error":null}]}`))
+	}))
+	defer server.Close()
+
+	sink, err := NewSink(SinkConfig{
+		URL:     server.URL,
+		Headers: map[string]string{"Authorization": "Bearer secret"},
+		Topic:   "default",
+		Topics:  map[byte]string{0x33: "noise"},
+		Key:     "json:device",
+	})
+	assert.NoError(t, err)
+
+	res, err := sink.Handler(0x33)(context.Background(), []byte(`{"device":"d-1","noise":42}`))
+	assert.NoError(t, err)
+	assert.Nil(t, res)
+	assert.Equal(t, "/topics/noise", topic)
+	assert.Equal(t, []produceRecord{{Key: []byte("d-1"), Value: []byte(`{"device":"d-1","noise":42}`)}}, records)
+
+	// the data which isn't a JSON object can't be keyed.
+	assert.Error(t, sink.Publish(context.Background(), 0x34, "tid", []byte("data")))
+}
+
+func TestSinkRejected(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error_code":40401,"message":"Topic not found."}`))
+	}))
+	defer server.Close()
+
+	sink, err := NewSink(SinkConfig{URL: server.URL, Topic: "unknown", Key: KeyTransactionID})
+	assert.NoError(t, err)
+	// the client errors are not retried.
+	err = sink.Publish(context.Background(), 0x33, "tid", []byte("data"))
+	assert.EqualError(t, err, "kafka rest proxy: status 404, error code 40401: Topic not found.")
+	assert.Equal(t, 1, calls)
+}
+
+func TestNewSink(t *testing.T) {
+	_, err := NewSink(SinkConfig{})
+	assert.Error(t, err)
+	_, err = NewSink(SinkConfig{URL: "http://localhost:8082", Key: "unknown"})
+	assert.Error(t, err)
+}
+
+func TestSinkCloudEvents(t *testing.T) {
+	var records []produceRecord
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Records []produceRecord `json:"records"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		records = append(records, req.Records...)
+		w.Write([]byte(`{"offsets":[{"partition":0,"offset":1,"error_code":null,"error":null}]}`))
+	}))
+	defer server.Close()
+
+	sink, err := NewSink(SinkConfig{
+		URL:         server.URL,
+		Topic:       "events",
+		Key:         "json:device",
+		CloudEvents: &cloudevents.Mapping{Source: "/noise", Types: map[string]byte{"com.example.noise": 0x33}},
+	})
+	assert.NoError(t, err)
+
+	// the record is keyed by the data, and its value is the structured event.
+	assert.NoError(t, sink.Publish(context.Background(), 0x33, "tid-1", []byte(`{"device":"d-1"}`)))
+	assert.Len(t, records, 1)
+	assert.Equal(t, "d-1", string(records[0].Key))
+	var e cloudevents.Event
+	assert.NoError(t, json.Unmarshal(records[0].Value, &e))
+	assert.Equal(t, "tid-1", e.ID)
+	assert.Equal(t, "/noise", e.Source)
+	assert.Equal(t, "com.example.noise", e.Type)
+	assert.JSONEq(t, `{"device":"d-1"}`, string(e.Data))
+}
@@ -0,0 +1,242 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/yomorun/yomo/connector"
+	"github.com/yomorun/yomo/connector/cloudevents"
+	"github.com/yomorun/yomo/logger"
+)
+
+const (
+	// DefaultPollTimeout is the default time to wait for the records in each poll.
+	DefaultPollTimeout = time.Second
+	// closeTimeout is the timeout of deleting the consumer instance when the source stops.
+	closeTimeout = 5 * time.Second
+)
+
+// the offsets where the consumer group starts when it has no committed offset.
+const (
+	OffsetEarliest = "earliest"
+	OffsetLatest   = "latest"
+)
+
+// SourceConfig represents the config of Kafka source.
+type SourceConfig struct {
+	// URL is the base URL of Kafka REST Proxy, e.g. "http://localhost:8082".
+	URL string `yaml:"url"`
+	// Headers are sent with each request to the proxy, such as "Authorization".
+	Headers map[string]string `yaml:"headers,omitempty"`
+	// Group is the consumer group, the partitions are shared by the sources in the same group.
+	Group string `yaml:"group"`
+	// Instance is the name of consumer instance in the group, the default is generated by the host name.
+	Instance string `yaml:"instance,omitempty"`
+	// Topics maps the topics to the data tags which their records are written with.
+	Topics map[string]byte `yaml:"topics"`
+	// Offset is where the consumer group starts when it has no committed offset, "earliest" or "latest",
+	// the default is "earliest".
+	Offset string `yaml:"offset,omitempty"`
+	// PollTimeout is the time to wait for the records in each poll, the default is 1s.
+	PollTimeout time.Duration `yaml:"poll_timeout,omitempty"`
+	// CloudEvents unwraps the records of CloudEvents in the structured mode, the data of event is written with the
+	// data tag of its type, or the tag of its topic if the type isn't mapped, and with its ID as the transaction ID.
+	// The records which aren't valid events are skipped.
+	CloudEvents *cloudevents.Mapping `yaml:"cloudevents,omitempty"`
+}
+
+// Source ingests the records of Kafka topics into YoMo-Zipper.
+type Source struct {
+	conf  SourceConfig
+	proxy *proxy
+}
+
+// NewSource creates a Kafka source.
+func NewSource(conf SourceConfig) (*Source, error) {
+	if conf.URL == "" {
+		return nil, errors.New("kafka: the URL of REST proxy is required")
+	}
+	if conf.Group == "" {
+		return nil, errors.New("kafka: the consumer group is required")
+	}
+	if len(conf.Topics) == 0 {
+		return nil, errors.New("kafka: no topic to consume")
+	}
+	switch conf.Offset {
+	case "":
+		conf.Offset = OffsetEarliest
+	case OffsetEarliest, OffsetLatest:
+	default:
+		return nil, fmt.Errorf("kafka: unknown offset %q", conf.Offset)
+	}
+	if conf.Instance == "" {
+		host, _ := os.Hostname()
+		conf.Instance = fmt.Sprintf("yomo-%s-%d", host, time.Now().UnixNano())
+	}
+	if conf.PollTimeout <= 0 {
+		conf.PollTimeout = DefaultPollTimeout
+	}
+	return &Source{conf: conf, proxy: newProxy(conf.URL, conf.Headers)}, nil
+}
+
+// consumedRecord is a record in the response of poll.
+type consumedRecord struct {
+	Topic     string `json:"topic"`
+	Key       []byte `json:"key"`
+	Value     []byte `json:"value"`
+	Partition int    `json:"partition"`
+	Offset    int64  `json:"offset"`
+}
+
+// topicPartition is a partition of topic.
+type topicPartition struct {
+	topic     string
+	partition int
+}
+
+// partitionOffset is the offset of a partition to commit, the consumer group resumes after it.
+type partitionOffset struct {
+	Topic     string `json:"topic"`
+	Partition int    `json:"partition"`
+	Offset    int64  `json:"offset"`
+}
+
+// Run writes the records of topics to w until ctx is done, e.g. the YoMo-Source client. The offsets are committed
+// after the records are written, the failed writes are retried, so the records are delivered at least once.
+// It returns nil when ctx is done, or the error if the consumer can't be created.
+func (s *Source) Run(ctx context.Context, w connector.Writer) error {
+	if err := s.subscribe(ctx); err != nil {
+		return err
+	}
+	defer s.close()
+
+	logger.Printf("✅ Consuming the Kafka topics %v in group %s", s.topics(), s.conf.Group)
+	for ctx.Err() == nil {
+		var records []consumedRecord
+		err := connector.Retry(ctx, -1, func() error {
+			err := s.proxy.do(ctx, http.MethodGet, s.instancePath()+"/records?timeout="+fmt.Sprint(s.conf.PollTimeout.Milliseconds()), "", contentTypeBinary, nil, &records)
+			if err != nil && ctx.Err() == nil {
+				logger.Error("[kafka] poll the records failed, will retry.", "group", s.conf.Group, "err", err)
+			}
+			return err
+		})
+		if err != nil {
+			break
+		}
+		if len(records) == 0 {
+			continue
+		}
+
+		offsets := make(map[topicPartition]int64)
+		for _, r := range records {
+			tag, tid, data, err := s.unwrap(r)
+			if err != nil {
+				logger.Error("[kafka] the record isn't a CloudEvent, it's skipped.", "topic", r.Topic, "offset", r.Offset, "err", err)
+				offsets[topicPartition{r.Topic, r.Partition}] = r.Offset
+				continue
+			}
+			err = connector.Retry(ctx, -1, func() error {
+				_, err := connector.WriteWithTransaction(w, tid, data, tag)
+				if err != nil && ctx.Err() == nil {
+					logger.Error("[kafka] write the record to YoMo-Zipper failed, will retry.", "topic", r.Topic, "offset", r.Offset, "err", err)
+				}
+				return err
+			})
+			if err != nil {
+				// the records which are not written are consumed again by the group.
+				return nil
+			}
+			offsets[topicPartition{r.Topic, r.Partition}] = r.Offset
+		}
+
+		commit := make([]partitionOffset, 0, len(offsets))
+		for tp, offset := range offsets {
+			commit = append(commit, partitionOffset{Topic: tp.topic, Partition: tp.partition, Offset: offset})
+		}
+		err = connector.Retry(ctx, -1, func() error {
+			err := s.proxy.do(ctx, http.MethodPost, s.instancePath()+"/offsets", contentTypeJSON, contentTypeJSON, map[string][]partitionOffset{"offsets": commit}, nil)
+			if err != nil && ctx.Err() == nil {
+				logger.Error("[kafka] commit the offsets failed, will retry.", "group", s.conf.Group, "err", err)
+			}
+			return err
+		})
+		if err != nil {
+			break
+		}
+	}
+	return nil
+}
+
+// unwrap returns the data tag, the transaction ID and the data of record, the ID is empty unless the record is
+// a CloudEvent.
+func (s *Source) unwrap(r consumedRecord) (tag byte, tid string, data []byte, err error) {
+	tag = s.conf.Topics[r.Topic]
+	if s.conf.CloudEvents == nil {
+		return tag, "", r.Value, nil
+	}
+	e := new(cloudevents.Event)
+	if err := json.Unmarshal(r.Value, e); err != nil {
+		return 0, "", nil, err
+	}
+	if err := e.Validate(); err != nil {
+		return 0, "", nil, err
+	}
+	if t, ok := s.conf.CloudEvents.Tag(e.Type); ok {
+		tag = t
+	}
+	return tag, e.ID, e.Data, nil
+}
+
+// subscribe creates the consumer instance and subscribes to the topics.
+func (s *Source) subscribe(ctx context.Context) error {
+	req := map[string]string{
+		"name":               s.conf.Instance,
+		"format":             "binary",
+		"auto.offset.reset":  s.conf.Offset,
+		"auto.commit.enable": "false",
+	}
+	err := connector.Retry(ctx, connector.DefaultMaxRetries, func() error {
+		return s.proxy.do(ctx, http.MethodPost, "/consumers/"+url.PathEscape(s.conf.Group), contentTypeJSON, contentTypeJSON, req, nil)
+	})
+	if err != nil {
+		return fmt.Errorf("kafka: create the consumer failed: %w", err)
+	}
+
+	err = connector.Retry(ctx, connector.DefaultMaxRetries, func() error {
+		return s.proxy.do(ctx, http.MethodPost, s.instancePath()+"/subscription", contentTypeJSON, contentTypeJSON, map[string][]string{"topics": s.topics()}, nil)
+	})
+	if err != nil {
+		s.close()
+		return fmt.Errorf("kafka: subscribe to the topics failed: %w", err)
+	}
+	return nil
+}
+
+// close deletes the consumer instance, so its partitions are rebalanced to the other sources at once.
+func (s *Source) close() {
+	ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+	defer cancel()
+	if err := s.proxy.do(ctx, http.MethodDelete, s.instancePath(), contentTypeJSON, contentTypeJSON, nil, nil); err != nil {
+		logger.Error("[kafka] delete the consumer failed.", "instance", s.conf.Instance, "err", err)
+	}
+}
+
+func (s *Source) instancePath() string {
+	return "/consumers/" + url.PathEscape(s.conf.Group) + "/instances/" + url.PathEscape(s.conf.Instance)
+}
+
+func (s *Source) topics() []string {
+	topics := make([]string, 0, len(s.conf.Topics))
+	for topic := range s.conf.Topics {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics
+}
@@ -0,0 +1,132 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/connector/cloudevents"
+)
+
+// fakeWriter is the YoMo-Source client which fails the first write.
+type fakeWriter struct {
+	mutex  sync.Mutex
+	failed bool
+	data   []string
+	tags   []byte
+}
+
+func (w *fakeWriter) WriteWithTags(data []byte, tags ...byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if !w.failed {
+		w.failed = true
+		return 0, errors.New("disconnected")
+	}
+	w.data = append(w.data, string(data))
+	w.tags = append(w.tags, tags...)
+	return len(data), nil
+}
+
+func TestSource(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mutex sync.Mutex
+	var requests []string
+	var committed []partitionOffset
+	polled := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+
+		switch r.URL.Path {
+		case "/consumers/group":
+			w.Write([]byte(`{"instance_id":"sfn","base_uri":"http://proxy/consumers/group/instances/sfn"}`))
+		case "/consumers/group/instances/sfn/records":
+			assert.Equal(t, contentTypeBinary, r.Header.Get("Accept"))
+			if polled {
+				w.Write([]byte(`[]`))
+				return
+			}
+			polled = true
+			w.Write([]byte(`[
+				{"topic":"noise","key":null,"value":"ZGF0YS0x","partition":0,"offset":7},
+				{"topic":"noise","key":null,"value":"ZGF0YS0y","partition":0,"offset":8}
+			]`))
+		case "/consumers/group/instances/sfn/offsets":
+			var req struct {
+				Offsets []partitionOffset `json:"offsets"`
+			}
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			committed = append(committed, req.Offsets...)
+			cancel()
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	source, err := NewSource(SourceConfig{URL: server.URL, Group: "group", Instance: "sfn", Topics: map[string]byte{"noise": 0x33}})
+	assert.NoError(t, err)
+
+	w := &fakeWriter{}
+	done := make(chan error)
+	go func() {
+		done <- source.Run(ctx, w)
+	}()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the source isn't stopped")
+	}
+
+	assert.Equal(t, []string{"data-1", "data-2"}, w.data)
+	assert.Equal(t, []byte{0x33, 0x33}, w.tags)
+	assert.Equal(t, []partitionOffset{{Topic: "noise", Partition: 0, Offset: 8}}, committed)
+	assert.Equal(t, "POST /consumers/group/instances/sfn/subscription", requests[1])
+	// the consumer instance is deleted when the source stops.
+	assert.Equal(t, "DELETE /consumers/group/instances/sfn", requests[len(requests)-1])
+}
+
+func TestNewSource(t *testing.T) {
+	_, err := NewSource(SourceConfig{URL: "http://localhost:8082", Group: "group"})
+	assert.Error(t, err)
+	_, err = NewSource(SourceConfig{URL: "http://localhost:8082", Group: "group", Topics: map[string]byte{"t": 0x33}, Offset: "middle"})
+	assert.Error(t, err)
+}
+
+func TestSourceCloudEvents(t *testing.T) {
+	source, err := NewSource(SourceConfig{
+		URL:         "http://localhost:8082",
+		Group:       "group",
+		Topics:      map[string]byte{"events": 0x33},
+		CloudEvents: &cloudevents.Mapping{Types: map[string]byte{"com.example.alert": 0x34}},
+	})
+	assert.NoError(t, err)
+
+	// the data tag is mapped from the type of event.
+	tag, tid, data, err := source.unwrap(consumedRecord{Topic: "events", Value: []byte(`{"specversion":"1.0","id":"e-1","source":"/s","type":"com.example.alert","data":{"level":3}}`)})
+	assert.NoError(t, err)
+	assert.Equal(t, byte(0x34), tag)
+	assert.Equal(t, "e-1", tid)
+	assert.JSONEq(t, `{"level":3}`, string(data))
+
+	// the data tag is the tag of topic if the type isn't mapped.
+	tag, _, data, err = source.unwrap(consumedRecord{Topic: "events", Value: []byte(`{"specversion":"1.0","id":"e-2","source":"/s","type":"other","data_base64":"AAE="}`)})
+	assert.NoError(t, err)
+	assert.Equal(t, byte(0x33), tag)
+	assert.Equal(t, []byte{0, 1}, data)
+
+	_, _, _, err = source.unwrap(consumedRecord{Topic: "events", Value: []byte(`{"id":"e-3"}`)})
+	assert.Error(t, err)
+}
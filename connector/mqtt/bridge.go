@@ -0,0 +1,382 @@
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/yomorun/yomo/connector"
+	"github.com/yomorun/yomo/logger"
+)
+
+const (
+	// DefaultKeepAlive is the default interval of the keepalive to the broker.
+	DefaultKeepAlive = 30 * time.Second
+	// DefaultMaxPacketSize is the default max size in bytes of the packets from the broker.
+	DefaultMaxPacketSize = 1 << 20
+	// dialTimeout is the timeout of connecting to the broker.
+	dialTimeout = 10 * time.Second
+	// subscribeID is the packet identifier of SUBSCRIBE, there's only one in a connection.
+	subscribeID = 1
+)
+
+// Config represents the config of MQTT bridge.
+type Config struct {
+	// Broker is the address of broker, e.g. "tcp://localhost:1883", "ssl://localhost:8883".
+	Broker string `yaml:"broker"`
+	// ClientID is the client identifier of the bridge, the default is generated by the host name.
+	// The session of broker is kept across reconnections if it's set and CleanSession is false.
+	ClientID string `yaml:"client_id,omitempty"`
+	// Username is the user name of the bridge on the broker.
+	Username string `yaml:"username,omitempty"`
+	// Password is the password of the bridge on the broker.
+	Password string `yaml:"password,omitempty"`
+	// CleanSession discards the session of broker when the bridge connects, the messages published while it's
+	// disconnected are lost.
+	CleanSession bool `yaml:"clean_session,omitempty"`
+	// Topics maps the topic filters to the data tags, the filters may have the wildcards "+" and "#". When a topic
+	// matches several filters, the most specific one applies, i.e. the filter without wildcard or the longest one.
+	Topics map[string]byte `yaml:"topics"`
+	// QoS is the max QoS of the subscriptions, 0 or 1. The messages of QoS 1 are delivered at least once.
+	QoS byte `yaml:"qos,omitempty"`
+	// KeepAlive is the interval of the keepalive to the broker, the default is 30s.
+	KeepAlive time.Duration `yaml:"keepalive,omitempty"`
+	// MaxPacketSize is the max size in bytes of the packets from the broker, the default is 1MB.
+	MaxPacketSize int `yaml:"max_packet_size,omitempty"`
+	// Envelope wraps the payload in the JSON of Message, so the stream functions know the topic of message,
+	// e.g. the device ID in "devices/123/telemetry".
+	Envelope bool `yaml:"envelope,omitempty"`
+}
+
+// Message is the envelope of a MQTT message, its payload is encoded in base64 in JSON.
+type Message struct {
+	Topic   string `json:"topic"`
+	Payload []byte `json:"payload"`
+}
+
+// Bridge subscribes to the topics on the broker and writes the messages to YoMo-Zipper.
+type Bridge struct {
+	conf      Config
+	tlsConfig *tls.Config
+	filters   []string // the topic filters from the most specific to the least.
+}
+
+// NewBridge creates a MQTT bridge, the tlsConfig is used for the brokers of "ssl://" and "tls://", the system roots
+// are trusted if it's nil.
+func NewBridge(conf Config, tlsConfig *tls.Config) (*Bridge, error) {
+	if conf.Broker == "" {
+		return nil, errors.New("mqtt: the broker is required")
+	}
+	if len(conf.Topics) == 0 {
+		return nil, errors.New("mqtt: no topic to subscribe")
+	}
+	if conf.QoS > 1 {
+		return nil, errors.New("mqtt: the QoS must be 0 or 1")
+	}
+	if conf.ClientID == "" {
+		host, _ := os.Hostname()
+		conf.ClientID = fmt.Sprintf("yomo-%s-%d", host, time.Now().UnixNano())
+	}
+	if conf.KeepAlive <= 0 {
+		conf.KeepAlive = DefaultKeepAlive
+	}
+	if conf.MaxPacketSize <= 0 {
+		conf.MaxPacketSize = DefaultMaxPacketSize
+	}
+
+	filters := make([]string, 0, len(conf.Topics))
+	for filter := range conf.Topics {
+		if !validFilter(filter) {
+			return nil, fmt.Errorf("mqtt: invalid topic filter %q", filter)
+		}
+		filters = append(filters, filter)
+	}
+	sort.Slice(filters, func(i, j int) bool {
+		wi, wj := strings.ContainsAny(filters[i], "+#"), strings.ContainsAny(filters[j], "+#")
+		if wi != wj {
+			return !wi
+		}
+		if len(filters[i]) != len(filters[j]) {
+			return len(filters[i]) > len(filters[j])
+		}
+		return filters[i] < filters[j]
+	})
+
+	return &Bridge{conf: conf, tlsConfig: tlsConfig, filters: filters}, nil
+}
+
+// Run writes the messages of broker to w until ctx is done, e.g. the YoMo-Source client. The bridge reconnects to
+// the broker when the connection is lost, and the failed writes are retried. It returns nil when ctx is done, or the error if the broker address is invalid.
+func (b *Bridge) Run(ctx context.Context, w connector.Writer) error {
+	err := connector.Retry(ctx, -1, func() error {
+		err := b.serve(ctx, w)
+		if err != nil && ctx.Err() == nil {
+			logger.Error("[mqtt] the connection to broker is lost, will reconnect.", "broker", b.conf.Broker, "err", err)
+		}
+		return err
+	})
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// serve connects to the broker and bridges the messages until the connection is lost or ctx is done.
+func (b *Bridge) serve(ctx context.Context, w connector.Writer) error {
+	conn, err := b.dial(ctx)
+	if err != nil {
+		return err
+	}
+	c := &session{conn: conn, r: bufio.NewReader(conn), keepAlive: b.conf.KeepAlive}
+	defer c.close()
+
+	// the connection is closed when ctx is done, so the reads are interrupted.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.close()
+		case <-done:
+		}
+	}()
+
+	if err := b.handshake(c); err != nil {
+		return err
+	}
+	logger.Printf("✅ Bridging the MQTT topics %v from %s", b.filters, b.conf.Broker)
+
+	go c.ping()
+	for {
+		p, err := c.read(b.conf.MaxPacketSize)
+		if err != nil {
+			return err
+		}
+		if p.typ != packetPublish {
+			continue
+		}
+		m, err := decodePublish(p)
+		if err != nil {
+			return err
+		}
+		if err := b.forward(ctx, w, m); err != nil {
+			return err
+		}
+		if m.qos > 0 {
+			if err := c.write(pubackPacket(m.id)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// dial connects to the broker by TCP or TLS.
+func (b *Bridge) dial(ctx context.Context) (net.Conn, error) {
+	u, err := url.Parse(b.conf.Broker)
+	if err != nil {
+		return nil, backoff.Permanent(err)
+	}
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	switch u.Scheme {
+	case "tcp", "mqtt":
+		return dialer.DialContext(ctx, "tcp", u.Host)
+	case "ssl", "tls", "mqtts":
+		conf := b.tlsConfig.Clone()
+		if conf == nil {
+			conf = &tls.Config{}
+		}
+		if conf.ServerName == "" {
+			conf.ServerName = u.Hostname()
+		}
+		conn, err := dialer.DialContext(ctx, "tcp", u.Host)
+		if err != nil {
+			return nil, err
+		}
+		tlsConn := tls.Client(conn, conf)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return tlsConn, nil
+	default:
+		return nil, backoff.Permanent(fmt.Errorf("mqtt: unknown scheme of broker %q", u.Scheme))
+	}
+}
+
+// handshake sends CONNECT and SUBSCRIBE, and waits for their acknowledgements.
+func (b *Bridge) handshake(c *session) error {
+	keepAlive := uint16(b.conf.KeepAlive / time.Second)
+	if err := c.write(connectPacket(b.conf.ClientID, b.conf.Username, b.conf.Password, keepAlive, b.conf.CleanSession)); err != nil {
+		return err
+	}
+	p, err := c.read(b.conf.MaxPacketSize)
+	if err != nil {
+		return err
+	}
+	if p.typ != packetConnack || len(p.body) < 2 {
+		return errors.New("mqtt: the broker didn't acknowledge the connection")
+	}
+	if code := p.body[1]; code != connectAccepted {
+		return fmt.Errorf("mqtt: the connection is refused by the broker, return code %d", code)
+	}
+
+	if err := c.write(subscribePacket(subscribeID, b.filters, b.conf.QoS)); err != nil {
+		return err
+	}
+	// the messages of the persistent session may arrive before SUBACK.
+	for {
+		p, err := c.read(b.conf.MaxPacketSize)
+		if err != nil {
+			return err
+		}
+		if p.typ == packetPublish {
+			c.pending = append(c.pending, p)
+			continue
+		}
+		if p.typ != packetSuback || len(p.body) < 2 || binary.BigEndian.Uint16(p.body) != subscribeID {
+			continue
+		}
+		for i, code := range p.body[2:] {
+			if code == subscribeFailure && i < len(b.filters) {
+				return fmt.Errorf("mqtt: the subscription to %s is rejected by the broker", b.filters[i])
+			}
+		}
+		return nil
+	}
+}
+
+// forward writes the message to YoMo-Zipper with the tag of its topic, the failed writes are retried until ctx is done.
+func (b *Bridge) forward(ctx context.Context, w connector.Writer, m publish) error {
+	tag, ok := b.tagOf(m.topic)
+	if !ok {
+		logger.Debug("[mqtt] the topic isn't mapped to a tag.", "topic", m.topic)
+		return nil
+	}
+	data := m.payload
+	if b.conf.Envelope {
+		buf, err := json.Marshal(Message{Topic: m.topic, Payload: m.payload})
+		if err != nil {
+			return err
+		}
+		data = buf
+	}
+	return connector.Retry(ctx, -1, func() error {
+		_, err := w.WriteWithTags(data, tag)
+		if err != nil && ctx.Err() == nil {
+			logger.Error("[mqtt] write the message to YoMo-Zipper failed, will retry.", "topic", m.topic, "err", err)
+		}
+		return err
+	})
+}
+
+// tagOf returns the tag of the most specific filter which matches the topic.
+func (b *Bridge) tagOf(topic string) (byte, bool) {
+	for _, filter := range b.filters {
+		if matchTopic(filter, topic) {
+			return b.conf.Topics[filter], true
+		}
+	}
+	return 0, false
+}
+
+// validFilter checks the wildcards of the topic filter, "#" must be the last level and "+" must be a whole level.
+func validFilter(filter string) bool {
+	if filter == "" {
+		return false
+	}
+	levels := strings.Split(filter, "/")
+	for i, level := range levels {
+		if strings.Contains(level, "#") && (level != "#" || i != len(levels)-1) {
+			return false
+		}
+		if strings.Contains(level, "+") && level != "+" {
+			return false
+		}
+	}
+	return true
+}
+
+// matchTopic checks if the topic matches the filter, the topics beginning with "$" don't match the wildcards
+// at the first level.
+func matchTopic(filter, topic string) bool {
+	if strings.HasPrefix(topic, "$") && (strings.HasPrefix(filter, "+") || strings.HasPrefix(filter, "#")) {
+		return false
+	}
+	fs, ts := strings.Split(filter, "/"), strings.Split(topic, "/")
+	for i, f := range fs {
+		if f == "#" {
+			return true
+		}
+		if i >= len(ts) || (f != "+" && f != ts[i]) {
+			return false
+		}
+	}
+	return len(fs) == len(ts)
+}
+
+// session is a connection to the broker.
+type session struct {
+	conn      net.Conn
+	r         *bufio.Reader
+	keepAlive time.Duration
+	mutex     sync.Mutex
+	closed    bool
+	pending   []packet // pending are the messages received before SUBACK.
+}
+
+// read reads the next packet, the connection is lost if nothing is received in 1.5 times of keepalive.
+func (c *session) read(maxSize int) (packet, error) {
+	if len(c.pending) > 0 {
+		p := c.pending[0]
+		c.pending = c.pending[1:]
+		return p, nil
+	}
+	c.conn.SetReadDeadline(time.Now().Add(c.keepAlive * 3 / 2))
+	return readPacket(c.r, maxSize)
+}
+
+// write writes the packet, it's safe for concurrent use.
+func (c *session) write(p packet) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.closed {
+		return net.ErrClosed
+	}
+	_, err := c.conn.Write(p.encode())
+	return err
+}
+
+// ping sends PINGREQ in each keepalive interval until the connection is closed.
+func (c *session) ping() {
+	ticker := time.NewTicker(c.keepAlive)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := c.write(packet{typ: packetPingreq}); err != nil {
+			return
+		}
+	}
+}
+
+// close sends DISCONNECT and closes the connection.
+func (c *session) close() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	c.conn.Write(packet{typ: packetDisconnect}.encode())
+	c.conn.Close()
+}
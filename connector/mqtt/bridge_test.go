@@ -0,0 +1,191 @@
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeWriter is the YoMo-Source client.
+type fakeWriter struct {
+	mutex sync.Mutex
+	data  []string
+	tags  []byte
+}
+
+func (w *fakeWriter) WriteWithTags(data []byte, tags ...byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.data = append(w.data, string(data))
+	w.tags = append(w.tags, tags...)
+	return len(data), nil
+}
+
+func publishPacket(topic string, qos byte, id uint16, payload string) packet {
+	body := appendString(nil, topic)
+	if qos > 0 {
+		body = appendUint16(body, id)
+	}
+	return packet{typ: packetPublish, flags: qos << 1, body: append(body, payload...)}
+}
+
+func TestBridge(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer ln.Close()
+
+	acked := make(chan uint16, 1)
+	var connect, subscribe packet
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+
+		connect, _ = readPacket(r, DefaultMaxPacketSize)
+		conn.Write(packet{typ: packetConnack, body: []byte{0, connectAccepted}}.encode())
+		subscribe, _ = readPacket(r, DefaultMaxPacketSize)
+		conn.Write(packet{typ: packetSuback, body: []byte{0, subscribeID, 1, 0}}.encode())
+
+		conn.Write(publishPacket("devices/1/telemetry", 1, 9, "t1").encode())
+		conn.Write(publishPacket("devices/1/status", 0, 0, "s1").encode())
+		conn.Write(publishPacket("unmapped", 0, 0, "x").encode())
+		for {
+			p, err := readPacket(r, DefaultMaxPacketSize)
+			if err != nil {
+				return
+			}
+			if p.typ == packetPuback {
+				acked <- uint16(p.body[0])<<8 | uint16(p.body[1])
+			}
+		}
+	}()
+
+	bridge, err := NewBridge(Config{
+		Broker:   "tcp://" + ln.Addr().String(),
+		ClientID: "bridge",
+		Username: "user",
+		Password: "secret",
+		Topics:   map[string]byte{"devices/+/telemetry": 0x33, "devices/#": 0x34},
+		QoS:      1,
+		Envelope: true,
+	}, nil)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &fakeWriter{}
+	done := make(chan error)
+	go func() { done <- bridge.Run(ctx, w) }()
+
+	select {
+	case id := <-acked:
+		assert.Equal(t, uint16(9), id)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the message isn't acknowledged")
+	}
+	assert.Eventually(t, func() bool {
+		w.mutex.Lock()
+		defer w.mutex.Unlock()
+		return len(w.data) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	assert.NoError(t, <-done)
+
+	assert.Equal(t, packetConnect, connect.typ)
+	assert.Equal(t, connectUsername|connectPassword, connect.body[7])
+	assert.Equal(t, subscribePacket(subscribeID, []string{"devices/+/telemetry", "devices/#"}, 1), subscribe)
+
+	assert.Equal(t, []byte{0x33, 0x34}, w.tags)
+	var m Message
+	assert.NoError(t, json.Unmarshal([]byte(w.data[0]), &m))
+	assert.Equal(t, Message{Topic: "devices/1/telemetry", Payload: []byte("t1")}, m)
+}
+
+func TestBridgeRefused(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer ln.Close()
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		readPacket(bufio.NewReader(conn), DefaultMaxPacketSize)
+		conn.Write(packet{typ: packetConnack, body: []byte{0, 5}}.encode())
+	}()
+
+	bridge, err := NewBridge(Config{Broker: "tcp://" + ln.Addr().String(), Topics: map[string]byte{"#": 0x33}}, nil)
+	assert.NoError(t, err)
+	c, err := bridge.dial(context.Background())
+	assert.NoError(t, err)
+	s := &session{conn: c, r: bufio.NewReader(c), keepAlive: DefaultKeepAlive}
+	defer s.close()
+	assert.EqualError(t, bridge.handshake(s), "mqtt: the connection is refused by the broker, return code 5")
+}
+
+func TestNewBridge(t *testing.T) {
+	_, err := NewBridge(Config{Topics: map[string]byte{"a": 1}}, nil)
+	assert.Error(t, err)
+	_, err = NewBridge(Config{Broker: "tcp://localhost:1883"}, nil)
+	assert.Error(t, err)
+	_, err = NewBridge(Config{Broker: "tcp://localhost:1883", Topics: map[string]byte{"a": 1}, QoS: 2}, nil)
+	assert.Error(t, err)
+	_, err = NewBridge(Config{Broker: "tcp://localhost:1883", Topics: map[string]byte{"a/#/b": 1}}, nil)
+	assert.Error(t, err)
+
+	bridge, err := NewBridge(Config{Broker: "ftp://localhost", Topics: map[string]byte{"a": 1}}, nil)
+	assert.NoError(t, err)
+	assert.Error(t, bridge.Run(context.Background(), &fakeWriter{}))
+}
+
+func TestTagOf(t *testing.T) {
+	bridge, err := NewBridge(Config{
+		Broker: "tcp://localhost:1883",
+		Topics: map[string]byte{"#": 1, "a/+": 2, "a/b": 3, "a/+/c": 4},
+	}, nil)
+	assert.NoError(t, err)
+
+	for topic, want := range map[string]byte{"a/b": 3, "a/x": 2, "a/x/c": 4, "a/x/d": 1, "b": 1} {
+		tag, ok := bridge.tagOf(topic)
+		assert.True(t, ok, topic)
+		assert.Equal(t, want, tag, topic)
+	}
+	_, ok := bridge.tagOf("$SYS/uptime")
+	assert.False(t, ok)
+}
+
+func TestMatchTopic(t *testing.T) {
+	assert.True(t, matchTopic("a/#", "a"))
+	assert.True(t, matchTopic("a/#", "a/b/c"))
+	assert.True(t, matchTopic("+/+", "a/b"))
+	assert.False(t, matchTopic("+", "a/b"))
+	assert.False(t, matchTopic("a/b", "a/b/c"))
+	assert.True(t, matchTopic("$SYS/#", "$SYS/uptime"))
+}
+
+func TestPacket(t *testing.T) {
+	p := publishPacket("topic", 1, 7, string(make([]byte, 300)))
+	got, err := readPacket(bufio.NewReader(bytes.NewReader(p.encode())), DefaultMaxPacketSize)
+	assert.NoError(t, err)
+	assert.Equal(t, p, got)
+
+	m, err := decodePublish(got)
+	assert.NoError(t, err)
+	assert.Equal(t, "topic", m.topic)
+	assert.Equal(t, uint16(7), m.id)
+	assert.Len(t, m.payload, 300)
+
+	_, err = readPacket(bufio.NewReader(bytes.NewReader(p.encode())), 100)
+	assert.Error(t, err)
+}
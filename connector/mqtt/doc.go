@@ -0,0 +1,5 @@
+// Package mqtt bridges the MQTT fleets to YoMo, the Bridge subscribes to the topics on an MQTT 3.1.1 broker and
+// writes the messages to YoMo-Zipper with the data tags mapped from the topics, so the devices feed YoMo without
+// firmware changes. The messages of QoS 1 are acknowledged after they're written to YoMo-Zipper, so they're
+// delivered at least once.
+package mqtt
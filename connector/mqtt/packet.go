@@ -0,0 +1,187 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// the types of MQTT control packets.
+const (
+	packetConnect    byte = 1
+	packetConnack    byte = 2
+	packetPublish    byte = 3
+	packetPuback     byte = 4
+	packetSubscribe  byte = 8
+	packetSuback     byte = 9
+	packetPingreq    byte = 12
+	packetPingresp   byte = 13
+	packetDisconnect byte = 14
+)
+
+// the flags of CONNECT packet.
+const (
+	connectCleanSession byte = 0x02
+	connectPassword     byte = 0x40
+	connectUsername     byte = 0x80
+)
+
+const (
+	// protocolLevel is the level of MQTT 3.1.1.
+	protocolLevel byte = 4
+	// connectAccepted is the return code of CONNACK when the connection is accepted.
+	connectAccepted byte = 0
+	// subscribeFailure is the return code of SUBACK when the subscription is rejected.
+	subscribeFailure byte = 0x80
+)
+
+// packet is a MQTT control packet.
+type packet struct {
+	typ   byte
+	flags byte
+	body  []byte
+}
+
+// readPacket reads a packet, the remaining length is limited by maxSize.
+func readPacket(r *bufio.Reader, maxSize int) (packet, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return packet{}, err
+	}
+
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return packet{}, errors.New("mqtt: malformed remaining length")
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return packet{}, err
+		}
+		length += int(b&0x7f) * multiplier
+		multiplier *= 128
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	if length > maxSize {
+		return packet{}, fmt.Errorf("mqtt: the packet of %d bytes exceeds the max size", length)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return packet{}, err
+	}
+	return packet{typ: header >> 4, flags: header & 0x0f, body: body}, nil
+}
+
+// encode encodes the packet with its fixed header.
+func (p packet) encode() []byte {
+	buf := []byte{p.typ<<4 | p.flags}
+	length := len(p.body)
+	for {
+		b := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			b |= 0x80
+		}
+		buf = append(buf, b)
+		if length == 0 {
+			break
+		}
+	}
+	return append(buf, p.body...)
+}
+
+// appendString appends the UTF-8 string with its length prefix.
+func appendString(buf []byte, s string) []byte {
+	buf = appendUint16(buf, uint16(len(s)))
+	return append(buf, s...)
+}
+
+// appendUint16 appends the uint16 in big endian.
+func appendUint16(buf []byte, v uint16) []byte {
+	return append(buf, byte(v>>8), byte(v))
+}
+
+// readString reads the string with its length prefix, it returns the rest of buf.
+func readString(buf []byte) (string, []byte, error) {
+	if len(buf) < 2 {
+		return "", nil, errors.New("mqtt: malformed string")
+	}
+	n := int(binary.BigEndian.Uint16(buf))
+	if len(buf) < 2+n {
+		return "", nil, errors.New("mqtt: malformed string")
+	}
+	return string(buf[2 : 2+n]), buf[2+n:], nil
+}
+
+// connectPacket encodes the CONNECT packet of MQTT 3.1.1.
+func connectPacket(clientID, username, password string, keepAlive uint16, clean bool) packet {
+	flags := byte(0)
+	if clean {
+		flags |= connectCleanSession
+	}
+	if username != "" {
+		flags |= connectUsername
+	}
+	if password != "" {
+		flags |= connectPassword
+	}
+
+	body := appendString(nil, "MQTT")
+	body = append(body, protocolLevel, flags)
+	body = appendUint16(body, keepAlive)
+	body = appendString(body, clientID)
+	if username != "" {
+		body = appendString(body, username)
+	}
+	if password != "" {
+		body = appendString(body, password)
+	}
+	return packet{typ: packetConnect, body: body}
+}
+
+// subscribePacket encodes the SUBSCRIBE packet of the topic filters with the QoS.
+func subscribePacket(id uint16, filters []string, qos byte) packet {
+	body := appendUint16(nil, id)
+	for _, filter := range filters {
+		body = appendString(body, filter)
+		body = append(body, qos)
+	}
+	return packet{typ: packetSubscribe, flags: 0x02, body: body}
+}
+
+// pubackPacket encodes the PUBACK packet of the message.
+func pubackPacket(id uint16) packet {
+	return packet{typ: packetPuback, body: appendUint16(nil, id)}
+}
+
+// publish is a message in the PUBLISH packet.
+type publish struct {
+	topic   string
+	qos     byte
+	id      uint16
+	payload []byte
+}
+
+// decodePublish decodes the PUBLISH packet.
+func decodePublish(p packet) (publish, error) {
+	m := publish{qos: (p.flags >> 1) & 0x03}
+	topic, rest, err := readString(p.body)
+	if err != nil {
+		return m, err
+	}
+	m.topic = topic
+	if m.qos > 0 {
+		if len(rest) < 2 {
+			return m, errors.New("mqtt: malformed publish")
+		}
+		m.id = binary.BigEndian.Uint16(rest)
+		rest = rest[2:]
+	}
+	m.payload = rest
+	return m, nil
+}
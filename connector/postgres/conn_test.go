@@ -0,0 +1,314 @@
+package postgres
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// statement is a statement executed by the fake server.
+type statement struct {
+	query string
+	args  [][]byte
+}
+
+// fakeServer is a PostgreSQL server which records the statements.
+type fakeServer struct {
+	ln net.Listener
+	// auth is the authentication method, one of "trust", "password", "md5" and "scram".
+	auth     string
+	password string
+	// reject returns the SQLSTATE code of the error if the statement is rejected.
+	reject func(args [][]byte) string
+
+	mutex      sync.Mutex
+	statements []statement
+	// failures are the SQLSTATE codes of the next executions.
+	failures []string
+	execs    int
+}
+
+func newFakeServer(t *testing.T, auth, password string) *fakeServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	s := &fakeServer{ln: ln, auth: auth, password: password}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.handle(conn)
+		}
+	}()
+	t.Cleanup(func() { ln.Close() })
+	return s
+}
+
+func (s *fakeServer) url(user, password string) string {
+	return "postgres://" + user + ":" + password + "@" + s.ln.Addr().String() + "/db"
+}
+
+func (s *fakeServer) executed() []statement {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]statement(nil), s.statements...)
+}
+
+// fail fails the next executions with the SQLSTATE codes.
+func (s *fakeServer) fail(codes ...string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.failures = append(s.failures, codes...)
+}
+
+func (s *fakeServer) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	body, err := readStartup(r)
+	if err != nil {
+		return
+	}
+	if binary.BigEndian.Uint32(body) == sslRequestCode {
+		conn.Write([]byte("N"))
+		if body, err = readStartup(r); err != nil {
+			return
+		}
+	}
+	params := strings.Split(string(body[4:]), "\x00")
+	user := params[1]
+
+	if !s.authenticate(conn, r, user) {
+		writeMessage(conn, 'E', errorFields("28P01", "password authentication failed"))
+		return
+	}
+	writeMessage(conn, 'R', int32Bytes(authOK))
+	writeMessage(conn, 'S', []byte("server_version\x0014\x00"))
+	writeMessage(conn, 'Z', []byte("I"))
+
+	var query string
+	var args [][]byte
+	for {
+		typ, body, err := readMessage(r)
+		if err != nil {
+			return
+		}
+		switch typ {
+		case 'P':
+			query = strings.Split(string(body[1:]), "\x00")[0]
+		case 'B':
+			args = parseBind(body)
+		case 'S':
+			s.respond(conn, query, args)
+		case 'X':
+			return
+		}
+	}
+}
+
+func (s *fakeServer) respond(conn net.Conn, query string, args [][]byte) {
+	s.mutex.Lock()
+	s.execs++
+	code := ""
+	if len(s.failures) > 0 {
+		code, s.failures = s.failures[0], s.failures[1:]
+	} else if s.reject != nil {
+		code = s.reject(args)
+	}
+	if code == "" {
+		s.statements = append(s.statements, statement{query: query, args: args})
+	}
+	s.mutex.Unlock()
+
+	if code != "" {
+		writeMessage(conn, 'E', errorFields(code, "rejected"))
+	} else {
+		writeMessage(conn, '1', nil)
+		writeMessage(conn, '2', nil)
+		writeMessage(conn, 'C', []byte("INSERT 0 1\x00"))
+	}
+	writeMessage(conn, 'Z', []byte("I"))
+}
+
+// authenticate authenticates the user by the method of server.
+func (s *fakeServer) authenticate(conn net.Conn, r *bufio.Reader, user string) bool {
+	switch s.auth {
+	case "password":
+		writeMessage(conn, 'R', int32Bytes(authCleartextPassword))
+		_, body, err := readMessage(r)
+		return err == nil && string(body) == s.password+"\x00"
+	case "md5":
+		salt := []byte{1, 2, 3, 4}
+		writeMessage(conn, 'R', append(int32Bytes(authMD5Password), salt...))
+		_, body, err := readMessage(r)
+		return err == nil && string(body) == md5Password(user, s.password, salt)+"\x00"
+	case "scram":
+		return s.authenticateSCRAM(conn, r)
+	}
+	return true
+}
+
+func (s *fakeServer) authenticateSCRAM(conn net.Conn, r *bufio.Reader) bool {
+	writeMessage(conn, 'R', append(int32Bytes(authSASL), "SCRAM-SHA-256\x00\x00"...))
+	_, body, err := readMessage(r)
+	if err != nil || !strings.HasPrefix(string(body), scramMechanism+"\x00") {
+		return false
+	}
+	clientFirstBare := strings.TrimPrefix(string(body[len(scramMechanism)+5:]), "n,,")
+
+	salt := []byte("salt")
+	serverFirst := "r=" + parseSCRAM(clientFirstBare)["r"] + "server,s=" + base64.StdEncoding.EncodeToString(salt) + ",i=4096"
+	writeMessage(conn, 'R', append(int32Bytes(authSASLContinue), serverFirst...))
+	_, body, err = readMessage(r)
+	if err != nil {
+		return false
+	}
+	clientFinal := string(body)
+	withoutProof := clientFinal[:strings.Index(clientFinal, ",p=")]
+	proof, _ := base64.StdEncoding.DecodeString(parseSCRAM(clientFinal)["p"])
+
+	authMessage := clientFirstBare + "," + serverFirst + "," + withoutProof
+	saltedPassword := pbkdf2.Key([]byte(s.password), salt, 4096, sha256.Size, sha256.New)
+	storedKey := sha256.Sum256(hmacSHA256(saltedPassword, "Client Key"))
+	clientKey := hmacSHA256(storedKey[:], authMessage)
+	if len(proof) != len(clientKey) {
+		return false
+	}
+	for i := range clientKey {
+		clientKey[i] ^= proof[i]
+	}
+	if sha256.Sum256(clientKey) != storedKey {
+		return false
+	}
+	serverSignature := hmacSHA256(hmacSHA256(saltedPassword, "Server Key"), authMessage)
+	writeMessage(conn, 'R', append(int32Bytes(authSASLFinal), "v="+base64.StdEncoding.EncodeToString(serverSignature)...))
+	return true
+}
+
+func readStartup(r io.Reader) ([]byte, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return nil, err
+	}
+	body := make([]byte, binary.BigEndian.Uint32(size[:])-4)
+	_, err := io.ReadFull(r, body)
+	return body, err
+}
+
+func readMessage(r io.Reader) (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	body := make([]byte, binary.BigEndian.Uint32(header[1:])-4)
+	_, err := io.ReadFull(r, body)
+	return header[0], body, err
+}
+
+func writeMessage(w io.Writer, typ byte, body []byte) {
+	msg := append([]byte{typ}, int32Bytes(int32(len(body)+4))...)
+	w.Write(append(msg, body...))
+}
+
+func int32Bytes(v int32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, uint32(v))
+	return b
+}
+
+func errorFields(code, msg string) []byte {
+	return []byte("SERROR\x00C" + code + "\x00M" + msg + "\x00\x00")
+}
+
+// parseBind parses the parameters of Bind, the names of portal and statement are empty.
+func parseBind(body []byte) [][]byte {
+	body = body[2:]
+	formats := int(binary.BigEndian.Uint16(body))
+	body = body[2+2*formats:]
+	n := int(binary.BigEndian.Uint16(body))
+	body = body[2:]
+	args := make([][]byte, n)
+	for i := range args {
+		size := int32(binary.BigEndian.Uint32(body))
+		body = body[4:]
+		if size < 0 {
+			continue
+		}
+		args[i] = append([]byte{}, body[:size]...)
+		body = body[size:]
+	}
+	return args
+}
+
+func TestDial(t *testing.T) {
+	for _, auth := range []string{"trust", "password", "md5", "scram"} {
+		t.Run(auth, func(t *testing.T) {
+			server := newFakeServer(t, auth, "secret")
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			conf, err := parseURL(server.url("yomo", "secret"))
+			assert.NoError(t, err)
+			c, err := dial(ctx, conf)
+			assert.NoError(t, err)
+			defer c.close()
+			assert.NoError(t, c.exec(ctx, "INSERT INTO t VALUES ($1, $2)", [][]byte{[]byte("a"), nil}))
+			assert.Equal(t, []statement{{query: "INSERT INTO t VALUES ($1, $2)", args: [][]byte{[]byte("a"), nil}}}, server.executed())
+
+			// the error of statement doesn't break the connection.
+			server.fail("23505")
+			err = c.exec(ctx, "INSERT INTO t VALUES ($1)", [][]byte{[]byte("a")})
+			assert.EqualError(t, err, "postgres: ERROR: rejected (SQLSTATE 23505)")
+			assert.NoError(t, c.exec(ctx, "INSERT INTO t VALUES ($1)", [][]byte{[]byte("b")}))
+
+			if auth == "trust" {
+				return
+			}
+			conf, err = parseURL(server.url("yomo", "wrong"))
+			assert.NoError(t, err)
+			_, err = dial(ctx, conf)
+			var pgErr *Error
+			if assert.ErrorAs(t, err, &pgErr) {
+				assert.Equal(t, "28P01", pgErr.Code)
+			}
+		})
+	}
+}
+
+func TestParseURL(t *testing.T) {
+	conf, err := parseURL("postgres://localhost")
+	assert.NoError(t, err)
+	assert.Equal(t, &dialConfig{addr: "localhost:5432", user: "postgres", database: "postgres", sslMode: "prefer", tlsConfig: conf.tlsConfig}, conf)
+	assert.True(t, conf.tlsConfig.InsecureSkipVerify)
+
+	conf, err = parseURL("postgresql://u:p%40ss@db:6432/telemetry?sslmode=verify-full")
+	assert.NoError(t, err)
+	assert.Equal(t, "db:6432", conf.addr)
+	assert.Equal(t, "p@ss", conf.password)
+	assert.Equal(t, "telemetry", conf.database)
+	assert.Equal(t, "db", conf.tlsConfig.ServerName)
+
+	for _, rawURL := range []string{"mysql://localhost", "postgres:///db", "postgres://localhost?sslmode=allow"} {
+		_, err := parseURL(rawURL)
+		assert.Error(t, err, rawURL)
+	}
+}
+
+func TestErrorTemporary(t *testing.T) {
+	for code, temporary := range map[string]bool{"08006": true, "40P01": true, "53300": true, "57P01": true, "23505": false, "22P02": false, "": false} {
+		assert.Equal(t, temporary, (&Error{Code: code}).temporary(), strconv.Quote(code))
+	}
+}
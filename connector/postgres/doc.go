@@ -0,0 +1,8 @@
+// Package postgres stores the results of stream functions in PostgreSQL, or TimescaleDB whose hypertables are written
+// as the plain tables. The Sink decodes the payloads by the codec registry of serde into the objects, maps their
+// fields to the columns of a table, and inserts the rows in batches, the rows are upserted on the conflict columns
+// if they're set.
+//
+// The handlers of a batch return after it's committed, and the failed batches are retried, so the delivery is at
+// least once. The protocol of PostgreSQL is implemented in this package, so no database driver is linked into YoMo.
+package postgres
@@ -0,0 +1,181 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSink(t *testing.T) {
+	server := newFakeServer(t, "scram", "secret")
+	sink, err := NewSink(SinkConfig{
+		URL:   server.url("yomo", "secret") + "?sslmode=disable",
+		Table: "public.noise",
+		Columns: map[string]string{
+			"time":   FieldTime,
+			"tid":    FieldTransactionID,
+			"tag":    FieldTag,
+			"device": "device",
+			"lat":    "location.lat",
+			"meta":   "meta",
+		},
+		BatchSize:     2,
+		FlushInterval: time.Minute,
+	})
+	assert.NoError(t, err)
+	defer sink.Close()
+
+	// the rows of concurrent handlers are inserted by a statement when the batch is full.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var wg sync.WaitGroup
+	for _, data := range []string{`{"device":"d-1","location":{"lat":30.5},"meta":{"fw":"1.0"}}`, `{"device":"d-2"}`} {
+		wg.Add(1)
+		go func(data string) {
+			defer wg.Done()
+			res, err := sink.Handler(0x33)(ctx, []byte(data))
+			assert.NoError(t, err)
+			assert.Nil(t, res)
+		}(data)
+	}
+	wg.Wait()
+
+	statements := server.executed()
+	assert.Len(t, statements, 1)
+	assert.Equal(t, `INSERT INTO "public"."noise" ("device", "lat", "meta", "tag", "tid", "time") VALUES ($1, $2, $3, $4, $5, $6), ($7, $8, $9, $10, $11, $12)`, statements[0].query)
+	args := statements[0].args
+	assert.Len(t, args, 12)
+	if bytes.Equal(args[0], []byte("d-2")) {
+		args = append(args[6:], args[:6]...)
+	}
+	assert.Equal(t, [][]byte{[]byte("d-1"), []byte("30.5"), []byte(`{"fw":"1.0"}`), []byte("51"), []byte("")}, args[:5])
+	_, err = time.Parse(time.RFC3339Nano, string(args[5]))
+	assert.NoError(t, err)
+	// the missing fields are NULL.
+	assert.Equal(t, [][]byte{[]byte("d-2"), nil, nil}, args[6:9])
+
+	// the data which isn't an object is rejected before it's batched.
+	assert.Error(t, sink.Publish(ctx, 0x33, "tid", []byte("noise")))
+}
+
+func TestSinkUpsert(t *testing.T) {
+	server := newFakeServer(t, "trust", "")
+	sink, err := NewSink(SinkConfig{
+		URL:             server.url("yomo", "") + "?sslmode=disable",
+		Table:           "devices",
+		Columns:         map[string]string{"id": "id", "state": "state"},
+		ConflictColumns: []string{"id"},
+		FlushInterval:   10 * time.Millisecond,
+		PassThrough:     true,
+	})
+	assert.NoError(t, err)
+	defer sink.Close()
+
+	// the batch is written when its flush interval elapsed.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	res, err := sink.Handler(0x33)(ctx, []byte(`{"id":"d-1","state":"on"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":"d-1","state":"on"}`, string(res))
+	assert.Equal(t, []statement{{
+		query: `INSERT INTO "devices" ("id", "state") VALUES ($1, $2) ON CONFLICT ("id") DO UPDATE SET "state" = EXCLUDED."state"`,
+		args:  [][]byte{[]byte("d-1"), []byte("on")},
+	}}, server.executed())
+
+	// the rows with the same conflict values are deduplicated in a batch, the last one wins.
+	rows := []*row{
+		{values: [][]byte{[]byte("d-1"), []byte("on")}},
+		{values: [][]byte{[]byte("d-2"), []byte("on")}},
+		{values: [][]byte{[]byte("d-1"), []byte("off")}},
+		{values: [][]byte{nil, []byte("on")}},
+		{values: [][]byte{nil, []byte("off")}},
+	}
+	assert.Equal(t, [][]*row{{rows[0], rows[2]}, {rows[1]}, {rows[3]}, {rows[4]}}, sink.dedupe(rows))
+
+	// the rows are kept if all columns are the conflict columns.
+	sink, err = NewSink(SinkConfig{URL: server.url("yomo", ""), Table: "t", Columns: map[string]string{"id": "id"}, ConflictColumns: []string{"id"}})
+	assert.NoError(t, err)
+	defer sink.Close()
+	assert.Equal(t, ` ON CONFLICT ("id") DO NOTHING`, sink.suffix)
+}
+
+func TestSinkRetry(t *testing.T) {
+	server := newFakeServer(t, "md5", "secret")
+	server.reject = func(args [][]byte) string {
+		for _, arg := range args {
+			if string(arg) == "bad" {
+				return "22P02"
+			}
+		}
+		return ""
+	}
+	sink, err := NewSink(SinkConfig{
+		URL:           server.url("yomo", "secret") + "?sslmode=disable",
+		Table:         "noise",
+		Columns:       map[string]string{"noise": "noise"},
+		BatchSize:     2,
+		FlushInterval: time.Minute,
+	})
+	assert.NoError(t, err)
+	defer sink.Close()
+
+	// the temporary errors are retried.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	server.fail("40P01")
+	var wg sync.WaitGroup
+	for _, data := range []string{`{"noise":1}`, `{"noise":2}`} {
+		wg.Add(1)
+		go func(data string) {
+			defer wg.Done()
+			assert.NoError(t, sink.Publish(ctx, 0x33, "tid", []byte(data)))
+		}(data)
+	}
+	wg.Wait()
+	assert.Len(t, server.executed(), 1)
+
+	// the rejected batch is inserted row by row, only the rejected row fails.
+	errs := make(map[string]error)
+	var mutex sync.Mutex
+	for _, data := range []string{`{"noise":3}`, `{"noise":"bad"}`} {
+		wg.Add(1)
+		go func(data string) {
+			defer wg.Done()
+			err := sink.Publish(ctx, 0x33, "tid", []byte(data))
+			mutex.Lock()
+			errs[data] = err
+			mutex.Unlock()
+		}(data)
+	}
+	wg.Wait()
+	assert.NoError(t, errs[`{"noise":3}`])
+	var pgErr *Error
+	if assert.ErrorAs(t, errs[`{"noise":"bad"}`], &pgErr) {
+		assert.Equal(t, "22P02", pgErr.Code)
+	}
+	statements := server.executed()
+	assert.Len(t, statements, 2)
+	assert.Equal(t, [][]byte{[]byte("3")}, statements[1].args)
+
+	// the data is rejected after the sink is closed.
+	assert.NoError(t, sink.Close())
+	assert.ErrorIs(t, sink.Publish(ctx, 0x33, "tid", []byte(`{"noise":4}`)), errClosed)
+}
+
+func TestNewSink(t *testing.T) {
+	columns := map[string]string{"id": "id"}
+	for _, conf := range []SinkConfig{
+		{},
+		{URL: "postgres://localhost", Columns: columns},
+		{URL: "postgres://localhost", Table: "t"},
+		{URL: "postgres://localhost", Table: "t", Columns: columns, ConflictColumns: []string{"unknown"}},
+		{URL: "postgres://localhost", Table: "t", Columns: columns, BatchSize: 65536},
+	} {
+		_, err := NewSink(conf)
+		assert.Error(t, err)
+	}
+}
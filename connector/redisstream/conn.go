@@ -0,0 +1,50 @@
+package redisstream
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// the fields of the stream entries.
+const (
+	// DefaultField is the default field of the data.
+	DefaultField = "data"
+	// FieldTransactionID is the field of the transaction ID.
+	FieldTransactionID = "tid"
+	// FieldTag is the field of the data tag in decimal.
+	FieldTag = "tag"
+)
+
+// newClient creates the client of Redis by the URL, e.g. "redis://:password@localhost:6379/0", or
+// "rediss://" for TLS.
+func newClient(url string) (*redis.Client, error) {
+	if url == "" {
+		return nil, errors.New("redisstream: the URL is required")
+	}
+	opt, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("redisstream: %v", err)
+	}
+	return redis.NewClient(opt), nil
+}
+
+// temporaryPrefixes are the prefixes of the server errors which succeed later, e.g. while the replica is promoted.
+var temporaryPrefixes = []string{"LOADING ", "READONLY ", "MASTERDOWN ", "TRYAGAIN ", "CLUSTERDOWN ", "BUSY "}
+
+// temporary reports whether err may succeed if it's retried, the network errors are temporary, and the other
+// server errors are permanent, e.g. the key holds a value of the wrong type.
+func temporary(err error) bool {
+	var e redis.Error
+	if !errors.As(err, &e) || e.Error() == "ERR max number of clients reached" {
+		return true
+	}
+	for _, prefix := range temporaryPrefixes {
+		if strings.HasPrefix(e.Error(), prefix) {
+			return true
+		}
+	}
+	return false
+}
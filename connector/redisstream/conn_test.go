@@ -0,0 +1,386 @@
+package redisstream
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+// entry is an entry of the fake streams, its fields are nil if it's deleted.
+type entry struct {
+	seq    int64
+	fields []string
+}
+
+// pendingEntry is an entry which is delivered to a consumer but not acknowledged.
+type pendingEntry struct {
+	consumer  string
+	delivered time.Time
+	count     int64
+}
+
+// group is a consumer group of the fake streams.
+type group struct {
+	last    int64
+	pending map[int64]*pendingEntry
+}
+
+// stream is a fake stream.
+type stream struct {
+	entries []*entry
+	groups  map[string]*group
+}
+
+// fakeServer is a Redis server which implements the commands of streams used by the connectors.
+type fakeServer struct {
+	ln net.Listener
+
+	mutex   sync.Mutex
+	seq     int64
+	streams map[string]*stream
+	// failures are the errors of the next commands by their names.
+	failures map[string][]string
+	calls    map[string]int
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	s := &fakeServer{ln: ln, streams: make(map[string]*stream), failures: make(map[string][]string), calls: make(map[string]int)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.handle(conn)
+		}
+	}()
+	t.Cleanup(func() { ln.Close() })
+	return s
+}
+
+func (s *fakeServer) url() string {
+	return "redis://" + s.ln.Addr().String() + "/0"
+}
+
+// fail fails the next commands of the name with the errors.
+func (s *fakeServer) fail(cmd string, errs ...string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.failures[cmd] = append(s.failures[cmd], errs...)
+}
+
+func (s *fakeServer) called(cmd string) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.calls[cmd]
+}
+
+// add appends an entry to the stream.
+func (s *fakeServer) add(key string, fields ...string) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.seq++
+	st := s.stream(key)
+	st.entries = append(st.entries, &entry{seq: s.seq, fields: fields})
+	return formatID(s.seq)
+}
+
+// entries returns the fields of the entries of stream.
+func (s *fakeServer) entries(key string) [][]string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var entries [][]string
+	for _, e := range s.stream(key).entries {
+		entries = append(entries, e.fields)
+	}
+	return entries
+}
+
+// pending returns the consumers of the pending entries of the group by their IDs.
+func (s *fakeServer) pending(key, name string) map[string]string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	consumers := make(map[string]string)
+	if g, ok := s.stream(key).groups[name]; ok {
+		for seq, p := range g.pending {
+			consumers[formatID(seq)] = p.consumer
+		}
+	}
+	return consumers
+}
+
+// deliver delivers the entry to the consumer of the group as if it's read before the idle time.
+func (s *fakeServer) deliver(key, name, id, consumer string, idle time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	g := s.stream(key).groups[name]
+	seq, _ := parseID(id)
+	g.pending[seq] = &pendingEntry{consumer: consumer, delivered: time.Now().Add(-idle), count: 1}
+	if seq > g.last {
+		g.last = seq
+	}
+}
+
+// delete deletes the entry of stream, it's still pending.
+func (s *fakeServer) delete(key, id string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	seq, _ := parseID(id)
+	for _, e := range s.stream(key).entries {
+		if e.seq == seq {
+			e.fields = nil
+		}
+	}
+}
+
+func (s *fakeServer) stream(key string) *stream {
+	st, ok := s.streams[key]
+	if !ok {
+		st = &stream{groups: make(map[string]*group)}
+		s.streams[key] = st
+	}
+	return st
+}
+
+func formatID(seq int64) string {
+	return strconv.FormatInt(seq, 10) + "-0"
+}
+
+func parseID(id string) (int64, error) {
+	return strconv.ParseInt(strings.TrimSuffix(id, "-0"), 10, 64)
+}
+
+func (s *fakeServer) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		s.exec(w, args)
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// readCommand reads a command of RESP, i.e. an array of bulk strings.
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return nil, errors.New("not an array")
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func writeEntries(w *bufio.Writer, entries []*entry) {
+	fmt.Fprintf(w, "*%d\r\n", len(entries))
+	for _, e := range entries {
+		fmt.Fprintf(w, "*2\r\n$%d\r\n%s\r\n", len(formatID(e.seq)), formatID(e.seq))
+		if e.fields == nil {
+			w.WriteString("*-1\r\n")
+			continue
+		}
+		fmt.Fprintf(w, "*%d\r\n", len(e.fields))
+		for _, f := range e.fields {
+			fmt.Fprintf(w, "$%d\r\n%s\r\n", len(f), f)
+		}
+	}
+}
+
+func (s *fakeServer) exec(w *bufio.Writer, args []string) {
+	cmd := strings.ToUpper(args[0])
+	s.mutex.Lock()
+	s.calls[cmd]++
+	if errs := s.failures[cmd]; len(errs) > 0 {
+		s.failures[cmd] = errs[1:]
+		s.mutex.Unlock()
+		fmt.Fprintf(w, "-%s\r\n", errs[0])
+		return
+	}
+
+	switch cmd {
+	case "XADD":
+		// XADD key [MAXLEN ~ count] * field value ...
+		st := s.stream(args[1])
+		i, maxLen := 2, 0
+		if strings.ToUpper(args[i]) == "MAXLEN" {
+			maxLen, _ = strconv.Atoi(args[i+2])
+			i += 3
+		}
+		s.seq++
+		st.entries = append(st.entries, &entry{seq: s.seq, fields: args[i+1:]})
+		if maxLen > 0 && len(st.entries) > maxLen {
+			st.entries = st.entries[len(st.entries)-maxLen:]
+		}
+		id := formatID(s.seq)
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(id), id)
+
+	case "XGROUP":
+		// XGROUP CREATE key group id MKSTREAM
+		st := s.stream(args[2])
+		if _, ok := st.groups[args[3]]; ok {
+			w.WriteString("-BUSYGROUP Consumer Group name already exists\r\n")
+			break
+		}
+		g := &group{pending: make(map[int64]*pendingEntry)}
+		if args[4] == "$" {
+			g.last = s.seq
+		}
+		st.groups[args[3]] = g
+		w.WriteString("+OK\r\n")
+
+	case "XREADGROUP":
+		// XREADGROUP GROUP group consumer COUNT count BLOCK ms STREAMS key ... id ...
+		name, consumer := args[2], args[3]
+		count, _ := strconv.Atoi(args[5])
+		block, _ := strconv.Atoi(args[7])
+		keys := args[9 : 9+(len(args)-9)/2]
+		ids := args[9+len(keys):]
+		deadline := time.Now().Add(time.Duration(block) * time.Millisecond)
+		for {
+			var n int
+			result := make(map[string][]*entry)
+			for i, key := range keys {
+				g := s.stream(key).groups[name]
+				for _, e := range s.stream(key).entries {
+					if len(result[key]) == count {
+						break
+					}
+					if ids[i] == ">" && e.seq > g.last {
+						g.last = e.seq
+						g.pending[e.seq] = &pendingEntry{consumer: consumer, delivered: time.Now(), count: 1}
+						result[key] = append(result[key], e)
+					} else if p, ok := g.pending[e.seq]; ids[i] != ">" && ok && p.consumer == consumer {
+						result[key] = append(result[key], e)
+					}
+				}
+				n += len(result[key])
+			}
+			if n == 0 && ids[0] == ">" {
+				if time.Now().Before(deadline) {
+					s.mutex.Unlock()
+					time.Sleep(5 * time.Millisecond)
+					s.mutex.Lock()
+					continue
+				}
+				w.WriteString("*-1\r\n")
+				break
+			}
+			fmt.Fprintf(w, "*%d\r\n", len(keys))
+			for _, key := range keys {
+				fmt.Fprintf(w, "*2\r\n$%d\r\n%s\r\n", len(key), key)
+				writeEntries(w, result[key])
+			}
+			break
+		}
+
+	case "XACK":
+		// XACK key group id ...
+		g := s.stream(args[1]).groups[args[2]]
+		n := 0
+		for _, id := range args[3:] {
+			seq, _ := parseID(id)
+			if _, ok := g.pending[seq]; ok {
+				delete(g.pending, seq)
+				n++
+			}
+		}
+		fmt.Fprintf(w, ":%d\r\n", n)
+
+	case "XPENDING":
+		// XPENDING key group - + count
+		g := s.stream(args[1]).groups[args[2]]
+		var seqs []int64
+		for seq := range g.pending {
+			seqs = append(seqs, seq)
+		}
+		fmt.Fprintf(w, "*%d\r\n", len(seqs))
+		for _, seq := range seqs {
+			p := g.pending[seq]
+			id := formatID(seq)
+			fmt.Fprintf(w, "*4\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n:%d\r\n:%d\r\n", len(id), id, len(p.consumer), p.consumer,
+				time.Since(p.delivered).Milliseconds(), p.count)
+		}
+
+	case "XCLAIM":
+		// XCLAIM key group consumer min-idle id ...
+		st := s.stream(args[1])
+		g := st.groups[args[2]]
+		minIdle, _ := strconv.Atoi(args[4])
+		var claimed []*entry
+		for _, id := range args[5:] {
+			seq, _ := parseID(id)
+			p, ok := g.pending[seq]
+			if !ok || time.Since(p.delivered) < time.Duration(minIdle)*time.Millisecond {
+				continue
+			}
+			p.consumer, p.delivered = args[3], time.Now()
+			p.count++
+			for _, e := range st.entries {
+				if e.seq == seq {
+					claimed = append(claimed, e)
+				}
+			}
+		}
+		writeEntries(w, claimed)
+
+	default:
+		fmt.Fprintf(w, "-ERR unknown command '%s'\r\n", args[0])
+	}
+	s.mutex.Unlock()
+}
+
+func TestTemporary(t *testing.T) {
+	s := newFakeServer(t)
+	s.fail("PING", "MASTERDOWN Link with MASTER is down", "WRONGTYPE Operation against a key holding the wrong kind of value")
+	client := redis.NewClient(&redis.Options{Addr: s.ln.Addr().String(), MaxRetries: -1})
+	defer client.Close()
+
+	assert.True(t, temporary(client.Ping(context.Background()).Err()))
+	assert.False(t, temporary(client.Ping(context.Background()).Err()))
+	assert.True(t, temporary(errors.New("dial tcp: connection refused")))
+
+	_, err := newClient("")
+	assert.Error(t, err)
+	_, err = newClient("http://localhost")
+	assert.Error(t, err)
+}
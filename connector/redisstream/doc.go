@@ -0,0 +1,13 @@
+// Package redisstream bridges YoMo to Redis Streams, it's the durable handoff between YoMo and the other services
+// for the small deployments which already run Redis.
+//
+// The Sink appends the data of a stream function to the streams mapped from its data tags, the entry carries the
+// data, the transaction ID and the data tag in the fields "data", "tid" and "tag". It's acknowledged after Redis
+// appended it, and the failed appends are retried, so the delivery is at least once.
+//
+// The Source ingests the entries of streams into YoMo-Zipper by a consumer group, with the data tags mapped from the
+// streams and the transaction IDs of the field "tid". The entries are acknowledged after they're written to
+// YoMo-Zipper, the pending entries of a consumer are delivered again when it restarts, and the ones of the consumers
+// which are gone are claimed by the others in the group, so the delivery is at least once. It requires Redis 5.0 or
+// later.
+package redisstream
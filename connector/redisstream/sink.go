@@ -0,0 +1,97 @@
+package redisstream
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/go-redis/redis/v8"
+	"github.com/yomorun/yomo/connector"
+	"github.com/yomorun/yomo/streamfunction"
+)
+
+// SinkConfig represents the config of Redis Streams sink.
+type SinkConfig struct {
+	// URL is the URL of Redis, e.g. "redis://:password@localhost:6379/0".
+	URL string `yaml:"url"`
+	// Stream is the stream which the data is appended to if its tag isn't in Streams.
+	Stream string `yaml:"stream,omitempty"`
+	// Streams maps the data tags to the streams.
+	Streams map[byte]string `yaml:"streams,omitempty"`
+	// Field is the field of the data in the entries, the default is "data".
+	Field string `yaml:"field,omitempty"`
+	// MaxLen trims the streams to about the count of entries when the data is appended, the streams aren't
+	// trimmed if it's zero.
+	MaxLen int64 `yaml:"max_len,omitempty"`
+	// MaxRetries is the count of retries when the append fails, the default is 5,
+	// it's retried until the handler is cancelled if it's negative.
+	MaxRetries int `yaml:"max_retries,omitempty"`
+	// PassThrough responds the data after it's appended, so the next stream functions receive it too.
+	PassThrough bool `yaml:"pass_through,omitempty"`
+}
+
+// Sink appends the data of stream function to Redis Streams.
+type Sink struct {
+	conf   SinkConfig
+	client *redis.Client
+}
+
+// NewSink creates a Redis Streams sink.
+func NewSink(conf SinkConfig) (*Sink, error) {
+	client, err := newClient(conf.URL)
+	if err != nil {
+		return nil, err
+	}
+	if conf.Field == "" {
+		conf.Field = DefaultField
+	}
+	if conf.MaxRetries == 0 {
+		conf.MaxRetries = connector.DefaultMaxRetries
+	}
+	return &Sink{conf: conf, client: client}, nil
+}
+
+// Publish appends the data with the tag to its stream, it returns after Redis appended the entry.
+func (s *Sink) Publish(ctx context.Context, tag byte, tid string, data []byte) error {
+	stream, ok := s.conf.Streams[tag]
+	if !ok {
+		stream = s.conf.Stream
+	}
+	if stream == "" {
+		return fmt.Errorf("redisstream: no stream for the tag %#x", tag)
+	}
+
+	args := &redis.XAddArgs{
+		Stream: stream,
+		MaxLen: s.conf.MaxLen,
+		Approx: true,
+		Values: []interface{}{s.conf.Field, data, FieldTransactionID, tid, FieldTag, strconv.Itoa(int(tag))},
+	}
+	return connector.Retry(ctx, s.conf.MaxRetries, func() error {
+		err := s.client.XAdd(ctx, args).Err()
+		if err != nil && !temporary(err) {
+			return backoff.Permanent(fmt.Errorf("redisstream: append to %s failed: %w", stream, err))
+		}
+		return err
+	})
+}
+
+// Handler returns the handler of stream function which appends the data observed by the tag, e.g.
+// sfn.PipeFunc(0x33, 0x34, sink.Handler(0x33)).
+func (s *Sink) Handler(tag byte) streamfunction.Handler {
+	return func(ctx context.Context, payload []byte) ([]byte, error) {
+		if err := s.Publish(ctx, tag, streamfunction.TransactionID(ctx), payload); err != nil {
+			return nil, err
+		}
+		if s.conf.PassThrough {
+			return payload, nil
+		}
+		return nil, nil
+	}
+}
+
+// Close closes the connections to Redis.
+func (s *Sink) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,57 @@
+package redisstream
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSink(t *testing.T) {
+	server := newFakeServer(t)
+	sink, err := NewSink(SinkConfig{
+		URL:         server.url(),
+		Stream:      "others",
+		Streams:     map[byte]string{0x33: "noise"},
+		MaxLen:      2,
+		PassThrough: true,
+	})
+	assert.NoError(t, err)
+	defer sink.Close()
+
+	res, err := sink.Handler(0x33)(context.Background(), []byte("data-1"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("data-1"), res)
+	assert.NoError(t, sink.Publish(context.Background(), 0x33, "tid-2", []byte("data-2")))
+	// the stream is trimmed.
+	assert.NoError(t, sink.Publish(context.Background(), 0x33, "tid-3", []byte("data-3")))
+	assert.NoError(t, sink.Publish(context.Background(), 0x34, "tid-4", []byte("data-4")))
+
+	assert.Equal(t, [][]string{
+		{"data", "data-2", "tid", "tid-2", "tag", "51"},
+		{"data", "data-3", "tid", "tid-3", "tag", "51"},
+	}, server.entries("noise"))
+	assert.Equal(t, [][]string{{"data", "data-4", "tid", "tid-4", "tag", "52"}}, server.entries("others"))
+}
+
+func TestSinkRetry(t *testing.T) {
+	server := newFakeServer(t)
+	sink, err := NewSink(SinkConfig{URL: server.url(), Streams: map[byte]string{0x33: "noise"}, Field: "payload"})
+	assert.NoError(t, err)
+	defer sink.Close()
+
+	// the temporary errors are retried.
+	server.fail("XADD", "MASTERDOWN Link with MASTER is down")
+	assert.NoError(t, sink.Publish(context.Background(), 0x33, "tid-1", []byte("data-1")))
+	assert.Equal(t, 2, server.called("XADD"))
+	assert.Equal(t, [][]string{{"payload", "data-1", "tid", "tid-1", "tag", "51"}}, server.entries("noise"))
+
+	// the other errors are not retried.
+	server.fail("XADD", "WRONGTYPE Operation against a key holding the wrong kind of value")
+	err = sink.Publish(context.Background(), 0x33, "tid-2", []byte("data-2"))
+	assert.EqualError(t, err, "redisstream: append to noise failed: WRONGTYPE Operation against a key holding the wrong kind of value")
+	assert.Equal(t, 3, server.called("XADD"))
+
+	// the data of the tags which aren't mapped is dropped.
+	assert.Error(t, sink.Publish(context.Background(), 0x34, "tid-3", []byte("data-3")))
+}
@@ -0,0 +1,285 @@
+package redisstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/yomorun/yomo/connector"
+	"github.com/yomorun/yomo/logger"
+)
+
+const (
+	// DefaultCount is the default max count of entries in each read.
+	DefaultCount = 100
+	// DefaultBlock is the default time to wait for the entries in each read.
+	DefaultBlock = time.Second
+	// DefaultClaimIdle is the default idle time after which the pending entries of the other consumers are claimed.
+	DefaultClaimIdle = time.Minute
+)
+
+// the positions where the consumer group starts when it's created.
+const (
+	StartEarliest = "earliest"
+	StartLatest   = "latest"
+)
+
+// SourceConfig represents the config of Redis Streams source.
+type SourceConfig struct {
+	// URL is the URL of Redis, e.g. "redis://:password@localhost:6379/0".
+	URL string `yaml:"url"`
+	// Group is the consumer group, the entries are shared by the sources in the same group.
+	Group string `yaml:"group"`
+	// Consumer is the name of consumer in the group, the default is generated by the host name. The pending entries
+	// of the consumer are delivered again when the source restarts with the same name.
+	Consumer string `yaml:"consumer,omitempty"`
+	// Streams maps the streams to the data tags which their entries are written with.
+	Streams map[string]byte `yaml:"streams"`
+	// Start is where the consumer group starts when it's created, "earliest" or "latest", the default is "earliest".
+	Start string `yaml:"start,omitempty"`
+	// Field is the field of the data in the entries, the default is "data".
+	Field string `yaml:"field,omitempty"`
+	// Count is the max count of entries in each read, the default is 100.
+	Count int64 `yaml:"count,omitempty"`
+	// Block is the time to wait for the entries in each read, the default is 1s.
+	Block time.Duration `yaml:"block,omitempty"`
+	// ClaimIdle is the idle time after which the pending entries of the other consumers are claimed, the default
+	// is 1m.
+	ClaimIdle time.Duration `yaml:"claim_idle,omitempty"`
+}
+
+// Source ingests the entries of Redis Streams into YoMo-Zipper.
+type Source struct {
+	conf   SourceConfig
+	client *redis.Client
+}
+
+// NewSource creates a Redis Streams source.
+func NewSource(conf SourceConfig) (*Source, error) {
+	if conf.Group == "" {
+		return nil, errors.New("redisstream: the consumer group is required")
+	}
+	if len(conf.Streams) == 0 {
+		return nil, errors.New("redisstream: no stream to consume")
+	}
+	switch conf.Start {
+	case "":
+		conf.Start = StartEarliest
+	case StartEarliest, StartLatest:
+	default:
+		return nil, fmt.Errorf("redisstream: unknown start %q", conf.Start)
+	}
+	client, err := newClient(conf.URL)
+	if err != nil {
+		return nil, err
+	}
+	if conf.Consumer == "" {
+		host, _ := os.Hostname()
+		conf.Consumer = fmt.Sprintf("yomo-%s-%d", host, time.Now().UnixNano())
+	}
+	if conf.Field == "" {
+		conf.Field = DefaultField
+	}
+	if conf.Count <= 0 {
+		conf.Count = DefaultCount
+	}
+	if conf.Block <= 0 {
+		conf.Block = DefaultBlock
+	}
+	if conf.ClaimIdle <= 0 {
+		conf.ClaimIdle = DefaultClaimIdle
+	}
+	return &Source{conf: conf, client: client}, nil
+}
+
+// Run writes the entries of streams to w until ctx is done, e.g. the YoMo-Source client. The entries are
+// acknowledged after they're written, the failed writes are retried, so the entries are delivered at least once.
+// It returns nil when ctx is done, or the error if the consumer group can't be created.
+func (s *Source) Run(ctx context.Context, w connector.Writer) error {
+	defer s.client.Close()
+	if err := s.createGroups(ctx); err != nil {
+		return err
+	}
+
+	logger.Printf("✅ Consuming the Redis streams %v in group %s", s.streams(), s.conf.Group)
+	// the pending entries of the consumer are read first, then the new entries, and the ones of the other consumers
+	// are claimed at once and every ClaimIdle.
+	id := "0"
+	var claimed time.Time
+	for ctx.Err() == nil {
+		if time.Since(claimed) >= s.conf.ClaimIdle {
+			if !s.claim(ctx, w) {
+				break
+			}
+			claimed = time.Now()
+		}
+
+		streams, err := s.read(ctx, id)
+		if err != nil {
+			break
+		}
+		n := 0
+		for _, stream := range streams {
+			n += len(stream.Messages)
+			if !s.deliver(ctx, w, stream.Stream, stream.Messages) {
+				return nil
+			}
+		}
+		if n == 0 {
+			id = ">"
+		}
+	}
+	return nil
+}
+
+// createGroups creates the consumer group of streams if it doesn't exist, the streams are created too.
+func (s *Source) createGroups(ctx context.Context) error {
+	start := "0"
+	if s.conf.Start == StartLatest {
+		start = "$"
+	}
+	for _, stream := range s.streams() {
+		err := connector.Retry(ctx, connector.DefaultMaxRetries, func() error {
+			err := s.client.XGroupCreateMkStream(ctx, stream, s.conf.Group, start).Err()
+			if err != nil && strings.HasPrefix(err.Error(), "BUSYGROUP ") {
+				return nil
+			}
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("redisstream: create the consumer group of %s failed: %w", stream, err)
+		}
+	}
+	return nil
+}
+
+// read reads the entries of streams after the id, it returns the error only if ctx is done.
+func (s *Source) read(ctx context.Context, id string) ([]redis.XStream, error) {
+	streams := s.streams()
+	for range s.conf.Streams {
+		streams = append(streams, id)
+	}
+	var res []redis.XStream
+	err := connector.Retry(ctx, -1, func() error {
+		var err error
+		res, err = s.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    s.conf.Group,
+			Consumer: s.conf.Consumer,
+			Streams:  streams,
+			Count:    s.conf.Count,
+			Block:    s.conf.Block,
+		}).Result()
+		if err == redis.Nil {
+			res, err = nil, nil
+		}
+		if err != nil && ctx.Err() == nil {
+			logger.Error("[redisstream] read the streams failed, will retry.", "group", s.conf.Group, "err", err)
+		}
+		return err
+	})
+	return res, err
+}
+
+// claim claims the pending entries of the other consumers which are idle longer than ClaimIdle and delivers them,
+// e.g. the consumers which are gone. It returns false if ctx is done.
+func (s *Source) claim(ctx context.Context, w connector.Writer) bool {
+	for _, stream := range s.streams() {
+		pending, err := s.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+			Stream: stream,
+			Group:  s.conf.Group,
+			Start:  "-",
+			End:    "+",
+			Count:  s.conf.Count,
+		}).Result()
+		if err != nil {
+			if ctx.Err() != nil {
+				return false
+			}
+			// they're claimed next time.
+			logger.Error("[redisstream] list the pending entries failed.", "stream", stream, "err", err)
+			continue
+		}
+		var ids []string
+		for _, p := range pending {
+			if p.Consumer != s.conf.Consumer && p.Idle >= s.conf.ClaimIdle {
+				ids = append(ids, p.ID)
+			}
+		}
+		if len(ids) == 0 {
+			continue
+		}
+
+		messages, err := s.client.XClaim(ctx, &redis.XClaimArgs{
+			Stream:   stream,
+			Group:    s.conf.Group,
+			Consumer: s.conf.Consumer,
+			MinIdle:  s.conf.ClaimIdle,
+			Messages: ids,
+		}).Result()
+		if err != nil {
+			if ctx.Err() != nil {
+				return false
+			}
+			logger.Error("[redisstream] claim the pending entries failed.", "stream", stream, "err", err)
+			continue
+		}
+		logger.Info("[redisstream] claimed the pending entries.", "stream", stream, "count", len(messages))
+		if !s.deliver(ctx, w, stream, messages) {
+			return false
+		}
+	}
+	return true
+}
+
+// deliver writes the entries of stream to w and acknowledges them, it returns false if ctx is done.
+func (s *Source) deliver(ctx context.Context, w connector.Writer, stream string, messages []redis.XMessage) bool {
+	if len(messages) == 0 {
+		return true
+	}
+	tag := s.conf.Streams[stream]
+	ids := make([]string, 0, len(messages))
+	for _, m := range messages {
+		ids = append(ids, m.ID)
+		data, ok := m.Values[s.conf.Field].(string)
+		if !ok {
+			// the entries which are deleted from the stream have no field.
+			logger.Error("[redisstream] the entry has no data, it's skipped.", "stream", stream, "id", m.ID, "field", s.conf.Field)
+			continue
+		}
+		tid, _ := m.Values[FieldTransactionID].(string)
+		err := connector.Retry(ctx, -1, func() error {
+			_, err := connector.WriteWithTransaction(w, tid, []byte(data), tag)
+			if err != nil && ctx.Err() == nil {
+				logger.Error("[redisstream] write the entry to YoMo-Zipper failed, will retry.", "stream", stream, "id", m.ID, "err", err)
+			}
+			return err
+		})
+		if err != nil {
+			// the entries which are not acknowledged are delivered again.
+			return false
+		}
+	}
+
+	err := connector.Retry(ctx, -1, func() error {
+		err := s.client.XAck(ctx, stream, s.conf.Group, ids...).Err()
+		if err != nil && ctx.Err() == nil {
+			logger.Error("[redisstream] acknowledge the entries failed, will retry.", "stream", stream, "err", err)
+		}
+		return err
+	})
+	return err == nil
+}
+
+func (s *Source) streams() []string {
+	streams := make([]string, 0, len(s.conf.Streams))
+	for stream := range s.conf.Streams {
+		streams = append(streams, stream)
+	}
+	sort.Strings(streams)
+	return streams
+}
@@ -0,0 +1,140 @@
+package redisstream
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeWriter is the YoMo-Source client which fails the first writes.
+type fakeWriter struct {
+	mutex    sync.Mutex
+	failures int
+	data     []string
+	tids     []string
+	tags     []byte
+}
+
+func (w *fakeWriter) WriteWithTags(data []byte, tags ...byte) (int, error) {
+	return w.WriteWithTransaction("", data, tags...)
+}
+
+func (w *fakeWriter) WriteWithTransaction(tid string, data []byte, tags ...byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.failures > 0 {
+		w.failures--
+		return 0, errors.New("disconnected")
+	}
+	w.data = append(w.data, string(data))
+	w.tids = append(w.tids, tid)
+	w.tags = append(w.tags, tags...)
+	return len(data), nil
+}
+
+func (w *fakeWriter) written() []string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return append([]string(nil), w.data...)
+}
+
+func TestSource(t *testing.T) {
+	server := newFakeServer(t)
+	server.add("noise", "data", "data-1", "tid", "tid-1")
+	// the entry without data is skipped.
+	server.add("noise", "other", "x")
+	server.add("sound", "data", "data-2")
+
+	source, err := NewSource(SourceConfig{
+		URL:      server.url(),
+		Group:    "yomo",
+		Consumer: "source-1",
+		Streams:  map[string]byte{"noise": 0x33, "sound": 0x34},
+		Block:    10 * time.Millisecond,
+	})
+	assert.NoError(t, err)
+	w := &fakeWriter{failures: 1}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- source.Run(ctx, w)
+	}()
+
+	assert.Eventually(t, func() bool { return len(w.written()) == 2 }, 5*time.Second, 10*time.Millisecond)
+	server.add("noise", "data", "data-3", "tid", "tid-3")
+	assert.Eventually(t, func() bool { return len(w.written()) == 3 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"data-1", "data-2", "data-3"}, w.written())
+	assert.Equal(t, []string{"tid-1", "", "tid-3"}, w.tids)
+	assert.Equal(t, []byte{0x33, 0x34, 0x33}, w.tags)
+	// the entries are acknowledged.
+	assert.Eventually(t, func() bool { return len(server.pending("noise", "yomo")) == 0 }, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the source isn't stopped")
+	}
+}
+
+func TestSourcePending(t *testing.T) {
+	server := newFakeServer(t)
+	source, err := NewSource(SourceConfig{
+		URL:       server.url(),
+		Group:     "yomo",
+		Consumer:  "source-1",
+		Streams:   map[string]byte{"noise": 0x33},
+		Start:     StartLatest,
+		Block:     10 * time.Millisecond,
+		ClaimIdle: 10 * time.Second,
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, source.createGroups(context.Background()))
+
+	// the entries before the group is created are not consumed.
+	server.add("noise", "data", "data-0")
+	assert.NoError(t, source.createGroups(context.Background()))
+	// the entry delivered to the consumer before it restarts.
+	id := server.add("noise", "data", "data-1")
+	server.deliver("noise", "yomo", id, "source-1", 0)
+	// the entry deleted from the stream.
+	id = server.add("noise", "data", "data-2")
+	server.deliver("noise", "yomo", id, "source-1", 0)
+	server.delete("noise", id)
+	// the entry delivered to the consumer which is gone.
+	id = server.add("noise", "data", "data-3")
+	server.deliver("noise", "yomo", id, "source-2", time.Minute)
+	// the entry being processed by the other consumer.
+	id = server.add("noise", "data", "data-4")
+	server.deliver("noise", "yomo", id, "source-3", 0)
+
+	w := &fakeWriter{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go source.Run(ctx, w)
+
+	assert.Eventually(t, func() bool { return len(w.written()) == 2 }, 5*time.Second, 10*time.Millisecond)
+	// the entries of the consumer which is gone are claimed at once.
+	assert.ElementsMatch(t, []string{"data-1", "data-3"}, w.written())
+	assert.Eventually(t, func() bool {
+		pending := server.pending("noise", "yomo")
+		return len(pending) == 1 && pending[id] == "source-3"
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestNewSource(t *testing.T) {
+	for _, conf := range []SourceConfig{
+		{Group: "yomo", Streams: map[string]byte{"noise": 0x33}},
+		{URL: "redis://localhost", Streams: map[string]byte{"noise": 0x33}},
+		{URL: "redis://localhost", Group: "yomo"},
+		{URL: "redis://localhost", Group: "yomo", Streams: map[string]byte{"noise": 0x33}, Start: "0"},
+	} {
+		_, err := NewSource(conf)
+		assert.Error(t, err)
+	}
+}
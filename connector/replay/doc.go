@@ -0,0 +1,11 @@
+// Package replay records the production traffic of YoMo into files and replays it, so the incidents are reproduced
+// and the new stream functions are load tested with the real data.
+//
+// The Recorder runs as a stream function which appends the data it observes to a file of newline-delimited JSON,
+// each line is a Record of the data, its data tag, its transaction ID and the time when it was issued by its source,
+// e.g. {"time":"2026-10-16T13:04:05.123456789Z","tag":51,"tid":"1792155600-1","data":"eW9tbw=="}. The file is
+// compressed by gzip if its name ends with ".gz".
+//
+// The Replayer ingests the records of a file into YoMo-Zipper by the YoMo-Source client, the records are written at
+// the pace they were recorded, or faster by the speed, or as fast as possible for the load tests.
+package replay
@@ -0,0 +1,96 @@
+package replay
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/yomorun/yomo/internal/core"
+)
+
+// maxRecordSize is the max size in bytes of a line of the file, it's the max frame size of YoMo with the overhead
+// of base64 and JSON.
+const maxRecordSize = core.DefaultMaxFrameSize/3*4 + 64<<10
+
+// Record is the data recorded in the file.
+type Record struct {
+	// Time is when the data was issued by its source, or when it was recorded if the source didn't stamp it.
+	Time time.Time `json:"time"`
+	// Tag is the data tag.
+	Tag byte `json:"tag"`
+	// TransactionID is the transaction ID of the data.
+	TransactionID string `json:"tid,omitempty"`
+	// Data is the data, it's encoded by base64 in the file.
+	Data []byte `json:"data"`
+}
+
+// Reader reads the records of a file in the order they were recorded.
+type Reader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+// NewReader creates the reader of the records from r, r is the content of file uncompressed.
+func NewReader(r io.Reader) *Reader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRecordSize)
+	return &Reader{scanner: scanner}
+}
+
+// Read reads the next record, it returns io.EOF after the last record. The empty lines are skipped.
+func (r *Reader) Read() (Record, error) {
+	for r.scanner.Scan() {
+		r.line++
+		line := r.scanner.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(line, &record); err != nil {
+			return Record{}, fmt.Errorf("replay: the record of line %d is malformed: %w", r.line, err)
+		}
+		return record, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return Record{}, err
+	}
+	return Record{}, io.EOF
+}
+
+// openFile opens the file to read the records, it's decompressed if its name ends with ".gz".
+func openFile(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if !gzipped(path) {
+		return f, nil
+	}
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("replay: read the gzip file %s failed: %w", path, err)
+	}
+	return &gzipFile{Reader: gr, file: f}, nil
+}
+
+// gzipped indicates if the file of path is compressed by gzip.
+func gzipped(path string) bool {
+	return strings.HasSuffix(path, ".gz")
+}
+
+// gzipFile is the file compressed by gzip.
+type gzipFile struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (f *gzipFile) Close() error {
+	f.Reader.Close()
+	return f.file.Close()
+}
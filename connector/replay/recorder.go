@@ -0,0 +1,136 @@
+package replay
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/yomorun/yomo/core/rx"
+	"github.com/yomorun/yomo/streamfunction"
+)
+
+// errClosed is the error of the data published after the recorder is closed.
+var errClosed = errors.New("replay: the recorder is closed")
+
+// RecorderConfig represents the config of the recorder.
+type RecorderConfig struct {
+	// Path is the path of file, the records are appended to it. The file is compressed by gzip if its name ends with
+	// ".gz", the compressed records are written when the recorder is closed or flushed.
+	Path string `yaml:"path"`
+	// PassThrough responds the data after it's recorded, so the next stream functions receive it too.
+	PassThrough bool `yaml:"pass_through,omitempty"`
+}
+
+// Recorder appends the data of stream function to the file.
+type Recorder struct {
+	conf   RecorderConfig
+	mutex  sync.Mutex
+	file   *os.File
+	gz     *gzip.Writer
+	w      io.Writer
+	closed bool
+}
+
+// NewRecorder creates the recorder, the file is created if it doesn't exist.
+func NewRecorder(conf RecorderConfig) (*Recorder, error) {
+	if conf.Path == "" {
+		return nil, errors.New("replay: the path of file is required")
+	}
+	f, err := os.OpenFile(conf.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	r := &Recorder{conf: conf, file: f, w: f}
+	if gzipped(conf.Path) {
+		// the appended gzip members are read as one file.
+		r.gz = gzip.NewWriter(f)
+		r.w = r.gz
+	}
+	return r, nil
+}
+
+// Publish records the data with the tag, the time of record is when the data was issued by its source, or now if
+// the source didn't stamp it.
+func (r *Recorder) Publish(ctx context.Context, tag byte, tid string, data []byte) error {
+	t, ok := streamfunction.Timestamp(ctx)
+	if !ok {
+		t = time.Now()
+	}
+	line, err := json.Marshal(Record{Time: t.UTC(), Tag: tag, TransactionID: tid, Data: data})
+	if err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.closed {
+		return errClosed
+	}
+	// the line is written at once, so the records are kept whole if the process crashes.
+	_, err = r.w.Write(append(line, '\n'))
+	return err
+}
+
+// Handler returns the handler of stream function which records the data observed by the tag, e.g.
+// sfn.PipeFunc(0x33, 0x34, recorder.Handler(0x33)).
+func (r *Recorder) Handler(tag byte) streamfunction.Handler {
+	return func(ctx context.Context, payload []byte) ([]byte, error) {
+		if err := r.Publish(ctx, tag, streamfunction.TransactionID(ctx), payload); err != nil {
+			return nil, err
+		}
+		if r.conf.PassThrough {
+			return payload, nil
+		}
+		return nil, nil
+	}
+}
+
+// RawHandler returns the handler of rx.Stream which records the raw carriages of the data, e.g. the data which
+// isn't encoded by Y3, it's passed on with the tag if PassThrough is set. The stream function observes the tag only
+// by Subscribe or the tags of its app in the workflow, e.g. sfn.Subscribe(0x33) and sfn.Pipe(recorder.RawHandler(0x33)).
+func (r *Recorder) RawHandler(tag byte) func(rxstream rx.Stream) rx.Stream {
+	return func(rxstream rx.Stream) rx.Stream {
+		return rxstream.RawBytes().FlatMapSlice(func(ctx context.Context, i interface{}) ([]interface{}, error) {
+			data := i.([]byte)
+			if err := r.Publish(ctx, tag, streamfunction.TransactionID(ctx), data); err != nil {
+				return nil, err
+			}
+			if r.conf.PassThrough {
+				return []interface{}{rx.TaggedData{Tag: tag, Data: data}}, nil
+			}
+			return nil, nil
+		})
+	}
+}
+
+// Flush writes the compressed records to the file, it does nothing if the file isn't compressed.
+func (r *Recorder) Flush() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.closed || r.gz == nil {
+		return nil
+	}
+	return r.gz.Flush()
+}
+
+// Close writes the records which are buffered and closes the file.
+func (r *Recorder) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	if r.gz != nil {
+		if err := r.gz.Close(); err != nil {
+			r.file.Close()
+			return err
+		}
+	}
+	return r.file.Close()
+}
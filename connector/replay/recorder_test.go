@@ -0,0 +1,86 @@
+package replay
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// readRecords reads all records of the file.
+func readRecords(t *testing.T, path string) []Record {
+	f, err := openFile(path)
+	if !assert.NoError(t, err) {
+		return nil
+	}
+	defer f.Close()
+
+	records := make([]Record, 0)
+	reader := NewReader(f)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return records
+		}
+		if !assert.NoError(t, err) {
+			return records
+		}
+		records = append(records, record)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	for _, name := range []string{"traffic.ndjson", "traffic.ndjson.gz"} {
+		path := filepath.Join(t.TempDir(), name)
+		r, err := NewRecorder(RecorderConfig{Path: path, PassThrough: true})
+		assert.NoError(t, err)
+
+		before := time.Now()
+		assert.NoError(t, r.Publish(context.Background(), 0x33, "tid-1", []byte("yo")))
+		buf, err := r.Handler(0x34)(context.Background(), []byte("mo"))
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("mo"), buf)
+		assert.NoError(t, r.Flush())
+		assert.NoError(t, r.Close())
+		assert.NoError(t, r.Close())
+		assert.ErrorIs(t, r.Publish(context.Background(), 0x33, "", nil), errClosed)
+
+		// the records are appended to the file.
+		r, err = NewRecorder(RecorderConfig{Path: path})
+		assert.NoError(t, err)
+		buf, err = r.Handler(0x35)(context.Background(), []byte("!"))
+		assert.NoError(t, err)
+		assert.Nil(t, buf)
+		assert.NoError(t, r.Close())
+
+		records := readRecords(t, path)
+		if assert.Len(t, records, 3, name) {
+			assert.Equal(t, Record{Time: records[0].Time, Tag: 0x33, TransactionID: "tid-1", Data: []byte("yo")}, records[0])
+			assert.False(t, records[0].Time.Before(before.Truncate(time.Microsecond)))
+			assert.Equal(t, byte(0x34), records[1].Tag)
+			assert.Equal(t, []byte("!"), records[2].Data)
+		}
+	}
+
+	_, err := NewRecorder(RecorderConfig{})
+	assert.EqualError(t, err, "replay: the path of file is required")
+}
+
+func TestReader(t *testing.T) {
+	reader := NewReader(strings.NewReader(`{"time":"2026-10-16T13:04:05Z","tag":51,"data":"eW9tbw=="}
+
+{"tag":
+`))
+	record, err := reader.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, "yomo", string(record.Data))
+	assert.Equal(t, time.Date(2026, 10, 16, 13, 4, 5, 0, time.UTC), record.Time)
+	_, err = reader.Read()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "replay: the record of line 3 is malformed")
+	}
+}
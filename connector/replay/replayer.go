@@ -0,0 +1,145 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"github.com/yomorun/yomo/connector"
+	"github.com/yomorun/yomo/logger"
+)
+
+// ReplayerConfig represents the config of the replayer.
+type ReplayerConfig struct {
+	// Path is the path of file recorded by the recorder.
+	Path string `yaml:"path"`
+	// Speed is the multiple of the pace which the records were recorded at, e.g. 2 replays them twice as fast, the
+	// default is 1.
+	Speed float64 `yaml:"speed,omitempty"`
+	// Unpaced writes the records as fast as possible regardless of their time, e.g. for the load tests.
+	Unpaced bool `yaml:"unpaced,omitempty"`
+	// Loops is the count of times the file is replayed, the default is 1, it's replayed until the replayer is
+	// stopped if it's negative.
+	Loops int `yaml:"loops,omitempty"`
+	// Tags maps the recorded data tags to the tags which the records are written with, e.g. the tag observed by a
+	// new stream function, the records of the other tags are written with their own tags.
+	Tags map[byte]byte `yaml:"tags,omitempty"`
+	// TransactionIDs writes the records with their recorded transaction IDs, the IDs are generated by default since
+	// the data of the same IDs is deduplicated by the stream functions.
+	TransactionIDs bool `yaml:"transaction_ids,omitempty"`
+}
+
+// Replayer ingests the records of the file into YoMo-Zipper.
+type Replayer struct {
+	conf ReplayerConfig
+}
+
+// NewReplayer creates the replayer of the file.
+func NewReplayer(conf ReplayerConfig) (*Replayer, error) {
+	if conf.Path == "" {
+		return nil, errors.New("replay: the path of file is required")
+	}
+	if _, err := os.Stat(conf.Path); err != nil {
+		return nil, err
+	}
+	if conf.Speed < 0 {
+		return nil, errors.New("replay: the speed must not be negative")
+	}
+	if conf.Speed == 0 {
+		conf.Speed = 1
+	}
+	if conf.Loops == 0 {
+		conf.Loops = 1
+	}
+	return &Replayer{conf: conf}, nil
+}
+
+// Run writes the records of the file to w until they're replayed or ctx is done, e.g. the YoMo-Source client. The
+// failed writes are retried, so the records are delivered at least once. It returns nil when the records are
+// replayed or ctx is done, or the error if the file can't be read.
+func (r *Replayer) Run(ctx context.Context, w connector.Writer) error {
+	logger.Printf("✅ Replaying the records of %s at the speed %gx", r.conf.Path, r.conf.Speed)
+	for loop := 0; r.conf.Loops < 0 || loop < r.conf.Loops; loop++ {
+		n, err := r.replay(ctx, w)
+		if err != nil {
+			return err
+		}
+		logger.Debug("[replay] the records are replayed.", "path", r.conf.Path, "loop", loop+1, "records", n)
+		// the file without records is replayed once.
+		if ctx.Err() != nil || n == 0 {
+			return nil
+		}
+	}
+	return nil
+}
+
+// replay writes the records of the file once, the records are written at the pace they were recorded, it returns
+// the count of records written.
+func (r *Replayer) replay(ctx context.Context, w connector.Writer) (int, error) {
+	f, err := openFile(r.conf.Path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	reader := NewReader(f)
+	start := time.Now()
+	var first time.Time
+	n := 0
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+
+		if !r.conf.Unpaced {
+			if first.IsZero() {
+				first = record.Time
+			}
+			// the records recorded out of order are written at once.
+			at := start.Add(time.Duration(float64(record.Time.Sub(first)) / r.conf.Speed))
+			if !sleep(ctx, time.Until(at)) {
+				return n, nil
+			}
+		}
+		tag := record.Tag
+		if mapped, ok := r.conf.Tags[tag]; ok {
+			tag = mapped
+		}
+		tid := ""
+		if r.conf.TransactionIDs {
+			tid = record.TransactionID
+		}
+		err = connector.Retry(ctx, -1, func() error {
+			_, err := connector.WriteWithTransaction(w, tid, record.Data, tag)
+			if err != nil && ctx.Err() == nil {
+				logger.Error("[replay] write the record to YoMo-Zipper failed, will retry.", "path", r.conf.Path, "err", err)
+			}
+			return err
+		})
+		if err != nil {
+			return n, nil
+		}
+		n++
+	}
+}
+
+// sleep waits for d, it returns false if ctx is done.
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
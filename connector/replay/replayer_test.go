@@ -0,0 +1,105 @@
+package replay
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeWriter struct {
+	mutex    sync.Mutex
+	failures int
+	data     []string
+	tids     []string
+	tags     []byte
+	times    []time.Time
+}
+
+func (w *fakeWriter) WriteWithTags(data []byte, tags ...byte) (int, error) {
+	return w.WriteWithTransaction("", data, tags...)
+}
+
+func (w *fakeWriter) WriteWithTransaction(tid string, data []byte, tags ...byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.failures > 0 {
+		w.failures--
+		return 0, errors.New("disconnected")
+	}
+	w.data = append(w.data, string(data))
+	w.tids = append(w.tids, tid)
+	w.tags = append(w.tags, tags...)
+	w.times = append(w.times, time.Now())
+	return len(data), nil
+}
+
+// recordFile records the data 100ms apart to the file.
+func recordFile(t *testing.T, data ...string) string {
+	path := filepath.Join(t.TempDir(), "traffic.ndjson")
+	f, err := os.Create(path)
+	assert.NoError(t, err)
+	defer f.Close()
+
+	start := time.Date(2026, 10, 16, 13, 0, 0, 0, time.UTC)
+	for i, d := range data {
+		line := `{"time":"` + start.Add(time.Duration(i)*100*time.Millisecond).Format(time.RFC3339Nano) +
+			`","tag":51,"tid":"tid-` + d + `","data":"` + base64.StdEncoding.EncodeToString([]byte(d)) + `"}` + "\n"
+		_, err := f.WriteString(line)
+		assert.NoError(t, err)
+	}
+	return path
+}
+
+func TestReplayer(t *testing.T) {
+	path := recordFile(t, "a", "b", "c")
+
+	// the records are replayed 10 times as fast as they were recorded.
+	r, err := NewReplayer(ReplayerConfig{Path: path, Speed: 10, Tags: map[byte]byte{0x33: 0x40}, TransactionIDs: true})
+	assert.NoError(t, err)
+	w := &fakeWriter{}
+	assert.NoError(t, r.Run(context.Background(), w))
+	assert.Equal(t, []string{"a", "b", "c"}, w.data)
+	assert.Equal(t, []string{"tid-a", "tid-b", "tid-c"}, w.tids)
+	assert.Equal(t, []byte{0x40, 0x40, 0x40}, w.tags)
+	elapsed := w.times[2].Sub(w.times[0])
+	assert.True(t, elapsed >= 20*time.Millisecond && elapsed < 150*time.Millisecond, elapsed)
+
+	// the records are replayed as fast as possible in loops, the failed writes are retried.
+	r, err = NewReplayer(ReplayerConfig{Path: path, Unpaced: true, Loops: 2})
+	assert.NoError(t, err)
+	w = &fakeWriter{failures: 1}
+	assert.NoError(t, r.Run(context.Background(), w))
+	assert.Equal(t, []string{"a", "b", "c", "a", "b", "c"}, w.data)
+	assert.Equal(t, []string{"", "", "", "", "", ""}, w.tids)
+	assert.Equal(t, byte(0x33), w.tags[0])
+
+	// the replay in the original pace is stopped by ctx.
+	r, err = NewReplayer(ReplayerConfig{Path: path, Loops: -1})
+	assert.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+	defer cancel()
+	w = &fakeWriter{}
+	assert.NoError(t, r.Run(ctx, w))
+	assert.Equal(t, []string{"a", "b"}, w.data)
+}
+
+func TestNewReplayer(t *testing.T) {
+	_, err := NewReplayer(ReplayerConfig{})
+	assert.EqualError(t, err, "replay: the path of file is required")
+	_, err = NewReplayer(ReplayerConfig{Path: filepath.Join(t.TempDir(), "none.ndjson")})
+	assert.Error(t, err)
+	_, err = NewReplayer(ReplayerConfig{Path: recordFile(t), Speed: -1})
+	assert.EqualError(t, err, "replay: the speed must not be negative")
+
+	// the empty file is replayed once.
+	r, err := NewReplayer(ReplayerConfig{Path: recordFile(t), Loops: -1})
+	assert.NoError(t, err)
+	assert.NoError(t, r.Run(context.Background(), &fakeWriter{}))
+}
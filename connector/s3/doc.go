@@ -0,0 +1,9 @@
+// Package s3 archives the data of stream functions in S3 or the compatible object storages, e.g. MinIO. The Sink
+// batches the data by its data tag and the time window into an object of newline-delimited JSON compressed by
+// gzip, the objects are partitioned by the keys in the Hive style, e.g.
+// "raw/tag=51/date=2026-10-16/hour=13/1792155600-5f3a9c1e-000001.ndjson.gz", so they're queried by Athena or
+// Spark directly. Parquet isn't supported, the NDJSON objects are compacted into it by the query engines if needed.
+//
+// The data is acknowledged when it's buffered, the buffers are uploaded when their windows end and the failed
+// uploads are retried, the buffered data is uploaded when the sink is closed but lost if the process crashes.
+package s3
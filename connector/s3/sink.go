@@ -0,0 +1,431 @@
+package s3
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/yomorun/yomo/connector"
+	"github.com/yomorun/yomo/internal/sigv4"
+	"github.com/yomorun/yomo/logger"
+	"github.com/yomorun/yomo/streamfunction"
+)
+
+const (
+	// DefaultRegion is the default region of the bucket.
+	DefaultRegion = "us-east-1"
+	// DefaultWindow is the default time window of the data in an object.
+	DefaultWindow = 5 * time.Minute
+	// DefaultMaxObjectSize is the default max size in bytes of the uncompressed data in an object.
+	DefaultMaxObjectSize = 64 << 20
+	// uploadTimeout is the timeout of uploading an object.
+	uploadTimeout = 5 * time.Minute
+)
+
+// the compressions of objects.
+const (
+	// CompressionGzip compresses the objects by gzip, the keys end with ".ndjson.gz".
+	CompressionGzip = "gzip"
+	// CompressionNone uploads the objects uncompressed, the keys end with ".ndjson".
+	CompressionNone = "none"
+)
+
+// errClosed is the error of the data published after the sink is closed.
+var errClosed = errors.New("s3: the sink is closed")
+
+// SinkConfig represents the config of S3 sink.
+type SinkConfig struct {
+	// Endpoint is the URL of the object storage, e.g. "http://localhost:9000" of MinIO, the default is the endpoint
+	// of AWS S3 in the region.
+	Endpoint string `yaml:"endpoint,omitempty"`
+	// Region is the region of the bucket, the default is "us-east-1".
+	Region string `yaml:"region,omitempty"`
+	// Bucket is the bucket which the objects are uploaded to.
+	Bucket string `yaml:"bucket"`
+	// PathStyle addresses the bucket in the path of URLs instead of the host, it's required by MinIO.
+	PathStyle bool `yaml:"path_style,omitempty"`
+	// Prefix is the prefix of the keys of objects, e.g. "raw/".
+	Prefix string `yaml:"prefix,omitempty"`
+	// AccessKeyID, SecretAccessKey and SessionToken are the credentials, the default is the environment variables
+	// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN.
+	AccessKeyID     string `yaml:"access_key_id,omitempty"`
+	SecretAccessKey string `yaml:"secret_access_key,omitempty"`
+	SessionToken    string `yaml:"session_token,omitempty"`
+	// Window is the time window of the data in an object, the data is windowed by the time when it was issued by
+	// its source, the default is 5m.
+	Window time.Duration `yaml:"window,omitempty"`
+	// MaxObjectSize is the max size in bytes of the uncompressed data in an object, the object is uploaded before
+	// its window ends if it's full, the default is 64MB.
+	MaxObjectSize int `yaml:"max_object_size,omitempty"`
+	// Compression is the compression of objects, it's "gzip" or "none", the default is "gzip".
+	Compression string `yaml:"compression,omitempty"`
+	// MaxRetries is the count of retries when an upload fails, the default is 5,
+	// it's retried until the sink is closed if it's negative.
+	MaxRetries int `yaml:"max_retries,omitempty"`
+	// PassThrough responds the data after it's buffered, so the next stream functions receive it too.
+	PassThrough bool `yaml:"pass_through,omitempty"`
+}
+
+// Record is a line of the objects. The payload is embedded as Data if it's a JSON value, or it's encoded in base64
+// as Payload.
+type Record struct {
+	Tag           byte            `json:"tag"`
+	TransactionID string          `json:"tid,omitempty"`
+	Time          time.Time       `json:"time"`
+	Data          json.RawMessage `json:"data,omitempty"`
+	Payload       []byte          `json:"payload,omitempty"`
+}
+
+// Sink archives the data of stream function in the objects of a bucket.
+type Sink struct {
+	conf     SinkConfig
+	endpoint *url.URL
+	creds    sigv4.Credentials
+	client   *http.Client
+	// instance is the random ID of sink in the keys of objects, so the sinks don't overwrite the objects of
+	// each other.
+	instance string
+
+	mutex   sync.Mutex
+	buffers map[window]*buffer
+	// full is the buffers which are full before their windows end, ready notifies the uploader of them.
+	full   []*buffer
+	ready  chan struct{}
+	seq    int
+	closed bool
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	stopped chan struct{}
+}
+
+// window is the data tag and the start time of a window.
+type window struct {
+	tag   byte
+	start time.Time
+}
+
+// buffer is the data of an object.
+type buffer struct {
+	window
+	seq  int
+	body bytes.Buffer
+	w    io.WriteCloser
+	size int
+	// count is the count of records.
+	count int
+}
+
+// NewSink creates a S3 sink.
+func NewSink(conf SinkConfig) (*Sink, error) {
+	if conf.Bucket == "" {
+		return nil, errors.New("s3: the bucket is required")
+	}
+	if conf.Region == "" {
+		conf.Region = DefaultRegion
+	}
+	if conf.Endpoint == "" {
+		conf.Endpoint = "https://s3." + conf.Region + ".amazonaws.com"
+	}
+	endpoint, err := url.Parse(conf.Endpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, fmt.Errorf("s3: invalid endpoint %q", conf.Endpoint)
+	}
+	if conf.Prefix != "" && !strings.HasSuffix(conf.Prefix, "/") {
+		conf.Prefix += "/"
+	}
+	if conf.Window <= 0 {
+		conf.Window = DefaultWindow
+	}
+	if conf.MaxObjectSize <= 0 {
+		conf.MaxObjectSize = DefaultMaxObjectSize
+	}
+	switch conf.Compression {
+	case "":
+		conf.Compression = CompressionGzip
+	case CompressionGzip, CompressionNone:
+	default:
+		return nil, fmt.Errorf("s3: unknown compression %q", conf.Compression)
+	}
+	if conf.MaxRetries == 0 {
+		conf.MaxRetries = connector.DefaultMaxRetries
+	}
+
+	creds := sigv4.Credentials{AccessKeyID: conf.AccessKeyID, SecretAccessKey: conf.SecretAccessKey, SessionToken: conf.SessionToken}
+	if creds.AccessKeyID == "" {
+		creds = sigv4.FromEnv()
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return nil, errors.New("s3: the credentials are required")
+	}
+
+	instance := make([]byte, 4)
+	if _, err := rand.Read(instance); err != nil {
+		return nil, err
+	}
+
+	s := &Sink{
+		conf:     conf,
+		endpoint: endpoint,
+		creds:    creds,
+		client:   &http.Client{Timeout: uploadTimeout},
+		instance: hex.EncodeToString(instance),
+		buffers:  make(map[window]*buffer),
+		ready:    make(chan struct{}, 1),
+		stopped:  make(chan struct{}),
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	go s.run()
+	return s, nil
+}
+
+// Publish buffers the data with the tag in the object of its window, the time of data is taken from ctx of the
+// handler, or it's the current time.
+func (s *Sink) Publish(ctx context.Context, tag byte, tid string, data []byte) error {
+	t, ok := streamfunction.Timestamp(ctx)
+	if !ok {
+		t = time.Now()
+	}
+	record := Record{Tag: tag, TransactionID: tid, Time: t.UTC()}
+	if json.Valid(data) {
+		record.Data = data
+	} else {
+		record.Payload = data
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closed {
+		return errClosed
+	}
+	w := window{tag: tag, start: t.UTC().Truncate(s.conf.Window)}
+	b, ok := s.buffers[w]
+	if !ok {
+		b = s.newBuffer(w)
+		s.buffers[w] = b
+	}
+	if _, err := b.w.Write(line); err != nil {
+		return err
+	}
+	b.size += len(line)
+	b.count++
+	if b.size >= s.conf.MaxObjectSize {
+		delete(s.buffers, w)
+		s.full = append(s.full, b)
+		select {
+		case s.ready <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+func (s *Sink) newBuffer(w window) *buffer {
+	s.seq++
+	b := &buffer{window: w, seq: s.seq}
+	if s.conf.Compression == CompressionGzip {
+		b.w = gzip.NewWriter(&b.body)
+	} else {
+		b.w = nopCloser{&b.body}
+	}
+	return b
+}
+
+// Handler returns the handler of stream function which archives the data observed by the tag, e.g.
+// sfn.PipeFunc(0x33, 0x34, sink.Handler(0x33)).
+func (s *Sink) Handler(tag byte) streamfunction.Handler {
+	return func(ctx context.Context, payload []byte) ([]byte, error) {
+		if err := s.Publish(ctx, tag, streamfunction.TransactionID(ctx), payload); err != nil {
+			return nil, err
+		}
+		if s.conf.PassThrough {
+			return payload, nil
+		}
+		return nil, nil
+	}
+}
+
+// Close uploads the buffered data and stops the sink.
+func (s *Sink) Close() error {
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mutex.Unlock()
+
+	s.cancel()
+	<-s.stopped
+	return nil
+}
+
+// run uploads the full buffers and the buffers whose windows ended, until the sink is closed.
+func (s *Sink) run() {
+	defer close(s.stopped)
+	// the windows are checked ten times in a window, at most every 10s.
+	interval := s.conf.Window / 10
+	if interval > 10*time.Second {
+		interval = 10 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ready:
+			for _, b := range s.take(func(window) bool { return false }) {
+				s.upload(s.ctx, s.conf.MaxRetries, b)
+			}
+		case now := <-ticker.C:
+			for _, b := range s.take(func(w window) bool { return !now.Before(w.start.Add(s.conf.Window)) }) {
+				s.upload(s.ctx, s.conf.MaxRetries, b)
+			}
+		case <-s.ctx.Done():
+			// the last uploads are retried as the default, since they can't be retried later.
+			maxRetries := s.conf.MaxRetries
+			if maxRetries < 0 {
+				maxRetries = connector.DefaultMaxRetries
+			}
+			for _, b := range s.take(func(window) bool { return true }) {
+				s.upload(context.Background(), maxRetries, b)
+			}
+			return
+		}
+	}
+}
+
+// take removes the full buffers and the buffers whose windows match, and returns them.
+func (s *Sink) take(match func(window) bool) []*buffer {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	taken := s.full
+	s.full = nil
+	for w, b := range s.buffers {
+		if match(w) {
+			delete(s.buffers, w)
+			taken = append(taken, b)
+		}
+	}
+	return taken
+}
+
+// upload uploads the buffer as an object, the data is dropped if it fails after the retries.
+func (s *Sink) upload(ctx context.Context, maxRetries int, b *buffer) {
+	if err := b.w.Close(); err != nil {
+		logger.Error("[s3] compress the object failed.", "err", err)
+		return
+	}
+	key := s.key(b)
+	contentType := "application/x-ndjson"
+	if s.conf.Compression == CompressionGzip {
+		contentType = "application/gzip"
+	}
+	err := connector.Retry(ctx, maxRetries, func() error {
+		err := s.put(ctx, key, contentType, b.body.Bytes())
+		if err != nil {
+			logger.Error("[s3] upload the object failed, will retry.", "key", key, "err", err)
+		}
+		return err
+	})
+	if err != nil {
+		logger.Error("[s3] the object is dropped.", "key", key, "records", b.count, "err", err)
+		return
+	}
+	logger.Debug("[s3] the object is uploaded.", "key", key, "records", b.count)
+}
+
+// key returns the key of the object of buffer, e.g. "raw/tag=51/date=2026-10-16/hour=13/1792155600-5f3a9c1e-000001.ndjson.gz".
+func (s *Sink) key(b *buffer) string {
+	start := b.start.UTC()
+	key := fmt.Sprintf("%stag=%d/date=%s/hour=%02d/%d-%s-%06d.ndjson", s.conf.Prefix, b.tag,
+		start.Format("2006-01-02"), start.Hour(), start.Unix(), s.instance, b.seq)
+	if s.conf.Compression == CompressionGzip {
+		key += ".gz"
+	}
+	return key
+}
+
+// s3Error is the error response of S3.
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// put uploads the object by PutObject, the client errors except the throttling and the timeout are permanent.
+func (s *Sink) put(ctx context.Context, key, contentType string, body []byte) error {
+	u := *s.endpoint
+	path := "/" + s.conf.Bucket + "/" + key
+	if !s.conf.PathStyle {
+		u.Host = s.conf.Bucket + "." + u.Host
+		path = "/" + key
+	}
+	// the path is sent as it's signed.
+	u.Path = strings.TrimSuffix(s.endpoint.Path, "/") + path
+	u.RawPath = strings.TrimSuffix(s.endpoint.EscapedPath(), "/") + escapePath(path)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return backoff.Permanent(err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	sigv4.Sign(req, s.creds, s.conf.Region, "s3", sigv4.PayloadHash(body), time.Now())
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 == 2 {
+		io.Copy(ioutil.Discard, res.Body)
+		return nil
+	}
+
+	msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 4096))
+	var e s3Error
+	if xml.Unmarshal(msg, &e) == nil && e.Code != "" {
+		err = fmt.Errorf("s3: status %d, %s: %s", res.StatusCode, e.Code, e.Message)
+	} else {
+		err = fmt.Errorf("s3: status %d", res.StatusCode)
+	}
+	if res.StatusCode >= 500 || res.StatusCode == http.StatusTooManyRequests || res.StatusCode == http.StatusRequestTimeout {
+		return err
+	}
+	return backoff.Permanent(err)
+}
+
+// escapePath escapes the segments of path as they're signed.
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = sigv4.Escape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
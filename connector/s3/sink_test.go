@@ -0,0 +1,183 @@
+package s3
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeStorage stores the objects uploaded by PutObject.
+type fakeStorage struct {
+	mutex    sync.Mutex
+	objects  map[string][]byte
+	failures int32
+}
+
+func (s *fakeStorage) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut || !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if atomic.AddInt32(&s.failures, -1) >= 0 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`<Error><Code>SlowDown</Code><Message>Please reduce your request rate.</Message></Error>`))
+		return
+	}
+	body, _ := ioutil.ReadAll(r.Body)
+	s.mutex.Lock()
+	s.objects[r.URL.EscapedPath()] = body
+	s.mutex.Unlock()
+}
+
+func (s *fakeStorage) keys() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var keys []string
+	for key := range s.objects {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// records decodes the records of object.
+func (s *fakeStorage) records(t *testing.T, key string, compressed bool) []Record {
+	s.mutex.Lock()
+	body := s.objects[key]
+	s.mutex.Unlock()
+	if compressed {
+		r, err := gzip.NewReader(bytes.NewReader(body))
+		assert.NoError(t, err)
+		body, err = ioutil.ReadAll(r)
+		assert.NoError(t, err)
+	}
+	var records []Record
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		var record Record
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func newFakeStorage(t *testing.T) (*fakeStorage, string) {
+	storage := &fakeStorage{objects: make(map[string][]byte)}
+	server := httptest.NewServer(storage)
+	t.Cleanup(server.Close)
+	return storage, server.URL
+}
+
+func TestSink(t *testing.T) {
+	storage, endpoint := newFakeStorage(t)
+	storage.failures = 1
+	sink, err := NewSink(SinkConfig{
+		Endpoint:        endpoint,
+		Bucket:          "archive",
+		PathStyle:       true,
+		Prefix:          "raw",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+		Window:          100 * time.Millisecond,
+		PassThrough:     true,
+	})
+	assert.NoError(t, err)
+	defer sink.Close()
+
+	res, err := sink.Handler(0x33)(context.Background(), []byte(`{"noise":42}`))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"noise":42}`, string(res))
+	assert.NoError(t, sink.Publish(context.Background(), 0x33, "tid-2", []byte("binary")))
+
+	// the objects are uploaded when their windows end, the failed upload is retried.
+	var records []Record
+	assert.Eventually(t, func() bool {
+		records = nil
+		for _, key := range storage.keys() {
+			records = append(records, storage.records(t, key, true)...)
+		}
+		return len(records) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Regexp(t, `^/archive/raw/tag%3D51/date%3D\d{4}-\d{2}-\d{2}/hour%3D\d{2}/\d+-[0-9a-f]{8}-\d{6}\.ndjson\.gz$`, storage.keys()[0])
+	if records[0].TransactionID != "" {
+		records[0], records[1] = records[1], records[0]
+	}
+	assert.Equal(t, byte(0x33), records[0].Tag)
+	assert.JSONEq(t, `{"noise":42}`, string(records[0].Data))
+	assert.Nil(t, records[0].Payload)
+	assert.Equal(t, "tid-2", records[1].TransactionID)
+	assert.Equal(t, "binary", string(records[1].Payload))
+	assert.WithinDuration(t, time.Now(), records[1].Time, 5*time.Second)
+}
+
+func TestSinkFull(t *testing.T) {
+	storage, endpoint := newFakeStorage(t)
+	sink, err := NewSink(SinkConfig{
+		Endpoint:        endpoint,
+		Bucket:          "archive",
+		PathStyle:       true,
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+		MaxObjectSize:   150,
+		Compression:     CompressionNone,
+	})
+	assert.NoError(t, err)
+
+	// the full object is uploaded before its window ends.
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, sink.Publish(context.Background(), 0x34, "", []byte(`"a value of 40 bytes....................."`)))
+	}
+	assert.Eventually(t, func() bool { return len(storage.keys()) == 1 }, 5*time.Second, 10*time.Millisecond)
+	assert.Len(t, storage.records(t, storage.keys()[0], false), 2)
+
+	// the buffered data is uploaded when the sink is closed.
+	assert.NoError(t, sink.Close())
+	assert.Len(t, storage.keys(), 2)
+	for _, key := range storage.keys() {
+		assert.True(t, strings.HasSuffix(key, ".ndjson"), key)
+	}
+	assert.ErrorIs(t, sink.Publish(context.Background(), 0x34, "", []byte("data")), errClosed)
+}
+
+func TestSinkRejected(t *testing.T) {
+	_, endpoint := newFakeStorage(t)
+	sink, err := NewSink(SinkConfig{Endpoint: endpoint, Bucket: "archive", PathStyle: true, AccessKeyID: "unknown", SecretAccessKey: "secret"})
+	assert.NoError(t, err)
+	defer sink.Close()
+
+	// the client errors are not retried.
+	err = sink.put(context.Background(), "key", "application/gzip", nil)
+	var permanent *backoff.PermanentError
+	assert.ErrorAs(t, err, &permanent)
+	assert.EqualError(t, err, "s3: status 403")
+
+	// the bucket is addressed by the host.
+	sink, err = NewSink(SinkConfig{Bucket: "archive", Region: "eu-west-1", AccessKeyID: "AKID", SecretAccessKey: "secret"})
+	assert.NoError(t, err)
+	defer sink.Close()
+	assert.Equal(t, "https://s3.eu-west-1.amazonaws.com", sink.endpoint.String())
+}
+
+func TestNewSink(t *testing.T) {
+	for _, conf := range []SinkConfig{
+		{},
+		{Bucket: "b", AccessKeyID: "AKID", SecretAccessKey: "secret", Endpoint: "localhost:9000"},
+		{Bucket: "b", AccessKeyID: "AKID", SecretAccessKey: "secret", Compression: "zstd"},
+		{Bucket: "b", AccessKeyID: "AKID"},
+	} {
+		_, err := NewSink(conf)
+		assert.Error(t, err)
+	}
+}
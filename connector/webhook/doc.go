@@ -0,0 +1,7 @@
+// Package webhook triggers the external systems by the results of stream functions. The Sink posts the data to the
+// HTTP endpoints whose tag filters match its data tag, each request is signed by HMAC-SHA256 with the secret of its
+// endpoint, so the receivers verify it's sent by YoMo and not replayed, see Verify.
+//
+// The failed deliveries are retried with the exponential backoff, and the ones which still fail are appended to the
+// dead letter file as JSON lines of DeadLetter, so they're inspected and replayed later instead of being lost.
+package webhook
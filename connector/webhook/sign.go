@@ -0,0 +1,88 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the headers of the requests.
+const (
+	// HeaderSignature is the header of the signature, e.g. "t=1629800000,v1=5257a869...".
+	HeaderSignature = "X-Yomo-Signature"
+	// HeaderTag is the header of the data tag in decimal.
+	HeaderTag = "X-Yomo-Tag"
+	// HeaderTransactionID is the header of the transaction ID, the receivers dedupe the retried deliveries by it.
+	HeaderTransactionID = "X-Yomo-Transaction-Id"
+)
+
+// DefaultTolerance is the default max age of the signatures accepted by Verify.
+const DefaultTolerance = 5 * time.Minute
+
+var (
+	// ErrNoSignature is returned by Verify if the header has no signature.
+	ErrNoSignature = errors.New("webhook: no signature")
+	// ErrSignatureMismatch is returned by Verify if no signature matches the body.
+	ErrSignatureMismatch = errors.New("webhook: the signature doesn't match")
+	// ErrSignatureExpired is returned by Verify if the signature is older than the tolerance.
+	ErrSignatureExpired = errors.New("webhook: the signature is expired")
+)
+
+// Sign returns the header of the signature of body at the time t, the signature is the HMAC-SHA256 of
+// "<unix seconds>.<body>" by the secret in hex.
+func Sign(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(signature(secret, ts, body))
+}
+
+// Verify verifies the header of the signature of body by the secret, the signatures older than the tolerance are
+// rejected, or DefaultTolerance is used if it's zero. The header may carry several signatures "v1", e.g. while the
+// secret is rotated, any of them matches.
+func Verify(secret, header string, body []byte, tolerance time.Duration) error {
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+	var ts string
+	var signatures [][]byte
+	for _, part := range strings.Split(header, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "t":
+			ts = kv[1]
+		case "v1":
+			if sig, err := hex.DecodeString(kv[1]); err == nil {
+				signatures = append(signatures, sig)
+			}
+		}
+	}
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return ErrNoSignature
+	}
+
+	expected := signature(secret, ts, body)
+	for _, sig := range signatures {
+		if hmac.Equal(sig, expected) {
+			if age := time.Since(time.Unix(sec, 0)); age > tolerance || age < -tolerance {
+				return ErrSignatureExpired
+			}
+			return nil
+		}
+	}
+	return ErrSignatureMismatch
+}
+
+func signature(secret, ts string, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(ts))
+	h.Write([]byte("."))
+	h.Write(body)
+	return h.Sum(nil)
+}
@@ -0,0 +1,30 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"noise":42}`)
+	now := time.Now()
+	header := Sign("secret", now, body)
+	assert.NoError(t, Verify("secret", header, body, 0))
+
+	assert.Equal(t, ErrSignatureMismatch, Verify("other", header, body, 0))
+	assert.Equal(t, ErrSignatureMismatch, Verify("secret", header, []byte(`{"noise":43}`), 0))
+	assert.Equal(t, ErrNoSignature, Verify("secret", "", body, 0))
+	assert.Equal(t, ErrNoSignature, Verify("secret", "v1=abcd", body, 0))
+
+	// the replayed requests are rejected.
+	old := Sign("secret", now.Add(-10*time.Minute), body)
+	assert.Equal(t, ErrSignatureExpired, Verify("secret", old, body, 0))
+	assert.NoError(t, Verify("secret", old, body, time.Hour))
+
+	// any signature matches while the secret is rotated.
+	rotated := Sign("new", now, body) + ",v1=" + Sign("secret", now, body)[len("t=1629800000,v1="):]
+	assert.NoError(t, Verify("secret", rotated, body, 0))
+	assert.NoError(t, Verify("new", rotated, body, 0))
+}
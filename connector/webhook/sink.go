@@ -0,0 +1,248 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/yomorun/yomo/connector"
+	"github.com/yomorun/yomo/logger"
+	"github.com/yomorun/yomo/streamfunction"
+)
+
+const (
+	// DefaultContentType is the default content type of the requests.
+	DefaultContentType = "application/json"
+	// DefaultTimeout is the default timeout of each request.
+	DefaultTimeout = 10 * time.Second
+)
+
+// EndpointConfig represents the config of an endpoint of webhook.
+type EndpointConfig struct {
+	// URL is the URL which the data is posted to.
+	URL string `yaml:"url"`
+	// Tags are the data tags posted to the endpoint, all the data is posted if it's empty.
+	Tags []byte `yaml:"tags,omitempty"`
+	// Secret is the key of the signatures of requests, the requests are not signed if it's empty.
+	Secret string `yaml:"secret,omitempty"`
+	// Headers are sent with each request, such as "Authorization".
+	Headers map[string]string `yaml:"headers,omitempty"`
+}
+
+// SinkConfig represents the config of webhook sink.
+type SinkConfig struct {
+	// Endpoints are the endpoints which the data is posted to.
+	Endpoints []EndpointConfig `yaml:"endpoints"`
+	// ContentType is the content type of the requests, the default is "application/json".
+	ContentType string `yaml:"content_type,omitempty"`
+	// Timeout is the timeout of each request, the default is 10s.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// MaxRetries is the count of retries when the delivery fails, the default is 5,
+	// it's retried until the handler is cancelled if it's negative.
+	MaxRetries int `yaml:"max_retries,omitempty"`
+	// DeadLetter is the path of the file which the failed deliveries are appended to, the handler returns the error
+	// of delivery if it's empty.
+	DeadLetter string `yaml:"dead_letter,omitempty"`
+	// PassThrough responds the data after it's delivered, so the next stream functions receive it too.
+	PassThrough bool `yaml:"pass_through,omitempty"`
+}
+
+// DeadLetter is a delivery which failed after the retries, its data is encoded in base64 in JSON.
+type DeadLetter struct {
+	Time          time.Time `json:"time"`
+	URL           string    `json:"url"`
+	Tag           byte      `json:"tag"`
+	TransactionID string    `json:"tid,omitempty"`
+	// Status is the HTTP status of the last attempt, it's zero if there is no response.
+	Status int    `json:"status,omitempty"`
+	Error  string `json:"error"`
+	Data   []byte `json:"data"`
+}
+
+// Sink posts the data of stream function to the webhook endpoints.
+type Sink struct {
+	conf   SinkConfig
+	client *http.Client
+
+	mutex      sync.Mutex
+	deadLetter *os.File
+}
+
+// NewSink creates a webhook sink, the dead letter file is opened for appending if it's set.
+func NewSink(conf SinkConfig) (*Sink, error) {
+	if len(conf.Endpoints) == 0 {
+		return nil, errors.New("webhook: no endpoint")
+	}
+	for _, ep := range conf.Endpoints {
+		u, err := url.Parse(ep.URL)
+		if err != nil {
+			return nil, fmt.Errorf("webhook: %v", err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return nil, fmt.Errorf("webhook: the URL %q isn't HTTP", ep.URL)
+		}
+	}
+	if conf.ContentType == "" {
+		conf.ContentType = DefaultContentType
+	}
+	if conf.Timeout <= 0 {
+		conf.Timeout = DefaultTimeout
+	}
+	if conf.MaxRetries == 0 {
+		conf.MaxRetries = connector.DefaultMaxRetries
+	}
+
+	s := &Sink{conf: conf, client: &http.Client{Timeout: conf.Timeout}}
+	if conf.DeadLetter != "" {
+		file, err := os.OpenFile(conf.DeadLetter, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("webhook: open the dead letter file failed: %w", err)
+		}
+		s.deadLetter = file
+	}
+	return s, nil
+}
+
+// Publish posts the data with the tag to the endpoints whose tags match it in parallel, it returns after they're
+// delivered or appended to the dead letter file. The error of the first endpoint which fails is returned if it's
+// not appended.
+func (s *Sink) Publish(ctx context.Context, tag byte, tid string, data []byte) error {
+	var wg sync.WaitGroup
+	errs := make([]error, len(s.conf.Endpoints))
+	for i := range s.conf.Endpoints {
+		ep := &s.conf.Endpoints[i]
+		if !ep.match(tag) {
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = s.deliver(ctx, ep, tag, tid, data)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deliver posts the data to the endpoint with retries, the failed delivery is appended to the dead letter file.
+func (s *Sink) deliver(ctx context.Context, ep *EndpointConfig, tag byte, tid string, data []byte) error {
+	var status int
+	err := connector.Retry(ctx, s.conf.MaxRetries, func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, ep.URL, bytes.NewReader(data))
+		if err != nil {
+			return backoff.Permanent(err)
+		}
+		req.Header.Set("Content-Type", s.conf.ContentType)
+		req.Header.Set(HeaderTag, strconv.Itoa(int(tag)))
+		if tid != "" {
+			req.Header.Set(HeaderTransactionID, tid)
+		}
+		for k, v := range ep.Headers {
+			req.Header.Set(k, v)
+		}
+		// the request is signed in each attempt, so the retries are not rejected as expired.
+		if ep.Secret != "" {
+			req.Header.Set(HeaderSignature, Sign(ep.Secret, time.Now(), data))
+		}
+
+		res, err := s.client.Do(req)
+		if err != nil {
+			status = 0
+			if ctx.Err() == nil {
+				logger.Error("[webhook] post the data failed, will retry.", "url", ep.URL, "tid", tid, "err", err)
+			}
+			return err
+		}
+		io.Copy(ioutil.Discard, res.Body)
+		res.Body.Close()
+		status = res.StatusCode
+		if status/100 == 2 {
+			return nil
+		}
+		err = fmt.Errorf("webhook: status %d of posting to %s", status, ep.URL)
+		// the client errors except the timeout and the throttling are permanent.
+		if status < http.StatusInternalServerError && status != http.StatusRequestTimeout && status != http.StatusTooManyRequests {
+			return backoff.Permanent(err)
+		}
+		logger.Error("[webhook] post the data failed, will retry.", "url", ep.URL, "tid", tid, "status", status)
+		return err
+	})
+	if err == nil || s.deadLetter == nil {
+		return err
+	}
+
+	letter := &DeadLetter{Time: time.Now(), URL: ep.URL, Tag: tag, TransactionID: tid, Status: status, Error: err.Error(), Data: data}
+	if buryErr := s.bury(letter); buryErr != nil {
+		logger.Error("[webhook] write the dead letter file failed.", "file", s.conf.DeadLetter, "err", buryErr)
+		return err
+	}
+	logger.Error("[webhook] the delivery failed, it's appended to the dead letter file.", "url", ep.URL, "tid", tid, "err", err)
+	return nil
+}
+
+// bury appends the dead letter to the file as a JSON line.
+func (s *Sink) bury(letter *DeadLetter) error {
+	line, err := json.Marshal(letter)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	_, err = s.deadLetter.Write(line)
+	return err
+}
+
+// Handler returns the handler of stream function which posts the data observed by the tag, e.g.
+// sfn.PipeFunc(0x33, 0x34, sink.Handler(0x33)).
+func (s *Sink) Handler(tag byte) streamfunction.Handler {
+	return func(ctx context.Context, payload []byte) ([]byte, error) {
+		if err := s.Publish(ctx, tag, streamfunction.TransactionID(ctx), payload); err != nil {
+			return nil, err
+		}
+		if s.conf.PassThrough {
+			return payload, nil
+		}
+		return nil, nil
+	}
+}
+
+// Close closes the dead letter file.
+func (s *Sink) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.deadLetter == nil {
+		return nil
+	}
+	return s.deadLetter.Close()
+}
+
+// match reports whether the data of the tag is posted to the endpoint.
+func (ep *EndpointConfig) match(tag byte) bool {
+	if len(ep.Tags) == 0 {
+		return true
+	}
+	for _, t := range ep.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
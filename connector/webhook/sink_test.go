@@ -0,0 +1,144 @@
+package webhook
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/core/serde"
+)
+
+// receiver is a webhook endpoint which records the verified requests.
+type receiver struct {
+	*httptest.Server
+	mutex    sync.Mutex
+	statuses []int
+	bodies   []string
+	headers  []http.Header
+}
+
+// newReceiver creates the endpoint which responds the statuses in order, and 200 after them.
+func newReceiver(t *testing.T, secret string, statuses ...int) *receiver {
+	r := &receiver{statuses: statuses}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		if secret != "" {
+			assert.NoError(t, Verify(secret, req.Header.Get(HeaderSignature), body, 0))
+		}
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		if len(r.statuses) > 0 {
+			status := r.statuses[0]
+			r.statuses = r.statuses[1:]
+			w.WriteHeader(status)
+			return
+		}
+		r.bodies = append(r.bodies, string(body))
+		r.headers = append(r.headers, req.Header)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *receiver) received() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]string(nil), r.bodies...)
+}
+
+func TestSink(t *testing.T) {
+	all := newReceiver(t, "secret-1", http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	noise := newReceiver(t, "")
+	sink, err := NewSink(SinkConfig{
+		Endpoints: []EndpointConfig{
+			{URL: all.URL, Secret: "secret-1"},
+			{URL: noise.URL, Tags: []byte{0x33}, Headers: map[string]string{"Authorization": "Bearer token"}},
+		},
+		PassThrough: true,
+	})
+	assert.NoError(t, err)
+	defer sink.Close()
+
+	res, err := sink.Handler(0x33)(context.Background(), []byte(`{"noise":42}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte(`{"noise":42}`), res)
+	assert.NoError(t, sink.Publish(context.Background(), 0x34, "tid-2", []byte(`{"sound":1}`)))
+
+	// the throttled and unavailable deliveries are retried.
+	assert.Equal(t, []string{`{"noise":42}`, `{"sound":1}`}, all.received())
+	assert.Equal(t, "52", all.headers[1].Get(HeaderTag))
+	assert.Equal(t, "tid-2", all.headers[1].Get(HeaderTransactionID))
+	assert.Equal(t, serde.JSON, all.headers[1].Get("Content-Type"))
+
+	assert.Equal(t, []string{`{"noise":42}`}, noise.received())
+	assert.Equal(t, "51", noise.headers[0].Get(HeaderTag))
+	assert.Equal(t, "Bearer token", noise.headers[0].Get("Authorization"))
+	assert.Empty(t, noise.headers[0].Get(HeaderSignature))
+}
+
+func TestSinkDeadLetter(t *testing.T) {
+	rejected := newReceiver(t, "", http.StatusBadRequest)
+	healthy := newReceiver(t, "")
+
+	// the error is returned without the dead letter file.
+	sink, err := NewSink(SinkConfig{Endpoints: []EndpointConfig{{URL: rejected.URL}, {URL: healthy.URL}}})
+	assert.NoError(t, err)
+	err = sink.Publish(context.Background(), 0x33, "tid-1", []byte("data-1"))
+	assert.EqualError(t, err, "webhook: status 400 of posting to "+rejected.URL)
+	assert.NoError(t, sink.Close())
+
+	file := filepath.Join(t.TempDir(), "dead.jsonl")
+	rejected = newReceiver(t, "", http.StatusBadRequest, http.StatusInternalServerError, http.StatusInternalServerError)
+	sink, err = NewSink(SinkConfig{
+		Endpoints:  []EndpointConfig{{URL: rejected.URL}, {URL: healthy.URL}},
+		MaxRetries: 1,
+		DeadLetter: file,
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, sink.Publish(context.Background(), 0x33, "tid-2", []byte("data-2")))
+	assert.NoError(t, sink.Publish(context.Background(), 0x34, "tid-3", []byte("data-3")))
+	assert.NoError(t, sink.Publish(context.Background(), 0x35, "tid-4", []byte("data-4")))
+	assert.NoError(t, sink.Close())
+
+	// the failed deliveries are in the dead letter file, the other endpoints still receive them.
+	assert.Equal(t, []string{"data-1", "data-2", "data-3", "data-4"}, healthy.received())
+	assert.Equal(t, []string{"data-4"}, rejected.received())
+	f, err := os.Open(file)
+	assert.NoError(t, err)
+	defer f.Close()
+	var letters []DeadLetter
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var letter DeadLetter
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &letter))
+		letters = append(letters, letter)
+	}
+	if assert.Len(t, letters, 2) {
+		assert.Equal(t, rejected.URL, letters[0].URL)
+		assert.Equal(t, byte(0x33), letters[0].Tag)
+		assert.Equal(t, "tid-2", letters[0].TransactionID)
+		assert.Equal(t, http.StatusBadRequest, letters[0].Status)
+		assert.Equal(t, []byte("data-2"), letters[0].Data)
+		assert.Equal(t, http.StatusInternalServerError, letters[1].Status)
+		assert.Equal(t, "tid-3", letters[1].TransactionID)
+	}
+}
+
+func TestNewSink(t *testing.T) {
+	for _, conf := range []SinkConfig{
+		{},
+		{Endpoints: []EndpointConfig{{URL: "ftp://example.com"}}},
+		{Endpoints: []EndpointConfig{{URL: "http://example.com"}}, DeadLetter: filepath.Join(t.TempDir(), "none", "dead.jsonl")},
+	} {
+		_, err := NewSink(conf)
+		assert.Error(t, err)
+	}
+}
@@ -0,0 +1,7 @@
+// Package websocket pushes the results of stream functions to the web dashboards. The Sink is an http.Handler which
+// accepts the WebSocket connections of RFC 6455, and fans the data observed by the stream function out to the
+// connected clients whose tag filters match its data tag, e.g. "ws://host/stream?tags=0x33,0x34".
+//
+// The delivery is best effort, the clients only receive the data since they connected, and the clients which can't
+// keep up are disconnected, so they don't hold the memory of YoMo.
+package websocket
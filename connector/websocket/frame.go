@@ -0,0 +1,134 @@
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// the opcodes of WebSocket frames.
+const (
+	opContinuation byte = 0x0
+	opText         byte = 0x1
+	opBinary       byte = 0x2
+	opClose        byte = 0x8
+	opPing         byte = 0x9
+	opPong         byte = 0xa
+)
+
+// the status codes of close frames.
+const (
+	closeNormal    uint16 = 1000
+	closeGoingAway uint16 = 1001
+	closeProtocol  uint16 = 1002
+	closeTooBig    uint16 = 1009
+	closeTryLater  uint16 = 1013
+)
+
+const (
+	finBit  byte = 0x80
+	maskBit byte = 0x80
+	// maxControlPayload is the max payload length of control frames.
+	maxControlPayload = 125
+)
+
+// errTooBig is the error when the frame exceeds the max size.
+var errTooBig = errors.New("websocket: the frame exceeds the max size")
+
+// acceptGUID is the GUID of RFC 6455 which the accept key is computed with.
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// acceptKey computes the value of Sec-WebSocket-Accept from Sec-WebSocket-Key.
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// frame is a WebSocket frame.
+type frame struct {
+	fin     bool
+	opcode  byte
+	payload []byte
+}
+
+// readFrame reads a frame, the payload is unmasked. The frames of clients must be masked, the payload length is
+// limited by maxSize.
+func readFrame(r *bufio.Reader, masked bool, maxSize int) (frame, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return frame{}, err
+	}
+	f := frame{fin: header[0]&finBit != 0, opcode: header[0] & 0x0f}
+	if header[0]&0x70 != 0 {
+		return f, errors.New("websocket: the reserved bits are set")
+	}
+	if (header[1]&maskBit != 0) != masked {
+		return f, errors.New("websocket: the masking of frame is invalid")
+	}
+
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return f, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return f, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if f.opcode >= opClose && (length > maxControlPayload || !f.fin) {
+		return f, errors.New("websocket: the control frame is invalid")
+	}
+	if length > uint64(maxSize) {
+		return f, errTooBig
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(r, mask[:]); err != nil {
+			return f, err
+		}
+	}
+	f.payload = make([]byte, length)
+	if _, err := io.ReadFull(r, f.payload); err != nil {
+		return f, err
+	}
+	if masked {
+		for i := range f.payload {
+			f.payload[i] ^= mask[i%4]
+		}
+	}
+	return f, nil
+}
+
+// encodeFrame encodes the final frame of the server, which is not masked.
+func encodeFrame(opcode byte, payload []byte) []byte {
+	buf := make([]byte, 0, len(payload)+10)
+	buf = append(buf, finBit|opcode)
+	switch n := len(payload); {
+	case n <= 125:
+		buf = append(buf, byte(n))
+	case n <= 0xffff:
+		buf = append(buf, 126, byte(n>>8), byte(n))
+	default:
+		buf = append(buf, 127)
+		for i := 7; i >= 0; i-- {
+			buf = append(buf, byte(uint64(n)>>(8*i)))
+		}
+	}
+	return append(buf, payload...)
+}
+
+// closePayload encodes the payload of close frame with the status code.
+func closePayload(code uint16, reason string) []byte {
+	return append([]byte{byte(code >> 8), byte(code)}, reason...)
+}
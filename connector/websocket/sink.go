@@ -0,0 +1,357 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/yomorun/yomo/logger"
+	"github.com/yomorun/yomo/streamfunction"
+)
+
+const (
+	// DefaultBufferSize is the default count of messages buffered for each client.
+	DefaultBufferSize = 64
+	// DefaultPingInterval is the default interval of pings to the clients.
+	DefaultPingInterval = 30 * time.Second
+	// DefaultMaxMessageSize is the default max size in bytes of the messages from the clients.
+	DefaultMaxMessageSize = 4096
+	// writeTimeout is the timeout of writing a message to a client.
+	writeTimeout = 10 * time.Second
+	// closeTimeout is the timeout of writing the close frame to a client.
+	closeTimeout = time.Second
+)
+
+// SinkConfig represents the config of WebSocket sink.
+type SinkConfig struct {
+	// AllowedOrigins are the origins of the web pages which may connect, e.g. "https://dashboard.example.com", and
+	// "*" allows any origin. Only the pages of the same host are allowed if it's empty.
+	AllowedOrigins []string `yaml:"allowed_origins,omitempty"`
+	// BufferSize is the count of messages buffered for each client, the client is disconnected when its buffer is
+	// full, the default is 64.
+	BufferSize int `yaml:"buffer_size,omitempty"`
+	// PingInterval is the interval of pings to the clients, the client is disconnected if it doesn't respond in
+	// two intervals, the default is 30s.
+	PingInterval time.Duration `yaml:"ping_interval,omitempty"`
+	// MaxMessageSize is the max size in bytes of the messages from the clients, the default is 4KB.
+	MaxMessageSize int `yaml:"max_message_size,omitempty"`
+	// Envelope wraps the data in the JSON of Message, so the clients know the data tag and the transaction of data.
+	Envelope bool `yaml:"envelope,omitempty"`
+	// PassThrough responds the data after it's pushed, so the next stream functions receive it too.
+	PassThrough bool `yaml:"pass_through,omitempty"`
+}
+
+// Message is the envelope of the data pushed to the clients, its payload is encoded in base64 in JSON.
+type Message struct {
+	Tag           byte   `json:"tag"`
+	TransactionID string `json:"tid,omitempty"`
+	Payload       []byte `json:"payload"`
+}
+
+// Sink pushes the data of stream function to the connected WebSocket clients. The clients filter the data tags by
+// the query of tags, e.g. "?tags=0x33,52", they receive all the data if it's empty.
+type Sink struct {
+	conf    SinkConfig
+	mutex   sync.RWMutex
+	clients map[*client]struct{}
+	closed  bool
+}
+
+// NewSink creates a WebSocket sink, it's mounted on an HTTP server, e.g. http.Handle("/stream", sink).
+func NewSink(conf SinkConfig) *Sink {
+	if conf.BufferSize <= 0 {
+		conf.BufferSize = DefaultBufferSize
+	}
+	if conf.PingInterval <= 0 {
+		conf.PingInterval = DefaultPingInterval
+	}
+	if conf.MaxMessageSize <= 0 {
+		conf.MaxMessageSize = DefaultMaxMessageSize
+	}
+	return &Sink{conf: conf, clients: make(map[*client]struct{})}
+}
+
+// ServeHTTP upgrades the request to WebSocket and pushes the data to the client until it disconnects.
+func (s *Sink) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet ||
+		!headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") ||
+		r.Header.Get("Sec-WebSocket-Key") == "" {
+		http.Error(w, "websocket: not a websocket handshake", http.StatusBadRequest)
+		return
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "websocket: unsupported version", http.StatusUpgradeRequired)
+		return
+	}
+	if !s.allowOrigin(r) {
+		http.Error(w, "websocket: the origin is not allowed", http.StatusForbidden)
+		return
+	}
+	tags, err := parseTags(r.URL.Query()["tags"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket: the connection can't be hijacked", http.StatusInternalServerError)
+		return
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		logger.Error("[websocket] hijack the connection failed.", "remote", r.RemoteAddr, "err", err)
+		return
+	}
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(r.Header.Get("Sec-WebSocket-Key")) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return
+	}
+
+	c := &client{
+		sink: s,
+		conn: conn,
+		r:    rw.Reader,
+		tags: tags,
+		send: make(chan []byte, s.conf.BufferSize),
+		done: make(chan struct{}),
+	}
+	if !s.add(c) {
+		c.close(closeGoingAway, "the server is closed")
+		return
+	}
+	logger.Debug("[websocket] the client is connected.", "remote", r.RemoteAddr, "tags", r.URL.Query()["tags"])
+
+	go c.writeLoop(s.conf.PingInterval)
+	c.readLoop(s.conf.PingInterval, s.conf.MaxMessageSize)
+}
+
+// Publish pushes the data with the tag to the clients whose filters match the tag, the clients which can't
+// keep up are disconnected.
+func (s *Sink) Publish(tag byte, tid string, data []byte) error {
+	opcode, payload := opBinary, data
+	if s.conf.Envelope {
+		buf, err := json.Marshal(Message{Tag: tag, TransactionID: tid, Payload: data})
+		if err != nil {
+			return err
+		}
+		opcode, payload = opText, buf
+	} else if utf8.Valid(data) {
+		opcode = opText
+	}
+	msg := encodeFrame(opcode, payload)
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	for c := range s.clients {
+		if c.tags != nil && !c.tags[tag] {
+			continue
+		}
+		select {
+		case c.send <- msg:
+		default:
+			logger.Warn("[websocket] the client is too slow, disconnect it.", "remote", c.conn.RemoteAddr().String())
+			go c.close(closeTryLater, "the client is too slow")
+		}
+	}
+	return nil
+}
+
+// Handler returns the handler of stream function which pushes the data observed by the tag, e.g.
+// sfn.PipeFunc(0x33, 0x34, sink.Handler(0x33)).
+func (s *Sink) Handler(tag byte) streamfunction.Handler {
+	return func(ctx context.Context, payload []byte) ([]byte, error) {
+		if err := s.Publish(tag, streamfunction.TransactionID(ctx), payload); err != nil {
+			return nil, err
+		}
+		if s.conf.PassThrough {
+			return payload, nil
+		}
+		return nil, nil
+	}
+}
+
+// Clients returns the count of connected clients.
+func (s *Sink) Clients() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return len(s.clients)
+}
+
+// Close disconnects all the clients, and the new clients are refused.
+func (s *Sink) Close() error {
+	s.mutex.Lock()
+	s.closed = true
+	clients := make([]*client, 0, len(s.clients))
+	for c := range s.clients {
+		clients = append(clients, c)
+	}
+	s.mutex.Unlock()
+
+	for _, c := range clients {
+		c.close(closeGoingAway, "the server is closed")
+	}
+	return nil
+}
+
+func (s *Sink) add(c *client) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closed {
+		return false
+	}
+	s.clients[c] = struct{}{}
+	return true
+}
+
+func (s *Sink) remove(c *client) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.clients, c)
+}
+
+// allowOrigin checks the Origin of request, the clients other than the browsers may have no Origin.
+func (s *Sink) allowOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if len(s.conf.AllowedOrigins) == 0 {
+		u, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(u.Host, r.Host)
+	}
+	for _, allowed := range s.conf.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTags parses the tag filters of the query in decimal or hexadecimal, nil is all the tags.
+func parseTags(values []string) (map[byte]bool, error) {
+	var tags map[byte]bool
+	for _, value := range values {
+		for _, v := range strings.Split(value, ",") {
+			v = strings.TrimSpace(v)
+			if v == "" {
+				continue
+			}
+			tag, err := strconv.ParseUint(v, 0, 8)
+			if err != nil {
+				return nil, errors.New("websocket: invalid tag " + v)
+			}
+			if tags == nil {
+				tags = make(map[byte]bool)
+			}
+			tags[byte(tag)] = true
+		}
+	}
+	return tags, nil
+}
+
+// headerContains checks if the comma-separated header has the token case-insensitively.
+func headerContains(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, v := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(v), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// client is a connected WebSocket client.
+type client struct {
+	sink      *Sink
+	conn      net.Conn
+	r         *bufio.Reader
+	tags      map[byte]bool
+	send      chan []byte
+	done      chan struct{}
+	writeLock sync.Mutex
+	closeOnce sync.Once
+}
+
+// writeLoop writes the messages and the pings until the client is closed.
+func (c *client) writeLoop(pingInterval time.Duration) {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	for {
+		var msg []byte
+		select {
+		case <-c.done:
+			return
+		case msg = <-c.send:
+		case <-ticker.C:
+			msg = encodeFrame(opPing, nil)
+		}
+		if err := c.write(msg, writeTimeout); err != nil {
+			c.close(closeGoingAway, "")
+			return
+		}
+	}
+}
+
+// readLoop reads the frames of client for the control frames, the data frames are discarded. The client is closed
+// if nothing is received in two ping intervals.
+func (c *client) readLoop(pingInterval time.Duration, maxSize int) {
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(2 * pingInterval))
+		f, err := readFrame(c.r, true, maxSize)
+		if err != nil {
+			code := closeProtocol
+			if errors.Is(err, errTooBig) {
+				code = closeTooBig
+			}
+			c.close(code, "")
+			return
+		}
+		switch f.opcode {
+		case opClose:
+			c.close(closeNormal, "")
+			return
+		case opPing:
+			if err := c.write(encodeFrame(opPong, f.payload), writeTimeout); err != nil {
+				c.close(closeGoingAway, "")
+				return
+			}
+		}
+	}
+}
+
+// write writes the encoded frame in the timeout, it's safe for concurrent use.
+func (c *client) write(msg []byte, timeout time.Duration) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(timeout))
+	_, err := c.conn.Write(msg)
+	return err
+}
+
+// close sends the close frame with the status code and closes the connection.
+func (c *client) close(code uint16, reason string) {
+	c.closeOnce.Do(func() {
+		c.sink.remove(c)
+		close(c.done)
+		// the pending write to a slow client is interrupted soon.
+		c.conn.SetWriteDeadline(time.Now().Add(closeTimeout))
+		c.write(encodeFrame(opClose, closePayload(code, reason)), closeTimeout)
+		c.conn.Close()
+		logger.Debug("[websocket] the client is disconnected.", "remote", c.conn.RemoteAddr().String(), "code", code)
+	})
+}
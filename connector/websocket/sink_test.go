@@ -0,0 +1,171 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testClient is a WebSocket client for the tests.
+type testClient struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func dial(t *testing.T, server *httptest.Server, query string) *testClient {
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	assert.NoError(t, err)
+
+	key := "dGhlIHNhbXBsZSBub25jZQ=="
+	req := "GET /stream" + query + " HTTP/1.1\r\nHost: " + server.Listener.Addr().String() + "\r\n" +
+		"Upgrade: websocket\r\nConnection: keep-alive, Upgrade\r\nSec-WebSocket-Version: 13\r\n" +
+		"Sec-WebSocket-Key: " + key + "\r\n\r\n"
+	_, err = conn.Write([]byte(req))
+	assert.NoError(t, err)
+
+	r := bufio.NewReader(conn)
+	res, err := http.ReadResponse(r, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusSwitchingProtocols, res.StatusCode)
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", res.Header.Get("Sec-WebSocket-Accept"))
+	return &testClient{conn: conn, r: r}
+}
+
+func (c *testClient) read(t *testing.T) frame {
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	f, err := readFrame(c.r, false, 1<<20)
+	assert.NoError(t, err)
+	return f
+}
+
+// write writes the masked frame of client.
+func (c *testClient) write(opcode byte, payload []byte) {
+	mask := []byte{1, 2, 3, 4}
+	buf := []byte{finBit | opcode, maskBit | byte(len(payload))}
+	buf = append(buf, mask...)
+	for i, b := range payload {
+		buf = append(buf, b^mask[i%4])
+	}
+	c.conn.Write(buf)
+}
+
+func TestSink(t *testing.T) {
+	sink := NewSink(SinkConfig{PassThrough: true})
+	server := httptest.NewServer(sink)
+	defer server.Close()
+
+	all := dial(t, server, "")
+	filtered := dial(t, server, "?tags=0x34,53")
+	assert.Eventually(t, func() bool { return sink.Clients() == 2 }, time.Second, 10*time.Millisecond)
+
+	out, err := sink.Handler(0x33)(context.Background(), []byte("t1"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("t1"), out)
+	assert.NoError(t, sink.Publish(0x34, "", []byte{0xff, 0xfe}))
+
+	assert.Equal(t, frame{fin: true, opcode: opText, payload: []byte("t1")}, all.read(t))
+	assert.Equal(t, frame{fin: true, opcode: opBinary, payload: []byte{0xff, 0xfe}}, all.read(t))
+	assert.Equal(t, frame{fin: true, opcode: opBinary, payload: []byte{0xff, 0xfe}}, filtered.read(t))
+
+	filtered.write(opPing, []byte("hi"))
+	assert.Equal(t, frame{fin: true, opcode: opPong, payload: []byte("hi")}, filtered.read(t))
+
+	filtered.write(opClose, closePayload(closeNormal, ""))
+	assert.Equal(t, frame{fin: true, opcode: opClose, payload: closePayload(closeNormal, "")}, filtered.read(t))
+	assert.Eventually(t, func() bool { return sink.Clients() == 1 }, time.Second, 10*time.Millisecond)
+
+	assert.NoError(t, sink.Close())
+	assert.Equal(t, frame{fin: true, opcode: opClose, payload: closePayload(closeGoingAway, "the server is closed")}, all.read(t))
+	assert.Equal(t, 0, sink.Clients())
+}
+
+func TestSinkEnvelope(t *testing.T) {
+	sink := NewSink(SinkConfig{Envelope: true})
+	server := httptest.NewServer(sink)
+	defer server.Close()
+	defer sink.Close()
+
+	c := dial(t, server, "?tags=51")
+	assert.Eventually(t, func() bool { return sink.Clients() == 1 }, time.Second, 10*time.Millisecond)
+
+	out, err := sink.Handler(0x34)(context.Background(), []byte("t0"))
+	assert.NoError(t, err)
+	assert.Nil(t, out)
+	assert.NoError(t, sink.Publish(0x33, "tid-1", []byte("t1")))
+
+	f := c.read(t)
+	assert.Equal(t, opText, f.opcode)
+	var m Message
+	assert.NoError(t, json.Unmarshal(f.payload, &m))
+	assert.Equal(t, Message{Tag: 0x33, TransactionID: "tid-1", Payload: []byte("t1")}, m)
+}
+
+func TestSinkSlowClient(t *testing.T) {
+	sink := NewSink(SinkConfig{BufferSize: 1})
+	server := httptest.NewServer(sink)
+	defer server.Close()
+
+	c := dial(t, server, "")
+	assert.Eventually(t, func() bool { return sink.Clients() == 1 }, time.Second, 10*time.Millisecond)
+
+	// the client doesn't read, so the buffers of socket are filled.
+	data := bytes.Repeat([]byte("x"), 1<<20)
+	assert.Eventually(t, func() bool {
+		sink.Publish(0x33, "", data)
+		return sink.Clients() == 0
+	}, 10*time.Second, time.Millisecond)
+	c.conn.Close()
+}
+
+func TestSinkHandshake(t *testing.T) {
+	sink := NewSink(SinkConfig{AllowedOrigins: []string{"https://dashboard.example.com"}})
+	server := httptest.NewServer(sink)
+	defer server.Close()
+	defer sink.Close()
+
+	request := func(header map[string]string, query string) int {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+query, nil)
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Sec-WebSocket-Version", "13")
+		req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		res, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	assert.Equal(t, http.StatusBadRequest, request(map[string]string{"Upgrade": "h2c"}, ""))
+	assert.Equal(t, http.StatusUpgradeRequired, request(map[string]string{"Sec-WebSocket-Version": "8"}, ""))
+	assert.Equal(t, http.StatusForbidden, request(map[string]string{"Origin": "https://evil.example.com"}, ""))
+	assert.Equal(t, http.StatusBadRequest, request(nil, "?tags=256"))
+	assert.Equal(t, http.StatusSwitchingProtocols, request(map[string]string{"Origin": "https://dashboard.example.com"}, ""))
+}
+
+func TestReadFrame(t *testing.T) {
+	_, err := readFrame(bufio.NewReader(bytes.NewReader(encodeFrame(opText, []byte("x")))), true, 10)
+	assert.Error(t, err)
+
+	for _, n := range []int{0, 125, 126, 0xffff, 0x10000} {
+		payload := bytes.Repeat([]byte("x"), n)
+		f, err := readFrame(bufio.NewReader(bytes.NewReader(encodeFrame(opBinary, payload))), false, 1<<20)
+		assert.NoError(t, err)
+		assert.Equal(t, n, len(f.payload))
+	}
+
+	_, err = readFrame(bufio.NewReader(bytes.NewReader(encodeFrame(opBinary, make([]byte, 11)))), false, 10)
+	assert.ErrorIs(t, err, errTooBig)
+	assert.True(t, strings.HasPrefix(acceptKey("dGhlIHNhbXBsZSBub25jZQ=="), "s3pPLMBiTxaQ9kYGzzhZRbK"))
+}
@@ -0,0 +1,232 @@
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sync"
+)
+
+// The IDs of codecs, they are carried in the MetaFrame of compressed DataFrames.
+const (
+	// None means the carriage is not compressed.
+	None byte = 0x00
+	// Gzip is the built-in codec of compress/gzip.
+	Gzip byte = 0x01
+	// Zstd is reserved for the Zstandard codec, it's registered by the application.
+	Zstd byte = 0x02
+	// LZ4 is reserved for the LZ4 codec, it's registered by the application.
+	LZ4 byte = 0x03
+)
+
+// DefaultThreshold is the default size in bytes above which the carriage is compressed.
+const DefaultThreshold = 1024
+
+// DefaultMaxSize is the default max size in bytes of the decompressed data.
+const DefaultMaxSize = 64 << 20
+
+var (
+	// ErrUnknownCodec is returned when the carriage is compressed by a codec which isn't registered.
+	ErrUnknownCodec = errors.New("compress: unknown codec")
+	// ErrTooLarge is returned when the decompressed data exceeds the max size.
+	ErrTooLarge = errors.New("compress: the decompressed data is too large")
+)
+
+// Codec compresses and decompresses the carriage of DataFrames.
+type Codec interface {
+	// ID is the identity of codec in the MetaFrame.
+	ID() byte
+	// Name is the name of codec in the handshake, e.g. "zstd".
+	Name() string
+	// Compress the data.
+	Compress(data []byte) ([]byte, error)
+	// Decompress the data.
+	Decompress(data []byte) ([]byte, error)
+}
+
+// LimitedDecompressor is implemented by the codecs which stop decompressing as soon as the output exceeds
+// the limit, so a small compressed bomb can't exhaust the memory.
+type LimitedDecompressor interface {
+	// DecompressLimit decompresses the data, ErrTooLarge is returned if the output exceeds limit.
+	DecompressLimit(data []byte, limit int) ([]byte, error)
+}
+
+// StreamCodec is implemented by the codecs which compress the streamed carriages, which are not held in memory.
+// The streamed carriages are sent uncompressed if the negotiated codec doesn't implement it.
+type StreamCodec interface {
+	// NewWriter returns the writer which compresses the data to w, it must be closed to flush the data.
+	NewWriter(w io.Writer) io.WriteCloser
+	// NewReader returns the reader which decompresses the data from r.
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+// Decompress the data by the codec, ErrTooLarge is returned if the output exceeds limit. The codecs which don't
+// implement LimitedDecompressor are checked after decompressing, DefaultMaxSize is used if limit is zero.
+func Decompress(codec Codec, data []byte, limit int) ([]byte, error) {
+	if limit <= 0 {
+		limit = DefaultMaxSize
+	}
+	if d, ok := codec.(LimitedDecompressor); ok {
+		return d.DecompressLimit(data, limit)
+	}
+
+	buf, err := codec.Decompress(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(buf) > limit {
+		return nil, ErrTooLarge
+	}
+	return buf, nil
+}
+
+var codecs = sync.Map{}
+
+func init() {
+	Register(gzipCodec{})
+}
+
+// Register a codec, the codec with the same ID is replaced. Only gzip is built in, the IDs of zstd and lz4 are
+// reserved for the codecs registered by the application, e.g.
+//
+//	compress.Register(compress.NewCodec(compress.Zstd, "zstd", encoder.EncodeAll, decoder.DecodeAll))
+//
+// The decoder should bound its memory, e.g. zstd.WithDecoderMaxMemory, since the output is checked against
+// the max size after decompressing. The codec must be registered in the clients and YoMo-Zipper on both ends.
+func Register(codec Codec) {
+	codecs.Store(codec.ID(), codec)
+}
+
+// Lookup returns the codec by ID.
+func Lookup(id byte) (Codec, bool) {
+	v, ok := codecs.Load(id)
+	if !ok {
+		return nil, false
+	}
+	return v.(Codec), true
+}
+
+// LookupName returns the codec by name.
+func LookupName(name string) (Codec, bool) {
+	var codec Codec
+	codecs.Range(func(_, v interface{}) bool {
+		if v.(Codec).Name() == name {
+			codec = v.(Codec)
+			return false
+		}
+		return true
+	})
+	return codec, codec != nil
+}
+
+// Names returns the names of the registered codecs in the order of preference, the reserved zstd and lz4 codecs
+// are preferred when they're registered.
+func Names() []string {
+	names := make([]string, 0)
+	for _, id := range []byte{Zstd, LZ4, Gzip} {
+		if codec, ok := Lookup(id); ok {
+			names = append(names, codec.Name())
+		}
+	}
+	codecs.Range(func(k, v interface{}) bool {
+		if id := k.(byte); id != Zstd && id != LZ4 && id != Gzip {
+			names = append(names, v.(Codec).Name())
+		}
+		return true
+	})
+	return names
+}
+
+// Negotiate returns the first codec in the offered names which is registered, ok is false if none is registered.
+func Negotiate(offered []string) (Codec, bool) {
+	for _, name := range offered {
+		if codec, ok := LookupName(name); ok {
+			return codec, true
+		}
+	}
+	return nil, false
+}
+
+// NewCodec creates a codec by the compress and decompress functions, it's the simplest way to plug in
+// a third-party library, the functions follow the signatures of the zstd EncodeAll and DecodeAll.
+func NewCodec(id byte, name string, compress func(src, dst []byte) []byte, decompress func(src, dst []byte) ([]byte, error)) Codec {
+	return &funcCodec{id: id, name: name, compress: compress, decompress: decompress}
+}
+
+type funcCodec struct {
+	id         byte
+	name       string
+	compress   func(src, dst []byte) []byte
+	decompress func(src, dst []byte) ([]byte, error)
+}
+
+func (c *funcCodec) ID() byte {
+	return c.id
+}
+
+func (c *funcCodec) Name() string {
+	return c.name
+}
+
+func (c *funcCodec) Compress(data []byte) ([]byte, error) {
+	return c.compress(data, nil), nil
+}
+
+func (c *funcCodec) Decompress(data []byte) ([]byte, error) {
+	return c.decompress(data, nil)
+}
+
+// gzipCodec is the codec of compress/gzip.
+type gzipCodec struct{}
+
+func (gzipCodec) ID() byte {
+	return Gzip
+}
+
+func (gzipCodec) Name() string {
+	return "gzip"
+}
+
+func (gzipCodec) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c gzipCodec) Decompress(data []byte) ([]byte, error) {
+	return c.DecompressLimit(data, DefaultMaxSize)
+}
+
+func (gzipCodec) DecompressLimit(data []byte, limit int) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	// one more byte is read to tell if the output exceeds the limit.
+	buf, err := ioutil.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(buf) > limit {
+		return nil, ErrTooLarge
+	}
+	return buf, nil
+}
+
+func (gzipCodec) NewWriter(w io.Writer) io.WriteCloser {
+	return gzip.NewWriter(w)
+}
+
+func (gzipCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
@@ -0,0 +1,83 @@
+package compress
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGzip(t *testing.T) {
+	codec, ok := LookupName("gzip")
+	assert.True(t, ok)
+	assert.Equal(t, Gzip, codec.ID())
+
+	data := bytes.Repeat([]byte(`{"noise":42}`), 100)
+	buf, err := codec.Compress(data)
+	assert.NoError(t, err)
+	assert.Less(t, len(buf), len(data))
+
+	decompressed, err := codec.Decompress(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, data, decompressed)
+}
+
+func TestGzipStream(t *testing.T) {
+	codec, _ := Lookup(Gzip)
+	sc, ok := codec.(StreamCodec)
+	assert.True(t, ok)
+
+	data := bytes.Repeat([]byte(`{"noise":42}`), 100)
+	var buf bytes.Buffer
+	w := sc.NewWriter(&buf)
+	_, err := w.Write(data)
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+
+	// the streamed carriage is compatible with the buffered one.
+	decompressed, err := codec.Decompress(buf.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, data, decompressed)
+
+	r, err := sc.NewReader(&buf)
+	assert.NoError(t, err)
+	decompressed, err = ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, data, decompressed)
+}
+
+func TestDecompressLimit(t *testing.T) {
+	codec, _ := Lookup(Gzip)
+	data := make([]byte, 1<<20)
+	compressed, err := codec.Compress(data)
+	assert.NoError(t, err)
+
+	_, err = Decompress(codec, compressed, 1024)
+	assert.Equal(t, ErrTooLarge, err)
+
+	decompressed, err := Decompress(codec, compressed, len(data))
+	assert.NoError(t, err)
+	assert.Equal(t, data, decompressed)
+}
+
+func TestNegotiate(t *testing.T) {
+	_, ok := Negotiate([]string{"brotli"})
+	assert.False(t, ok)
+
+	reverse := func(src, dst []byte) []byte {
+		for i := len(src) - 1; i >= 0; i-- {
+			dst = append(dst, src[i])
+		}
+		return dst
+	}
+	Register(NewCodec(LZ4, "lz4", reverse, func(src, dst []byte) ([]byte, error) {
+		return reverse(src, dst), nil
+	}))
+	defer codecs.Delete(LZ4)
+
+	assert.Equal(t, []string{"lz4", "gzip"}, Names())
+	codec, ok := Negotiate([]string{"zstd", "lz4", "gzip"})
+	assert.True(t, ok)
+	assert.Equal(t, "lz4", codec.Name())
+}
@@ -0,0 +1,4 @@
+// Package compress manages the codecs which compress the carriage of DataFrames.
+// The codecs are negotiated in the handshake, the ID of codec is carried in the MetaFrame,
+// so the receivers decompress the carriage transparently.
+package compress
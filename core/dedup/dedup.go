@@ -0,0 +1,86 @@
+// Package dedup detects the duplicated frames by the TransactionID and the hash of content in a time window,
+// so the retries and replays upstream don't cause duplicate side effects in the sinks.
+package dedup
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"sync"
+	"time"
+)
+
+// key is the identity of a frame.
+type key struct {
+	tid  string
+	hash [sha256.Size]byte
+}
+
+type entry struct {
+	key    key
+	expire time.Time
+}
+
+// Window remembers the frames within the TTL, at most size frames are remembered.
+type Window struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	size    int
+	entries map[key]*list.Element
+	order   *list.List // order is the entries in the order of insertion, the oldest is in the front.
+	now     func() time.Time
+}
+
+// New creates a new dedup Window.
+func New(ttl time.Duration, size int) *Window {
+	return &Window{
+		ttl:     ttl,
+		size:    size,
+		entries: make(map[key]*list.Element),
+		order:   list.New(),
+		now:     time.Now,
+	}
+}
+
+// Seen returns true if the frame with the same TransactionID and content was seen in the window,
+// otherwise the frame is remembered and false is returned.
+func (w *Window) Seen(tid string, data []byte) bool {
+	k := key{tid: tid, hash: sha256.Sum256(data)}
+	now := w.now()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.evict(now)
+
+	if _, ok := w.entries[k]; ok {
+		return true
+	}
+
+	w.entries[k] = w.order.PushBack(&entry{key: k, expire: now.Add(w.ttl)})
+	if w.size > 0 && w.order.Len() > w.size {
+		w.remove(w.order.Front())
+	}
+	return false
+}
+
+// Len returns the count of frames in the window.
+func (w *Window) Len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.order.Len()
+}
+
+// evict the expired entries.
+func (w *Window) evict(now time.Time) {
+	for e := w.order.Front(); e != nil; e = w.order.Front() {
+		if e.Value.(*entry).expire.After(now) {
+			return
+		}
+		w.remove(e)
+	}
+}
+
+func (w *Window) remove(e *list.Element) {
+	w.order.Remove(e)
+	delete(w.entries, e.Value.(*entry).key)
+}
@@ -0,0 +1,29 @@
+package dedup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWindowSeen(t *testing.T) {
+	now := time.Now()
+	w := New(time.Minute, 2)
+	w.now = func() time.Time { return now }
+
+	assert.False(t, w.Seen("1", []byte("a")))
+	assert.True(t, w.Seen("1", []byte("a")))
+	// the same TransactionID with different content.
+	assert.False(t, w.Seen("1", []byte("b")))
+	assert.Equal(t, 2, w.Len())
+
+	// the oldest is evicted when the window is full.
+	assert.False(t, w.Seen("2", []byte("a")))
+	assert.False(t, w.Seen("1", []byte("a")))
+
+	// the entries are expired after ttl.
+	now = now.Add(2 * time.Minute)
+	assert.False(t, w.Seen("2", []byte("a")))
+	assert.Equal(t, 1, w.Len())
+}
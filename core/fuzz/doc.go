@@ -0,0 +1,17 @@
+// Package fuzz provides the fuzz targets of the frame parser and the pluggable codecs, in the signature of
+// go-fuzz, so the applications which embed the parser or register their own codecs harden them, e.g.
+//
+//	// +build gofuzz
+//
+//	package mycodec
+//
+//	func Fuzz(data []byte) int {
+//		return fuzz.Codec(NewCodec())(data)
+//	}
+//
+// Since Go 1.18 the targets can be called in the native fuzz tests as well, e.g.
+//
+//	f.Fuzz(func(t *testing.T, data []byte) { fuzz.Frame(data) })
+//
+// The targets panic when an invariant is broken, the fuzzer reports the panics as crashes.
+package fuzz
@@ -0,0 +1,62 @@
+package fuzz
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/yomorun/yomo/core/compress"
+	"github.com/yomorun/yomo/core/serde"
+	"github.com/yomorun/yomo/internal/core"
+)
+
+// maxFrameSize is the limit of the frames parsed by the fuzz target, the fuzzers generate small inputs.
+const maxFrameSize = 1 << 20
+
+// Frame is the fuzz target of the frame parser, it returns 1 if data is parsed as a frame, 0 otherwise.
+// The frame parsed should be parsed again from its encoding.
+func Frame(data []byte) int {
+	f, err := core.ParseFrameWithLimit(bytes.NewReader(data), maxFrameSize)
+	if err != nil {
+		return 0
+	}
+
+	if _, err := core.ParseFrameWithLimit(bytes.NewReader(f.Encode()), maxFrameSize); err != nil {
+		panic(fmt.Sprintf("fuzz: the encoded %s can't be parsed: %v", f.Type(), err))
+	}
+	return 1
+}
+
+// Codec returns the fuzz target of the compression codec, the codec should decompress the arbitrary data
+// without panics, and the data compressed should be decompressed to the same bytes.
+func Codec(codec compress.Codec) func(data []byte) int {
+	return func(data []byte) int {
+		compressed, err := codec.Compress(data)
+		if err != nil {
+			panic(fmt.Sprintf("fuzz: %s compresses %d bytes failed: %v", codec.Name(), len(data), err))
+		}
+		decompressed, err := codec.Decompress(compressed)
+		if err != nil || !bytes.Equal(data, decompressed) {
+			panic(fmt.Sprintf("fuzz: %s doesn't decompress the data it compressed, err: %v", codec.Name(), err))
+		}
+
+		if _, err := codec.Decompress(data); err != nil {
+			return 0
+		}
+		return 1
+	}
+}
+
+// Serializer returns the fuzz target of the serializer, the serializer should unmarshal the arbitrary data
+// into the value which factory creates without panics, and the value unmarshaled should be marshaled again.
+func Serializer(serializer serde.Serializer, factory func() interface{}) func(data []byte) int {
+	return func(data []byte) int {
+		v := factory()
+		if err := serializer.Unmarshal(data, v); err != nil {
+			return 0
+		}
+		if _, err := serializer.Marshal(v); err != nil {
+			panic(fmt.Sprintf("fuzz: %s doesn't marshal the value it unmarshaled: %v", serializer.ContentType(), err))
+		}
+		return 1
+	}
+}
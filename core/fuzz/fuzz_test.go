@@ -0,0 +1,44 @@
+package fuzz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/core/compress"
+	"github.com/yomorun/yomo/core/serde"
+	"github.com/yomorun/yomo/internal/frame"
+)
+
+func TestFrame(t *testing.T) {
+	data := frame.NewDataFrame("1234")
+	data.SetCarriage(0x10, []byte("yomo"))
+	assert.Equal(t, 1, Frame(data.Encode()))
+	assert.Equal(t, 1, Frame(frame.NewPingFrame().Encode()))
+
+	for _, buf := range [][]byte{
+		nil,
+		{0xFF},
+		{0x80 | byte(frame.TagOfDataFrame), 0x87, 0xFF, 0xFF, 0x7F},
+		data.Encode()[:6],
+	} {
+		assert.Equal(t, 0, Frame(buf))
+	}
+}
+
+func TestCodec(t *testing.T) {
+	gzip, ok := compress.Lookup(compress.Gzip)
+	assert.True(t, ok)
+
+	target := Codec(gzip)
+	assert.Equal(t, 0, target([]byte("not gzip")))
+	assert.NotPanics(t, func() { target(nil) })
+}
+
+func TestSerializer(t *testing.T) {
+	json, ok := serde.Lookup(serde.JSON)
+	assert.True(t, ok)
+
+	target := Serializer(json, func() interface{} { return &map[string]interface{}{} })
+	assert.Equal(t, 1, target([]byte(`{"noise":1}`)))
+	assert.Equal(t, 0, target([]byte(`{"noise"`)))
+}
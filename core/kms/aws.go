@@ -0,0 +1,57 @@
+package kms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/yomorun/yomo/internal/sigv4"
+)
+
+// NewAWSDecrypter creates a DecryptFunc which decrypts the ciphertext by the Decrypt API of AWS KMS in the region,
+// the region is the environment variable AWS_REGION if it's empty, and the endpoint is the endpoint of region if
+// it's empty, e.g. the LocalStack endpoint. The credentials are the environment variables AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN.
+func NewAWSDecrypter(region string, endpoint string) DecryptFunc {
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	if endpoint == "" {
+		endpoint = "https://kms." + region + ".amazonaws.com"
+	}
+	endpoint = strings.TrimSuffix(endpoint, "/") + "/"
+	creds := sigv4.FromEnv()
+
+	return func(ctx context.Context, ciphertext []byte) ([]byte, error) {
+		body, _ := json.Marshal(map[string][]byte{"CiphertextBlob": ciphertext})
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+		req.Header.Set("X-Amz-Target", "TrentService.Decrypt")
+		sigv4.Sign(req, creds, region, "kms", sigv4.PayloadHash(body), time.Now())
+
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("kms: decrypt by aws kms failed, status: %s", res.Status)
+		}
+		var decrypted struct {
+			Plaintext []byte `json:"Plaintext"`
+		}
+		if err = json.NewDecoder(res.Body).Decode(&decrypted); err != nil {
+			return nil, err
+		}
+		return decrypted.Plaintext, nil
+	}
+}
@@ -0,0 +1,53 @@
+package kms
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+)
+
+// ErrCiphertext is returned when the ciphertext is too short or it fails to be authenticated.
+var ErrCiphertext = errors.New("kms: invalid ciphertext")
+
+// Seal encrypts and authenticates the plaintext by AES-GCM with the key, the material of key must be 16, 24 or
+// 32 bytes for AES-128, AES-192 or AES-256. The random nonce is prepended to the ciphertext, and the
+// additional data is authenticated but not encrypted, e.g. the transaction ID of the frame.
+func Seal(k Key, plaintext, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(k)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// Open decrypts and authenticates the ciphertext which is sealed by Seal with the same key and additional data.
+func Open(k Key, ciphertext, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(k)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < aead.NonceSize()+aead.Overhead() {
+		return nil, ErrCiphertext
+	}
+	nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, sealed, additionalData)
+	if err != nil {
+		return nil, ErrCiphertext
+	}
+	return plaintext, nil
+}
+
+func newGCM(k Key) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(k.Material)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
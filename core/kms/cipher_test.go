@@ -0,0 +1,32 @@
+package kms
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSealAndOpen(t *testing.T) {
+	k, err := NewKey(32)
+	assert.NoError(t, err)
+
+	ciphertext, err := Seal(k, []byte("hello"), []byte("tid"))
+	assert.NoError(t, err)
+	assert.NotContains(t, string(ciphertext), "hello")
+
+	plaintext, err := Open(k, ciphertext, []byte("tid"))
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(plaintext))
+
+	// the additional data is authenticated.
+	_, err = Open(k, ciphertext, []byte("other"))
+	assert.Equal(t, ErrCiphertext, err)
+
+	// the ciphertext is too short.
+	_, err = Open(k, ciphertext[:8], []byte("tid"))
+	assert.Equal(t, ErrCiphertext, err)
+
+	// the size of key is invalid.
+	_, err = Seal(Key{ID: "k", Material: []byte("short")}, []byte("hello"), nil)
+	assert.Error(t, err)
+}
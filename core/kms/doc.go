@@ -0,0 +1,4 @@
+// Package kms manages the keys used to sign or encrypt the payload of DataFrames.
+// Every key has an ID which is carried in the MetaFrame, so the receivers can always find the key
+// which was used by the sender, even when the keys are rotating.
+package kms
@@ -0,0 +1,54 @@
+package kms
+
+import (
+	"crypto/rand"
+	"errors"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrKeyNotFound is returned when the key ID is unknown by the keyring.
+	ErrKeyNotFound = errors.New("kms: key not found")
+	// ErrKeyExpired is returned when the key is found but it has been expired.
+	ErrKeyExpired = errors.New("kms: key expired")
+	// ErrNoActiveKey is returned when there isn't any key can be used right now.
+	ErrNoActiveKey = errors.New("kms: no active key")
+)
+
+// Key represents a secret which is identified by an ID.
+type Key struct {
+	// ID is the identity of key, it will be written to the MetaFrame.
+	ID string `json:"id"`
+	// Material is the secret bytes of key.
+	Material []byte `json:"material"`
+	// NotBefore is the time from when the key will be used to sign/encrypt the new data.
+	NotBefore time.Time `json:"not_before"`
+	// NotAfter is the time after which the key can't be used anymore, zero means the key never expires.
+	NotAfter time.Time `json:"not_after,omitempty"`
+}
+
+// NewKey generates a new random key with the given size in bytes, the key is active from now.
+func NewKey(size int) (Key, error) {
+	material := make([]byte, size)
+	if _, err := rand.Read(material); err != nil {
+		return Key{}, err
+	}
+
+	now := time.Now()
+	return Key{
+		ID:        strconv.FormatInt(now.UnixNano(), 36),
+		Material:  material,
+		NotBefore: now,
+	}, nil
+}
+
+// IsExpired indicates whether the key has been expired at time t.
+func (k Key) IsExpired(t time.Time) bool {
+	return !k.NotAfter.IsZero() && !t.Before(k.NotAfter)
+}
+
+// IsActive indicates whether the key can be used to sign/encrypt the new data at time t.
+func (k Key) IsActive(t time.Time) bool {
+	return !t.Before(k.NotBefore) && !k.IsExpired(t)
+}
@@ -0,0 +1,217 @@
+package kms
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yomorun/yomo/logger"
+)
+
+// Keyring holds the keys loaded from a KeyProvider, it always picks the newest active key to sign/encrypt
+// the data, and keeps the previous keys until they are expired so the in-flight data can still be verified/decrypted.
+type Keyring struct {
+	provider    KeyProvider
+	gracePeriod time.Duration
+	mutex       sync.RWMutex
+	keys        map[string]Key
+	now         func() time.Time
+}
+
+// NewKeyring creates a new Keyring with a KeyProvider, the provider can be nil if the keys are managed by Add and Rotate.
+func NewKeyring(provider KeyProvider, opts ...Option) *Keyring {
+	options := newOptions(opts...)
+	return &Keyring{
+		provider:    provider,
+		gracePeriod: options.gracePeriod,
+		keys:        make(map[string]Key),
+		now:         time.Now,
+	}
+}
+
+// Load creates a Keyring with the keys of provider, it fails if the keys can't be loaded. If the refresh interval
+// is set by WithRefreshInterval, the keyring is refreshed in every interval until the ctx is done.
+func Load(ctx context.Context, provider KeyProvider, opts ...Option) (*Keyring, error) {
+	r := NewKeyring(provider, opts...)
+	if err := r.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	if interval := newOptions(opts...).refreshInterval; interval > 0 {
+		go r.Watch(ctx, interval)
+	}
+	return r, nil
+}
+
+// Refresh reloads the keys from provider. The keys without NotBefore are active since they're loaded, so a new key
+// published to provider replaces the current key. The keys which are not in provider anymore are rotated out, they
+// are kept until the grace period is over, so the in-flight data can still be verified/decrypted.
+func (r *Keyring) Refresh(ctx context.Context) error {
+	if r.provider == nil {
+		return nil
+	}
+
+	keys, err := r.provider.Keys(ctx)
+	if err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := r.now()
+	m := make(map[string]Key, len(keys))
+	for _, k := range keys {
+		if k.NotBefore.IsZero() {
+			k.NotBefore = now
+			if prev, ok := r.keys[k.ID]; ok {
+				k.NotBefore = prev.NotBefore
+			}
+		}
+		m[k.ID] = k
+	}
+
+	expiry := now.Add(r.gracePeriod)
+	for id, k := range r.keys {
+		// the scheduled keys which are withdrawn are removed at once.
+		if _, ok := m[id]; ok || k.IsExpired(now) || k.NotBefore.After(now) {
+			continue
+		}
+		if k.NotAfter.IsZero() || k.NotAfter.After(expiry) {
+			k.NotAfter = expiry
+		}
+		m[id] = k
+	}
+	r.keys = m
+	return nil
+}
+
+// Watch reloads the keys from provider in every interval until the ctx is done,
+// so the new keys published to provider will be picked up without restarting.
+func (r *Keyring) Watch(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if err := r.Refresh(ctx); err != nil {
+				logger.Error("[kms] refresh the keyring failed.", "err", err)
+			}
+		}
+	}
+}
+
+// Add a key to the keyring.
+func (r *Keyring) Add(k Key) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.keys[k.ID] = k
+}
+
+// Rotate adds a new key which becomes the current key at k.NotBefore (immediately if it's zero), so a rotation
+// can be scheduled in advance. The previous current key will be expired after the grace period since then,
+// the data signed/encrypted by it can still be handled by the receivers during the grace period.
+func (r *Keyring) Rotate(k Key) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := r.now()
+	if k.NotBefore.IsZero() {
+		k.NotBefore = now
+	}
+
+	expiry := k.NotBefore.Add(r.gracePeriod)
+	if prev, ok := r.current(now); ok && (prev.NotAfter.IsZero() || prev.NotAfter.After(expiry)) {
+		prev.NotAfter = expiry
+		r.keys[prev.ID] = prev
+	}
+	r.keys[k.ID] = k
+}
+
+// Current returns the newest active key, it should be used to sign/encrypt the new data.
+func (r *Keyring) Current() (Key, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	k, ok := r.current(r.now())
+	if !ok {
+		return Key{}, ErrNoActiveKey
+	}
+	return k, nil
+}
+
+// Lookup returns the key by ID, it should be used to verify/decrypt the data with the key ID in MetaFrame.
+func (r *Keyring) Lookup(id string) (Key, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	k, ok := r.keys[id]
+	if !ok {
+		return Key{}, ErrKeyNotFound
+	}
+	if k.IsExpired(r.now()) {
+		return Key{}, ErrKeyExpired
+	}
+	return k, nil
+}
+
+// Keys returns the keys which aren't expired, the newest first, they should be tried in turn to verify the data
+// which doesn't carry the key ID.
+func (r *Keyring) Keys() []Key {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	now := r.now()
+	keys := make([]Key, 0, len(r.keys))
+	for _, k := range r.keys {
+		if !k.IsExpired(now) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return newer(keys[i], keys[j])
+	})
+	return keys
+}
+
+// Purge removes the expired keys from the keyring.
+func (r *Keyring) Purge() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := r.now()
+	for id, k := range r.keys {
+		if k.IsExpired(now) {
+			delete(r.keys, id)
+		}
+	}
+}
+
+func (r *Keyring) current(now time.Time) (Key, bool) {
+	var (
+		current Key
+		found   bool
+	)
+	for _, k := range r.keys {
+		if !k.IsActive(now) {
+			continue
+		}
+		// the keys which are active since the same time are ordered by their IDs, so the current key is stable.
+		if !found || newer(k, current) {
+			current = k
+			found = true
+		}
+	}
+	return current, found
+}
+
+// newer indicates whether the key a is newer than the key b.
+func newer(a, b Key) bool {
+	if a.NotBefore.Equal(b.NotBefore) {
+		return a.ID > b.ID
+	}
+	return a.NotBefore.After(b.NotBefore)
+}
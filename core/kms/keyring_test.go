@@ -0,0 +1,97 @@
+package kms
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyringRotate(t *testing.T) {
+	now := time.Now()
+	r := NewKeyring(nil, WithGracePeriod(time.Minute))
+	r.now = func() time.Time { return now }
+
+	_, err := r.Current()
+	assert.Equal(t, ErrNoActiveKey, err)
+
+	r.Rotate(Key{ID: "k1", Material: []byte("secret-1")})
+	current, err := r.Current()
+	assert.NoError(t, err)
+	assert.Equal(t, "k1", current.ID)
+
+	// schedule the next key in advance.
+	r.Rotate(Key{ID: "k2", Material: []byte("secret-2"), NotBefore: now.Add(time.Hour)})
+	current, _ = r.Current()
+	assert.Equal(t, "k1", current.ID)
+
+	// the new key is active, the previous key is still valid in the grace period.
+	now = now.Add(time.Hour)
+	current, _ = r.Current()
+	assert.Equal(t, "k2", current.ID)
+	_, err = r.Lookup("k1")
+	assert.NoError(t, err)
+
+	// the previous key is expired after the grace period.
+	now = now.Add(time.Minute)
+	_, err = r.Lookup("k1")
+	assert.Equal(t, ErrKeyExpired, err)
+	r.Purge()
+	_, err = r.Lookup("k1")
+	assert.Equal(t, ErrKeyNotFound, err)
+}
+
+func TestKeyringFileProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	err := os.WriteFile(path, []byte(`[{"id":"a","material":"c2VjcmV0","not_before":"2021-01-01T00:00:00Z"}]`), 0600)
+	assert.NoError(t, err)
+
+	r := NewKeyring(NewFileProvider(path))
+	assert.NoError(t, r.Refresh(context.Background()))
+
+	k, err := r.Lookup("a")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("secret"), k.Material)
+
+	current, err := r.Current()
+	assert.NoError(t, err)
+	assert.Equal(t, "a", current.ID)
+}
+
+func TestKeyringRefreshRotation(t *testing.T) {
+	now := time.Now()
+	keys := []Key{{ID: "k1", Material: []byte("secret-1")}}
+	r := NewKeyring(KeyProviderFunc(func(context.Context) ([]Key, error) {
+		return keys, nil
+	}), WithGracePeriod(time.Minute))
+	r.now = func() time.Time { return now }
+
+	assert.NoError(t, r.Refresh(context.Background()))
+	current, err := r.Current()
+	assert.NoError(t, err)
+	assert.Equal(t, "k1", current.ID)
+
+	// the new key replaces the previous key which is removed from the provider.
+	now = now.Add(time.Hour)
+	keys = []Key{{ID: "k2", Material: []byte("secret-2")}}
+	assert.NoError(t, r.Refresh(context.Background()))
+	current, _ = r.Current()
+	assert.Equal(t, "k2", current.ID)
+	assert.Equal(t, []string{"k2", "k1"}, []string{r.Keys()[0].ID, r.Keys()[1].ID})
+
+	// the previous key is kept in the grace period.
+	now = now.Add(30 * time.Second)
+	assert.NoError(t, r.Refresh(context.Background()))
+	_, err = r.Lookup("k1")
+	assert.NoError(t, err)
+	current, _ = r.Current()
+	assert.Equal(t, "k2", current.ID)
+
+	now = now.Add(30 * time.Second)
+	_, err = r.Lookup("k1")
+	assert.Equal(t, ErrKeyExpired, err)
+	assert.Len(t, r.Keys(), 1)
+}
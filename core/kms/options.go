@@ -0,0 +1,43 @@
+package kms
+
+import "time"
+
+// DefaultGracePeriod is the default duration that a rotated key can still be used to verify/decrypt the data.
+const DefaultGracePeriod = 10 * time.Minute
+
+// Option is a function that applies a Keyring option.
+type Option func(o *options)
+
+// options are the options for Keyring.
+type options struct {
+	gracePeriod     time.Duration // gracePeriod is the duration that the previous key is kept after rotation.
+	refreshInterval time.Duration // refreshInterval is the interval that Load refreshes the keyring, 0 means never.
+}
+
+// WithGracePeriod sets the duration that the previous key is kept after rotation.
+func WithGracePeriod(d time.Duration) Option {
+	return func(o *options) {
+		o.gracePeriod = d
+	}
+}
+
+// WithRefreshInterval refreshes the keyring created by Load in every interval, so the keys rotated in the
+// provider are picked up without restarting.
+func WithRefreshInterval(d time.Duration) Option {
+	return func(o *options) {
+		o.refreshInterval = d
+	}
+}
+
+// newOptions creates a new options for Keyring.
+func newOptions(opts ...Option) *options {
+	options := &options{
+		gracePeriod: DefaultGracePeriod,
+	}
+
+	for _, o := range opts {
+		o(options)
+	}
+
+	return options
+}
@@ -0,0 +1,196 @@
+package kms
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// KeyProvider provides the keys from where they're kept, such as an environment variable, a local file, Vault or
+// a cloud KMS, so the keys aren't hard-coded in the source. The keys are rotated by publishing the new keys to the
+// provider, the Keyring picks them up when it's refreshed.
+type KeyProvider interface {
+	// Keys returns all the keys in the provider.
+	Keys(ctx context.Context) ([]Key, error)
+}
+
+// KeyProviderFunc is an adapter to allow the use of ordinary functions as KeyProvider,
+// it is the simplest way to plug in a secret manager client.
+type KeyProviderFunc func(ctx context.Context) ([]Key, error)
+
+// Keys calls f(ctx).
+func (f KeyProviderFunc) Keys(ctx context.Context) ([]Key, error) {
+	return f(ctx)
+}
+
+// envProvider loads the keys from an environment variable.
+type envProvider struct {
+	name string
+}
+
+// NewEnvProvider creates a KeyProvider which reads the keys from the environment variable, the value is either an
+// array of Key in JSON, or the base64 encoded material of a single key whose ID is derived from the material, so
+// the ID changes when the key is rotated by the new value.
+// e.g. NewEnvProvider("YOMO_KEYS")
+func NewEnvProvider(name string) KeyProvider {
+	return &envProvider{name: name}
+}
+
+func (p *envProvider) Keys(_ context.Context) ([]Key, error) {
+	value := strings.TrimSpace(os.Getenv(p.name))
+	if value == "" {
+		return nil, fmt.Errorf("kms: the environment variable %s is not set", p.name)
+	}
+
+	if strings.HasPrefix(value, "[") {
+		var keys []Key
+		if err := json.Unmarshal([]byte(value), &keys); err != nil {
+			return nil, fmt.Errorf("kms: parse the keys of environment variable %s failed: %v", p.name, err)
+		}
+		return keys, nil
+	}
+
+	material, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("kms: the key of environment variable %s is not base64 encoded: %v", p.name, err)
+	}
+	sum := sha256.Sum256(material)
+	return []Key{{ID: hex.EncodeToString(sum[:8]), Material: material}}, nil
+}
+
+// fileProvider loads the keys from a JSON file.
+type fileProvider struct {
+	path string
+}
+
+// NewFileProvider creates a KeyProvider which reads the keys from a JSON file,
+// the content of file is an array of Key, the material is base64 encoded.
+func NewFileProvider(path string) KeyProvider {
+	return &fileProvider{path: path}
+}
+
+func (p *fileProvider) Keys(_ context.Context) ([]Key, error) {
+	buf, err := os.ReadFile(p.path)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []Key
+	if err = json.Unmarshal(buf, &keys); err != nil {
+		return nil, fmt.Errorf("kms: parse the key file %s failed: %v", p.path, err)
+	}
+	return keys, nil
+}
+
+// vaultProvider loads the keys from the KV secrets engine (version 2) of HashiCorp Vault.
+type vaultProvider struct {
+	addr   string
+	token  string
+	path   string
+	client *http.Client
+}
+
+// NewVaultProvider creates a KeyProvider which reads the keys from a KV v2 secret in Vault,
+// the secret should have a field "keys" which is an array of Key.
+// e.g. NewVaultProvider("https://vault:8200", token, "secret/data/yomo/keys")
+func NewVaultProvider(addr string, token string, path string) KeyProvider {
+	return &vaultProvider{
+		addr:   strings.TrimSuffix(addr, "/"),
+		token:  token,
+		path:   strings.TrimPrefix(path, "/"),
+		client: http.DefaultClient,
+	}
+}
+
+func (p *vaultProvider) Keys(ctx context.Context) ([]Key, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.addr+"/v1/"+p.path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", p.token)
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("kms: read the secret %s from vault failed, status: %s", p.path, res.Status)
+	}
+
+	var secret struct {
+		Data struct {
+			Data struct {
+				Keys []Key `json:"keys"`
+			} `json:"data"`
+		} `json:"data"`
+	}
+	if err = json.NewDecoder(res.Body).Decode(&secret); err != nil {
+		return nil, err
+	}
+	return secret.Data.Data.Keys, nil
+}
+
+// DecryptFunc decrypts the material of a key which is encrypted by the master key of a KMS,
+// e.g. it calls the Decrypt API of AWS KMS or Google Cloud KMS.
+type DecryptFunc func(ctx context.Context, ciphertext []byte) ([]byte, error)
+
+// kmsProvider decrypts the keys of another provider by a KMS.
+type kmsProvider struct {
+	provider KeyProvider
+	decrypt  DecryptFunc
+}
+
+// NewKMSProvider creates a KeyProvider which decrypts the material of the keys of provider by the KMS, it's the
+// envelope encryption: the keys are kept encrypted in a file or Vault, and only the KMS holds the master key.
+// e.g. NewKMSProvider(NewFileProvider("keys.enc.json"), decryptByAWSKMS)
+func NewKMSProvider(provider KeyProvider, decrypt DecryptFunc) KeyProvider {
+	return &kmsProvider{provider: provider, decrypt: decrypt}
+}
+
+func (p *kmsProvider) Keys(ctx context.Context) ([]Key, error) {
+	keys, err := p.provider.Keys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	decrypted := make([]Key, 0, len(keys))
+	for _, k := range keys {
+		if k.Material, err = p.decrypt(ctx, k.Material); err != nil {
+			return nil, fmt.Errorf("kms: decrypt the key %s failed: %v", k.ID, err)
+		}
+		decrypted = append(decrypted, k)
+	}
+	return decrypted, nil
+}
+
+// Backend is the storage of keys.
+//
+// Deprecated: use KeyProvider instead.
+type Backend = KeyProvider
+
+// BackendFunc is an adapter to allow the use of ordinary functions as Backend.
+//
+// Deprecated: use KeyProviderFunc instead.
+type BackendFunc = KeyProviderFunc
+
+// NewFileBackend creates a Backend which reads the keys from a JSON file.
+//
+// Deprecated: use NewFileProvider instead.
+func NewFileBackend(path string) Backend {
+	return NewFileProvider(path)
+}
+
+// NewVaultBackend creates a Backend which reads the keys from a KV v2 secret in Vault.
+//
+// Deprecated: use NewVaultProvider instead.
+func NewVaultBackend(addr string, token string, path string) Backend {
+	return NewVaultProvider(addr, token, path)
+}
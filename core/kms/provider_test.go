@@ -0,0 +1,90 @@
+package kms
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvProvider(t *testing.T) {
+	p := NewEnvProvider("YOMO_TEST_KEYS")
+	_, err := p.Keys(context.Background())
+	assert.EqualError(t, err, "kms: the environment variable YOMO_TEST_KEYS is not set")
+
+	// the ID of a single key is derived from the material.
+	t.Setenv("YOMO_TEST_KEYS", "c2VjcmV0")
+	keys, err := p.Keys(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, keys, 1)
+	assert.Equal(t, []byte("secret"), keys[0].Material)
+	assert.Len(t, keys[0].ID, 16)
+
+	t.Setenv("YOMO_TEST_KEYS", "c2VjcmV0LTI=")
+	rotated, err := p.Keys(context.Background())
+	assert.NoError(t, err)
+	assert.NotEqual(t, keys[0].ID, rotated[0].ID)
+
+	t.Setenv("YOMO_TEST_KEYS", `[{"id":"a","material":"c2VjcmV0"},{"id":"b","material":"c2VjcmV0LTI="}]`)
+	keys, err = p.Keys(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []Key{{ID: "a", Material: []byte("secret")}, {ID: "b", Material: []byte("secret-2")}}, keys)
+
+	t.Setenv("YOMO_TEST_KEYS", "not base64")
+	_, err = p.Keys(context.Background())
+	assert.Error(t, err)
+}
+
+func TestKMSProvider(t *testing.T) {
+	encrypted := KeyProviderFunc(func(context.Context) ([]Key, error) {
+		return []Key{{ID: "a", Material: []byte("wrapped:secret")}}, nil
+	})
+	decrypt := func(_ context.Context, ciphertext []byte) ([]byte, error) {
+		if len(ciphertext) < 8 || string(ciphertext[:8]) != "wrapped:" {
+			return nil, errors.New("invalid ciphertext")
+		}
+		return ciphertext[8:], nil
+	}
+
+	r, err := Load(context.Background(), NewKMSProvider(encrypted, decrypt))
+	assert.NoError(t, err)
+	k, err := r.Current()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("secret"), k.Material)
+
+	plain := KeyProviderFunc(func(context.Context) ([]Key, error) {
+		return []Key{{ID: "b", Material: []byte("secret")}}, nil
+	})
+	_, err = Load(context.Background(), NewKMSProvider(plain, decrypt))
+	assert.EqualError(t, err, "kms: decrypt the key b failed: invalid ciphertext")
+}
+
+func TestAWSDecrypter(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "SECRET")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "TrentService.Decrypt", r.Header.Get("X-Amz-Target"))
+		assert.Contains(t, r.Header.Get("Authorization"), "/us-east-1/kms/aws4_request")
+		var req struct {
+			CiphertextBlob []byte
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		if string(req.CiphertextBlob) != "wrapped" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string][]byte{"Plaintext": []byte("secret")})
+	}))
+	defer server.Close()
+
+	decrypt := NewAWSDecrypter("us-east-1", server.URL)
+	plaintext, err := decrypt(context.Background(), []byte("wrapped"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("secret"), plaintext)
+	_, err = decrypt(context.Background(), []byte("plain"))
+	assert.EqualError(t, err, "kms: decrypt by aws kms failed, status: 400 Bad Request")
+}
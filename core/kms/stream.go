@@ -0,0 +1,189 @@
+package kms
+
+import (
+	"bufio"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+// SegmentSize is the size in bytes of the plaintext segments of the stream sealed by NewSealWriter.
+const SegmentSize = 64 << 10
+
+// the nonce of a segment is the random prefix of the stream, the counter of the segment and the final flag,
+// so the segments can't be reordered, and the truncated stream is detected since it lacks the final segment.
+const (
+	noncePrefixSize  = 7
+	nonceCounterSize = 4
+)
+
+// errSegmentOverflow is returned when the stream has more segments than the counter of the nonce can hold.
+var errSegmentOverflow = errors.New("kms: too many segments in the stream")
+
+type sealWriter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	ad      []byte
+	prefix  []byte
+	counter uint64
+	buf     []byte
+	err     error
+}
+
+// NewSealWriter returns the writer which encrypts and authenticates the stream by AES-GCM with the key, the
+// plaintext is sealed in segments of SegmentSize so the stream isn't held in memory. The random prefix of the
+// nonces is written first, the writer must be closed to seal the final segment.
+func NewSealWriter(k Key, w io.Writer, additionalData []byte) (io.WriteCloser, error) {
+	aead, err := newGCM(k)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := make([]byte, noncePrefixSize)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(prefix); err != nil {
+		return nil, err
+	}
+	return &sealWriter{w: w, aead: aead, ad: additionalData, prefix: prefix, buf: make([]byte, 0, SegmentSize)}, nil
+}
+
+func (s *sealWriter) Write(p []byte) (int, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+
+	n := 0
+	for len(p) > 0 {
+		// the full segment is sealed when more data comes, since the last one must be sealed as the final.
+		if len(s.buf) == SegmentSize {
+			if s.err = s.seal(false); s.err != nil {
+				return n, s.err
+			}
+		}
+		m := copy(s.buf[len(s.buf):SegmentSize], p)
+		s.buf = s.buf[:len(s.buf)+m]
+		p = p[m:]
+		n += m
+	}
+	return n, nil
+}
+
+func (s *sealWriter) Close() error {
+	if s.err != nil {
+		return s.err
+	}
+	s.err = s.seal(true)
+	if s.err != nil {
+		return s.err
+	}
+	s.err = io.ErrClosedPipe
+	return nil
+}
+
+func (s *sealWriter) seal(final bool) error {
+	nonce, err := segmentNonce(s.prefix, s.counter, final)
+	if err != nil {
+		return err
+	}
+	s.counter++
+
+	sealed := s.aead.Seal(nil, nonce, s.buf, s.ad)
+	s.buf = s.buf[:0]
+	_, err = s.w.Write(sealed)
+	return err
+}
+
+type openReader struct {
+	r       *bufio.Reader
+	aead    cipher.AEAD
+	ad      []byte
+	prefix  []byte
+	counter uint64
+	seg     []byte
+	buf     []byte
+	final   bool
+}
+
+// NewOpenReader returns the reader which decrypts and authenticates the stream sealed by NewSealWriter with the
+// same key and additional data. ErrCiphertext is returned by Read if a segment fails to be authenticated or
+// the stream is truncated, the data which has been read is authenticated.
+func NewOpenReader(k Key, r io.Reader, additionalData []byte) (io.Reader, error) {
+	aead, err := newGCM(k)
+	if err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReaderSize(r, SegmentSize+aead.Overhead()+1)
+	prefix := make([]byte, noncePrefixSize)
+	if _, err := io.ReadFull(br, prefix); err != nil {
+		return nil, ErrCiphertext
+	}
+	return &openReader{r: br, aead: aead, ad: additionalData, prefix: prefix, seg: make([]byte, SegmentSize+aead.Overhead())}, nil
+}
+
+func (o *openReader) Read(p []byte) (int, error) {
+	for len(o.buf) == 0 {
+		if o.final {
+			return 0, io.EOF
+		}
+		if err := o.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, o.buf)
+	o.buf = o.buf[n:]
+	return n, nil
+}
+
+func (o *openReader) open() error {
+	n, err := io.ReadFull(o.r, o.seg)
+	switch err {
+	case nil:
+		// the full segment is the final one if nothing follows it.
+		if _, err := o.r.Peek(1); err == io.EOF {
+			o.final = true
+		} else if err != nil {
+			return err
+		}
+	case io.ErrUnexpectedEOF:
+		o.final = true
+	case io.EOF:
+		// the final segment is missing, the stream is truncated.
+		return ErrCiphertext
+	default:
+		return err
+	}
+
+	nonce, err := segmentNonce(o.prefix, o.counter, o.final)
+	if err != nil {
+		return err
+	}
+	o.counter++
+
+	plaintext, err := o.aead.Open(o.seg[:0], nonce, o.seg[:n], o.ad)
+	if err != nil {
+		return ErrCiphertext
+	}
+	o.buf = plaintext
+	return nil
+}
+
+func segmentNonce(prefix []byte, counter uint64, final bool) ([]byte, error) {
+	if counter > math.MaxUint32 {
+		return nil, errSegmentOverflow
+	}
+
+	nonce := make([]byte, noncePrefixSize+nonceCounterSize+1)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[noncePrefixSize:], uint32(counter))
+	if final {
+		nonce[len(nonce)-1] = 1
+	}
+	return nonce, nil
+}
@@ -0,0 +1,51 @@
+package kms
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSealWriterAndOpenReader(t *testing.T) {
+	k, err := NewKey(32)
+	assert.NoError(t, err)
+
+	for _, size := range []int{0, 5, SegmentSize, 2*SegmentSize + 7} {
+		plaintext := make([]byte, size)
+		_, _ = rand.Read(plaintext)
+
+		var buf bytes.Buffer
+		w, err := NewSealWriter(k, &buf, []byte("tid"))
+		assert.NoError(t, err)
+		_, err = w.Write(plaintext)
+		assert.NoError(t, err)
+		assert.NoError(t, w.Close())
+		sealed := buf.Bytes()
+
+		r, err := NewOpenReader(k, bytes.NewReader(sealed), []byte("tid"))
+		assert.NoError(t, err)
+		opened, err := ioutil.ReadAll(r)
+		assert.NoError(t, err)
+		assert.Equal(t, plaintext, opened)
+
+		// the additional data is authenticated.
+		r, err = NewOpenReader(k, bytes.NewReader(sealed), []byte("other"))
+		assert.NoError(t, err)
+		_, err = ioutil.ReadAll(r)
+		assert.Equal(t, ErrCiphertext, err)
+
+		// the truncated stream is detected, even at the boundary of segments.
+		for _, n := range []int{noncePrefixSize, noncePrefixSize + SegmentSize + 16, len(sealed) - 1} {
+			if n >= len(sealed) {
+				continue
+			}
+			r, err = NewOpenReader(k, bytes.NewReader(sealed[:n]), []byte("tid"))
+			assert.NoError(t, err)
+			_, err = ioutil.ReadAll(r)
+			assert.Equal(t, ErrCiphertext, err, "size %d truncated at %d", size, n)
+		}
+	}
+}
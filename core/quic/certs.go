@@ -0,0 +1,113 @@
+package quic
+
+import (
+	"context"
+	"crypto/tls"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/yomorun/yomo/logger"
+)
+
+// CertReloader serves the server certificate which can be reloaded at runtime, the new certificate is used
+// by the following handshakes, so the established sessions are not dropped when the certificate is rotated.
+type CertReloader struct {
+	certFile string
+	keyFile  string
+	mutex    sync.RWMutex
+	cert     *tls.Certificate
+	modTime  time.Time
+}
+
+// NewCertReloader loads the certificate and the private key.
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	r := &CertReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload the certificate and the private key from the files, the current certificate is kept if they are invalid.
+func (r *CertReloader) Reload() error {
+	modTime := r.lastModified()
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	r.cert = &cert
+	r.modTime = modTime
+	r.mutex.Unlock()
+	return nil
+}
+
+// GetCertificate returns the current certificate, it's set to tls.Config.GetCertificate.
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.cert, nil
+}
+
+// GetClientCertificate returns the current certificate, it's set to tls.Config.GetClientCertificate.
+func (r *CertReloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return r.GetCertificate(nil)
+}
+
+// TLSConfig applies the reloadable certificate to the server TLS config.
+func (r *CertReloader) TLSConfig(conf *tls.Config) *tls.Config {
+	conf = conf.Clone()
+	conf.Certificates = nil
+	conf.GetCertificate = r.GetCertificate
+	return conf
+}
+
+// ClientTLSConfig applies the reloadable certificate to the client TLS config, so the certificate is rotated
+// for the following dials too.
+func (r *CertReloader) ClientTLSConfig(conf *tls.Config) *tls.Config {
+	conf = conf.Clone()
+	conf.Certificates = nil
+	conf.GetClientCertificate = r.GetClientCertificate
+	return conf
+}
+
+// Watch reloads the certificate when the files are modified, the files are checked at interval until ctx is done.
+// The certificate renewed by e.g. certbot is picked up without restarting.
+func (r *CertReloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.mutex.RLock()
+			modified := r.lastModified().After(r.modTime)
+			r.mutex.RUnlock()
+			if !modified {
+				continue
+			}
+
+			if err := r.Reload(); err != nil {
+				// the files may be written partially, retry at the next tick.
+				logger.Error("[QUIC server] reload the certificate failed.", "cert", r.certFile, "err", err)
+				continue
+			}
+			logger.Printf("✅ The certificate %s is reloaded", r.certFile)
+		}
+	}
+}
+
+// lastModified returns the latest modification time of the certificate and the private key.
+func (r *CertReloader) lastModified() time.Time {
+	var modTime time.Time
+	for _, file := range []string{r.certFile, r.keyFile} {
+		if info, err := os.Stat(file); err == nil && info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+	}
+	return modTime
+}
@@ -0,0 +1,71 @@
+package quic
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeCertificate writes a new self-signed certificate and its private key in PEM.
+func writeCertificate(t *testing.T, certFile, keyFile string) []byte {
+	cert, err := generateCertificate("localhost")
+	assert.NoError(t, err)
+	key, err := x509.MarshalECPrivateKey(cert.PrivateKey.(*ecdsa.PrivateKey))
+	assert.NoError(t, err)
+
+	assert.NoError(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0600))
+	assert.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: key}), 0600))
+	return cert.Certificate[0]
+}
+
+func TestCertReloader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certs")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+
+	first := writeCertificate(t, certFile, keyFile)
+	r, err := NewCertReloader(certFile, keyFile)
+	assert.NoError(t, err)
+	conf := r.TLSConfig(generateTLSConfig())
+	assert.Empty(t, conf.Certificates)
+	clientConf := r.ClientTLSConfig(&tls.Config{})
+	assert.Empty(t, clientConf.Certificates)
+
+	cert, err := conf.GetCertificate(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, first, cert.Certificate[0])
+
+	// the current certificate is kept if the files are invalid.
+	assert.NoError(t, ioutil.WriteFile(keyFile, []byte("invalid"), 0600))
+	assert.Error(t, r.Reload())
+	cert, _ = conf.GetCertificate(nil)
+	assert.Equal(t, first, cert.Certificate[0])
+
+	// the renewed certificate is picked up by the watch.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Watch(ctx, 10*time.Millisecond)
+
+	later := time.Now().Add(time.Second)
+	second := writeCertificate(t, certFile, keyFile)
+	assert.NoError(t, os.Chtimes(certFile, later, later))
+	assert.Eventually(t, func() bool {
+		cert, _ := conf.GetCertificate(nil)
+		return bytes.Equal(second, cert.Certificate[0])
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// the client certificate is rotated too.
+	cert, _ = clientConf.GetClientCertificate(nil)
+	assert.Equal(t, second, cert.Certificate[0])
+}
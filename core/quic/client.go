@@ -22,13 +22,19 @@ type Client interface {
 	// CreateStream creates a unidirectional stream.
 	CreateUniStream(ctx context.Context) (SendStream, error)
 
+	// SendDatagram sends the data in a QUIC DATAGRAM frame, it's unreliable and the data may be lost.
+	// ErrDatagramTooLarge is returned if the data exceeds MaxDatagramSize.
+	SendDatagram(data []byte) error
+
 	// Close the QUIC client.
 	Close() error
 }
 
 // NewClient inits the default implementation of QUIC client.
-func NewClient(addr string) (Client, error) {
-	client := &quicGoClient{}
+func NewClient(addr string, opts ...Option) (Client, error) {
+	client := &quicGoClient{
+		opts: newOptions(opts...),
+	}
 	err := client.Connect(addr)
 
 	if err != nil {
@@ -13,6 +13,8 @@ const (
 	ErrConnectionClosed string = "Application error 0x0"
 	// HeartbeatTimeOut is the duration when the heartbeat will be time-out.
 	HeartbeatTimeOut = 5 * time.Second
+	// HeartbeatInterval is the default interval of sending heartbeat.
+	HeartbeatInterval = 3 * time.Second
 )
 
 // Conn represents the QUIC connection.
@@ -35,6 +37,8 @@ type Conn struct {
 	OnHeartbeatReceived func()
 	// OnHeartbeatExpired is the callback when the heartbeat is expired.
 	OnHeartbeatExpired func()
+	// HeartbeatTimeout is the duration when the heartbeat will be time-out, HeartbeatTimeOut is used if it's zero.
+	HeartbeatTimeout time.Duration
 }
 
 // NewConn inits a new QUIC connection.
@@ -60,6 +64,11 @@ func (c *Conn) SendSignal(f frame.Frame) error {
 
 // Healthcheck checks if peer is online by heartbeat.
 func (c *Conn) Healthcheck() {
+	timeout := c.HeartbeatTimeout
+	if timeout <= 0 {
+		timeout = HeartbeatTimeOut
+	}
+
 	go func() {
 		// receive heartbeat
 		defer c.Close()
@@ -74,7 +83,7 @@ func (c *Conn) Healthcheck() {
 					c.OnHeartbeatReceived()
 				}
 
-			case <-time.After(HeartbeatTimeOut):
+			case <-time.After(timeout):
 				// didn't receive the heartbeat after a certain duration, call the callback function when expired.
 				if c.OnHeartbeatExpired != nil {
 					c.OnHeartbeatExpired()
@@ -0,0 +1,32 @@
+package quic
+
+import (
+	"errors"
+)
+
+// MaxDatagramSize is the max size of data which can be sent in one QUIC DATAGRAM frame.
+const MaxDatagramSize = 1100
+
+// ErrDatagramTooLarge is returned when the data exceeds MaxDatagramSize.
+var ErrDatagramTooLarge = errors.New("quic: the datagram is too large")
+
+// DatagramHandler is the optional interface of ServerHandler to handle the QUIC DATAGRAM frames (RFC 9221).
+type DatagramHandler interface {
+	// ReadDatagram is the callback function when the QUIC server receiving a datagram.
+	ReadDatagram(addr string, sess Session, data []byte) error
+}
+
+// DatagramReader reads the QUIC DATAGRAM frames from a session.
+type DatagramReader struct {
+	sess Session
+}
+
+// NewDatagramReader creates a new DatagramReader.
+func NewDatagramReader(sess Session) *DatagramReader {
+	return &DatagramReader{sess: sess}
+}
+
+// ReadDatagram reads the next datagram, blocking until one is available.
+func (r *DatagramReader) ReadDatagram() ([]byte, error) {
+	return r.sess.ReceiveMessage()
+}
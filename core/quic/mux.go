@@ -0,0 +1,483 @@
+package quic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	quicGo "github.com/lucas-clemente/quic-go"
+	"github.com/yomorun/yomo/logger"
+)
+
+// muxHeader is the first byte of the streams in logical channels, it's followed by the channel ID in varint.
+// The frames always start with a Y3 node packet whose first byte is greater than 0x80, so the streams of
+// logical channels can be distinguished from the streams of plain sessions.
+const muxHeader byte = 0x4D
+
+// muxCloseHeader is the first byte of the stream which notifies the peer that a logical channel is closed,
+// it's followed by the channel ID in varint.
+const muxCloseHeader byte = 0x4E
+
+// muxHeaderTimeout is the deadline of reading the header of an accepted stream, so a peer which never sends
+// the header doesn't hold the stream.
+var muxHeaderTimeout = 5 * time.Second
+
+// errMuxClosedByPeer is returned by muxListener.route when the stream is the close notice of a channel.
+var errMuxClosedByPeer = errors.New("quic: the multiplexed channel is closed by peer")
+
+// ErrMuxDatagram is returned when sending datagram in a logical channel, the datagrams are not multiplexed.
+var ErrMuxDatagram = errors.New("quic: the datagram is not supported in a multiplexed channel")
+
+// Mux shares one QUIC session among the YoMo clients in a process, e.g. several stream functions,
+// each client registers itself over a distinct logical channel, so the handshakes and file descriptors are saved.
+type Mux struct {
+	opts   []Option
+	mutex  sync.Mutex
+	addr   string
+	client *quicGoClient
+	conn   *muxConn
+}
+
+// NewMux creates a Mux, the options are applied to the shared QUIC session.
+func NewMux(opts ...Option) *Mux {
+	return &Mux{opts: opts}
+}
+
+// Dial opens a new logical channel to addr, the QUIC session is dialed on the first call
+// and redialed after it's closed. All channels of a Mux must connect to the same address.
+func (m *Mux) Dial(addr string) (Client, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.conn != nil && m.conn.session.Context().Err() == nil {
+		if addr != m.addr {
+			return nil, fmt.Errorf("quic: the mux is connected to %s, can't dial %s", m.addr, addr)
+		}
+	} else {
+		client := &quicGoClient{opts: newOptions(m.opts...)}
+		if err := client.Connect(addr); err != nil {
+			return nil, err
+		}
+		m.addr = addr
+		m.client = client
+		m.conn = newMuxConn(client.session, true)
+	}
+
+	return &muxClient{
+		session:  m.conn.open(),
+		physical: m.client,
+	}, nil
+}
+
+// muxClient is the Client of a logical channel.
+type muxClient struct {
+	session  *muxSession
+	physical *quicGoClient
+}
+
+func (c *muxClient) AcceptStream(ctx context.Context) (Stream, error) {
+	return c.session.AcceptStream(ctx)
+}
+
+func (c *muxClient) AcceptUniStream(ctx context.Context) (ReceiveStream, error) {
+	return c.session.AcceptUniStream(ctx)
+}
+
+func (c *muxClient) CreateStream(ctx context.Context) (Stream, error) {
+	return c.session.OpenStream()
+}
+
+func (c *muxClient) CreateUniStream(ctx context.Context) (SendStream, error) {
+	return c.session.OpenUniStream()
+}
+
+func (c *muxClient) SendDatagram(data []byte) error {
+	return ErrMuxDatagram
+}
+
+// Context returns a context that is cancelled when the logical channel is closed.
+func (c *muxClient) Context() context.Context {
+	return c.session.Context()
+}
+
+// Close the logical channel, the shared QUIC session is closed when all channels are closed.
+func (c *muxClient) Close() error {
+	return c.session.CloseWithError(0, "")
+}
+
+// muxConn demultiplexes the streams of a QUIC session to the logical channels.
+type muxConn struct {
+	session  quicGo.Session
+	isClient bool
+	mutex    sync.Mutex
+	channels map[uint64]*muxSession
+	nextID   uint64
+	once     sync.Once
+}
+
+func newMuxConn(session quicGo.Session, isClient bool) *muxConn {
+	m := &muxConn{
+		session:  session,
+		isClient: isClient,
+		channels: make(map[uint64]*muxSession),
+	}
+	if isClient {
+		// the server opens the bidirectional streams only to the clients, it's never used by YoMo-Zipper now.
+		go m.acceptStreams()
+	}
+	return m
+}
+
+// open a new channel by the client.
+func (m *muxConn) open() *muxSession {
+	m.mutex.Lock()
+	m.nextID++
+	id := m.nextID
+	m.mutex.Unlock()
+	return m.channel(id)
+}
+
+// channel returns the channel by id, it's created if not exists.
+func (m *muxConn) channel(id uint64) *muxSession {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if ch, ok := m.channels[id]; ok {
+		return ch
+	}
+
+	ctx, cancel := context.WithCancel(m.session.Context())
+	ch := &muxSession{
+		Session: m.session,
+		id:      id,
+		conn:    m,
+		bidi:    make(chan quicGo.Stream, 100),
+		uni:     make(chan quicGo.ReceiveStream, 100),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+	m.channels[id] = ch
+	return ch
+}
+
+// lookup returns the channel by id, it's nil if the channel is closed.
+func (m *muxConn) lookup(id uint64) *muxSession {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.channels[id]
+}
+
+// remove the channel and shut it down, the remaining count of channels is returned.
+func (m *muxConn) remove(id uint64) (removed bool, remaining int) {
+	m.mutex.Lock()
+	ch, ok := m.channels[id]
+	delete(m.channels, id)
+	remaining = len(m.channels)
+	m.mutex.Unlock()
+
+	if ok {
+		ch.shutdown()
+	}
+	return ok, remaining
+}
+
+// close the channel and notify the peer, the QUIC session is closed when all channels are closed.
+func (m *muxConn) close(id uint64, code quicGo.ApplicationErrorCode, reason string) error {
+	removed, remaining := m.remove(id)
+	if remaining == 0 {
+		return m.session.CloseWithError(code, reason)
+	}
+	if removed {
+		m.notifyClosed(id)
+	}
+	return nil
+}
+
+// closeByPeer closes the channel which is closed by the peer, the QUIC session is closed when all channels
+// are closed.
+func (m *muxConn) closeByPeer(id uint64) {
+	if removed, remaining := m.remove(id); removed && remaining == 0 {
+		m.session.CloseWithError(0, "")
+	}
+}
+
+// notifyClosed sends the close notice of the channel, so the peer doesn't wait for the closed channel until
+// the QUIC session is closed.
+func (m *muxConn) notifyClosed(id uint64) {
+	stream, err := m.session.OpenStream()
+	if err != nil {
+		logger.Debug("[QUIC mux] notify the closed channel failed.", "id", id, "err", err)
+		return
+	}
+	if _, err := stream.Write(appendVarint([]byte{muxCloseHeader}, id)); err != nil {
+		logger.Debug("[QUIC mux] notify the closed channel failed.", "id", id, "err", err)
+	}
+	stream.Close()
+}
+
+// acceptStreams dispatches the bidirectional streams to the channels.
+func (m *muxConn) acceptStreams() {
+	for {
+		stream, err := m.session.AcceptStream(context.Background())
+		if err != nil {
+			return
+		}
+		go func() {
+			stream.SetReadDeadline(time.Now().Add(muxHeaderTimeout))
+			header, id, err := readStreamHeader(stream)
+			stream.SetReadDeadline(time.Time{})
+			if err != nil {
+				stream.CancelRead(0)
+				return
+			}
+			if header == muxCloseHeader {
+				m.closeByPeer(id)
+				return
+			}
+			if ch := m.lookup(id); ch != nil {
+				ch.track(stream)
+				ch.push(stream, ch.bidi)
+			}
+		}()
+	}
+}
+
+// acceptUniStreams dispatches the unidirectional streams to the channels, it's started when a channel accepts.
+func (m *muxConn) acceptUniStreams() {
+	m.once.Do(func() {
+		go func() {
+			for {
+				stream, err := m.session.AcceptUniStream(context.Background())
+				if err != nil {
+					return
+				}
+				go func() {
+					stream.SetReadDeadline(time.Now().Add(muxHeaderTimeout))
+					header, id, err := readStreamHeader(stream)
+					stream.SetReadDeadline(time.Time{})
+					if err == nil && header != muxHeader {
+						err = fmt.Errorf("quic: unexpected header %#x of unidirectional stream", header)
+					}
+					if err != nil {
+						stream.CancelRead(0)
+						return
+					}
+					if ch := m.lookup(id); ch != nil {
+						ch.pushUni(stream)
+					} else {
+						stream.CancelRead(0)
+					}
+				}()
+			}
+		}()
+	})
+}
+
+// muxSession is a logical channel in the QUIC session.
+type muxSession struct {
+	quicGo.Session
+	id     uint64
+	conn   *muxConn
+	bidi   chan quicGo.Stream
+	uni    chan quicGo.ReceiveStream
+	ctx    context.Context
+	cancel context.CancelFunc
+	mutex  sync.Mutex
+	// first is the first bidirectional stream of the channel, i.e. the signal stream of YoMo client, it's reset
+	// when the channel is closed, so the reader of signals knows the channel is gone.
+	first quicGo.Stream
+}
+
+// track records the first bidirectional stream of the channel.
+func (s *muxSession) track(stream quicGo.Stream) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.first == nil {
+		s.first = stream
+	}
+}
+
+// shutdown cancels the context of channel and resets its first stream.
+func (s *muxSession) shutdown() {
+	s.cancel()
+	s.mutex.Lock()
+	first := s.first
+	s.mutex.Unlock()
+	if first != nil {
+		first.CancelRead(0)
+		first.CancelWrite(0)
+	}
+}
+
+func (s *muxSession) push(stream quicGo.Stream, ch chan quicGo.Stream) {
+	select {
+	case ch <- stream:
+	case <-s.ctx.Done():
+		stream.CancelRead(0)
+	}
+}
+
+func (s *muxSession) pushUni(stream quicGo.ReceiveStream) {
+	select {
+	case s.uni <- stream:
+	case <-s.ctx.Done():
+		stream.CancelRead(0)
+	}
+}
+
+func (s *muxSession) header() []byte {
+	return appendVarint([]byte{muxHeader}, s.id)
+}
+
+func (s *muxSession) AcceptStream(ctx context.Context) (quicGo.Stream, error) {
+	select {
+	case stream := <-s.bidi:
+		return stream, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-s.ctx.Done():
+		return nil, errors.New(ErrConnectionClosed)
+	}
+}
+
+func (s *muxSession) AcceptUniStream(ctx context.Context) (quicGo.ReceiveStream, error) {
+	s.conn.acceptUniStreams()
+	select {
+	case stream := <-s.uni:
+		return stream, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-s.ctx.Done():
+		return nil, errors.New(ErrConnectionClosed)
+	}
+}
+
+func (s *muxSession) OpenStream() (quicGo.Stream, error) {
+	stream, err := s.Session.OpenStream()
+	if err != nil {
+		return nil, err
+	}
+	s.track(stream)
+	_, err = stream.Write(s.header())
+	return stream, err
+}
+
+func (s *muxSession) OpenStreamSync(ctx context.Context) (quicGo.Stream, error) {
+	stream, err := s.Session.OpenStreamSync(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.track(stream)
+	_, err = stream.Write(s.header())
+	return stream, err
+}
+
+func (s *muxSession) OpenUniStream() (quicGo.SendStream, error) {
+	stream, err := s.Session.OpenUniStream()
+	if err != nil {
+		return nil, err
+	}
+	_, err = stream.Write(s.header())
+	return stream, err
+}
+
+func (s *muxSession) OpenUniStreamSync(ctx context.Context) (quicGo.SendStream, error) {
+	stream, err := s.Session.OpenUniStreamSync(ctx)
+	if err != nil {
+		return nil, err
+	}
+	_, err = stream.Write(s.header())
+	return stream, err
+}
+
+// CloseWithError closes the channel, the QUIC session is closed when all channels are closed.
+func (s *muxSession) CloseWithError(code quicGo.ApplicationErrorCode, reason string) error {
+	return s.conn.close(s.id, code, reason)
+}
+
+func (s *muxSession) Context() context.Context {
+	return s.ctx
+}
+
+func (s *muxSession) SendMessage(data []byte) error {
+	return ErrMuxDatagram
+}
+
+// readStreamHeader reads the header and the channel ID from the stream of a logical channel or a close notice.
+func readStreamHeader(r io.Reader) (byte, uint64, error) {
+	b := make([]byte, 1)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return 0, 0, err
+	}
+	if b[0] != muxHeader && b[0] != muxCloseHeader {
+		return 0, 0, fmt.Errorf("quic: unexpected header %#x of multiplexed stream", b[0])
+	}
+	id, err := readVarint(r)
+	return b[0], id, err
+}
+
+// muxListener routes the streams accepted by server, the streams of logical channels are passed to the
+// handler with the channel sessions, the other streams are passed with the QUIC session.
+// The streams are routed concurrently, so the muxConn is guarded by the mutex.
+type muxListener struct {
+	session quicGo.Session
+	mutex   sync.Mutex
+	conn    *muxConn
+}
+
+// muxConnOf returns the muxConn of session, it's created on the first logical channel.
+func (l *muxListener) muxConnOf(addr string) *muxConn {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.conn == nil {
+		logger.Debug("[QUIC server] the session is multiplexed.", "addr", addr)
+		l.conn = newMuxConn(l.session, false)
+	}
+	return l.conn
+}
+
+// route returns the address, the session and the stream which should be passed to the handler.
+// The header is read in muxHeaderTimeout, errMuxClosedByPeer is returned if the stream is a close notice.
+func (l *muxListener) route(addr string, stream quicGo.Stream) (string, quicGo.Session, quicGo.Stream, error) {
+	stream.SetReadDeadline(time.Now().Add(muxHeaderTimeout))
+	defer stream.SetReadDeadline(time.Time{})
+
+	b := make([]byte, 1)
+	if _, err := io.ReadFull(stream, b); err != nil {
+		return "", nil, nil, err
+	}
+	if b[0] != muxHeader && b[0] != muxCloseHeader {
+		return addr, l.session, &prefixedStream{Stream: stream, prefix: b}, nil
+	}
+
+	id, err := readVarint(stream)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	conn := l.muxConnOf(addr)
+	if b[0] == muxCloseHeader {
+		conn.closeByPeer(id)
+		return "", nil, nil, errMuxClosedByPeer
+	}
+	ch := conn.channel(id)
+	ch.track(stream)
+	return fmt.Sprintf("%s#%d", addr, id), ch, stream, nil
+}
+
+// prefixedStream gives back the bytes which have been read from the stream.
+type prefixedStream struct {
+	quicGo.Stream
+	prefix []byte
+}
+
+func (s *prefixedStream) Read(p []byte) (int, error) {
+	if len(s.prefix) > 0 {
+		n := copy(p, s.prefix)
+		s.prefix = s.prefix[n:]
+		return n, nil
+	}
+	return s.Stream.Read(p)
+}
@@ -0,0 +1,151 @@
+package quic
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	quicGo "github.com/lucas-clemente/quic-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMux(t *testing.T) {
+	sessions := make(chan quicGo.Session, 1)
+	listener, err := listenTCP("127.0.0.1:0", generateTLSConfig("127.0.0.1"), func(session quicGo.Session) {
+		sessions <- session
+	})
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	session, err := dialTCP(listener.Addr().String(), &tls.Config{InsecureSkipVerify: true, NextProtos: []string{nextProto}})
+	assert.NoError(t, err)
+	client := newMuxConn(session, true)
+	ch1, ch2 := client.open(), client.open()
+
+	// the signal streams of two channels.
+	for _, ch := range []*muxSession{ch1, ch2} {
+		stream, err := ch.OpenStream()
+		assert.NoError(t, err)
+		_, err = stream.Write([]byte("handshake"))
+		assert.NoError(t, err)
+		assert.NoError(t, stream.Close())
+	}
+
+	var server quicGo.Session
+	select {
+	case server = <-sessions:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the session is not accepted")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	mux := &muxListener{session: server}
+	channels := make(map[string]quicGo.Session)
+	for i := 0; i < 2; i++ {
+		stream, err := server.AcceptStream(ctx)
+		assert.NoError(t, err)
+		addr, sess, st, err := mux.route("peer", stream)
+		assert.NoError(t, err)
+		buf, err := ioutil.ReadAll(st)
+		assert.NoError(t, err)
+		assert.Equal(t, "handshake", string(buf))
+		channels[addr] = sess
+	}
+	assert.Contains(t, channels, "peer#1")
+	assert.Contains(t, channels, "peer#2")
+
+	// server -> the second channel.
+	uni, err := channels["peer#2"].OpenUniStream()
+	assert.NoError(t, err)
+	_, err = uni.Write([]byte("data"))
+	assert.NoError(t, err)
+	assert.NoError(t, uni.Close())
+
+	received, err := ch2.AcceptUniStream(ctx)
+	assert.NoError(t, err)
+	buf, err := ioutil.ReadAll(received)
+	assert.NoError(t, err)
+	assert.Equal(t, "data", string(buf))
+
+	// the first channel doesn't receive the stream of the second channel.
+	short, cancelShort := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancelShort()
+	_, err = ch1.AcceptUniStream(short)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	// the server is notified when the first channel is closed.
+	assert.NoError(t, ch1.CloseWithError(0, ""))
+	assert.Error(t, ch1.Context().Err())
+	assert.Nil(t, session.Context().Err())
+	notice, err := server.AcceptStream(ctx)
+	assert.NoError(t, err)
+	_, _, _, err = mux.route("peer", notice)
+	assert.ErrorIs(t, err, errMuxClosedByPeer)
+	assert.Error(t, channels["peer#1"].Context().Err())
+	assert.Nil(t, channels["peer#2"].Context().Err())
+
+	// the session is closed after all channels are closed.
+	assert.NoError(t, ch2.CloseWithError(0, ""))
+	_, err = server.AcceptStream(ctx)
+	assert.EqualError(t, err, ErrConnectionClosed)
+}
+
+func TestMuxHeaderTimeout(t *testing.T) {
+	defer func(timeout time.Duration) { muxHeaderTimeout = timeout }(muxHeaderTimeout)
+	muxHeaderTimeout = 100 * time.Millisecond
+
+	sessions := make(chan quicGo.Session, 1)
+	listener, err := listenTCP("127.0.0.1:0", generateTLSConfig("127.0.0.1"), func(session quicGo.Session) {
+		sessions <- session
+	})
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	session, err := dialTCP(listener.Addr().String(), &tls.Config{InsecureSkipVerify: true, NextProtos: []string{nextProto}})
+	assert.NoError(t, err)
+	defer session.CloseWithError(0, "")
+	// the header is incomplete without the channel ID.
+	stream, err := session.OpenStream()
+	assert.NoError(t, err)
+	_, err = stream.Write([]byte{muxHeader})
+	assert.NoError(t, err)
+
+	server := <-sessions
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	accepted, err := server.AcceptStream(ctx)
+	assert.NoError(t, err)
+
+	start := time.Now()
+	_, _, _, err = (&muxListener{session: server}).route("peer", accepted)
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+type mockStream struct {
+	quicGo.Stream
+	*bytes.Reader
+}
+
+func (s *mockStream) Read(p []byte) (int, error) {
+	return s.Reader.Read(p)
+}
+
+func (s *mockStream) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+func TestPrefixedStream(t *testing.T) {
+	stream := &mockStream{Reader: bytes.NewReader([]byte{0xBD, 0x01})}
+	addr, _, st, err := (&muxListener{}).route("peer", stream)
+	assert.NoError(t, err)
+	assert.Equal(t, "peer", addr)
+	buf, err := ioutil.ReadAll(st)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0xBD, 0x01}, buf)
+}
@@ -0,0 +1,283 @@
+package quic
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// ProxyEnv is the environment variable of the proxy URL, it's used when the proxy is not set by WithProxy.
+const ProxyEnv = "YOMO_PROXY"
+
+// ErrUnsupportedProxy is returned when the scheme of proxy URL is not supported.
+var ErrUnsupportedProxy = errors.New("quic: only the socks5 proxy is supported")
+
+const (
+	socks5Version        = 0x05
+	socks5AuthNone       = 0x00
+	socks5AuthPassword   = 0x02
+	socks5AuthNoAccepted = 0xFF
+	socks5CmdUDP         = 0x03
+	socks5AddrIPv4       = 0x01
+	socks5AddrDomain     = 0x03
+	socks5AddrIPv6       = 0x04
+	socks5Succeeded      = 0x00
+)
+
+// proxyURL returns the proxy URL of the option or the environment variable, it's empty if no proxy is used.
+func proxyURL(proxy string) string {
+	if proxy != "" {
+		return proxy
+	}
+	return os.Getenv(ProxyEnv)
+}
+
+// dialProxy creates the socket which relays the UDP packets to addr by the proxy,
+// the MASQUE CONNECT-UDP proxy is not supported because it requires HTTP/3 datagrams.
+func dialProxy(proxy string, addr string) (*socks5Conn, *net.UDPAddr, error) {
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return nil, nil, err
+	}
+	if u.Scheme != "socks5" {
+		return nil, nil, ErrUnsupportedProxy
+	}
+
+	target, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conn, err := dialSOCKS5(u)
+	if err != nil {
+		return nil, nil, fmt.Errorf("quic: socks5 proxy %s: %w", u.Host, err)
+	}
+	return conn, target, nil
+}
+
+// socks5Conn is the UDP socket associated by a SOCKS5 proxy, the packets are relayed by the proxy with
+// the SOCKS5 UDP request header. The association is kept until the TCP control connection is closed.
+type socks5Conn struct {
+	control net.Conn
+	conn    *net.UDPConn
+	relay   *net.UDPAddr
+	// buf is reused by ReadFrom for the relayed packets with the SOCKS5 UDP request header.
+	mutex sync.Mutex
+	buf   []byte
+}
+
+// socks5HeaderSize is the max size of the SOCKS5 UDP request header, i.e. the domain name of 255 bytes.
+const socks5HeaderSize = 262
+
+// dialSOCKS5 negotiates the UDP ASSOCIATE command with the SOCKS5 proxy.
+func dialSOCKS5(u *url.URL) (*socks5Conn, error) {
+	control, err := net.DialTimeout("tcp", u.Host, 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	relay, err := socks5Associate(control, u.User)
+	if err != nil {
+		control.Close()
+		return nil, err
+	}
+	if relay.IP.IsUnspecified() {
+		// the relay is on the same host of the proxy.
+		relay.IP = control.RemoteAddr().(*net.TCPAddr).IP
+	}
+
+	conn, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		control.Close()
+		return nil, err
+	}
+
+	return &socks5Conn{control: control, conn: conn, relay: relay}, nil
+}
+
+// socks5Associate authenticates and requests the UDP relay, the address of relay is returned.
+func socks5Associate(rw io.ReadWriter, user *url.Userinfo) (*net.UDPAddr, error) {
+	method := byte(socks5AuthNone)
+	if user != nil {
+		method = socks5AuthPassword
+	}
+	if _, err := rw.Write([]byte{socks5Version, 1, method}); err != nil {
+		return nil, err
+	}
+
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(rw, reply); err != nil {
+		return nil, err
+	}
+	if reply[0] != socks5Version || reply[1] == socks5AuthNoAccepted || reply[1] != method {
+		return nil, errors.New("no acceptable authentication method")
+	}
+
+	if method == socks5AuthPassword {
+		password, _ := user.Password()
+		req := []byte{0x01, byte(len(user.Username()))}
+		req = append(req, user.Username()...)
+		req = append(req, byte(len(password)))
+		req = append(req, password...)
+		if _, err := rw.Write(req); err != nil {
+			return nil, err
+		}
+		if _, err := io.ReadFull(rw, reply); err != nil {
+			return nil, err
+		}
+		if reply[1] != socks5Succeeded {
+			return nil, errors.New("authentication failed")
+		}
+	}
+
+	// the client address is unknown before the UDP socket is bound, so it's all zeros.
+	if _, err := rw.Write([]byte{socks5Version, socks5CmdUDP, 0, socks5AddrIPv4, 0, 0, 0, 0, 0, 0}); err != nil {
+		return nil, err
+	}
+
+	head := make([]byte, 3)
+	if _, err := io.ReadFull(rw, head); err != nil {
+		return nil, err
+	}
+	if head[0] != socks5Version {
+		return nil, errors.New("unexpected version of reply")
+	}
+	if head[1] != socks5Succeeded {
+		return nil, fmt.Errorf("UDP ASSOCIATE failed with code %d", head[1])
+	}
+
+	ip, port, err := readSOCKS5Addr(rw)
+	if err != nil {
+		return nil, err
+	}
+	return &net.UDPAddr{IP: ip, Port: port}, nil
+}
+
+// readSOCKS5Addr reads ATYP, ADDR and PORT, the domain name is resolved.
+func readSOCKS5Addr(r io.Reader) (net.IP, int, error) {
+	atyp := make([]byte, 1)
+	if _, err := io.ReadFull(r, atyp); err != nil {
+		return nil, 0, err
+	}
+
+	var ip net.IP
+	switch atyp[0] {
+	case socks5AddrIPv4:
+		ip = make(net.IP, net.IPv4len)
+		if _, err := io.ReadFull(r, ip); err != nil {
+			return nil, 0, err
+		}
+	case socks5AddrIPv6:
+		ip = make(net.IP, net.IPv6len)
+		if _, err := io.ReadFull(r, ip); err != nil {
+			return nil, 0, err
+		}
+	case socks5AddrDomain:
+		n := make([]byte, 1)
+		if _, err := io.ReadFull(r, n); err != nil {
+			return nil, 0, err
+		}
+		host := make([]byte, n[0])
+		if _, err := io.ReadFull(r, host); err != nil {
+			return nil, 0, err
+		}
+		addr, err := net.ResolveIPAddr("ip", string(host))
+		if err != nil {
+			return nil, 0, err
+		}
+		ip = addr.IP
+	default:
+		return nil, 0, fmt.Errorf("unknown address type %d", atyp[0])
+	}
+
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(r, port); err != nil {
+		return nil, 0, err
+	}
+	return ip, int(binary.BigEndian.Uint16(port)), nil
+}
+
+// appendSOCKS5Addr appends ATYP, ADDR and PORT of the address.
+func appendSOCKS5Addr(b []byte, addr *net.UDPAddr) []byte {
+	if ip4 := addr.IP.To4(); ip4 != nil {
+		b = append(b, socks5AddrIPv4)
+		b = append(b, ip4...)
+	} else {
+		b = append(b, socks5AddrIPv6)
+		b = append(b, addr.IP.To16()...)
+	}
+	return append(b, byte(addr.Port>>8), byte(addr.Port))
+}
+
+// ReadFrom reads a packet relayed by the proxy, the packets which are not sent by the relay are dropped.
+func (c *socks5Conn) ReadFrom(p []byte) (int, net.Addr, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if cap(c.buf) < len(p)+socks5HeaderSize {
+		c.buf = make([]byte, len(p)+socks5HeaderSize)
+	}
+	buf := c.buf[:len(p)+socks5HeaderSize]
+
+	for {
+		n, from, err := c.conn.ReadFromUDP(buf)
+		if err != nil {
+			return 0, nil, err
+		}
+		if !from.IP.Equal(c.relay.IP) || from.Port != c.relay.Port {
+			continue
+		}
+
+		// RSV(2) | FRAG(1) | ATYP | DST.ADDR | DST.PORT | DATA, the fragments are dropped.
+		if n < 4 || buf[2] != 0 {
+			continue
+		}
+		r := bytes.NewReader(buf[3:n])
+		ip, port, err := readSOCKS5Addr(r)
+		if err != nil {
+			continue
+		}
+		return copy(p, buf[n-r.Len():n]), &net.UDPAddr{IP: ip, Port: port}, nil
+	}
+}
+
+func (c *socks5Conn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	udpAddr, ok := addr.(*net.UDPAddr)
+	if !ok {
+		return 0, fmt.Errorf("quic: unexpected address %s", addr)
+	}
+
+	buf := appendSOCKS5Addr([]byte{0, 0, 0}, udpAddr)
+	buf = append(buf, p...)
+	if _, err := c.conn.WriteTo(buf, c.relay); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (c *socks5Conn) Close() error {
+	c.control.Close()
+	return c.conn.Close()
+}
+
+func (c *socks5Conn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+func (c *socks5Conn) SetDeadline(t time.Time) error {
+	return c.conn.SetDeadline(t)
+}
+
+func (c *socks5Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+func (c *socks5Conn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
@@ -0,0 +1,116 @@
+package quic
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// serveSOCKS5 accepts one UDP ASSOCIATE with the username and password, and relays the UDP packets.
+func serveSOCKS5(t *testing.T, listener net.Listener) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	buf := make([]byte, 512)
+	io.ReadFull(conn, buf[:3])
+	assert.Equal(t, []byte{socks5Version, 1, socks5AuthPassword}, buf[:3])
+	conn.Write([]byte{socks5Version, socks5AuthPassword})
+
+	io.ReadFull(conn, buf[:2])
+	ulen := int(buf[1])
+	io.ReadFull(conn, buf[:ulen+1])
+	user := string(buf[:ulen])
+	pass := make([]byte, buf[ulen])
+	io.ReadFull(conn, pass)
+	assert.Equal(t, "user", user)
+	assert.Equal(t, "pass", string(pass))
+	conn.Write([]byte{0x01, socks5Succeeded})
+
+	io.ReadFull(conn, buf[:10])
+	assert.Equal(t, byte(socks5CmdUDP), buf[1])
+
+	relay, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.NoError(t, err)
+	defer relay.Close()
+	conn.Write(appendSOCKS5Addr([]byte{socks5Version, socks5Succeeded, 0}, relay.LocalAddr().(*net.UDPAddr)))
+
+	var client net.Addr
+	go func() {
+		for {
+			n, from, err := relay.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if client == nil || from.String() == client.String() {
+				// client -> target
+				client = from
+				r := bytes.NewReader(buf[3:n])
+				ip, port, _ := readSOCKS5Addr(r)
+				relay.WriteTo(buf[n-r.Len():n], &net.UDPAddr{IP: ip, Port: port})
+			} else {
+				// target -> client
+				packet := appendSOCKS5Addr([]byte{0, 0, 0}, from.(*net.UDPAddr))
+				relay.WriteTo(append(packet, buf[:n]...), client)
+			}
+		}
+	}()
+
+	// the association is kept until the control connection is closed.
+	io.Copy(io.Discard, conn)
+}
+
+func TestSOCKS5Proxy(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	go serveSOCKS5(t, listener)
+
+	// the echo server behind the proxy.
+	echo, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.NoError(t, err)
+	defer echo.Close()
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, from, err := echo.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			echo.WriteTo(buf[:n], from)
+		}
+	}()
+
+	conn, target, err := dialProxy("socks5://user:pass@"+listener.Addr().String(), echo.LocalAddr().String())
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	// the packet which isn't sent by the relay is dropped.
+	spoof, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.NoError(t, err)
+	defer spoof.Close()
+	local := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: conn.LocalAddr().(*net.UDPAddr).Port}
+	_, err = spoof.WriteTo(append(appendSOCKS5Addr([]byte{0, 0, 0}, target), "spoofed"...), local)
+	assert.NoError(t, err)
+
+	_, err = conn.WriteTo([]byte("hello"), target)
+	assert.NoError(t, err)
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 512)
+	n, from, err := conn.ReadFrom(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(buf[:n]))
+	assert.Equal(t, target.String(), from.String())
+}
+
+func TestUnsupportedProxy(t *testing.T) {
+	_, _, err := dialProxy("https://proxy:443", "127.0.0.1:9000")
+	assert.Equal(t, ErrUnsupportedProxy, err)
+}
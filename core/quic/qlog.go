@@ -0,0 +1,79 @@
+package quic
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/lucas-clemente/quic-go/logging"
+	"github.com/lucas-clemente/quic-go/qlog"
+	"github.com/yomorun/yomo/logger"
+)
+
+// QlogWriter creates the writer of qlog trace for a connection, the perspective is "server" or "client"
+// and the connection ID is in hex. The writer is closed after the connection is closed, the connection
+// is not traced if it returns nil.
+type QlogWriter func(perspective string, connectionID string) io.WriteCloser
+
+// WithQlog writes the qlog traces of connections to the directory, one file for each connection,
+// the traces can be analyzed by qvis to debug the handshake failures and loss patterns.
+func WithQlog(dir string) Option {
+	return WithQlogWriter(qlogDir(dir))
+}
+
+// WithQlogWriter writes the qlog traces of connections to the writers.
+func WithQlogWriter(w QlogWriter) Option {
+	return func(o *options) {
+		o.qlog = w
+	}
+}
+
+// qlogDir creates the qlog file in the directory for each connection.
+func qlogDir(dir string) QlogWriter {
+	return func(perspective string, connectionID string) io.WriteCloser {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			logger.Error("[QUIC] create the qlog directory failed.", "dir", dir, "err", err)
+			return nil
+		}
+		name := filepath.Join(dir, fmt.Sprintf("%s_%s.qlog", connectionID, perspective))
+		f, err := os.Create(name)
+		if err != nil {
+			logger.Error("[QUIC] create the qlog file failed.", "file", name, "err", err)
+			return nil
+		}
+		return &bufferedWriteCloser{Writer: bufio.NewWriter(f), file: f}
+	}
+}
+
+// bufferedWriteCloser flushes the buffer before closing the file.
+type bufferedWriteCloser struct {
+	*bufio.Writer
+	file *os.File
+}
+
+func (w *bufferedWriteCloser) Close() error {
+	if err := w.Writer.Flush(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
+
+// tracer returns the tracer of QUIC connections, the stats are always collected.
+func (o *options) tracer() logging.Tracer {
+	stats := newStatsTracer()
+	if o.qlog == nil {
+		return stats
+	}
+
+	w := o.qlog
+	return logging.NewMultiplexedTracer(stats, qlog.NewTracer(func(p logging.Perspective, connectionID []byte) io.WriteCloser {
+		perspective := "client"
+		if p == logging.PerspectiveServer {
+			perspective = "server"
+		}
+		return w(perspective, fmt.Sprintf("%x", connectionID))
+	}))
+}
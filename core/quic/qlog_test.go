@@ -0,0 +1,22 @@
+package quic
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQlogDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "qlog")
+	w := qlogDir(dir)("server", "0a0b")
+	assert.NotNil(t, w)
+	_, err := w.Write([]byte("{}"))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+
+	buf, err := ioutil.ReadFile(filepath.Join(dir, "0a0b_server.qlog"))
+	assert.NoError(t, err)
+	assert.Equal(t, "{}", string(buf))
+}
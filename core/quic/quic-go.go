@@ -11,6 +11,7 @@ import (
 	"crypto/x509/pkix"
 	"encoding/pem"
 	"errors"
+	"io"
 	"math/big"
 	"net"
 	"time"
@@ -20,8 +21,9 @@ import (
 )
 
 type quicGoServer struct {
-	handler  ServerHandler
-	listener quicGo.Listener
+	handler   ServerHandler
+	listeners []io.Closer // listeners are the QUIC listeners and the TCP listeners of fallback.
+	opts      *options
 }
 
 func (s *quicGoServer) SetHandler(handler ServerHandler) {
@@ -37,25 +39,93 @@ func (s *quicGoServer) ListenAndServe(ctx context.Context, addr string) error {
 		MaxIncomingStreams:      1000000,
 		MaxIncomingUniStreams:   1000000,
 		DisablePathMTUDiscovery: true,
+		EnableDatagrams:         true,
+		Tracer:                  s.opts.tracer(),
 	}
+	if s.opts.idle > 0 {
+		conf.MaxIdleTimeout = s.opts.idle
+	}
+	s.opts.flow.apply(conf)
 
 	// listen the address
-	listener, err := quicGo.ListenAddr(addr, generateTLSConfig(addr), conf)
+	tlsConf := s.opts.tlsConfig
+	if tlsConf == nil {
+		tlsConf = generateTLSConfig(addr)
+	} else if len(tlsConf.NextProtos) == 0 {
+		tlsConf = tlsConf.Clone()
+		tlsConf.NextProtos = []string{nextProto}
+	}
+	accept, err := s.listen(addr, tlsConf, conf)
 	if err != nil {
 		return err
 	}
-	s.listener = listener
 
-	// serve
-	logger.Print("✅ Listening on " + addr)
+	// the sessions accepted on the other addresses are served by the same handler.
+	accepts := make([]func(ctx context.Context) (quicGo.Session, error), 0, len(s.opts.listenAddrs))
+	for _, a := range s.opts.listenAddrs {
+		acc, err := s.listen(a, tlsConf, conf)
+		if err != nil {
+			s.Close()
+			return err
+		}
+		accepts = append(accepts, acc)
+	}
 
+	// serve
 	if s.handler != nil {
 		s.handler.Listen()
 	}
 
+	for i, acc := range accepts {
+		go func(addr string, accept func(ctx context.Context) (quicGo.Session, error)) {
+			if err := s.serve(accept); err != nil {
+				logger.Debug("[QUIC server] stop accepting sessions.", "addr", addr, "err", err)
+			}
+		}(s.opts.listenAddrs[i], acc)
+	}
+
+	return s.serve(accept)
+}
+
+// listen listens on the address, and the TCP address if the fallback is enabled.
+func (s *quicGoServer) listen(addr string, tlsConf *tls.Config, conf *quicGo.Config) (func(ctx context.Context) (quicGo.Session, error), error) {
+	var accept func(ctx context.Context) (quicGo.Session, error)
+	if s.opts.earlyData {
+		listener, err := quicGo.ListenAddrEarly(addr, tlsConf, conf)
+		if err != nil {
+			return nil, err
+		}
+		s.listeners = append(s.listeners, listener)
+		accept = func(ctx context.Context) (quicGo.Session, error) {
+			return listener.Accept(ctx)
+		}
+	} else {
+		listener, err := quicGo.ListenAddr(addr, tlsConf, conf)
+		if err != nil {
+			return nil, err
+		}
+		s.listeners = append(s.listeners, listener)
+		accept = listener.Accept
+	}
+	logger.Print("✅ Listening on " + addr)
+
+	if s.opts.tcp {
+		tcpListener, err := listenTCP(addr, tlsConf, s.serveSession)
+		if err != nil {
+			return nil, err
+		}
+		s.listeners = append(s.listeners, tcpListener)
+		logger.Print("✅ Listening TCP fallback on " + addr)
+	}
+
+	return accept, nil
+}
+
+// serve accepts the sessions until the listener is closed.
+func (s *quicGoServer) serve(accept func(ctx context.Context) (quicGo.Session, error)) error {
 	for {
 		ctx, cancel := context.WithCancel(context.Background())
-		session, err := s.listener.Accept(ctx)
+		session, err := accept(ctx)
 		if err != nil {
 			cancel()
 			return err
@@ -63,27 +133,75 @@ func (s *quicGoServer) ListenAndServe(ctx context.Context, addr string) error {
 
 		go func(session quicGo.Session, cancel context.CancelFunc) {
 			defer cancel()
-			addr := session.RemoteAddr().String()
-
-			for {
-				stream, err := session.AcceptStream(context.Background())
-				if err != nil {
-					break
-				}
-				defer stream.Close()
-				if s.handler != nil {
-					s.handler.Read(addr, session, stream)
-				} else {
-					logger.Print("handler isn't set in QUIC server")
-					break
-				}
-			}
+			s.serveSession(session)
 		}(session, cancel)
 	}
 }
 
+// serveSession passes the streams of session to the handler until the session is closed.
+func (s *quicGoServer) serveSession(session quicGo.Session) {
+	addr := session.RemoteAddr().String()
+
+	if h, ok := s.handler.(DatagramHandler); ok {
+		go s.serveDatagrams(addr, session, h)
+	}
+
+	// the streams of logical channels are passed to the handler by the channel sessions.
+	mux := &muxListener{session: session}
+	// the headers are read concurrently, but the streams are passed to the handler in the accepted order,
+	// e.g. the signal stream of a client is always passed before its data streams.
+	prev := make(chan struct{})
+	close(prev)
+	for {
+		stream, err := session.AcceptStream(context.Background())
+		if err != nil {
+			break
+		}
+		defer stream.Close()
+		if s.handler == nil {
+			logger.Print("handler isn't set in QUIC server")
+			break
+		}
+
+		done := make(chan struct{})
+		go func(stream quicGo.Stream, prev <-chan struct{}, done chan<- struct{}) {
+			defer close(done)
+			streamAddr, sess, st, err := mux.route(addr, stream)
+			<-prev
+			if errors.Is(err, errMuxClosedByPeer) {
+				return
+			}
+			if err != nil {
+				logger.Debug("[QUIC server] read the stream header failed.", "addr", addr, "err", err)
+				stream.CancelRead(0)
+				return
+			}
+			s.handler.Read(streamAddr, sess, st)
+		}(stream, prev, done)
+		prev = done
+	}
+}
+
+// serveDatagrams reads the datagrams from the session until it's closed.
+func (s *quicGoServer) serveDatagrams(addr string, session quicGo.Session, h DatagramHandler) {
+	reader := NewDatagramReader(session)
+	for {
+		data, err := reader.ReadDatagram()
+		if err != nil {
+			logger.Debug("[QUIC server] stop reading datagrams.", "addr", addr, "err", err)
+			return
+		}
+
+		if err := h.ReadDatagram(addr, session, data); err != nil {
+			logger.Debug("[QUIC server] handle the datagram failed.", "addr", addr, "err", err)
+		}
+	}
+}
+
 type quicGoClient struct {
 	session quicGo.Session
+	opts    *options
+	proxy   *socks5Conn // proxy is the socket associated by the proxy, it's nil if no proxy is used.
 }
 
 func (c *quicGoClient) Connect(addr string) error {
@@ -92,19 +210,93 @@ func (c *quicGoClient) Connect(addr string) error {
 		NextProtos:         []string{"spdy/3", "h2", "hq-29"},
 		ClientSessionCache: tls.NewLRUClientSessionCache(1),
 	}
+	if c.opts.tlsConfig != nil {
+		tlsConf = c.opts.tlsConfig.Clone()
+		if len(tlsConf.NextProtos) == 0 {
+			tlsConf.NextProtos = []string{nextProto}
+		}
+		if tlsConf.ClientSessionCache == nil {
+			tlsConf.ClientSessionCache = tls.NewLRUClientSessionCache(1)
+		}
+	}
 
-	session, err := quicGo.DialAddr(addr, tlsConf, &quicGo.Config{
+	conf := &quicGo.Config{
 		MaxIdleTimeout:        time.Minute * 10080,
 		KeepAlive:             true,
 		MaxIncomingStreams:    1000000,
 		MaxIncomingUniStreams: 1000000,
 		TokenStore:            quicGo.NewLRUTokenStore(1, 1),
-	})
+		EnableDatagrams:       true,
+		Tracer:                c.opts.tracer(),
+	}
+	if c.opts.idle > 0 {
+		conf.MaxIdleTimeout = c.opts.idle
+	}
+	c.opts.flow.apply(conf)
+
+	// reuse the session tickets and tokens of previous connections.
+	if store := c.opts.resumption; store != nil {
+		tlsConf.ClientSessionCache = store.sessions
+		conf.TokenStore = store.tokens
+	}
+
+	err := c.dial(addr, tlsConf, conf)
+	if err != nil && c.opts.tcp {
+		logger.Printf("QUIC dial to %s failed, fall back to TCP: %v", addr, err)
+		c.session, err = dialTCP(addr, tlsConf)
+	}
+	return err
+}
+
+// dial dials the QUIC session.
+func (c *quicGoClient) dial(addr string, tlsConf *tls.Config, conf *quicGo.Config) error {
+	if proxy := proxyURL(c.opts.proxy); proxy != "" {
+		return c.dialProxy(proxy, addr, tlsConf, conf)
+	}
+
+	if c.opts.earlyData {
+		// the streams can be opened and written before the handshake completes.
+		session, err := quicGo.DialAddrEarly(addr, tlsConf, conf)
+		if err != nil {
+			return err
+		}
+		c.session = session
+		return nil
+	}
+
+	session, err := quicGo.DialAddr(addr, tlsConf, conf)
+	if err != nil {
+		return err
+	}
+	c.session = session
+	return nil
+}
+
+// dialProxy dials the QUIC session by the proxy.
+func (c *quicGoClient) dialProxy(proxy string, addr string, tlsConf *tls.Config, conf *quicGo.Config) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	pconn, remoteAddr, err := dialProxy(proxy, addr)
+	if err != nil {
+		return err
+	}
+	logger.Debug("[QUIC client] dial by the proxy.", "addr", addr, "relay", pconn.relay.String())
 
+	var session quicGo.Session
+	if c.opts.earlyData {
+		session, err = quicGo.DialEarly(pconn, remoteAddr, host, tlsConf, conf)
+	} else {
+		session, err = quicGo.Dial(pconn, remoteAddr, host, tlsConf, conf)
+	}
 	if err != nil {
+		pconn.Close()
 		return err
 	}
+
 	c.session = session
+	c.proxy = pconn
 	return nil
 }
 
@@ -140,8 +332,30 @@ func (c *quicGoClient) CreateUniStream(ctx context.Context) (SendStream, error)
 	return c.session.OpenUniStream()
 }
 
+func (c *quicGoClient) SendDatagram(data []byte) error {
+	if c.session == nil {
+		return errors.New("[QUIC client] session is nil")
+	}
+
+	if len(data) > MaxDatagramSize {
+		return ErrDatagramTooLarge
+	}
+
+	return c.session.SendMessage(data)
+}
+
+// Context returns a context that is cancelled when the session is closed.
+func (c *quicGoClient) Context() context.Context {
+	return c.session.Context()
+}
+
 func (c *quicGoClient) Close() error {
-	return c.session.CloseWithError(0, "")
+	err := c.session.CloseWithError(0, "")
+	// the socket passed to quic-go.Dial is not closed by the session.
+	if c.proxy != nil {
+		c.proxy.Close()
+	}
+	return err
 }
 
 // generateTLSConfig Setup a bare-bones TLS config for the server
@@ -222,9 +436,12 @@ func generateCertificate(host ...string) (tls.Certificate, error) {
 
 // Close the server. All active sessions will be closed.
 func (s *quicGoServer) Close() error {
-	if s.listener != nil {
-		logger.Debug("quicGoServer closing...")
-		return s.listener.Close()
+	logger.Debug("quicGoServer closing...")
+	var err error
+	for _, listener := range s.listeners {
+		if e := listener.Close(); e != nil && err == nil {
+			err = e
+		}
 	}
-	return nil
+	return err
 }
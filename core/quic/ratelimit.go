@@ -0,0 +1,89 @@
+package quic
+
+import (
+	"io"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimiter limits the bytes per second by a token bucket, it's shared by the streams of a connection
+// to cap the bandwidth of the connection.
+type RateLimiter struct {
+	mutex  sync.Mutex
+	rate   float64 // rate is the bytes per second.
+	burst  float64 // burst is the max bytes which can be read at once after idle.
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter creates a RateLimiter of the bytes per second, the burst is one second of the rate.
+func NewRateLimiter(bytesPerSecond int) *RateLimiter {
+	return &RateLimiter{
+		rate:   float64(bytesPerSecond),
+		burst:  float64(bytesPerSecond),
+		tokens: float64(bytesPerSecond),
+		last:   time.Now(),
+	}
+}
+
+// reserve takes n bytes from the bucket and returns the duration to wait until the bucket isn't in debt.
+func (l *RateLimiter) reserve(n int) time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens -= float64(n)
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// Wait blocks until n bytes are allowed.
+func (l *RateLimiter) Wait(n int) {
+	if d := l.reserve(n); d > 0 {
+		time.Sleep(d)
+	}
+}
+
+// rateLimitedStream pauses reading the stream after the bandwidth is exceeded, the data is not buffered
+// by the reader, so the flow control of QUIC slows down the peer.
+type rateLimitedStream struct {
+	Stream
+	limiter *RateLimiter
+}
+
+// NewRateLimitedStream wraps the stream whose reads are limited by the limiter.
+func NewRateLimitedStream(st Stream, limiter *RateLimiter) Stream {
+	return &rateLimitedStream{Stream: st, limiter: limiter}
+}
+
+func (s *rateLimitedStream) Read(p []byte) (int, error) {
+	n, err := s.Stream.Read(p)
+	if n > 0 {
+		s.limiter.Wait(n)
+	}
+	return n, err
+}
+
+// rateLimitedReader pauses reading after the bandwidth is exceeded, e.g. the unidirectional streams.
+type rateLimitedReader struct {
+	io.Reader
+	limiter *RateLimiter
+}
+
+// NewRateLimitedReader wraps the reader whose reads are limited by the limiter.
+func NewRateLimitedReader(r io.Reader, limiter *RateLimiter) io.Reader {
+	return &rateLimitedReader{Reader: r, limiter: limiter}
+}
+
+func (r *rateLimitedReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if n > 0 {
+		r.limiter.Wait(n)
+	}
+	return n, err
+}
@@ -0,0 +1,23 @@
+package quic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := NewRateLimiter(1000)
+
+	// the burst is allowed without waiting.
+	assert.Equal(t, time.Duration(0), l.reserve(1000))
+
+	// the debt is paid back at the rate.
+	d := l.reserve(500)
+	assert.True(t, d > 400*time.Millisecond && d <= 500*time.Millisecond, "wait %v", d)
+
+	start := time.Now()
+	l.Wait(100)
+	assert.True(t, time.Since(start) >= 500*time.Millisecond)
+}
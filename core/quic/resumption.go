@@ -0,0 +1,22 @@
+package quic
+
+import (
+	"crypto/tls"
+
+	quicGo "github.com/lucas-clemente/quic-go"
+)
+
+// ResumptionStore keeps the TLS session tickets and the address validation tokens between connections,
+// so a reconnecting client can resume the session and send 0-RTT data without a full handshake round trip.
+type ResumptionStore struct {
+	sessions tls.ClientSessionCache
+	tokens   quicGo.TokenStore
+}
+
+// NewResumptionStore creates a new ResumptionStore.
+func NewResumptionStore() *ResumptionStore {
+	return &ResumptionStore{
+		sessions: tls.NewLRUClientSessionCache(10),
+		tokens:   quicGo.NewLRUTokenStore(10, 4),
+	}
+}
@@ -26,8 +26,10 @@ type ServerHandler interface {
 }
 
 // NewServer inits the default implementation of QUIC server.
-func NewServer(handler ServerHandler) Server {
-	server := &quicGoServer{}
+func NewServer(handler ServerHandler, opts ...Option) Server {
+	server := &quicGoServer{
+		opts: newOptions(opts...),
+	}
 	server.SetHandler(handler)
 	return server
 }
@@ -0,0 +1,159 @@
+package quic
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/logging"
+)
+
+// Stats is the transport telemetry of a QUIC session.
+type Stats struct {
+	// SmoothedRTT is the smoothed round-trip time.
+	SmoothedRTT time.Duration
+	// LatestRTT is the latest sample of round-trip time.
+	LatestRTT time.Duration
+	// MinRTT is the min round-trip time observed.
+	MinRTT time.Duration
+	// PacketsSent is the count of packets sent.
+	PacketsSent uint64
+	// PacketsLost is the count of packets declared lost.
+	PacketsLost uint64
+	// LossRate is the ratio of the lost packets to the sent packets.
+	LossRate float64
+	// BytesSent is the size of packets sent.
+	BytesSent uint64
+	// BytesReceived is the size of packets received.
+	BytesReceived uint64
+	// Throughput is the bytes per second sent and received since the session started.
+	Throughput float64
+}
+
+// sessionStats is the collectors of the QUIC sessions by remote address.
+var sessionStats = sync.Map{}
+
+// StatsOf returns the transport telemetry of the session, false is returned if the stats are not collected,
+// e.g. the session falls back to TCP. The stats are looked up by the remote address, the stats of a session
+// are lost after it migrates to a new address.
+func StatsOf(session Session) (Stats, bool) {
+	v, ok := sessionStats.Load(session.RemoteAddr().String())
+	if !ok {
+		return Stats{}, false
+	}
+	return v.(*statsTracer).stats(), true
+}
+
+// newStatsTracer creates the tracer which collects the stats of each QUIC session.
+func newStatsTracer() logging.Tracer {
+	return &statsTracerFactory{}
+}
+
+type statsTracerFactory struct{}
+
+func (f *statsTracerFactory) TracerForConnection(ctx context.Context, p logging.Perspective, odcid logging.ConnectionID) logging.ConnectionTracer {
+	return &statsTracer{}
+}
+
+func (f *statsTracerFactory) SentPacket(net.Addr, *logging.Header, logging.ByteCount, []logging.Frame) {
+}
+
+func (f *statsTracerFactory) DroppedPacket(net.Addr, logging.PacketType, logging.ByteCount, logging.PacketDropReason) {
+}
+
+var _ logging.ConnectionTracer = &statsTracer{}
+
+// statsTracer collects the stats of a QUIC session, the methods which are not related to stats are no-ops.
+type statsTracer struct {
+	addr          string
+	startedAt     time.Time
+	packetsSent   uint64
+	packetsLost   uint64
+	bytesSent     uint64
+	bytesReceived uint64
+
+	mutex       sync.RWMutex
+	smoothedRTT time.Duration
+	latestRTT   time.Duration
+	minRTT      time.Duration
+}
+
+func (t *statsTracer) stats() Stats {
+	s := Stats{
+		PacketsSent:   atomic.LoadUint64(&t.packetsSent),
+		PacketsLost:   atomic.LoadUint64(&t.packetsLost),
+		BytesSent:     atomic.LoadUint64(&t.bytesSent),
+		BytesReceived: atomic.LoadUint64(&t.bytesReceived),
+	}
+	if s.PacketsSent > 0 {
+		s.LossRate = float64(s.PacketsLost) / float64(s.PacketsSent)
+	}
+	if elapsed := time.Since(t.startedAt).Seconds(); elapsed > 0 {
+		s.Throughput = float64(s.BytesSent+s.BytesReceived) / elapsed
+	}
+
+	t.mutex.RLock()
+	s.SmoothedRTT = t.smoothedRTT
+	s.LatestRTT = t.latestRTT
+	s.MinRTT = t.minRTT
+	t.mutex.RUnlock()
+	return s
+}
+
+func (t *statsTracer) StartedConnection(local, remote net.Addr, srcConnID, destConnID logging.ConnectionID) {
+	t.addr = remote.String()
+	t.startedAt = time.Now()
+	sessionStats.Store(t.addr, t)
+}
+
+func (t *statsTracer) Close() {
+	if v, ok := sessionStats.Load(t.addr); ok && v == t {
+		sessionStats.Delete(t.addr)
+	}
+}
+
+func (t *statsTracer) SentPacket(hdr *logging.ExtendedHeader, size logging.ByteCount, ack *logging.AckFrame, frames []logging.Frame) {
+	atomic.AddUint64(&t.packetsSent, 1)
+	atomic.AddUint64(&t.bytesSent, uint64(size))
+}
+
+func (t *statsTracer) ReceivedPacket(hdr *logging.ExtendedHeader, size logging.ByteCount, frames []logging.Frame) {
+	atomic.AddUint64(&t.bytesReceived, uint64(size))
+}
+
+func (t *statsTracer) LostPacket(logging.EncryptionLevel, logging.PacketNumber, logging.PacketLossReason) {
+	atomic.AddUint64(&t.packetsLost, 1)
+}
+
+func (t *statsTracer) UpdatedMetrics(rttStats *logging.RTTStats, cwnd, bytesInFlight logging.ByteCount, packetsInFlight int) {
+	t.mutex.Lock()
+	t.smoothedRTT = rttStats.SmoothedRTT()
+	t.latestRTT = rttStats.LatestRTT()
+	t.minRTT = rttStats.MinRTT()
+	t.mutex.Unlock()
+}
+
+func (t *statsTracer) NegotiatedVersion(chosen logging.VersionNumber, clientVersions, serverVersions []logging.VersionNumber) {
+}
+func (t *statsTracer) ClosedConnection(error)                                                    {}
+func (t *statsTracer) SentTransportParameters(*logging.TransportParameters)                      {}
+func (t *statsTracer) ReceivedTransportParameters(*logging.TransportParameters)                  {}
+func (t *statsTracer) RestoredTransportParameters(*logging.TransportParameters)                  {}
+func (t *statsTracer) ReceivedVersionNegotiationPacket(*logging.Header, []logging.VersionNumber) {}
+func (t *statsTracer) ReceivedRetry(*logging.Header)                                             {}
+func (t *statsTracer) BufferedPacket(logging.PacketType)                                         {}
+func (t *statsTracer) DroppedPacket(logging.PacketType, logging.ByteCount, logging.PacketDropReason) {
+}
+func (t *statsTracer) AcknowledgedPacket(logging.EncryptionLevel, logging.PacketNumber)   {}
+func (t *statsTracer) UpdatedCongestionState(logging.CongestionState)                     {}
+func (t *statsTracer) UpdatedPTOCount(value uint32)                                       {}
+func (t *statsTracer) UpdatedKeyFromTLS(logging.EncryptionLevel, logging.Perspective)     {}
+func (t *statsTracer) UpdatedKey(generation logging.KeyPhase, remote bool)                {}
+func (t *statsTracer) DroppedEncryptionLevel(logging.EncryptionLevel)                     {}
+func (t *statsTracer) DroppedKey(generation logging.KeyPhase)                             {}
+func (t *statsTracer) SetLossTimer(logging.TimerType, logging.EncryptionLevel, time.Time) {}
+func (t *statsTracer) LossTimerExpired(logging.TimerType, logging.EncryptionLevel)        {}
+func (t *statsTracer) LossTimerCanceled()                                                 {}
+func (t *statsTracer) Debug(name, msg string)                                             {}
@@ -0,0 +1,48 @@
+package quic
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	quicGo "github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockSession struct {
+	quicGo.Session
+	addr net.Addr
+}
+
+func (s *mockSession) RemoteAddr() net.Addr {
+	return s.addr
+}
+
+func TestStatsOf(t *testing.T) {
+	addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 9001}
+	session := &mockSession{addr: addr}
+	_, ok := StatsOf(session)
+	assert.False(t, ok)
+
+	tracer := newStatsTracer().TracerForConnection(context.Background(), logging.PerspectiveServer, nil)
+	tracer.StartedConnection(nil, addr, nil, nil)
+	for i := 0; i < 4; i++ {
+		tracer.SentPacket(nil, 100, nil, nil)
+	}
+	tracer.ReceivedPacket(nil, 50, nil)
+	tracer.LostPacket(0, 1, 0)
+
+	stats, ok := StatsOf(session)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(4), stats.PacketsSent)
+	assert.Equal(t, uint64(1), stats.PacketsLost)
+	assert.Equal(t, 0.25, stats.LossRate)
+	assert.Equal(t, uint64(400), stats.BytesSent)
+	assert.Equal(t, uint64(50), stats.BytesReceived)
+	assert.True(t, stats.Throughput > 0)
+
+	tracer.Close()
+	_, ok = StatsOf(session)
+	assert.False(t, ok)
+}
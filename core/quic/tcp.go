@@ -0,0 +1,715 @@
+package quic
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	quicGo "github.com/lucas-clemente/quic-go"
+	"github.com/yomorun/yomo/logger"
+)
+
+// The TCP fallback carries the same frame protocol in the streams which are multiplexed on a TLS connection,
+// every frame of multiplexer is: type (1 byte) | stream ID (varint) | length (varint) | payload.
+// The streams are identified like QUIC: the lowest bit is the initiator and the second bit is the direction.
+// Unlike QUIC, the streams share the head-of-line blocking of TCP and the datagrams are reliable.
+const (
+	tcpFrameData     byte = 0x00
+	tcpFrameFin      byte = 0x01
+	tcpFrameReset    byte = 0x02
+	tcpFrameDatagram byte = 0x03
+	tcpFrameClose    byte = 0x04
+	tcpFrameWindow   byte = 0x05
+
+	// tcpMaxPayload is the max payload of a frame, the larger writes are split.
+	tcpMaxPayload = 16 * 1024
+	// tcpStreamWindow is the bytes which can be sent in a stream before the peer reads them, it applies the
+	// backpressure of each stream like the flow control of QUIC, so a slow stream doesn't block the others.
+	tcpStreamWindow = 256 * 1024
+	// tcpDialTimeout is the timeout of dialing the TCP fallback.
+	tcpDialTimeout = 10 * time.Second
+	// tcpHandshakeTimeout is the timeout of the TLS handshake of an accepted connection.
+	tcpHandshakeTimeout = 10 * time.Second
+)
+
+// tcpCloseError is the error when the session is closed, its message is the same as the application error of QUIC.
+type tcpCloseError struct {
+	code   quicGo.ApplicationErrorCode
+	reason string
+}
+
+func (e *tcpCloseError) Error() string {
+	if e.reason == "" {
+		return fmt.Sprintf("Application error %#x", uint64(e.code))
+	}
+	return fmt.Sprintf("Application error %#x: %s", uint64(e.code), e.reason)
+}
+
+// dialTCP dials the TCP fallback of YoMo-Zipper.
+func dialTCP(addr string, tlsConf *tls.Config) (quicGo.Session, error) {
+	dialer := &net.Dialer{Timeout: tcpDialTimeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", addr, tlsConf)
+	if err != nil {
+		return nil, err
+	}
+	return newTCPSession(conn, true), nil
+}
+
+// tcpSession implements the QUIC session by multiplexing the streams on a TLS connection.
+type tcpSession struct {
+	conn      *tls.Conn
+	reader    *bufio.Reader
+	isClient  bool
+	writeMu   sync.Mutex
+	mu        sync.Mutex
+	streams   map[quicGo.StreamID]*tcpStream
+	nextBidi  quicGo.StreamID
+	nextUni   quicGo.StreamID
+	accept    chan *tcpStream
+	acceptUni chan *tcpStream
+	datagrams chan []byte
+	ctx       context.Context
+	cancel    context.CancelFunc
+	err       error // err is the reason why the session is closed.
+}
+
+func newTCPSession(conn *tls.Conn, isClient bool) *tcpSession {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &tcpSession{
+		conn:      conn,
+		reader:    bufio.NewReader(conn),
+		isClient:  isClient,
+		streams:   make(map[quicGo.StreamID]*tcpStream),
+		accept:    make(chan *tcpStream, 100),
+		acceptUni: make(chan *tcpStream, 100),
+		datagrams: make(chan []byte, 100),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+	// the client initiated streams are even, the server initiated streams are odd.
+	s.nextBidi, s.nextUni = 0, 2
+	if !isClient {
+		s.nextBidi, s.nextUni = 1, 3
+	}
+	go s.run()
+	return s
+}
+
+// run reads the frames and dispatches them to the streams until the connection is closed.
+func (s *tcpSession) run() {
+	for {
+		frameType, id, payload, err := s.readFrame()
+		if err != nil {
+			s.close(err)
+			return
+		}
+
+		switch frameType {
+		case tcpFrameDatagram:
+			select {
+			case s.datagrams <- payload:
+			default:
+				logger.Debug("[TCP fallback] drop the datagram because the buffer is full.")
+			}
+		case tcpFrameClose:
+			r := bytes.NewReader(payload)
+			code, _ := readVarint(r)
+			reason, _ := io.ReadAll(r)
+			s.close(&tcpCloseError{code: quicGo.ApplicationErrorCode(code), reason: string(reason)})
+			return
+		case tcpFrameWindow:
+			// the window update may arrive after this side finished reading, so the stream isn't created.
+			credit, err := readVarint(bytes.NewReader(payload))
+			if err != nil {
+				s.close(err)
+				return
+			}
+			if stream := s.lookupStream(id); stream != nil {
+				stream.grant(int(credit))
+			}
+		default:
+			stream := s.peerStream(id)
+			if stream == nil {
+				continue
+			}
+			switch frameType {
+			case tcpFrameData:
+				if err := stream.push(payload); err != nil {
+					s.close(err)
+					return
+				}
+			case tcpFrameFin:
+				stream.finRead()
+			case tcpFrameReset:
+				stream.reset(errors.New("stream reset by peer"))
+			}
+		}
+	}
+}
+
+func (s *tcpSession) readFrame() (byte, quicGo.StreamID, []byte, error) {
+	frameType, err := s.reader.ReadByte()
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	id, err := readVarint(s.reader)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	length, err := readVarint(s.reader)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	if length > tcpMaxPayload+1024 {
+		return 0, 0, nil, fmt.Errorf("[TCP fallback] the frame is too large: %d", length)
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(s.reader, payload); err != nil {
+		return 0, 0, nil, err
+	}
+	return frameType, quicGo.StreamID(id), payload, nil
+}
+
+func (s *tcpSession) writeFrame(frameType byte, id quicGo.StreamID, payload []byte) error {
+	buf := append([]byte{frameType}, appendVarint(nil, uint64(id))...)
+	buf = appendVarint(buf, uint64(len(payload)))
+	buf = append(buf, payload...)
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if s.ctx.Err() != nil {
+		return s.closeErr()
+	}
+	_, err := s.conn.Write(buf)
+	return err
+}
+
+// lookupStream returns the stream by id, it's nil if the stream is not open.
+func (s *tcpSession) lookupStream(id quicGo.StreamID) *tcpStream {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.streams[id]
+}
+
+// peerStream returns the stream by id, the stream is created if it's a new stream initiated by peer.
+func (s *tcpSession) peerStream(id quicGo.StreamID) *tcpStream {
+	s.mu.Lock()
+	if stream, ok := s.streams[id]; ok {
+		s.mu.Unlock()
+		return stream
+	}
+	// the stream initiated by this side was closed.
+	if (id&1 == 0) == s.isClient {
+		s.mu.Unlock()
+		return nil
+	}
+	stream := newTCPStream(s, id)
+	s.streams[id] = stream
+	s.mu.Unlock()
+
+	accept := s.accept
+	if id&2 != 0 {
+		accept = s.acceptUni
+	}
+	select {
+	case accept <- stream:
+	case <-s.ctx.Done():
+	}
+	return stream
+}
+
+func (s *tcpSession) openStream(uni bool) (*tcpStream, error) {
+	if s.ctx.Err() != nil {
+		return nil, s.closeErr()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var id quicGo.StreamID
+	if uni {
+		id = s.nextUni
+		s.nextUni += 4
+	} else {
+		id = s.nextBidi
+		s.nextBidi += 4
+	}
+	stream := newTCPStream(s, id)
+	s.streams[id] = stream
+	return stream, nil
+}
+
+func (s *tcpSession) removeStream(id quicGo.StreamID) {
+	s.mu.Lock()
+	delete(s.streams, id)
+	s.mu.Unlock()
+}
+
+func (s *tcpSession) close(err error) {
+	s.mu.Lock()
+	if s.err == nil {
+		s.err = err
+	}
+	s.mu.Unlock()
+	s.cancel()
+	s.conn.Close()
+}
+
+func (s *tcpSession) closeErr() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err == nil {
+		return errors.New(ErrConnectionClosed)
+	}
+	return s.err
+}
+
+func (s *tcpSession) AcceptStream(ctx context.Context) (quicGo.Stream, error) {
+	select {
+	case stream := <-s.accept:
+		return stream, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-s.ctx.Done():
+		return nil, s.closeErr()
+	}
+}
+
+func (s *tcpSession) AcceptUniStream(ctx context.Context) (quicGo.ReceiveStream, error) {
+	select {
+	case stream := <-s.acceptUni:
+		return stream, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-s.ctx.Done():
+		return nil, s.closeErr()
+	}
+}
+
+func (s *tcpSession) OpenStream() (quicGo.Stream, error) {
+	return s.openStream(false)
+}
+
+func (s *tcpSession) OpenStreamSync(ctx context.Context) (quicGo.Stream, error) {
+	return s.openStream(false)
+}
+
+func (s *tcpSession) OpenUniStream() (quicGo.SendStream, error) {
+	return s.openStream(true)
+}
+
+func (s *tcpSession) OpenUniStreamSync(ctx context.Context) (quicGo.SendStream, error) {
+	return s.openStream(true)
+}
+
+func (s *tcpSession) LocalAddr() net.Addr {
+	return s.conn.LocalAddr()
+}
+
+func (s *tcpSession) RemoteAddr() net.Addr {
+	return s.conn.RemoteAddr()
+}
+
+func (s *tcpSession) CloseWithError(code quicGo.ApplicationErrorCode, reason string) error {
+	if s.ctx.Err() != nil {
+		return nil
+	}
+	payload := append(appendVarint(nil, uint64(code)), reason...)
+	err := s.writeFrame(tcpFrameClose, 0, payload)
+	s.close(&tcpCloseError{code: code, reason: reason})
+	return err
+}
+
+func (s *tcpSession) Context() context.Context {
+	return s.ctx
+}
+
+// ConnectionState returns the TLS state of the connection, the datagrams are supported but they're reliable.
+func (s *tcpSession) ConnectionState() quicGo.ConnectionState {
+	tlsState := s.conn.ConnectionState()
+	var state quicGo.ConnectionState
+	state.TLS.Version = tlsState.Version
+	state.TLS.HandshakeComplete = tlsState.HandshakeComplete
+	state.TLS.CipherSuite = tlsState.CipherSuite
+	state.TLS.NegotiatedProtocol = tlsState.NegotiatedProtocol
+	state.TLS.ServerName = tlsState.ServerName
+	state.TLS.PeerCertificates = tlsState.PeerCertificates
+	state.TLS.VerifiedChains = tlsState.VerifiedChains
+	state.SupportsDatagrams = true
+	return state
+}
+
+func (s *tcpSession) SendMessage(data []byte) error {
+	if len(data) > MaxDatagramSize {
+		return ErrDatagramTooLarge
+	}
+	return s.writeFrame(tcpFrameDatagram, 0, data)
+}
+
+func (s *tcpSession) ReceiveMessage() ([]byte, error) {
+	select {
+	case data := <-s.datagrams:
+		return data, nil
+	case <-s.ctx.Done():
+		return nil, s.closeErr()
+	}
+}
+
+// tcpStream is a stream multiplexed on the TLS connection.
+type tcpStream struct {
+	id            quicGo.StreamID
+	session       *tcpSession
+	mu            sync.Mutex
+	chunks        [][]byte      // chunks are the received payloads which are not read yet.
+	buffered      int           // buffered is the size of the received payloads which are not read yet.
+	consumed      int           // consumed is the size of the payloads read since the last window update.
+	fin           bool          // fin indicates the peer finished writing.
+	credit        int           // credit is the bytes which can be sent until the peer updates the window.
+	readDone      bool          // readDone indicates the read direction is finished, reset or cancelled.
+	writeDone     bool          // writeDone indicates the write direction is closed or cancelled.
+	readNotify    chan struct{} // readNotify is signaled when a payload is received or the peer finishes writing.
+	writeNotify   chan struct{} // writeNotify is signaled when the peer updates the window.
+	buf           []byte        // buf is the unread part of the current chunk.
+	readCancelled chan struct{} // readCancelled is closed when CancelRead is called.
+	resetCh       chan struct{} // resetCh is closed when the stream is reset by peer.
+	resetErr      error
+	finOnce       sync.Once
+	closeOnce     sync.Once
+	cancelOnce    sync.Once
+	resetOnce     sync.Once
+	ctx           context.Context
+	cancel        context.CancelFunc
+	deadlineMu    sync.Mutex
+	readDeadline  time.Time
+	writeDeadline time.Time
+}
+
+func newTCPStream(session *tcpSession, id quicGo.StreamID) *tcpStream {
+	ctx, cancel := context.WithCancel(session.ctx)
+	s := &tcpStream{
+		id:            id,
+		session:       session,
+		credit:        tcpStreamWindow,
+		readNotify:    make(chan struct{}, 1),
+		writeNotify:   make(chan struct{}, 1),
+		readCancelled: make(chan struct{}),
+		resetCh:       make(chan struct{}),
+		ctx:           ctx,
+		cancel:        cancel,
+	}
+	// the unidirectional streams have only one direction.
+	if id&2 != 0 {
+		s.readDone = s.initiatedByLocal()
+		s.writeDone = !s.initiatedByLocal()
+	}
+	return s
+}
+
+func (s *tcpStream) StreamID() quicGo.StreamID {
+	return s.id
+}
+
+func (s *tcpStream) Read(p []byte) (int, error) {
+	if len(s.buf) == 0 {
+		s.deadlineMu.Lock()
+		deadline := s.readDeadline
+		s.deadlineMu.Unlock()
+
+		var timeout <-chan time.Time
+		if !deadline.IsZero() {
+			timer := time.NewTimer(time.Until(deadline))
+			defer timer.Stop()
+			timeout = timer.C
+		}
+
+		for len(s.buf) == 0 {
+			chunk, fin := s.pop()
+			if chunk != nil {
+				s.buf = chunk
+				break
+			}
+			if fin {
+				return 0, io.EOF
+			}
+
+			select {
+			case <-s.readNotify:
+			case <-s.resetCh:
+				return 0, s.resetErr
+			case <-s.readCancelled:
+				return 0, errors.New("[TCP fallback] read on a cancelled stream")
+			case <-s.session.ctx.Done():
+				return 0, s.session.closeErr()
+			case <-timeout:
+				return 0, errors.New("[TCP fallback] read deadline exceeded")
+			}
+		}
+	}
+
+	n := copy(p, s.buf)
+	s.buf = s.buf[n:]
+	s.consume(n)
+	return n, nil
+}
+
+// push buffers the payload received from peer, it returns an error if the peer exceeds the window.
+func (s *tcpStream) push(payload []byte) error {
+	if len(payload) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	if s.readDone {
+		// the payload is discarded, the window is given back so the peer isn't blocked.
+		s.mu.Unlock()
+		return s.session.writeFrame(tcpFrameWindow, s.id, appendVarint(nil, uint64(len(payload))))
+	}
+	if s.buffered+len(payload) > tcpStreamWindow {
+		s.mu.Unlock()
+		return fmt.Errorf("[TCP fallback] the stream %d exceeds the window", s.id)
+	}
+	s.chunks = append(s.chunks, payload)
+	s.buffered += len(payload)
+	s.mu.Unlock()
+
+	signal(s.readNotify)
+	return nil
+}
+
+// pop returns the next received payload, it's nil if no payload is buffered.
+func (s *tcpStream) pop() ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.chunks) == 0 {
+		return nil, s.fin
+	}
+	chunk := s.chunks[0]
+	s.chunks[0] = nil
+	s.chunks = s.chunks[1:]
+	return chunk, false
+}
+
+// consume counts the bytes read, the window is updated after half of it is read.
+func (s *tcpStream) consume(n int) {
+	s.mu.Lock()
+	s.buffered -= n
+	s.consumed += n
+	if s.consumed < tcpStreamWindow/2 || s.fin {
+		s.mu.Unlock()
+		return
+	}
+	credit := s.consumed
+	s.consumed = 0
+	s.mu.Unlock()
+
+	s.session.writeFrame(tcpFrameWindow, s.id, appendVarint(nil, uint64(credit)))
+}
+
+// grant the credit of writing when the peer updates the window.
+func (s *tcpStream) grant(credit int) {
+	s.mu.Lock()
+	s.credit += credit
+	s.mu.Unlock()
+	signal(s.writeNotify)
+}
+
+// reserve waits until the window is available, it returns the bytes which can be written.
+func (s *tcpStream) reserve(size int, timeout <-chan time.Time) (int, error) {
+	for {
+		s.mu.Lock()
+		if s.credit > 0 {
+			n := size
+			if n > tcpMaxPayload {
+				n = tcpMaxPayload
+			}
+			if n > s.credit {
+				n = s.credit
+			}
+			s.credit -= n
+			s.mu.Unlock()
+			return n, nil
+		}
+		s.mu.Unlock()
+
+		select {
+		case <-s.writeNotify:
+		case <-s.ctx.Done():
+			return 0, errors.New("[TCP fallback] write on a closed stream")
+		case <-timeout:
+			return 0, errors.New("[TCP fallback] write deadline exceeded")
+		}
+	}
+}
+
+func (s *tcpStream) CancelRead(code quicGo.StreamErrorCode) {
+	s.cancelOnce.Do(func() {
+		close(s.readCancelled)
+		s.mu.Lock()
+		s.chunks = nil
+		s.mu.Unlock()
+		s.done(true)
+	})
+}
+
+func (s *tcpStream) SetReadDeadline(t time.Time) error {
+	s.deadlineMu.Lock()
+	s.readDeadline = t
+	s.deadlineMu.Unlock()
+	return nil
+}
+
+func (s *tcpStream) Write(p []byte) (int, error) {
+	s.deadlineMu.Lock()
+	deadline := s.writeDeadline
+	s.deadlineMu.Unlock()
+	if !deadline.IsZero() && time.Now().After(deadline) {
+		return 0, errors.New("[TCP fallback] write deadline exceeded")
+	}
+	if s.ctx.Err() != nil {
+		return 0, errors.New("[TCP fallback] write on a closed stream")
+	}
+
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	written := 0
+	for len(p) > 0 {
+		n, err := s.reserve(len(p), timeout)
+		if err != nil {
+			return written, err
+		}
+		if err := s.session.writeFrame(tcpFrameData, s.id, p[:n]); err != nil {
+			return written, err
+		}
+		written += n
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// Close the write direction of the stream.
+func (s *tcpStream) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		err = s.session.writeFrame(tcpFrameFin, s.id, nil)
+		s.cancel()
+		s.done(false)
+	})
+	return err
+}
+
+func (s *tcpStream) CancelWrite(code quicGo.StreamErrorCode) {
+	s.closeOnce.Do(func() {
+		s.session.writeFrame(tcpFrameReset, s.id, nil)
+		s.cancel()
+		s.done(false)
+	})
+}
+
+func (s *tcpStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *tcpStream) SetWriteDeadline(t time.Time) error {
+	s.deadlineMu.Lock()
+	s.writeDeadline = t
+	s.deadlineMu.Unlock()
+	return nil
+}
+
+func (s *tcpStream) SetDeadline(t time.Time) error {
+	s.SetReadDeadline(t)
+	return s.SetWriteDeadline(t)
+}
+
+// initiatedByLocal indicates if the stream is opened by this side.
+func (s *tcpStream) initiatedByLocal() bool {
+	return (s.id&1 == 0) == s.session.isClient
+}
+
+// done finishes a direction of the stream, the stream is removed from the session when both are finished.
+func (s *tcpStream) done(read bool) {
+	s.mu.Lock()
+	if read {
+		s.readDone = true
+	} else {
+		s.writeDone = true
+	}
+	finished := s.readDone && s.writeDone
+	s.mu.Unlock()
+
+	if finished {
+		s.session.removeStream(s.id)
+	}
+}
+
+// finRead closes the read direction when the peer finishes writing.
+func (s *tcpStream) finRead() {
+	s.finOnce.Do(func() {
+		s.mu.Lock()
+		s.fin = true
+		s.mu.Unlock()
+		signal(s.readNotify)
+		s.done(true)
+	})
+}
+
+// reset the stream when the peer cancels writing.
+func (s *tcpStream) reset(err error) {
+	s.resetOnce.Do(func() {
+		s.resetErr = err
+		close(s.resetCh)
+		s.done(true)
+	})
+}
+
+// signal notifies the waiter of ch without blocking.
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// listenTCP listens the TCP fallback on the same address as QUIC, the accepted connections are served as QUIC sessions.
+func listenTCP(addr string, tlsConf *tls.Config, serve func(session quicGo.Session)) (net.Listener, error) {
+	listener, err := tls.Listen("tcp", addr, tlsConf)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				logger.Debug("[TCP fallback] stop accepting connections.", "err", err)
+				return
+			}
+
+			go func() {
+				tlsConn := conn.(*tls.Conn)
+				// the silent clients are dropped instead of holding the connection.
+				tlsConn.SetDeadline(time.Now().Add(tcpHandshakeTimeout))
+				if err := tlsConn.Handshake(); err != nil {
+					logger.Debug("[TCP fallback] TLS handshake failed.", "addr", conn.RemoteAddr().String(), "err", err)
+					conn.Close()
+					return
+				}
+				tlsConn.SetDeadline(time.Time{})
+				serve(newTCPSession(tlsConn, false))
+			}()
+		}
+	}()
+
+	return listener, nil
+}
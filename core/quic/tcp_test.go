@@ -0,0 +1,168 @@
+package quic
+
+import (
+	"context"
+	"crypto/tls"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	quicGo "github.com/lucas-clemente/quic-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTCPFallback(t *testing.T) {
+	sessions := make(chan quicGo.Session, 1)
+	listener, err := listenTCP("127.0.0.1:0", generateTLSConfig("127.0.0.1"), func(session quicGo.Session) {
+		sessions <- session
+	})
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	client, err := dialTCP(listener.Addr().String(), &tls.Config{InsecureSkipVerify: true, NextProtos: []string{nextProto}})
+	assert.NoError(t, err)
+
+	// client -> server in a bidirectional stream.
+	stream, err := client.OpenStream()
+	assert.NoError(t, err)
+	_, err = stream.Write([]byte("hello"))
+	assert.NoError(t, err)
+	assert.NoError(t, stream.Close())
+
+	var server quicGo.Session
+	select {
+	case server = <-sessions:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the session is not accepted")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	accepted, err := server.AcceptStream(ctx)
+	assert.NoError(t, err)
+	buf, err := ioutil.ReadAll(accepted)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(buf))
+
+	// server -> client in a unidirectional stream.
+	uni, err := server.OpenUniStream()
+	assert.NoError(t, err)
+	_, err = uni.Write([]byte("world"))
+	assert.NoError(t, err)
+	assert.NoError(t, uni.Close())
+
+	received, err := client.AcceptUniStream(ctx)
+	assert.NoError(t, err)
+	buf, err = ioutil.ReadAll(received)
+	assert.NoError(t, err)
+	assert.Equal(t, "world", string(buf))
+
+	// datagram.
+	assert.NoError(t, client.SendMessage([]byte("ping")))
+	msg, err := server.ReceiveMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, "ping", string(msg))
+
+	// close.
+	assert.NoError(t, client.CloseWithError(0, ""))
+	_, err = server.AcceptStream(ctx)
+	assert.EqualError(t, err, ErrConnectionClosed)
+}
+
+func TestTCPFallbackBackpressure(t *testing.T) {
+	sessions := make(chan quicGo.Session, 1)
+	listener, err := listenTCP("127.0.0.1:0", generateTLSConfig("127.0.0.1"), func(session quicGo.Session) {
+		sessions <- session
+	})
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	client, err := dialTCP(listener.Addr().String(), &tls.Config{InsecureSkipVerify: true, NextProtos: []string{nextProto}})
+	assert.NoError(t, err)
+	defer client.CloseWithError(0, "")
+
+	// the slow stream writes more than the window, the writer is blocked until the peer reads.
+	slow, err := client.OpenStream()
+	assert.NoError(t, err)
+	payload := make([]byte, tcpStreamWindow*2)
+	written := make(chan error, 1)
+	go func() {
+		_, err := slow.Write(payload)
+		slow.Close()
+		written <- err
+	}()
+
+	server := <-sessions
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	slowAccepted, err := server.AcceptStream(ctx)
+	assert.NoError(t, err)
+
+	// the other streams are not blocked by the slow stream.
+	fast, err := client.OpenStream()
+	assert.NoError(t, err)
+	_, err = fast.Write([]byte("hello"))
+	assert.NoError(t, err)
+	assert.NoError(t, fast.Close())
+
+	fastAccepted, err := server.AcceptStream(ctx)
+	assert.NoError(t, err)
+	buf, err := ioutil.ReadAll(fastAccepted)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(buf))
+
+	select {
+	case <-written:
+		t.Fatal("the write exceeding the window is not blocked")
+	default:
+	}
+
+	buf, err = ioutil.ReadAll(slowAccepted)
+	assert.NoError(t, err)
+	assert.Len(t, buf, len(payload))
+	assert.NoError(t, <-written)
+}
+
+func TestTCPFallbackRemoveStreams(t *testing.T) {
+	sessions := make(chan quicGo.Session, 1)
+	listener, err := listenTCP("127.0.0.1:0", generateTLSConfig("127.0.0.1"), func(session quicGo.Session) {
+		sessions <- session
+	})
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	client, err := dialTCP(listener.Addr().String(), &tls.Config{InsecureSkipVerify: true, NextProtos: []string{nextProto}})
+	assert.NoError(t, err)
+	defer client.CloseWithError(0, "")
+
+	stream, err := client.OpenStream()
+	assert.NoError(t, err)
+	_, err = stream.Write([]byte("ping"))
+	assert.NoError(t, err)
+	assert.NoError(t, stream.Close())
+
+	server := <-sessions
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	accepted, err := server.AcceptStream(ctx)
+	assert.NoError(t, err)
+	_, err = ioutil.ReadAll(accepted)
+	assert.NoError(t, err)
+	_, err = accepted.Write([]byte("pong"))
+	assert.NoError(t, err)
+	assert.NoError(t, accepted.Close())
+
+	buf, err := ioutil.ReadAll(stream)
+	assert.NoError(t, err)
+	assert.Equal(t, "pong", string(buf))
+
+	// the bidirectional streams are removed when both directions are finished.
+	assert.Eventually(t, func() bool {
+		c, s := client.(*tcpSession), server.(*tcpSession)
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return len(c.streams) == 0 && len(s.streams) == 0
+	}, time.Second, 10*time.Millisecond)
+}
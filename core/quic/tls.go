@@ -0,0 +1,98 @@
+package quic
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+)
+
+// nextProto is the ALPN protocol of YoMo.
+const nextProto = "hq-29"
+
+// LoadServerTLSConfig loads the certificate of server, the clients must present a certificate which is signed by the CA.
+// The client certificates are not required if caFile is empty.
+func LoadServerTLSConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	conf := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{nextProto},
+	}
+
+	if caFile != "" {
+		pool, err := loadCertPool(caFile)
+		if err != nil {
+			return nil, err
+		}
+		conf.ClientCAs = pool
+		conf.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return conf, nil
+}
+
+// LoadClientTLSConfig loads the certificate of client and verifies the server by the CA.
+// The server is not verified if caFile is empty.
+func LoadClientTLSConfig(certFile, keyFile, caFile, serverName string) (*tls.Config, error) {
+	conf := &tls.Config{
+		ServerName: serverName,
+		NextProtos: []string{nextProto},
+	}
+
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+
+	if caFile != "" {
+		pool, err := loadCertPool(caFile)
+		if err != nil {
+			return nil, err
+		}
+		conf.RootCAs = pool
+	} else {
+		conf.InsecureSkipVerify = true
+	}
+
+	return conf, nil
+}
+
+func loadCertPool(caFile string) (*x509.CertPool, error) {
+	buf, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(buf) {
+		return nil, errors.New("quic: no certificate is found in " + caFile)
+	}
+	return pool, nil
+}
+
+// PeerIdentities returns the identities of the peer in the verified client certificate,
+// which are the common name, the DNS names and the URIs of the certificate.
+func PeerIdentities(sess Session) []string {
+	certs := sess.ConnectionState().TLS.PeerCertificates
+	if len(certs) == 0 {
+		return nil
+	}
+
+	cert := certs[0]
+	identities := make([]string, 0, 1+len(cert.DNSNames)+len(cert.URIs))
+	if cert.Subject.CommonName != "" {
+		identities = append(identities, cert.Subject.CommonName)
+	}
+	identities = append(identities, cert.DNSNames...)
+	for _, uri := range cert.URIs {
+		identities = append(identities, uri.String())
+	}
+	return identities
+}
@@ -0,0 +1,634 @@
+package quic
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	quicGo "github.com/lucas-clemente/quic-go"
+	"github.com/yomorun/yomo/logger"
+	"golang.org/x/net/http2/hpack"
+)
+
+// webTransportProto is the ALPN of HTTP/3, WebTransport sessions are established by the extended CONNECT of HTTP/3.
+const webTransportProto = "h3"
+
+// The HTTP/3 stream types, frame types and settings used by WebTransport.
+const (
+	h3StreamTypeControl         = 0x00
+	h3StreamTypeWebTransportUni = 0x54
+	h3FrameTypeHeaders          = 0x01
+	h3FrameTypeSettings         = 0x04
+	h3FrameTypeWebTransportBidi = 0x41
+
+	h3SettingQPACKMaxTableCapacity  = 0x01
+	h3SettingEnableConnectProtocol  = 0x08
+	h3SettingH3Datagram             = 0x33
+	h3SettingEnableWebTransport     = 0x2b603742
+	h3SettingWebTransportMaxSession = 0xc671706a
+
+	// the indexes of QPACK static table.
+	qpackMethodConnect = 15
+	qpackStatus200     = 25
+	qpackStatus404     = 27
+	qpackStatus400     = 56
+	qpackStatus403     = 57
+
+	// webTransportProtocol is the value of ":protocol" pseudo-header of the WebTransport requests.
+	webTransportProtocol = "webtransport"
+	// maxHeadersSize is the max size of the field section of request.
+	maxHeadersSize = 16 << 10
+	// rejectLinger is the time to wait for the client to close the connection after the request is rejected.
+	rejectLinger = time.Second
+)
+
+// DefaultWebTransportPath is the default path of WebTransport endpoint.
+const DefaultWebTransportPath = "/"
+
+// ErrNoCertificate is returned when the WebTransport server is started without a certificate, the browsers
+// don't trust the self-signed certificate which is generated by default.
+var ErrNoCertificate = errors.New("quic: WebTransport requires a certificate")
+
+// qpackStaticTable are the entries of QPACK static table which are used by the WebTransport requests,
+// the other entries are decoded as unknown fields.
+var qpackStaticTable = map[uint64][2]string{
+	0:  {":authority", ""},
+	1:  {":path", "/"},
+	15: {":method", "CONNECT"},
+	16: {":method", "DELETE"},
+	17: {":method", "GET"},
+	18: {":method", "HEAD"},
+	19: {":method", "OPTIONS"},
+	20: {":method", "POST"},
+	21: {":method", "PUT"},
+	22: {":scheme", "http"},
+	23: {":scheme", "https"},
+	90: {"origin", ""},
+}
+
+// NewWebTransportServer inits a WebTransport server, so the browsers can act as YoMo clients without a native client.
+// The streams and datagrams of a WebTransport session are passed to the handler after the WebTransport headers are
+// stripped, so they share the same frame parsing path with the QUIC server. The handler is supposed to be shared
+// with a QUIC server, so its Listen callback is not called by the WebTransport server.
+//
+// Only the minimum of HTTP/3 is implemented: the dynamic table of QPACK is disabled and the request is accepted
+// if it's an extended CONNECT of WebTransport to the path set by WithWebTransportPath, and its origin is one of
+// WithWebTransportOrigins or the same origin as the endpoint. The certificate must be set by WithTLSConfig.
+func NewWebTransportServer(handler ServerHandler, opts ...Option) Server {
+	return &webTransportServer{
+		handler: handler,
+		opts:    newOptions(opts...),
+	}
+}
+
+type webTransportServer struct {
+	handler  ServerHandler
+	listener io.Closer
+	opts     *options
+}
+
+func (s *webTransportServer) SetHandler(handler ServerHandler) {
+	s.handler = handler
+}
+
+func (s *webTransportServer) ListenAndServe(ctx context.Context, addr string) error {
+	conf := &quicGo.Config{
+		Versions:                []quicGo.VersionNumber{quicGo.Version1},
+		MaxIdleTimeout:          time.Minute * 10080,
+		KeepAlive:               true,
+		MaxIncomingStreams:      1000000,
+		MaxIncomingUniStreams:   1000000,
+		DisablePathMTUDiscovery: true,
+		EnableDatagrams:         true,
+	}
+
+	if s.opts.tlsConfig == nil {
+		return ErrNoCertificate
+	}
+	tlsConf := s.opts.tlsConfig.Clone()
+	tlsConf.NextProtos = []string{webTransportProto}
+
+	listener, err := quicGo.ListenAddr(addr, tlsConf, conf)
+	if err != nil {
+		return err
+	}
+	logger.Print("✅ Listening WebTransport on " + addr)
+
+	return s.serve(ctx, listener)
+}
+
+// serve accepts the HTTP/3 connections from the listener until it's closed.
+func (s *webTransportServer) serve(ctx context.Context, listener quicGo.Listener) error {
+	s.listener = listener
+	for {
+		session, err := listener.Accept(ctx)
+		if err != nil {
+			return err
+		}
+		go s.serveSession(session)
+	}
+}
+
+// serveSession serves the HTTP/3 connection of a browser.
+func (s *webTransportServer) serveSession(session quicGo.Session) {
+	addr := session.RemoteAddr().String()
+	if err := writeSettings(session); err != nil {
+		logger.Error("[WebTransport] send the settings failed", "addr", addr, "err", err)
+		session.CloseWithError(0x101, err.Error())
+		return
+	}
+
+	// the first request stream establishes the WebTransport session.
+	stream, err := session.AcceptStream(context.Background())
+	if err != nil {
+		return
+	}
+	if err := acceptConnect(stream, s.opts.webTransportPath, s.opts.webTransportOrigins); err != nil {
+		logger.Error("[WebTransport] reject the request", "addr", addr, "err", err)
+		// the response is delivered before the connection is closed.
+		stream.Close()
+		select {
+		case <-session.Context().Done():
+		case <-time.After(rejectLinger):
+		}
+		session.CloseWithError(0x10c, err.Error())
+		return
+	}
+
+	wt := &webTransportSession{
+		Session: session,
+		id:      uint64(stream.StreamID()),
+		uni:     make(chan quicGo.ReceiveStream, 100),
+		done:    make(chan struct{}),
+	}
+	go wt.acceptUniStreams()
+	if h, ok := s.handler.(DatagramHandler); ok {
+		go wt.serveDatagrams(addr, h)
+	}
+
+	for {
+		stream, err := session.AcceptStream(context.Background())
+		if err != nil {
+			return
+		}
+		if err := wt.readHeader(stream, h3FrameTypeWebTransportBidi); err != nil {
+			logger.Debug("[WebTransport] drop the stream", "addr", addr, "err", err)
+			stream.CancelRead(0)
+			continue
+		}
+		defer stream.Close()
+		if s.handler != nil {
+			s.handler.Read(addr, wt, stream)
+		}
+	}
+}
+
+// Close the server. All active sessions will be closed.
+func (s *webTransportServer) Close() error {
+	if s.listener != nil {
+		return s.listener.Close()
+	}
+	return nil
+}
+
+// webTransportSession is a WebTransport session in the HTTP/3 connection, the streams opened by it are prefixed
+// with the WebTransport headers, and the datagrams are prefixed with the quarter stream ID.
+type webTransportSession struct {
+	quicGo.Session
+	id   uint64                    // id is the ID of the CONNECT stream.
+	uni  chan quicGo.ReceiveStream // the unidirectional streams of this session.
+	done chan struct{}             // done is closed when the HTTP/3 connection is closed.
+}
+
+// acceptUniStreams dispatches the unidirectional streams of HTTP/3 connection by the stream types.
+func (wt *webTransportSession) acceptUniStreams() {
+	defer close(wt.done)
+	for {
+		stream, err := wt.Session.AcceptUniStream(context.Background())
+		if err != nil {
+			return
+		}
+
+		go func() {
+			streamType, err := readVarint(stream)
+			if err != nil {
+				return
+			}
+			if streamType != h3StreamTypeWebTransportUni {
+				// the control stream and the QPACK streams are drained.
+				io.Copy(ioutil.Discard, stream)
+				return
+			}
+			id, err := readVarint(stream)
+			if err != nil || id != wt.id {
+				stream.CancelRead(0)
+				return
+			}
+			select {
+			case wt.uni <- stream:
+			case <-wt.done:
+			}
+		}()
+	}
+}
+
+// readHeader reads the WebTransport header of the stream.
+func (wt *webTransportSession) readHeader(stream io.Reader, streamType uint64) error {
+	t, err := readVarint(stream)
+	if err != nil {
+		return err
+	}
+	if t != streamType {
+		return fmt.Errorf("unexpected stream type %#x", t)
+	}
+	id, err := readVarint(stream)
+	if err != nil {
+		return err
+	}
+	if id != wt.id {
+		return fmt.Errorf("unknown session %d", id)
+	}
+	return nil
+}
+
+func (wt *webTransportSession) header(streamType uint64) []byte {
+	return appendVarint(appendVarint(nil, streamType), wt.id)
+}
+
+func (wt *webTransportSession) AcceptUniStream(ctx context.Context) (quicGo.ReceiveStream, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-wt.done:
+		return nil, errors.New(ErrConnectionClosed)
+	case stream := <-wt.uni:
+		return stream, nil
+	}
+}
+
+func (wt *webTransportSession) OpenStream() (quicGo.Stream, error) {
+	stream, err := wt.Session.OpenStream()
+	if err != nil {
+		return nil, err
+	}
+	_, err = stream.Write(wt.header(h3FrameTypeWebTransportBidi))
+	return stream, err
+}
+
+func (wt *webTransportSession) OpenStreamSync(ctx context.Context) (quicGo.Stream, error) {
+	stream, err := wt.Session.OpenStreamSync(ctx)
+	if err != nil {
+		return nil, err
+	}
+	_, err = stream.Write(wt.header(h3FrameTypeWebTransportBidi))
+	return stream, err
+}
+
+func (wt *webTransportSession) OpenUniStream() (quicGo.SendStream, error) {
+	stream, err := wt.Session.OpenUniStream()
+	if err != nil {
+		return nil, err
+	}
+	_, err = stream.Write(wt.header(h3StreamTypeWebTransportUni))
+	return stream, err
+}
+
+func (wt *webTransportSession) OpenUniStreamSync(ctx context.Context) (quicGo.SendStream, error) {
+	stream, err := wt.Session.OpenUniStreamSync(ctx)
+	if err != nil {
+		return nil, err
+	}
+	_, err = stream.Write(wt.header(h3StreamTypeWebTransportUni))
+	return stream, err
+}
+
+// SendMessage sends a HTTP/3 datagram of this session.
+func (wt *webTransportSession) SendMessage(data []byte) error {
+	return wt.Session.SendMessage(append(appendVarint(nil, wt.id/4), data...))
+}
+
+// ReceiveMessage receives a HTTP/3 datagram of this session.
+func (wt *webTransportSession) ReceiveMessage() ([]byte, error) {
+	for {
+		data, err := wt.Session.ReceiveMessage()
+		if err != nil {
+			return nil, err
+		}
+		r := bytes.NewReader(data)
+		id, err := readVarint(r)
+		if err != nil || id != wt.id/4 {
+			continue
+		}
+		return data[len(data)-r.Len():], nil
+	}
+}
+
+// serveDatagrams reads the datagrams from the session until it's closed.
+func (wt *webTransportSession) serveDatagrams(addr string, h DatagramHandler) {
+	for {
+		data, err := wt.ReceiveMessage()
+		if err != nil {
+			return
+		}
+		if err := h.ReadDatagram(addr, wt, data); err != nil {
+			logger.Debug("[WebTransport] handle the datagram failed.", "addr", addr, "err", err)
+		}
+	}
+}
+
+// writeSettings opens the control stream and sends the SETTINGS frame which enables WebTransport.
+func writeSettings(session quicGo.Session) error {
+	stream, err := session.OpenUniStream()
+	if err != nil {
+		return err
+	}
+
+	var settings []byte
+	for _, setting := range [][2]uint64{
+		{h3SettingQPACKMaxTableCapacity, 0},
+		{h3SettingEnableConnectProtocol, 1},
+		{h3SettingH3Datagram, 1},
+		{h3SettingEnableWebTransport, 1},
+		{h3SettingWebTransportMaxSession, 1},
+	} {
+		settings = appendVarint(appendVarint(settings, setting[0]), setting[1])
+	}
+
+	buf := appendVarint(nil, h3StreamTypeControl)
+	buf = appendFrame(buf, h3FrameTypeSettings, settings)
+	_, err = stream.Write(buf)
+	return err
+}
+
+// acceptConnect reads the request headers and responds 200 if it's an extended CONNECT of WebTransport to the path
+// from the allowed origins, otherwise an error status is responded and the error is returned.
+func acceptConnect(stream io.ReadWriter, path string, origins []string) error {
+	frameType, err := readVarint(stream)
+	if err != nil {
+		return err
+	}
+	length, err := readVarint(stream)
+	if err != nil {
+		return err
+	}
+	if length > maxHeadersSize {
+		return fmt.Errorf("the headers exceed %d bytes", maxHeadersSize)
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(stream, payload); err != nil {
+		return err
+	}
+	if frameType != h3FrameTypeHeaders {
+		return fmt.Errorf("unexpected frame type %#x", frameType)
+	}
+
+	status := byte(qpackStatus200)
+	fields, err := decodeFields(payload)
+	if err == nil {
+		status, err = checkConnect(fields, path, origins)
+	} else {
+		status = qpackStatus400
+	}
+
+	// the field section prefix is 0 because the dynamic table is disabled.
+	if _, werr := stream.Write(appendFrame(nil, h3FrameTypeHeaders, []byte{0x00, 0x00, 0xc0 | status})); werr != nil && err == nil {
+		err = werr
+	}
+	return err
+}
+
+// checkConnect checks the request fields, and returns the index of response status in QPACK static table.
+func checkConnect(fields map[string]string, path string, origins []string) (byte, error) {
+	if fields[":method"] != "CONNECT" || fields[":protocol"] != webTransportProtocol {
+		return qpackStatus400, errors.New("not a WebTransport CONNECT request")
+	}
+	if fields[":scheme"] != "https" || fields[":authority"] == "" {
+		return qpackStatus400, errors.New("the scheme or authority of request is invalid")
+	}
+
+	if path == "" {
+		path = DefaultWebTransportPath
+	}
+	if p := fields[":path"]; strings.SplitN(p, "?", 2)[0] != path {
+		return qpackStatus404, fmt.Errorf("unknown path %q", p)
+	}
+
+	origin, ok := fields["origin"]
+	if !ok {
+		return qpackStatus403, errors.New("the origin is missing")
+	}
+	if !allowOrigin(origin, fields[":authority"], origins) {
+		return qpackStatus403, fmt.Errorf("the origin %q is not allowed", origin)
+	}
+	return qpackStatus200, nil
+}
+
+// allowOrigin indicates if the origin is allowed, the same origin as the authority is allowed if no origins
+// are configured, and "*" allows all origins.
+func allowOrigin(origin, authority string, origins []string) bool {
+	if len(origins) == 0 {
+		u, err := url.Parse(origin)
+		if err != nil || u.Scheme != "https" {
+			return false
+		}
+		return sameHost(u.Host, authority)
+	}
+	for _, o := range origins {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameHost compares the hosts of "host[:port]", the default port of https is 443.
+func sameHost(a, b string) bool {
+	split := func(hostport string) (string, string) {
+		host, port, err := net.SplitHostPort(hostport)
+		if err != nil {
+			return hostport, "443"
+		}
+		return host, port
+	}
+	hostA, portA := split(a)
+	hostB, portB := split(b)
+	return strings.EqualFold(hostA, hostB) && portA == portB
+}
+
+// decodeFields decodes the QPACK encoded field section, the field lines which refer to the dynamic table are
+// rejected because it's disabled.
+func decodeFields(section []byte) (map[string]string, error) {
+	r := bytes.NewReader(section)
+	// required insert count and delta base.
+	insertCount, err := readPrefixedInt(r, 8)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := readPrefixedInt(r, 7); err != nil {
+		return nil, err
+	}
+	if insertCount != 0 {
+		return nil, errors.New("qpack: the dynamic table is disabled")
+	}
+
+	fields := make(map[string]string)
+	for r.Len() > 0 {
+		b, _ := r.ReadByte()
+		r.UnreadByte()
+		switch {
+		case b&0x80 != 0:
+			// indexed field line, static table if T is set.
+			index, err := readPrefixedInt(r, 6)
+			if err != nil {
+				return nil, err
+			}
+			if b&0x40 == 0 {
+				return nil, errors.New("qpack: the dynamic table is disabled")
+			}
+			if entry, ok := qpackStaticTable[index]; ok {
+				fields[entry[0]] = entry[1]
+			}
+		case b&0x40 != 0:
+			// literal field line with name reference, static table if T is set.
+			index, err := readPrefixedInt(r, 4)
+			if err != nil {
+				return nil, err
+			}
+			if b&0x10 == 0 {
+				return nil, errors.New("qpack: the dynamic table is disabled")
+			}
+			value, err := readStringValue(r, 7)
+			if err != nil {
+				return nil, err
+			}
+			if entry, ok := qpackStaticTable[index]; ok {
+				fields[entry[0]] = value
+			}
+		case b&0x20 != 0:
+			// literal field line with literal name.
+			name, err := readStringValue(r, 3)
+			if err != nil {
+				return nil, err
+			}
+			value, err := readStringValue(r, 7)
+			if err != nil {
+				return nil, err
+			}
+			fields[name] = value
+		default:
+			// the post-base representations are not used without the dynamic table.
+			return nil, errors.New("qpack: the dynamic table is disabled")
+		}
+	}
+	return fields, nil
+}
+
+// readStringValue reads a string literal with n-bit length prefix, and decodes it if it's Huffman encoded.
+func readStringValue(r *bytes.Reader, n uint) (string, error) {
+	value, huffman, err := readString(r, n)
+	if err != nil {
+		return "", err
+	}
+	if huffman {
+		// QPACK uses the same Huffman code as HPACK.
+		return hpack.HuffmanDecodeToString(value)
+	}
+	return string(value), nil
+}
+
+// readPrefixedInt reads an integer with n-bit prefix of QPACK.
+func readPrefixedInt(r io.ByteReader, n uint) (uint64, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	mask := uint64(1)<<n - 1
+	v := uint64(b) & mask
+	if v < mask {
+		return v, nil
+	}
+
+	var shift uint
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		v += uint64(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return v, nil
+		}
+		shift += 7
+		if shift > 62 {
+			return 0, errors.New("qpack: integer overflow")
+		}
+	}
+}
+
+// readString reads a string literal with n-bit length prefix, the H bit is the bit before the prefix.
+func readString(r *bytes.Reader, n uint) (value []byte, huffman bool, err error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return nil, false, err
+	}
+	r.UnreadByte()
+	huffman = b&(1<<n) != 0
+	length, err := readPrefixedInt(r, n)
+	if err != nil {
+		return nil, false, err
+	}
+	if length > uint64(r.Len()) {
+		return nil, false, io.ErrUnexpectedEOF
+	}
+	value = make([]byte, length)
+	_, err = r.Read(value)
+	return value, huffman, err
+}
+
+// appendFrame appends a HTTP/3 frame.
+func appendFrame(b []byte, frameType uint64, payload []byte) []byte {
+	b = appendVarint(b, frameType)
+	b = appendVarint(b, uint64(len(payload)))
+	return append(b, payload...)
+}
+
+// appendVarint appends a variable-length integer of QUIC.
+func appendVarint(b []byte, v uint64) []byte {
+	switch {
+	case v < 1<<6:
+		return append(b, byte(v))
+	case v < 1<<14:
+		return append(b, 0x40|byte(v>>8), byte(v))
+	case v < 1<<30:
+		return append(b, 0x80|byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+	default:
+		return append(b, 0xc0|byte(v>>56), byte(v>>48), byte(v>>40), byte(v>>32), byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+	}
+}
+
+// readVarint reads a variable-length integer of QUIC byte by byte, so the stream is not over read.
+func readVarint(r io.Reader) (uint64, error) {
+	buf := make([]byte, 8)
+	if _, err := io.ReadFull(r, buf[:1]); err != nil {
+		return 0, err
+	}
+	length := 1 << (buf[0] >> 6)
+	v := uint64(buf[0] & 0x3f)
+	if length == 1 {
+		return v, nil
+	}
+	if _, err := io.ReadFull(r, buf[1:length]); err != nil {
+		return 0, err
+	}
+	for _, b := range buf[1:length] {
+		v = v<<8 | uint64(b)
+	}
+	return v, nil
+}
@@ -0,0 +1,196 @@
+package quic
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"io"
+	"testing"
+	"time"
+
+	quicGo "github.com/lucas-clemente/quic-go"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVarint(t *testing.T) {
+	for _, v := range []uint64{0, 63, 64, 16383, 16384, 1<<30 - 1, 1 << 30, h3SettingWebTransportMaxSession} {
+		got, err := readVarint(bytes.NewReader(appendVarint(nil, v)))
+		assert.NoError(t, err)
+		assert.Equal(t, v, got)
+	}
+}
+
+// appendPrefixedInt appends an integer with n-bit prefix of QPACK, the flags are the bits before the prefix.
+func appendPrefixedInt(b []byte, flags byte, n uint, v uint64) []byte {
+	mask := uint64(1)<<n - 1
+	if v < mask {
+		return append(b, flags|byte(v))
+	}
+	b = append(b, flags|byte(mask))
+	for v -= mask; v >= 0x80; v >>= 7 {
+		b = append(b, byte(v)|0x80)
+	}
+	return append(b, byte(v))
+}
+
+// appendLiteral appends a literal field line with literal name, the strings are not Huffman encoded.
+func appendLiteral(b []byte, name, value string) []byte {
+	b = appendPrefixedInt(b, 0x20, 3, uint64(len(name)))
+	b = append(b, name...)
+	b = appendPrefixedInt(b, 0x00, 7, uint64(len(value)))
+	return append(b, value...)
+}
+
+// connectRequest encodes the field section of the extended CONNECT request.
+func connectRequest(path, origin string) []byte {
+	section := []byte{0x00, 0x00, 0xc0 | qpackMethodConnect, 0xc0 | 23}
+	// :authority (name reference).
+	section = append(section, 0x50|0x00, byte(len("localhost:9443")))
+	section = append(section, "localhost:9443"...)
+	section = appendLiteral(section, ":path", path)
+	section = appendLiteral(section, ":protocol", "webtransport")
+	if origin != "" {
+		section = appendLiteral(section, "origin", origin)
+	}
+	return section
+}
+
+func TestDecodeFields(t *testing.T) {
+	fields, err := decodeFields(connectRequest("/yomo", "https://localhost:9443"))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		":method":    "CONNECT",
+		":scheme":    "https",
+		":authority": "localhost:9443",
+		":path":      "/yomo",
+		":protocol":  "webtransport",
+		"origin":     "https://localhost:9443",
+	}, fields)
+
+	// Huffman encoded "www.example.com" of RFC 7541 C.4.1.
+	section := []byte{0x00, 0x00, 0x50 | 0x00, 0x80 | 0x0c, 0xf1, 0xe3, 0xc2, 0xe5, 0xf2, 0x3a, 0x6b, 0xa0, 0xab, 0x90, 0xf4, 0xff}
+	fields, err = decodeFields(section)
+	assert.NoError(t, err)
+	assert.Equal(t, "www.example.com", fields[":authority"])
+
+	// the dynamic table is disabled.
+	_, err = decodeFields([]byte{0x00, 0x00, 0x80 | 0x01})
+	assert.Error(t, err)
+}
+
+type mockRequestStream struct {
+	*bytes.Reader
+	written bytes.Buffer
+}
+
+func (s *mockRequestStream) Write(p []byte) (int, error) {
+	return s.written.Write(p)
+}
+
+func TestAcceptConnect(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		section []byte
+		path    string
+		origins []string
+		status  byte
+	}{
+		{"same origin", connectRequest("/", "https://localhost:9443"), "", nil, qpackStatus200},
+		{"allowed origin", connectRequest("/yomo?v=1", "https://app.yomo.run"), "/yomo", []string{"https://app.yomo.run"}, qpackStatus200},
+		{"cross origin", connectRequest("/", "https://evil.com"), "", nil, qpackStatus403},
+		{"no origin", connectRequest("/", ""), "", nil, qpackStatus403},
+		{"unknown path", connectRequest("/admin", "https://localhost:9443"), "/yomo", nil, qpackStatus404},
+		{"not webtransport", []byte{0x00, 0x00, 0xc0 | qpackMethodConnect}, "", nil, qpackStatus400},
+		{"GET", []byte{0x00, 0x00, 0xc0 | 17}, "", nil, qpackStatus400},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			stream := &mockRequestStream{Reader: bytes.NewReader(appendFrame(nil, h3FrameTypeHeaders, c.section))}
+			err := acceptConnect(stream, c.path, c.origins)
+			if c.status == qpackStatus200 {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+			assert.Equal(t, []byte{h3FrameTypeHeaders, 0x03, 0x00, 0x00, 0xc0 | c.status}, stream.written.Bytes())
+		})
+	}
+}
+
+func TestWebTransportNoCertificate(t *testing.T) {
+	server := NewWebTransportServer(nil)
+	assert.Equal(t, ErrNoCertificate, server.ListenAndServe(context.Background(), "127.0.0.1:0"))
+}
+
+type mockStreamHandler struct {
+	streams chan Stream
+}
+
+func (h *mockStreamHandler) Listen() error {
+	return nil
+}
+
+func (h *mockStreamHandler) Read(addr string, sess Session, st Stream) error {
+	h.streams <- st
+	return nil
+}
+
+func TestWebTransportHandshake(t *testing.T) {
+	tlsConf := generateTLSConfig("localhost")
+	tlsConf.NextProtos = []string{webTransportProto}
+	listener, err := quicGo.ListenAddr("127.0.0.1:0", tlsConf, &quicGo.Config{EnableDatagrams: true})
+	assert.NoError(t, err)
+
+	handler := &mockStreamHandler{streams: make(chan Stream, 1)}
+	server := NewWebTransportServer(handler, WithTLSConfig(tlsConf), WithWebTransportPath("/yomo")).(*webTransportServer)
+	go server.serve(context.Background(), listener)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	dial := func() quicGo.Session {
+		session, err := quicGo.DialAddrContext(ctx, listener.Addr().String(),
+			&tls.Config{InsecureSkipVerify: true, NextProtos: []string{webTransportProto}},
+			&quicGo.Config{EnableDatagrams: true})
+		assert.NoError(t, err)
+		return session
+	}
+	connect := func(session quicGo.Session, origin string) (quicGo.Stream, []byte) {
+		stream, err := session.OpenStreamSync(ctx)
+		assert.NoError(t, err)
+		_, err = stream.Write(appendFrame(nil, h3FrameTypeHeaders, connectRequest("/yomo", origin)))
+		assert.NoError(t, err)
+		response := make([]byte, 5)
+		_, err = io.ReadFull(stream, response)
+		assert.NoError(t, err)
+		return stream, response
+	}
+
+	// the cross origin request is rejected.
+	rejected := dial()
+	defer rejected.CloseWithError(0, "")
+	_, response := connect(rejected, "https://evil.com")
+	assert.Equal(t, byte(0xc0|qpackStatus403), response[4])
+
+	// the WebTransport stream is passed to the handler without the header.
+	session := dial()
+	defer session.CloseWithError(0, "")
+	request, response := connect(session, "https://localhost:9443")
+	assert.Equal(t, byte(0xc0|qpackStatus200), response[4])
+
+	stream, err := session.OpenStreamSync(ctx)
+	assert.NoError(t, err)
+	header := appendVarint(appendVarint(nil, h3FrameTypeWebTransportBidi), uint64(request.StreamID()))
+	_, err = stream.Write(append(header, "hello"...))
+	assert.NoError(t, err)
+
+	select {
+	case st := <-handler.streams:
+		buf := make([]byte, 5)
+		_, err := io.ReadFull(st, buf)
+		assert.NoError(t, err)
+		assert.Equal(t, "hello", string(buf))
+	case <-ctx.Done():
+		t.Fatal("the WebTransport stream is not accepted")
+	}
+}
@@ -0,0 +1,76 @@
+// Package boltstore provides the rx.StateStore which saves the states in a BoltDB file.
+package boltstore
+
+import (
+	"errors"
+
+	"github.com/yomorun/yomo/core/rx"
+	bolt "go.etcd.io/bbolt"
+)
+
+// DefaultBucket is the bucket of states if it's not specified.
+const DefaultBucket = "yomo_states"
+
+// Store is the rx.StateStore of BoltDB.
+type Store struct {
+	db     *bolt.DB
+	bucket []byte
+}
+
+var _ rx.StateStore = (*Store)(nil)
+
+// New opens the BoltDB file at path and creates the bucket of states if it doesn't exist.
+func New(path string, bucket string) (*Store, error) {
+	if bucket == "" {
+		bucket = DefaultBucket
+	}
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(bucket))
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db, bucket: []byte(bucket)}, nil
+}
+
+// Load returns the state saved by the name.
+func (s *Store) Load(name string) ([]byte, bool, error) {
+	var state []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(s.bucket)
+		if b == nil {
+			return errors.New("boltstore: the bucket of states is not found")
+		}
+		if v := b.Get([]byte(name)); v != nil {
+			// the value is only valid in the transaction.
+			state = append([]byte{}, v...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return state, state != nil, nil
+}
+
+// Save the state by the name.
+func (s *Store) Save(name string, state []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(s.bucket)
+		if b == nil {
+			return errors.New("boltstore: the bucket of states is not found")
+		}
+		return b.Put([]byte(name), state)
+	})
+}
+
+// Close the BoltDB file.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
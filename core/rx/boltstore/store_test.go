@@ -0,0 +1,31 @@
+package boltstore
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "states.db")
+	store, err := New(path, "")
+	assert.NoError(t, err)
+
+	_, ok, err := store.Load("count")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.NoError(t, store.Save("count", []byte("1")))
+	assert.NoError(t, store.Save("count", []byte("2")))
+	assert.NoError(t, store.Close())
+
+	// the state is kept after reopening.
+	store, err = New(path, "")
+	assert.NoError(t, err)
+	defer store.Close()
+	state, ok, err := store.Load("count")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("2"), state)
+}
@@ -0,0 +1,119 @@
+package rx
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/reactivex/rxgo/v2"
+)
+
+// Merge combines the streams into one by emitting the items of all streams as they arrive,
+// it completes when all streams complete.
+func Merge(streams ...Stream) Stream {
+	f := func(ctx context.Context, next chan rxgo.Item) {
+		defer close(next)
+		var wg sync.WaitGroup
+		for _, stream := range streams {
+			wg.Add(1)
+			go func(observe <-chan rxgo.Item) {
+				defer wg.Done()
+				for item := range observe {
+					if !item.SendContext(ctx, next) {
+						return
+					}
+				}
+			}(stream.Observe())
+		}
+		wg.Wait()
+	}
+	return CreateObservable(streamsContext(streams), f)
+}
+
+// Concat emits the items of the streams one stream after another, the next stream is observed when the former
+// one completes.
+func Concat(streams ...Stream) Stream {
+	f := func(ctx context.Context, next chan rxgo.Item) {
+		defer close(next)
+		for _, stream := range streams {
+			for item := range stream.Observe() {
+				if !item.SendContext(ctx, next) {
+					return
+				}
+			}
+		}
+	}
+	return CreateObservable(streamsContext(streams), f)
+}
+
+// Zip takes an item from every stream in turn, and emits the value returned by the zipper with the slice of the
+// items in the order of streams. The errors are emitted as they arrive, and it completes when any stream completes.
+func Zip(zipper func(items []interface{}) (interface{}, error), streams ...Stream) Stream {
+	if len(streams) < 2 {
+		next := make(chan rxgo.Item, 1)
+		next <- rxgo.Error(errors.New("[Zip] the number of streams must be >= 2"))
+		close(next)
+		return &StreamImpl{observable: rxgo.FromChannel(next)}
+	}
+
+	f := func(ctx context.Context, next chan rxgo.Item) {
+		defer close(next)
+		observes := make([]<-chan rxgo.Item, len(streams))
+		for i, stream := range streams {
+			observes[i] = stream.Observe()
+		}
+
+		for {
+			items := make([]interface{}, len(streams))
+			for i, observe := range observes {
+				item, ok := nextValue(ctx, observe, next)
+				if !ok {
+					return
+				}
+				items[i] = item.V
+			}
+
+			v, err := zipper(items)
+			var item rxgo.Item
+			if err != nil {
+				item = rxgo.Error(err)
+			} else {
+				item = Of(v)
+			}
+			if !item.SendContext(ctx, next) {
+				return
+			}
+		}
+	}
+	return CreateObservable(streamsContext(streams), f)
+}
+
+// nextValue returns the next item which is not an error, the errors are sent to next.
+func nextValue(ctx context.Context, observe <-chan rxgo.Item, next chan rxgo.Item) (rxgo.Item, bool) {
+	for {
+		select {
+		case <-ctx.Done():
+			return rxgo.Item{}, false
+		case item, ok := <-observe:
+			if !ok {
+				return item, false
+			}
+			if !item.Error() {
+				return item, true
+			}
+			if !item.SendContext(ctx, next) {
+				return item, false
+			}
+		}
+	}
+}
+
+// streamsContext returns the context of the first stream which has one.
+func streamsContext(streams []Stream) context.Context {
+	for _, stream := range streams {
+		if impl, ok := stream.(*StreamImpl); ok && impl.ctx != nil {
+			return impl.ctx
+		}
+	}
+	return context.Background()
+}
@@ -0,0 +1,45 @@
+package rx
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/reactivex/rxgo/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerge(t *testing.T) {
+	st := Merge(toStream(rxgo.Just(1, 2)()), toStream(rxgo.Just(3)()))
+	result, err := st.ToSlice(0)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []interface{}{1, 2, 3}, result)
+}
+
+func TestConcat(t *testing.T) {
+	st := Concat(toStream(rxgo.Just(1, 2)()), toStream(rxgo.Just(3)()))
+	result, err := st.ToSlice(0)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{1, 2, 3}, result)
+}
+
+func TestZip(t *testing.T) {
+	errFoo := errors.New("foo")
+	sum := func(items []interface{}) (interface{}, error) {
+		return items[0].(int) + items[1].(int), nil
+	}
+
+	t.Run("zip two streams", func(t *testing.T) {
+		st := Zip(sum, toStream(rxgo.Just(1, errFoo, 2, 3)()), toStream(rxgo.Just(10, 20)()))
+		result := make([]rxgo.Item, 0)
+		for item := range st.Observe() {
+			result = append(result, item)
+		}
+		assert.Equal(t, []rxgo.Item{Of(11), rxgo.Error(errFoo), Of(22)}, result)
+	})
+
+	t.Run("one stream", func(t *testing.T) {
+		for item := range Zip(sum, toStream(rxgo.Just(1)())).Observe() {
+			assert.True(t, item.Error())
+		}
+	})
+}
@@ -90,6 +90,8 @@ func (fac *factoryImpl) FromQuicStream(ctx context.Context, stream quic.Stream)
 func (fac *factoryImpl) FromItems(ctx context.Context, items []interface{}) Stream {
 	next := make(chan rxgo.Item)
 	go func() {
+		// the stream completes after the items, so the handler on it ends.
+		defer close(next)
 		for _, item := range items {
 			next <- Of(item)
 		}
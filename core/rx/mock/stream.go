@@ -58,6 +58,20 @@ func (mr *MockStreamMockRecorder) All(predicate interface{}, opts ...interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "All", reflect.TypeOf((*MockStream)(nil).All), varargs...)
 }
 
+// Also mocks base method.
+func (m *MockStream) Also(key byte, function func([]byte) (interface{}, error)) rx.Stream {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Also", key, function)
+	ret0, _ := ret[0].(rx.Stream)
+	return ret0
+}
+
+// Also indicates an expected call of Also.
+func (mr *MockStreamMockRecorder) Also(key, function interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Also", reflect.TypeOf((*MockStream)(nil).Also), key, function)
+}
+
 // AuditTime mocks base method.
 func (m *MockStream) AuditTime(milliseconds uint32, opts ...rxgo.Option) rx.Stream {
 	m.ctrl.T.Helper()
@@ -426,6 +440,25 @@ func (mr *MockStreamMockRecorder) DistinctUntilChanged(apply interface{}, opts .
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DistinctUntilChanged", reflect.TypeOf((*MockStream)(nil).DistinctUntilChanged), varargs...)
 }
 
+// DistinctWithTTL mocks base method.
+func (m *MockStream) DistinctWithTTL(apply rxgo.Func, ttlInMS uint32, opts ...rxgo.Option) rx.Stream {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{apply, ttlInMS}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DistinctWithTTL", varargs...)
+	ret0, _ := ret[0].(rx.Stream)
+	return ret0
+}
+
+// DistinctWithTTL indicates an expected call of DistinctWithTTL.
+func (mr *MockStreamMockRecorder) DistinctWithTTL(apply, ttlInMS interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{apply, ttlInMS}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DistinctWithTTL", reflect.TypeOf((*MockStream)(nil).DistinctWithTTL), varargs...)
+}
+
 // DoOnCompleted mocks base method.
 func (m *MockStream) DoOnCompleted(completedFunc rxgo.CompletedFunc, opts ...rxgo.Option) rxgo.Disposed {
 	m.ctrl.T.Helper()
@@ -521,6 +554,25 @@ func (mr *MockStreamMockRecorder) Encode(key interface{}, opts ...interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Encode", reflect.TypeOf((*MockStream)(nil).Encode), varargs...)
 }
 
+// EncodeWith mocks base method.
+func (m *MockStream) EncodeWith(key byte, contentType string, opts ...rxgo.Option) rx.Stream {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{key, contentType}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "EncodeWith", varargs...)
+	ret0, _ := ret[0].(rx.Stream)
+	return ret0
+}
+
+// EncodeWith indicates an expected call of EncodeWith.
+func (mr *MockStreamMockRecorder) EncodeWith(key, contentType interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{key, contentType}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EncodeWith", reflect.TypeOf((*MockStream)(nil).EncodeWith), varargs...)
+}
+
 // Error mocks base method.
 func (m *MockStream) Error(opts ...rxgo.Option) error {
 	m.ctrl.T.Helper()
@@ -557,6 +609,25 @@ func (mr *MockStreamMockRecorder) Errors(opts ...interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Errors", reflect.TypeOf((*MockStream)(nil).Errors), opts...)
 }
 
+// EventTimeWindow mocks base method.
+func (m *MockStream) EventTimeWindow(timeExtractor func(interface{}) time.Time, windowInMS, allowedLatenessInMS uint32, opts ...rxgo.Option) rx.Stream {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{timeExtractor, windowInMS, allowedLatenessInMS}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "EventTimeWindow", varargs...)
+	ret0, _ := ret[0].(rx.Stream)
+	return ret0
+}
+
+// EventTimeWindow indicates an expected call of EventTimeWindow.
+func (mr *MockStreamMockRecorder) EventTimeWindow(timeExtractor, windowInMS, allowedLatenessInMS interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{timeExtractor, windowInMS, allowedLatenessInMS}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EventTimeWindow", reflect.TypeOf((*MockStream)(nil).EventTimeWindow), varargs...)
+}
+
 // Filter mocks base method.
 func (m *MockStream) Filter(apply rxgo.Predicate, opts ...rxgo.Option) rx.Stream {
 	m.ctrl.T.Helper()
@@ -651,6 +722,25 @@ func (mr *MockStreamMockRecorder) FlatMap(apply interface{}, opts ...interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FlatMap", reflect.TypeOf((*MockStream)(nil).FlatMap), varargs...)
 }
 
+// FlatMapSlice mocks base method.
+func (m *MockStream) FlatMapSlice(apply func(context.Context, interface{}) ([]interface{}, error), opts ...rxgo.Option) rx.Stream {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{apply}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "FlatMapSlice", varargs...)
+	ret0, _ := ret[0].(rx.Stream)
+	return ret0
+}
+
+// FlatMapSlice indicates an expected call of FlatMapSlice.
+func (mr *MockStreamMockRecorder) FlatMapSlice(apply interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{apply}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FlatMapSlice", reflect.TypeOf((*MockStream)(nil).FlatMapSlice), varargs...)
+}
+
 // ForEach mocks base method.
 func (m *MockStream) ForEach(nextFunc rxgo.NextFunc, errFunc rxgo.ErrFunc, completedFunc rxgo.CompletedFunc, opts ...rxgo.Option) rxgo.Disposed {
 	m.ctrl.T.Helper()
@@ -745,6 +835,20 @@ func (mr *MockStreamMockRecorder) Join(joiner, right, timeExtractor, windowInMS
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Join", reflect.TypeOf((*MockStream)(nil).Join), varargs...)
 }
 
+// JoinByKey mocks base method.
+func (m *MockStream) JoinByKey(observer, other rx.KeyObserveFunc, keyFn func(interface{}) string, windowInMS uint32) rx.Stream {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "JoinByKey", observer, other, keyFn, windowInMS)
+	ret0, _ := ret[0].(rx.Stream)
+	return ret0
+}
+
+// JoinByKey indicates an expected call of JoinByKey.
+func (mr *MockStreamMockRecorder) JoinByKey(observer, other, keyFn, windowInMS interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "JoinByKey", reflect.TypeOf((*MockStream)(nil).JoinByKey), observer, other, keyFn, windowInMS)
+}
+
 // Last mocks base method.
 func (m *MockStream) Last(opts ...rxgo.Option) rx.Stream {
 	m.ctrl.T.Helper()
@@ -801,6 +905,63 @@ func (mr *MockStreamMockRecorder) Map(apply interface{}, opts ...interface{}) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Map", reflect.TypeOf((*MockStream)(nil).Map), varargs...)
 }
 
+// MapParallel mocks base method.
+func (m *MockStream) MapParallel(apply rxgo.Func, concurrency int, ordered bool, opts ...rxgo.Option) rx.Stream {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{apply, concurrency, ordered}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "MapParallel", varargs...)
+	ret0, _ := ret[0].(rx.Stream)
+	return ret0
+}
+
+// MapParallel indicates an expected call of MapParallel.
+func (mr *MockStreamMockRecorder) MapParallel(apply, concurrency, ordered interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{apply, concurrency, ordered}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MapParallel", reflect.TypeOf((*MockStream)(nil).MapParallel), varargs...)
+}
+
+// MapWithRetry mocks base method.
+func (m *MockStream) MapWithRetry(apply rxgo.Func, count int, backOffCfg backoff.BackOff, opts ...rxgo.Option) rx.Stream {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{apply, count, backOffCfg}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "MapWithRetry", varargs...)
+	ret0, _ := ret[0].(rx.Stream)
+	return ret0
+}
+
+// MapWithRetry indicates an expected call of MapWithRetry.
+func (mr *MockStreamMockRecorder) MapWithRetry(apply, count, backOffCfg interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{apply, count, backOffCfg}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MapWithRetry", reflect.TypeOf((*MockStream)(nil).MapWithRetry), varargs...)
+}
+
+// MapWithTimeout mocks base method.
+func (m *MockStream) MapWithTimeout(apply rxgo.Func, milliseconds uint32, opts ...rxgo.Option) rx.Stream {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{apply, milliseconds}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "MapWithTimeout", varargs...)
+	ret0, _ := ret[0].(rx.Stream)
+	return ret0
+}
+
+// MapWithTimeout indicates an expected call of MapWithTimeout.
+func (mr *MockStreamMockRecorder) MapWithTimeout(apply, milliseconds interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{apply, milliseconds}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MapWithTimeout", reflect.TypeOf((*MockStream)(nil).MapWithTimeout), varargs...)
+}
+
 // Marshal mocks base method.
 func (m *MockStream) Marshal(marshaller decoder.Marshaller, opts ...rxgo.Option) rx.Stream {
 	m.ctrl.T.Helper()
@@ -876,6 +1037,20 @@ func (mr *MockStreamMockRecorder) Observe(opts ...interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Observe", reflect.TypeOf((*MockStream)(nil).Observe), opts...)
 }
 
+// OnError mocks base method.
+func (m *MockStream) OnError(handler func(error, []byte), policy rx.ErrorPolicy) rx.Stream {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OnError", handler, policy)
+	ret0, _ := ret[0].(rx.Stream)
+	return ret0
+}
+
+// OnError indicates an expected call of OnError.
+func (mr *MockStreamMockRecorder) OnError(handler, policy interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnError", reflect.TypeOf((*MockStream)(nil).OnError), handler, policy)
+}
+
 // OnErrorResumeNext mocks base method.
 func (m *MockStream) OnErrorResumeNext(resumeSequence rxgo.ErrorToObservable, opts ...rxgo.Option) rx.Stream {
 	m.ctrl.T.Helper()
@@ -895,6 +1070,25 @@ func (mr *MockStreamMockRecorder) OnErrorResumeNext(resumeSequence interface{},
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnErrorResumeNext", reflect.TypeOf((*MockStream)(nil).OnErrorResumeNext), varargs...)
 }
 
+// OnErrorResumeWith mocks base method.
+func (m *MockStream) OnErrorResumeWith(fallback func(context.Context, error) (interface{}, error), opts ...rxgo.Option) rx.Stream {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{fallback}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "OnErrorResumeWith", varargs...)
+	ret0, _ := ret[0].(rx.Stream)
+	return ret0
+}
+
+// OnErrorResumeWith indicates an expected call of OnErrorResumeWith.
+func (mr *MockStreamMockRecorder) OnErrorResumeWith(fallback interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{fallback}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnErrorResumeWith", reflect.TypeOf((*MockStream)(nil).OnErrorResumeWith), varargs...)
+}
+
 // OnErrorReturn mocks base method.
 func (m *MockStream) OnErrorReturn(resumeFunc rxgo.ErrorFunc, opts ...rxgo.Option) rx.Stream {
 	m.ctrl.T.Helper()
@@ -947,6 +1141,20 @@ func (mr *MockStreamMockRecorder) OnObserve(function interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnObserve", reflect.TypeOf((*MockStream)(nil).OnObserve), function)
 }
 
+// OnObserveWithContext mocks base method.
+func (m *MockStream) OnObserveWithContext(function func(context.Context, []byte) (interface{}, error)) rx.Stream {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OnObserveWithContext", function)
+	ret0, _ := ret[0].(rx.Stream)
+	return ret0
+}
+
+// OnObserveWithContext indicates an expected call of OnObserveWithContext.
+func (mr *MockStreamMockRecorder) OnObserveWithContext(function interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnObserveWithContext", reflect.TypeOf((*MockStream)(nil).OnObserveWithContext), function)
+}
+
 // RawBytes mocks base method.
 func (m *MockStream) RawBytes() rx.Stream {
 	m.ctrl.T.Helper()
@@ -980,6 +1188,44 @@ func (mr *MockStreamMockRecorder) Reduce(apply interface{}, opts ...interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reduce", reflect.TypeOf((*MockStream)(nil).Reduce), varargs...)
 }
 
+// ReduceByKey mocks base method.
+func (m *MockStream) ReduceByKey(keySelector func(interface{}) string, apply rxgo.Func2, opts ...rxgo.Option) rx.Stream {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{keySelector, apply}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ReduceByKey", varargs...)
+	ret0, _ := ret[0].(rx.Stream)
+	return ret0
+}
+
+// ReduceByKey indicates an expected call of ReduceByKey.
+func (mr *MockStreamMockRecorder) ReduceByKey(keySelector, apply interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{keySelector, apply}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReduceByKey", reflect.TypeOf((*MockStream)(nil).ReduceByKey), varargs...)
+}
+
+// ReduceByKeyWithState mocks base method.
+func (m *MockStream) ReduceByKeyWithState(keySelector func(interface{}) string, apply rxgo.Func2, state *rx.State, opts ...rxgo.Option) rx.Stream {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{keySelector, apply, state}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ReduceByKeyWithState", varargs...)
+	ret0, _ := ret[0].(rx.Stream)
+	return ret0
+}
+
+// ReduceByKeyWithState indicates an expected call of ReduceByKeyWithState.
+func (mr *MockStreamMockRecorder) ReduceByKeyWithState(keySelector, apply, state interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{keySelector, apply, state}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReduceByKeyWithState", reflect.TypeOf((*MockStream)(nil).ReduceByKeyWithState), varargs...)
+}
+
 // Repeat mocks base method.
 func (m *MockStream) Repeat(count int64, milliseconds uint32, opts ...rxgo.Option) rx.Stream {
 	m.ctrl.T.Helper()
@@ -1055,6 +1301,25 @@ func (mr *MockStreamMockRecorder) Sample(iterable interface{}, opts ...interface
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Sample", reflect.TypeOf((*MockStream)(nil).Sample), varargs...)
 }
 
+// SampleWithTime mocks base method.
+func (m *MockStream) SampleWithTime(milliseconds uint32, opts ...rxgo.Option) rx.Stream {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{milliseconds}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "SampleWithTime", varargs...)
+	ret0, _ := ret[0].(rx.Stream)
+	return ret0
+}
+
+// SampleWithTime indicates an expected call of SampleWithTime.
+func (mr *MockStreamMockRecorder) SampleWithTime(milliseconds interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{milliseconds}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SampleWithTime", reflect.TypeOf((*MockStream)(nil).SampleWithTime), varargs...)
+}
+
 // Scan mocks base method.
 func (m *MockStream) Scan(apply rxgo.Func2, opts ...rxgo.Option) rx.Stream {
 	m.ctrl.T.Helper()
@@ -1074,6 +1339,44 @@ func (mr *MockStreamMockRecorder) Scan(apply interface{}, opts ...interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Scan", reflect.TypeOf((*MockStream)(nil).Scan), varargs...)
 }
 
+// ScanByKey mocks base method.
+func (m *MockStream) ScanByKey(keySelector func(interface{}) string, apply rxgo.Func2, opts ...rxgo.Option) rx.Stream {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{keySelector, apply}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ScanByKey", varargs...)
+	ret0, _ := ret[0].(rx.Stream)
+	return ret0
+}
+
+// ScanByKey indicates an expected call of ScanByKey.
+func (mr *MockStreamMockRecorder) ScanByKey(keySelector, apply interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{keySelector, apply}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ScanByKey", reflect.TypeOf((*MockStream)(nil).ScanByKey), varargs...)
+}
+
+// ScanByKeyWithState mocks base method.
+func (m *MockStream) ScanByKeyWithState(keySelector func(interface{}) string, apply rxgo.Func2, state *rx.State, opts ...rxgo.Option) rx.Stream {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{keySelector, apply, state}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ScanByKeyWithState", varargs...)
+	ret0, _ := ret[0].(rx.Stream)
+	return ret0
+}
+
+// ScanByKeyWithState indicates an expected call of ScanByKeyWithState.
+func (mr *MockStreamMockRecorder) ScanByKeyWithState(keySelector, apply, state interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{keySelector, apply, state}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ScanByKeyWithState", reflect.TypeOf((*MockStream)(nil).ScanByKeyWithState), varargs...)
+}
+
 // Send mocks base method.
 func (m *MockStream) Send(output chan<- rxgo.Item, opts ...rxgo.Option) {
 	m.ctrl.T.Helper()
@@ -1129,6 +1432,44 @@ func (mr *MockStreamMockRecorder) Serialize(from, identifier interface{}, opts .
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Serialize", reflect.TypeOf((*MockStream)(nil).Serialize), varargs...)
 }
 
+// SessionWindow mocks base method.
+func (m *MockStream) SessionWindow(gapInMS uint32, opts ...rxgo.Option) rx.Stream {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{gapInMS}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "SessionWindow", varargs...)
+	ret0, _ := ret[0].(rx.Stream)
+	return ret0
+}
+
+// SessionWindow indicates an expected call of SessionWindow.
+func (mr *MockStreamMockRecorder) SessionWindow(gapInMS interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{gapInMS}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SessionWindow", reflect.TypeOf((*MockStream)(nil).SessionWindow), varargs...)
+}
+
+// SessionWindowWithState mocks base method.
+func (m *MockStream) SessionWindowWithState(gapInMS uint32, state *rx.State, opts ...rxgo.Option) rx.Stream {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{gapInMS, state}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "SessionWindowWithState", varargs...)
+	ret0, _ := ret[0].(rx.Stream)
+	return ret0
+}
+
+// SessionWindowWithState indicates an expected call of SessionWindowWithState.
+func (mr *MockStreamMockRecorder) SessionWindowWithState(gapInMS, state interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{gapInMS, state}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SessionWindowWithState", reflect.TypeOf((*MockStream)(nil).SessionWindowWithState), varargs...)
+}
+
 // Skip mocks base method.
 func (m *MockStream) Skip(nth uint, opts ...rxgo.Option) rx.Stream {
 	m.ctrl.T.Helper()
@@ -1186,6 +1527,44 @@ func (mr *MockStreamMockRecorder) SkipWhile(apply interface{}, opts ...interface
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SkipWhile", reflect.TypeOf((*MockStream)(nil).SkipWhile), varargs...)
 }
 
+// SlidingWindow mocks base method.
+func (m *MockStream) SlidingWindow(windowTimeInMS, slideTimeInMS uint32, opts ...rxgo.Option) rx.Stream {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{windowTimeInMS, slideTimeInMS}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "SlidingWindow", varargs...)
+	ret0, _ := ret[0].(rx.Stream)
+	return ret0
+}
+
+// SlidingWindow indicates an expected call of SlidingWindow.
+func (mr *MockStreamMockRecorder) SlidingWindow(windowTimeInMS, slideTimeInMS interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{windowTimeInMS, slideTimeInMS}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SlidingWindow", reflect.TypeOf((*MockStream)(nil).SlidingWindow), varargs...)
+}
+
+// SlidingWindowWithState mocks base method.
+func (m *MockStream) SlidingWindowWithState(windowTimeInMS, slideTimeInMS uint32, state *rx.State, opts ...rxgo.Option) rx.Stream {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{windowTimeInMS, slideTimeInMS, state}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "SlidingWindowWithState", varargs...)
+	ret0, _ := ret[0].(rx.Stream)
+	return ret0
+}
+
+// SlidingWindowWithState indicates an expected call of SlidingWindowWithState.
+func (mr *MockStreamMockRecorder) SlidingWindowWithState(windowTimeInMS, slideTimeInMS, state interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{windowTimeInMS, slideTimeInMS, state}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SlidingWindowWithState", reflect.TypeOf((*MockStream)(nil).SlidingWindowWithState), varargs...)
+}
+
 // SlidingWindowWithCount mocks base method.
 func (m *MockStream) SlidingWindowWithCount(windowSize, slideSize int, handler rx.Handler, opts ...rxgo.Option) rx.Stream {
 	m.ctrl.T.Helper()
@@ -1224,6 +1603,25 @@ func (mr *MockStreamMockRecorder) SlidingWindowWithTime(windowTimeInMS, slideTim
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SlidingWindowWithTime", reflect.TypeOf((*MockStream)(nil).SlidingWindowWithTime), varargs...)
 }
 
+// Split mocks base method.
+func (m *MockStream) Split(selector func(interface{}) byte, opts ...rxgo.Option) rx.Stream {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{selector}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Split", varargs...)
+	ret0, _ := ret[0].(rx.Stream)
+	return ret0
+}
+
+// Split indicates an expected call of Split.
+func (mr *MockStreamMockRecorder) Split(selector interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{selector}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Split", reflect.TypeOf((*MockStream)(nil).Split), varargs...)
+}
+
 // StartWith mocks base method.
 func (m *MockStream) StartWith(iterable rxgo.Iterable, opts ...rxgo.Option) rx.Stream {
 	m.ctrl.T.Helper()
@@ -1405,6 +1803,25 @@ func (mr *MockStreamMockRecorder) TakeWhile(apply interface{}, opts ...interface
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TakeWhile", reflect.TypeOf((*MockStream)(nil).TakeWhile), varargs...)
 }
 
+// ThrottleFirst mocks base method.
+func (m *MockStream) ThrottleFirst(milliseconds uint32, opts ...rxgo.Option) rx.Stream {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{milliseconds}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ThrottleFirst", varargs...)
+	ret0, _ := ret[0].(rx.Stream)
+	return ret0
+}
+
+// ThrottleFirst indicates an expected call of ThrottleFirst.
+func (mr *MockStreamMockRecorder) ThrottleFirst(milliseconds interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{milliseconds}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ThrottleFirst", reflect.TypeOf((*MockStream)(nil).ThrottleFirst), varargs...)
+}
+
 // TimeInterval mocks base method.
 func (m *MockStream) TimeInterval(opts ...rxgo.Option) rx.Stream {
 	m.ctrl.T.Helper()
@@ -1499,6 +1916,25 @@ func (mr *MockStreamMockRecorder) ToSlice(initialCapacity interface{}, opts ...i
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ToSlice", reflect.TypeOf((*MockStream)(nil).ToSlice), varargs...)
 }
 
+// Transform mocks base method.
+func (m *MockStream) Transform(operator rx.Operator, opts ...rxgo.Option) rx.Stream {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{operator}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Transform", varargs...)
+	ret0, _ := ret[0].(rx.Stream)
+	return ret0
+}
+
+// Transform indicates an expected call of Transform.
+func (mr *MockStreamMockRecorder) Transform(operator interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{operator}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transform", reflect.TypeOf((*MockStream)(nil).Transform), varargs...)
+}
+
 // Unmarshal mocks base method.
 func (m *MockStream) Unmarshal(unmarshaller decoder.Unmarshaller, factory func() interface{}, opts ...rxgo.Option) rx.Stream {
 	m.ctrl.T.Helper()
@@ -1518,6 +1954,25 @@ func (mr *MockStreamMockRecorder) Unmarshal(unmarshaller, factory interface{}, o
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unmarshal", reflect.TypeOf((*MockStream)(nil).Unmarshal), varargs...)
 }
 
+// UnmarshalPayload mocks base method.
+func (m *MockStream) UnmarshalPayload(factory func() interface{}, opts ...rxgo.Option) rx.Stream {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{factory}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "UnmarshalPayload", varargs...)
+	ret0, _ := ret[0].(rx.Stream)
+	return ret0
+}
+
+// UnmarshalPayload indicates an expected call of UnmarshalPayload.
+func (mr *MockStreamMockRecorder) UnmarshalPayload(factory interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{factory}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnmarshalPayload", reflect.TypeOf((*MockStream)(nil).UnmarshalPayload), varargs...)
+}
+
 // WindowWithCount mocks base method.
 func (m *MockStream) WindowWithCount(count int, opts ...rxgo.Option) rx.Stream {
 	m.ctrl.T.Helper()
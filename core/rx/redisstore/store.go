@@ -0,0 +1,45 @@
+// Package redisstore provides the rx.StateStore which saves the states in Redis.
+package redisstore
+
+import (
+	"context"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/yomorun/yomo/core/rx"
+)
+
+// DefaultPrefix is the prefix of the keys of states if it's not specified.
+const DefaultPrefix = "yomo:state:"
+
+// Store is the rx.StateStore of Redis, the state is saved in the key of prefix and name without expiration.
+type Store struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+var _ rx.StateStore = (*Store)(nil)
+
+// New creates the Store by the client of Redis, the client is closed by the caller.
+func New(client redis.UniversalClient, prefix string) *Store {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+	return &Store{client: client, prefix: prefix}
+}
+
+// Load returns the state saved by the name.
+func (s *Store) Load(name string) ([]byte, bool, error) {
+	state, err := s.client.Get(context.Background(), s.prefix+name).Bytes()
+	if err == redis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return state, true, nil
+}
+
+// Save the state by the name.
+func (s *Store) Save(name string, state []byte) error {
+	return s.client.Set(context.Background(), s.prefix+name, state, 0).Err()
+}
@@ -0,0 +1,204 @@
+package rx
+
+import (
+	"bytes"
+	"encoding/gob"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/yomorun/yomo/logger"
+)
+
+// StateStore persists the state of the stateful operators, so a restarted stream function resumes the aggregations.
+// The stores in memory and files are in this package, the stores of BoltDB and Redis are in the packages
+// core/rx/boltstore and core/rx/redisstore, so the stream functions only depend on the database they use.
+type StateStore interface {
+	// Load returns the state saved by the name, ok is false if the state is not found.
+	Load(name string) (state []byte, ok bool, err error)
+	// Save the state by the name, the former state is replaced.
+	Save(name string, state []byte) error
+}
+
+// State is the checkpointed state of a stateful operator. The values are encoded by encoding/gob, so the types
+// other than the built-in ones should be registered by gob.Register.
+//
+// The keyed aggregations, i.e. ScanByKeyWithState and ReduceByKeyWithState, and the windows, i.e.
+// SlidingWindowWithState and SessionWindowWithState, are checkpointed. GroupBy and GroupByDynamic emit an
+// Observable for each group, which can't be checkpointed, the keyed aggregations should be used instead.
+type State struct {
+	// Store is where the state is saved.
+	Store StateStore
+	// Name is the identity of state in the store, it must be unique across the operators which share the store.
+	Name string
+	// CheckpointInMS is the interval of checkpoints in milliseconds, the state is saved on every change if it's 0.
+	// The state is always saved when the stream completes.
+	CheckpointInMS uint32
+}
+
+// load the values of state, the empty values are returned if the state isn't found or can't be decoded.
+func (st *State) load() map[string]interface{} {
+	values := make(map[string]interface{})
+	buf, ok, err := st.Store.Load(st.Name)
+	if err != nil {
+		logger.Error("[State] load the state failed.", "name", st.Name, "err", err)
+		return values
+	}
+	if !ok {
+		return values
+	}
+	if err := gob.NewDecoder(bytes.NewReader(buf)).Decode(&values); err != nil {
+		logger.Error("[State] decode the state failed.", "name", st.Name, "err", err)
+		return make(map[string]interface{})
+	}
+	return values
+}
+
+// save the values of state.
+func (st *State) save(values map[string]interface{}) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(values); err != nil {
+		logger.Error("[State] encode the state failed.", "name", st.Name, "err", err)
+		return
+	}
+	if err := st.Store.Save(st.Name, buf.Bytes()); err != nil {
+		logger.Error("[State] save the state failed.", "name", st.Name, "err", err)
+	}
+}
+
+func init() {
+	// the items of windows.
+	gob.Register([]interface{}{})
+	gob.Register([]time.Time{})
+}
+
+// checkpointer saves the state when the checkpoint interval is passed.
+type checkpointer struct {
+	state    *State
+	last     time.Time
+	modified bool
+	ticker   *time.Ticker
+}
+
+// newCheckpointer creates the checkpointer of state, it does nothing if state is nil.
+func newCheckpointer(state *State) *checkpointer {
+	c := &checkpointer{state: state}
+	if state != nil && state.CheckpointInMS > 0 {
+		c.ticker = time.NewTicker(time.Duration(state.CheckpointInMS) * time.Millisecond)
+	}
+	return c
+}
+
+// ticks returns the channel of checkpoint intervals, so the modified state is saved without waiting for the next
+// item. It's nil if the state is saved on every change.
+func (c *checkpointer) ticks() <-chan time.Time {
+	if c.ticker == nil {
+		return nil
+	}
+	return c.ticker.C
+}
+
+// stop the ticker of checkpoint intervals.
+func (c *checkpointer) stop() {
+	if c.ticker != nil {
+		c.ticker.Stop()
+	}
+}
+
+// changed marks the state is modified, and saves it if the interval is passed.
+func (c *checkpointer) changed(values map[string]interface{}) {
+	if c.state == nil {
+		return
+	}
+	c.modified = true
+	if time.Since(c.last) >= time.Duration(c.state.CheckpointInMS)*time.Millisecond {
+		c.flush(values)
+	}
+}
+
+// isSaving indicates if the state is checkpointed.
+func (c *checkpointer) isSaving() bool {
+	return c.state != nil
+}
+
+// flush saves the state if it's modified since the last checkpoint.
+func (c *checkpointer) flush(values map[string]interface{}) {
+	if c.state == nil || !c.modified {
+		return
+	}
+	c.state.save(values)
+	c.last = time.Now()
+	c.modified = false
+}
+
+// NewMemoryStateStore creates a StateStore in memory, the state is kept across the streams in a process only.
+func NewMemoryStateStore() StateStore {
+	return &memoryStateStore{states: make(map[string][]byte)}
+}
+
+type memoryStateStore struct {
+	mutex  sync.RWMutex
+	states map[string][]byte
+}
+
+func (s *memoryStateStore) Load(name string) ([]byte, bool, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	state, ok := s.states[name]
+	return state, ok, nil
+}
+
+func (s *memoryStateStore) Save(name string, state []byte) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.states[name] = append([]byte(nil), state...)
+	return nil
+}
+
+// NewFileStateStore creates a StateStore which saves every state in a file of the directory.
+func NewFileStateStore(dir string) (StateStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &fileStateStore{dir: dir}, nil
+}
+
+type fileStateStore struct {
+	dir string
+}
+
+func (s *fileStateStore) path(name string) string {
+	return filepath.Join(s.dir, url.PathEscape(name)+".state")
+}
+
+func (s *fileStateStore) Load(name string) ([]byte, bool, error) {
+	state, err := ioutil.ReadFile(s.path(name))
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return state, true, nil
+}
+
+// Save writes the state to a temporary file and renames it, so the former state is kept if the process crashes.
+func (s *fileStateStore) Save(name string, state []byte) error {
+	tmp, err := ioutil.TempFile(s.dir, ".state-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(state); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(name))
+}
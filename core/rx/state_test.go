@@ -0,0 +1,45 @@
+package rx
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStateStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	fileStore, err := NewFileStateStore(dir)
+	assert.NoError(t, err)
+
+	for name, store := range map[string]StateStore{"memory": NewMemoryStateStore(), "file": fileStore} {
+		t.Run(name, func(t *testing.T) {
+			_, ok, err := store.Load("noise/avg")
+			assert.NoError(t, err)
+			assert.False(t, ok)
+
+			assert.NoError(t, store.Save("noise/avg", []byte{1}))
+			assert.NoError(t, store.Save("noise/avg", []byte{2}))
+			state, ok, err := store.Load("noise/avg")
+			assert.NoError(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, []byte{2}, state)
+		})
+	}
+}
+
+func TestStateCheckpoint(t *testing.T) {
+	state := &State{Store: NewMemoryStateStore(), Name: "sum", CheckpointInMS: 60000}
+	checkpoint := &checkpointer{state: state}
+
+	// the first change is saved, the following changes are saved by flush.
+	checkpoint.changed(map[string]interface{}{"a": 1})
+	checkpoint.changed(map[string]interface{}{"a": 2})
+	assert.Equal(t, map[string]interface{}{"a": 1}, state.load())
+
+	checkpoint.flush(map[string]interface{}{"a": 2, "b": "x"})
+	assert.Equal(t, map[string]interface{}{"a": 2, "b": "x"}, state.load())
+}
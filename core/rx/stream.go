@@ -2,6 +2,7 @@ package rx
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/cenkalti/backoff/v4"
@@ -10,6 +11,16 @@ import (
 )
 
 // Stream is the interface for RxStream.
+//
+// The stateful operators, e.g. the windows, BufferWithCount, ScanByKey, ReduceByKey, JoinByKey, ThrottleFirst,
+// SampleWithTime, EventTimeWindow and DistinctWithTTL, keep their state in the stream. A stream function runs its
+// handler on a new stream for each data by default, so they only take effect if the stream function is created
+// with streamfunction.WithStatefulPipeline, which runs the handler once on a long-lived stream of all data.
+//
+// The resilience of the side effects in handlers is expressed by MapWithRetry, MapWithTimeout and OnErrorResumeWith.
+// They're not named Retry(n, backoff) and Timeout(d), since Retry is the operator of rxgo which resubscribes to the
+// whole source, and a timeout of the stream would end it rather than fail the item which is timed out. The function
+// of side effect is wrapped instead, so only the failed item is retried or timed out.
 type Stream interface {
 	rxgo.Iterable
 
@@ -19,9 +30,30 @@ type Stream interface {
 	// OnObserve calls the function to process the observed data.
 	OnObserve(function func(v []byte) (interface{}, error)) Stream
 
+	// OnObserveWithContext calls the function to process the observed data with the context of stream, which carries
+	// the deadline, the tracing span and the metadata of the frame, and is cancelled if the frame is given up.
+	OnObserveWithContext(function func(ctx context.Context, v []byte) (interface{}, error)) Stream
+
+	// Also observes one more key by its own function after Subscribe(key).OnObserve(function), and merges the outputs
+	// of all keys, e.g. rx.Subscribe(0x10).OnObserve(f).Also(0x12, g).
+	Also(key byte, function func(v []byte) (interface{}, error)) Stream
+
+	// OnError calls the handler for every error in stream, the raw data is set if the error is returned by the function
+	// of OnObserve. The error is skipped, stops the stream or is routed to a tag by the policy. The errors of the
+	// functions of OnObserve and Also are logged and skipped unless OnError directly follows them.
+	OnError(handler func(err error, raw []byte), policy ErrorPolicy) Stream
+
 	// Encode the data with a specified key by Y3 Codec and append it to stream.
 	Encode(key byte, opts ...rxgo.Option) Stream
 
+	// Split encodes each item with the key returned by the selector by Y3 Codec, and the stream function sends it to
+	// YoMo-Zipper with the key as its tag, e.g. the normal readings to 0x11 and the anomalies to 0x20.
+	Split(selector func(v interface{}) byte, opts ...rxgo.Option) Stream
+
+	// EncodeWith marshals the data by the serializer of the content type, e.g. serde.JSON, and the stream function sends
+	// it to YoMo-Zipper with the key as its tag and the content type, so the handler returns the structs directly.
+	EncodeWith(key byte, contentType string, opts ...rxgo.Option) Stream
+
 	// RawBytes get the raw bytes in Stream which receives from YoMo-Zipper.
 	RawBytes() Stream
 
@@ -105,6 +137,10 @@ type Stream interface {
 	// Cannot be run in parallel.
 	DistinctUntilChanged(apply rxgo.Func, opts ...rxgo.Option) Stream
 
+	// DistinctWithTTL suppresses the items of which the key returned by apply is emitted in the last ttl milliseconds,
+	// the keys are forgotten after ttl, so the memory is bounded by the keys in ttl.
+	DistinctWithTTL(apply rxgo.Func, ttlInMS uint32, opts ...rxgo.Option) Stream
+
 	// DoOnCompleted registers a callback action that will be called once the Observable terminates.
 	DoOnCompleted(completedFunc rxgo.CompletedFunc, opts ...rxgo.Option) rxgo.Disposed
 
@@ -144,6 +180,10 @@ type Stream interface {
 	// FlatMap transforms the items emitted by an Observable into Observables, then flatten the emissions from those into a single Observable.
 	FlatMap(apply rxgo.ItemToObservable, opts ...rxgo.Option) Stream
 
+	// FlatMapSlice transforms each item into zero or more items by applying a function to it, and emits them one by one,
+	// e.g. splitting a batch upload into the records, the stream function sends every record as its own frame.
+	FlatMapSlice(apply func(ctx context.Context, i interface{}) ([]interface{}, error), opts ...rxgo.Option) Stream
+
 	// ForEach subscribes to the Observable and receives notifications for each element.
 	ForEach(nextFunc rxgo.NextFunc, errFunc rxgo.ErrFunc, completedFunc rxgo.CompletedFunc, opts ...rxgo.Option) rxgo.Disposed
 
@@ -174,6 +214,20 @@ type Stream interface {
 	// Map transforms the items emitted by an Observable by applying a function to each item.
 	Map(apply rxgo.Func, opts ...rxgo.Option) Stream
 
+	// MapParallel transforms the items by applying a function to each item concurrently with at most concurrency goroutines,
+	// e.g. the CPU-bound image decoding. The order of items is preserved if ordered is true.
+	MapParallel(apply rxgo.Func, concurrency int, ordered bool, opts ...rxgo.Option) Stream
+
+	// MapWithRetry transforms the items by applying a function to each item, the function is retried at most count times
+	// by the backoff if it returns an error, e.g. calling a flaky external API. It's the Retry(n, backoff) of a
+	// function rather than of the source, which Retry resubscribes to.
+	MapWithRetry(apply rxgo.Func, count int, backOffCfg backoff.BackOff, opts ...rxgo.Option) Stream
+
+	// MapWithTimeout transforms the items by applying a function to each item, ErrTimeout is emitted if the function
+	// doesn't return within the timeout in milliseconds. It's the Timeout(d) of a function, the stream goes on with
+	// the next item after the error.
+	MapWithTimeout(apply rxgo.Func, milliseconds uint32, opts ...rxgo.Option) Stream
+
 	// Marshal transforms the items emitted by an Observable by applying a marshalling to each item.
 	Marshal(marshaller decoder.Marshaller, opts ...rxgo.Option) Stream
 
@@ -187,6 +241,10 @@ type Stream interface {
 	// onError if it encounters an error.
 	OnErrorResumeNext(resumeSequence rxgo.ErrorToObservable, opts ...rxgo.Option) Stream
 
+	// OnErrorResumeWith replaces every error by the value returned by the fallback function and continues the stream,
+	// the error of the fallback function is emitted instead.
+	OnErrorResumeWith(fallback func(ctx context.Context, err error) (interface{}, error), opts ...rxgo.Option) Stream
+
 	// OnErrorReturn instructs an Observable to emit an item (returned by a specified function)
 	// rather than invoking onError if it encounters an error.
 	OnErrorReturn(resumeFunc rxgo.ErrorFunc, opts ...rxgo.Option) Stream
@@ -197,6 +255,13 @@ type Stream interface {
 	// Reduce applies a function to each item emitted by an Observable, sequentially, and emit the final value.
 	Reduce(apply rxgo.Func2, opts ...rxgo.Option) Stream
 
+	// ReduceByKey applies a function to each item of the same key, sequentially, and emits the final value of each key
+	// as KeyedItem when the stream completes.
+	ReduceByKey(keySelector func(interface{}) string, apply rxgo.Func2, opts ...rxgo.Option) Stream
+
+	// ReduceByKeyWithState is ReduceByKey of which the accumulators are resumed from the state and checkpointed to it.
+	ReduceByKeyWithState(keySelector func(interface{}) string, apply rxgo.Func2, state *State, opts ...rxgo.Option) Stream
+
 	// Repeat returns an Observable that repeats the sequence of items emitted by the source Observable
 	// at most count times, at a particular frequency.
 	// Cannot run in parallel.
@@ -213,10 +278,21 @@ type Stream interface {
 	// Iterable whenever the input Iterable emits an item.
 	Sample(iterable rxgo.Iterable, opts ...rxgo.Option) Stream
 
+	// SampleWithTime emits the most recent item at every interval in milliseconds if any item is received in the interval.
+	SampleWithTime(milliseconds uint32, opts ...rxgo.Option) Stream
+
 	// Scan apply a Func2 to each item emitted by an Observable, sequentially, and emit each successive value.
 	// Cannot be run in parallel.
 	Scan(apply rxgo.Func2, opts ...rxgo.Option) Stream
 
+	// ScanByKey applies a function to each item of the same key, sequentially, and emits each successive value of the key
+	// as KeyedItem, e.g. the running average of each sensor.
+	ScanByKey(keySelector func(interface{}) string, apply rxgo.Func2, opts ...rxgo.Option) Stream
+
+	// ScanByKeyWithState is ScanByKey of which the accumulators are resumed from the state and checkpointed to it,
+	// so a restarted stream function resumes the aggregations.
+	ScanByKeyWithState(keySelector func(interface{}) string, apply rxgo.Func2, state *State, opts ...rxgo.Option) Stream
+
 	// SequenceEqual emits true if an Observable and the input Observable emit the same items,
 	// in the same order, with the same termination state. Otherwise, it emits false.
 	SequenceEqual(iterable rxgo.Iterable, opts ...rxgo.Option) Stream
@@ -271,12 +347,19 @@ type Stream interface {
 	// Cannot be run in parallel.
 	TakeWhile(apply rxgo.Predicate, opts ...rxgo.Option) Stream
 
+	// ThrottleFirst emits the first item, then ignores the items for duration milliseconds.
+	ThrottleFirst(milliseconds uint32, opts ...rxgo.Option) Stream
+
 	// TimeInterval converts an Observable that emits items into one that emits indications of the amount of time elapsed between those emissions.
 	TimeInterval(opts ...rxgo.Option) Stream
 
 	// Timestamp attaches a timestamp to each item emitted by an Observable indicating when it was emitted.
 	Timestamp(opts ...rxgo.Option) Stream
 
+	// Transform applies the custom operator to the stream, so the operators out of this package compose with the
+	// built-in ones, e.g. a Kalman filter.
+	Transform(operator Operator, opts ...rxgo.Option) Stream
+
 	// ToMap convert the sequence of items emitted by an Observable
 	// into a map keyed by a specified key function.
 	// Cannot be run in parallel.
@@ -295,6 +378,10 @@ type Stream interface {
 	// Unmarshal transforms the items emitted by an Observable by applying an unmarshalling to each item.
 	Unmarshal(unmarshaller decoder.Unmarshaller, factory func() interface{}, opts ...rxgo.Option) Stream
 
+	// UnmarshalPayload transforms the items emitted by an Observable by unmarshalling each item with the serializer
+	// of its content type, which is set by the source. The items are raw bytes if the content type isn't set.
+	UnmarshalPayload(factory func() interface{}, opts ...rxgo.Option) Stream
+
 	// WindowWithCount periodically subdivides items from an Observable into Observable windows of a given size and emit these windows
 	// rather than emitting the items one at a time.
 	WindowWithCount(count int, opts ...rxgo.Option) Stream
@@ -319,8 +406,120 @@ type Stream interface {
 	// It returns the orginal data to Stream, not the buffered slice.
 	SlidingWindowWithTime(windowTimeInMS uint32, slideTimeInMS uint32, handler Handler, opts ...rxgo.Option) Stream
 
+	// SlidingWindow emits the slice of items received in the last window time in milliseconds at every slide time,
+	// it's a tumbling window when the slide time equals the window time, e.g. the average noise per 10s.
+	// The empty windows are not emitted.
+	SlidingWindow(windowTimeInMS uint32, slideTimeInMS uint32, opts ...rxgo.Option) Stream
+
+	// SlidingWindowWithState is SlidingWindow of which the items in the window are resumed from the state and
+	// checkpointed to it, so a restarted stream function doesn't lose the window.
+	SlidingWindowWithState(windowTimeInMS uint32, slideTimeInMS uint32, state *State, opts ...rxgo.Option) Stream
+
+	// SessionWindow emits the slice of items which are received without a gap longer than the gap time in milliseconds,
+	// e.g. the readings of a burst of activity.
+	SessionWindow(gapInMS uint32, opts ...rxgo.Option) Stream
+
+	// SessionWindowWithState is SessionWindow of which the items of current session are resumed from the state and
+	// checkpointed to it, so a restarted stream function continues the session.
+	SessionWindowWithState(gapInMS uint32, state *State, opts ...rxgo.Option) Stream
+
+	// EventTimeWindow groups the items into the tumbling windows by the event time extracted by timeExtractor, e.g. the
+	// timestamp in payload, and emits an EventWindow when the watermark passes its end. The watermark is the latest event
+	// time minus the allowed lateness in milliseconds, so the delayed items within the lateness are still aggregated.
+	EventTimeWindow(timeExtractor func(interface{}) time.Time, windowInMS uint32, allowedLatenessInMS uint32, opts ...rxgo.Option) Stream
+
 	// ZipMultiObservers subscribes multi Y3 observers, zips the values into a slice and calls the zipper callback when all keys are observed.
 	ZipMultiObservers(observers []KeyObserveFunc, zipper func(items []interface{}) (interface{}, error)) Stream
+
+	// JoinByKey subscribes two Y3 observers, and emits the pair of values which have the same join key by keyFn within
+	// the window time in milliseconds, e.g. the GPS and the accelerometer readings of the same device within 500ms.
+	// The pair is a slice of the value of observer and the value of other.
+	JoinByKey(observer KeyObserveFunc, other KeyObserveFunc, keyFn func(interface{}) string, windowInMS uint32) Stream
+}
+
+// Operator is a custom operator of Stream, it reads the items of the upstream from in, and returns the channel of
+// the items to the downstream. The returned channel must be closed when in is closed or ctx is done, e.g.
+//
+//	func dedupe(ctx context.Context, in <-chan rxgo.Item) <-chan rxgo.Item {
+//		out := make(chan rxgo.Item)
+//		go func() {
+//			defer close(out)
+//			var last interface{}
+//			for item := range in {
+//				if item.Error() || item.V != last {
+//					last = item.V
+//					if !item.SendContext(ctx, out) {
+//						return
+//					}
+//				}
+//			}
+//		}()
+//		return out
+//	}
+type Operator func(ctx context.Context, in <-chan rxgo.Item) <-chan rxgo.Item
+
+// ObserveError is the error returned by the function of OnObserve or Also, with the observed raw data.
+type ObserveError struct {
+	Key byte
+	Raw []byte
+	Err error
+}
+
+func (e *ObserveError) Error() string {
+	return fmt.Sprintf("observe the key %#x failed: %v", e.Key, e.Err)
+}
+
+func (e *ObserveError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorPolicy is the policy of OnError to handle the errors in stream.
+type ErrorPolicy struct {
+	stop    bool
+	route   bool
+	tag     byte
+	counter *uint64
+}
+
+var (
+	// SkipOnError drops the errors and continues the stream.
+	SkipOnError = ErrorPolicy{}
+	// StopOnError emits the error and stops the stream.
+	StopOnError = ErrorPolicy{stop: true}
+)
+
+// RouteOnError sends the raw data, or the message of error if there is no raw data, to the tag as TaggedData,
+// e.g. a dead letter tag observed by another stream function.
+func RouteOnError(tag byte) ErrorPolicy {
+	return ErrorPolicy{route: true, tag: tag}
+}
+
+// WithCounter returns a copy of the policy which adds the number of errors handled by OnError to counter, so each
+// pipeline counts its own errors. The counter is read by atomic.LoadUint64.
+func (p ErrorPolicy) WithCounter(counter *uint64) ErrorPolicy {
+	p.counter = counter
+	return p
+}
+
+// EventWindow is the window of items emitted by EventTimeWindow, the event time of items is in [Start, End).
+type EventWindow struct {
+	Start time.Time
+	End   time.Time
+	Items []interface{}
+}
+
+// TaggedData is the encoded data with the tag of DataFrame, it's emitted by Split and EncodeWith.
+type TaggedData struct {
+	Tag  byte
+	Data []byte
+	// ContentType is the content type of Data, the content type of stream function is used if it's empty.
+	ContentType string
+}
+
+// KeyedItem is the aggregated value of a key.
+type KeyedItem struct {
+	Key   string
+	Value interface{}
 }
 
 // KeyObserveFunc is a pair of subscribed key and onObserve callback.
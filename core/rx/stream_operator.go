@@ -4,16 +4,23 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"reflect"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/cenkalti/backoff/v4"
 	"github.com/reactivex/rxgo/v2"
 	y3 "github.com/yomorun/y3-codec-golang"
+	"github.com/yomorun/yomo/core/serde"
 	"github.com/yomorun/yomo/internal/decoder"
 	"github.com/yomorun/yomo/logger"
 )
 
+// ErrTimeout is emitted by MapWithTimeout when the function doesn't return within the timeout.
+var ErrTimeout = errors.New("rx: the function is timed out")
+
 // Of creates an item from a value.
 func Of(i interface{}) rxgo.Item {
 	return rxgo.Item{V: i}
@@ -23,6 +30,17 @@ func Of(i interface{}) rxgo.Item {
 type StreamImpl struct {
 	ctx        context.Context
 	observable rxgo.Observable
+	// subscription is the subscribed keys of the source stream, it's set by Subscribe, OnObserve and Also.
+	subscription *subscription
+	// observeErrors is set to 1 by OnError which follows OnObserve or Also, so the errors of their functions are
+	// emitted instead of skipped. It's nil for the other operators.
+	observeErrors *int32
+}
+
+// subscription is the Y3 observers of the source stream, the source is observed once for all keys.
+type subscription struct {
+	source    *StreamImpl
+	observers []KeyObserveFunc
 }
 
 // appendContinueOnError appends the "ContinueOnError" to options
@@ -162,6 +180,70 @@ func (s *StreamImpl) DistinctUntilChanged(apply rxgo.Func, opts ...rxgo.Option)
 	return &StreamImpl{ctx: s.ctx, observable: rxgo.FromChannel(s.observable.DistinctUntilChanged(apply, opts...).Observe(), opts...)}
 }
 
+// DistinctWithTTL suppresses the items of which the key is emitted in the last ttl milliseconds, the item is emitted
+// again if its key isn't emitted in ttl, e.g. a sensor resends the same reading. The keys which can't be compared,
+// e.g. []byte, are compared by their content.
+func (s *StreamImpl) DistinctWithTTL(apply rxgo.Func, ttlInMS uint32, opts ...rxgo.Option) Stream {
+	ttl := time.Duration(ttlInMS) * time.Millisecond
+	f := func(ctx context.Context, next chan rxgo.Item) {
+		defer close(next)
+		// the emitted keys, and the keys in the order of time to forget the expired ones.
+		seen := make(map[interface{}]struct{})
+		queue := make([]slidingWithTimeItem, 0)
+
+		for item := range s.Observe() {
+			if item.Error() {
+				if !item.SendContext(ctx, next) {
+					return
+				}
+				continue
+			}
+
+			key, err := apply(ctx, item.V)
+			if err != nil {
+				if !rxgo.Error(err).SendContext(ctx, next) {
+					return
+				}
+				continue
+			}
+
+			key = distinctKey(key)
+			now := time.Now()
+			i := 0
+			for i < len(queue) && now.Sub(queue[i].timestamp) >= ttl {
+				delete(seen, queue[i].data)
+				i++
+			}
+			queue = queue[i:]
+
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			queue = append(queue, slidingWithTimeItem{timestamp: now, data: key})
+			if !item.SendContext(ctx, next) {
+				return
+			}
+		}
+	}
+	return CreateObservable(s.ctx, f, opts...)
+}
+
+// distinctKey returns the key which can be stored in a map, the keys which can't be compared are replaced by
+// the representation of their content, since they panic as the keys of map.
+func distinctKey(key interface{}) interface{} {
+	switch k := key.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return string(k)
+	}
+	if reflect.TypeOf(key).Comparable() {
+		return key
+	}
+	return fmt.Sprintf("%#v", key)
+}
+
 // DoOnCompleted registers a callback action that will be called once the Observable terminates.
 func (s *StreamImpl) DoOnCompleted(completedFunc rxgo.CompletedFunc, opts ...rxgo.Option) rxgo.Disposed {
 	opts = appendContinueOnError(s.ctx, opts...)
@@ -234,6 +316,35 @@ func (s *StreamImpl) FlatMap(apply rxgo.ItemToObservable, opts ...rxgo.Option) S
 	return &StreamImpl{ctx: s.ctx, observable: rxgo.FromChannel(s.observable.FlatMap(apply, opts...).Observe(), opts...)}
 }
 
+// FlatMapSlice transforms each item into zero or more items by applying a function to it, and emits them in order.
+func (s *StreamImpl) FlatMapSlice(apply func(ctx context.Context, i interface{}) ([]interface{}, error), opts ...rxgo.Option) Stream {
+	f := func(ctx context.Context, next chan rxgo.Item) {
+		defer close(next)
+		for item := range s.Observe() {
+			if item.Error() {
+				if !item.SendContext(ctx, next) {
+					return
+				}
+				continue
+			}
+
+			values, err := apply(ctx, item.V)
+			if err != nil {
+				if !rxgo.Error(err).SendContext(ctx, next) {
+					return
+				}
+				continue
+			}
+			for _, v := range values {
+				if !Of(v).SendContext(ctx, next) {
+					return
+				}
+			}
+		}
+	}
+	return CreateObservable(s.ctx, f, opts...)
+}
+
 // ForEach subscribes to the Observable and receives notifications for each element.
 func (s *StreamImpl) ForEach(nextFunc rxgo.NextFunc, errFunc rxgo.ErrFunc, completedFunc rxgo.CompletedFunc, opts ...rxgo.Option) rxgo.Disposed {
 	opts = appendContinueOnError(s.ctx, opts...)
@@ -288,6 +399,176 @@ func (s *StreamImpl) Map(apply rxgo.Func, opts ...rxgo.Option) Stream {
 	return &StreamImpl{ctx: s.ctx, observable: rxgo.FromChannel(s.observable.Map(apply, opts...).Observe(), opts...)}
 }
 
+// MapParallel transforms the items by applying a function to each item concurrently with at most concurrency goroutines.
+// The items are emitted in the order they are completed unless ordered is true, a slow item holds the following
+// items back if the order is preserved.
+func (s *StreamImpl) MapParallel(apply rxgo.Func, concurrency int, ordered bool, opts ...rxgo.Option) Stream {
+	if concurrency <= 0 {
+		return s.thrown(errors.New("concurrency must be positive"))
+	}
+
+	mapItem := func(ctx context.Context, item rxgo.Item) rxgo.Item {
+		if item.Error() {
+			return item
+		}
+		v, err := apply(ctx, item.V)
+		if err != nil {
+			return rxgo.Error(err)
+		}
+		return Of(v)
+	}
+
+	if ordered {
+		f := func(ctx context.Context, next chan rxgo.Item) {
+			defer close(next)
+			// the results in the order of items, at most concurrency items are in flight.
+			results := make(chan chan rxgo.Item, concurrency-1)
+			go func() {
+				defer close(results)
+				for item := range s.Observe() {
+					result := make(chan rxgo.Item, 1)
+					select {
+					case <-ctx.Done():
+						return
+					case results <- result:
+					}
+					go func(item rxgo.Item) {
+						result <- mapItem(ctx, item)
+					}(item)
+				}
+			}()
+
+			for result := range results {
+				select {
+				case <-ctx.Done():
+					return
+				case item := <-result:
+					if !item.SendContext(ctx, next) {
+						return
+					}
+				}
+			}
+		}
+		return CreateObservable(s.ctx, f, opts...)
+	}
+
+	f := func(ctx context.Context, next chan rxgo.Item) {
+		defer close(next)
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, concurrency)
+		for item := range s.Observe() {
+			select {
+			case <-ctx.Done():
+				wg.Wait()
+				return
+			case sem <- struct{}{}:
+			}
+			wg.Add(1)
+			go func(item rxgo.Item) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				mapItem(ctx, item).SendContext(ctx, next)
+			}(item)
+		}
+		wg.Wait()
+	}
+	return CreateObservable(s.ctx, f, opts...)
+}
+
+// MapWithRetry transforms the items by applying a function to each item, the function is retried at most count times
+// by the backoff if it returns an error, the last error is emitted if all retries fail. The backoff is reset for each
+// item, and the function is retried immediately if the backoff is nil.
+func (s *StreamImpl) MapWithRetry(apply rxgo.Func, count int, backOffCfg backoff.BackOff, opts ...rxgo.Option) Stream {
+	f := func(ctx context.Context, next chan rxgo.Item) {
+		defer close(next)
+		for item := range s.Observe() {
+			if item.Error() {
+				if !item.SendContext(ctx, next) {
+					return
+				}
+				continue
+			}
+
+			if backOffCfg != nil {
+				backOffCfg.Reset()
+			}
+			var result rxgo.Item
+			for attempt := 0; ; attempt++ {
+				v, err := apply(ctx, item.V)
+				if err == nil {
+					result = Of(v)
+					break
+				}
+				result = rxgo.Error(err)
+				if attempt >= count {
+					break
+				}
+
+				var wait time.Duration
+				if backOffCfg != nil {
+					if wait = backOffCfg.NextBackOff(); wait == backoff.Stop {
+						break
+					}
+				}
+				logger.Debug("[MapWithRetry] retry the function.", "attempt", attempt+1, "wait", wait, "err", err)
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(wait):
+				}
+			}
+
+			if !result.SendContext(ctx, next) {
+				return
+			}
+		}
+	}
+	return CreateObservable(s.ctx, f, opts...)
+}
+
+// MapWithTimeout transforms the items by applying a function to each item, ErrTimeout is emitted if the function
+// doesn't return within the timeout. The context of function is cancelled when it's timed out.
+func (s *StreamImpl) MapWithTimeout(apply rxgo.Func, milliseconds uint32, opts ...rxgo.Option) Stream {
+	timeout := time.Duration(milliseconds) * time.Millisecond
+	f := func(ctx context.Context, next chan rxgo.Item) {
+		defer close(next)
+		for item := range s.Observe() {
+			if item.Error() {
+				if !item.SendContext(ctx, next) {
+					return
+				}
+				continue
+			}
+
+			applyCtx, cancel := context.WithTimeout(ctx, timeout)
+			done := make(chan rxgo.Item, 1)
+			go func(v interface{}) {
+				v, err := apply(applyCtx, v)
+				if err != nil {
+					done <- rxgo.Error(err)
+					return
+				}
+				done <- Of(v)
+			}(item.V)
+
+			var result rxgo.Item
+			select {
+			case result = <-done:
+			case <-applyCtx.Done():
+				result = rxgo.Error(ErrTimeout)
+			}
+			cancel()
+
+			if ctx.Err() != nil || !result.SendContext(ctx, next) {
+				return
+			}
+		}
+	}
+	return CreateObservable(s.ctx, f, opts...)
+}
+
 // Marshal transforms the items emitted by an Observable by applying a marshalling to each item.
 func (s *StreamImpl) Marshal(marshaller decoder.Marshaller, opts ...rxgo.Option) Stream {
 	opts = appendContinueOnError(s.ctx, opts...)
@@ -297,10 +578,22 @@ func (s *StreamImpl) Marshal(marshaller decoder.Marshaller, opts ...rxgo.Option)
 	}, opts...)
 }
 
+// UnmarshalPayload transforms the items emitted by an Observable by unmarshalling each item with the serializer
+// of its content type.
+func (s *StreamImpl) UnmarshalPayload(factory func() interface{}, opts ...rxgo.Option) Stream {
+	contentType, _ := serde.FromContext(s.ctx)
+	return s.Unmarshal(func(data []byte, v interface{}) error {
+		return serde.Unmarshal(contentType, data, v)
+	}, factory, opts...)
+}
+
 // Unmarshal transforms the items emitted by an Observable by applying an unmarshalling to each item.
 func (s *StreamImpl) Unmarshal(unmarshaller decoder.Unmarshaller, factory func() interface{}, opts ...rxgo.Option) Stream {
 	f := func(ctx context.Context, next chan rxgo.Item) {
+		// next is closed after all items are unmarshalled.
+		var wg sync.WaitGroup
 		defer close(next)
+		defer wg.Wait()
 		observe := s.Observe()
 		for {
 			select {
@@ -313,7 +606,9 @@ func (s *StreamImpl) Unmarshal(unmarshaller decoder.Unmarshaller, factory func()
 				if item.Error() {
 					continue
 				}
+				wg.Add(1)
 				go func() {
+					defer wg.Done()
 					onObserve := (item.V).(decoder.Observable).Unmarshal(unmarshaller, factory)
 
 					for {
@@ -355,6 +650,27 @@ func (s *StreamImpl) OnErrorResumeNext(resumeSequence rxgo.ErrorToObservable, op
 	return &StreamImpl{ctx: s.ctx, observable: rxgo.FromChannel(s.observable.OnErrorResumeNext(resumeSequence, opts...).Observe(), opts...)}
 }
 
+// OnErrorResumeWith replaces every error by the value returned by the fallback function and continues the stream.
+func (s *StreamImpl) OnErrorResumeWith(fallback func(ctx context.Context, err error) (interface{}, error), opts ...rxgo.Option) Stream {
+	f := func(ctx context.Context, next chan rxgo.Item) {
+		defer close(next)
+		for item := range s.Observe() {
+			if item.Error() {
+				v, err := fallback(ctx, item.E)
+				if err != nil {
+					item = rxgo.Error(err)
+				} else {
+					item = Of(v)
+				}
+			}
+			if !item.SendContext(ctx, next) {
+				return
+			}
+		}
+	}
+	return CreateObservable(s.ctx, f, opts...)
+}
+
 // OnErrorReturn instructs an Observable to emit an item (returned by a specified function)
 // rather than invoking onError if it encounters an error.
 func (s *StreamImpl) OnErrorReturn(resumeFunc rxgo.ErrorFunc, opts ...rxgo.Option) Stream {
@@ -374,6 +690,85 @@ func (s *StreamImpl) Reduce(apply rxgo.Func2, opts ...rxgo.Option) Stream {
 	return &StreamImpl{ctx: s.ctx, observable: rxgo.FromChannel(s.observable.Reduce(apply, opts...).Observe(), opts...)}
 }
 
+// ReduceByKey applies a function to each item of the same key, sequentially, and emits the final value of each key
+// in the order the keys are first seen when the stream completes.
+func (s *StreamImpl) ReduceByKey(keySelector func(interface{}) string, apply rxgo.Func2, opts ...rxgo.Option) Stream {
+	return s.reduceByKey(keySelector, apply, nil, opts...)
+}
+
+// ReduceByKeyWithState is ReduceByKey of which the accumulators are resumed from the state and checkpointed to it,
+// the resumed keys are emitted in the order of keys.
+func (s *StreamImpl) ReduceByKeyWithState(keySelector func(interface{}) string, apply rxgo.Func2, state *State, opts ...rxgo.Option) Stream {
+	if state == nil || state.Store == nil {
+		return s.thrown(errors.New("the store of state must be set"))
+	}
+	return s.reduceByKey(keySelector, apply, state, opts...)
+}
+
+func (s *StreamImpl) reduceByKey(keySelector func(interface{}) string, apply rxgo.Func2, state *State, opts ...rxgo.Option) Stream {
+	f := func(ctx context.Context, next chan rxgo.Item) {
+		defer close(next)
+		keys := make([]string, 0)
+		values := make(map[string]interface{})
+		if state != nil {
+			values = state.load()
+			for key := range values {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+		}
+		checkpoint := newCheckpointer(state)
+		defer checkpoint.stop()
+
+		observe := s.Observe()
+	LOOP:
+		for {
+			var item rxgo.Item
+			var ok bool
+			select {
+			case <-checkpoint.ticks():
+				checkpoint.flush(values)
+				continue
+			case item, ok = <-observe:
+				if !ok {
+					break LOOP
+				}
+			}
+			if item.Error() {
+				if !item.SendContext(ctx, next) {
+					return
+				}
+				continue
+			}
+
+			key := keySelector(item.V)
+			acc, ok := values[key]
+			if !ok {
+				keys = append(keys, key)
+			}
+			v, err := apply(ctx, acc, item.V)
+			if err != nil {
+				if !rxgo.Error(err).SendContext(ctx, next) {
+					return
+				}
+				continue
+			}
+			values[key] = v
+			checkpoint.changed(values)
+		}
+		checkpoint.flush(values)
+
+		for _, key := range keys {
+			if v, ok := values[key]; ok {
+				if !Of(KeyedItem{Key: key, Value: v}).SendContext(ctx, next) {
+					return
+				}
+			}
+		}
+	}
+	return CreateObservable(s.ctx, f, opts...)
+}
+
 // Repeat returns an Observable that repeats the sequence of items emitted by the source Observable
 // at most count times, at a particular frequency.
 // Cannot run in parallel.
@@ -402,6 +797,52 @@ func (s *StreamImpl) Sample(iterable rxgo.Iterable, opts ...rxgo.Option) Stream
 	return &StreamImpl{ctx: s.ctx, observable: rxgo.FromChannel(s.observable.Sample(iterable, opts...).Observe(), opts...)}
 }
 
+// SampleWithTime emits the most recent item at every interval if any item is received in the interval,
+// the most recent item is emitted when the stream completes.
+func (s *StreamImpl) SampleWithTime(milliseconds uint32, opts ...rxgo.Option) Stream {
+	if milliseconds == 0 {
+		return s.thrown(errors.New("milliseconds must be positive"))
+	}
+
+	f := func(ctx context.Context, next chan rxgo.Item) {
+		defer close(next)
+		observe := s.Observe()
+		ticker := time.NewTicker(time.Duration(milliseconds) * time.Millisecond)
+		defer ticker.Stop()
+		var latest *rxgo.Item
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if latest == nil {
+					continue
+				}
+				if !latest.SendContext(ctx, next) {
+					return
+				}
+				latest = nil
+			case item, ok := <-observe:
+				if !ok {
+					if latest != nil {
+						latest.SendContext(ctx, next)
+					}
+					return
+				}
+				if item.Error() {
+					if !item.SendContext(ctx, next) {
+						return
+					}
+					continue
+				}
+				latest = &item
+			}
+		}
+	}
+	return CreateObservable(s.ctx, f, opts...)
+}
+
 // Scan apply a Func2 to each item emitted by an Observable, sequentially, and emit each successive value.
 // Cannot be run in parallel.
 func (s *StreamImpl) Scan(apply rxgo.Func2, opts ...rxgo.Option) Stream {
@@ -409,6 +850,69 @@ func (s *StreamImpl) Scan(apply rxgo.Func2, opts ...rxgo.Option) Stream {
 	return &StreamImpl{ctx: s.ctx, observable: rxgo.FromChannel(s.observable.Scan(apply, opts...).Observe(), opts...)}
 }
 
+// ScanByKey applies a function to each item of the same key, sequentially, and emits each successive value of the key.
+// The accumulator of the first item of a key is nil, the state of a key is kept unchanged if the function returns an error.
+func (s *StreamImpl) ScanByKey(keySelector func(interface{}) string, apply rxgo.Func2, opts ...rxgo.Option) Stream {
+	return s.scanByKey(keySelector, apply, nil, opts...)
+}
+
+// ScanByKeyWithState is ScanByKey of which the accumulators are resumed from the state and checkpointed to it.
+func (s *StreamImpl) ScanByKeyWithState(keySelector func(interface{}) string, apply rxgo.Func2, state *State, opts ...rxgo.Option) Stream {
+	if state == nil || state.Store == nil {
+		return s.thrown(errors.New("the store of state must be set"))
+	}
+	return s.scanByKey(keySelector, apply, state, opts...)
+}
+
+func (s *StreamImpl) scanByKey(keySelector func(interface{}) string, apply rxgo.Func2, state *State, opts ...rxgo.Option) Stream {
+	f := func(ctx context.Context, next chan rxgo.Item) {
+		defer close(next)
+		values := make(map[string]interface{})
+		if state != nil {
+			values = state.load()
+		}
+		checkpoint := newCheckpointer(state)
+		defer checkpoint.stop()
+		defer checkpoint.flush(values)
+
+		observe := s.Observe()
+		for {
+			var item rxgo.Item
+			var ok bool
+			select {
+			case <-checkpoint.ticks():
+				checkpoint.flush(values)
+				continue
+			case item, ok = <-observe:
+				if !ok {
+					return
+				}
+			}
+			if item.Error() {
+				if !item.SendContext(ctx, next) {
+					return
+				}
+				continue
+			}
+
+			key := keySelector(item.V)
+			v, err := apply(ctx, values[key], item.V)
+			if err != nil {
+				if !rxgo.Error(err).SendContext(ctx, next) {
+					return
+				}
+				continue
+			}
+			values[key] = v
+			checkpoint.changed(values)
+			if !Of(KeyedItem{Key: key, Value: v}).SendContext(ctx, next) {
+				return
+			}
+		}
+	}
+	return CreateObservable(s.ctx, f, opts...)
+}
+
 // Send sends the items to a given channel.
 func (s *StreamImpl) Send(output chan<- rxgo.Item, opts ...rxgo.Option) {
 	opts = appendContinueOnError(s.ctx, opts...)
@@ -505,6 +1009,28 @@ func (s *StreamImpl) TakeWhile(apply rxgo.Predicate, opts ...rxgo.Option) Stream
 	return &StreamImpl{ctx: s.ctx, observable: rxgo.FromChannel(s.observable.TakeWhile(apply, opts...).Observe(), opts...)}
 }
 
+// ThrottleFirst emits the first item, then ignores the items for duration milliseconds, the errors are not throttled.
+func (s *StreamImpl) ThrottleFirst(milliseconds uint32, opts ...rxgo.Option) Stream {
+	duration := time.Duration(milliseconds) * time.Millisecond
+	f := func(ctx context.Context, next chan rxgo.Item) {
+		defer close(next)
+		var last time.Time
+		for item := range s.Observe() {
+			if !item.Error() {
+				now := time.Now()
+				if !last.IsZero() && now.Sub(last) < duration {
+					continue
+				}
+				last = now
+			}
+			if !item.SendContext(ctx, next) {
+				return
+			}
+		}
+	}
+	return CreateObservable(s.ctx, f, opts...)
+}
+
 // TimeInterval converts an Observable that emits items into one that emits indications of the amount of time elapsed between those emissions.
 func (s *StreamImpl) TimeInterval(opts ...rxgo.Option) Stream {
 	opts = appendContinueOnError(s.ctx, opts...)
@@ -517,10 +1043,23 @@ func (s *StreamImpl) Timestamp(opts ...rxgo.Option) Stream {
 	return &StreamImpl{ctx: s.ctx, observable: rxgo.FromChannel(s.observable.Timestamp(opts...).Observe(), opts...)}
 }
 
-// ToMap convert the sequence of items emitted by an Observable
-// into a map keyed by a specified key function.
-// Cannot be run in parallel.
-func (s *StreamImpl) ToMap(keySelector rxgo.Func, opts ...rxgo.Option) Stream {
+// Transform applies the custom operator to the stream, the items returned by the operator are emitted downstream.
+func (s *StreamImpl) Transform(operator Operator, opts ...rxgo.Option) Stream {
+	f := func(ctx context.Context, next chan rxgo.Item) {
+		defer close(next)
+		for item := range operator(ctx, s.Observe()) {
+			if !item.SendContext(ctx, next) {
+				return
+			}
+		}
+	}
+	return CreateObservable(s.ctx, f, opts...)
+}
+
+// ToMap convert the sequence of items emitted by an Observable
+// into a map keyed by a specified key function.
+// Cannot be run in parallel.
+func (s *StreamImpl) ToMap(keySelector rxgo.Func, opts ...rxgo.Option) Stream {
 	opts = appendContinueOnError(s.ctx, opts...)
 	return &StreamImpl{ctx: s.ctx, observable: rxgo.FromChannel(s.observable.ToMap(keySelector, opts...).Observe(), opts...)}
 }
@@ -640,7 +1179,8 @@ func (s *StreamImpl) AuditTime(milliseconds uint32, opts ...rxgo.Option) Stream
 	return ConvertObservable(s.ctx, o)
 }
 
-// Subscribe the specified key by Y3 Codec.
+// Subscribe the specified key by Y3 Codec. The source is observed when the stream is observed, so the keys of
+// the following Also are observed together.
 func (s *StreamImpl) Subscribe(key byte) Stream {
 
 	f := func(ctx context.Context, next chan rxgo.Item) {
@@ -669,13 +1209,20 @@ func (s *StreamImpl) Subscribe(key byte) Stream {
 			}
 		}
 	}
-	return CreateObservable(s.ctx, f)
+	stream := createLazyObservable(s.ctx, f)
+	stream.subscription = &subscription{source: s, observers: []KeyObserveFunc{{Key: key}}}
+	return stream
 }
 
 // RawBytes get the raw bytes in Stream which receives from YoMo-Zipper.
 func (s *StreamImpl) RawBytes() Stream {
 	f := func(ctx context.Context, next chan rxgo.Item) {
-		defer close(next)
+		// next is closed after the raw bytes of all observables are sent.
+		var wg sync.WaitGroup
+		defer func() {
+			wg.Wait()
+			close(next)
+		}()
 		observe := s.Observe()
 		for {
 			select {
@@ -695,7 +1242,9 @@ func (s *StreamImpl) RawBytes() Stream {
 				}
 
 				bufCh := y3stream.RawBytes()
+				wg.Add(1)
 				go func() {
+					defer wg.Done()
 					for buf := range bufCh {
 						logger.Debug("[RawBytes] get the raw bytes from YoMo-Zipper.", "buf", logger.BytesString(buf))
 						Of(buf).SendContext(ctx, next)
@@ -826,9 +1375,192 @@ func (s *StreamImpl) ZipMultiObservers(observers []KeyObserveFunc, zipper func(i
 	return CreateObservable(s.ctx, f)
 }
 
+// JoinByKey subscribes two Y3 observers, and emits the pair of values which have the same join key within the window time.
+// Every value is joined with all values of the other observer received in the last window time, so a value may be emitted
+// in more than one pair.
+func (s *StreamImpl) JoinByKey(observer KeyObserveFunc, other KeyObserveFunc, keyFn func(interface{}) string, windowInMS uint32) Stream {
+	if observer.Key == other.Key {
+		return s.thrown(errors.New("[JoinByKey] the keys of observers must be different"))
+	}
+	if windowInMS == 0 {
+		return s.thrown(errors.New("[JoinByKey] windowInMS must be positive"))
+	}
+
+	f := func(ctx context.Context, next chan rxgo.Item) {
+		defer close(next)
+		window := time.Duration(windowInMS) * time.Millisecond
+		keyObserveMap := map[byte]decoder.OnObserveFunc{
+			observer.Key: observer.OnObserve,
+			other.Key:    other.OnObserve,
+		}
+		// the values received in the last window time, grouped by the join key.
+		buffers := map[byte]map[string][]slidingWithTimeItem{
+			observer.Key: make(map[string][]slidingWithTimeItem),
+			other.Key:    make(map[string][]slidingWithTimeItem),
+		}
+
+		observe := s.Observe()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case item, ok := <-observe:
+				if !ok {
+					return
+				}
+				if item.Error() {
+					continue
+				}
+				y3stream, ok := (item.V).(decoder.Observable)
+				if !ok {
+					logger.Error("[JoinByKey] the type of item.V is not `decoder.Observable`")
+					return
+				}
+
+				kvCh := y3stream.MultiSubscribe(observer.Key, other.Key).OnMultiObserve(keyObserveMap)
+				for kv := range kvCh {
+					now := time.Now()
+					start := now.Add(-window)
+					for _, buf := range buffers {
+						evictJoinBuffer(buf, start)
+					}
+
+					key := keyFn(kv.Value)
+					counterpart := other.Key
+					if kv.Key == other.Key {
+						counterpart = observer.Key
+					}
+					for _, v := range buffers[counterpart][key] {
+						pair := []interface{}{kv.Value, v.data}
+						if kv.Key == other.Key {
+							pair = []interface{}{v.data, kv.Value}
+						}
+						if !Of(pair).SendContext(ctx, next) {
+							return
+						}
+					}
+					buffers[kv.Key][key] = append(buffers[kv.Key][key], slidingWithTimeItem{timestamp: now, data: kv.Value})
+				}
+			}
+		}
+	}
+	return CreateObservable(s.ctx, f)
+}
+
+// evictJoinBuffer removes the values which are received before start.
+func evictJoinBuffer(buf map[string][]slidingWithTimeItem, start time.Time) {
+	for key, items := range buf {
+		i := 0
+		for i < len(items) && items[i].timestamp.Before(start) {
+			i++
+		}
+		if i == len(items) {
+			delete(buf, key)
+		} else {
+			buf[key] = items[i:]
+		}
+	}
+}
+
+// OnObserveWithContext calls the function to process the observed data with the context of stream, the data isn't
+// processed if the context is done.
+func (s *StreamImpl) OnObserveWithContext(function func(ctx context.Context, v []byte) (interface{}, error)) Stream {
+	ctx := s.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return s.OnObserve(func(v []byte) (interface{}, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return function(ctx, v)
+	})
+}
+
+// Also observes one more key by its own function after Subscribe(key).OnObserve(function), the source is observed
+// once by all keys, and the outputs of all functions are merged.
+func (s *StreamImpl) Also(key byte, function func(v []byte) (interface{}, error)) Stream {
+	if s.subscription == nil || s.subscription.observers[0].OnObserve == nil {
+		return s.thrown(errors.New("[Also] it must follow Subscribe(key).OnObserve(function)"))
+	}
+	for _, observer := range s.subscription.observers {
+		if observer.Key == key {
+			return s.thrown(fmt.Errorf("[Also] the key %v is observed already", key))
+		}
+	}
+
+	observers := append([]KeyObserveFunc{}, s.subscription.observers...)
+	observers = append(observers, KeyObserveFunc{Key: key, OnObserve: function})
+	return s.subscription.source.observeKeys(observers)
+}
+
+// observeKeys subscribes the keys of observers, and emits the outputs of their functions.
+func (s *StreamImpl) observeKeys(observers []KeyObserveFunc) *StreamImpl {
+	surface := new(int32)
+	keys := make([]byte, len(observers))
+	keyObserveMap := make(map[byte]decoder.OnObserveFunc, len(observers))
+	for i, observer := range observers {
+		keys[i] = observer.Key
+		keyObserveMap[observer.Key] = observeWithError(observer.Key, observer.OnObserve)
+	}
+
+	f := func(ctx context.Context, next chan rxgo.Item) {
+		// next is closed after all keys are observed.
+		var wg sync.WaitGroup
+		defer close(next)
+		defer wg.Wait()
+		observe := s.Observe()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case item, ok := <-observe:
+				if !ok {
+					return
+				}
+				if item.Error() {
+					continue
+				}
+				y3stream, ok := (item.V).(decoder.Observable)
+				if !ok {
+					logger.Error("[OnObserve] the type of item.V is not `decoder.Observable`")
+					return
+				}
+
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					kvCh := y3stream.MultiSubscribe(keys...).OnMultiObserve(keyObserveMap)
+					for kv := range kvCh {
+						logger.Debug("[OnObserve] Get data after OnObserve.", "key", kv.Key, "data", kv.Value)
+						item, ok := observed(kv.Value, surface)
+						if !ok {
+							continue
+						}
+						if !item.SendContext(ctx, next) {
+							return
+						}
+					}
+				}()
+			}
+		}
+	}
+
+	stream := createLazyObservable(s.ctx, f)
+	stream.subscription = &subscription{source: s, observers: observers}
+	stream.observeErrors = surface
+	return stream
+}
+
 // OnObserve calls the function to process the observed data.
 func (s *StreamImpl) OnObserve(function func(v []byte) (interface{}, error)) Stream {
+	if s.subscription != nil && len(s.subscription.observers) == 1 && s.subscription.observers[0].OnObserve == nil {
+		// Subscribe(key).OnObserve(function), the key is observed with the keys of the following Also.
+		key := s.subscription.observers[0].Key
+		return s.subscription.source.observeKeys([]KeyObserveFunc{{Key: key, OnObserve: function}})
+	}
 
+	surface := new(int32)
 	f := func(ctx context.Context, next chan rxgo.Item) {
 		defer close(next)
 		observe := s.Observe()
@@ -844,7 +1576,7 @@ func (s *StreamImpl) OnObserve(function func(v []byte) (interface{}, error)) Str
 					continue
 				}
 				go func() {
-					onObserve := (item.V).(decoder.Observable).OnObserve(function)
+					onObserve := (item.V).(decoder.Observable).OnObserve(observeWithError(0, function))
 
 					for {
 						select {
@@ -855,7 +1587,11 @@ func (s *StreamImpl) OnObserve(function func(v []byte) (interface{}, error)) Str
 								return
 							}
 							logger.Debug("[OnObserve] Get data after OnObserve.", "data", item)
-							if !Of(item).SendContext(ctx, next) {
+							observedItem, ok := observed(item, surface)
+							if !ok {
+								continue
+							}
+							if !observedItem.SendContext(ctx, next) {
 								return
 							}
 						}
@@ -864,6 +1600,79 @@ func (s *StreamImpl) OnObserve(function func(v []byte) (interface{}, error)) Str
 			}
 		}
 	}
+	// the stream is lazy, so OnError is attached before the first error is observed.
+	stream := createLazyObservable(s.ctx, f)
+	stream.observeErrors = surface
+	return stream
+}
+
+// observeWithError returns the error of function as the value, so the decoder passes it to the stream instead of
+// logging and dropping it.
+func observeWithError(key byte, function decoder.OnObserveFunc) decoder.OnObserveFunc {
+	return func(v []byte) (interface{}, error) {
+		value, err := function(v)
+		if err != nil {
+			return &ObserveError{Key: key, Raw: append([]byte(nil), v...), Err: err}, nil
+		}
+		return value, nil
+	}
+}
+
+// observed creates the item of the observed value, which is an error if the function of OnObserve failed and
+// OnError is attached, ok is false if the error is skipped.
+func observed(v interface{}, surface *int32) (item rxgo.Item, ok bool) {
+	err, isErr := v.(*ObserveError)
+	if !isErr {
+		return Of(v), true
+	}
+	if atomic.LoadInt32(surface) == 0 {
+		logger.Error("[OnObserve] the function of OnObserve failed, skip the data.", "key", err.Key, "err", err.Err)
+		return rxgo.Item{}, false
+	}
+	return rxgo.Error(err), true
+}
+
+// OnError calls the handler for every error in stream, and handles the error by the policy.
+func (s *StreamImpl) OnError(handler func(err error, raw []byte), policy ErrorPolicy) Stream {
+	if s.observeErrors != nil {
+		atomic.StoreInt32(s.observeErrors, 1)
+	}
+	f := func(ctx context.Context, next chan rxgo.Item) {
+		defer close(next)
+		for item := range s.Observe() {
+			if !item.Error() {
+				if !item.SendContext(ctx, next) {
+					return
+				}
+				continue
+			}
+
+			if policy.counter != nil {
+				atomic.AddUint64(policy.counter, 1)
+			}
+			var raw []byte
+			var observeErr *ObserveError
+			if errors.As(item.E, &observeErr) {
+				raw = observeErr.Raw
+			}
+			if handler != nil {
+				handler(item.E, raw)
+			}
+
+			switch {
+			case policy.stop:
+				item.SendContext(ctx, next)
+				return
+			case policy.route:
+				if raw == nil {
+					raw = []byte(item.E.Error())
+				}
+				if !Of(TaggedData{Tag: policy.tag, Data: raw}).SendContext(ctx, next) {
+					return
+				}
+			}
+		}
+	}
 	return CreateObservable(s.ctx, f)
 }
 
@@ -904,6 +1713,83 @@ func (s *StreamImpl) Encode(key byte, opts ...rxgo.Option) Stream {
 	return CreateObservable(s.ctx, f, opts...)
 }
 
+// EncodeWith marshals the data by the serializer of the content type, and emits TaggedData with the key as the tag.
+func (s *StreamImpl) EncodeWith(key byte, contentType string, opts ...rxgo.Option) Stream {
+	var serializer serde.Serializer
+	if contentType == serde.Y3 {
+		// the data is observed by the key of Y3 codec as Encode.
+		serializer = serde.NewSerializer(serde.Y3, y3.NewCodec(key).Marshal, y3.ToObject)
+	} else {
+		var ok bool
+		if serializer, ok = serde.Lookup(contentType); !ok {
+			return s.thrown(fmt.Errorf("%w: %s", serde.ErrUnknownContentType, contentType))
+		}
+	}
+
+	f := func(ctx context.Context, next chan rxgo.Item) {
+		defer close(next)
+		for item := range s.Observe(opts...) {
+			if item.Error() {
+				continue
+			}
+
+			buf, err := serializer.Marshal(item.V)
+			if err != nil {
+				logger.Debug("[EncodeWith Operator] encodes data failed.", "key", key, "contentType", contentType, "data", item.V, "err", err)
+				continue
+			}
+
+			data := TaggedData{Tag: key, Data: buf, ContentType: serializer.ContentType()}
+			if !Of(data).SendContext(ctx, next) {
+				return
+			}
+		}
+	}
+	return CreateObservable(s.ctx, f, opts...)
+}
+
+// Split encodes each item with the key returned by the selector by Y3 Codec, and emits TaggedData with the key as the tag.
+func (s *StreamImpl) Split(selector func(v interface{}) byte, opts ...rxgo.Option) Stream {
+	codecs := make(map[byte]y3.Codec)
+
+	f := func(ctx context.Context, next chan rxgo.Item) {
+		defer close(next)
+		observe := s.Observe(opts...)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case item, ok := <-observe:
+				if !ok {
+					return
+				}
+
+				if item.Error() {
+					continue
+				}
+
+				key := selector(item.V)
+				y3codec, ok := codecs[key]
+				if !ok {
+					y3codec = y3.NewCodec(key)
+					codecs[key] = y3codec
+				}
+				buf, err := y3codec.Marshal(item.V)
+				if err != nil {
+					logger.Debug("[Split Operator] encodes data failed via Y3 Codec.", "key", key, "data", item.V, "err", err)
+					continue
+				}
+
+				if !Of(TaggedData{Tag: key, Data: buf}).SendContext(ctx, next) {
+					return
+				}
+			}
+		}
+	}
+	return CreateObservable(s.ctx, f, opts...)
+}
+
 // SlidingWindowWithCount buffers the data in the specified sliding window size, the buffered data can be processed in the handler func.
 // It returns the orginal data to Stream, not the buffered slice.
 func (s *StreamImpl) SlidingWindowWithCount(windowSize int, slideSize int, handler Handler, opts ...rxgo.Option) Stream {
@@ -1054,6 +1940,281 @@ func (s *StreamImpl) SlidingWindowWithTime(windowTimeInMS uint32, slideTimeInMS
 	return CreateObservable(s.ctx, f, opts...)
 }
 
+// SlidingWindow emits the slice of items received in the last window time at every slide time, the items of
+// the last window are emitted when the stream completes.
+func (s *StreamImpl) SlidingWindow(windowTimeInMS uint32, slideTimeInMS uint32, opts ...rxgo.Option) Stream {
+	return s.slidingWindow(windowTimeInMS, slideTimeInMS, nil, opts...)
+}
+
+// SlidingWindowWithState is SlidingWindow of which the items in the window are resumed from the state and
+// checkpointed to it.
+func (s *StreamImpl) SlidingWindowWithState(windowTimeInMS uint32, slideTimeInMS uint32, state *State, opts ...rxgo.Option) Stream {
+	if state == nil || state.Store == nil {
+		return s.thrown(errors.New("the store of state must be set"))
+	}
+	return s.slidingWindow(windowTimeInMS, slideTimeInMS, state, opts...)
+}
+
+func (s *StreamImpl) slidingWindow(windowTimeInMS uint32, slideTimeInMS uint32, state *State, opts ...rxgo.Option) Stream {
+	if windowTimeInMS == 0 {
+		return s.thrown(errors.New("windowTimeInMS must be positive"))
+	}
+	if slideTimeInMS == 0 {
+		return s.thrown(errors.New("slideTimeInMS must be positive"))
+	}
+
+	f := func(ctx context.Context, next chan rxgo.Item) {
+		defer close(next)
+		observe := s.Observe()
+		window := time.Duration(windowTimeInMS) * time.Millisecond
+		ticker := time.NewTicker(time.Duration(slideTimeInMS) * time.Millisecond)
+		defer ticker.Stop()
+		buf := make([]slidingWithTimeItem, 0)
+
+		checkpoint := newCheckpointer(state)
+		defer checkpoint.stop()
+		// the items and their timestamps are saved in the state.
+		snapshot := func() map[string]interface{} {
+			items := make([]interface{}, len(buf))
+			timestamps := make([]time.Time, len(buf))
+			for i, item := range buf {
+				items[i], timestamps[i] = item.data, item.timestamp
+			}
+			return map[string]interface{}{"items": items, "timestamps": timestamps}
+		}
+		if state != nil {
+			values := state.load()
+			items, _ := values["items"].([]interface{})
+			timestamps, _ := values["timestamps"].([]time.Time)
+			for i := 0; i < len(items) && i < len(timestamps); i++ {
+				buf = append(buf, slidingWithTimeItem{timestamp: timestamps[i], data: items[i]})
+			}
+		}
+		defer func() {
+			if checkpoint.isSaving() {
+				checkpoint.flush(snapshot())
+			}
+		}()
+
+		// emit the items in the window which ends at now.
+		emit := func(now time.Time) bool {
+			start := now.Add(-window)
+			i := 0
+			for i < len(buf) && buf[i].timestamp.Before(start) {
+				i++
+			}
+			buf = buf[i:]
+			if i > 0 && checkpoint.isSaving() {
+				checkpoint.changed(snapshot())
+			}
+			if len(buf) == 0 {
+				return true
+			}
+
+			items := make([]interface{}, len(buf))
+			for i, item := range buf {
+				items[i] = item.data
+			}
+			return Of(items).SendContext(ctx, next)
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if !emit(now) {
+					return
+				}
+			case <-checkpoint.ticks():
+				checkpoint.flush(snapshot())
+			case item, ok := <-observe:
+				if !ok {
+					emit(time.Now())
+					return
+				}
+				if item.Error() {
+					continue
+				}
+				buf = append(buf, slidingWithTimeItem{
+					timestamp: time.Now(),
+					data:      item.V,
+				})
+				if checkpoint.isSaving() {
+					checkpoint.changed(snapshot())
+				}
+			}
+		}
+	}
+	return CreateObservable(s.ctx, f, opts...)
+}
+
+// SessionWindow emits the slice of items which are received without a gap longer than the gap time, the session
+// is closed when no item is received within the gap time or the stream completes.
+func (s *StreamImpl) SessionWindow(gapInMS uint32, opts ...rxgo.Option) Stream {
+	return s.sessionWindow(gapInMS, nil, opts...)
+}
+
+// SessionWindowWithState is SessionWindow of which the items of current session are resumed from the state and
+// checkpointed to it, the resumed session is closed if no item is received within the gap time after resuming.
+func (s *StreamImpl) SessionWindowWithState(gapInMS uint32, state *State, opts ...rxgo.Option) Stream {
+	if state == nil || state.Store == nil {
+		return s.thrown(errors.New("the store of state must be set"))
+	}
+	return s.sessionWindow(gapInMS, state, opts...)
+}
+
+func (s *StreamImpl) sessionWindow(gapInMS uint32, state *State, opts ...rxgo.Option) Stream {
+	if gapInMS == 0 {
+		return s.thrown(errors.New("gapInMS must be positive"))
+	}
+
+	f := func(ctx context.Context, next chan rxgo.Item) {
+		defer close(next)
+		observe := s.Observe()
+		gap := time.Duration(gapInMS) * time.Millisecond
+		timer := time.NewTimer(gap)
+		defer timer.Stop()
+		buf := make([]interface{}, 0)
+
+		checkpoint := newCheckpointer(state)
+		defer checkpoint.stop()
+		snapshot := func() map[string]interface{} {
+			return map[string]interface{}{"items": buf}
+		}
+		if state != nil {
+			if items, ok := state.load()["items"].([]interface{}); ok {
+				buf = items
+			}
+		}
+		defer func() {
+			if checkpoint.isSaving() {
+				checkpoint.flush(snapshot())
+			}
+		}()
+
+		// emit the items of current session.
+		emit := func() bool {
+			if len(buf) == 0 {
+				return true
+			}
+			items := buf
+			buf = make([]interface{}, 0)
+			if checkpoint.isSaving() {
+				checkpoint.changed(snapshot())
+			}
+			return Of(items).SendContext(ctx, next)
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+				if !emit() {
+					return
+				}
+			case <-checkpoint.ticks():
+				checkpoint.flush(snapshot())
+			case item, ok := <-observe:
+				if !ok {
+					emit()
+					return
+				}
+				if item.Error() {
+					continue
+				}
+				buf = append(buf, item.V)
+				if checkpoint.isSaving() {
+					checkpoint.changed(snapshot())
+				}
+
+				// the session is extended by the item.
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				timer.Reset(gap)
+			}
+		}
+	}
+	return CreateObservable(s.ctx, f, opts...)
+}
+
+// EventTimeWindow groups the items into the tumbling windows by the event time, and emits the windows in the order of
+// time when the watermark passes their ends. The items later than the watermark are dropped, and all open windows are
+// emitted when the stream completes.
+func (s *StreamImpl) EventTimeWindow(timeExtractor func(interface{}) time.Time, windowInMS uint32, allowedLatenessInMS uint32, opts ...rxgo.Option) Stream {
+	if windowInMS == 0 {
+		return s.thrown(errors.New("windowInMS must be positive"))
+	}
+
+	f := func(ctx context.Context, next chan rxgo.Item) {
+		defer close(next)
+		window := time.Duration(windowInMS) * time.Millisecond
+		lateness := time.Duration(allowedLatenessInMS) * time.Millisecond
+		// the open windows by their start.
+		windows := make(map[time.Time]*EventWindow)
+		var watermark time.Time
+
+		// emit the windows which end before the watermark, all windows are emitted if all is true.
+		emit := func(all bool) bool {
+			starts := make([]time.Time, 0)
+			for start, w := range windows {
+				if all || !w.End.After(watermark) {
+					starts = append(starts, start)
+				}
+			}
+			sort.Slice(starts, func(i, j int) bool {
+				return starts[i].Before(starts[j])
+			})
+			for _, start := range starts {
+				w := windows[start]
+				delete(windows, start)
+				if !Of(*w).SendContext(ctx, next) {
+					return false
+				}
+			}
+			return true
+		}
+
+		for item := range s.Observe() {
+			if item.Error() {
+				if !item.SendContext(ctx, next) {
+					return
+				}
+				continue
+			}
+
+			t := timeExtractor(item.V)
+			start := t.Truncate(window)
+			if !watermark.IsZero() && !start.Add(window).After(watermark) {
+				logger.Debug("[EventTimeWindow] drop the late item.", "time", t, "watermark", watermark)
+				continue
+			}
+
+			w, ok := windows[start]
+			if !ok {
+				w = &EventWindow{Start: start, End: start.Add(window)}
+				windows[start] = w
+			}
+			w.Items = append(w.Items, item.V)
+
+			// the watermark advances with the latest event time.
+			if mark := t.Add(-lateness); mark.After(watermark) {
+				watermark = mark
+				if !emit(false) {
+					return
+				}
+			}
+		}
+		emit(true)
+	}
+	return CreateObservable(s.ctx, f, opts...)
+}
+
 type slidingWithTimeItem struct {
 	timestamp time.Time
 	data      interface{}
@@ -1080,6 +2241,27 @@ func CreateObservable(ctx context.Context, f func(ctx context.Context, next chan
 	return &StreamImpl{ctx: ctx, observable: rxgo.FromChannel(next, opts...)}
 }
 
+// createLazyObservable creates a new observable which calls f when it's observed, so the source is not consumed
+// by the operator which is never observed.
+func createLazyObservable(ctx context.Context, f func(ctx context.Context, next chan rxgo.Item)) *StreamImpl {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	producer := func(_ context.Context, next chan<- rxgo.Item) {
+		ch := make(chan rxgo.Item)
+		go f(ctx, ch)
+		for item := range ch {
+			select {
+			case <-ctx.Done():
+				return
+			case next <- item:
+			}
+		}
+	}
+	opts := appendContinueOnError(ctx)
+	return &StreamImpl{ctx: ctx, observable: rxgo.Defer([]rxgo.Producer{producer}, opts...)}
+}
+
 // CreateZipperObservable creates a new observable with the capacity 100 for Zipper.
 func CreateZipperObservable(ctx context.Context, f func(ctx context.Context, next chan rxgo.Item), opts ...rxgo.Option) Stream {
 	next := make(chan rxgo.Item, 100)
@@ -4,12 +4,16 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/cenkalti/backoff/v4"
 	"github.com/reactivex/rxgo/v2"
 	"github.com/stretchr/testify/assert"
 	y3 "github.com/yomorun/y3-codec-golang"
+	"github.com/yomorun/yomo/core/serde"
+	"github.com/yomorun/yomo/internal/decoder"
 )
 
 // HELPER FUNCTIONS
@@ -282,6 +286,36 @@ func Test_Encode(t *testing.T) {
 	})
 }
 
+func Test_EncodeWith(t *testing.T) {
+	t.Run("json", func(t *testing.T) {
+		st := toStream(rxgo.Just(testStruct{ID: 1, Name: "foo"})()).EncodeWith(0x11, serde.JSON)
+		result, err := st.ToSlice(0)
+		assert.NoError(t, err)
+		assert.Equal(t, []interface{}{TaggedData{Tag: 0x11, Data: []byte(`{"ID":1,"Name":"foo"}`), ContentType: serde.JSON}}, result)
+	})
+
+	t.Run("unknown content type", func(t *testing.T) {
+		for item := range testStream.EncodeWith(0x11, "application/unknown").Observe() {
+			assert.ErrorIs(t, item.E, serde.ErrUnknownContentType)
+		}
+	})
+}
+
+func Test_Split(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	st := toStream(rxgo.Just("abc", "anomaly")()).Split(func(v interface{}) byte {
+		if v == "anomaly" {
+			return 0x20
+		}
+		return 0x11
+	})
+	rxgo.Assert(ctx, t, st, rxgo.HasItems(
+		TaggedData{Tag: 0x11, Data: []uint8{0x81, 0x5, 0x11, 0x3, 0x61, 0x62, 0x63}},
+		TaggedData{Tag: 0x20, Data: []uint8{0x81, 0x9, 0x20, 0x7, 0x61, 0x6e, 0x6f, 0x6d, 0x61, 0x6c, 0x79}},
+	))
+}
+
 func Test_SlidingWindowWithCount(t *testing.T) {
 	t.Run("window size = 1, slide size = 1, handler does nothing", func(t *testing.T) {
 		ctx, cancel := context.WithCancel(context.Background())
@@ -336,6 +370,567 @@ func Test_SlidingWindowWithTime(t *testing.T) {
 	})
 }
 
+// windows collects the windows emitted by the stream.
+func windows(st Stream) [][]interface{} {
+	result := make([][]interface{}, 0)
+	for item := range st.Observe() {
+		if item.Error() {
+			continue
+		}
+		result = append(result, item.V.([]interface{}))
+	}
+	return result
+}
+
+func Test_SlidingWindow(t *testing.T) {
+	t.Run("tumbling window, the last window is emitted on completion", func(t *testing.T) {
+		st := testStream.SlidingWindow(1000, 1000)
+		assert.Equal(t, [][]interface{}{{1, 2, 3}}, windows(st))
+	})
+
+	t.Run("window size = 1000ms, slide size = 150ms", func(t *testing.T) {
+		st := testStream.SlidingWindow(1000, 150)
+		result := windows(st)
+		assert.Equal(t, []interface{}{1, 2}, result[0])
+		assert.Equal(t, []interface{}{1, 2, 3}, result[len(result)-1])
+	})
+
+	t.Run("zero slide size", func(t *testing.T) {
+		for item := range testStream.SlidingWindow(1000, 0).Observe() {
+			assert.True(t, item.Error())
+		}
+	})
+}
+
+func Test_SessionWindow(t *testing.T) {
+	t.Run("gap = 50ms, every item is a session", func(t *testing.T) {
+		st := testStream.SessionWindow(50)
+		assert.Equal(t, [][]interface{}{{1}, {2}, {3}}, windows(st))
+	})
+
+	t.Run("gap = 500ms, all items are in a session", func(t *testing.T) {
+		st := testStream.SessionWindow(500)
+		assert.Equal(t, [][]interface{}{{1, 2, 3}}, windows(st))
+	})
+
+	t.Run("zero gap", func(t *testing.T) {
+		for item := range testStream.SessionWindow(0).Observe() {
+			assert.True(t, item.Error())
+		}
+	})
+}
+
+// parity is the key selector of odd and even numbers.
+func parity(i interface{}) string {
+	if i.(int)%2 == 0 {
+		return "even"
+	}
+	return "odd"
+}
+
+// sum adds the item to the accumulator.
+func sum(_ context.Context, acc interface{}, i interface{}) (interface{}, error) {
+	if acc == nil {
+		return i, nil
+	}
+	return acc.(int) + i.(int), nil
+}
+
+func Test_EventTimeWindow(t *testing.T) {
+	base := time.Unix(0, 0)
+	at := func(i interface{}) time.Time {
+		return base.Add(time.Duration(i.(int)) * time.Millisecond)
+	}
+
+	// 40 is delayed within the lateness, 30 is dropped after the window is emitted.
+	st := toStream(rxgo.Just(10, 60, 120, 40, 170, 30)()).EventTimeWindow(at, 100, 50)
+	result, err := st.ToSlice(0)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{
+		EventWindow{Start: at(0), End: at(100), Items: []interface{}{10, 60, 40}},
+		EventWindow{Start: at(100), End: at(200), Items: []interface{}{120, 170}},
+	}, result)
+}
+
+func Test_ScanByKey(t *testing.T) {
+	st := toStream(rxgo.Just(1, 2, 3, 4, 5)()).ScanByKey(parity, sum)
+	result := make([]KeyedItem, 0)
+	for item := range st.Observe() {
+		result = append(result, item.V.(KeyedItem))
+	}
+	assert.Equal(t, []KeyedItem{
+		{Key: "odd", Value: 1},
+		{Key: "even", Value: 2},
+		{Key: "odd", Value: 4},
+		{Key: "even", Value: 6},
+		{Key: "odd", Value: 9},
+	}, result)
+}
+
+func Test_ReduceByKey(t *testing.T) {
+	errFoo := errors.New("foo")
+	st := toStream(rxgo.Just(1, 2, 3, 4, 5)()).ReduceByKey(parity, func(ctx context.Context, acc interface{}, i interface{}) (interface{}, error) {
+		if i == 4 {
+			return nil, errFoo
+		}
+		return sum(ctx, acc, i)
+	})
+	result := make([]KeyedItem, 0)
+	for item := range st.Observe() {
+		if item.Error() {
+			assert.Equal(t, errFoo, item.E)
+			continue
+		}
+		result = append(result, item.V.(KeyedItem))
+	}
+	assert.Equal(t, []KeyedItem{{Key: "odd", Value: 9}, {Key: "even", Value: 2}}, result)
+}
+
+func Test_ScanByKeyWithState(t *testing.T) {
+	state := &State{Store: NewMemoryStateStore(), Name: "parity"}
+	st := toStream(rxgo.Just(1, 2, 3)()).ScanByKeyWithState(parity, sum, state)
+	_, err := st.ToSlice(0)
+	assert.NoError(t, err)
+
+	// the restarted stream resumes the sums.
+	st = toStream(rxgo.Just(4, 5)()).ScanByKeyWithState(parity, sum, state)
+	result, err := st.ToSlice(0)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{KeyedItem{Key: "even", Value: 6}, KeyedItem{Key: "odd", Value: 9}}, result)
+
+	st = toStream(rxgo.Just(6)()).ReduceByKeyWithState(parity, sum, state)
+	result, err = st.ToSlice(0)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{KeyedItem{Key: "even", Value: 12}, KeyedItem{Key: "odd", Value: 9}}, result)
+}
+
+func Test_ScanByKeyWithStateCheckpointInterval(t *testing.T) {
+	state := &State{Store: NewMemoryStateStore(), Name: "parity", CheckpointInMS: 50}
+	ch := make(chan rxgo.Item)
+	st := toStream(rxgo.FromChannel(ch)).ScanByKeyWithState(parity, sum, state)
+	go func() {
+		for range st.Observe() {
+		}
+	}()
+
+	ch <- rxgo.Of(1)
+	ch <- rxgo.Of(3)
+	// the latest sum is saved on the interval without the next item.
+	assert.Eventually(t, func() bool {
+		return state.load()["odd"] == 4
+	}, time.Second, 10*time.Millisecond)
+	close(ch)
+}
+
+func Test_WindowsWithState(t *testing.T) {
+	t.Run("sliding window", func(t *testing.T) {
+		state := &State{Store: NewMemoryStateStore(), Name: "sliding"}
+		ch := make(chan rxgo.Item)
+		st := toStream(rxgo.FromChannel(ch)).SlidingWindowWithState(60000, 60000, state)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for range st.Observe() {
+			}
+		}()
+		ch <- rxgo.Of(1)
+		ch <- rxgo.Of(2)
+		assert.Eventually(t, func() bool {
+			items, _ := state.load()["items"].([]interface{})
+			return len(items) == 2
+		}, time.Second, 10*time.Millisecond)
+
+		// the restarted window resumes the items.
+		result, err := toStream(rxgo.Just(3)()).SlidingWindowWithState(60000, 60000, state).ToSlice(0)
+		assert.NoError(t, err)
+		assert.Equal(t, []interface{}{[]interface{}{1, 2, 3}}, result)
+		close(ch)
+		<-done
+	})
+
+	t.Run("session window", func(t *testing.T) {
+		state := &State{Store: NewMemoryStateStore(), Name: "session"}
+		ch := make(chan rxgo.Item)
+		st := toStream(rxgo.FromChannel(ch)).SessionWindowWithState(60000, state)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for range st.Observe() {
+			}
+		}()
+		ch <- rxgo.Of("a")
+		assert.Eventually(t, func() bool {
+			items, _ := state.load()["items"].([]interface{})
+			return len(items) == 1
+		}, time.Second, 10*time.Millisecond)
+
+		result, err := toStream(rxgo.Just("b")()).SessionWindowWithState(60000, state).ToSlice(0)
+		assert.NoError(t, err)
+		assert.Equal(t, []interface{}{[]interface{}{"a", "b"}}, result)
+		close(ch)
+		<-done
+	})
+
+	t.Run("no store", func(t *testing.T) {
+		_, err := toStream(rxgo.Just(1)()).SessionWindowWithState(100, nil).ToSlice(0)
+		assert.Error(t, err)
+	})
+}
+
+func Test_JoinByKey(t *testing.T) {
+	observeFunc := func(v []byte) (interface{}, error) {
+		return v, nil
+	}
+	device := func(v interface{}) string {
+		return string(v.([]byte)[:1])
+	}
+
+	t.Run("join the values of the same device", func(t *testing.T) {
+		// 0x10 and 0x11 of device 1, and 0x11 of device 2.
+		items := []interface{}{[]byte{16, 2, 1, 9}, []byte{17, 2, 1, 8}, []byte{17, 2, 2, 7}}
+		st := NewFactory().FromItemsWithDecoder(items).
+			JoinByKey(KeyObserveFunc{Key: 0x10, OnObserve: observeFunc}, KeyObserveFunc{Key: 0x11, OnObserve: observeFunc}, device, 500)
+
+		result := make([]interface{}, 0)
+		for item := range st.Observe() {
+			result = append(result, item.V)
+		}
+		assert.Equal(t, []interface{}{[]interface{}{[]byte{1, 9}, []byte{1, 8}}}, result)
+	})
+
+	t.Run("the same keys", func(t *testing.T) {
+		st := testStream.JoinByKey(KeyObserveFunc{Key: 0x10}, KeyObserveFunc{Key: 0x10}, device, 500)
+		for item := range st.Observe() {
+			assert.True(t, item.Error())
+		}
+	})
+}
+
+func Test_MapParallel(t *testing.T) {
+	var running, peak int32
+	// the former items are slower.
+	apply := func(_ context.Context, i interface{}) (interface{}, error) {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(time.Duration(50*(5-i.(int))) * time.Millisecond)
+		return i.(int) * 10, nil
+	}
+	collect := func(st Stream) []interface{} {
+		result := make([]interface{}, 0)
+		for item := range st.Observe() {
+			result = append(result, item.V)
+		}
+		return result
+	}
+
+	t.Run("ordered", func(t *testing.T) {
+		atomic.StoreInt32(&peak, 0)
+		st := toStream(rxgo.Just(1, 2, 3, 4)()).MapParallel(apply, 2, true)
+		assert.Equal(t, []interface{}{10, 20, 30, 40}, collect(st))
+		assert.Equal(t, int32(2), atomic.LoadInt32(&peak))
+	})
+
+	t.Run("unordered", func(t *testing.T) {
+		atomic.StoreInt32(&peak, 0)
+		st := toStream(rxgo.Just(1, 2, 3, 4)()).MapParallel(apply, 4, false)
+		assert.Equal(t, []interface{}{40, 30, 20, 10}, collect(st))
+		assert.Equal(t, int32(4), atomic.LoadInt32(&peak))
+	})
+
+	t.Run("zero concurrency", func(t *testing.T) {
+		for item := range testStream.MapParallel(apply, 0, true).Observe() {
+			assert.True(t, item.Error())
+		}
+	})
+}
+
+func Test_MapWithRetry(t *testing.T) {
+	errFoo := errors.New("foo")
+	var calls int32
+	// the function succeeds at the third call of every item.
+	apply := func(_ context.Context, i interface{}) (interface{}, error) {
+		if atomic.AddInt32(&calls, 1)%3 != 0 {
+			return nil, errFoo
+		}
+		return i, nil
+	}
+
+	t.Run("succeeded after retries", func(t *testing.T) {
+		atomic.StoreInt32(&calls, 0)
+		st := toStream(rxgo.Just(1, 2)()).MapWithRetry(apply, 2, backoff.NewConstantBackOff(time.Millisecond))
+		result, err := st.ToSlice(0)
+		assert.NoError(t, err)
+		assert.Equal(t, []interface{}{1, 2}, result)
+		assert.Equal(t, int32(6), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("failed after retries", func(t *testing.T) {
+		atomic.StoreInt32(&calls, 0)
+		st := toStream(rxgo.Just(1)()).MapWithRetry(apply, 1, nil)
+		for item := range st.Observe() {
+			assert.Equal(t, errFoo, item.E)
+		}
+		assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	})
+}
+
+func Test_FlatMapSlice(t *testing.T) {
+	errFoo := errors.New("foo")
+	apply := func(_ context.Context, i interface{}) ([]interface{}, error) {
+		n := i.(int)
+		if n < 0 {
+			return nil, errFoo
+		}
+		values := make([]interface{}, n)
+		for j := range values {
+			values[j] = n
+		}
+		return values, nil
+	}
+	st := toStream(rxgo.Just(2, 0, -1, 1)()).FlatMapSlice(apply)
+	result := make([]rxgo.Item, 0)
+	for item := range st.Observe() {
+		result = append(result, item)
+	}
+	assert.Equal(t, []rxgo.Item{Of(2), Of(2), rxgo.Error(errFoo), Of(1)}, result)
+}
+
+func Test_MapWithTimeout(t *testing.T) {
+	apply := func(ctx context.Context, i interface{}) (interface{}, error) {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Duration(i.(int)) * time.Millisecond):
+			return i, nil
+		}
+	}
+	st := toStream(rxgo.Just(10, 500, 20)()).MapWithTimeout(apply, 100)
+	result := make([]rxgo.Item, 0)
+	for item := range st.Observe() {
+		result = append(result, item)
+	}
+	assert.Equal(t, []rxgo.Item{Of(10), rxgo.Error(ErrTimeout), Of(20)}, result)
+}
+
+func Test_OnErrorResumeWith(t *testing.T) {
+	errFoo := errors.New("foo")
+	errBar := errors.New("bar")
+	st := toStream(rxgo.Just(1, errFoo, errBar)()).OnErrorResumeWith(func(_ context.Context, err error) (interface{}, error) {
+		if err == errBar {
+			return nil, err
+		}
+		return 0, nil
+	})
+	result := make([]rxgo.Item, 0)
+	for item := range st.Observe() {
+		result = append(result, item)
+	}
+	assert.Equal(t, []rxgo.Item{Of(1), Of(0), rxgo.Error(errBar)}, result)
+}
+
+func Test_ThrottleFirst(t *testing.T) {
+	result, err := testStream.ThrottleFirst(150).ToSlice(0)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{1, 3}, result)
+}
+
+func Test_SampleWithTime(t *testing.T) {
+	result, err := testStream.SampleWithTime(150).ToSlice(0)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{2, 3}, result)
+}
+
+func Test_Also(t *testing.T) {
+	observeFunc := func(v []byte) (interface{}, error) {
+		return v, nil
+	}
+
+	t.Run("observe two keys", func(t *testing.T) {
+		items := []interface{}{[]byte{16, 1, 1, 17, 1, 9, 18, 1, 2}}
+		st := NewFactory().FromItemsWithDecoder(items).
+			Subscribe(0x10).
+			OnObserve(observeFunc).
+			Also(0x12, func(v []byte) (interface{}, error) {
+				return append(v, 0), nil
+			})
+		result, err := st.ToSlice(0)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []interface{}{[]byte{1}, []byte{2, 0}}, result)
+	})
+
+	t.Run("the key is observed already", func(t *testing.T) {
+		st := NewFactory().FromItemsWithDecoder(nil).Subscribe(0x10).OnObserve(observeFunc).Also(0x10, observeFunc)
+		for item := range st.Observe() {
+			assert.True(t, item.Error())
+		}
+	})
+
+	t.Run("not follow OnObserve", func(t *testing.T) {
+		for item := range testStream.Also(0x10, observeFunc).Observe() {
+			assert.True(t, item.Error())
+		}
+	})
+}
+
+func Test_OnObserveWithContext(t *testing.T) {
+	type ctxKey struct{}
+	observeFunc := func(ctx context.Context, v []byte) (interface{}, error) {
+		return ctx.Value(ctxKey{}), nil
+	}
+	items := []interface{}{[]byte{0x10, 1, 1}}
+
+	t.Run("the context of stream is passed", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), ctxKey{}, "foo")
+		st := NewFactory().FromItemsWithDecoder(items, decoder.WithContext(ctx)).
+			Subscribe(0x10).
+			OnObserveWithContext(observeFunc)
+		result, err := st.ToSlice(0)
+		assert.NoError(t, err)
+		assert.Equal(t, []interface{}{"foo"}, result)
+	})
+
+	t.Run("the context is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		st := NewFactory().FromItemsWithDecoder(items, decoder.WithContext(ctx)).
+			Subscribe(0x10).
+			OnObserveWithContext(func(ctx context.Context, v []byte) (interface{}, error) {
+				cancel()
+				<-ctx.Done()
+				return nil, ctx.Err()
+			})
+		for item := range st.Observe() {
+			assert.ErrorIs(t, item.E, context.Canceled)
+		}
+	})
+}
+
+func Test_OnError(t *testing.T) {
+	errFoo := errors.New("foo")
+	// the odd numbers are invalid.
+	observeFunc := func(v []byte) (interface{}, error) {
+		if v[0]%2 == 1 {
+			return nil, errFoo
+		}
+		return v[0], nil
+	}
+	newStream := func(values ...byte) Stream {
+		items := make([]interface{}, len(values))
+		for i, v := range values {
+			items[i] = []byte{0x10, 1, v}
+		}
+		return NewFactory().FromItemsWithDecoder(items).Subscribe(0x10).OnObserve(observeFunc)
+	}
+
+	t.Run("skip", func(t *testing.T) {
+		raws := make([][]byte, 0)
+		var errs uint64
+		st := newStream(1, 2, 3).OnError(func(err error, raw []byte) {
+			assert.ErrorIs(t, err, errFoo)
+			raws = append(raws, raw)
+		}, SkipOnError.WithCounter(&errs))
+		result, err := st.ToSlice(0)
+		assert.NoError(t, err)
+		assert.Equal(t, []interface{}{byte(2)}, result)
+		assert.ElementsMatch(t, [][]byte{{1}, {3}}, raws)
+		assert.Equal(t, uint64(2), atomic.LoadUint64(&errs))
+	})
+
+	t.Run("skipped without OnError", func(t *testing.T) {
+		// the errors don't end the pipeline which doesn't handle them.
+		result, err := newStream(1, 2, 3).ToSlice(0)
+		assert.NoError(t, err)
+		assert.Equal(t, []interface{}{byte(2)}, result)
+	})
+
+	t.Run("stop", func(t *testing.T) {
+		st := newStream(1).OnError(nil, StopOnError)
+		for item := range st.Observe() {
+			assert.ErrorIs(t, item.E, errFoo)
+		}
+	})
+
+	t.Run("route", func(t *testing.T) {
+		st := newStream(3).OnError(nil, RouteOnError(0x20))
+		result, err := st.ToSlice(0)
+		assert.NoError(t, err)
+		assert.Equal(t, []interface{}{TaggedData{Tag: 0x20, Data: []byte{3}}}, result)
+	})
+}
+
+func Test_Transform(t *testing.T) {
+	// dedupe drops the items which are the same as the previous one.
+	dedupe := func(ctx context.Context, in <-chan rxgo.Item) <-chan rxgo.Item {
+		out := make(chan rxgo.Item)
+		go func() {
+			defer close(out)
+			var last interface{}
+			for item := range in {
+				if item.Error() || item.V != last {
+					last = item.V
+					if !item.SendContext(ctx, out) {
+						return
+					}
+				}
+			}
+		}()
+		return out
+	}
+
+	st := toStream(rxgo.Just(1, 1, 2, 2, 1)()).Transform(dedupe).Filter(func(i interface{}) bool {
+		return i != 2
+	})
+	result, err := st.ToSlice(0)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{1, 1}, result)
+}
+
+func Test_DistinctWithTTL(t *testing.T) {
+	// the readings of the same value are resent every 100ms.
+	readings := toStream(rxgo.Defer([]rxgo.Producer{func(_ context.Context, ch chan<- rxgo.Item) {
+		for _, v := range []int{1, 1, 2, 1, 1} {
+			ch <- rxgo.Of(v)
+			time.Sleep(100 * time.Millisecond)
+		}
+	}}))
+	st := readings.DistinctWithTTL(func(_ context.Context, i interface{}) (interface{}, error) {
+		return i, nil
+	}, 250)
+	result, err := st.ToSlice(0)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{1, 2, 1}, result)
+
+	// the keys of []byte and the other keys which can't be compared are compared by their content.
+	payloads := toStream(rxgo.Defer([]rxgo.Producer{func(_ context.Context, ch chan<- rxgo.Item) {
+		for _, v := range []interface{}{[]byte("a"), []byte("a"), []byte("b")} {
+			ch <- rxgo.Of(v)
+		}
+	}}))
+	st = payloads.DistinctWithTTL(func(_ context.Context, i interface{}) (interface{}, error) {
+		return i, nil
+	}, 1000)
+	result, err = st.ToSlice(0)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{[]byte("a"), []byte("b")}, result)
+
+	readingsByID := toStream(rxgo.Defer([]rxgo.Producer{func(_ context.Context, ch chan<- rxgo.Item) {
+		for _, v := range []interface{}{[]int{1, 2}, []int{1, 2}, map[string]int{"a": 1}} {
+			ch <- rxgo.Of(v)
+		}
+	}}))
+	st = readingsByID.DistinctWithTTL(func(_ context.Context, i interface{}) (interface{}, error) {
+		return i, nil
+	}, 1000)
+	result, err = st.ToSlice(0)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{[]int{1, 2}, map[string]int{"a": 1}}, result)
+}
+
 func Test_ContinueOnError(t *testing.T) {
 	t.Run("ContinueOnError on a single operator by default", func(t *testing.T) {
 		ctx, cancel := context.WithCancel(context.Background())
@@ -0,0 +1,4 @@
+// Package serde manages the serializers of the carriage of DataFrames by the content type.
+// The content type and the schema ID are carried in the MetaFrame, so the stream functions decode the carriage
+// generically by Unmarshal instead of parsing the bytes by hand.
+package serde
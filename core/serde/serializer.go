@@ -0,0 +1,227 @@
+package serde
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/vmihailenco/msgpack/v5"
+	y3 "github.com/yomorun/y3-codec-golang"
+)
+
+// The content types of the built-in serializers.
+const (
+	// Raw is the carriage of bytes or string as is.
+	Raw = "application/octet-stream"
+	// JSON is the carriage encoded by encoding/json.
+	JSON = "application/json"
+	// Y3 is the carriage encoded by the Y3 codec.
+	Y3 = "application/y3"
+	// Protobuf is the carriage encoded by the Protocol Buffers.
+	Protobuf = "application/protobuf"
+	// MsgPack is the carriage encoded by the MessagePack.
+	MsgPack = "application/msgpack"
+	// CBOR is reserved for the CBOR serializer, it's registered by the application.
+	CBOR = "application/cbor"
+)
+
+// ErrUnknownContentType is returned when the content type has no registered serializer.
+var ErrUnknownContentType = errors.New("serde: unknown content type")
+
+// Serializer marshals and unmarshals the carriage of DataFrames.
+type Serializer interface {
+	// ContentType is the identity of serializer in the MetaFrame, e.g. "application/json".
+	ContentType() string
+	// Marshal the value to bytes.
+	Marshal(v interface{}) ([]byte, error)
+	// Unmarshal the bytes into the value which v points to.
+	Unmarshal(data []byte, v interface{}) error
+}
+
+var serializers = sync.Map{}
+
+func init() {
+	Register(rawSerializer{})
+	Register(NewSerializer(JSON, json.Marshal, json.Unmarshal))
+	Register(y3Serializer{})
+	Register(protobufSerializer{})
+	Register(NewSerializer(MsgPack, msgpack.Marshal, msgpack.Unmarshal))
+}
+
+// Register a serializer, the serializer with the same content type is replaced, e.g. the CBOR serializer:
+//
+//	serde.Register(serde.NewSerializer(serde.CBOR, cbor.Marshal, cbor.Unmarshal))
+func Register(s Serializer) {
+	serializers.Store(s.ContentType(), s)
+}
+
+// Lookup returns the serializer by the content type, the Raw serializer is returned if it's empty.
+func Lookup(contentType string) (Serializer, bool) {
+	if contentType == "" {
+		contentType = Raw
+	}
+	v, ok := serializers.Load(contentType)
+	if !ok {
+		return nil, false
+	}
+	return v.(Serializer), true
+}
+
+// Marshal the value by the serializer of content type.
+func Marshal(contentType string, v interface{}) ([]byte, error) {
+	s, ok := Lookup(contentType)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownContentType, contentType)
+	}
+	return s.Marshal(v)
+}
+
+// Unmarshal the data into the value which v points to by the serializer of content type.
+func Unmarshal(contentType string, data []byte, v interface{}) error {
+	s, ok := Lookup(contentType)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownContentType, contentType)
+	}
+	return s.Unmarshal(data, v)
+}
+
+// NewSerializer creates a serializer by the marshal and unmarshal functions, it's the simplest way to plug in
+// a third-party library, the functions follow the signatures of encoding/json.
+func NewSerializer(contentType string, marshal func(v interface{}) ([]byte, error), unmarshal func(data []byte, v interface{}) error) Serializer {
+	return &funcSerializer{contentType: contentType, marshal: marshal, unmarshal: unmarshal}
+}
+
+type funcSerializer struct {
+	contentType string
+	marshal     func(v interface{}) ([]byte, error)
+	unmarshal   func(data []byte, v interface{}) error
+}
+
+func (s *funcSerializer) ContentType() string {
+	return s.contentType
+}
+
+func (s *funcSerializer) Marshal(v interface{}) ([]byte, error) {
+	return s.marshal(v)
+}
+
+func (s *funcSerializer) Unmarshal(data []byte, v interface{}) error {
+	return s.unmarshal(data, v)
+}
+
+// rawSerializer passes the bytes or string through.
+type rawSerializer struct{}
+
+func (rawSerializer) ContentType() string {
+	return Raw
+}
+
+func (rawSerializer) Marshal(v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	default:
+		return nil, fmt.Errorf("serde: %T can't be marshaled as raw bytes", v)
+	}
+}
+
+func (rawSerializer) Unmarshal(data []byte, v interface{}) error {
+	switch v := v.(type) {
+	case *[]byte:
+		*v = data
+	case *string:
+		*v = string(data)
+	default:
+		return fmt.Errorf("serde: raw bytes can't be unmarshaled into %T", v)
+	}
+	return nil
+}
+
+// y3Key is the key of the value encoded by the Y3 codec, it's the data tag of the sources.
+const y3Key = 0x10
+
+// y3Serializer encodes the value by the Y3 codec.
+type y3Serializer struct{}
+
+func (y3Serializer) ContentType() string {
+	return Y3
+}
+
+func (y3Serializer) Marshal(v interface{}) ([]byte, error) {
+	return y3.NewCodec(y3Key).Marshal(v)
+}
+
+func (y3Serializer) Unmarshal(data []byte, v interface{}) error {
+	return y3.ToObject(data, v)
+}
+
+// protoMessage is the message generated by the Protocol Buffers compilers with the Marshal and Unmarshal methods,
+// e.g. gogo/protobuf. The messages of google.golang.org/protobuf can be supported by registering a serializer
+// of proto.Marshal and proto.Unmarshal with the Protobuf content type.
+type protoMessage interface {
+	Marshal() ([]byte, error)
+	Unmarshal(data []byte) error
+}
+
+// protobufSerializer encodes the messages which marshal themselves.
+type protobufSerializer struct{}
+
+func (protobufSerializer) ContentType() string {
+	return Protobuf
+}
+
+func (protobufSerializer) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(protoMessage)
+	if !ok {
+		return nil, fmt.Errorf("serde: %T isn't a protobuf message", v)
+	}
+	return m.Marshal()
+}
+
+func (protobufSerializer) Unmarshal(data []byte, v interface{}) error {
+	m, ok := v.(protoMessage)
+	if !ok {
+		return fmt.Errorf("serde: %T isn't a protobuf message", v)
+	}
+	return m.Unmarshal(data)
+}
+
+type contextKey struct{}
+
+// responseKey is the key of the content type and the schema ID of the responses in the context.
+type responseKey struct{}
+
+// metadata is the content type and the schema ID of the carriage.
+type metadata struct {
+	contentType string
+	schemaID    string
+}
+
+// NewContext returns a copy of ctx which carries the content type and the schema ID of the carriage,
+// the stream functions pass it to the handler.
+func NewContext(ctx context.Context, contentType string, schemaID string) context.Context {
+	return context.WithValue(ctx, contextKey{}, metadata{contentType: contentType, schemaID: schemaID})
+}
+
+// FromContext returns the content type and the schema ID of the carriage in ctx, they're empty if not set.
+func FromContext(ctx context.Context) (contentType string, schemaID string) {
+	m, _ := ctx.Value(contextKey{}).(metadata)
+	return m.contentType, m.schemaID
+}
+
+// NewResponseContext returns a copy of ctx which carries the content type and the schema ID of the responses,
+// the stream functions pass it to the handler so the responses are marshaled as they're declared.
+func NewResponseContext(ctx context.Context, contentType string, schemaID string) context.Context {
+	return context.WithValue(ctx, responseKey{}, metadata{contentType: contentType, schemaID: schemaID})
+}
+
+// ResponseFromContext returns the content type and the schema ID of the responses in ctx, the responses are
+// raw bytes if they're empty.
+func ResponseFromContext(ctx context.Context) (contentType string, schemaID string) {
+	m, _ := ctx.Value(responseKey{}).(metadata)
+	return m.contentType, m.schemaID
+}
@@ -0,0 +1,64 @@
+package serde
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type noise struct {
+	Noise float32 `json:"noise" y3:"0x11" msgpack:"noise"`
+	From  string  `json:"from" y3:"0x12" msgpack:"from"`
+}
+
+func TestMarshalAndUnmarshal(t *testing.T) {
+	for _, contentType := range []string{JSON, Y3, MsgPack} {
+		buf, err := Marshal(contentType, noise{Noise: 42, From: "sensor"})
+		assert.NoError(t, err)
+
+		var v noise
+		assert.NoError(t, Unmarshal(contentType, buf, &v))
+		assert.Equal(t, noise{Noise: 42, From: "sensor"}, v)
+	}
+}
+
+func TestRawSerializer(t *testing.T) {
+	// the carriage is raw bytes if the content type isn't set.
+	buf, err := Marshal("", "hello")
+	assert.NoError(t, err)
+
+	var s string
+	assert.NoError(t, Unmarshal("", buf, &s))
+	assert.Equal(t, "hello", s)
+
+	_, err = Marshal(Raw, noise{})
+	assert.Error(t, err)
+}
+
+func TestUnknownContentType(t *testing.T) {
+	var v noise
+	assert.ErrorIs(t, Unmarshal(CBOR, []byte{0xa0}, &v), ErrUnknownContentType)
+
+	Register(NewSerializer(CBOR, func(v interface{}) ([]byte, error) { return []byte{0xa0}, nil }, func(data []byte, v interface{}) error { return nil }))
+	defer serializers.Delete(CBOR)
+	assert.NoError(t, Unmarshal(CBOR, []byte{0xa0}, &v))
+}
+
+func TestContext(t *testing.T) {
+	contentType, schemaID := FromContext(context.Background())
+	assert.Empty(t, contentType)
+	assert.Empty(t, schemaID)
+
+	ctx := NewContext(context.Background(), JSON, "noise-v1")
+	contentType, schemaID = FromContext(ctx)
+	assert.Equal(t, JSON, contentType)
+	assert.Equal(t, "noise-v1", schemaID)
+
+	// the content type of the responses is carried apart from the carriage.
+	ctx = NewResponseContext(ctx, Raw, "")
+	contentType, _ = FromContext(ctx)
+	assert.Equal(t, JSON, contentType)
+	contentType, _ = ResponseFromContext(ctx)
+	assert.Equal(t, Raw, contentType)
+}
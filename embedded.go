@@ -0,0 +1,155 @@
+package yomo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/yomorun/yomo/core/rx"
+	"github.com/yomorun/yomo/internal/decoder"
+	"github.com/yomorun/yomo/logger"
+	"github.com/yomorun/yomo/source"
+	"github.com/yomorun/yomo/streamfunction"
+	"github.com/yomorun/yomo/zipper"
+)
+
+// responseTag is the tag of the []byte results of handlers, the same as the stream functions connected over QUIC.
+const responseTag byte = 0x13
+
+// Embedded runs YoMo-Zipper, the sources and the stream functions in one process without QUIC, the data is passed
+// in memory from the sources through the handlers in order to the sinks, e.g. for the unit tests, the local
+// development and the small edge gateways where the network hops are unnecessary.
+//
+//	e := yomo.NewEmbedded().
+//		Pipe("noise", Handler, 0x33).
+//		Sink(func(tag byte, data []byte) { ... })
+//	if err := e.Start(); err != nil { ... }
+//	defer e.Close()
+//	e.Source("sensor").WriteWithTags(data, 0x33)
+type Embedded struct {
+	conf     zipper.WorkflowConfig
+	opts     []zipper.Option
+	sinks    []func(tag byte, data []byte)
+	embedded *zipper.Embedded
+}
+
+// EmbeddedSource writes the data of a source to the embedded YoMo-Zipper.
+type EmbeddedSource struct {
+	name     string
+	embedded *Embedded
+}
+
+// NewEmbedded creates the embedded YoMo-Zipper, the handlers and the sinks are registered before it's started.
+func NewEmbedded() *Embedded {
+	return &Embedded{conf: zipper.WorkflowConfig{Name: "embedded"}}
+}
+
+// Pipe appends the stream function of name which runs the handler of rx.Stream, it only receives the data with
+// the tags if they are specified. The []byte results of handler are observed by the tag 0x13 as the stream
+// functions connected over QUIC, and the results of rx.TaggedData by their own tags.
+func (e *Embedded) Pipe(name string, handler func(rxstream rx.Stream) rx.Stream, tags ...byte) *Embedded {
+	e.conf.Functions = append(e.conf.Functions, zipper.App{Name: name, Tags: tags})
+	e.opts = append(e.opts, zipper.WithLocalHandler(name, rxLocalHandler(name, handler)))
+	return e
+}
+
+// PipeFunc appends the stream function of name which runs the simple handler on the data of the observe tag, the
+// response is observed by the respond tag, and nothing is passed on if the response is nil.
+func (e *Embedded) PipeFunc(name string, observe byte, respond byte, handler streamfunction.Handler) *Embedded {
+	e.conf.Functions = append(e.conf.Functions, zipper.App{Name: name, Tags: []byte{observe}})
+	e.opts = append(e.opts, zipper.WithLocalHandler(name, func(ctx context.Context, _ byte, data []byte) ([]zipper.LocalResult, error) {
+		buf, err := handler(ctx, data)
+		if err != nil || buf == nil {
+			return nil, err
+		}
+		return []zipper.LocalResult{{Tag: respond, Data: buf}}, nil
+	}))
+	return e
+}
+
+// Sink receives the data which has passed all stream functions.
+func (e *Embedded) Sink(fn func(tag byte, data []byte)) *Embedded {
+	e.sinks = append(e.sinks, fn)
+	return e
+}
+
+// Start starts the embedded YoMo-Zipper, the data can be written by the sources after it's started.
+func (e *Embedded) Start() error {
+	if e.embedded != nil {
+		return errors.New("yomo: the embedded YoMo-Zipper is started")
+	}
+	sinks := e.sinks
+	opts := append(append([]zipper.Option(nil), e.opts...), zipper.WithOutputHandler(func(tag byte, data []byte) {
+		for _, sink := range sinks {
+			sink(tag, data)
+		}
+	}))
+	embedded, err := zipper.NewEmbedded(&e.conf, opts...)
+	if err != nil {
+		return err
+	}
+	e.embedded = embedded
+	return nil
+}
+
+// Source returns the source of name which writes the data to the embedded YoMo-Zipper.
+func (e *Embedded) Source(name string) *EmbeddedSource {
+	return &EmbeddedSource{name: name, embedded: e}
+}
+
+// Close stops the embedded YoMo-Zipper, the data in flight is discarded.
+func (e *Embedded) Close() error {
+	if e.embedded == nil {
+		return nil
+	}
+	return e.embedded.Close()
+}
+
+// Write writes the data with the default data tag of sources.
+func (s *EmbeddedSource) Write(data []byte) (int, error) {
+	return s.WriteWithTags(data, source.DefaultDataTag)
+}
+
+// WriteWithTags writes the data observed by the data tags, the first tag is the tag of payload.
+func (s *EmbeddedSource) WriteWithTags(data []byte, tags ...byte) (int, error) {
+	if s.embedded.embedded == nil {
+		return 0, errors.New("yomo: the embedded YoMo-Zipper is not started")
+	}
+	if err := s.embedded.embedded.Write(s.name, data, tags...); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// rxLocalHandler adapts the handler of rx.Stream to the local handler of YoMo-Zipper, the handler runs on each
+// data in its own stream as the stream functions do.
+func rxLocalHandler(name string, handler func(rxstream rx.Stream) rx.Stream) zipper.LocalHandler {
+	fac := rx.NewFactory()
+	return func(ctx context.Context, tag byte, data []byte) ([]zipper.LocalResult, error) {
+		rxstream := fac.FromItemsWithDecoder([]interface{}{data}, decoder.WithContext(ctx), decoder.WithTags(tag))
+
+		results := make([]zipper.LocalResult, 0)
+		for item := range rxstream.Observe() {
+			if item.Error() {
+				return nil, item.E
+			}
+
+			for result := range handler(fac.FromItems(ctx, []interface{}{item.V})).Observe() {
+				if result.Error() {
+					logger.Error("[embedded] the handler got the error.", "stream-fn", name, "err", result.E)
+					continue
+				}
+				switch v := result.V.(type) {
+				case []byte:
+					results = append(results, zipper.LocalResult{Tag: responseTag, Data: v})
+				case rx.TaggedData:
+					results = append(results, zipper.LocalResult{Tag: v.Tag, Data: v.Data})
+				default:
+					logger.Debug("[embedded] the result is not a []byte in RxStream, won't pass it on.", "stream-fn", name)
+				}
+			}
+			// one data per time.
+			break
+		}
+		return results, nil
+	}
+}
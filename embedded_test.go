@@ -0,0 +1,59 @@
+package yomo
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/core/rx"
+)
+
+func TestEmbedded(t *testing.T) {
+	outputs := make(chan string, 10)
+	e := NewEmbedded().
+		Pipe("embedded-split", func(rxstream rx.Stream) rx.Stream {
+			return rxstream.RawBytes().FlatMapSlice(func(_ context.Context, i interface{}) ([]interface{}, error) {
+				words := make([]interface{}, 0)
+				for _, word := range strings.Fields(string(i.([]byte))) {
+					words = append(words, rx.TaggedData{Tag: 0x34, Data: []byte(word)})
+				}
+				return words, nil
+			})
+		}, 0x33).
+		PipeFunc("embedded-upper", 0x34, 0x35, func(_ context.Context, data []byte) ([]byte, error) {
+			if string(data) == "drop" {
+				return nil, nil
+			}
+			return []byte(strings.ToUpper(string(data))), nil
+		}).
+		Sink(func(tag byte, data []byte) {
+			outputs <- string([]byte{tag}) + string(data)
+		})
+
+	_, err := e.Source("sensor").Write([]byte("a"))
+	assert.EqualError(t, err, "yomo: the embedded YoMo-Zipper is not started")
+	assert.NoError(t, e.Start())
+	defer e.Close()
+	assert.EqualError(t, e.Start(), "yomo: the embedded YoMo-Zipper is started")
+
+	n, err := e.Source("sensor").WriteWithTags([]byte("hello drop yomo"), 0x33)
+	assert.NoError(t, err)
+	assert.Equal(t, 15, n)
+	received := make([]string, 0)
+	for len(received) < 2 {
+		select {
+		case output := <-outputs:
+			received = append(received, output)
+		case <-time.After(time.Second):
+			t.Fatal("the output of embedded YoMo-Zipper isn't received", received)
+		}
+	}
+	assert.Equal(t, []string{"\x35HELLO", "\x35YOMO"}, received)
+
+	// the data of other tags passes through the stream functions.
+	_, err = e.Source("sensor").Write([]byte("raw"))
+	assert.NoError(t, err)
+	assert.Equal(t, "\x10raw", <-outputs)
+}
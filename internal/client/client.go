@@ -2,11 +2,15 @@ package client
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"net"
+	"sync/atomic"
 	"time"
 
+	"github.com/cenkalti/backoff/v4"
+	"github.com/yomorun/yomo/core/compress"
 	"github.com/yomorun/yomo/core/quic"
 	"github.com/yomorun/yomo/internal/core"
 	"github.com/yomorun/yomo/internal/frame"
@@ -26,6 +30,66 @@ type Client interface {
 
 	// EnableDebug enables the development model for logging.
 	EnableDebug()
+
+	// Connected indicates if the client is connected to YoMo-Zipper and the connection is accepted.
+	Connected() bool
+}
+
+// ErrRejected is returned by Connect when the connection is rejected by YoMo-Zipper, the error of rejection is a
+// *RejectedError which wraps it.
+var ErrRejected = errors.New("the connection is rejected by YoMo-Zipper")
+
+// ErrUnauthenticated is returned by Connect when YoMo-Zipper rejects the token of client, it wraps ErrRejected.
+var ErrUnauthenticated = fmt.Errorf("%w: the credential is missing or invalid", ErrRejected)
+
+// ErrUnauthorized is returned by Connect when the client is not allowed to connect as its name by the policies of
+// YoMo-Zipper, it wraps ErrRejected.
+var ErrUnauthorized = fmt.Errorf("%w: the client is not authorized", ErrRejected)
+
+// ErrQuotaExceeded is returned by Connect when the max connections of the tenant of client are reached, it wraps
+// ErrRejected. The client keeps reconnecting after it, since the connections may be closed later.
+var ErrQuotaExceeded = fmt.Errorf("%w: the quota is exceeded", ErrRejected)
+
+// QuotaExceeded tells the data written by the client is dropped because the quota of its tenant is exceeded.
+type QuotaExceeded struct {
+	// TransactionID is the transaction ID of the dropped data.
+	TransactionID string
+	// Quota is the name of exceeded quota, e.g. "frames_per_second" or "bytes_per_day".
+	Quota string
+	// RetryAfter is when the quota is available again, it's 0 if YoMo-Zipper doesn't tell it.
+	RetryAfter time.Duration
+}
+
+// RejectedError is the rejection of YoMo-Zipper with its reason.
+type RejectedError struct {
+	// Code is the typed reason of rejection.
+	Code frame.RejectCode
+	// Message is the reason of rejection, it's empty if YoMo-Zipper doesn't tell it.
+	Message string
+}
+
+// Error returns the reason of rejection.
+func (e *RejectedError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%v (%v)", ErrRejected, e.Code)
+	}
+	return fmt.Sprintf("%v (%v): %s", ErrRejected, e.Code, e.Message)
+}
+
+// Is reports if the rejection is ErrRejected, or ErrUnauthenticated, ErrUnauthorized and ErrQuotaExceeded of the
+// typed reasons.
+func (e *RejectedError) Is(target error) bool {
+	switch target {
+	case ErrRejected:
+		return true
+	case ErrUnauthenticated:
+		return e.Code == frame.RejectUnauthenticated
+	case ErrUnauthorized:
+		return e.Code == frame.RejectUnauthorized
+	case ErrQuotaExceeded:
+		return e.Code == frame.RejectQuotaExceeded
+	}
+	return false
 }
 
 // Impl is the implementation of Client interface.
@@ -36,12 +100,51 @@ type Impl struct {
 	Session    quic.Client
 	Stream     *core.FrameStream // Stream is the stream to receive actual data from source.
 	isRejected bool
+	rejection  error // rejection is the reason why the connection is rejected by YoMo-Zipper.
+	tlsConfig  *tls.Config
+	earlyData  bool
+	tcp        bool                  // tcp enables the TCP fallback when QUIC dial fails.
+	keepAlive  time.Duration         // keepAlive is the interval of sending Ping to YoMo-Zipper.
+	idle       time.Duration         // idle is the timeout when no Pong or packet is received from YoMo-Zipper.
+	resumption *quic.ResumptionStore // resumption keeps the session tickets between reconnections.
+	flow       quic.FlowControl      // flow is the flow control windows of the connection.
+	proxy      string                // proxy is the URL of proxy to YoMo-Zipper.
+	qlog       string                // qlog is the directory of qlog traces, the connection is not traced if it's empty.
+	mux        *quic.Mux             // mux shares the QUIC session with other clients, it's nil if the session is not shared.
+	codecs     []string              // codecs are the names of compression codecs offered in the handshake.
+	threshold  int                   // threshold is the size above which the carriage is compressed.
+	codec      compress.Codec        // codec is the compression codec negotiated with YoMo-Zipper, it's nil if not compressed.
+	version    uint32                // version is the version of wire protocol negotiated with YoMo-Zipper.
+	token      string                // token is the credential sent in the handshake, it's empty if not authenticated by token.
+
+	retryInitial time.Duration // retryInitial is the initial interval of reconnecting to YoMo-Zipper.
+	retryMax     time.Duration // retryMax is the max interval of reconnecting to YoMo-Zipper.
+	onReconnect  func()        // onReconnect is called after the client reconnected to YoMo-Zipper.
+
+	onQuota func(QuotaExceeded) // onQuota is called when the data written by the client is dropped by the quota.
+
+	sessionCtx   atomic.Value  // sessionCtx is the context.Context of the current session, it's cancelled when the session is closed.
+	inflight     int64         // inflight is the count of the writes and the handlers which are not done.
+	drainTimeout time.Duration // drainTimeout is the max time to wait for the in-flight writes when closing.
 }
 
+const (
+	// defaultRetryInitial is the default initial interval of reconnecting.
+	defaultRetryInitial = time.Second
+	// defaultRetryMax is the default max interval of reconnecting.
+	defaultRetryMax = 30 * time.Second
+	// defaultDrainTimeout is the default max time to wait for the in-flight writes when closing.
+	defaultDrainTimeout = 5 * time.Second
+	// drainInterval is the interval of checking whether the in-flight writes are done.
+	drainInterval = 10 * time.Millisecond
+)
+
 // New creates a new client.
 func New(appName string, clientType core.ConnectionType) *Impl {
 	c := &Impl{
-		conn: quic.NewConn(appName, clientType),
+		conn:         quic.NewConn(appName, clientType),
+		resumption:   quic.NewResumptionStore(),
+		drainTimeout: defaultDrainTimeout,
 	}
 
 	c.conn.OnHeartbeatExpired = func() {
@@ -69,16 +172,159 @@ func New(appName string, clientType core.ConnectionType) *Impl {
 	return c
 }
 
-// BaseConnect connects to YoMo-Zipper.
-// TODO: login auth
+// SetTLSConfig sets the TLS config for connecting to YoMo-Zipper, the client certificate is presented for mutual TLS.
+func (c *Impl) SetTLSConfig(conf *tls.Config) {
+	c.tlsConfig = conf
+}
+
+// Set0RTT enables the 0-RTT session resumption, the client sends data before the QUIC handshake completes
+// when it reconnects to YoMo-Zipper.
+func (c *Impl) Set0RTT(enabled bool) {
+	c.earlyData = enabled
+}
+
+// SetTCPFallback enables the TLS over TCP fallback when the QUIC dial fails, e.g. UDP is blocked by the network.
+func (c *Impl) SetTCPFallback(enabled bool) {
+	c.tcp = enabled
+}
+
+// SetKeepAlive sets the interval of sending Ping and the idle timeout of the connection to YoMo-Zipper,
+// the client reconnects when no Pong is received within the idle timeout. The defaults are used if they're zero.
+func (c *Impl) SetKeepAlive(interval time.Duration, idleTimeout time.Duration) {
+	c.keepAlive = interval
+	c.idle = idleTimeout
+	c.conn.HeartbeatTimeout = idleTimeout
+}
+
+// SetFlowControl sets the flow control windows of the connection to YoMo-Zipper.
+func (c *Impl) SetFlowControl(fc quic.FlowControl) {
+	c.flow = fc
+}
+
+// SetProxy sets the URL of proxy to YoMo-Zipper, e.g. "socks5://proxy:1080".
+func (c *Impl) SetProxy(proxy string) {
+	c.proxy = proxy
+}
+
+// SetQlog writes the qlog traces of the connections to YoMo-Zipper to the directory.
+func (c *Impl) SetQlog(dir string) {
+	c.qlog = dir
+}
+
+// SetReconnectBackoff sets the initial and max intervals of reconnecting to YoMo-Zipper, the interval grows
+// exponentially with a random jitter after each failed attempt. The defaults are used if they're zero.
+func (c *Impl) SetReconnectBackoff(initial time.Duration, max time.Duration) {
+	c.retryInitial = initial
+	c.retryMax = max
+}
+
+// SetOnReconnect sets the callback which is called after the client reconnected to YoMo-Zipper, e.g. to flush
+// the data buffered during the disconnection. The name and type of client are registered again in the handshake,
+// so YoMo-Zipper resumes dispatching the subscribed tags without the callback.
+func (c *Impl) SetOnReconnect(fn func()) {
+	c.onReconnect = fn
+}
+
+// SetOnQuotaExceeded sets the callback which is called when the data written by the client is dropped because the
+// quota of its tenant is exceeded, it's logged if the callback is not set.
+func (c *Impl) SetOnQuotaExceeded(fn func(QuotaExceeded)) {
+	c.onQuota = fn
+}
+
+// SetMux shares the QUIC session of mux with other clients in the process, the client connects over a logical channel.
+// The QUIC options of the client are ignored because the session is dialed by mux.
+func (c *Impl) SetMux(mux *quic.Mux) {
+	c.mux = mux
+}
+
+// SetToken sets the credential which is sent in the handshake to authenticate the client.
+func (c *Impl) SetToken(token string) {
+	c.token = token
+}
+
+// SetCompression offers the compression codecs to YoMo-Zipper in the handshake, the carriage larger than threshold
+// is compressed by the negotiated codec. All registered codecs are offered if codecs is empty, and the
+// compress.DefaultThreshold is used if threshold is zero.
+func (c *Impl) SetCompression(codecs []string, threshold int) {
+	if len(codecs) == 0 {
+		codecs = compress.Names()
+	}
+	c.codecs = codecs
+	c.threshold = threshold
+	if c.threshold <= 0 {
+		c.threshold = compress.DefaultThreshold
+	}
+}
+
+// CompressFrame compresses the carriage of frame by the codec negotiated with YoMo-Zipper.
+func (c *Impl) CompressFrame(f *frame.DataFrame) (*frame.DataFrame, error) {
+	return core.CompressFrame(f, c.codec, c.threshold)
+}
+
+// DecompressFrame decompresses the carriage of frame in place, the frame must be compressed by the codec
+// negotiated with YoMo-Zipper.
+func (c *Impl) DecompressFrame(f *frame.DataFrame) error {
+	return core.DecompressFrame(f, c.codec, core.DefaultMaxFrameSize)
+}
+
+// SendControl sends the control frame to YoMo-Zipper on the signal stream.
+func (c *Impl) SendControl(f *frame.ControlFrame) error {
+	if !c.Supports(f.Type()) {
+		return frame.ErrUnsupportedVersion
+	}
+	return c.conn.SendSignal(f)
+}
+
+// Downgrade returns the frame in the layout of the version negotiated with YoMo-Zipper.
+func (c *Impl) Downgrade(f *frame.DataFrame) (*frame.DataFrame, error) {
+	return frame.Downgrade(f, c.Version())
+}
+
+// Supports reports if the frame type is understood by YoMo-Zipper.
+func (c *Impl) Supports(t frame.FrameType) bool {
+	return frame.Supports(t, c.Version())
+}
+
+// Version returns the version of wire protocol negotiated with YoMo-Zipper, it's 0 before the handshake.
+func (c *Impl) Version() uint32 {
+	return atomic.LoadUint32(&c.version)
+}
+
+// BaseConnect connects to YoMo-Zipper, the error wraps ErrRejected if the connection is rejected in the handshake.
 func (c *Impl) BaseConnect(ip string, port int) (*Impl, error) {
+	c.isRejected, c.rejection = false, nil
 	c.serverIP = ip
 	c.serverPort = port
 	addr := getServerAddr(c.serverIP, c.serverPort)
 	logger.Printf("Connecting to YoMo-Zipper %s...", addr)
 
 	// connect to YoMo-Zipper
-	client, err := quic.NewClient(addr)
+	opts := []quic.Option{quic.WithTLSConfig(c.tlsConfig), quic.WithResumptionStore(c.resumption)}
+	if c.earlyData {
+		opts = append(opts, quic.With0RTT())
+	}
+	if c.tcp {
+		opts = append(opts, quic.WithTCPFallback())
+	}
+	if c.idle > 0 {
+		opts = append(opts, quic.WithIdleTimeout(c.idle))
+	}
+	if c.flow != (quic.FlowControl{}) {
+		opts = append(opts, quic.WithFlowControl(c.flow))
+	}
+	if c.proxy != "" {
+		opts = append(opts, quic.WithProxy(c.proxy))
+	}
+	if c.qlog != "" {
+		opts = append(opts, quic.WithQlog(c.qlog))
+	}
+	var client quic.Client
+	var err error
+	if c.mux != nil {
+		client, err = c.mux.Dial(addr)
+	} else {
+		client, err = quic.NewClient(addr, opts...)
+	}
 	if err != nil {
 		logger.Error("[client] quic.NewClient Error:", "err", err)
 		return c, err
@@ -93,10 +339,16 @@ func (c *Impl) BaseConnect(ip string, port int) (*Impl, error) {
 
 	// set session and signal
 	c.Session = client
+	if s, ok := client.(sessionContext); ok {
+		c.sessionCtx.Store(s.Context())
+	}
 	c.conn.Signal = core.NewFrameStream(stream)
 
 	// handshake frame
 	handshakeFrame := frame.NewHandshakeFrame(c.conn.Name, byte(c.conn.Type))
+	handshakeFrame.Codecs = c.codecs
+	handshakeFrame.Version = frame.Version
+	handshakeFrame.Token = c.token
 	logger.Debug(fmt.Sprintf("[HandshakeFrame] name=%s, type=%s ", handshakeFrame.Name, handshakeFrame.Type()))
 	c.conn.Signal.WriteFrame(handshakeFrame)
 
@@ -105,13 +357,11 @@ func (c *Impl) BaseConnect(ip string, port int) (*Impl, error) {
 	c.handleSignal(accepted)
 
 	// waiting when the connection is accepted.
-	<-accepted
-
-	if c.isRejected {
+	if !<-accepted {
 		logger.Printf("❌ The connection to YoMo-Zipper %s was rejected.", addr)
-	} else {
-		logger.Printf("✅ Connected to YoMo-Zipper %s.", addr)
+		return c, c.rejection
 	}
+	logger.Printf("✅ Connected to YoMo-Zipper %s.", addr)
 
 	// send ping to zipper.
 	c.ping()
@@ -151,6 +401,24 @@ func (c *Impl) handleSignal(accepted chan bool) {
 				c.conn.Heartbeat <- true
 
 			case frame.TagOfAcceptedFrame:
+				acceptedFrame := f.(*frame.AcceptedFrame)
+				version, err := frame.CheckAcceptedVersion(acceptedFrame.Version)
+				if err != nil {
+					logger.Error("[client] ❌ the protocol version of zipper is incompatible, please upgrade YoMo-Zipper.", "err", err)
+					c.Close()
+					c.isRejected = true
+					c.rejection = &RejectedError{Code: frame.RejectVersion, Message: err.Error()}
+					accepted <- false
+					break LOOP
+				}
+				atomic.StoreUint32(&c.version, version)
+
+				c.codec = nil
+				if name := acceptedFrame.Codec; name != "" {
+					c.codec, _ = compress.LookupName(name)
+					logger.Debug("[client] the compression codec is negotiated.", "codec", name)
+				}
+
 				// create stream
 				if c.conn.Type == core.ConnTypeSource || c.conn.Type == core.ConnTypeUpstreamZipper {
 					stream, err := c.Session.CreateStream(context.Background())
@@ -164,15 +432,35 @@ func (c *Impl) handleSignal(accepted chan bool) {
 				accepted <- true
 
 			case frame.TagOfRejectedFrame:
-				if c.conn.Type == core.ConnTypeStreamFunction {
+				rejected := f.(*frame.RejectedFrame)
+				if rejected.Code == frame.RejectUnauthenticated {
+					logger.Error("[client] ❌ the connection was rejected by zipper, please check the token of client.", "reason", rejected.Message)
+				} else if rejected.Code == frame.RejectUnauthorized {
+					logger.Error("[client] ❌ the connection was rejected by zipper, please check the policies of the client's identity.", "reason", rejected.Message)
+				} else if rejected.Code == frame.RejectQuotaExceeded {
+					logger.Error("[client] ❌ the connection was rejected by zipper, the max connections of the tenant are reached.", "reason", rejected.Message)
+				} else if message := rejected.Message; message != "" {
+					logger.Error("[client] ❌ the connection was rejected by zipper.", "reason", message)
+				} else if c.conn.Type == core.ConnTypeStreamFunction {
 					logger.Error("[client] ❌ the connection was rejected by zipper, please check if the function name matches the one in zipper config.")
 				} else {
 					logger.Error("[client] ❌ the connection was rejected by zipper.")
 				}
 				c.Close()
 				c.isRejected = true
+				c.rejection = &RejectedError{Code: rejected.Code, Message: rejected.Message}
+				accepted <- false
 				break LOOP
 
+			case frame.TagOfQuotaFrame:
+				quota := f.(*frame.QuotaFrame)
+				exceeded := QuotaExceeded{TransactionID: quota.TransactionID, Quota: quota.Quota, RetryAfter: quota.RetryAfter}
+				if c.onQuota != nil {
+					c.onQuota(exceeded)
+				} else {
+					logger.Error("[client] ❌ the data was dropped by zipper, the quota is exceeded.", "TransactionID", quota.TransactionID, "quota", quota.Quota, "retry_after", quota.RetryAfter)
+				}
+
 			default:
 				logger.Debug("[client] unknown signal.", "frame", logger.BytesString(f.Encode()))
 			}
@@ -180,10 +468,15 @@ func (c *Impl) handleSignal(accepted chan bool) {
 	}()
 }
 
-// Ping sends the PingFrame to YoMo-Zipper in every 3s.
+// Ping sends the PingFrame to YoMo-Zipper in every 3s by default.
 func (c *Impl) ping() {
+	interval := c.keepAlive
+	if interval <= 0 {
+		interval = quic.HeartbeatInterval
+	}
+
 	go func(c *Impl) {
-		t := time.NewTicker(3 * time.Second)
+		t := time.NewTicker(interval)
 		for {
 			select {
 			case <-t.C:
@@ -207,34 +500,124 @@ func (c *Impl) ping() {
 
 // Retry the connection between client and server.
 func (c *Impl) Retry() {
+	b := c.newBackOff()
 	for {
 		logger.Debug("[client] retry to connect the YoMo-Zipper...", "addr", getServerAddr(c.serverIP, c.serverPort))
 		_, err := c.BaseConnect(c.serverIP, c.serverPort)
 		if err == nil {
+			c.reconnected()
+			break
+		}
+		if errors.Is(err, ErrRejected) && !errors.Is(err, ErrQuotaExceeded) {
+			// the rejected client won't be accepted by retrying, e.g. its token is revoked.
+			logger.Error("[client] stop reconnecting to YoMo-Zipper.", "err", err)
 			break
 		}
 
-		time.Sleep(3 * time.Second)
+		time.Sleep(b.NextBackOff())
 	}
 }
 
 // RetryWithCount the connection with a certain count.
 func (c *Impl) RetryWithCount(count int) bool {
+	b := c.newBackOff()
 	for i := 0; i < count; i++ {
 		logger.Debug("[client] retry to connect the YoMo-Zipper with count...", "addr", getServerAddr(c.serverIP, c.serverPort), "count", count)
 		_, err := c.BaseConnect(c.serverIP, c.serverPort)
 		if err == nil {
+			c.reconnected()
 			return true
 		}
+		if errors.Is(err, ErrRejected) && !errors.Is(err, ErrQuotaExceeded) {
+			return false
+		}
 
-		time.Sleep(3 * time.Second)
+		time.Sleep(b.NextBackOff())
 	}
 	return false
 }
 
-// Close the client.
+// newBackOff creates the jittered exponential backoff of reconnecting, it never stops.
+func (c *Impl) newBackOff() backoff.BackOff {
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = defaultRetryInitial
+	if c.retryInitial > 0 {
+		b.InitialInterval = c.retryInitial
+	}
+	b.MaxInterval = defaultRetryMax
+	if c.retryMax > 0 {
+		b.MaxInterval = c.retryMax
+	}
+	b.MaxElapsedTime = 0
+	b.Reset()
+	return b
+}
+
+// reconnected calls the callback of reconnection if the connection is accepted.
+func (c *Impl) reconnected() {
+	if c.isRejected || c.onReconnect == nil {
+		return
+	}
+	c.onReconnect()
+}
+
+// sessionContext is implemented by the QUIC clients which expose the context of their session.
+type sessionContext interface {
+	Context() context.Context
+}
+
+// SessionContext returns the context of the current session, it's cancelled when the session is closed. It's nil
+// if the client isn't connected or the session doesn't expose its context.
+func (c *Impl) SessionContext() context.Context {
+	ctx, _ := c.sessionCtx.Load().(context.Context)
+	return ctx
+}
+
+// Connected indicates if the client is connected to YoMo-Zipper and the connection is accepted.
+func (c *Impl) Connected() bool {
+	if c.Session == nil || c.isRejected {
+		return false
+	}
+	if ctx := c.SessionContext(); ctx != nil && ctx.Err() != nil {
+		return false
+	}
+	return true
+}
+
+// Track marks a write or a handler in flight, the returned function must be called when it's done.
+// Close waits for the in-flight ones before saying goodbye to YoMo-Zipper.
+func (c *Impl) Track() func() {
+	atomic.AddInt64(&c.inflight, 1)
+	return func() {
+		atomic.AddInt64(&c.inflight, -1)
+	}
+}
+
+// drain waits until the in-flight writes and handlers are done or the timeout is exceeded, it returns false
+// if they're not done.
+func (c *Impl) drain(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for atomic.LoadInt64(&c.inflight) > 0 {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(drainInterval)
+	}
+	return true
+}
+
+// Close the client, the in-flight writes are drained before the session is closed.
 func (c *Impl) Close() error {
 	logger.Debug("[client] close the connection to YoMo-Zipper.")
+	if !c.drain(c.drainTimeout) {
+		logger.Printf("The in-flight writes are not done in %v, close the connection anyway.", c.drainTimeout)
+	}
+	if c.conn.Signal != nil && c.Supports(frame.TagOfGoodbyeFrame) {
+		// YoMo-Zipper removes the client from the dispatch pool at once, and ignores the streams after it.
+		if err := c.conn.SendSignal(frame.NewGoodbyeFrame("closed by client")); err != nil {
+			logger.Debug("[client] send the goodbye frame failed.", "err", err)
+		}
+	}
 	if c.Session != nil {
 		err := c.Session.Close()
 		if err != nil {
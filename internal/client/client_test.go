@@ -0,0 +1,86 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/internal/core"
+	"github.com/yomorun/yomo/internal/frame"
+)
+
+func TestReconnectBackoff(t *testing.T) {
+	c := New("test", core.ConnTypeSource)
+	c.SetReconnectBackoff(100*time.Millisecond, time.Second)
+
+	b := c.newBackOff()
+	prev := time.Duration(0)
+	for i := 0; i < 20; i++ {
+		d := b.NextBackOff()
+		assert.True(t, d > 0 && d <= time.Second+time.Second/2, "the interval %v should be capped with jitter", d)
+		if i == 0 {
+			assert.True(t, d <= 150*time.Millisecond)
+		}
+		prev = d
+	}
+	assert.True(t, prev >= time.Second/2)
+}
+
+func TestOnReconnect(t *testing.T) {
+	c := New("test", core.ConnTypeSource)
+	called := 0
+	c.SetOnReconnect(func() { called++ })
+
+	c.reconnected()
+	assert.Equal(t, 1, called)
+
+	c.isRejected = true
+	c.reconnected()
+	assert.Equal(t, 1, called)
+}
+
+func TestDrain(t *testing.T) {
+	c := New("test", core.ConnTypeSource)
+	assert.True(t, c.drain(0))
+
+	done := c.Track()
+	assert.False(t, c.drain(50*time.Millisecond))
+
+	time.AfterFunc(50*time.Millisecond, done)
+	assert.True(t, c.drain(time.Second))
+}
+
+func TestSessionContext(t *testing.T) {
+	c := New("test", core.ConnTypeStreamFunction)
+	assert.Nil(t, c.SessionContext())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.sessionCtx.Store(ctx)
+	assert.Equal(t, ctx, c.SessionContext())
+	cancel()
+	assert.Error(t, c.SessionContext().Err())
+}
+
+func TestRejectedError(t *testing.T) {
+	var err error = &RejectedError{Code: frame.RejectUnauthenticated, Message: "the token is expired"}
+	assert.True(t, errors.Is(err, ErrRejected))
+	assert.True(t, errors.Is(err, ErrUnauthenticated))
+	assert.EqualError(t, err, "the connection is rejected by YoMo-Zipper (unauthenticated): the token is expired")
+
+	err = &RejectedError{Code: frame.RejectNotAllowed}
+	assert.True(t, errors.Is(err, ErrRejected))
+	assert.False(t, errors.Is(err, ErrUnauthenticated))
+	assert.EqualError(t, err, "the connection is rejected by YoMo-Zipper (not allowed)")
+
+	err = &RejectedError{Code: frame.RejectUnauthorized, Message: "the client is not authorized"}
+	assert.True(t, errors.Is(err, ErrUnauthorized))
+	assert.False(t, errors.Is(err, ErrUnauthenticated))
+	assert.EqualError(t, err, "the connection is rejected by YoMo-Zipper (unauthorized): the client is not authorized")
+
+	err = &RejectedError{Code: frame.RejectQuotaExceeded}
+	assert.True(t, errors.Is(err, ErrQuotaExceeded))
+	assert.False(t, errors.Is(err, ErrUnauthorized))
+	assert.EqualError(t, err, "the connection is rejected by YoMo-Zipper (quota exceeded)")
+}
@@ -0,0 +1,86 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/yomorun/yomo/internal/frame"
+)
+
+const (
+	// DefaultChunkSize is the default max size of each chunk in bytes.
+	DefaultChunkSize = 1 << 20
+	// DefaultMaxFrameSize is the default max size of a reassembled DataFrame in bytes.
+	DefaultMaxFrameSize = 64 << 20
+)
+
+// ErrFrameTooLarge is returned when the frame or the reassembled DataFrame exceeds the max size.
+var ErrFrameTooLarge = errors.New("the frame exceeds the max size")
+
+// SplitFrame splits the encoded DataFrame into ChunkFrames if it's larger than chunkSize,
+// otherwise the DataFrame itself is returned.
+func SplitFrame(f *frame.DataFrame, chunkSize int) []frame.Frame {
+	buf := f.Encode()
+	if chunkSize <= 0 || len(buf) <= chunkSize {
+		return []frame.Frame{f}
+	}
+
+	total := (len(buf) + chunkSize - 1) / chunkSize
+	chunks := make([]frame.Frame, 0, total)
+	for i := 0; i < total; i++ {
+		end := (i + 1) * chunkSize
+		if end > len(buf) {
+			end = len(buf)
+		}
+		chunks = append(chunks, frame.NewChunkFrame(f.TransactionID(), uint32(i), uint32(total), buf[i*chunkSize:end]))
+	}
+	return chunks
+}
+
+// Reassembler reassembles the ChunkFrames received on a stream to the DataFrame.
+type Reassembler struct {
+	maxSize int
+	id      string
+	next    uint32
+	total   uint32
+	buf     []byte
+}
+
+// NewReassembler creates a Reassembler, the DataFrame larger than maxSize is dropped.
+// The DefaultMaxFrameSize is used if maxSize is zero.
+func NewReassembler(maxSize int) *Reassembler {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxFrameSize
+	}
+	return &Reassembler{maxSize: maxSize}
+}
+
+// Push a chunk, the DataFrame is returned after its last chunk is pushed, otherwise nil is returned.
+// The incomplete DataFrame is dropped when a chunk is missing or the max size is exceeded.
+func (r *Reassembler) Push(c *frame.ChunkFrame) (*frame.DataFrame, error) {
+	if c.Index == 0 {
+		r.id, r.next, r.total, r.buf = c.ID, 0, c.Total, r.buf[:0]
+	}
+	if c.ID != r.id || c.Index != r.next || c.Total != r.total {
+		r.reset()
+		return nil, fmt.Errorf("the chunk %d/%d of frame %s is out of order", c.Index, c.Total, c.ID)
+	}
+	if len(r.buf)+len(c.Data) > r.maxSize {
+		r.reset()
+		return nil, ErrFrameTooLarge
+	}
+
+	r.buf = append(r.buf, c.Data...)
+	r.next++
+	if r.next < r.total {
+		return nil, nil
+	}
+
+	buf := r.buf
+	r.reset()
+	return frame.DecodeToDataFrame(buf)
+}
+
+func (r *Reassembler) reset() {
+	r.id, r.next, r.total, r.buf = "", 0, 0, nil
+}
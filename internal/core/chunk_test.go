@@ -0,0 +1,64 @@
+package core
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/internal/frame"
+)
+
+func TestSplitAndReassembleFrame(t *testing.T) {
+	f := frame.NewDataFrame("1234")
+	f.SetCarriage(0x10, bytes.Repeat([]byte("yomo"), 1024))
+
+	// the small frame is not split.
+	assert.Equal(t, []frame.Frame{f}, SplitFrame(f, len(f.Encode())))
+
+	chunks := SplitFrame(f, 1000)
+	assert.Len(t, chunks, 5)
+
+	r := NewReassembler(0)
+	for i, c := range chunks {
+		received, err := ParseFrame(bytes.NewReader(c.Encode()))
+		assert.NoError(t, err)
+		data, err := r.Push(received.(*frame.ChunkFrame))
+		assert.NoError(t, err)
+		if i < len(chunks)-1 {
+			assert.Nil(t, data)
+			continue
+		}
+		assert.Equal(t, "1234", data.TransactionID())
+		assert.Equal(t, f.GetCarriage(), data.GetCarriage())
+	}
+}
+
+func TestReassembleInvalidChunks(t *testing.T) {
+	f := frame.NewDataFrame("1234")
+	f.SetCarriage(0x10, bytes.Repeat([]byte("yomo"), 1024))
+	chunks := SplitFrame(f, 1000)
+
+	// the frame is dropped if a chunk is missing.
+	r := NewReassembler(0)
+	_, err := r.Push(chunks[0].(*frame.ChunkFrame))
+	assert.NoError(t, err)
+	_, err = r.Push(chunks[2].(*frame.ChunkFrame))
+	assert.Error(t, err)
+
+	// the next frame is reassembled after the dropped one.
+	var data *frame.DataFrame
+	for _, c := range chunks {
+		data, err = r.Push(c.(*frame.ChunkFrame))
+		assert.NoError(t, err)
+	}
+	assert.NotNil(t, data)
+
+	// the frame larger than the max size is dropped.
+	r = NewReassembler(2000)
+	for _, c := range chunks {
+		if _, err = r.Push(c.(*frame.ChunkFrame)); err != nil {
+			break
+		}
+	}
+	assert.Equal(t, ErrFrameTooLarge, err)
+}
@@ -0,0 +1,101 @@
+package core
+
+import (
+	"errors"
+	"io"
+
+	"github.com/yomorun/yomo/core/compress"
+	"github.com/yomorun/yomo/internal/frame"
+)
+
+// ErrCompressionNotNegotiated is returned when the carriage is compressed by a codec which isn't negotiated.
+var ErrCompressionNotNegotiated = errors.New("the compression is not negotiated")
+
+// CompressFrame returns the frame whose carriage is compressed by codec if the carriage is larger than threshold,
+// the original frame is returned if it's not compressed or the compressed carriage isn't smaller.
+// The original frame is not modified, so it can be shared by several receivers. The encrypted carriage is not
+// compressed because it's incompressible, it should be compressed before the encryption. The streamed carriage
+// is compressed as it's read if the codec implements compress.StreamCodec, whatever its size.
+func CompressFrame(f *frame.DataFrame, codec compress.Codec, threshold int) (*frame.DataFrame, error) {
+	if codec == nil || f.Compression() != compress.None || f.KeyID() != "" {
+		return f, nil
+	}
+	if f.Streamed() {
+		return compressStreamedFrame(f, codec), nil
+	}
+	if len(f.GetCarriage()) <= threshold {
+		return f, nil
+	}
+
+	buf, err := codec.Compress(f.GetCarriage())
+	if err != nil {
+		return nil, err
+	}
+	if len(buf) >= len(f.GetCarriage()) {
+		return f, nil
+	}
+
+	compressed := f.Clone()
+	compressed.SetCarriage(f.GetDataTagID(), buf)
+	compressed.SetCompression(codec.ID())
+	return compressed, nil
+}
+
+// DecompressFrame decompresses the carriage of the frame in place if it's compressed, the encrypted carriage
+// is left as is, it's decompressed by DecryptFrame of the receiver which holds the key.
+// The carriage must be compressed by the codec negotiated with the peer, which is nil if no codec is negotiated,
+// and the decompressed carriage is up to maxSize, DefaultMaxFrameSize is used if maxSize is zero. The streamed
+// carriage is decompressed as it's read, it's not bounded since it's not held in memory.
+func DecompressFrame(f *frame.DataFrame, codec compress.Codec, maxSize int) error {
+	id := f.Compression()
+	if id == compress.None || f.KeyID() != "" {
+		return nil
+	}
+
+	if codec == nil || codec.ID() != id {
+		return ErrCompressionNotNegotiated
+	}
+	if f.Streamed() {
+		return decompressStreamedFrame(f, codec)
+	}
+	if maxSize <= 0 {
+		maxSize = DefaultMaxFrameSize
+	}
+	buf, err := compress.Decompress(codec, f.GetCarriage(), maxSize)
+	if err != nil {
+		return err
+	}
+
+	f.SetCarriage(f.GetDataTagID(), buf)
+	f.SetCompression(compress.None)
+	return nil
+}
+
+func compressStreamedFrame(f *frame.DataFrame, codec compress.Codec) *frame.DataFrame {
+	sc, ok := codec.(compress.StreamCodec)
+	if !ok {
+		return f
+	}
+
+	compressed := f.Clone()
+	compressed.SetCarriageReader(f.GetDataTagID(), pipeCarriage(f.CarriageReader(), func(w io.Writer) (io.WriteCloser, error) {
+		return sc.NewWriter(w), nil
+	}))
+	compressed.SetCompression(codec.ID())
+	return compressed
+}
+
+func decompressStreamedFrame(f *frame.DataFrame, codec compress.Codec) error {
+	sc, ok := codec.(compress.StreamCodec)
+	if !ok {
+		return ErrCompressionNotNegotiated
+	}
+
+	r, err := sc.NewReader(f.CarriageReader())
+	if err != nil {
+		return err
+	}
+	f.SetCarriageReader(f.GetDataTagID(), &carriageReader{Reader: r, closer: r, underlying: f.CarriageReader()})
+	f.SetCompression(compress.None)
+	return nil
+}
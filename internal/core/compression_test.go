@@ -0,0 +1,58 @@
+package core
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/core/compress"
+	"github.com/yomorun/yomo/internal/frame"
+)
+
+func TestCompressFrame(t *testing.T) {
+	codec, _ := compress.Lookup(compress.Gzip)
+	data := bytes.Repeat([]byte(`{"noise":42}`), 100)
+	f := frame.NewDataFrame("1234")
+	f.SetCarriage(0x10, data)
+
+	// the small carriage is not compressed.
+	small, err := CompressFrame(f, codec, len(data))
+	assert.NoError(t, err)
+	assert.Equal(t, f, small)
+
+	compressed, err := CompressFrame(f, codec, compress.DefaultThreshold)
+	assert.NoError(t, err)
+	assert.Equal(t, compress.Gzip, compressed.Compression())
+	assert.Less(t, len(compressed.GetCarriage()), len(data))
+	// the original frame is not modified.
+	assert.Equal(t, data, f.GetCarriage())
+
+	received, err := frame.DecodeToDataFrame(compressed.Encode())
+	assert.NoError(t, err)
+	assert.NoError(t, DecompressFrame(received, codec, 0))
+	assert.Equal(t, compress.None, received.Compression())
+	assert.Equal(t, byte(0x10), received.GetDataTagID())
+	assert.Equal(t, data, received.GetCarriage())
+}
+
+func TestDecompressFrameNotNegotiated(t *testing.T) {
+	codec, _ := compress.Lookup(compress.Gzip)
+	f := frame.NewDataFrame("1234")
+	f.SetCarriage(0x10, bytes.Repeat([]byte("a"), 2048))
+	compressed, err := CompressFrame(f, codec, compress.DefaultThreshold)
+	assert.NoError(t, err)
+
+	assert.Equal(t, ErrCompressionNotNegotiated, DecompressFrame(compressed, nil, 0))
+}
+
+func TestDecompressFrameTooLarge(t *testing.T) {
+	codec, _ := compress.Lookup(compress.Gzip)
+	f := frame.NewDataFrame("1234")
+	f.SetCarriage(0x10, make([]byte, 1<<20))
+	compressed, err := CompressFrame(f, codec, compress.DefaultThreshold)
+	assert.NoError(t, err)
+	// a bomb of 1MB zeros is compressed to about 1KB.
+	assert.Less(t, len(compressed.GetCarriage()), 4096)
+
+	assert.Equal(t, compress.ErrTooLarge, DecompressFrame(compressed, codec, 1024))
+}
@@ -0,0 +1,89 @@
+package core
+
+import (
+	"errors"
+	"io"
+
+	"github.com/yomorun/yomo/core/compress"
+	"github.com/yomorun/yomo/core/kms"
+	"github.com/yomorun/yomo/internal/frame"
+)
+
+// ErrNotEncrypted is returned when the carriage isn't encrypted but the receiver holds a keyring, the plaintext data
+// is rejected since it could be injected or forged by any YoMo-Zipper in the path.
+var ErrNotEncrypted = errors.New("the carriage is not encrypted")
+
+// EncryptFrame encrypts the carriage of the frame in place by the current key of keyring, the ID of key is
+// written to the MetaFrame. The transaction ID is authenticated with the carriage, while the data tag isn't
+// because it can be remapped by YoMo-Zipper. The streamed carriage is encrypted in segments as it's read.
+func EncryptFrame(f *frame.DataFrame, keyring *kms.Keyring) error {
+	k, err := keyring.Current()
+	if err != nil {
+		return err
+	}
+
+	if f.Streamed() {
+		carriage, ad := f.CarriageReader(), []byte(f.TransactionID())
+		f.SetCarriageReader(f.GetDataTagID(), pipeCarriage(carriage, func(w io.Writer) (io.WriteCloser, error) {
+			return kms.NewSealWriter(k, w, ad)
+		}))
+		f.SetKeyID(k.ID)
+		return nil
+	}
+
+	buf, err := kms.Seal(k, f.GetCarriage(), []byte(f.TransactionID()))
+	if err != nil {
+		return err
+	}
+
+	f.SetCarriage(f.GetDataTagID(), buf)
+	f.SetKeyID(k.ID)
+	return nil
+}
+
+// DecryptFrame decrypts the carriage of the frame in place, the key is looked up by the key ID. It returns
+// ErrNotEncrypted if the carriage isn't encrypted. The carriage compressed before the encryption is decompressed
+// by the codec of its ID up to DefaultMaxFrameSize, since the codec is chosen by the sender rather than negotiated
+// with the receiver.
+func DecryptFrame(f *frame.DataFrame, keyring *kms.Keyring) error {
+	if f.KeyID() == "" {
+		return ErrNotEncrypted
+	}
+
+	k, err := keyring.Lookup(f.KeyID())
+	if err != nil {
+		return err
+	}
+
+	if f.Streamed() {
+		carriage := f.CarriageReader()
+		r, err := kms.NewOpenReader(k, carriage, []byte(f.TransactionID()))
+		if err != nil {
+			return err
+		}
+		f.SetCarriageReader(f.GetDataTagID(), &carriageReader{Reader: r, underlying: carriage})
+		f.SetKeyID("")
+		return decompressDecrypted(f)
+	}
+
+	buf, err := kms.Open(k, f.GetCarriage(), []byte(f.TransactionID()))
+	if err != nil {
+		return err
+	}
+
+	f.SetCarriage(f.GetDataTagID(), buf)
+	f.SetKeyID("")
+	return decompressDecrypted(f)
+}
+
+// decompressDecrypted decompresses the decrypted carriage by the codec registered for its ID.
+func decompressDecrypted(f *frame.DataFrame) error {
+	if f.Compression() == compress.None {
+		return nil
+	}
+	codec, ok := compress.Lookup(f.Compression())
+	if !ok {
+		return ErrCompressionNotNegotiated
+	}
+	return DecompressFrame(f, codec, DefaultMaxFrameSize)
+}
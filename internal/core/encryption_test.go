@@ -0,0 +1,80 @@
+package core
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/core/compress"
+	"github.com/yomorun/yomo/core/kms"
+	"github.com/yomorun/yomo/internal/frame"
+)
+
+func TestEncryptFrame(t *testing.T) {
+	k, err := kms.NewKey(32)
+	assert.NoError(t, err)
+	keyring := kms.NewKeyring(nil)
+	keyring.Rotate(k)
+
+	f := frame.NewDataFrame("1234")
+	f.SetCarriage(0x10, []byte("hello"))
+	assert.NoError(t, EncryptFrame(f, keyring))
+	assert.Equal(t, k.ID, f.KeyID())
+	assert.NotEqual(t, []byte("hello"), f.GetCarriage())
+
+	// the data tag can be remapped by YoMo-Zipper.
+	received, err := frame.DecodeToDataFrame(f.Encode())
+	assert.NoError(t, err)
+	received.SetDataTagID(0x20)
+	assert.NoError(t, DecryptFrame(received, keyring))
+	assert.Equal(t, "", received.KeyID())
+	assert.Equal(t, []byte("hello"), received.GetCarriage())
+
+	// the key is unknown.
+	assert.Equal(t, kms.ErrKeyNotFound, DecryptFrame(f, kms.NewKeyring(nil)))
+
+	// the plaintext could be forged by any YoMo-Zipper in the path.
+	plaintext := frame.NewDataFrame("5678")
+	plaintext.SetCarriage(0x10, []byte("forged"))
+	assert.Equal(t, ErrNotEncrypted, DecryptFrame(plaintext, keyring))
+}
+
+func TestCompressAndEncryptFrame(t *testing.T) {
+	k, err := kms.NewKey(32)
+	assert.NoError(t, err)
+	keyring := kms.NewKeyring(nil)
+	keyring.Rotate(k)
+
+	// the source and the stream function negotiate different codecs with their YoMo-Zippers.
+	sourceCodec, _ := compress.Lookup(compress.Gzip)
+	sfnCodec := compress.NewCodec(0x7e, "test-copy", func(src, dst []byte) []byte {
+		return append(dst, src...)
+	}, func(src, dst []byte) ([]byte, error) {
+		return append(dst, src...), nil
+	})
+	compress.Register(sfnCodec)
+
+	payload := bytes.Repeat([]byte(`{"noise":42}`), 1000)
+	f := frame.NewDataFrame("1234")
+	f.SetCarriage(0x10, payload)
+	compressed, err := CompressFrame(f, sourceCodec, compress.DefaultThreshold)
+	assert.NoError(t, err)
+	assert.Equal(t, compress.Gzip, compressed.Compression())
+	assert.NoError(t, EncryptFrame(compressed, keyring))
+
+	for _, codec := range []compress.Codec{sfnCodec, nil} {
+		received, err := frame.DecodeToDataFrame(compressed.Encode())
+		assert.NoError(t, err)
+		// YoMo-Zipper leaves the encrypted carriage as is, and doesn't compress it again.
+		assert.NoError(t, DecompressFrame(received, sourceCodec, 0))
+		forwarded, err := CompressFrame(received, codec, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, compress.Gzip, forwarded.Compression())
+
+		// the stream function decompresses the carriage by the codec of the source after the decryption.
+		assert.NoError(t, DecryptFrame(forwarded, keyring))
+		assert.Equal(t, compress.None, forwarded.Compression())
+		assert.NoError(t, DecompressFrame(forwarded, codec, 0))
+		assert.Equal(t, payload, forwarded.GetCarriage())
+	}
+}
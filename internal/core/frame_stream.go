@@ -3,6 +3,7 @@ package core
 import (
 	"errors"
 	"io"
+	"sync"
 
 	"github.com/yomorun/yomo/internal/frame"
 )
@@ -11,6 +12,8 @@ import (
 type FrameStream struct {
 	// Stream is a QUIC stream.
 	stream io.ReadWriter
+	// mutex keeps the chunks of a DataFrame in order when several goroutines write.
+	mutex sync.Mutex
 }
 
 // NewFrameStream creates a new FrameStream.
@@ -33,9 +36,30 @@ func (fs *FrameStream) WriteFrame(f frame.Frame) (int, error) {
 	if fs.stream == nil {
 		return 0, errors.New("stream can not be nil")
 	}
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
 	return fs.stream.Write(f.Encode())
 }
 
+// WriteFrames writes the frames into QUIC stream consecutively, e.g. the chunks of a DataFrame.
+func (fs *FrameStream) WriteFrames(frames []frame.Frame) (int, error) {
+	if fs.stream == nil {
+		return 0, errors.New("stream can not be nil")
+	}
+
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	total := 0
+	for _, f := range frames {
+		n, err := fs.stream.Write(f.Encode())
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
 // // Close the frame stream.
 // func (fs *FrameStream) Close() error {
 // 	if fs.stream == nil {
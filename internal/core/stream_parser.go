@@ -1,17 +1,48 @@
 package core
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"sync/atomic"
 
-	"github.com/yomorun/y3"
 	"github.com/yomorun/yomo/internal/frame"
 	"github.com/yomorun/yomo/logger"
 )
 
-// ParseFrame parses the frame from QUIC stream.
+// The errors of parsing frames, the callers tell them apart by errors.Is. The frames larger than the limit
+// are rejected with ErrFrameTooLarge.
+var (
+	// ErrUnknownFrame is returned when the tag of frame is not a known frame type.
+	ErrUnknownFrame = errors.New("unknown frame type")
+	// ErrMalformedFrame is returned when the length prefix or the content of frame can't be decoded.
+	ErrMalformedFrame = errors.New("malformed frame")
+)
+
+// maxLengthBytes is the max size of the length prefix, which is a signed varint of int32 in Y3.
+const maxLengthBytes = 5
+
+// corruptedFrames is the count of frames whose checksum mismatches.
+var corruptedFrames uint64
+
+// CorruptedFrames returns the count of corrupted frames parsed by ParseFrame, they are dropped by the caller.
+func CorruptedFrames() uint64 {
+	return atomic.LoadUint64(&corruptedFrames)
+}
+
+// ParseFrame parses the frame from QUIC stream, the frames larger than DefaultMaxFrameSize are rejected.
 func ParseFrame(stream io.Reader) (frame.Frame, error) {
-	buf, err := y3.ReadPacket(stream)
+	return ParseFrameWithLimit(stream, DefaultMaxFrameSize)
+}
+
+// ParseFrameWithLimit parses the frame from QUIC stream, the frames larger than maxSize bytes are rejected
+// before reading the content, DefaultMaxFrameSize is used if maxSize is zero.
+func ParseFrameWithLimit(stream io.Reader, maxSize int) (f frame.Frame, err error) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxFrameSize
+	}
+	buf, err := readPacket(stream, maxSize)
 	if err != nil {
 		logger.Error("\t\t ||||read first byte||||", "err", err)
 		return nil, err
@@ -22,44 +53,121 @@ func ParseFrame(stream io.Reader) (frame.Frame, error) {
 		logger.Debug(fmt.Sprintf("🔗 parsed out: [%# x]", buf))
 	}
 
-	frameType := buf[0]
-	// determine the frame type
-	switch frameType {
-	case 0x80 | byte(frame.TagOfHandshakeFrame):
-		handshakeFrame := readHandshakeFrame(buf)
+	// the decoders of Y3 may panic on the crafted content.
+	defer func() {
+		if r := recover(); r != nil {
+			f, err = nil, fmt.Errorf("%w: %v", ErrMalformedFrame, r)
+		}
+	}()
+
+	f, err = decodeFrame(buf)
+	if err != nil {
+		if errors.Is(err, frame.ErrChecksumMismatch) {
+			atomic.AddUint64(&corruptedFrames, 1)
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", ErrMalformedFrame, err)
+	}
+
+	if data, ok := f.(*frame.DataFrame); ok && data.Streamed() {
+		// the carriage is the rest of stream.
+		data.SetCarriageReader(data.GetDataTagID(), stream)
+	}
+	return f, nil
+}
+
+// knownFrame returns true if the tag is a frame type which ParseFrame decodes.
+func knownFrame(tag byte) bool {
+	if tag&0x80 == 0 {
+		// the frames are node packets.
+		return false
+	}
+	switch frame.FrameType(tag & 0x3F) {
+	case frame.TagOfHandshakeFrame, frame.TagOfDataFrame, frame.TagOfPingFrame, frame.TagOfPongFrame,
+		frame.TagOfAcceptedFrame, frame.TagOfRejectedFrame, frame.TagOfChunkFrame, frame.TagOfControlFrame,
+		frame.TagOfGoodbyeFrame, frame.TagOfQuotaFrame:
+		return true
+	}
+	return false
+}
+
+// readPacket reads a Y3 packet of frame, the tag and the length prefix are validated before the content
+// is read, and the buffer grows as the content arrives instead of being allocated by the length prefix.
+func readPacket(r io.Reader, maxSize int) ([]byte, error) {
+	b := make([]byte, 1)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	if !knownFrame(b[0]) {
+		return nil, fmt.Errorf("%w: %# x", ErrUnknownFrame, b[0])
+	}
+	head := []byte{b[0]}
+
+	var length int64
+	for i := 0; ; i++ {
+		if i == maxLengthBytes {
+			return nil, fmt.Errorf("%w: the length prefix exceeds %d bytes", ErrMalformedFrame, maxLengthBytes)
+		}
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		head = append(head, b[0])
+		if i == 0 && b[0]&0x40 != 0 {
+			return nil, fmt.Errorf("%w: negative length", ErrMalformedFrame)
+		}
+		length = length<<7 | int64(b[0]&0x7F)
+		if b[0]&0x80 == 0 {
+			break
+		}
+	}
+	if length > int64(maxSize) {
+		return nil, fmt.Errorf("%w: %d bytes", ErrFrameTooLarge, length)
+	}
+
+	buf := bytes.NewBuffer(head)
+	if _, err := io.CopyN(buf, r, length); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeFrame decodes the packet by the frame type.
+func decodeFrame(buf []byte) (frame.Frame, error) {
+	switch frame.FrameType(buf[0] & 0x3F) {
+	case frame.TagOfHandshakeFrame:
+		handshakeFrame, err := frame.DecodeToHandshakeFrame(buf)
+		if err != nil {
+			return nil, err
+		}
 		logger.Debug(fmt.Sprintf("[HandshakeFrame] name=%s, type=%s", handshakeFrame.Name, handshakeFrame.Type()))
 		return handshakeFrame, nil
-	case 0x80 | byte(frame.TagOfDataFrame):
-		data := readDataFrame(buf)
+	case frame.TagOfDataFrame:
+		data, err := frame.DecodeToDataFrame(buf)
+		if err != nil {
+			return nil, err
+		}
 		logger.Debug(fmt.Sprintf("[DataFrame] tid=%s, data-tag=%v, len(carriage)=%d", data.TransactionID(), data.GetDataTagID(), len(data.GetCarriage())))
 		return data, nil
-	case 0x80 | byte(frame.TagOfPingFrame):
+	case frame.TagOfPingFrame:
 		return frame.DecodeToPingFrame(buf)
-	case 0x80 | byte(frame.TagOfPongFrame):
+	case frame.TagOfPongFrame:
 		return frame.DecodeToPongFrame(buf)
-	case 0x80 | byte(frame.TagOfAcceptedFrame):
+	case frame.TagOfAcceptedFrame:
 		return frame.DecodeToAcceptedFrame(buf)
-	case 0x80 | byte(frame.TagOfRejectedFrame):
+	case frame.TagOfRejectedFrame:
 		return frame.DecodeToRejectedFrame(buf)
+	case frame.TagOfChunkFrame:
+		return frame.DecodeToChunkFrame(buf)
+	case frame.TagOfControlFrame:
+		return frame.DecodeToControlFrame(buf)
+	case frame.TagOfGoodbyeFrame:
+		return frame.DecodeToGoodbyeFrame(buf)
+	case frame.TagOfQuotaFrame:
+		return frame.DecodeToQuotaFrame(buf)
 	default:
-		return nil, fmt.Errorf("unknown frame type, buf[0]=%# x", buf[0])
-	}
-}
-
-func readHandshakeFrame(buf []byte) *frame.HandshakeFrame {
-	// parse to HandshakeFrame
-	handshake, err := frame.DecodeToHandshakeFrame(buf)
-	if err != nil {
-		panic(err)
-	}
-	return handshake
-}
-
-func readDataFrame(buf []byte) *frame.DataFrame {
-	// parse to DataFrame
-	data, err := frame.DecodeToDataFrame(buf)
-	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("%w: %# x", ErrUnknownFrame, buf[0])
 	}
-	return data
 }
@@ -0,0 +1,59 @@
+package core
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/internal/frame"
+)
+
+func TestParseCorruptedFrame(t *testing.T) {
+	f := frame.NewDataFrame("1234")
+	f.SetCarriage(0x10, []byte("yomo"))
+	f.SetChecksum(true)
+	buf := f.Encode()
+	buf[len(buf)-1] ^= 0xFF
+
+	corrupted := CorruptedFrames()
+	_, err := ParseFrame(bytes.NewReader(buf))
+	assert.ErrorIs(t, err, frame.ErrChecksumMismatch)
+	assert.Equal(t, corrupted+1, CorruptedFrames())
+
+	// the frame without checksum is parsed as before.
+	f.SetChecksum(false)
+	parsed, err := ParseFrame(bytes.NewReader(f.Encode()))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("yomo"), parsed.(*frame.DataFrame).GetCarriage())
+	assert.Equal(t, corrupted+1, CorruptedFrames())
+}
+
+func TestParseMalformedFrame(t *testing.T) {
+	data := frame.NewDataFrame("1234")
+	data.SetCarriage(0x10, []byte("yomo"))
+
+	tests := []struct {
+		name string
+		buf  []byte
+		err  error
+	}{
+		{"primitive packet", []byte{0x3F, 0x00}, ErrUnknownFrame},
+		{"unknown tag", []byte{0x80 | 0x10, 0x00}, ErrUnknownFrame},
+		{"negative length", []byte{0x80 | byte(frame.TagOfDataFrame), 0x40}, ErrMalformedFrame},
+		{"long length prefix", []byte{0x80 | byte(frame.TagOfDataFrame), 0x81, 0x81, 0x81, 0x81, 0x81, 0x01}, ErrMalformedFrame},
+		{"exceeds the limit", []byte{0x80 | byte(frame.TagOfDataFrame), 0x87, 0xFF, 0xFF, 0x7F}, ErrFrameTooLarge},
+		{"truncated", data.Encode()[:8], io.ErrUnexpectedEOF},
+		{"malformed content", []byte{0x80 | byte(frame.TagOfDataFrame), 0x02, 0xFF, 0x7F}, ErrMalformedFrame},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseFrameWithLimit(bytes.NewReader(tt.buf), 1024)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+
+	f, err := ParseFrameWithLimit(bytes.NewReader(data.Encode()), 1024)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("yomo"), f.(*frame.DataFrame).GetCarriage())
+}
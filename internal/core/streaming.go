@@ -0,0 +1,74 @@
+package core
+
+import (
+	"io"
+
+	"github.com/yomorun/yomo/internal/frame"
+)
+
+// WriteStreamedFrame writes the DataFrame and then copies its carriage reader to w, the carriage is not
+// buffered in memory. The streamed DataFrame must be the last frame in the stream, the receiver reads
+// the carriage until the end of stream. The carriage is closed by CloseCarriage once it's written.
+func WriteStreamedFrame(w io.Writer, f *frame.DataFrame) (int64, error) {
+	n, err := w.Write(f.Encode())
+	if err != nil {
+		CloseCarriage(f)
+		return int64(n), err
+	}
+	if !f.Streamed() {
+		return int64(n), nil
+	}
+
+	written, err := io.Copy(w, f.CarriageReader())
+	CloseCarriage(f)
+	return int64(n) + written, err
+}
+
+// CloseCarriage closes the streamed carriage of the frame if it's an io.Closer, e.g. the stream of peer or
+// the carriage which is compressed or encrypted as it's read, so the stream is cancelled and the goroutines
+// are released if the carriage is abandoned. It's a no-op if the carriage isn't streamed.
+func CloseCarriage(f *frame.DataFrame) error {
+	if !f.Streamed() {
+		return nil
+	}
+	return closeReader(f.CarriageReader())
+}
+
+func closeReader(r io.Reader) error {
+	if c, ok := r.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// carriageReader reads the streamed carriage through a transformer, closing it closes the transformer and
+// then the underlying carriage.
+type carriageReader struct {
+	io.Reader
+	closer     io.Closer
+	underlying io.Reader
+}
+
+func (c *carriageReader) Close() error {
+	if c.closer != nil {
+		c.closer.Close()
+	}
+	return closeReader(c.underlying)
+}
+
+// pipeCarriage returns the carriage which is written to the writer returned by wrap as it's read, e.g. the
+// compressor, the writer is closed at the end of the carriage.
+func pipeCarriage(r io.Reader, wrap func(w io.Writer) (io.WriteCloser, error)) io.Reader {
+	pr, pw := io.Pipe()
+	go func() {
+		w, err := wrap(pw)
+		if err == nil {
+			_, err = io.Copy(w, r)
+			if cerr := w.Close(); err == nil {
+				err = cerr
+			}
+		}
+		pw.CloseWithError(err)
+	}()
+	return &carriageReader{Reader: pr, closer: pr, underlying: r}
+}
@@ -0,0 +1,96 @@
+package core
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/core/compress"
+	"github.com/yomorun/yomo/core/kms"
+	"github.com/yomorun/yomo/internal/frame"
+)
+
+func TestWriteStreamedFrame(t *testing.T) {
+	payload := bytes.Repeat([]byte("yomo"), 1024)
+	f := frame.NewDataFrame("1234")
+	f.SetCarriageReader(0x10, bytes.NewReader(payload))
+	assert.True(t, f.Streamed())
+
+	var buf bytes.Buffer
+	n, err := WriteStreamedFrame(&buf, f)
+	assert.NoError(t, err)
+	assert.EqualValues(t, buf.Len(), n)
+
+	received, err := ParseFrame(&buf)
+	assert.NoError(t, err)
+	dataFrame := received.(*frame.DataFrame)
+	assert.True(t, dataFrame.Streamed())
+	assert.Equal(t, "1234", dataFrame.TransactionID())
+	assert.EqualValues(t, 0x10, dataFrame.GetDataTagID())
+	assert.Empty(t, dataFrame.GetCarriage())
+
+	// the carriage is the rest of stream.
+	carriage, err := ioutil.ReadAll(dataFrame.CarriageReader())
+	assert.NoError(t, err)
+	assert.Equal(t, payload, carriage)
+
+	// the response of stream function isn't streamed.
+	dataFrame.SetCarriage(0x13, []byte("ok"))
+	assert.False(t, dataFrame.Streamed())
+	assert.Equal(t, []byte("ok"), dataFrame.GetCarriage())
+}
+
+func TestCompressAndEncryptStreamedFrame(t *testing.T) {
+	codec, _ := compress.Lookup(compress.Gzip)
+	k, err := kms.NewKey(32)
+	assert.NoError(t, err)
+	keyring := kms.NewKeyring(nil)
+	keyring.Rotate(k)
+
+	payload := bytes.Repeat([]byte(`{"noise":42}`), 10000)
+	f := frame.NewDataFrame("1234")
+	f.SetCarriageReader(0x10, bytes.NewReader(payload))
+
+	// the streamed carriage is compressed whatever its size.
+	compressed, err := CompressFrame(f, codec, len(payload))
+	assert.NoError(t, err)
+	assert.Equal(t, compress.Gzip, compressed.Compression())
+	assert.NoError(t, EncryptFrame(compressed, keyring))
+
+	var buf bytes.Buffer
+	_, err = WriteStreamedFrame(&buf, compressed)
+	assert.NoError(t, err)
+	assert.Less(t, buf.Len(), len(payload))
+
+	received, err := ParseFrame(&buf)
+	assert.NoError(t, err)
+	dataFrame := received.(*frame.DataFrame)
+	assert.Equal(t, k.ID, dataFrame.KeyID())
+	// the encrypted carriage is left as is.
+	assert.NoError(t, DecompressFrame(dataFrame, codec, 0))
+	assert.NoError(t, DecryptFrame(dataFrame, keyring))
+	assert.NoError(t, DecompressFrame(dataFrame, codec, 0))
+	assert.True(t, dataFrame.Streamed())
+
+	carriage, err := ioutil.ReadAll(dataFrame.CarriageReader())
+	assert.NoError(t, err)
+	assert.Equal(t, payload, carriage)
+	assert.NoError(t, CloseCarriage(dataFrame))
+}
+
+func TestCloseCarriage(t *testing.T) {
+	codec, _ := compress.Lookup(compress.Gzip)
+	pr, pw := io.Pipe()
+	f := frame.NewDataFrame("1234")
+	f.SetCarriageReader(0x10, pr)
+
+	compressed, err := CompressFrame(f, codec, 0)
+	assert.NoError(t, err)
+	assert.NoError(t, CloseCarriage(compressed))
+
+	// the underlying carriage is closed too.
+	_, err = pw.Write([]byte("yomo"))
+	assert.Equal(t, io.ErrClosedPipe, err)
+}
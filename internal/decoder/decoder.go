@@ -63,6 +63,7 @@ type Observable interface {
 type observableImpl struct {
 	ctx      context.Context
 	iterable Iterable
+	tags     []byte // tags are the data tags of the frame, see WithTags.
 }
 
 // KeyBuf is a pair of subscribed key and buffer.
@@ -148,7 +149,23 @@ func FromItems(items []interface{}, opts ...Option) Observable {
 		}
 	}
 
-	return createObservable(options.ctx, f)
+	o := createObservable(options.ctx, f)
+	o.(*observableImpl).tags = options.tags
+	return o
+}
+
+// aliases returns the keys of packets observed by the subscribed keys which are not the key of packets, the
+// values are the subscribed keys. The packets keyed by the tag of payload are observed by the other tags of frame.
+func (o *observableImpl) aliases(subscribed map[byte]bool) map[byte]byte {
+	if len(o.tags) < 2 || subscribed[o.tags[0]] {
+		return nil
+	}
+	for _, tag := range o.tags[1:] {
+		if subscribed[tag] {
+			return map[byte]byte{o.tags[0]: tag}
+		}
+	}
+	return nil
 }
 
 // OnObserve calls the callback function when the key is observed.
@@ -247,6 +264,11 @@ func (o *observableImpl) MultiSubscribe(keys ...byte) Observable {
 	for _, key := range keys {
 		m[key] = true
 	}
+	// the packets are reported by the subscribed key if it's an alias.
+	aliases := o.aliases(m)
+	for k := range aliases {
+		m[k] = true
+	}
 
 	f := func(ctx context.Context, next chan interface{}) {
 		defer close(next)
@@ -389,6 +411,9 @@ func (o *observableImpl) MultiSubscribe(keys ...byte) Observable {
 								}
 
 								// subscribe multi keys, return key value to distinguish the values of different keys.
+								if alias, ok := aliases[k]; ok {
+									k = alias
+								}
 								next <- KeyBuf{
 									Key: k,
 									Buf: buffer,
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/y3-codec-golang"
 )
 
 // observeFunc is the callback function for `OnObserve` in Y3 Decoder.
@@ -160,3 +161,26 @@ func TestUnmarshalFromJSONDecoder(t *testing.T) {
 		break
 	}
 }
+
+func TestObserveDataByExtraTags(t *testing.T) {
+	data, err := y3.NewCodec(0x10).Marshal("test")
+	assert.NoError(t, err)
+	observe := func(opts ...Option) [][]byte {
+		var observed [][]byte
+		for v := range FromItems([]interface{}{data}, opts...).Subscribe(0x20).OnObserve(observeFunc) {
+			observed = append(observed, v.([]byte))
+		}
+		return observed
+	}
+
+	// the payload keyed by 0x10 is observed by 0x20 if the frame is tagged with both.
+	observed := observe(WithTags(0x10, 0x20))
+	if assert.Len(t, observed, 1) {
+		s, err := y3.ToUTF8String(observed[0])
+		assert.NoError(t, err)
+		assert.Equal(t, "test", s)
+	}
+
+	assert.Empty(t, observe())
+	assert.Empty(t, observe(WithTags(0x10, 0x30)))
+}
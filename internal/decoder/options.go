@@ -9,6 +9,7 @@ type Option func(o *options)
 type options struct {
 	ctx            context.Context // WithContext allows to pass a context.
 	OnReceivedData func([]byte)    // OnReceivedData is the function which will be triggered when the data is received.
+	tags           []byte          // tags are the data tags of the frame, the first one is the tag of payload.
 }
 
 // WithReceivedDataFunc sets the function which will be executed when the data is received.
@@ -18,6 +19,14 @@ func WithReceivedDataFunc(f func([]byte)) Option {
 	}
 }
 
+// WithTags sets the data tags of the frame which carries the items, the first one is the tag of payload and the
+// others observe the payload besides it, so subscribing any of them observes the packets keyed by the first one.
+func WithTags(tags ...byte) Option {
+	return func(o *options) {
+		o.tags = tags
+	}
+}
+
 // WithContext allows to pass a context.
 func WithContext(ctx context.Context) Option {
 	return func(o *options) {
@@ -3,7 +3,12 @@ package frame
 import "github.com/yomorun/y3"
 
 // AcceptedFrame is a Y3 encoded bytes, Tag is a fixed value TYPE_ID_ACCEPTED_FRAME
-type AcceptedFrame struct{}
+type AcceptedFrame struct {
+	// Codec is the name of compression codec negotiated in the handshake, it's empty if the carriage isn't compressed
+	Codec string
+	// Version is the version of wire protocol negotiated in the handshake, 0 means the zipper is before the negotiation
+	Version uint32
+}
 
 // NewAcceptedFrame creates a new AcceptedFrame with a given TagID of user's data
 func NewAcceptedFrame() *AcceptedFrame {
@@ -18,7 +23,22 @@ func (m *AcceptedFrame) Type() FrameType {
 // Encode to Y3 encoded bytes.
 func (m *AcceptedFrame) Encode() []byte {
 	accepted := y3.NewNodePacketEncoder(byte(m.Type()))
-	accepted.AddBytes(nil)
+	if m.Codec == "" && m.Version == 0 {
+		accepted.AddBytes(nil)
+		return accepted.Encode()
+	}
+
+	if m.Codec != "" {
+		codecBlock := y3.NewPrimitivePacketEncoder(byte(TagOfAcceptedCodec))
+		codecBlock.SetStringValue(m.Codec)
+		accepted.AddPrimitivePacket(codecBlock)
+	}
+
+	if m.Version > 0 {
+		versionBlock := y3.NewPrimitivePacketEncoder(byte(TagOfAcceptedVersion))
+		versionBlock.SetUInt32Value(m.Version)
+		accepted.AddPrimitivePacket(versionBlock)
+	}
 
 	return accepted.Encode()
 }
@@ -30,5 +50,22 @@ func DecodeToAcceptedFrame(buf []byte) (*AcceptedFrame, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &AcceptedFrame{}, nil
+
+	accepted := &AcceptedFrame{}
+	if codecBlock, ok := nodeBlock.PrimitivePackets[byte(TagOfAcceptedCodec)]; ok {
+		codec, err := codecBlock.ToUTF8String()
+		if err != nil {
+			return nil, err
+		}
+		accepted.Codec = codec
+	}
+
+	if versionBlock, ok := nodeBlock.PrimitivePackets[byte(TagOfAcceptedVersion)]; ok {
+		version, err := versionBlock.ToUInt32()
+		if err != nil {
+			return nil, err
+		}
+		accepted.Version = version
+	}
+	return accepted, nil
 }
@@ -17,3 +17,11 @@ func TestAcceptedFrameDecode(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, []byte{0x80 | byte(TagOfAcceptedFrame), 0x00}, ping.Encode())
 }
+
+func TestAcceptedFrameWithCodec(t *testing.T) {
+	f := NewAcceptedFrame()
+	f.Codec = "gzip"
+	accepted, err := DecodeToAcceptedFrame(f.Encode())
+	assert.NoError(t, err)
+	assert.Equal(t, "gzip", accepted.Codec)
+}
@@ -0,0 +1,103 @@
+package frame
+
+import "github.com/yomorun/y3"
+
+// The tags of fields in `ChunkFrame`.
+const (
+	TagOfChunkID    FrameType = 0x01
+	TagOfChunkIndex FrameType = 0x02
+	TagOfChunkTotal FrameType = 0x03
+	TagOfChunkData  FrameType = 0x04
+)
+
+// ChunkFrame is a piece of the Y3 encoded bytes of a large DataFrame, the chunks of a DataFrame are sent
+// in order on the same stream and reassembled by the receiver.
+type ChunkFrame struct {
+	// ID is the transaction ID of the DataFrame
+	ID string
+	// Index is the index of chunk, it starts from 0
+	Index uint32
+	// Total is the count of chunks of the DataFrame
+	Total uint32
+	// Data is the piece of the encoded DataFrame
+	Data []byte
+}
+
+// NewChunkFrame creates a new ChunkFrame.
+func NewChunkFrame(id string, index uint32, total uint32, data []byte) *ChunkFrame {
+	return &ChunkFrame{
+		ID:    id,
+		Index: index,
+		Total: total,
+		Data:  data,
+	}
+}
+
+// Type gets the type of Frame.
+func (c *ChunkFrame) Type() FrameType {
+	return TagOfChunkFrame
+}
+
+// Encode to Y3 encoded bytes.
+func (c *ChunkFrame) Encode() []byte {
+	idBlock := y3.NewPrimitivePacketEncoder(byte(TagOfChunkID))
+	idBlock.SetStringValue(c.ID)
+
+	indexBlock := y3.NewPrimitivePacketEncoder(byte(TagOfChunkIndex))
+	indexBlock.SetUInt32Value(c.Index)
+
+	totalBlock := y3.NewPrimitivePacketEncoder(byte(TagOfChunkTotal))
+	totalBlock.SetUInt32Value(c.Total)
+
+	dataBlock := y3.NewPrimitivePacketEncoder(byte(TagOfChunkData))
+	dataBlock.SetBytesValue(c.Data)
+
+	chunk := y3.NewNodePacketEncoder(byte(c.Type()))
+	chunk.AddPrimitivePacket(idBlock)
+	chunk.AddPrimitivePacket(indexBlock)
+	chunk.AddPrimitivePacket(totalBlock)
+	chunk.AddPrimitivePacket(dataBlock)
+
+	return chunk.Encode()
+}
+
+// DecodeToChunkFrame decodes Y3 encoded bytes to ChunkFrame.
+func DecodeToChunkFrame(buf []byte) (*ChunkFrame, error) {
+	node := y3.NodePacket{}
+	_, err := y3.DecodeToNodePacket(buf, &node)
+	if err != nil {
+		return nil, err
+	}
+
+	chunk := &ChunkFrame{}
+
+	if idBlock, ok := node.PrimitivePackets[byte(TagOfChunkID)]; ok {
+		id, err := idBlock.ToUTF8String()
+		if err != nil {
+			return nil, err
+		}
+		chunk.ID = id
+	}
+
+	if indexBlock, ok := node.PrimitivePackets[byte(TagOfChunkIndex)]; ok {
+		index, err := indexBlock.ToUInt32()
+		if err != nil {
+			return nil, err
+		}
+		chunk.Index = index
+	}
+
+	if totalBlock, ok := node.PrimitivePackets[byte(TagOfChunkTotal)]; ok {
+		total, err := totalBlock.ToUInt32()
+		if err != nil {
+			return nil, err
+		}
+		chunk.Total = total
+	}
+
+	if dataBlock, ok := node.PrimitivePackets[byte(TagOfChunkData)]; ok {
+		chunk.Data = dataBlock.ToBytes()
+	}
+
+	return chunk, nil
+}
@@ -0,0 +1,19 @@
+package frame
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChunkFrameEncodeAndDecode(t *testing.T) {
+	f := NewChunkFrame("1234", 1, 3, []byte("yomo"))
+	assert.Equal(t, TagOfChunkFrame, f.Type())
+
+	chunk, err := DecodeToChunkFrame(f.Encode())
+	assert.NoError(t, err)
+	assert.Equal(t, "1234", chunk.ID)
+	assert.EqualValues(t, 1, chunk.Index)
+	assert.EqualValues(t, 3, chunk.Total)
+	assert.Equal(t, []byte("yomo"), chunk.Data)
+}
@@ -0,0 +1,71 @@
+package frame
+
+import "github.com/yomorun/y3"
+
+// The tags of fields in `ControlFrame`.
+const (
+	TagOfControlSubscribe   FrameType = 0x01
+	TagOfControlUnsubscribe FrameType = 0x02
+)
+
+// ControlFrame is sent by a running stream function to change the data tags it observes,
+// YoMo-Zipper updates its routing table without reconnecting.
+type ControlFrame struct {
+	// Subscribe are the data tags which are added to the observed tags
+	Subscribe []byte
+	// Unsubscribe are the data tags which are removed from the observed tags
+	Unsubscribe []byte
+}
+
+// NewControlFrame creates a new ControlFrame.
+func NewControlFrame(subscribe []byte, unsubscribe []byte) *ControlFrame {
+	return &ControlFrame{
+		Subscribe:   subscribe,
+		Unsubscribe: unsubscribe,
+	}
+}
+
+// Type gets the type of Frame.
+func (c *ControlFrame) Type() FrameType {
+	return TagOfControlFrame
+}
+
+// Encode to Y3 encoded bytes.
+func (c *ControlFrame) Encode() []byte {
+	control := y3.NewNodePacketEncoder(byte(c.Type()))
+
+	if len(c.Subscribe) > 0 {
+		subscribeBlock := y3.NewPrimitivePacketEncoder(byte(TagOfControlSubscribe))
+		subscribeBlock.SetBytesValue(c.Subscribe)
+		control.AddPrimitivePacket(subscribeBlock)
+	}
+
+	if len(c.Unsubscribe) > 0 {
+		unsubscribeBlock := y3.NewPrimitivePacketEncoder(byte(TagOfControlUnsubscribe))
+		unsubscribeBlock.SetBytesValue(c.Unsubscribe)
+		control.AddPrimitivePacket(unsubscribeBlock)
+	}
+
+	return control.Encode()
+}
+
+// DecodeToControlFrame decodes Y3 encoded bytes to ControlFrame.
+func DecodeToControlFrame(buf []byte) (*ControlFrame, error) {
+	node := y3.NodePacket{}
+	_, err := y3.DecodeToNodePacket(buf, &node)
+	if err != nil {
+		return nil, err
+	}
+
+	control := &ControlFrame{}
+
+	if subscribeBlock, ok := node.PrimitivePackets[byte(TagOfControlSubscribe)]; ok {
+		control.Subscribe = subscribeBlock.ToBytes()
+	}
+
+	if unsubscribeBlock, ok := node.PrimitivePackets[byte(TagOfControlUnsubscribe)]; ok {
+		control.Unsubscribe = unsubscribeBlock.ToBytes()
+	}
+
+	return control, nil
+}
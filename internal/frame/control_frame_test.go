@@ -0,0 +1,22 @@
+package frame
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestControlFrameEncodeAndDecode(t *testing.T) {
+	f := NewControlFrame([]byte{0x10, 0x11}, []byte{0x12})
+	assert.Equal(t, TagOfControlFrame, f.Type())
+
+	control, err := DecodeToControlFrame(f.Encode())
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x10, 0x11}, control.Subscribe)
+	assert.Equal(t, []byte{0x12}, control.Unsubscribe)
+
+	control, err = DecodeToControlFrame(NewControlFrame(nil, []byte{0x12}).Encode())
+	assert.NoError(t, err)
+	assert.Empty(t, control.Subscribe)
+	assert.Equal(t, []byte{0x12}, control.Unsubscribe)
+}
@@ -1,14 +1,32 @@
 package frame
 
-import "github.com/yomorun/y3"
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"time"
+
+	"github.com/yomorun/y3"
+)
 
 // DataFrame defines the data structure carried with user's data
 // when transfering within YoMo
 type DataFrame struct {
 	metaFrame    *MetaFrame
 	payloadFrame *PayloadFrame
+	reader       io.Reader // reader is the streamed carriage, it's not encoded in the frame.
+	checksum     bool      // checksum appends the CRC32C of the frame in the trailer.
 }
 
+// ErrChecksumMismatch is returned when the checksum in the trailer of `DataFrame` mismatches its content,
+// the frame is corrupted by e.g. a custom codec or a proxy.
+var ErrChecksumMismatch = errors.New("frame: checksum mismatch")
+
+// castagnoli is the table of CRC32C.
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
 // NewDataFrame create `DataFrame` with a transactionID string,
 // consider change transactionID to UUID type later
 func NewDataFrame(transactionID string) *DataFrame {
@@ -26,6 +44,8 @@ func (d *DataFrame) Type() FrameType {
 // SetCarriage set user's raw data in `DataFrame`
 func (d *DataFrame) SetCarriage(sid byte, carriage []byte) {
 	d.payloadFrame = NewPayloadFrame(sid).SetCarriage(carriage)
+	d.metaFrame.SetStreamed(false)
+	d.reader = nil
 }
 
 // GetCarriage return user's raw data in `DataFrame`
@@ -33,23 +53,204 @@ func (d *DataFrame) GetCarriage() []byte {
 	return d.payloadFrame.Carriage
 }
 
+// SetCarriageReader set user's raw data as a stream, the carriage is written after the frame
+// until the reader returns io.EOF, so it's not held in memory
+func (d *DataFrame) SetCarriageReader(sid byte, r io.Reader) {
+	d.payloadFrame = NewPayloadFrame(sid).SetCarriage(nil)
+	d.metaFrame.SetStreamed(true)
+	d.reader = r
+}
+
+// CarriageReader return user's raw data as a stream, it reads the carriage of `DataFrame` if it's not streamed
+func (d *DataFrame) CarriageReader() io.Reader {
+	if d.reader != nil {
+		return d.reader
+	}
+	return bytes.NewReader(d.GetCarriage())
+}
+
+// Streamed return true if the carriage follows the frame in the stream
+func (d *DataFrame) Streamed() bool {
+	return d.metaFrame.Streamed()
+}
+
 // TransactionID return transactionID string
 func (d *DataFrame) TransactionID() string {
 	return d.metaFrame.TransactionID()
 }
 
-// GetDataTagID return the Tag of user's data
+// KeyID return the ID of the key which is used to sign/encrypt the carriage
+func (d *DataFrame) KeyID() string {
+	return d.metaFrame.KeyID()
+}
+
+// SetKeyID set the ID of the key which is used to sign/encrypt the carriage
+func (d *DataFrame) SetKeyID(keyID string) {
+	d.metaFrame.SetKeyID(keyID)
+}
+
+// Deadline return the deadline of the frame, ok is false when no deadline is set
+func (d *DataFrame) Deadline() (deadline time.Time, ok bool) {
+	return d.metaFrame.Deadline()
+}
+
+// SetDeadline set the deadline of the frame, the handler should give up processing after the deadline
+func (d *DataFrame) SetDeadline(deadline time.Time) {
+	d.metaFrame.SetDeadline(deadline)
+}
+
+// Sequence return the sequence of the frame in the source, ok is false when no sequence is set
+func (d *DataFrame) Sequence() (sequence uint64, ok bool) {
+	return d.metaFrame.Sequence()
+}
+
+// SetSequence set the sequence of the frame in the source, the late frames are dropped by the sequence
+func (d *DataFrame) SetSequence(sequence uint64) {
+	d.metaFrame.SetSequence(sequence)
+}
+
+// Compression return the ID of codec which compressed the carriage, 0 means the carriage is not compressed
+func (d *DataFrame) Compression() byte {
+	return d.metaFrame.Compression()
+}
+
+// SetCompression set the ID of codec which compressed the carriage
+func (d *DataFrame) SetCompression(codec byte) {
+	d.metaFrame.SetCompression(codec)
+}
+
+// ContentType return the content type of the carriage, the carriage is raw bytes if it's empty
+func (d *DataFrame) ContentType() string {
+	return d.metaFrame.ContentType()
+}
+
+// SetContentType set the content type of the carriage, e.g. "application/json"
+func (d *DataFrame) SetContentType(contentType string) {
+	d.metaFrame.SetContentType(contentType)
+}
+
+// SchemaID return the ID of schema which the carriage conforms to
+func (d *DataFrame) SchemaID() string {
+	return d.metaFrame.SchemaID()
+}
+
+// SetSchemaID set the ID of schema which the carriage conforms to
+func (d *DataFrame) SetSchemaID(schemaID string) {
+	d.metaFrame.SetSchemaID(schemaID)
+}
+
+// Timestamp return the time when the source created the frame, ok is false when the frame is not stamped
+func (d *DataFrame) Timestamp() (timestamp time.Time, ok bool) {
+	return d.metaFrame.Timestamp()
+}
+
+// SetTimestamp set the time when the source created the frame, the end-to-end latency is measured from it
+func (d *DataFrame) SetTimestamp(timestamp time.Time) {
+	d.metaFrame.SetTimestamp(timestamp)
+}
+
+// Hops return the count of YoMo-Zippers which the frame passed through
+func (d *DataFrame) Hops() uint32 {
+	return d.metaFrame.Hops()
+}
+
+// TraceParent return the W3C traceparent of the frame, it's empty if the frame is not traced
+func (d *DataFrame) TraceParent() string {
+	return d.metaFrame.TraceParent()
+}
+
+// SetTraceParent set the W3C traceparent of the frame, it's propagated through YoMo-Zippers and stream functions
+func (d *DataFrame) SetTraceParent(traceParent string) {
+	d.metaFrame.SetTraceParent(traceParent)
+}
+
+// TraceState return the W3C tracestate of the frame
+func (d *DataFrame) TraceState() string {
+	return d.metaFrame.TraceState()
+}
+
+// SetTraceState set the W3C tracestate of the frame
+func (d *DataFrame) SetTraceState(traceState string) {
+	d.metaFrame.SetTraceState(traceState)
+}
+
+// IncrHops increase the count of YoMo-Zippers which the frame passed through, the new count is returned
+func (d *DataFrame) IncrHops() uint32 {
+	return d.metaFrame.IncrHops()
+}
+
+// SetChecksum set if the CRC32C of the frame is appended in the trailer, the receivers validate it when decoding
+func (d *DataFrame) SetChecksum(enabled bool) {
+	d.checksum = enabled
+}
+
+// HasChecksum return true if the CRC32C of the frame is appended in the trailer
+func (d *DataFrame) HasChecksum() bool {
+	return d.checksum
+}
+
+// Clone return a copy of `DataFrame`, the carriage is shared with the original frame
+func (d *DataFrame) Clone() *DataFrame {
+	meta := *d.metaFrame
+	payload := *d.payloadFrame
+	return &DataFrame{
+		metaFrame:    &meta,
+		payloadFrame: &payload,
+		reader:       d.reader,
+		checksum:     d.checksum,
+	}
+}
+
+// GetDataTagID return the Tag of user's data, it's 0 if the carriage is not set.
 func (d *DataFrame) GetDataTagID() byte {
+	if d.payloadFrame == nil {
+		return 0
+	}
 	return d.payloadFrame.Sid
 }
 
+// ExtraTags return the data tags which observe the carriage besides the Tag of user's data
+func (d *DataFrame) ExtraTags() []byte {
+	return d.metaFrame.ExtraTags()
+}
+
+// SetExtraTags set the data tags which observe the carriage besides the Tag of user's data, so the carriage
+// relevant to several stream functions is transmitted once.
+func (d *DataFrame) SetExtraTags(tags ...byte) {
+	d.metaFrame.SetExtraTags(tags)
+}
+
+// Tags return all the data tags which observe the carriage, the Tag of user's data is the first one
+func (d *DataFrame) Tags() []byte {
+	tags := []byte{d.payloadFrame.Sid}
+	for _, tag := range d.metaFrame.ExtraTags() {
+		if bytes.IndexByte(tags, tag) < 0 {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// SetDataTagID set the Tag of user's data
+func (d *DataFrame) SetDataTagID(tag byte) {
+	d.payloadFrame.Sid = tag
+}
+
 // Encode return Y3 encoded bytes of `DataFrame`
 func (d *DataFrame) Encode() []byte {
 	data := y3.NewNodePacketEncoder(byte(d.Type()))
 	// MetaFrame
-	data.AddBytes(d.metaFrame.Encode())
+	meta := d.metaFrame.Encode()
+	data.AddBytes(meta)
 	// PayloadFrame
-	data.AddBytes(d.payloadFrame.Encode())
+	payload := d.payloadFrame.Encode()
+	data.AddBytes(payload)
+	// Checksum, only presents when it's enabled
+	if d.checksum {
+		checksumPacket := y3.NewPrimitivePacketEncoder(byte(TagOfChecksum))
+		checksumPacket.SetBytesValue(checksumOf(meta, payload))
+		data.AddPrimitivePacket(checksumPacket)
+	}
 
 	return data.Encode()
 }
@@ -80,5 +281,22 @@ func DecodeToDataFrame(buf []byte) (*DataFrame, error) {
 		data.payloadFrame = payload
 	}
 
+	if checksumBlock, ok := packet.PrimitivePackets[byte(TagOfChecksum)]; ok {
+		meta := packet.NodePackets[byte(TagOfMetaFrame)]
+		payload := packet.NodePackets[byte(TagOfPayloadFrame)]
+		if !bytes.Equal(checksumBlock.ToBytes(), checksumOf(meta.GetRawBytes(), payload.GetRawBytes())) {
+			return nil, ErrChecksumMismatch
+		}
+		data.checksum = true
+	}
+
 	return data, nil
 }
+
+// checksumOf returns the big-endian CRC32C of the encoded MetaFrame and PayloadFrame.
+func checksumOf(meta []byte, payload []byte) []byte {
+	crc := crc32.Update(crc32.Checksum(meta, castagnoli), castagnoli, payload)
+	buf := make([]byte, 4)
+	binary.BigEndian.PutUint32(buf, crc)
+	return buf
+}
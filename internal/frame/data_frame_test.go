@@ -32,3 +32,32 @@ func TestDataFrameDecode(t *testing.T) {
 	assert.EqualValues(t, userDataTag, data.GetDataTagID())
 	assert.EqualValues(t, []byte("yomo"), data.GetCarriage())
 }
+
+func TestDataFrameChecksum(t *testing.T) {
+	d := NewDataFrame("1234")
+	d.SetCarriage(0x15, []byte("yomo"))
+	d.SetChecksum(true)
+	buf := d.Encode()
+
+	data, err := DecodeToDataFrame(buf)
+	assert.NoError(t, err)
+	assert.True(t, data.HasChecksum())
+	assert.EqualValues(t, []byte("yomo"), data.GetCarriage())
+
+	// a byte of carriage is corrupted on the way.
+	buf[len(buf)-7] ^= 0xFF
+	_, err = DecodeToDataFrame(buf)
+	assert.Equal(t, ErrChecksumMismatch, err)
+}
+
+func TestDataFrameTags(t *testing.T) {
+	d := NewDataFrame("1234")
+	d.SetCarriage(0x15, []byte("yomo"))
+	assert.Equal(t, []byte{0x15}, d.Tags())
+
+	d.SetExtraTags(0x16, 0x15, 0x17)
+	data, err := DecodeToDataFrame(d.Encode())
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x16, 0x15, 0x17}, data.ExtraTags())
+	assert.Equal(t, []byte{0x15, 0x16, 0x17}, data.Tags())
+}
@@ -9,11 +9,31 @@ const (
 	TagOfPongFrame      FrameType = 0x3B
 	TagOfAcceptedFrame  FrameType = 0x3A
 	TagOfRejectedFrame  FrameType = 0x39
+	TagOfChunkFrame     FrameType = 0x38
+	TagOfControlFrame   FrameType = 0x37
+	TagOfGoodbyeFrame   FrameType = 0x36
+	TagOfQuotaFrame     FrameType = 0x35
 	TagOfMetaFrame      FrameType = 0x2F // in `DataFrame`
 	TagOfPayloadFrame   FrameType = 0x2E // in `DataFrame`
+	TagOfChecksum       FrameType = 0x2D // in `DataFrame`
 	TagOfTransactionID  FrameType = 0x01 // in `MetaFrame`
+	TagOfKeyID          FrameType = 0x02 // in `MetaFrame`
+	TagOfDeadline       FrameType = 0x03 // in `MetaFrame`
+	TagOfSequence       FrameType = 0x04 // in `MetaFrame`
+	TagOfCompression    FrameType = 0x05 // in `MetaFrame`
+	TagOfStreamed       FrameType = 0x06 // in `MetaFrame`
+	TagOfContentType    FrameType = 0x07 // in `MetaFrame`
+	TagOfSchemaID       FrameType = 0x08 // in `MetaFrame`
+	TagOfTimestamp      FrameType = 0x09 // in `MetaFrame`
+	TagOfHops           FrameType = 0x0A // in `MetaFrame`
+	TagOfTraceParent    FrameType = 0x0B // in `MetaFrame`
+	TagOfTraceState     FrameType = 0x0C // in `MetaFrame`
+	TagOfExtraTags      FrameType = 0x0D // in `MetaFrame`
 	TagOfHandshakeName  FrameType = 0x01 // in `HandshakeFrame`
 	TagOfHandshakeType  FrameType = 0x02 // in `HandshakeFrame`
+	TagOfHandshakeCodec FrameType = 0x03 // in `HandshakeFrame`
+	TagOfHandshakeToken FrameType = 0x05 // in `HandshakeFrame`
+	TagOfAcceptedCodec  FrameType = 0x01 // in `AcceptedFrame`
 )
 
 // FrameType represents the type of frame.
@@ -44,6 +64,14 @@ func (f FrameType) String() string {
 		return "AcceptedFrame"
 	case TagOfRejectedFrame:
 		return "RejectedFrame"
+	case TagOfChunkFrame:
+		return "ChunkFrame"
+	case TagOfControlFrame:
+		return "ControlFrame"
+	case TagOfGoodbyeFrame:
+		return "GoodbyeFrame"
+	case TagOfQuotaFrame:
+		return "QuotaFrame"
 	case TagOfMetaFrame:
 		return "MetaFrame"
 	case TagOfPayloadFrame:
@@ -0,0 +1,59 @@
+package frame
+
+import "github.com/yomorun/y3"
+
+// TagOfGoodbyeReason is the tag of reason in `GoodbyeFrame`.
+const TagOfGoodbyeReason FrameType = 0x01
+
+// GoodbyeFrame is sent by the clients before disconnecting, YoMo-Zipper removes the client from the dispatch
+// pool at once instead of discovering the departure by the later write errors.
+type GoodbyeFrame struct {
+	// Reason is why the client departs, it's for logging.
+	Reason string
+}
+
+// NewGoodbyeFrame creates a new GoodbyeFrame with the reason.
+func NewGoodbyeFrame(reason string) *GoodbyeFrame {
+	return &GoodbyeFrame{Reason: reason}
+}
+
+// Type gets the type of Frame.
+func (g *GoodbyeFrame) Type() FrameType {
+	return TagOfGoodbyeFrame
+}
+
+// Encode to Y3 encoded bytes.
+func (g *GoodbyeFrame) Encode() []byte {
+	goodbye := y3.NewNodePacketEncoder(byte(g.Type()))
+
+	if g.Reason != "" {
+		reasonBlock := y3.NewPrimitivePacketEncoder(byte(TagOfGoodbyeReason))
+		reasonBlock.SetStringValue(g.Reason)
+		goodbye.AddPrimitivePacket(reasonBlock)
+	} else {
+		goodbye.AddBytes(nil)
+	}
+
+	return goodbye.Encode()
+}
+
+// DecodeToGoodbyeFrame decodes Y3 encoded bytes to GoodbyeFrame.
+func DecodeToGoodbyeFrame(buf []byte) (*GoodbyeFrame, error) {
+	node := y3.NodePacket{}
+	_, err := y3.DecodeToNodePacket(buf, &node)
+	if err != nil {
+		return nil, err
+	}
+
+	goodbye := &GoodbyeFrame{}
+
+	if reasonBlock, ok := node.PrimitivePackets[byte(TagOfGoodbyeReason)]; ok {
+		reason, err := reasonBlock.ToUTF8String()
+		if err != nil {
+			return nil, err
+		}
+		goodbye.Reason = reason
+	}
+
+	return goodbye, nil
+}
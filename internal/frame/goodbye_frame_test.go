@@ -0,0 +1,20 @@
+package frame
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGoodbyeFrameEncodeAndDecode(t *testing.T) {
+	f := NewGoodbyeFrame("shutdown")
+	assert.Equal(t, TagOfGoodbyeFrame, f.Type())
+
+	goodbye, err := DecodeToGoodbyeFrame(f.Encode())
+	assert.NoError(t, err)
+	assert.Equal(t, "shutdown", goodbye.Reason)
+
+	goodbye, err = DecodeToGoodbyeFrame(NewGoodbyeFrame("").Encode())
+	assert.NoError(t, err)
+	assert.Empty(t, goodbye.Reason)
+}
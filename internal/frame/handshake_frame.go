@@ -1,6 +1,8 @@
 package frame
 
 import (
+	"strings"
+
 	"github.com/yomorun/y3"
 )
 
@@ -10,6 +12,12 @@ type HandshakeFrame struct {
 	Name string
 	// ClientType represents client type (source or sfn)
 	ClientType byte
+	// Codecs are the names of compression codecs which the client supports, in the order of preference
+	Codecs []string
+	// Version is the version of wire protocol of the client, 0 means the client is before the version negotiation
+	Version uint32
+	// Token is the credential which authenticates the client, it's empty if the client isn't authenticated by token
+	Token string
 }
 
 // NewHandshakeFrame creates a new HandshakeFrame.
@@ -37,6 +45,24 @@ func (h *HandshakeFrame) Encode() []byte {
 	handshake.AddPrimitivePacket(nameBlock)
 	handshake.AddPrimitivePacket(typeBlock)
 
+	if len(h.Codecs) > 0 {
+		codecBlock := y3.NewPrimitivePacketEncoder(byte(TagOfHandshakeCodec))
+		codecBlock.SetStringValue(strings.Join(h.Codecs, ","))
+		handshake.AddPrimitivePacket(codecBlock)
+	}
+
+	if h.Version > 0 {
+		versionBlock := y3.NewPrimitivePacketEncoder(byte(TagOfHandshakeVersion))
+		versionBlock.SetUInt32Value(h.Version)
+		handshake.AddPrimitivePacket(versionBlock)
+	}
+
+	if h.Token != "" {
+		tokenBlock := y3.NewPrimitivePacketEncoder(byte(TagOfHandshakeToken))
+		tokenBlock.SetStringValue(h.Token)
+		handshake.AddPrimitivePacket(tokenBlock)
+	}
+
 	return handshake.Encode()
 }
 
@@ -63,5 +89,31 @@ func DecodeToHandshakeFrame(buf []byte) (*HandshakeFrame, error) {
 		handshake.ClientType = clientType[0]
 	}
 
+	if codecBlock, ok := node.PrimitivePackets[byte(TagOfHandshakeCodec)]; ok {
+		codecs, err := codecBlock.ToUTF8String()
+		if err != nil {
+			return nil, err
+		}
+		if codecs != "" {
+			handshake.Codecs = strings.Split(codecs, ",")
+		}
+	}
+
+	if versionBlock, ok := node.PrimitivePackets[byte(TagOfHandshakeVersion)]; ok {
+		version, err := versionBlock.ToUInt32()
+		if err != nil {
+			return nil, err
+		}
+		handshake.Version = version
+	}
+
+	if tokenBlock, ok := node.PrimitivePackets[byte(TagOfHandshakeToken)]; ok {
+		token, err := tokenBlock.ToUTF8String()
+		if err != nil {
+			return nil, err
+		}
+		handshake.Token = token
+	}
+
 	return handshake, nil
 }
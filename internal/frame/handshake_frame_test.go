@@ -20,3 +20,21 @@ func TestHandshakeFrameEncode(t *testing.T) {
 	assert.EqualValues(t, expectedName, Handshake.Name)
 	assert.EqualValues(t, expectedType, Handshake.ClientType)
 }
+
+func TestHandshakeFrameWithCodecs(t *testing.T) {
+	m := NewHandshakeFrame("1234", 0xD3)
+	m.Codecs = []string{"zstd", "gzip"}
+
+	handshake, err := DecodeToHandshakeFrame(m.Encode())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"zstd", "gzip"}, handshake.Codecs)
+}
+
+func TestHandshakeFrameWithToken(t *testing.T) {
+	m := NewHandshakeFrame("1234", 0xD3)
+	m.Token = "secret"
+
+	handshake, err := DecodeToHandshakeFrame(m.Encode())
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", handshake.Token)
+}
@@ -1,12 +1,26 @@
 package frame
 
 import (
+	"time"
+
 	"github.com/yomorun/y3"
 )
 
 // MetaFrame defines the data structure of meta data in a `DataFrame`
 type MetaFrame struct {
 	transactionID string
+	keyID         string
+	deadline      int64  // the unix milliseconds of deadline, 0 means no deadline.
+	sequence      uint64 // the sequence of frame in the source, 0 means no sequence.
+	compression   byte   // the ID of codec which compressed the carriage, 0 means not compressed.
+	streamed      bool   // the carriage follows the frame in the stream instead of being in the frame.
+	contentType   string // the content type of carriage, e.g. "application/json", it's raw bytes if empty.
+	schemaID      string // the ID of schema which the carriage conforms to.
+	timestamp     int64  // the unix nanoseconds when the source created the frame, 0 means not stamped.
+	hops          uint32 // the count of YoMo-Zippers which the frame passed through.
+	traceParent   string // the W3C traceparent of the span which the frame belongs to.
+	traceState    string // the W3C tracestate of the span which the frame belongs to.
+	extraTags     []byte // the data tags which observe the carriage besides the tag of payload.
 }
 
 // NewMetaFrame creates a new MetaFrame with a given transactionID
@@ -21,6 +35,133 @@ func (m *MetaFrame) TransactionID() string {
 	return m.transactionID
 }
 
+// KeyID returns the ID of the key which is used to sign/encrypt the payload
+func (m *MetaFrame) KeyID() string {
+	return m.keyID
+}
+
+// SetKeyID sets the ID of the key which is used to sign/encrypt the payload
+func (m *MetaFrame) SetKeyID(keyID string) {
+	m.keyID = keyID
+}
+
+// Deadline returns the deadline of the frame, ok is false when no deadline is set
+func (m *MetaFrame) Deadline() (deadline time.Time, ok bool) {
+	if m.deadline == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(0, m.deadline*int64(time.Millisecond)), true
+}
+
+// SetDeadline sets the deadline of the frame, the handler should give up processing after the deadline
+func (m *MetaFrame) SetDeadline(deadline time.Time) {
+	m.deadline = deadline.UnixNano() / int64(time.Millisecond)
+}
+
+// Sequence returns the sequence of the frame in the source, ok is false when no sequence is set
+func (m *MetaFrame) Sequence() (sequence uint64, ok bool) {
+	return m.sequence, m.sequence != 0
+}
+
+// SetSequence sets the sequence of the frame in the source, it starts from 1
+func (m *MetaFrame) SetSequence(sequence uint64) {
+	m.sequence = sequence
+}
+
+// Compression returns the ID of codec which compressed the carriage, 0 means the carriage is not compressed
+func (m *MetaFrame) Compression() byte {
+	return m.compression
+}
+
+// SetCompression sets the ID of codec which compressed the carriage
+func (m *MetaFrame) SetCompression(codec byte) {
+	m.compression = codec
+}
+
+// Streamed returns true if the carriage follows the frame in the stream until the end of stream
+func (m *MetaFrame) Streamed() bool {
+	return m.streamed
+}
+
+// SetStreamed sets if the carriage follows the frame in the stream
+func (m *MetaFrame) SetStreamed(streamed bool) {
+	m.streamed = streamed
+}
+
+// ContentType returns the content type of the carriage, the carriage is raw bytes if it's empty
+func (m *MetaFrame) ContentType() string {
+	return m.contentType
+}
+
+// SetContentType sets the content type of the carriage
+func (m *MetaFrame) SetContentType(contentType string) {
+	m.contentType = contentType
+}
+
+// SchemaID returns the ID of schema which the carriage conforms to
+func (m *MetaFrame) SchemaID() string {
+	return m.schemaID
+}
+
+// SetSchemaID sets the ID of schema which the carriage conforms to
+func (m *MetaFrame) SetSchemaID(schemaID string) {
+	m.schemaID = schemaID
+}
+
+// Timestamp returns the time when the source created the frame, ok is false when the frame is not stamped
+func (m *MetaFrame) Timestamp() (timestamp time.Time, ok bool) {
+	if m.timestamp == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(0, m.timestamp), true
+}
+
+// SetTimestamp sets the time when the source created the frame
+func (m *MetaFrame) SetTimestamp(timestamp time.Time) {
+	m.timestamp = timestamp.UnixNano()
+}
+
+// Hops returns the count of YoMo-Zippers which the frame passed through
+func (m *MetaFrame) Hops() uint32 {
+	return m.hops
+}
+
+// IncrHops increases the count of YoMo-Zippers which the frame passed through, the new count is returned
+func (m *MetaFrame) IncrHops() uint32 {
+	m.hops++
+	return m.hops
+}
+
+// TraceParent returns the W3C traceparent of the frame, it's empty if the frame is not traced
+func (m *MetaFrame) TraceParent() string {
+	return m.traceParent
+}
+
+// SetTraceParent sets the W3C traceparent of the frame
+func (m *MetaFrame) SetTraceParent(traceParent string) {
+	m.traceParent = traceParent
+}
+
+// TraceState returns the W3C tracestate of the frame
+func (m *MetaFrame) TraceState() string {
+	return m.traceState
+}
+
+// SetTraceState sets the W3C tracestate of the frame
+func (m *MetaFrame) SetTraceState(traceState string) {
+	m.traceState = traceState
+}
+
+// ExtraTags returns the data tags which observe the carriage besides the tag of payload
+func (m *MetaFrame) ExtraTags() []byte {
+	return m.extraTags
+}
+
+// SetExtraTags sets the data tags which observe the carriage besides the tag of payload
+func (m *MetaFrame) SetExtraTags(tags []byte) {
+	m.extraTags = tags
+}
+
 // Encode returns Y3 encoded bytes of the MetaFrame
 func (m *MetaFrame) Encode() []byte {
 	metaNode := y3.NewNodePacketEncoder(byte(TagOfMetaFrame))
@@ -29,6 +170,78 @@ func (m *MetaFrame) Encode() []byte {
 	tidPacket.SetStringValue(m.transactionID)
 	// add TransactionID to MetaFrame
 	metaNode.AddPrimitivePacket(tidPacket)
+	// KeyID string, only presents when the payload is signed/encrypted
+	if m.keyID != "" {
+		kidPacket := y3.NewPrimitivePacketEncoder(byte(TagOfKeyID))
+		kidPacket.SetStringValue(m.keyID)
+		metaNode.AddPrimitivePacket(kidPacket)
+	}
+	// Deadline int64, only presents when the deadline is set
+	if m.deadline != 0 {
+		deadlinePacket := y3.NewPrimitivePacketEncoder(byte(TagOfDeadline))
+		deadlinePacket.SetInt64Value(m.deadline)
+		metaNode.AddPrimitivePacket(deadlinePacket)
+	}
+	// Sequence uint64, only presents when the sequence is set
+	if m.sequence != 0 {
+		sequencePacket := y3.NewPrimitivePacketEncoder(byte(TagOfSequence))
+		sequencePacket.SetUInt64Value(m.sequence)
+		metaNode.AddPrimitivePacket(sequencePacket)
+	}
+	// Compression byte, only presents when the carriage is compressed
+	if m.compression != 0 {
+		compressionPacket := y3.NewPrimitivePacketEncoder(byte(TagOfCompression))
+		compressionPacket.SetBytesValue([]byte{m.compression})
+		metaNode.AddPrimitivePacket(compressionPacket)
+	}
+	// Streamed bool, only presents when the carriage follows the frame
+	if m.streamed {
+		streamedPacket := y3.NewPrimitivePacketEncoder(byte(TagOfStreamed))
+		streamedPacket.SetBoolValue(true)
+		metaNode.AddPrimitivePacket(streamedPacket)
+	}
+	// ContentType string, only presents when the content type is set
+	if m.contentType != "" {
+		contentTypePacket := y3.NewPrimitivePacketEncoder(byte(TagOfContentType))
+		contentTypePacket.SetStringValue(m.contentType)
+		metaNode.AddPrimitivePacket(contentTypePacket)
+	}
+	// SchemaID string, only presents when the schema is set
+	if m.schemaID != "" {
+		schemaPacket := y3.NewPrimitivePacketEncoder(byte(TagOfSchemaID))
+		schemaPacket.SetStringValue(m.schemaID)
+		metaNode.AddPrimitivePacket(schemaPacket)
+	}
+	// Timestamp int64, only presents when the frame is stamped
+	if m.timestamp != 0 {
+		timestampPacket := y3.NewPrimitivePacketEncoder(byte(TagOfTimestamp))
+		timestampPacket.SetInt64Value(m.timestamp)
+		metaNode.AddPrimitivePacket(timestampPacket)
+	}
+	// Hops uint32, only presents when the frame passed through YoMo-Zippers
+	if m.hops != 0 {
+		hopsPacket := y3.NewPrimitivePacketEncoder(byte(TagOfHops))
+		hopsPacket.SetUInt32Value(m.hops)
+		metaNode.AddPrimitivePacket(hopsPacket)
+	}
+	// TraceParent string, only presents when the frame is traced
+	if m.traceParent != "" {
+		traceParentPacket := y3.NewPrimitivePacketEncoder(byte(TagOfTraceParent))
+		traceParentPacket.SetStringValue(m.traceParent)
+		metaNode.AddPrimitivePacket(traceParentPacket)
+	}
+	// TraceState string, only presents when the vendors set it
+	if m.traceState != "" {
+		traceStatePacket := y3.NewPrimitivePacketEncoder(byte(TagOfTraceState))
+		traceStatePacket.SetStringValue(m.traceState)
+		metaNode.AddPrimitivePacket(traceStatePacket)
+	}
+	// ExtraTags []byte, only presents when the carriage is observed by several tags
+	if len(m.extraTags) > 0 {
+		extraTagsPacket := y3.NewPrimitivePacketEncoder(byte(TagOfExtraTags))
+		extraTagsPacket.SetBytesValue(m.extraTags)
+		metaNode.AddPrimitivePacket(extraTagsPacket)
+	}
 
 	return metaNode.Encode()
 }
@@ -50,8 +263,112 @@ func DecodeToMetaFrame(buf []byte) (*MetaFrame, error) {
 		}
 	}
 
+	var kid string
+	if s, ok := packet.PrimitivePackets[byte(TagOfKeyID)]; ok {
+		kid, err = s.ToUTF8String()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var deadline int64
+	if s, ok := packet.PrimitivePackets[byte(TagOfDeadline)]; ok {
+		deadline, err = s.ToInt64()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var sequence uint64
+	if s, ok := packet.PrimitivePackets[byte(TagOfSequence)]; ok {
+		sequence, err = s.ToUInt64()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var compression byte
+	if c, ok := packet.PrimitivePackets[byte(TagOfCompression)]; ok {
+		if b := c.ToBytes(); len(b) > 0 {
+			compression = b[0]
+		}
+	}
+
+	var streamed bool
+	if s, ok := packet.PrimitivePackets[byte(TagOfStreamed)]; ok {
+		streamed, err = s.ToBool()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var contentType string
+	if s, ok := packet.PrimitivePackets[byte(TagOfContentType)]; ok {
+		contentType, err = s.ToUTF8String()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var schemaID string
+	if s, ok := packet.PrimitivePackets[byte(TagOfSchemaID)]; ok {
+		schemaID, err = s.ToUTF8String()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var timestamp int64
+	if s, ok := packet.PrimitivePackets[byte(TagOfTimestamp)]; ok {
+		timestamp, err = s.ToInt64()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var hops uint32
+	if s, ok := packet.PrimitivePackets[byte(TagOfHops)]; ok {
+		hops, err = s.ToUInt32()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var traceParent string
+	if s, ok := packet.PrimitivePackets[byte(TagOfTraceParent)]; ok {
+		traceParent, err = s.ToUTF8String()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var traceState string
+	if s, ok := packet.PrimitivePackets[byte(TagOfTraceState)]; ok {
+		traceState, err = s.ToUTF8String()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var extraTags []byte
+	if s, ok := packet.PrimitivePackets[byte(TagOfExtraTags)]; ok {
+		extraTags = s.ToBytes()
+	}
+
 	meta := &MetaFrame{
 		transactionID: tid,
+		keyID:         kid,
+		deadline:      deadline,
+		sequence:      sequence,
+		compression:   compression,
+		streamed:      streamed,
+		contentType:   contentType,
+		schemaID:      schemaID,
+		timestamp:     timestamp,
+		hops:          hops,
+		traceParent:   traceParent,
+		traceState:    traceState,
+		extraTags:     extraTags,
 	}
 	return meta, nil
 }
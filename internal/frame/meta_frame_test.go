@@ -2,6 +2,7 @@ package frame
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -17,3 +18,87 @@ func TestMetaFrameDecode(t *testing.T) {
 	assert.NoError(t, err)
 	assert.EqualValues(t, "1234", meta.TransactionID())
 }
+
+func TestMetaFrameWithKeyID(t *testing.T) {
+	m := NewMetaFrame("1234")
+	m.SetKeyID("k1")
+	buf := m.Encode()
+	assert.Equal(t, []byte{0x80 | byte(TagOfMetaFrame), 0x0A,
+		byte(TagOfTransactionID), 0x04, 0x31, 0x32, 0x33, 0x34,
+		byte(TagOfKeyID), 0x02, 0x6B, 0x31}, buf)
+
+	meta, err := DecodeToMetaFrame(buf)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "1234", meta.TransactionID())
+	assert.EqualValues(t, "k1", meta.KeyID())
+}
+
+func TestMetaFrameWithDeadline(t *testing.T) {
+	m := NewMetaFrame("1234")
+	_, ok := m.Deadline()
+	assert.False(t, ok)
+
+	deadline := time.Unix(1630000000, 123000000)
+	m.SetDeadline(deadline)
+
+	meta, err := DecodeToMetaFrame(m.Encode())
+	assert.NoError(t, err)
+	assert.EqualValues(t, "1234", meta.TransactionID())
+	got, ok := meta.Deadline()
+	assert.True(t, ok)
+	assert.True(t, deadline.Equal(got))
+}
+
+func TestMetaFrameWithSequence(t *testing.T) {
+	m := NewMetaFrame("1234")
+	_, ok := m.Sequence()
+	assert.False(t, ok)
+
+	m.SetSequence(42)
+	meta, err := DecodeToMetaFrame(m.Encode())
+	assert.NoError(t, err)
+	sequence, ok := meta.Sequence()
+	assert.True(t, ok)
+	assert.Equal(t, uint64(42), sequence)
+}
+
+func TestMetaFrameWithContentType(t *testing.T) {
+	m := NewMetaFrame("1234")
+	m.SetContentType("application/json")
+	m.SetSchemaID("noise-v1")
+
+	meta, err := DecodeToMetaFrame(m.Encode())
+	assert.NoError(t, err)
+	assert.Equal(t, "application/json", meta.ContentType())
+	assert.Equal(t, "noise-v1", meta.SchemaID())
+}
+
+func TestMetaFrameWithTimestampAndHops(t *testing.T) {
+	m := NewMetaFrame("1234")
+	_, ok := m.Timestamp()
+	assert.False(t, ok)
+	assert.Equal(t, uint32(0), m.Hops())
+
+	now := time.Now()
+	m.SetTimestamp(now)
+	assert.Equal(t, uint32(1), m.IncrHops())
+	assert.Equal(t, uint32(2), m.IncrHops())
+
+	meta, err := DecodeToMetaFrame(m.Encode())
+	assert.NoError(t, err)
+	timestamp, ok := meta.Timestamp()
+	assert.True(t, ok)
+	assert.Equal(t, now.UnixNano(), timestamp.UnixNano())
+	assert.Equal(t, uint32(2), meta.Hops())
+}
+
+func TestMetaFrameWithTraceContext(t *testing.T) {
+	m := NewMetaFrame("1234")
+	m.SetTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	m.SetTraceState("yomo=1")
+
+	meta, err := DecodeToMetaFrame(m.Encode())
+	assert.NoError(t, err)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", meta.TraceParent())
+	assert.Equal(t, "yomo=1", meta.TraceState())
+}
@@ -0,0 +1,94 @@
+package frame
+
+import (
+	"time"
+
+	"github.com/yomorun/y3"
+)
+
+// The tags of fields in `QuotaFrame`.
+const (
+	TagOfQuotaTransactionID FrameType = 0x01
+	TagOfQuotaName          FrameType = 0x02
+	TagOfQuotaRetryAfter    FrameType = 0x03
+)
+
+// QuotaFrame is sent by YoMo-Zipper to the source whose data is dropped because the quota of its tenant is
+// exceeded, so the source can back off instead of losing the data silently.
+type QuotaFrame struct {
+	// TransactionID is the transaction ID of the dropped data.
+	TransactionID string
+	// Quota is the name of exceeded quota, e.g. "frames_per_second" or "bytes_per_day".
+	Quota string
+	// RetryAfter is when the quota is available again, it's 0 if YoMo-Zipper doesn't tell it.
+	RetryAfter time.Duration
+}
+
+// NewQuotaFrame creates a new QuotaFrame of the dropped data.
+func NewQuotaFrame(transactionID string, quota string, retryAfter time.Duration) *QuotaFrame {
+	return &QuotaFrame{
+		TransactionID: transactionID,
+		Quota:         quota,
+		RetryAfter:    retryAfter,
+	}
+}
+
+// Type gets the type of Frame.
+func (q *QuotaFrame) Type() FrameType {
+	return TagOfQuotaFrame
+}
+
+// Encode to Y3 encoded bytes.
+func (q *QuotaFrame) Encode() []byte {
+	quota := y3.NewNodePacketEncoder(byte(q.Type()))
+
+	tidBlock := y3.NewPrimitivePacketEncoder(byte(TagOfQuotaTransactionID))
+	tidBlock.SetStringValue(q.TransactionID)
+	quota.AddPrimitivePacket(tidBlock)
+
+	nameBlock := y3.NewPrimitivePacketEncoder(byte(TagOfQuotaName))
+	nameBlock.SetStringValue(q.Quota)
+	quota.AddPrimitivePacket(nameBlock)
+
+	// RetryAfter int64 in milliseconds, only presents when it's told
+	if q.RetryAfter > 0 {
+		retryBlock := y3.NewPrimitivePacketEncoder(byte(TagOfQuotaRetryAfter))
+		retryBlock.SetInt64Value(q.RetryAfter.Milliseconds())
+		quota.AddPrimitivePacket(retryBlock)
+	}
+
+	return quota.Encode()
+}
+
+// DecodeToQuotaFrame decodes Y3 encoded bytes to QuotaFrame.
+func DecodeToQuotaFrame(buf []byte) (*QuotaFrame, error) {
+	node := y3.NodePacket{}
+	_, err := y3.DecodeToNodePacket(buf, &node)
+	if err != nil {
+		return nil, err
+	}
+
+	quota := &QuotaFrame{}
+
+	if tidBlock, ok := node.PrimitivePackets[byte(TagOfQuotaTransactionID)]; ok {
+		if quota.TransactionID, err = tidBlock.ToUTF8String(); err != nil {
+			return nil, err
+		}
+	}
+
+	if nameBlock, ok := node.PrimitivePackets[byte(TagOfQuotaName)]; ok {
+		if quota.Quota, err = nameBlock.ToUTF8String(); err != nil {
+			return nil, err
+		}
+	}
+
+	if retryBlock, ok := node.PrimitivePackets[byte(TagOfQuotaRetryAfter)]; ok {
+		retryAfter, err := retryBlock.ToInt64()
+		if err != nil {
+			return nil, err
+		}
+		quota.RetryAfter = time.Duration(retryAfter) * time.Millisecond
+	}
+
+	return quota, nil
+}
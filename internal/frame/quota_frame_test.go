@@ -0,0 +1,22 @@
+package frame
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuotaFrameEncodeAndDecode(t *testing.T) {
+	f := NewQuotaFrame("tid", "frames_per_second", 250*time.Millisecond)
+	assert.Equal(t, TagOfQuotaFrame, f.Type())
+
+	quota, err := DecodeToQuotaFrame(f.Encode())
+	assert.NoError(t, err)
+	assert.Equal(t, f, quota)
+
+	quota, err = DecodeToQuotaFrame(NewQuotaFrame("tid", "bytes_per_day", 0).Encode())
+	assert.NoError(t, err)
+	assert.Equal(t, "bytes_per_day", quota.Quota)
+	assert.Zero(t, quota.RetryAfter)
+}
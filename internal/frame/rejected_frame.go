@@ -2,8 +2,54 @@ package frame
 
 import "github.com/yomorun/y3"
 
+// RejectCode is the reason of rejection, so the client can tell the unauthenticated credential from the other
+// reasons without parsing the message.
+type RejectCode byte
+
+// The reasons of rejection.
+const (
+	// RejectUnknown is the rejection without the code, e.g. by the zippers before the codes.
+	RejectUnknown RejectCode = iota
+	// RejectVersion means the protocol version of client is not supported.
+	RejectVersion
+	// RejectNotAllowed means the name of client is not allowed by the workflow.
+	RejectNotAllowed
+	// RejectUnauthenticated means the credential of client is missing or invalid.
+	RejectUnauthenticated
+	// RejectUnauthorized means the authenticated client is not allowed to connect as its name by the policies.
+	RejectUnauthorized
+	// RejectQuotaExceeded means the max connections of the tenant of client are reached.
+	RejectQuotaExceeded
+)
+
+// TagOfRejectedCode is the tag of the code of rejection.
+const TagOfRejectedCode FrameType = 0x02 // in `RejectedFrame`
+
+// String returns the name of code.
+func (c RejectCode) String() string {
+	switch c {
+	case RejectVersion:
+		return "unsupported version"
+	case RejectNotAllowed:
+		return "not allowed"
+	case RejectUnauthenticated:
+		return "unauthenticated"
+	case RejectUnauthorized:
+		return "unauthorized"
+	case RejectQuotaExceeded:
+		return "quota exceeded"
+	default:
+		return "unknown"
+	}
+}
+
 // RejectedFrame is a Y3 encoded bytes, Tag is a fixed value TYPE_ID_REJECTED_FRAME
-type RejectedFrame struct{}
+type RejectedFrame struct {
+	// Message is the reason of rejection, it's empty if the reason is not given
+	Message string
+	// Code is the typed reason of rejection, it's RejectUnknown if the reason is not given
+	Code RejectCode
+}
 
 // NewRejectedFrame creates a new RejectedFrame with a given TagID of user's data
 func NewRejectedFrame() *RejectedFrame {
@@ -18,7 +64,22 @@ func (m *RejectedFrame) Type() FrameType {
 // Encode to Y3 encoded bytes
 func (m *RejectedFrame) Encode() []byte {
 	rejected := y3.NewNodePacketEncoder(byte(m.Type()))
-	rejected.AddBytes(nil)
+	if m.Message == "" && m.Code == RejectUnknown {
+		rejected.AddBytes(nil)
+		return rejected.Encode()
+	}
+
+	if m.Message != "" {
+		messageBlock := y3.NewPrimitivePacketEncoder(byte(TagOfRejectedMessage))
+		messageBlock.SetStringValue(m.Message)
+		rejected.AddPrimitivePacket(messageBlock)
+	}
+
+	if m.Code != RejectUnknown {
+		codeBlock := y3.NewPrimitivePacketEncoder(byte(TagOfRejectedCode))
+		codeBlock.SetBytesValue([]byte{byte(m.Code)})
+		rejected.AddPrimitivePacket(codeBlock)
+	}
 
 	return rejected.Encode()
 }
@@ -30,5 +91,19 @@ func DecodeToRejectedFrame(buf []byte) (*RejectedFrame, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &RejectedFrame{}, nil
+
+	rejected := &RejectedFrame{}
+	if messageBlock, ok := nodeBlock.PrimitivePackets[byte(TagOfRejectedMessage)]; ok {
+		message, err := messageBlock.ToUTF8String()
+		if err != nil {
+			return nil, err
+		}
+		rejected.Message = message
+	}
+	if codeBlock, ok := nodeBlock.PrimitivePackets[byte(TagOfRejectedCode)]; ok {
+		if code := codeBlock.ToBytes(); len(code) == 1 {
+			rejected.Code = RejectCode(code[0])
+		}
+	}
+	return rejected, nil
 }
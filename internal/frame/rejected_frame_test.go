@@ -17,3 +17,18 @@ func TestRejectedFrameDecode(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, []byte{0x80 | byte(TagOfRejectedFrame), 0x00}, ping.Encode())
 }
+
+func TestRejectedFrameCode(t *testing.T) {
+	f := NewRejectedFrame()
+	f.Code = RejectUnauthenticated
+	rejected, err := DecodeToRejectedFrame(f.Encode())
+	assert.NoError(t, err)
+	assert.Equal(t, RejectUnauthenticated, rejected.Code)
+	assert.Equal(t, "", rejected.Message)
+	assert.Equal(t, "unauthenticated", rejected.Code.String())
+
+	f.Message = "the credential is missing or invalid"
+	rejected, err = DecodeToRejectedFrame(f.Encode())
+	assert.NoError(t, err)
+	assert.Equal(t, f, rejected)
+}
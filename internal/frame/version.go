@@ -0,0 +1,91 @@
+package frame
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The versions of the wire protocol, the version is increased when the layout of frames evolves.
+const (
+	// Version1 is the wire protocol before the version negotiation, the MetaFrame carries only the transaction ID.
+	Version1 uint32 = 1
+	// Version2 adds the fields of MetaFrame besides the transaction ID, the checksum of DataFrame, the ChunkFrame,
+	// ControlFrame and GoodbyeFrame, and the DataFrames of sources in datagrams and unidirectional streams.
+	Version2 uint32 = 2
+	// Version3 adds the QuotaFrame, which tells the source its data is dropped by the quota.
+	Version3 uint32 = 3
+	// Version is the current version of the wire protocol.
+	Version = Version3
+	// MinVersion is the oldest version of the wire protocol which is still supported, the peers of older versions
+	// are rejected in the handshake. The frames are downgraded for the peers of Version1, so it's still supported.
+	MinVersion = Version1
+)
+
+// The tags of the version negotiation.
+const (
+	TagOfHandshakeVersion FrameType = 0x04 // in `HandshakeFrame`
+	TagOfAcceptedVersion  FrameType = 0x02 // in `AcceptedFrame`
+	TagOfRejectedMessage  FrameType = 0x01 // in `RejectedFrame`
+)
+
+// ErrUnsupportedVersion is returned when a frame or a field can't be understood by the peer of negotiated version.
+var ErrUnsupportedVersion = errors.New("frame: not supported by the protocol version of peer")
+
+// NegotiateVersion returns the highest version supported by both the peer and us, an error is returned if the
+// version of peer is too old. The peers before the version negotiation are the version 1, which don't send
+// the version.
+func NegotiateVersion(peer uint32) (uint32, error) {
+	if peer == 0 {
+		peer = Version1
+	}
+	if peer < MinVersion {
+		return 0, fmt.Errorf("the protocol version %d is not supported, the supported versions are %d to %d", peer, MinVersion, Version)
+	}
+	if peer > Version {
+		return Version, nil
+	}
+	return peer, nil
+}
+
+// CheckAcceptedVersion returns the version accepted by YoMo-Zipper, an error is returned if it's not supported by
+// us, i.e. YoMo-Zipper doesn't negotiate the version by NegotiateVersion. The YoMo-Zippers before the version
+// negotiation are the version 1, which don't send the version.
+func CheckAcceptedVersion(accepted uint32) (uint32, error) {
+	if accepted == 0 {
+		return Version1, nil
+	}
+	if accepted < MinVersion || accepted > Version {
+		return 0, fmt.Errorf("the protocol version %d is not supported, the supported versions are %d to %d", accepted, MinVersion, Version)
+	}
+	return accepted, nil
+}
+
+// Supports reports if the frame type is understood by the peer of version, the version 0 means it's not
+// negotiated yet and the current version is assumed.
+func Supports(t FrameType, version uint32) bool {
+	switch t {
+	case TagOfChunkFrame, TagOfControlFrame, TagOfGoodbyeFrame:
+		return version == 0 || version >= Version2
+	case TagOfQuotaFrame:
+		return version == 0 || version >= Version3
+	}
+	return true
+}
+
+// Downgrade returns the DataFrame in the layout of version, the MetaFrame of Version1 carries only the transaction
+// ID and the DataFrame has no checksum. ErrUnsupportedVersion is returned if the carriage can't be read without
+// the dropped fields, i.e. it's compressed, encrypted or streamed. The frame is returned as is for the version 0.
+func Downgrade(d *DataFrame, version uint32) (*DataFrame, error) {
+	if version == 0 || version >= Version2 {
+		return d, nil
+	}
+	if d.Compression() != 0 || d.KeyID() != "" || d.Streamed() {
+		return nil, fmt.Errorf("%w: the carriage is compressed, encrypted or streamed", ErrUnsupportedVersion)
+	}
+
+	payload := *d.payloadFrame
+	return &DataFrame{
+		metaFrame:    NewMetaFrame(d.TransactionID()),
+		payloadFrame: &payload,
+	}, nil
+}
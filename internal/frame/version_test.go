@@ -0,0 +1,91 @@
+package frame
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiateVersion(t *testing.T) {
+	// the peer before the version negotiation.
+	version, err := NegotiateVersion(0)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1), version)
+
+	version, err = NegotiateVersion(Version)
+	assert.NoError(t, err)
+	assert.Equal(t, Version, version)
+
+	// the newer peer falls back to our version.
+	version, err = NegotiateVersion(Version + 1)
+	assert.NoError(t, err)
+	assert.Equal(t, Version, version)
+}
+
+func TestCheckAcceptedVersion(t *testing.T) {
+	// YoMo-Zipper before the version negotiation.
+	version, err := CheckAcceptedVersion(0)
+	assert.NoError(t, err)
+	assert.Equal(t, Version1, version)
+
+	version, err = CheckAcceptedVersion(Version)
+	assert.NoError(t, err)
+	assert.Equal(t, Version, version)
+
+	_, err = CheckAcceptedVersion(Version + 1)
+	assert.Error(t, err)
+}
+
+func TestDowngrade(t *testing.T) {
+	f := NewDataFrame("tid")
+	f.SetCarriage(0x10, []byte("yomo"))
+	f.SetContentType("application/json")
+	f.SetExtraTags(0x11)
+	f.SetChecksum(true)
+
+	same, err := Downgrade(f, Version)
+	assert.NoError(t, err)
+	assert.Same(t, f, same)
+
+	v1, err := Downgrade(f, Version1)
+	assert.NoError(t, err)
+	decoded, err := DecodeToDataFrame(v1.Encode())
+	assert.NoError(t, err)
+	assert.Equal(t, "tid", decoded.TransactionID())
+	assert.Equal(t, []byte("yomo"), decoded.GetCarriage())
+	assert.Empty(t, decoded.ContentType())
+	assert.Empty(t, decoded.ExtraTags())
+	assert.False(t, decoded.HasChecksum())
+	// the original frame isn't modified.
+	assert.Equal(t, "application/json", f.ContentType())
+
+	f.SetKeyID("key")
+	_, err = Downgrade(f, Version1)
+	assert.ErrorIs(t, err, ErrUnsupportedVersion)
+
+	assert.False(t, Supports(TagOfGoodbyeFrame, Version1))
+	assert.True(t, Supports(TagOfGoodbyeFrame, Version2))
+	assert.True(t, Supports(TagOfDataFrame, Version1))
+	assert.False(t, Supports(TagOfQuotaFrame, Version2))
+	assert.True(t, Supports(TagOfQuotaFrame, Version3))
+}
+
+func TestVersionInHandshake(t *testing.T) {
+	h := NewHandshakeFrame("1234", 0xD3)
+	h.Version = Version
+	handshake, err := DecodeToHandshakeFrame(h.Encode())
+	assert.NoError(t, err)
+	assert.Equal(t, Version, handshake.Version)
+
+	a := NewAcceptedFrame()
+	a.Version = Version
+	accepted, err := DecodeToAcceptedFrame(a.Encode())
+	assert.NoError(t, err)
+	assert.Equal(t, Version, accepted.Version)
+
+	r := NewRejectedFrame()
+	r.Message = "the protocol version 9 is not supported"
+	rejected, err := DecodeToRejectedFrame(r.Encode())
+	assert.NoError(t, err)
+	assert.Equal(t, r.Message, rejected.Message)
+}
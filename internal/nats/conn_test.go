@@ -0,0 +1,71 @@
+package nats
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/internal/nats/mock"
+)
+
+func TestConn(t *testing.T) {
+	server, err := mock.NewServer()
+	assert.NoError(t, err)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, err := Dial(ctx, server.URL(), Options{Name: "test"})
+	assert.NoError(t, err)
+	defer c.Close()
+
+	// the subscription of another connection responds the requests.
+	responder, err := Dial(ctx, server.URL(), Options{})
+	assert.NoError(t, err)
+	defer responder.Close()
+	requests := make(chan *Msg, 1)
+	_, err = responder.Subscribe("echo", requests)
+	assert.NoError(t, err)
+	go func() {
+		for msg := range requests {
+			responder.Publish(msg.Reply, "", nil, append([]byte("echo: "), msg.Data...))
+		}
+	}()
+	assert.Eventually(t, func() bool {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		msg, err := c.Request(ctx, "echo", nil, []byte("hi"))
+		return err == nil && string(msg.Data) == "echo: hi"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// the headers are published.
+	_, err = c.Request(ctx, "$JS.API.STREAM.CREATE.S", nil, []byte(`{"subjects":["s"]}`))
+	assert.NoError(t, err)
+	msg, err := c.Request(ctx, "s", map[string]string{"Nats-Msg-Id": "id-1"}, []byte("data"))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"stream":"mock","seq":1}`, string(msg.Data))
+	assert.Equal(t, "id-1", server.Messages()[0].ID)
+
+	// the status of headers is parsed.
+	_, err = c.Request(ctx, "$JS.API.CONSUMER.DURABLE.CREATE.S.D", nil, []byte(`{}`))
+	assert.NoError(t, err)
+	msg, err = c.Request(ctx, "$JS.API.CONSUMER.MSG.NEXT.S.D", nil, []byte(`{"batch":1}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "data", string(msg.Data))
+	msg, err = c.Request(ctx, "$JS.API.CONSUMER.MSG.NEXT.S.D", nil, []byte(`{"batch":1,"no_wait":true}`))
+	assert.NoError(t, err)
+	assert.Equal(t, 404, msg.Status)
+	assert.Equal(t, "No Messages", msg.Description)
+
+	assert.NoError(t, c.Close())
+	<-c.Done()
+	assert.ErrorIs(t, c.Publish("s", "", nil, nil), ErrClosed)
+}
+
+func TestDialRefused(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err := Dial(ctx, "nats://127.0.0.1:1", Options{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,328 @@
+// Package mock is a NATS server in memory for the tests, it implements the subset of JetStream used by
+// YoMo-Zipper: a stream, the durable and ephemeral pull consumers, the fetches and the acknowledgements.
+package mock
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message is a message stored in the stream.
+type Message struct {
+	Seq     uint64
+	Subject string
+	ID      string
+	Data    []byte
+	Time    time.Time
+}
+
+// Server is a NATS server in memory.
+type Server struct {
+	ln        net.Listener
+	mutex     sync.Mutex
+	subjects  []string
+	messages  []Message
+	consumers map[string]*consumer
+	acked     map[uint64]bool
+	subs      map[*client]map[string]string // the subjects of subscriptions by sid.
+	nextID    int
+}
+
+// consumer is a pull consumer, it delivers the messages after its cursor.
+type consumer struct {
+	cursor    int
+	startTime time.Time
+}
+
+type client struct {
+	conn  net.Conn
+	mutex sync.Mutex
+}
+
+func (c *client) write(s string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.conn.Write([]byte(s))
+}
+
+// NewServer starts a server on a random port of localhost.
+func NewServer() (*Server, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		ln:        ln,
+		consumers: make(map[string]*consumer),
+		acked:     make(map[uint64]bool),
+		subs:      make(map[*client]map[string]string),
+	}
+	go s.serve()
+	return s, nil
+}
+
+// URL returns the URL of server.
+func (s *Server) URL() string {
+	return "nats://" + s.ln.Addr().String()
+}
+
+// Close stops the server.
+func (s *Server) Close() error {
+	err := s.ln.Close()
+	s.mutex.Lock()
+	for c := range s.subs {
+		c.conn.Close()
+	}
+	s.mutex.Unlock()
+	return err
+}
+
+// Messages returns the messages in the stream.
+func (s *Server) Messages() []Message {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+// Acked returns the count of acknowledged messages.
+func (s *Server) Acked() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.acked)
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	c := &client{conn: conn}
+	s.mutex.Lock()
+	s.subs[c] = make(map[string]string)
+	s.mutex.Unlock()
+	defer func() {
+		s.mutex.Lock()
+		delete(s.subs, c)
+		s.mutex.Unlock()
+		conn.Close()
+	}()
+
+	c.write(`INFO {"server_id":"mock","version":"2.9.0","headers":true,"jetstream":true,"max_payload":1048576}` + "\r\n")
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		args := strings.Fields(line)
+		if len(args) == 0 {
+			continue
+		}
+		switch strings.ToUpper(args[0]) {
+		case "CONNECT":
+		case "PING":
+			c.write("PONG\r\n")
+		case "SUB":
+			s.mutex.Lock()
+			s.subs[c][args[len(args)-1]] = args[1]
+			s.mutex.Unlock()
+		case "UNSUB":
+			s.mutex.Lock()
+			delete(s.subs[c], args[1])
+			s.mutex.Unlock()
+		case "PUB", "HPUB":
+			hpub := strings.EqualFold(args[0], "HPUB")
+			size, _ := strconv.Atoi(args[len(args)-1])
+			buf := make([]byte, size+2)
+			if _, err := io.ReadFull(r, buf); err != nil {
+				return
+			}
+			reply := ""
+			if (hpub && len(args) == 5) || (!hpub && len(args) == 4) {
+				reply = args[2]
+			}
+			var header textproto.MIMEHeader
+			data := buf[:size]
+			if hpub {
+				hdrSize, _ := strconv.Atoi(args[len(args)-2])
+				tr := textproto.NewReader(bufio.NewReader(strings.NewReader(string(buf[:hdrSize]))))
+				tr.ReadLine()
+				header, _ = tr.ReadMIMEHeader()
+				data = buf[hdrSize:size]
+			}
+			s.publish(args[1], reply, header, data)
+		}
+	}
+}
+
+// publish handles the message published by a client.
+func (s *Server) publish(subject, reply string, header textproto.MIMEHeader, data []byte) {
+	switch {
+	case strings.HasPrefix(subject, "$JS.API.STREAM.CREATE."):
+		var req struct {
+			Subjects []string `json:"subjects"`
+		}
+		json.Unmarshal(data, &req)
+		s.mutex.Lock()
+		s.subjects = req.Subjects
+		s.mutex.Unlock()
+		s.reply(reply, "", `{"type":"io.nats.jetstream.api.v1.stream_create_response"}`)
+	case strings.HasPrefix(subject, "$JS.API.CONSUMER.DURABLE.CREATE."):
+		name := subject[strings.LastIndexByte(subject, '.')+1:]
+		s.mutex.Lock()
+		if _, ok := s.consumers[name]; !ok {
+			s.consumers[name] = &consumer{}
+		}
+		s.mutex.Unlock()
+		s.reply(reply, "", `{"name":"`+name+`"}`)
+	case strings.HasPrefix(subject, "$JS.API.CONSUMER.CREATE."):
+		var req struct {
+			Config struct {
+				OptStartTime time.Time `json:"opt_start_time"`
+			} `json:"config"`
+		}
+		json.Unmarshal(data, &req)
+		s.mutex.Lock()
+		s.nextID++
+		name := "ephemeral-" + strconv.Itoa(s.nextID)
+		s.consumers[name] = &consumer{startTime: req.Config.OptStartTime}
+		s.mutex.Unlock()
+		s.reply(reply, "", `{"name":"`+name+`"}`)
+	case strings.HasPrefix(subject, "$JS.API.CONSUMER.DELETE."):
+		s.mutex.Lock()
+		delete(s.consumers, subject[strings.LastIndexByte(subject, '.')+1:])
+		s.mutex.Unlock()
+		s.reply(reply, "", `{"success":true}`)
+	case strings.HasPrefix(subject, "$JS.API.CONSUMER.MSG.NEXT."):
+		var req struct {
+			Batch   int           `json:"batch"`
+			Expires time.Duration `json:"expires"`
+			NoWait  bool          `json:"no_wait"`
+		}
+		json.Unmarshal(data, &req)
+		go s.fetch(subject[strings.LastIndexByte(subject, '.')+1:], reply, req.Batch, req.Expires, req.NoWait)
+	case strings.HasPrefix(subject, "$JS.ACK."):
+		tokens := strings.Split(subject, ".")
+		seq, _ := strconv.ParseUint(tokens[5], 10, 64)
+		s.mutex.Lock()
+		s.acked[seq] = true
+		s.mutex.Unlock()
+	case s.inStream(subject):
+		id := header.Get("Nats-Msg-Id")
+		s.mutex.Lock()
+		for _, m := range s.messages {
+			if id != "" && m.ID == id {
+				s.mutex.Unlock()
+				s.reply(reply, "", fmt.Sprintf(`{"stream":"mock","seq":%d,"duplicate":true}`, m.Seq))
+				return
+			}
+		}
+		seq := uint64(len(s.messages) + 1)
+		s.messages = append(s.messages, Message{Seq: seq, Subject: subject, ID: id, Data: data, Time: time.Now()})
+		s.mutex.Unlock()
+		s.reply(reply, "", fmt.Sprintf(`{"stream":"mock","seq":%d}`, seq))
+	default:
+		s.deliver(subject, reply, "", data)
+	}
+}
+
+func (s *Server) inStream(subject string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, sub := range s.subjects {
+		if sub == subject {
+			return true
+		}
+	}
+	return false
+}
+
+// fetch delivers the batch of messages of the consumer, it responds 404 if there's no message and noWait is set,
+// or 408 when it expires.
+func (s *Server) fetch(name, reply string, batch int, expires time.Duration, noWait bool) {
+	deadline := time.Now().Add(expires)
+	sent := 0
+	for {
+		s.mutex.Lock()
+		c, ok := s.consumers[name]
+		var pending []Message
+		if ok {
+			for c.cursor < len(s.messages) && sent+len(pending) < batch {
+				m := s.messages[c.cursor]
+				c.cursor++
+				if m.Time.Before(c.startTime) {
+					continue
+				}
+				pending = append(pending, m)
+			}
+		}
+		s.mutex.Unlock()
+		if !ok {
+			s.reply(reply, "NATS/1.0 409 Consumer Deleted\r\n\r\n", "")
+			return
+		}
+
+		for _, m := range pending {
+			ack := fmt.Sprintf("$JS.ACK.mock.%s.1.%d.%d.%d.0", name, m.Seq, m.Seq, m.Time.UnixNano())
+			s.deliver(reply, ack, "", m.Data)
+			sent++
+		}
+		if sent >= batch {
+			return
+		}
+		if sent == 0 && noWait {
+			s.reply(reply, "NATS/1.0 404 No Messages\r\n\r\n", "")
+			return
+		}
+		if time.Now().After(deadline) {
+			s.reply(reply, "NATS/1.0 408 Request Timeout\r\n\r\n", "")
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func (s *Server) reply(subject, header, data string) {
+	if subject == "" {
+		return
+	}
+	s.deliver(subject, "", header, []byte(data))
+}
+
+// deliver delivers the message to the subscriptions of subject, the wildcard "*" is supported at the last token.
+func (s *Server) deliver(subject, reply, header string, data []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for c, subs := range s.subs {
+		for sid, pattern := range subs {
+			if pattern != subject && !(strings.HasSuffix(pattern, ".*") &&
+				strings.HasPrefix(subject, pattern[:len(pattern)-1]) &&
+				!strings.Contains(subject[len(pattern)-1:], ".")) {
+				continue
+			}
+			args := subject + " " + sid + " "
+			if reply != "" {
+				args += reply + " "
+			}
+			if header == "" {
+				c.write(fmt.Sprintf("MSG %s%d\r\n%s\r\n", args, len(data), data))
+			} else {
+				c.write(fmt.Sprintf("HMSG %s%d %d\r\n%s%s\r\n", args, len(header), len(header)+len(data), header, data))
+			}
+		}
+	}
+}
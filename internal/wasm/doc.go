@@ -0,0 +1,8 @@
+// Package wasm runs the WebAssembly modules by wazero, it runs the stream functions compiled to WebAssembly in the
+// process of YoMo-Zipper, e.g. by Rust, TinyGo or AssemblyScript, without the network hop.
+//
+// The modules are decoded and validated by the WebAssembly 2.0 core specification when they're loaded, so the
+// invalid modules are rejected before any of their code runs. The modules are compiled once and shared by their
+// instances, and each instance runs in its own runtime which is isolated by the limit of its memory, a call is
+// interrupted when its context is done, so the faulty functions can't exhaust YoMo-Zipper.
+package wasm
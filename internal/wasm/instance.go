@@ -0,0 +1,182 @@
+package wasm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+var (
+	// ErrOutOfBounds is returned by the host functions when they access the memory out of bounds.
+	ErrOutOfBounds = errors.New("wasm: out of bounds memory access")
+	// ErrInterrupted is returned when the call is interrupted since its context is done.
+	ErrInterrupted = errors.New("wasm: interrupted")
+)
+
+const (
+	// DefaultMaxPages is the default max count of 64KB pages of the memory of instance, it's 16MB.
+	DefaultMaxPages = 256
+	// maxPages is the max count of pages of the 32-bit memory.
+	maxPages = 65536
+)
+
+// Config is the limits of instance.
+type Config struct {
+	// MaxPages is the max count of 64KB pages of memory, memory.grow fails beyond it, and the modules which require
+	// more memory can't be instantiated. The default is 256.
+	MaxPages uint32
+}
+
+// HostFunc is a function of the host imported by the module. The call traps if Fn returns an error, the error is
+// wrapped in the error returned by Call.
+type HostFunc struct {
+	Type FuncType
+	Fn   func(inst *Instance, args []uint64) ([]uint64, error)
+}
+
+// Imports are the host functions imported by modules, by the names of module and function.
+type Imports map[string]map[string]HostFunc
+
+// Instance is an instance of module in its own runtime, so it has its own memory and host functions. It can't be
+// called concurrently, and it should be discarded when a call traps, it's closed if the call is interrupted.
+type Instance struct {
+	module  *Module
+	runtime wazero.Runtime
+	guest   api.Module
+}
+
+// Instantiate creates an instance of the module, the data and elements are initialized, then the start function is
+// called.
+func Instantiate(ctx context.Context, m *Module, imports Imports, conf Config) (*Instance, error) {
+	if conf.MaxPages == 0 || conf.MaxPages > maxPages {
+		conf.MaxPages = DefaultMaxPages
+	}
+	inst := &Instance{module: m}
+	inst.runtime = wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithCompilationCache(cache).
+		WithMemoryLimitPages(conf.MaxPages).
+		WithCloseOnContextDone(true))
+
+	if err := inst.instantiate(ctx, imports); err != nil {
+		inst.Close()
+		return nil, err
+	}
+	return inst, nil
+}
+
+func (inst *Instance) instantiate(ctx context.Context, imports Imports) error {
+	hosts := make(map[string]wazero.HostModuleBuilder)
+	for _, imp := range inst.module.imports {
+		fn, ok := imports[imp.Module][imp.Name]
+		if !ok {
+			return fmt.Errorf("wasm: the import %s.%s is not found", imp.Module, imp.Name)
+		}
+		if !fn.Type.equal(imp.Type) {
+			return fmt.Errorf("wasm: the import %s.%s must be %v, not %v", imp.Module, imp.Name, imp.Type, fn.Type)
+		}
+		if hosts[imp.Module] == nil {
+			hosts[imp.Module] = inst.runtime.NewHostModuleBuilder(imp.Module)
+		}
+		hosts[imp.Module].NewFunctionBuilder().
+			WithGoModuleFunction(inst.hostFunc(fn), apiTypes(fn.Type.Params), apiTypes(fn.Type.Results)).
+			Export(imp.Name)
+	}
+	for _, host := range hosts {
+		if _, err := host.Instantiate(ctx); err != nil {
+			return fmt.Errorf("wasm: %w", err)
+		}
+	}
+
+	// the module is compiled in the runtime of instance, since its memory is validated by the limit of runtime.
+	compiled, err := inst.runtime.CompileModule(ctx, inst.module.buf)
+	if err != nil {
+		return fmt.Errorf("wasm: %w", err)
+	}
+	// only the start section is called, the WASI commands aren't run by "_start".
+	inst.guest, err = inst.runtime.InstantiateModule(ctx, compiled, wazero.NewModuleConfig().WithName("").WithStartFunctions())
+	return callError(err)
+}
+
+// hostFunc binds the host function to the instance.
+func (inst *Instance) hostFunc(fn HostFunc) api.GoModuleFunc {
+	return func(_ context.Context, guest api.Module, stack []uint64) {
+		// the start function calls the host functions before the guest is returned by the instantiation.
+		inst.guest = guest
+		args := append([]uint64(nil), stack[:len(fn.Type.Params)]...)
+		results, err := fn.Fn(inst, args)
+		if err != nil {
+			// the panic is recovered by wazero, the call traps with the error.
+			panic(err)
+		}
+		if len(results) != len(fn.Type.Results) {
+			panic(fmt.Errorf("wasm: the host function returns %d results, not %d", len(results), len(fn.Type.Results)))
+		}
+		copy(stack, results)
+	}
+}
+
+// Module returns the module of instance.
+func (inst *Instance) Module() *Module {
+	return inst.module
+}
+
+// Read returns a copy of the bytes of memory, it returns false if they're out of bounds.
+func (inst *Instance) Read(ptr, length uint32) ([]byte, bool) {
+	if inst.guest == nil || inst.guest.Memory() == nil {
+		return nil, false
+	}
+	buf, ok := inst.guest.Memory().Read(ptr, length)
+	if !ok {
+		return nil, false
+	}
+	return append([]byte{}, buf...), true
+}
+
+// Write writes the bytes to memory, it returns false if they're out of bounds.
+func (inst *Instance) Write(ptr uint32, buf []byte) bool {
+	if inst.guest == nil || inst.guest.Memory() == nil {
+		return false
+	}
+	return inst.guest.Memory().Write(ptr, buf)
+}
+
+// Call calls the exported function by the name, the args and results are the bits of values, i32 is in the low 32
+// bits, and the floats are their IEEE 754 bits. The call is interrupted when the context is done.
+func (inst *Instance) Call(ctx context.Context, name string, args ...uint64) ([]uint64, error) {
+	t, ok := inst.module.exports[name]
+	if !ok {
+		return nil, fmt.Errorf("wasm: the function %s is not exported", name)
+	}
+	if len(t.Params) != len(args) {
+		return nil, fmt.Errorf("wasm: the function %s requires %d args, not %d", name, len(t.Params), len(args))
+	}
+	results, err := inst.guest.ExportedFunction(name).Call(ctx, args...)
+	if err != nil {
+		return nil, callError(err)
+	}
+	// the high bits of the 32-bit results are undefined.
+	for i, typ := range t.Results {
+		if typ == I32 || typ == F32 {
+			results[i] = uint64(uint32(results[i]))
+		}
+	}
+	return results, nil
+}
+
+// Close closes the runtime of instance, it can't be called any more.
+func (inst *Instance) Close() error {
+	return inst.runtime.Close(context.Background())
+}
+
+// callError returns ErrInterrupted if the call is interrupted by its context, or the trap as it is.
+func callError(err error) error {
+	var exit *sys.ExitError
+	if errors.As(err, &exit) && (exit.ExitCode() == sys.ExitCodeContextCanceled || exit.ExitCode() == sys.ExitCodeDeadlineExceeded) {
+		return fmt.Errorf("%w: %v", ErrInterrupted, err)
+	}
+	return err
+}
@@ -0,0 +1,139 @@
+package wasm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+)
+
+// ValueType is the type of values.
+type ValueType byte
+
+// the value types.
+const (
+	I32 ValueType = ValueType(api.ValueTypeI32)
+	I64 ValueType = ValueType(api.ValueTypeI64)
+	F32 ValueType = ValueType(api.ValueTypeF32)
+	F64 ValueType = ValueType(api.ValueTypeF64)
+)
+
+func (t ValueType) String() string {
+	switch t {
+	case I32:
+		return "i32"
+	case I64:
+		return "i64"
+	case F32:
+		return "f32"
+	case F64:
+		return "f64"
+	default:
+		return fmt.Sprintf("type(%#x)", byte(t))
+	}
+}
+
+// FuncType is the signature of functions.
+type FuncType struct {
+	Params  []ValueType
+	Results []ValueType
+}
+
+func (t FuncType) equal(o FuncType) bool {
+	return equalTypes(t.Params, o.Params) && equalTypes(t.Results, o.Results)
+}
+
+func (t FuncType) String() string {
+	return fmt.Sprintf("%v -> %v", t.Params, t.Results)
+}
+
+func equalTypes(a, b []ValueType) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// funcType is the signature of the function defined by wazero.
+func funcType(def api.FunctionDefinition) FuncType {
+	return FuncType{Params: valueTypes(def.ParamTypes()), Results: valueTypes(def.ResultTypes())}
+}
+
+func valueTypes(types []api.ValueType) []ValueType {
+	results := make([]ValueType, 0, len(types))
+	for _, t := range types {
+		results = append(results, ValueType(t))
+	}
+	return results
+}
+
+func apiTypes(types []ValueType) []api.ValueType {
+	results := make([]api.ValueType, 0, len(types))
+	for _, t := range types {
+		results = append(results, api.ValueType(t))
+	}
+	return results
+}
+
+// Import is a function imported by the module.
+type Import struct {
+	Module string
+	Name   string
+	Type   FuncType
+}
+
+// cache keeps the compiled code of modules, so the runtimes of instances don't compile the module again.
+var cache = wazero.NewCompilationCache()
+
+// Module is a decoded, validated and compiled WebAssembly module, it's shared by its instances.
+type Module struct {
+	buf      []byte
+	compiled wazero.CompiledModule
+	imports  []Import
+	exports  map[string]FuncType
+}
+
+// Decode decodes, validates and compiles the binary module, the modules which are invalid by the specification are
+// rejected.
+func Decode(buf []byte) (*Module, error) {
+	ctx := context.Background()
+	r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().WithCompilationCache(cache))
+	// the compiled code is kept in the cache when the runtime is closed.
+	defer r.Close(ctx)
+
+	compiled, err := r.CompileModule(ctx, buf)
+	if err != nil {
+		return nil, fmt.Errorf("wasm: %w", err)
+	}
+	m := &Module{buf: buf, compiled: compiled, exports: make(map[string]FuncType)}
+	for _, def := range compiled.ImportedFunctions() {
+		module, name, _ := def.Import()
+		m.imports = append(m.imports, Import{Module: module, Name: name, Type: funcType(def)})
+	}
+	for name, def := range compiled.ExportedFunctions() {
+		m.exports[name] = funcType(def)
+	}
+	return m, nil
+}
+
+// Imports returns the functions imported by the module.
+func (m *Module) Imports() []Import {
+	return m.imports
+}
+
+// ExportedFunc returns the type of the exported function, it returns false if it's not exported.
+func (m *Module) ExportedFunc(name string) (FuncType, bool) {
+	t, ok := m.exports[name]
+	return t, ok
+}
+
+// Close drops the compiled code of module, the instances which are created keep working until they're closed.
+func (m *Module) Close() error {
+	return m.compiled.Close(context.Background())
+}
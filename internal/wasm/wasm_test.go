@@ -0,0 +1,423 @@
+package wasm_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/internal/wasm"
+	"github.com/yomorun/yomo/internal/wasm/wasmtest"
+)
+
+var (
+	i32  = wasm.I32
+	i64  = wasm.I64
+	f32  = wasm.F32
+	f64  = wasm.F64
+	void = []wasm.ValueType{}
+)
+
+func sig(params []wasm.ValueType, results ...wasm.ValueType) wasm.FuncType {
+	return wasm.FuncType{Params: params, Results: results}
+}
+
+func types(t ...wasm.ValueType) []wasm.ValueType {
+	return t
+}
+
+func instantiate(t *testing.T, m wasmtest.Module, conf wasm.Config, imports wasm.Imports) *wasm.Instance {
+	mod, err := wasm.Decode(m.Bytes())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	inst, err := wasm.Instantiate(context.Background(), mod, imports, conf)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return inst
+}
+
+func call(t *testing.T, inst *wasm.Instance, name string, args ...uint64) uint64 {
+	res, err := inst.Call(context.Background(), name, args...)
+	if !assert.NoError(t, err) || !assert.Len(t, res, 1) {
+		t.FailNow()
+	}
+	return res[0]
+}
+
+func TestControl(t *testing.T) {
+	inst := instantiate(t, wasmtest.Module{
+		// the type 0 is the block which takes i32 and returns i32 and i32.
+		Types: []wasm.FuncType{sig(types(i32), i32, i32)},
+		Funcs: []wasmtest.Func{
+			{
+				// the recursive factorial.
+				Type:   sig(types(i64), i64),
+				Export: "fac",
+				Code: wasmtest.Code(
+					0x20, 0, 0x50, // local.get 0, i64.eqz
+					0x04, i64, wasmtest.I64(1), // if (result i64)
+					0x05,                                    // else
+					0x20, 0, 0x20, 0, wasmtest.I64(1), 0x7D, // local.get 0, local.get 0, i64.const 1, i64.sub
+					0x10, 0, 0x7E, // call 0, i64.mul
+					0x0B,
+				),
+			},
+			{
+				// the iterative fibonacci.
+				Type:   sig(types(i32), i32),
+				Locals: types(i32, i32, i32),
+				Export: "fib",
+				Code: wasmtest.Code(
+					wasmtest.I32(1), 0x21, 2, // b = 1
+					0x02, 0x40, 0x03, 0x40, // block, loop
+					0x20, 0, 0x45, 0x0D, 1, // br_if 1 if n == 0
+					0x20, 1, 0x20, 2, 0x6A, 0x21, 3, // t = a + b
+					0x20, 2, 0x21, 1, 0x20, 3, 0x21, 2, // a = b, b = t
+					0x20, 0, wasmtest.I32(1), 0x6B, 0x21, 0, // n--
+					0x0C, 0, // br 0
+					0x0B, 0x0B,
+					0x20, 1,
+				),
+			},
+			{
+				// br_table selects the constant of blocks by the index.
+				Type:   sig(types(i32), i32),
+				Export: "switch",
+				Code: wasmtest.Code(
+					0x02, 0x40, 0x02, 0x40, 0x02, 0x40,
+					0x20, 0, 0x0E, 2, 0, 1, 2, // br_table 0 1 2
+					0x0B, wasmtest.I32(10), 0x0F,
+					0x0B, wasmtest.I32(20), 0x0F,
+					0x0B, wasmtest.I32(30),
+				),
+			},
+			{
+				// the block of type 0 takes the param and returns it with its square.
+				Type:   sig(types(i32), i32),
+				Export: "multi",
+				Code: wasmtest.Code(
+					0x20, 0,
+					0x02, 0x00, // block (type 0)
+					0x22, 0, 0x20, 0, 0x20, 0, 0x6C, // local.tee 0, local.get 0, local.get 0, i32.mul
+					0x0B,
+					0x6A,
+				),
+			},
+			{
+				Type:   sig(types(i32, i32, i32), i32),
+				Export: "select",
+				Code:   wasmtest.Code(0x20, 0, 0x20, 1, 0x20, 2, 0x1B),
+			},
+		},
+	}, wasm.Config{}, nil)
+
+	assert.Equal(t, uint64(3628800), call(t, inst, "fac", 10))
+	assert.Equal(t, uint64(55), call(t, inst, "fib", 10))
+	assert.Equal(t, uint64(10), call(t, inst, "switch", 0))
+	assert.Equal(t, uint64(20), call(t, inst, "switch", 1))
+	assert.Equal(t, uint64(30), call(t, inst, "switch", 2))
+	assert.Equal(t, uint64(30), call(t, inst, "switch", 99))
+	assert.Equal(t, uint64(42), call(t, inst, "multi", 6))
+	assert.Equal(t, uint64(1), call(t, inst, "select", 1, 2, 1))
+	assert.Equal(t, uint64(2), call(t, inst, "select", 1, 2, 0))
+
+	_, err := inst.Call(context.Background(), "fac")
+	assert.EqualError(t, err, "wasm: the function fac requires 1 args, not 0")
+	_, err = inst.Call(context.Background(), "none")
+	assert.EqualError(t, err, "wasm: the function none is not exported")
+}
+
+func TestNumeric(t *testing.T) {
+	binop := func(typ wasm.ValueType, op byte) wasmtest.Func {
+		return wasmtest.Func{Type: sig(types(typ, typ), typ), Code: wasmtest.Code(0x20, 0, 0x20, 1, op)}
+	}
+	unop := func(from, to wasm.ValueType, op ...byte) wasmtest.Func {
+		return wasmtest.Func{Type: sig(types(from), to), Code: wasmtest.Code(0x20, 0, op)}
+	}
+	funcs := map[string]wasmtest.Func{
+		"i32.div_s":           binop(i32, 0x6D),
+		"i32.rem_s":           binop(i32, 0x6F),
+		"i32.shr_s":           binop(i32, 0x75),
+		"i32.rotr":            binop(i32, 0x78),
+		"i64.div_u":           binop(i64, 0x80),
+		"f32.min":             binop(f32, 0x96),
+		"f64.max":             binop(f64, 0xA5),
+		"f64.copysign":        binop(f64, 0xA6),
+		"i32.clz":             unop(i32, i32, 0x67),
+		"i64.popcnt":          unop(i64, i64, 0x7B),
+		"f64.nearest":         unop(f64, f64, 0x9E),
+		"i32.trunc_f64_s":     unop(f64, i32, 0xAA),
+		"i64.trunc_f64_u":     unop(f64, i64, 0xB1),
+		"i32.trunc_sat_f64_s": unop(f64, i32, 0xFC, 2),
+		"i64.trunc_sat_f32_u": unop(f32, i64, 0xFC, 5),
+		"f32.convert_i64_u":   unop(i64, f32, 0xB5),
+		"i64.extend_i32_s":    unop(i32, i64, 0xAC),
+		"i32.extend8_s":       unop(i32, i32, 0xC0),
+	}
+	var m wasmtest.Module
+	for name, fn := range funcs {
+		fn.Export = name
+		m.Funcs = append(m.Funcs, fn)
+	}
+	inst := instantiate(t, m, wasm.Config{}, nil)
+
+	neg := func(v int32) uint64 { return uint64(uint32(v)) }
+	f32b := func(f float32) uint64 { return uint64(math.Float32bits(f)) }
+	f64b := math.Float64bits
+	for _, c := range []struct {
+		name string
+		args []uint64
+		want uint64
+		trap string
+	}{
+		{"i32.div_s", []uint64{neg(-7), 2}, neg(-3), ""},
+		{"i32.div_s", []uint64{1, 0}, 0, "integer divide by zero"},
+		{"i32.div_s", []uint64{neg(math.MinInt32), neg(-1)}, 0, "integer overflow"},
+		{"i32.rem_s", []uint64{neg(math.MinInt32), neg(-1)}, 0, ""},
+		{"i32.rem_s", []uint64{neg(-7), 2}, neg(-1), ""},
+		{"i32.shr_s", []uint64{neg(-8), 33}, neg(-4), ""},
+		{"i32.rotr", []uint64{1, 1}, 0x80000000, ""},
+		{"i64.div_u", []uint64{math.MaxUint64, 2}, math.MaxInt64, ""},
+		{"f32.min", []uint64{f32b(0), f32b(float32(math.Copysign(0, -1)))}, f32b(float32(math.Copysign(0, -1))), ""},
+		{"f64.max", []uint64{f64b(1), f64b(math.NaN())}, 0, ""},
+		{"f64.copysign", []uint64{f64b(2), f64b(-1)}, f64b(-2), ""},
+		{"i32.clz", []uint64{1}, 31, ""},
+		{"i64.popcnt", []uint64{math.MaxUint64}, 64, ""},
+		{"f64.nearest", []uint64{f64b(2.5)}, f64b(2), ""},
+		{"f64.nearest", []uint64{f64b(-3.5)}, f64b(-4), ""},
+		{"i32.trunc_f64_s", []uint64{f64b(-3.9)}, neg(-3), ""},
+		{"i32.trunc_f64_s", []uint64{f64b(2147483648)}, 0, "integer overflow"},
+		{"i32.trunc_f64_s", []uint64{f64b(math.NaN())}, 0, "invalid conversion to integer"},
+		{"i64.trunc_f64_u", []uint64{f64b(-0.9)}, 0, ""},
+		{"i64.trunc_f64_u", []uint64{f64b(-1)}, 0, "integer overflow"},
+		{"i64.trunc_f64_u", []uint64{f64b(1e19)}, 10000000000000000000, ""},
+		{"i32.trunc_sat_f64_s", []uint64{f64b(1e10)}, math.MaxInt32, ""},
+		{"i32.trunc_sat_f64_s", []uint64{f64b(-1e10)}, neg(math.MinInt32), ""},
+		{"i32.trunc_sat_f64_s", []uint64{f64b(math.NaN())}, 0, ""},
+		{"i64.trunc_sat_f32_u", []uint64{f32b(-5)}, 0, ""},
+		{"i64.trunc_sat_f32_u", []uint64{f32b(float32(math.Inf(1)))}, math.MaxUint64, ""},
+		{"f32.convert_i64_u", []uint64{math.MaxUint64}, f32b(1 << 64), ""},
+		{"i64.extend_i32_s", []uint64{neg(-1)}, math.MaxUint64, ""},
+		{"i32.extend8_s", []uint64{0x80}, neg(-128), ""},
+	} {
+		res, err := inst.Call(context.Background(), c.name, c.args...)
+		if c.trap != "" {
+			if assert.Error(t, err, c.name) {
+				assert.Contains(t, err.Error(), c.trap, c.name)
+			}
+			continue
+		}
+		if assert.NoError(t, err, c.name) {
+			if c.name == "f64.max" {
+				assert.True(t, math.IsNaN(math.Float64frombits(res[0])), c.name)
+				continue
+			}
+			assert.Equal(t, c.want, res[0], "%s %v", c.name, c.args)
+		}
+	}
+}
+
+func TestMemory(t *testing.T) {
+	inst := instantiate(t, wasmtest.Module{
+		Memory:    1,
+		MaxMemory: 4,
+		Data:      []wasmtest.Data{{Offset: 16, Bytes: []byte("hello")}},
+		Funcs: []wasmtest.Func{
+			{
+				// loads the i32 at the address with the offset 1.
+				Type:   sig(types(i32), i32),
+				Export: "load",
+				Code:   wasmtest.Code(0x20, 0, 0x28, 2, 1),
+			},
+			{
+				// stores the byte at the address.
+				Type:   sig(types(i32, i32)),
+				Export: "store8",
+				Code:   wasmtest.Code(0x20, 0, 0x20, 1, 0x3A, 0, 0),
+			},
+			{
+				Type:   sig(types(i32), i32),
+				Export: "grow",
+				Code:   wasmtest.Code(0x20, 0, 0x40, 0),
+			},
+			{
+				Type:   sig(void, i32),
+				Export: "size",
+				Code:   wasmtest.Code(0x3F, 0),
+			},
+			{
+				// copies the 5 bytes from 16 to 32, and fills the 2 bytes at 34 with "!".
+				Type:   sig(void),
+				Export: "copy",
+				Code: wasmtest.Code(
+					wasmtest.I32(32), wasmtest.I32(16), wasmtest.I32(5), 0xFC, 10, 0, 0,
+					wasmtest.I32(34), wasmtest.I32('!'), wasmtest.I32(2), 0xFC, 11, 0,
+				),
+			},
+		},
+	}, wasm.Config{MaxPages: 2}, nil)
+
+	buf, ok := inst.Read(16, 5)
+	assert.True(t, ok)
+	assert.Equal(t, "hello", string(buf))
+	assert.Equal(t, uint64(0x6c6c6568), call(t, inst, "load", 15))
+
+	_, err := inst.Call(context.Background(), "load", 65533)
+	assert.Contains(t, err.Error(), "out of bounds memory access")
+	_, err = inst.Call(context.Background(), "store8", 65536, 1)
+	assert.Contains(t, err.Error(), "out of bounds memory access")
+	_, err = inst.Call(context.Background(), "store8", 65535, 'x')
+	assert.NoError(t, err)
+
+	// the memory grows to the limit of config, which is less than the max of module.
+	assert.Equal(t, uint64(1), call(t, inst, "grow", 1))
+	assert.Equal(t, uint64(math.MaxUint32), call(t, inst, "grow", 1))
+	assert.Equal(t, uint64(2), call(t, inst, "size"))
+	buf, _ = inst.Read(65535, 1)
+	assert.Equal(t, "x", string(buf))
+
+	_, err = inst.Call(context.Background(), "copy")
+	assert.NoError(t, err)
+	buf, _ = inst.Read(32, 5)
+	assert.Equal(t, "he!!o", string(buf))
+
+	assert.True(t, inst.Write(131071, []byte("y")))
+	assert.False(t, inst.Write(131071, []byte("yz")))
+	_, ok = inst.Read(131070, 3)
+	assert.False(t, ok)
+
+	// the module which requires more memory than the limit can't be instantiated.
+	mod, err := wasm.Decode(wasmtest.Module{Memory: 3}.Bytes())
+	assert.NoError(t, err)
+	_, err = wasm.Instantiate(context.Background(), mod, nil, wasm.Config{MaxPages: 2})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "min 3 pages (192 Ki) over limit of 2 pages (128 Ki)")
+	}
+}
+
+func TestImports(t *testing.T) {
+	m := wasmtest.Module{
+		Imports: []wasmtest.Import{{Module: "env", Name: "add", Type: sig(types(i32, i32), i32)}},
+		Globals: []wasmtest.Global{{Type: i32, Mutable: true, Value: 100}},
+		Funcs: []wasmtest.Func{
+			{
+				// the start function adds 1 to the global.
+				Type: sig(void),
+				Code: wasmtest.Code(0x23, 0, wasmtest.I32(1), 0x10, 0, 0x24, 0),
+			},
+			{
+				Type:   sig(types(i32), i32),
+				Export: "run",
+				Code:   wasmtest.Code(0x20, 0, 0x23, 0, 0x10, 0),
+			},
+		},
+		Start: 2,
+	}
+	errZero := errors.New("zero")
+	add := wasm.HostFunc{
+		Type: sig(types(i32, i32), i32),
+		Fn: func(inst *wasm.Instance, args []uint64) ([]uint64, error) {
+			if args[0] == 0 {
+				return nil, errZero
+			}
+			return []uint64{args[0] + args[1]}, nil
+		},
+	}
+	inst := instantiate(t, m, wasm.Config{}, wasm.Imports{"env": {"add": add}})
+	assert.Equal(t, uint64(108), call(t, inst, "run", 7))
+	_, err := inst.Call(context.Background(), "run", 0)
+	assert.ErrorIs(t, err, errZero)
+
+	mod, err := wasm.Decode(m.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, []wasm.Import{{Module: "env", Name: "add", Type: sig(types(i32, i32), i32)}}, mod.Imports())
+	typ, ok := mod.ExportedFunc("run")
+	assert.True(t, ok)
+	assert.Equal(t, sig(types(i32), i32), typ)
+
+	_, err = wasm.Instantiate(context.Background(), mod, nil, wasm.Config{})
+	assert.EqualError(t, err, "wasm: the import env.add is not found")
+	add.Type = sig(types(i32), i32)
+	_, err = wasm.Instantiate(context.Background(), mod, wasm.Imports{"env": {"add": add}}, wasm.Config{})
+	assert.EqualError(t, err, "wasm: the import env.add must be [i32 i32] -> [i32], not [i32] -> [i32]")
+}
+
+func TestCallIndirect(t *testing.T) {
+	inst := instantiate(t, wasmtest.Module{
+		Types: []wasm.FuncType{sig(void, i32)},
+		Funcs: []wasmtest.Func{
+			{Type: sig(void, i32), Code: wasmtest.I32(1)},
+			{Type: sig(void, i64), Code: wasmtest.I64(2)},
+			{
+				Type:   sig(types(i32), i32),
+				Export: "dispatch",
+				Code:   wasmtest.Code(0x20, 0, 0x11, 0, 0),
+			},
+		},
+		Table: []uint32{0, 1},
+	}, wasm.Config{}, nil)
+
+	assert.Equal(t, uint64(1), call(t, inst, "dispatch", 0))
+	_, err := inst.Call(context.Background(), "dispatch", 1)
+	assert.Contains(t, err.Error(), "indirect call type mismatch")
+	_, err = inst.Call(context.Background(), "dispatch", 2)
+	assert.Contains(t, err.Error(), "invalid table access")
+}
+
+func TestLimits(t *testing.T) {
+	m := wasmtest.Module{
+		Funcs: []wasmtest.Func{
+			{Type: sig(void), Export: "spin", Code: wasmtest.Code(0x03, 0x40, 0x0C, 0, 0x0B)},
+			{Type: sig(void), Export: "recurse", Code: wasmtest.Code(0x10, 1)},
+			{Type: sig(void), Export: "trap", Code: wasmtest.Code(0x00)},
+		},
+	}
+
+	inst := instantiate(t, m, wasm.Config{}, nil)
+	_, err := inst.Call(context.Background(), "trap")
+	assert.Contains(t, err.Error(), "unreachable")
+	_, err = inst.Call(context.Background(), "recurse")
+	assert.Contains(t, err.Error(), "stack overflow")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = inst.Call(ctx, "spin")
+	assert.ErrorIs(t, err, wasm.ErrInterrupted)
+	assert.NoError(t, inst.Close())
+}
+
+func TestDecode(t *testing.T) {
+	_, err := wasm.Decode([]byte("\x00asn\x01\x00\x00\x00"))
+	assert.Error(t, err)
+	_, err = wasm.Decode([]byte("\x00asm\x01\x00"))
+	assert.Error(t, err)
+
+	// the invalid code is rejected when it's decoded, before any function of the module runs.
+	for _, code := range [][]byte{
+		wasmtest.Code(0x0C, 1),                                 // unknown label
+		wasmtest.Code(0x20, 0),                                 // unknown local
+		wasmtest.Code(0x10, 9),                                 // unknown function
+		wasmtest.Code(0x02, 0x40),                              // the function isn't ended
+		wasmtest.Code(0x41, 0x80),                              // unexpected end of constant
+		wasmtest.Code(0x02, 0x05, 0),                           // unknown type
+		wasmtest.Code(0x6A, 0x1A),                              // i32.add on the empty stack
+		wasmtest.Code(wasmtest.I64(1), wasmtest.I32(1), 0x6A),  // i32.add of i64 and i32
+		wasmtest.Code(wasmtest.I32(1)),                         // the value is left on the stack
+		wasmtest.Code(wasmtest.I32(0), 0x28, 2, 0),             // load without memory
+		wasmtest.Code(wasmtest.I32(0), 0x04, 0x40, 0x0B, 0x6A), // i32.add after the block pops its condition
+		wasmtest.Code(0xFE, 0x03, 0),                           // the threads are not supported
+	} {
+		_, err = wasm.Decode(wasmtest.Module{Funcs: []wasmtest.Func{{Type: sig(void), Code: code}}}.Bytes())
+		assert.Error(t, err, "%x", code)
+	}
+
+	// the result of function is checked.
+	_, err = wasm.Decode(wasmtest.Module{Funcs: []wasmtest.Func{{Type: sig(void, i32), Code: wasmtest.I64(1)}}}.Bytes())
+	assert.Error(t, err)
+	_, err = wasm.Decode(wasmtest.Module{Funcs: []wasmtest.Func{{Type: sig(void, i32)}}}.Bytes())
+	assert.Error(t, err)
+}
@@ -0,0 +1,253 @@
+// Package wasmtest assembles the binary WebAssembly modules for the tests, the code of functions is written in the
+// opcodes.
+package wasmtest
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/yomorun/yomo/internal/wasm"
+)
+
+// Import is an imported function.
+type Import struct {
+	Module, Name string
+	Type         wasm.FuncType
+}
+
+// Func is a function defined by the module, it's exported if Export isn't empty.
+type Func struct {
+	Type   wasm.FuncType
+	Locals []wasm.ValueType
+	Code   []byte
+	Export string
+}
+
+// Data is an active data segment.
+type Data struct {
+	Offset uint32
+	Bytes  []byte
+}
+
+// Global is a global of module.
+type Global struct {
+	Type    wasm.ValueType
+	Mutable bool
+	Value   uint64
+}
+
+// Module is the module to assemble. The function indexes start from the imports, and the type indexes start from
+// Types, so they can be used by the blocks and call_indirect.
+type Module struct {
+	Types   []wasm.FuncType
+	Imports []Import
+	Funcs   []Func
+	// Memory is the count of its min pages, the memory is exported as "memory" if it's not 0, and MaxMemory is the
+	// count of its max pages, 0 is unlimited.
+	Memory    uint32
+	MaxMemory uint32
+	Data      []Data
+	Globals   []Global
+	// Table is the function indexes of the table from 0.
+	Table []uint32
+	// Start is the index of start function plus 1, 0 is none.
+	Start uint32
+}
+
+// Bytes assembles the module.
+func (m Module) Bytes() []byte {
+	types := append([]wasm.FuncType(nil), m.Types...)
+	var typeSection, importSection, funcSection, exportSection, codeSection []byte
+
+	for _, imp := range m.Imports {
+		importSection = append(importSection, name(imp.Module)...)
+		importSection = append(importSection, name(imp.Name)...)
+		importSection = append(importSection, 0x00)
+		importSection = append(importSection, U32(uint32(len(types)))...)
+		types = append(types, imp.Type)
+	}
+	exports := 0
+	for i, fn := range m.Funcs {
+		funcSection = append(funcSection, U32(uint32(len(types)))...)
+		types = append(types, fn.Type)
+		if fn.Export != "" {
+			exportSection = append(exportSection, name(fn.Export)...)
+			exportSection = append(exportSection, 0x00)
+			exportSection = append(exportSection, U32(uint32(len(m.Imports)+i))...)
+			exports++
+		}
+
+		var body []byte
+		body = append(body, U32(uint32(len(fn.Locals)))...)
+		for _, t := range fn.Locals {
+			body = append(body, 1, byte(t))
+		}
+		body = append(body, fn.Code...)
+		body = append(body, 0x0B)
+		codeSection = append(codeSection, U32(uint32(len(body)))...)
+		codeSection = append(codeSection, body...)
+	}
+	for _, t := range types {
+		typeSection = append(typeSection, 0x60)
+		typeSection = append(typeSection, valueTypes(t.Params)...)
+		typeSection = append(typeSection, valueTypes(t.Results)...)
+	}
+	if m.Memory > 0 {
+		exportSection = append(exportSection, name("memory")...)
+		exportSection = append(exportSection, 0x02, 0x00)
+		exports++
+	}
+
+	buf := []byte("\x00asm\x01\x00\x00\x00")
+	buf = section(buf, 1, len(types), typeSection)
+	buf = section(buf, 2, len(m.Imports), importSection)
+	buf = section(buf, 3, len(m.Funcs), funcSection)
+	if len(m.Table) > 0 {
+		buf = section(buf, 4, 1, Code(0x70, 0x00, U32(uint32(len(m.Table)))))
+	}
+	if m.Memory > 0 {
+		if m.MaxMemory > 0 {
+			buf = section(buf, 5, 1, Code(0x01, U32(m.Memory), U32(m.MaxMemory)))
+		} else {
+			buf = section(buf, 5, 1, Code(0x00, U32(m.Memory)))
+		}
+	}
+	var globalSection []byte
+	for _, g := range m.Globals {
+		globalSection = append(globalSection, byte(g.Type))
+		if g.Mutable {
+			globalSection = append(globalSection, 1)
+		} else {
+			globalSection = append(globalSection, 0)
+		}
+		globalSection = append(globalSection, constant(g.Type, g.Value)...)
+		globalSection = append(globalSection, 0x0B)
+	}
+	buf = section(buf, 6, len(m.Globals), globalSection)
+	buf = section(buf, 7, exports, exportSection)
+	if m.Start > 0 {
+		buf = append(buf, 8)
+		buf = append(buf, U32(uint32(len(U32(m.Start-1))))...)
+		buf = append(buf, U32(m.Start-1)...)
+	}
+	if len(m.Table) > 0 {
+		elem := Code(0x00, I32(0), 0x0B, U32(uint32(len(m.Table))))
+		for _, fn := range m.Table {
+			elem = append(elem, U32(fn)...)
+		}
+		buf = section(buf, 9, 1, elem)
+	}
+	buf = section(buf, 10, len(m.Funcs), codeSection)
+	var dataSection []byte
+	for _, d := range m.Data {
+		dataSection = append(dataSection, Code(0x00, I32(int32(d.Offset)), 0x0B, U32(uint32(len(d.Bytes))), d.Bytes)...)
+	}
+	buf = section(buf, 11, len(m.Data), dataSection)
+	return buf
+}
+
+// Code concatenates the opcodes and immediates, the parts are bytes, ints of opcodes or byte slices.
+func Code(parts ...interface{}) []byte {
+	var buf []byte
+	for _, p := range parts {
+		switch p := p.(type) {
+		case byte:
+			buf = append(buf, p)
+		case int:
+			buf = append(buf, byte(p))
+		case wasm.ValueType:
+			buf = append(buf, byte(p))
+		case []byte:
+			buf = append(buf, p...)
+		default:
+			panic("wasmtest: unknown part of code")
+		}
+	}
+	return buf
+}
+
+// U32 encodes the unsigned LEB128 of immediate.
+func U32(v uint32) []byte {
+	var buf []byte
+	for {
+		b := byte(v & 0x7F)
+		v >>= 7
+		if v == 0 {
+			return append(buf, b)
+		}
+		buf = append(buf, b|0x80)
+	}
+}
+
+// S64 encodes the signed LEB128 of immediate.
+func S64(v int64) []byte {
+	var buf []byte
+	for {
+		b := byte(v & 0x7F)
+		v >>= 7
+		if (v == 0 && b&0x40 == 0) || (v == -1 && b&0x40 != 0) {
+			return append(buf, b)
+		}
+		buf = append(buf, b|0x80)
+	}
+}
+
+// I32 is i32.const.
+func I32(v int32) []byte {
+	return append([]byte{0x41}, S64(int64(v))...)
+}
+
+// I64 is i64.const.
+func I64(v int64) []byte {
+	return append([]byte{0x42}, S64(v)...)
+}
+
+// F32 is f32.const.
+func F32(v float32) []byte {
+	buf := []byte{0x43, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint32(buf[1:], math.Float32bits(v))
+	return buf
+}
+
+// F64 is f64.const.
+func F64(v float64) []byte {
+	buf := make([]byte, 9)
+	buf[0] = 0x44
+	binary.LittleEndian.PutUint64(buf[1:], math.Float64bits(v))
+	return buf
+}
+
+func constant(t wasm.ValueType, v uint64) []byte {
+	switch t {
+	case wasm.I64:
+		return I64(int64(v))
+	case wasm.F32:
+		return F32(math.Float32frombits(uint32(v)))
+	case wasm.F64:
+		return F64(math.Float64frombits(v))
+	default:
+		return I32(int32(v))
+	}
+}
+
+func name(s string) []byte {
+	return append(U32(uint32(len(s))), s...)
+}
+
+func valueTypes(types []wasm.ValueType) []byte {
+	buf := U32(uint32(len(types)))
+	for _, t := range types {
+		buf = append(buf, byte(t))
+	}
+	return buf
+}
+
+func section(buf []byte, id byte, count int, content []byte) []byte {
+	if count == 0 {
+		return buf
+	}
+	content = append(U32(uint32(count)), content...)
+	buf = append(buf, id)
+	buf = append(buf, U32(uint32(len(content)))...)
+	return append(buf, content...)
+}
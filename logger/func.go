@@ -0,0 +1,92 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+)
+
+// Level is the level of a log message.
+type Level int8
+
+const (
+	// DebugLevel logs the messages for debugging.
+	DebugLevel Level = iota
+	// InfoLevel is the default level of messages, e.g. the ones of Print and Printf.
+	InfoLevel
+	// WarnLevel logs the messages which are more important than Info, but don't need to be reviewed.
+	WarnLevel
+	// ErrorLevel logs the errors which should be reviewed.
+	ErrorLevel
+	// PanicLevel logs a message, then panics.
+	PanicLevel
+	// FatalLevel logs a message, then calls os.Exit(1).
+	FatalLevel
+)
+
+// String returns the lower-case name of the level.
+func (l Level) String() string {
+	switch l {
+	case DebugLevel:
+		return "debug"
+	case InfoLevel:
+		return "info"
+	case WarnLevel:
+		return "warn"
+	case ErrorLevel:
+		return "error"
+	case PanicLevel:
+		return "panic"
+	case FatalLevel:
+		return "fatal"
+	default:
+		return fmt.Sprintf("Level(%d)", l)
+	}
+}
+
+// Func is the adapter which turns a function into Logger, so any backend can be plugged in by SetLogger,
+// e.g. slog or logrus. The kvPairs are the alternating keys and values of the fields, the messages of Print and
+// Printf are logged at InfoLevel. Func panics after logging at PanicLevel, and exits after logging at FatalLevel.
+type Func func(level Level, msg string, kvPairs ...interface{})
+
+// Print prints a farmat message at InfoLevel.
+func (f Func) Print(v ...interface{}) {
+	f(InfoLevel, fmt.Sprint(v...))
+}
+
+// Printf prints a formated message at InfoLevel.
+func (f Func) Printf(format string, v ...interface{}) {
+	f(InfoLevel, fmt.Sprintf(format, v...))
+}
+
+// Debug logs a message at DebugLevel.
+func (f Func) Debug(msg string, kvPairs ...interface{}) {
+	f(DebugLevel, msg, kvPairs...)
+}
+
+// Info logs a message at InfoLevel.
+func (f Func) Info(msg string, kvPairs ...interface{}) {
+	f(InfoLevel, msg, kvPairs...)
+}
+
+// Warn logs a message at WarnLevel.
+func (f Func) Warn(msg string, kvPairs ...interface{}) {
+	f(WarnLevel, msg, kvPairs...)
+}
+
+// Error logs a message at ErrorLevel.
+func (f Func) Error(msg string, kvPairs ...interface{}) {
+	f(ErrorLevel, msg, kvPairs...)
+}
+
+// Panic logs a message at PanicLevel, then panics.
+func (f Func) Panic(msg string, kvPairs ...interface{}) {
+	f(PanicLevel, msg, kvPairs...)
+	panic(msg)
+}
+
+// Fatal logs a message at FatalLevel.
+// The logger then calls os.Exit(1).
+func (f Func) Fatal(msg string, kvPairs ...interface{}) {
+	f(FatalLevel, msg, kvPairs...)
+	os.Exit(1)
+}
@@ -3,6 +3,7 @@ package logger
 import (
 	"fmt"
 	"os"
+	"sync"
 )
 
 // Logger is the interface for logger.
@@ -33,52 +34,110 @@ type Logger interface {
 	Fatal(msg string, kvPairs ...interface{})
 }
 
-var logger = newLogger(isEnableDebug())
+var (
+	mutex   sync.RWMutex
+	logger  = newLogger(isEnableDebug())
+	custom  bool     // custom indicates the logger is replaced by SetLogger.
+	sampler *Sampler // sampler samples the repeated messages, it's nil if the sampling is disabled.
+)
+
+// SetLogger replaces the logger of YoMo, the logs of zipper, core and SDKs are written to it,
+// so the application can plug in its own backend, e.g. zap, slog or logrus by Func.
+// The messages are still sampled if the sampling is enabled.
+func SetLogger(l Logger) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	custom = true
+	setLogger(l)
+}
+
+// SetSampling samples the repeated messages of the logger of YoMo by conf, so the errors of a broken client
+// don't flood the logs. Calling it again changes the config and resets the counters.
+func SetSampling(conf SamplingConfig) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	next := logger
+	if sampler != nil {
+		next = sampler.next
+	}
+	sampler = NewSampler(next, conf)
+	logger = sampler
+}
 
-// EnableDebug enables the development model for logging.
+// Suppressed returns the total count of messages suppressed by the sampling.
+func Suppressed() uint64 {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	if sampler == nil {
+		return 0
+	}
+	return sampler.Suppressed()
+}
+
+// setLogger replaces the backend of logger behind the sampler, the mutex is held.
+func setLogger(l Logger) {
+	if sampler != nil {
+		sampler.setNext(l)
+		return
+	}
+	logger = l
+}
+
+// Default returns the logger of YoMo.
+func Default() Logger {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return logger
+}
+
+// EnableDebug enables the development model for logging, it has no effect if the logger is replaced by SetLogger.
 func EnableDebug() {
-	logger = newLogger(true)
+	mutex.Lock()
+	defer mutex.Unlock()
+	if !custom {
+		setLogger(newLogger(true))
+	}
 }
 
 // Print prints a farmat message without a specified level.
 func Print(v ...interface{}) {
-	logger.Print(v...)
+	Default().Print(v...)
 }
 
 // Printf prints a formated message without a specified level.
 func Printf(format string, v ...interface{}) {
-	logger.Printf(format, v...)
+	Default().Printf(format, v...)
 }
 
 // Debug logs a message at DebugLevel.
 func Debug(msg string, kvPairs ...interface{}) {
-	logger.Debug(msg, kvPairs...)
+	Default().Debug(msg, kvPairs...)
 }
 
 // Info logs a message at InfoLevel.
 func Info(msg string, kvPairs ...interface{}) {
-	logger.Info(msg, kvPairs...)
+	Default().Info(msg, kvPairs...)
 }
 
 // Warn logs a message at WarnLevel.
 func Warn(msg string, kvPairs ...interface{}) {
-	logger.Warn(msg, kvPairs...)
+	Default().Warn(msg, kvPairs...)
 }
 
 // Error logs a message at ErrorLevel.
 func Error(msg string, kvPairs ...interface{}) {
-	logger.Error(msg, kvPairs...)
+	Default().Error(msg, kvPairs...)
 }
 
 // Panic logs a message at PanicLevel.
 func Panic(msg string, kvPairs ...interface{}) {
-	logger.Panic(msg, kvPairs...)
+	Default().Panic(msg, kvPairs...)
 }
 
 // Fatal logs a message at FatalLevel.
 // The logger then calls os.Exit(1).
 func Fatal(msg string, kvPairs ...interface{}) {
-	logger.Fatal(msg, kvPairs...)
+	Default().Fatal(msg, kvPairs...)
 }
 
 // BytesString formats the bytes to string.
@@ -0,0 +1,45 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetLogger(t *testing.T) {
+	defer func(l Logger, c bool, s *Sampler) {
+		logger, custom, sampler = l, c, s
+	}(Default(), custom, sampler)
+
+	var levels []Level
+	var fields []interface{}
+	SetLogger(Func(func(level Level, msg string, kvPairs ...interface{}) {
+		levels = append(levels, level)
+		fields = append(fields, kvPairs...)
+	}))
+	// the custom logger isn't replaced.
+	EnableDebug()
+
+	Debug("debug", "k", 1)
+	Warn("warn")
+	Printf("%d", 1)
+	assert.Equal(t, []Level{DebugLevel, WarnLevel, InfoLevel}, levels)
+	assert.Equal(t, []interface{}{"k", 1}, fields)
+	assert.Panics(t, func() { Panic("panic") })
+}
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, WarnLevel, true)
+	l.Info("info")
+	l.Error("error", "addr", "localhost:9000")
+	l.Print("print")
+
+	var record map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "error", record["level"])
+	assert.Equal(t, "error", record["msg"])
+	assert.Equal(t, "localhost:9000", record["addr"])
+}
@@ -0,0 +1,194 @@
+package logger
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultSamplingInterval is the default interval in which the repeated messages are counted.
+	DefaultSamplingInterval = time.Second
+	// DefaultSamplingFirst is the default count of the repeated messages logged in each interval.
+	DefaultSamplingFirst = 10
+	// DefaultSamplingThereafter is the default rate of the repeated messages logged after the first ones.
+	DefaultSamplingThereafter = 100
+	// maxSampledKeys is the max count of messages which are counted, the messages beyond it are not sampled,
+	// so the counters don't grow without bound when the messages are formatted with varying content.
+	maxSampledKeys = 10000
+)
+
+// SamplingConfig represents the sampling of the repeated messages, e.g. the errors of a broken client.
+// The messages are keyed by their level and their text, the format for Printf. In each interval, the first messages
+// of a key are logged, then one of every Thereafter messages, the rest are suppressed and counted.
+type SamplingConfig struct {
+	// Interval is the interval in which the messages are counted, the default is 1s.
+	Interval time.Duration `yaml:"interval,omitempty"`
+	// First is the count of messages of a key logged in each interval, the default is 10.
+	First int `yaml:"first,omitempty"`
+	// Thereafter is the rate of messages of a key logged after the first ones, the default is 100.
+	// All of them are suppressed if it's negative.
+	Thereafter int `yaml:"thereafter,omitempty"`
+}
+
+// sampleKey is the key of the repeated messages.
+type sampleKey struct {
+	level Level
+	msg   string
+}
+
+// sampleCounter counts the messages of a key in the current interval.
+type sampleCounter struct {
+	start      time.Time
+	count      int
+	suppressed uint64
+}
+
+// Sampler is the Logger which samples the repeated messages of another Logger. When an interval ends, the count of
+// suppressed messages of each key is logged at WarnLevel with its next message. The messages at PanicLevel and
+// FatalLevel are never suppressed.
+type Sampler struct {
+	conf       SamplingConfig
+	mutex      sync.Mutex
+	next       Logger
+	counters   map[sampleKey]*sampleCounter
+	suppressed uint64
+}
+
+// NewSampler creates a Sampler which writes the sampled messages to l.
+func NewSampler(l Logger, conf SamplingConfig) *Sampler {
+	if conf.Interval <= 0 {
+		conf.Interval = DefaultSamplingInterval
+	}
+	if conf.First <= 0 {
+		conf.First = DefaultSamplingFirst
+	}
+	if conf.Thereafter == 0 {
+		conf.Thereafter = DefaultSamplingThereafter
+	}
+	return &Sampler{
+		conf:     conf,
+		next:     l,
+		counters: make(map[sampleKey]*sampleCounter),
+	}
+}
+
+// Suppressed returns the total count of suppressed messages.
+func (s *Sampler) Suppressed() uint64 {
+	return atomic.LoadUint64(&s.suppressed)
+}
+
+// setNext replaces the Logger which the sampled messages are written to.
+func (s *Sampler) setNext(l Logger) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.next = l
+}
+
+// sample returns the Logger to write the message to, and false if the message is suppressed.
+func (s *Sampler) sample(level Level, msg string) (Logger, bool) {
+	now := time.Now()
+	key := sampleKey{level: level, msg: msg}
+
+	s.mutex.Lock()
+	next := s.next
+	c, ok := s.counters[key]
+	if !ok {
+		if len(s.counters) >= maxSampledKeys {
+			s.prune(now)
+		}
+		if len(s.counters) >= maxSampledKeys {
+			s.mutex.Unlock()
+			return next, true
+		}
+		c = &sampleCounter{start: now}
+		s.counters[key] = c
+	}
+
+	var suppressed uint64
+	if now.Sub(c.start) >= s.conf.Interval {
+		suppressed = c.suppressed
+		c.start, c.count, c.suppressed = now, 0, 0
+	}
+	c.count++
+	allowed := c.count <= s.conf.First || (s.conf.Thereafter > 0 && (c.count-s.conf.First)%s.conf.Thereafter == 0)
+	if !allowed {
+		c.suppressed++
+		atomic.AddUint64(&s.suppressed, 1)
+	}
+	s.mutex.Unlock()
+
+	if suppressed > 0 {
+		next.Warn("[logger] the repeated messages were suppressed.", "level", level.String(), "msg", msg, "suppressed", suppressed)
+	}
+	return next, allowed
+}
+
+// prune removes the counters whose interval has ended without suppressed messages, the mutex is held.
+func (s *Sampler) prune(now time.Time) {
+	for key, c := range s.counters {
+		if now.Sub(c.start) >= s.conf.Interval && c.suppressed == 0 {
+			delete(s.counters, key)
+		}
+	}
+}
+
+// Print prints a farmat message without a specified level.
+func (s *Sampler) Print(v ...interface{}) {
+	if l, ok := s.sample(InfoLevel, fmt.Sprint(v...)); ok {
+		l.Print(v...)
+	}
+}
+
+// Printf prints a formated message without a specified level, the messages are keyed by the format.
+func (s *Sampler) Printf(format string, v ...interface{}) {
+	if l, ok := s.sample(InfoLevel, format); ok {
+		l.Printf(format, v...)
+	}
+}
+
+// Debug logs a message at DebugLevel.
+func (s *Sampler) Debug(msg string, kvPairs ...interface{}) {
+	if l, ok := s.sample(DebugLevel, msg); ok {
+		l.Debug(msg, kvPairs...)
+	}
+}
+
+// Info logs a message at InfoLevel.
+func (s *Sampler) Info(msg string, kvPairs ...interface{}) {
+	if l, ok := s.sample(InfoLevel, msg); ok {
+		l.Info(msg, kvPairs...)
+	}
+}
+
+// Warn logs a message at WarnLevel.
+func (s *Sampler) Warn(msg string, kvPairs ...interface{}) {
+	if l, ok := s.sample(WarnLevel, msg); ok {
+		l.Warn(msg, kvPairs...)
+	}
+}
+
+// Error logs a message at ErrorLevel.
+func (s *Sampler) Error(msg string, kvPairs ...interface{}) {
+	if l, ok := s.sample(ErrorLevel, msg); ok {
+		l.Error(msg, kvPairs...)
+	}
+}
+
+// Panic logs a message at PanicLevel, it's never suppressed.
+func (s *Sampler) Panic(msg string, kvPairs ...interface{}) {
+	s.mutex.Lock()
+	next := s.next
+	s.mutex.Unlock()
+	next.Panic(msg, kvPairs...)
+}
+
+// Fatal logs a message at FatalLevel, it's never suppressed.
+// The logger then calls os.Exit(1).
+func (s *Sampler) Fatal(msg string, kvPairs ...interface{}) {
+	s.mutex.Lock()
+	next := s.next
+	s.mutex.Unlock()
+	next.Fatal(msg, kvPairs...)
+}
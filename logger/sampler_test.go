@@ -0,0 +1,64 @@
+package logger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type record struct {
+	level   Level
+	msg     string
+	kvPairs []interface{}
+}
+
+func recorder(records *[]record) Logger {
+	return Func(func(level Level, msg string, kvPairs ...interface{}) {
+		*records = append(*records, record{level, msg, kvPairs})
+	})
+}
+
+func TestSampler(t *testing.T) {
+	var records []record
+	s := NewSampler(recorder(&records), SamplingConfig{Interval: 50 * time.Millisecond, First: 2, Thereafter: 3})
+
+	for i := 0; i < 8; i++ {
+		s.Error("parse frame failed", "i", i)
+	}
+	s.Warn("parse frame failed")
+	// the 1st, 2nd and 5th, 8th errors are logged, the warning is keyed by its level.
+	assert.Len(t, records, 5)
+	assert.Equal(t, []interface{}{"i", 4}, records[2].kvPairs)
+	assert.Equal(t, []interface{}{"i", 7}, records[3].kvPairs)
+	assert.Equal(t, WarnLevel, records[4].level)
+	assert.Equal(t, uint64(4), s.Suppressed())
+
+	// the count of suppressed messages is logged in the next interval.
+	time.Sleep(60 * time.Millisecond)
+	records = nil
+	s.Error("parse frame failed")
+	assert.Len(t, records, 2)
+	assert.Equal(t, "[logger] the repeated messages were suppressed.", records[0].msg)
+	assert.Equal(t, []interface{}{"level", "error", "msg", "parse frame failed", "suppressed", uint64(4)}, records[0].kvPairs)
+	assert.Equal(t, "parse frame failed", records[1].msg)
+
+	assert.Panics(t, func() { s.Panic("panic") })
+}
+
+func TestSetSampling(t *testing.T) {
+	defer func(l Logger, c bool, s *Sampler) {
+		logger, custom, sampler = l, c, s
+	}(Default(), custom, sampler)
+
+	var records []record
+	SetSampling(SamplingConfig{First: 1, Thereafter: -1})
+	// the sampler is kept when the backend is replaced.
+	SetLogger(recorder(&records))
+
+	Printf("waiting %d", 1)
+	Printf("waiting %d", 2)
+	Info("info")
+	assert.Len(t, records, 2)
+	assert.Equal(t, uint64(1), Suppressed())
+}
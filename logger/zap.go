@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"io"
 	"log"
 
 	"go.uber.org/zap"
@@ -36,20 +37,69 @@ func newLogger(isDebug bool) Logger {
 
 	return zapLogger{
 		logger: logger.Sugar(),
+		std:    true,
+	}
+}
+
+// New creates the default implementation of Logger which writes the messages at or above level to w,
+// in JSON if json is true, or in the console format otherwise.
+func New(w io.Writer, level Level, json bool) Logger {
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+
+	encoder := zapcore.NewConsoleEncoder(encoderConfig)
+	if json {
+		encoder = zapcore.NewJSONEncoder(encoderConfig)
+	}
+	core := zapcore.NewCore(encoder, zapcore.AddSync(w), zapLevel(level))
+	return NewZapLogger(zap.New(core))
+}
+
+func zapLevel(level Level) zapcore.Level {
+	switch level {
+	case DebugLevel:
+		return zapcore.DebugLevel
+	case InfoLevel:
+		return zapcore.InfoLevel
+	case WarnLevel:
+		return zapcore.WarnLevel
+	case ErrorLevel:
+		return zapcore.ErrorLevel
+	case PanicLevel:
+		return zapcore.PanicLevel
+	default:
+		return zapcore.FatalLevel
+	}
+}
+
+// NewZapLogger adapts the zap logger of application to Logger, the messages of Print and Printf are logged at
+// InfoLevel.
+func NewZapLogger(l *zap.Logger) Logger {
+	return zapLogger{
+		logger: l.Sugar(),
 	}
 }
 
 // zapLogger is the logger implementation in go.uber.org/zap
 type zapLogger struct {
 	logger *zap.SugaredLogger
+	std    bool // std prints the messages of Print and Printf by the standard logger.
 }
 
 func (z zapLogger) Print(v ...interface{}) {
-	log.Print(v...)
+	if z.std {
+		log.Print(v...)
+		return
+	}
+	z.logger.Info(v...)
 }
 
 func (z zapLogger) Printf(format string, v ...interface{}) {
-	log.Printf(format, v...)
+	if z.std {
+		log.Printf(format, v...)
+		return
+	}
+	z.logger.Infof(format, v...)
 }
 
 func (z zapLogger) Debug(msg string, fields ...interface{}) {
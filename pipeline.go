@@ -0,0 +1,111 @@
+package yomo
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/yomorun/yomo/zipper"
+)
+
+// Pipeline composes the workflow of YoMo-Zipper programmatically,
+// it produces the same config as the workflow.yaml.
+type Pipeline struct {
+	conf zipper.WorkflowConfig
+	errs []string
+}
+
+// Route is a branch of pipeline which sends the data with the specific tags to the stream functions in order.
+type Route struct {
+	tags []byte
+	fns  []string
+}
+
+// NewRoute creates a branch for the data with tags.
+func NewRoute(tags []byte, fns ...string) Route {
+	return Route{tags: tags, fns: fns}
+}
+
+// NewPipeline creates a new pipeline builder.
+func NewPipeline() *Pipeline {
+	return &Pipeline{}
+}
+
+// Name sets the name of YoMo-Zipper.
+func (p *Pipeline) Name(name string) *Pipeline {
+	p.conf.Name = name
+	return p
+}
+
+// Listen sets the host and port of YoMo-Zipper.
+func (p *Pipeline) Listen(host string, port int) *Pipeline {
+	p.conf.Host = host
+	p.conf.Port = port
+	return p
+}
+
+// From sets the YoMo-Sources which are allowed to connect to the pipeline.
+func (p *Pipeline) From(sources ...string) *Pipeline {
+	for _, name := range sources {
+		p.conf.Sources = append(p.conf.Sources, zipper.App{Name: name})
+	}
+	return p
+}
+
+// Via appends a stream function to the pipeline, it only receives the data with the tags if they are specified.
+func (p *Pipeline) Via(fn string, tags ...byte) *Pipeline {
+	p.conf.Functions = append(p.conf.Functions, zipper.App{Name: fn, Tags: tags})
+	return p
+}
+
+// Branch routes the data to different stream functions by tags, the tags of routes must not overlap.
+// The data which isn't matched by any route passes through the branch.
+func (p *Pipeline) Branch(routes ...Route) *Pipeline {
+	owners := make(map[byte]int)
+	for i, route := range routes {
+		if len(route.tags) == 0 {
+			p.errs = append(p.errs, fmt.Sprintf("the route %d of branch has no tags", i))
+		}
+		for _, tag := range route.tags {
+			if j, ok := owners[tag]; ok {
+				p.errs = append(p.errs, fmt.Sprintf("the tag %#x is in both route %d and route %d of branch", tag, j, i))
+			}
+			owners[tag] = i
+		}
+	}
+
+	for _, route := range routes {
+		for _, fn := range route.fns {
+			p.Via(fn, route.tags...)
+		}
+	}
+	return p
+}
+
+// To appends the sink to the end of pipeline.
+func (p *Pipeline) To(sink string, tags ...byte) *Pipeline {
+	return p.Via(sink, tags...)
+}
+
+// Build validates the pipeline and returns the workflow config for YoMo-Zipper.
+func (p *Pipeline) Build() (*zipper.WorkflowConfig, error) {
+	errs := append([]string(nil), p.errs...)
+
+	names := make(map[string]bool)
+	for _, app := range p.conf.Functions {
+		if names[app.Name] {
+			errs = append(errs, fmt.Sprintf("the stream function %s is duplicated", app.Name))
+		}
+		names[app.Name] = true
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.New("yomo: invalid pipeline: " + strings.Join(errs, "; "))
+	}
+
+	conf := p.conf
+	if err := zipper.Validate(&conf); err != nil {
+		return nil, err
+	}
+	return &conf, nil
+}
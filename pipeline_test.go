@@ -0,0 +1,50 @@
+package yomo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/zipper"
+)
+
+func TestPipelineBuild(t *testing.T) {
+	conf, err := NewPipeline().
+		Name("zipper").
+		Listen("localhost", 9000).
+		From("source").
+		Via("fn-a", 0x10, 0x11).
+		Branch(
+			NewRoute([]byte{0x10}, "fn-b"),
+			NewRoute([]byte{0x11}, "fn-c", "fn-d"),
+		).
+		To("sink").
+		Build()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []zipper.App{{Name: "source"}}, conf.Sources)
+	assert.Equal(t, []zipper.App{
+		{Name: "fn-a", Tags: []byte{0x10, 0x11}},
+		{Name: "fn-b", Tags: []byte{0x10}},
+		{Name: "fn-c", Tags: []byte{0x11}},
+		{Name: "fn-d", Tags: []byte{0x11}},
+		{Name: "sink"},
+	}, conf.Functions)
+}
+
+func TestPipelineBuildError(t *testing.T) {
+	_, err := NewPipeline().
+		Name("zipper").
+		Listen("localhost", 9000).
+		Branch(
+			NewRoute([]byte{0x10}, "fn-a"),
+			NewRoute([]byte{0x10}, "fn-b"),
+		).
+		Build()
+	assert.Error(t, err)
+
+	_, err = NewPipeline().Name("zipper").Listen("localhost", 9000).Via("fn-a").Via("fn-a").Build()
+	assert.Error(t, err)
+
+	_, err = NewPipeline().Via("fn-a").Build()
+	assert.Error(t, err)
+}
@@ -1,14 +1,19 @@
 package source
 
 import (
+	"context"
 	"errors"
 	"io"
+	"io/ioutil"
 	"strconv"
+	"sync/atomic"
 	"time"
 
+	"github.com/yomorun/yomo/core/quic"
 	"github.com/yomorun/yomo/internal/client"
 	"github.com/yomorun/yomo/internal/core"
 	"github.com/yomorun/yomo/internal/frame"
+	"github.com/yomorun/yomo/zipper/tracing"
 )
 
 // Client is the client for YoMo-Source.
@@ -16,44 +21,265 @@ import (
 type Client interface {
 	io.Writer
 
+	// ReaderFrom streams the data of reader to downstream without holding it in memory, e.g. the large blobs.
+	io.ReaderFrom
+
 	client.Client
 
+	// WriteWithContext writes the data in the trace of ctx, the trace context is carried in the MetaFrame,
+	// so the spans of YoMo-Zipper and stream functions are the descendants of the span in ctx.
+	WriteWithContext(ctx context.Context, data []byte) (int, error)
+
+	// WriteWithTags writes the data observed by several data tags, the data relevant to several stream
+	// functions is transmitted once instead of being written for each tag.
+	WriteWithTags(data []byte, tags ...byte) (int, error)
+
+	// WriteWithTransaction writes the data observed by the data tags with the transaction ID assigned by the
+	// caller, e.g. the ID of an event bridged from the other systems, instead of the ID generated by the client.
+	WriteWithTransaction(tid string, data []byte, tags ...byte) (int, error)
+
 	// Connect to YoMo-Zipper
 	Connect(ip string, port int) (Client, error)
 }
 
 type clientImpl struct {
 	*client.Impl
+	opts     *options
+	sequence *uint64 // sequence is the last sequence of frames sent in partially reliable mode.
 }
 
+// ErrFrameAbandoned is returned when the frame isn't fully transmitted within the deadline in partially reliable mode.
+var ErrFrameAbandoned = errors.New("[Source] the frame is abandoned after the deadline")
+
+// ErrUnauthenticated is returned by Connect when YoMo-Zipper rejects the token of source.
+var ErrUnauthenticated = client.ErrUnauthenticated
+
+// ErrUnauthorized is returned by Connect when the source is not allowed to connect as its name by the policies of
+// YoMo-Zipper.
+var ErrUnauthorized = client.ErrUnauthorized
+
+// ErrQuotaExceeded is returned by Connect when the max connections of the tenant of source are reached.
+var ErrQuotaExceeded = client.ErrQuotaExceeded
+
+// QuotaExceeded tells the data written by the source is dropped because the quota of its tenant is exceeded.
+type QuotaExceeded = client.QuotaExceeded
+
+// abandonedCode is the error code of the stream reset when the frame is abandoned.
+const abandonedCode = 0x1
+
 // New a YoMo-Source client.
-func New(appName string) Client {
+func New(appName string, opts ...Option) Client {
 	c := &clientImpl{
-		Impl: client.New(appName, core.ConnTypeSource),
+		Impl:     client.New(appName, core.ConnTypeSource),
+		opts:     newOptions(opts...),
+		sequence: new(uint64),
+	}
+	c.SetTLSConfig(c.opts.tls)
+	c.Set0RTT(c.opts.zeroRTT)
+	c.SetTCPFallback(c.opts.tcp)
+	c.SetKeepAlive(c.opts.ping, c.opts.idle)
+	c.SetFlowControl(c.opts.flow)
+	c.SetReconnectBackoff(c.opts.retryInitial, c.opts.retryMax)
+	c.SetOnReconnect(c.opts.onReconnect)
+	c.SetProxy(c.opts.proxy)
+	c.SetQlog(c.opts.qlog)
+	c.SetToken(c.opts.token)
+	c.SetOnQuotaExceeded(c.opts.onQuota)
+	if c.opts.compression {
+		c.SetCompression(c.opts.codecs, c.opts.threshold)
 	}
 	return c
 }
 
 // Write the data to downstream.
 func (c *clientImpl) Write(data []byte) (int, error) {
+	return c.write(context.Background(), "", data)
+}
+
+// WriteWithContext writes the data in the trace of ctx.
+func (c *clientImpl) WriteWithContext(ctx context.Context, data []byte) (int, error) {
+	return c.write(ctx, "", data)
+}
+
+// WriteWithTags writes the data observed by several data tags.
+func (c *clientImpl) WriteWithTags(data []byte, tags ...byte) (int, error) {
+	return c.write(context.Background(), "", data, tags...)
+}
+
+// WriteWithTransaction writes the data with the transaction ID.
+func (c *clientImpl) WriteWithTransaction(tid string, data []byte, tags ...byte) (int, error) {
+	if tid == "" {
+		return 0, errors.New("[Source] the transaction ID is empty")
+	}
+	return c.write(context.Background(), tid, data, tags...)
+}
+
+// write the data with the data tags, the first tag is the tag of payload. The transaction ID is generated if
+// txid is empty.
+func (c *clientImpl) write(ctx context.Context, txid string, data []byte, tags ...byte) (int, error) {
 	if c.Stream == nil {
 		return 0, errors.New("[Source] Stream is nil")
 	}
+	defer c.Track()()
+	// YoMo-Zipper of the version 1 reads the whole frames in the stream only, the datagrams, partially reliable
+	// streams and chunks fall back to the stream.
+	streamOnly := c.Version() == frame.Version1
 
 	// wrap data with frame.
-	txid := strconv.FormatInt(time.Now().UnixNano(), 10)
+	if txid == "" {
+		txid = strconv.FormatInt(time.Now().UnixNano(), 10)
+	}
 	frame := frame.NewDataFrame(txid)
 	// playload frame
-	// TODO: tag id
-	frame.SetCarriage(0x10, data)
+	if len(tags) == 0 {
+		frame.SetCarriage(c.opts.dataTag, data)
+	} else {
+		frame.SetCarriage(tags[0], data)
+		frame.SetExtraTags(tags[1:]...)
+	}
+	frame.SetContentType(c.opts.contentType)
+	frame.SetSchemaID(c.opts.schemaID)
+	frame.SetChecksum(c.opts.checksum)
+	frame.SetTimestamp(time.Now())
+	// tracing
+	span := tracing.NewSpanToFrame(ctx, frame, "source", "source-write-to-zipper")
+	defer span.End()
+	if c.opts.ttl > 0 {
+		frame.SetDeadline(time.Now().Add(c.opts.ttl))
+	}
+	if c.opts.compression {
+		compressed, err := c.CompressFrame(frame)
+		if err != nil {
+			return 0, err
+		}
+		frame = compressed
+	}
+	if c.opts.keyring != nil {
+		if err := core.EncryptFrame(frame, c.opts.keyring); err != nil {
+			return 0, err
+		}
+	}
+	downgraded, err := c.Downgrade(frame)
+	if err != nil {
+		return 0, err
+	}
+	frame = downgraded
+
+	// send the small data in QUIC DATAGRAM frame.
+	if c.opts.datagram && c.Session != nil && !streamOnly {
+		buf := frame.Encode()
+		if len(buf) <= quic.MaxDatagramSize {
+			err := c.Session.SendDatagram(buf)
+			if err != nil {
+				return 0, err
+			}
+			return len(buf), nil
+		}
+	}
+
+	if c.opts.partial > 0 && c.Session != nil && !streamOnly {
+		return c.writePartial(frame)
+	}
+
+	if c.opts.chunk > 0 && !streamOnly {
+		return c.Stream.WriteFrames(core.SplitFrame(frame, c.opts.chunk))
+	}
 
 	return c.Stream.WriteFrame(frame)
 }
 
+// writePartial sends the frame in a new unidirectional stream, the stream is reset if the frame isn't
+// written within the deadline, so the late frame is not transmitted after the deadline.
+func (c *clientImpl) writePartial(f *frame.DataFrame) (int, error) {
+	deadline := time.Now().Add(c.opts.partial)
+	f.SetSequence(atomic.AddUint64(c.sequence, 1))
+
+	stream, err := c.Session.CreateUniStream(context.Background())
+	if err != nil {
+		return 0, err
+	}
+
+	// the reset is stopped once the stream is closed, the frame which has been written is delivered reliably.
+	timer := time.AfterFunc(time.Until(deadline), func() {
+		stream.CancelWrite(abandonedCode)
+	})
+	buf := f.Encode()
+	stream.SetWriteDeadline(deadline)
+	if _, err := stream.Write(buf); err != nil {
+		timer.Stop()
+		stream.CancelWrite(abandonedCode)
+		return 0, ErrFrameAbandoned
+	}
+	if err := stream.Close(); err != nil {
+		// the stream has been reset by the timer.
+		return 0, ErrFrameAbandoned
+	}
+	if !timer.Stop() {
+		// the timer fired between Write and Close, the stream may have been reset.
+		return 0, ErrFrameAbandoned
+	}
+	return len(buf), nil
+}
+
+// ReadFrom streams the data of r to downstream in its own unidirectional stream until r returns io.EOF,
+// the data is compressed and encrypted as it's read like Write. YoMo-Zipper pipes the stream to the first
+// stream function of workflow which observes the data tag, which reads the data from the io.Reader it receives.
+func (c *clientImpl) ReadFrom(r io.Reader) (int64, error) {
+	if c.Session == nil {
+		return 0, errors.New("[Source] Session is nil")
+	}
+	defer c.Track()()
+	if c.Version() == frame.Version1 {
+		return 0, frame.ErrUnsupportedVersion
+	}
+
+	txid := strconv.FormatInt(time.Now().UnixNano(), 10)
+	f := frame.NewDataFrame(txid)
+	// the reader of application is not closed after the carriage is written.
+	f.SetCarriageReader(c.opts.dataTag, ioutil.NopCloser(r))
+	f.SetContentType(c.opts.contentType)
+	f.SetSchemaID(c.opts.schemaID)
+	f.SetChecksum(c.opts.checksum)
+	f.SetTimestamp(time.Now())
+	// tracing
+	span := tracing.NewSpanToFrame(context.Background(), f, "source", "source-stream-to-zipper")
+	defer span.End()
+	if c.opts.ttl > 0 {
+		f.SetDeadline(time.Now().Add(c.opts.ttl))
+	}
+
+	if c.opts.compression {
+		compressed, err := c.CompressFrame(f)
+		if err != nil {
+			return 0, err
+		}
+		f = compressed
+	}
+	if c.opts.keyring != nil {
+		if err := core.EncryptFrame(f, c.opts.keyring); err != nil {
+			return 0, err
+		}
+	}
+
+	stream, err := c.Session.CreateUniStream(context.Background())
+	if err != nil {
+		core.CloseCarriage(f)
+		return 0, err
+	}
+	n, err := core.WriteStreamedFrame(stream, f)
+	if err != nil {
+		stream.CancelWrite(abandonedCode)
+		return n, err
+	}
+	return n, stream.Close()
+}
+
 // Connect to YoMo-Zipper.
 func (c *clientImpl) Connect(ip string, port int) (Client, error) {
 	cli, err := c.BaseConnect(ip, port)
 	return &clientImpl{
-		cli,
+		Impl:     cli,
+		opts:     c.opts,
+		sequence: c.sequence,
 	}, err
 }
@@ -0,0 +1,127 @@
+package yomo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/yomorun/yomo/core/rx"
+	"github.com/yomorun/yomo/core/serde"
+)
+
+// Stream is the stream of typed items on top of rx.Stream, the data is decoded into T by the serializer of its
+// content type, so the handlers don't assert the type of items by hand.
+//
+//	func Handler(rxstream rx.Stream) rx.Stream {
+//		noise := yomo.Observe[NoiseData](rxstream)
+//		return yomo.Map(noise, func(ctx context.Context, v NoiseData) (float32, error) {
+//			return v.Noise / 10, nil
+//		}).Encode()
+//	}
+type Stream[T any] struct {
+	stream rx.Stream
+}
+
+// Item is the typed item of Stream, E is the error of the item.
+type Item[T any] struct {
+	V T
+	E error
+}
+
+// Observe decodes the carriage of DataFrames in the rx stream into T by the serializer of the content type.
+func Observe[T any](stream rx.Stream) Stream[T] {
+	decoded := stream.UnmarshalPayload(func() interface{} {
+		return new(T)
+	}).Map(func(_ context.Context, i interface{}) (interface{}, error) {
+		return *(i.(*T)), nil
+	})
+	return Stream[T]{stream: decoded}
+}
+
+// From wraps the rx stream of which the items are T, the item which is not a T is emitted as an error.
+func From[T any](stream rx.Stream) Stream[T] {
+	return Stream[T]{stream: stream}
+}
+
+// Map transforms the items of the stream by applying the function to each item.
+func Map[T any, U any](s Stream[T], apply func(ctx context.Context, v T) (U, error)) Stream[U] {
+	mapped := s.stream.Map(func(ctx context.Context, i interface{}) (interface{}, error) {
+		v, err := cast[T](i)
+		if err != nil {
+			return nil, err
+		}
+		return apply(ctx, v)
+	})
+	return Stream[U]{stream: mapped}
+}
+
+// FlatMap transforms each item of the stream into zero or more items by applying the function to it, e.g. one batch
+// upload is split into many records, and every record is sent downstream as its own frame.
+func FlatMap[T any, U any](s Stream[T], apply func(ctx context.Context, v T) ([]U, error)) Stream[U] {
+	flattened := s.stream.FlatMapSlice(func(ctx context.Context, i interface{}) ([]interface{}, error) {
+		v, err := cast[T](i)
+		if err != nil {
+			return nil, err
+		}
+		values, err := apply(ctx, v)
+		if err != nil {
+			return nil, err
+		}
+		items := make([]interface{}, len(values))
+		for i, value := range values {
+			items[i] = value
+		}
+		return items, nil
+	})
+	return Stream[U]{stream: flattened}
+}
+
+// Filter emits only the items of the stream which pass the predicate.
+func (s Stream[T]) Filter(predicate func(v T) bool) Stream[T] {
+	filtered := s.stream.Filter(func(i interface{}) bool {
+		v, err := cast[T](i)
+		return err == nil && predicate(v)
+	})
+	return Stream[T]{stream: filtered}
+}
+
+// Observe returns the channel of the typed items, it's closed when the stream completes.
+func (s Stream[T]) Observe() <-chan Item[T] {
+	next := make(chan Item[T])
+	go func() {
+		defer close(next)
+		for item := range s.stream.Observe() {
+			if item.Error() {
+				next <- Item[T]{E: item.E}
+				continue
+			}
+			v, err := cast[T](item.V)
+			next <- Item[T]{V: v, E: err}
+		}
+	}()
+	return next
+}
+
+// Encode marshals the items by the serializer of the content type which the stream function sets by
+// WithContentType, the items must be raw bytes if it's not set. The result is the rx stream which is returned
+// by the handler of stream function.
+func (s Stream[T]) Encode() rx.Stream {
+	return s.stream.Map(func(ctx context.Context, i interface{}) (interface{}, error) {
+		contentType, _ := serde.ResponseFromContext(ctx)
+		return serde.Marshal(contentType, i)
+	})
+}
+
+// Rx returns the underlying rx stream, so the untyped operators can be used.
+func (s Stream[T]) Rx() rx.Stream {
+	return s.stream
+}
+
+// cast asserts the item to T.
+func cast[T any](i interface{}) (T, error) {
+	v, ok := i.(T)
+	if !ok {
+		var zero T
+		return zero, fmt.Errorf("yomo: the item is %T, not %T", i, zero)
+	}
+	return v, nil
+}
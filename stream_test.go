@@ -0,0 +1,86 @@
+package yomo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/core/rx"
+	"github.com/yomorun/yomo/core/serde"
+	"github.com/yomorun/yomo/internal/decoder"
+)
+
+type noiseData struct {
+	Noise float32 `json:"noise"`
+	From  string  `json:"from"`
+}
+
+func TestTypedStream(t *testing.T) {
+	ctx := serde.NewContext(context.Background(), serde.JSON, "")
+	items := []interface{}{[]byte(`{"noise":42,"from":"a"}`), []byte(`{"noise":7,"from":"b"}`)}
+	stream := rx.NewFactory().FromItemsWithDecoder(items, decoder.WithContext(ctx))
+
+	noise := Observe[noiseData](stream).Filter(func(v noiseData) bool {
+		return v.Noise > 10
+	})
+	level := Map(noise, func(_ context.Context, v noiseData) (float32, error) {
+		return v.Noise / 2, nil
+	})
+
+	for item := range level.Observe() {
+		assert.NoError(t, item.E)
+		assert.Equal(t, float32(21), item.V)
+	}
+}
+
+func TestTypedStreamMismatch(t *testing.T) {
+	stream := rx.NewFactory().FromItems(context.Background(), []interface{}{"not a number"})
+	for item := range From[int](stream).Observe() {
+		assert.EqualError(t, item.E, "yomo: the item is string, not int")
+		break
+	}
+}
+
+func TestTypedStreamFlatMap(t *testing.T) {
+	next := make(chan interface{})
+	go func() {
+		defer close(next)
+		next <- []noiseData{{Noise: 1, From: "a"}, {Noise: 2, From: "b"}}
+		next <- []noiseData{}
+	}()
+	batches := From[[]noiseData](rx.NewFactory().FromChannel(context.Background(), next))
+	noise := FlatMap(batches, func(_ context.Context, v []noiseData) ([]float32, error) {
+		values := make([]float32, len(v))
+		for i, data := range v {
+			values[i] = data.Noise
+		}
+		return values, nil
+	})
+
+	result := make([]float32, 0)
+	for item := range noise.Observe() {
+		assert.NoError(t, item.E)
+		result = append(result, item.V)
+	}
+	assert.Equal(t, []float32{1, 2}, result)
+}
+
+func TestTypedStreamEncode(t *testing.T) {
+	// the data is JSON while the responses are declared as raw bytes.
+	ctx := serde.NewContext(context.Background(), serde.JSON, "")
+	ctx = serde.NewResponseContext(ctx, serde.Raw, "")
+	items := []interface{}{[]byte(`{"noise":42,"from":"a"}`)}
+	stream := rx.NewFactory().FromItemsWithDecoder(items, decoder.WithContext(ctx))
+
+	noise := Observe[noiseData](stream)
+	from := Map(noise, func(_ context.Context, v noiseData) ([]byte, error) {
+		return []byte(v.From), nil
+	})
+
+	result := make([]interface{}, 0)
+	for item := range from.Encode().Observe() {
+		assert.NoError(t, item.E)
+		result = append(result, item.V)
+	}
+	assert.Equal(t, []interface{}{[]byte("a")}, result)
+}
@@ -3,16 +3,25 @@ package streamfunction
 import (
 	"context"
 	"errors"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/yomorun/yomo/core/dedup"
+	"github.com/yomorun/yomo/core/kms"
 	"github.com/yomorun/yomo/core/quic"
 	"github.com/yomorun/yomo/core/rx"
+	"github.com/yomorun/yomo/core/serde"
 	"github.com/yomorun/yomo/internal/client"
 	"github.com/yomorun/yomo/internal/core"
 	"github.com/yomorun/yomo/internal/decoder"
 	"github.com/yomorun/yomo/internal/frame"
 	"github.com/yomorun/yomo/logger"
 	"github.com/yomorun/yomo/zipper/tracing"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Client is the client for YoMo Stream Function.
@@ -25,28 +34,123 @@ type Client interface {
 	// Pipe the Handler function.
 	// This method is blocking.
 	Pipe(handler func(rxstream rx.Stream) rx.Stream)
+
+	// PipeFunc runs the simple Handler on the data of the observe key, and the response is encoded with the respond
+	// key by Y3 Codec, so the request/response transforms don't need Rx.
+	// This method is blocking.
+	PipeFunc(observe byte, respond byte, handler Handler)
+
+	// Subscribe adds the data tags observed by the stream function at runtime, e.g. the debug data while investigating.
+	// The change applies to all instances of the stream function until YoMo-Zipper restarts.
+	Subscribe(tags ...byte) error
+
+	// Unsubscribe removes the data tags observed by the stream function at runtime.
+	Unsubscribe(tags ...byte) error
+
+	// Relay forwards the data to the processes of stream function which connect to the listener, and their results
+	// to YoMo-Zipper, so the handler is reloaded in a new process without re-registering the stream function.
+	// This method is blocking until the listener is closed.
+	Relay(listener net.Listener) error
 }
 
+// ErrUnauthenticated is returned by Connect when YoMo-Zipper rejects the token of stream function.
+var ErrUnauthenticated = client.ErrUnauthenticated
+
+// ErrUnauthorized is returned by Connect when the stream function is not allowed to connect as its name by the policies of
+// YoMo-Zipper.
+var ErrUnauthorized = client.ErrUnauthorized
+
+// Handler is the simple handler of Stream Function, it transforms the observed data into the response, nothing is sent
+// to YoMo-Zipper if the response is nil. The handler is called concurrently, one goroutine per data.
+type Handler func(ctx context.Context, payload []byte) ([]byte, error)
+
 type clientImpl struct {
 	*client.Impl
+	dedup   *dedup.Window // dedup drops the duplicated frames, it's nil if deduplication is disabled.
+	keyring *kms.Keyring  // keyring decrypts the carriage and encrypts the responses, it's nil if not encrypted.
+	chunk   int           // chunk is the max size of each chunk of the large responses, they're not split if zero.
+
+	contentType string // contentType is the content type of the responses.
+	schemaID    string // schemaID is the ID of schema which the responses conform to.
+	checksum    bool   // checksum appends the CRC32C of each response.
+
+	stateful bool      // stateful runs the handler once on a long-lived stream of all data.
+	pipeline *pipeline // pipeline is the long-lived stream of the handler, it's nil if not stateful.
+
+	subscriptions *subscriptions // subscriptions are the tags changed at runtime, they're replayed after reconnecting.
+
+	relay  chan *frame.DataFrame // relay is the data forwarded to the processes of stream function, it's nil if not relaying.
+	worker *worker               // worker is the connection to the relay, it's nil if connected to YoMo-Zipper.
 }
 
 // New a YoMo Stream Function client.
 // The "appName" should match the name of functions in workflow.yaml in YoMo-Zipper.
-func New(appName string) Client {
+func New(appName string, opts ...Option) Client {
+	options := newOptions(opts...)
 	c := &clientImpl{
-		Impl: client.New(appName, core.ConnTypeStreamFunction),
+		Impl:          client.New(appName, core.ConnTypeStreamFunction),
+		subscriptions: &subscriptions{tags: make(map[byte]bool)},
+	}
+	c.SetTLSConfig(options.tls)
+	c.SetTCPFallback(options.tcp)
+	c.SetKeepAlive(options.ping, options.idle)
+	c.SetFlowControl(options.flow)
+	c.SetReconnectBackoff(options.retryInitial, options.retryMax)
+	c.SetOnReconnect(func() {
+		// YoMo-Zipper forgets the tags changed at runtime when the last instance is disconnected.
+		if err := c.subscriptions.replay(c.Impl); err != nil {
+			logger.Error("[Stream Function Client] replay the subscription failed.", "err", err)
+		}
+		if options.onReconnect != nil {
+			options.onReconnect()
+		}
+	})
+	c.SetProxy(options.proxy)
+	c.SetQlog(options.qlog)
+	c.SetToken(options.token)
+	if options.compression {
+		c.SetCompression(options.codecs, options.threshold)
+	}
+	if options.mux != nil {
+		c.SetMux(options.mux)
+	}
+	c.keyring = options.keyring
+	c.chunk = options.chunk
+	c.contentType = options.contentType
+	c.schemaID = options.schemaID
+	c.checksum = options.checksum
+	c.stateful = options.stateful
+	if options.dedupTTL > 0 {
+		c.dedup = dedup.New(options.dedupTTL, options.dedupSize)
 	}
 	return c
 }
 
 // Write the data to downstream.
 func (c *clientImpl) Write(data *frame.DataFrame) (int, error) {
+	if c.worker != nil {
+		return c.worker.write(data, c.keyring)
+	}
 	if c.Session == nil {
 		// the connection was disconnected, retry again.
 		c.RetryWithCount(1)
 		return 0, errors.New("[Stream Function Client] Session is nil")
 	}
+	defer c.Track()()
+
+	data, err := c.CompressFrame(data)
+	if err != nil {
+		return 0, err
+	}
+	if c.keyring != nil {
+		if err := core.EncryptFrame(data, c.keyring); err != nil {
+			return 0, err
+		}
+	}
+	data, err = c.Downgrade(data)
+	if err != nil {
+		return 0, err
+	}
 
 	// create a new stream
 	stream, err := c.Session.CreateUniStream(context.Background())
@@ -57,27 +161,141 @@ func (c *clientImpl) Write(data *frame.DataFrame) (int, error) {
 	defer stream.Close()
 
 	// tracing
-	span := tracing.NewSpanFromData(string(data.GetCarriage()), "sfn", "sfn-write-to-zipper")
+	span := tracing.NewSpanFromFrame(data, "sfn", "sfn-write-to-zipper")
 	if span != nil {
 		defer span.End()
 	}
 
-	return stream.Write(data.Encode())
+	// the large responses are split into chunks, YoMo-Zipper reassembles them.
+	chunk := c.chunk
+	if !c.Supports(frame.TagOfChunkFrame) {
+		chunk = 0
+	}
+	total := 0
+	for _, f := range core.SplitFrame(data, chunk) {
+		n, err := stream.Write(f.Encode())
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
 }
 
-// Connect to YoMo-Zipper.
+// Connect to YoMo-Zipper, or to the relay if the environment variable RelayAddrEnv is set.
 func (c *clientImpl) Connect(ip string, port int) (Client, error) {
+	if addr := os.Getenv(RelayAddrEnv); addr != "" {
+		return c.connectRelay(addr)
+	}
 	cli, err := c.BaseConnect(ip, port)
+	return c.connected(cli), err
+}
+
+// connected returns the client connected by cli with the same options.
+func (c *clientImpl) connected(cli *client.Impl) *clientImpl {
 	return &clientImpl{
-		cli,
-	}, err
+		Impl:    cli,
+		dedup:   c.dedup,
+		keyring: c.keyring,
+		chunk:   c.chunk,
+
+		contentType: c.contentType,
+		schemaID:    c.schemaID,
+		checksum:    c.checksum,
+		stateful:    c.stateful,
+
+		subscriptions: c.subscriptions,
+	}
+}
+
+// Close the connection to YoMo-Zipper or to the relay.
+func (c *clientImpl) Close() error {
+	if c.worker != nil {
+		return c.worker.conn.Close()
+	}
+	return c.Impl.Close()
+}
+
+// Subscribe adds the data tags observed by the stream function, YoMo-Zipper updates its routing table live.
+func (c *clientImpl) Subscribe(tags ...byte) error {
+	c.subscriptions.update(tags, true)
+	return c.sendControl(frame.NewControlFrame(tags, nil))
+}
+
+// Unsubscribe removes the data tags observed by the stream function, YoMo-Zipper updates its routing table live.
+func (c *clientImpl) Unsubscribe(tags ...byte) error {
+	c.subscriptions.update(tags, false)
+	return c.sendControl(frame.NewControlFrame(nil, tags))
+}
+
+// sendControl sends the control frame to YoMo-Zipper, or to the relay which sends it on behalf of the process.
+func (c *clientImpl) sendControl(f *frame.ControlFrame) error {
+	if c.worker != nil {
+		_, err := c.worker.write(f, nil)
+		return err
+	}
+	return c.SendControl(f)
+}
+
+// subscriptions are the data tags subscribed or unsubscribed at runtime.
+type subscriptions struct {
+	mutex sync.Mutex
+	tags  map[byte]bool // the tag is subscribed if true, unsubscribed if false.
+}
+
+// update records the tags are subscribed or unsubscribed.
+func (s *subscriptions) update(tags []byte, subscribed bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, tag := range tags {
+		s.tags[tag] = subscribed
+	}
+}
+
+// frame returns the control frame of the changed tags, it's nil if no tag is changed.
+func (s *subscriptions) frame() *frame.ControlFrame {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if len(s.tags) == 0 {
+		return nil
+	}
+
+	subscribe, unsubscribe := make([]byte, 0), make([]byte, 0)
+	for tag, subscribed := range s.tags {
+		if subscribed {
+			subscribe = append(subscribe, tag)
+		} else {
+			unsubscribe = append(unsubscribe, tag)
+		}
+	}
+	return frame.NewControlFrame(subscribe, unsubscribe)
+}
+
+// replay sends the changed tags to YoMo-Zipper again.
+func (s *subscriptions) replay(c *client.Impl) error {
+	f := s.frame()
+	if f == nil {
+		return nil
+	}
+	return c.SendControl(f)
 }
 
 // Pipe the handler function in Stream Function.
 // This method is blocking.
 func (c *clientImpl) Pipe(handler func(rxstream rx.Stream) rx.Stream) {
 	fac := rx.NewFactory()
+	if c.stateful {
+		c.pipeline = c.startPipeline(handler, fac)
+	}
+	if c.worker != nil {
+		c.worker.run(c, handler, fac)
+		return
+	}
+	c.accept(handler, fac)
+}
 
+// accept accepts the QUIC streams from zipper, and runs `Handler` on the data of each stream.
+func (c *clientImpl) accept(handler func(rxstream rx.Stream) rx.Stream, fac rx.Factory) {
 	for {
 		// TODO: escape out of here, cause will enter endless loop if c.Session has been destroyed
 		if c.Session == nil {
@@ -98,34 +316,120 @@ func (c *clientImpl) Pipe(handler func(rxstream rx.Stream) rx.Stream) {
 	}
 }
 
+// PipeFunc runs the simple handler in Stream Function.
+// This method is blocking.
+func (c *clientImpl) PipeFunc(observe byte, respond byte, handler Handler) {
+	c.Pipe(rxHandler(observe, respond, handler))
+}
+
+// rxHandler adapts the simple handler to the handler of rx.Stream.
+func rxHandler(observe byte, respond byte, handler Handler) func(rxstream rx.Stream) rx.Stream {
+	return func(rxstream rx.Stream) rx.Stream {
+		return rxstream.
+			Subscribe(observe).
+			OnObserveWithContext(func(ctx context.Context, v []byte) (interface{}, error) {
+				return handler(ctx, v)
+			}).
+			Filter(func(i interface{}) bool {
+				buf, ok := i.([]byte)
+				return ok && buf != nil
+			}).
+			Encode(respond)
+	}
+}
+
 // readStreamAndRunHandler reads the QUIC stream from zipper and run `Handler`.
+// The frames batched by YoMo-Zipper are read until the end of stream, each of them runs the handler concurrently
+// like the frames in their own streams.
 func (c *clientImpl) readStreamAndRunHandler(stream quic.ReceiveStream, handler func(rxstream rx.Stream) rx.Stream, fac rx.Factory) {
-	f, err := core.ParseFrame(stream)
-	if err != nil {
-		logger.Error("[Stream Function Client] receive data from zipper failed.", "err", err)
+	for i := 0; ; i++ {
+		f, err := readFrame(stream)
+		if err == io.EOF && i > 0 {
+			return
+		}
+		if err != nil {
+			logger.Error("[Stream Function Client] receive data from zipper failed.", "err", err)
+			return
+		}
+
+		if f.Type() != frame.TagOfDataFrame {
+			logger.Debug("[Stream Function Client] YoMo-Zipper received frame from `stream-fn`, but the frame type is not a DataFrame.", "type", f.Type().String())
+			return
+		}
+
+		dataFrame := f.(*frame.DataFrame)
+		// the handler is in flight until its results are written, Close waits for it.
+		done := c.Track()
+		// the streamed carriage is the rest of stream.
+		if dataFrame.Streamed() {
+			defer done()
+			c.handleDataFrame(dataFrame, handler, fac)
+			return
+		}
+		go func() {
+			defer done()
+			c.handleDataFrame(dataFrame, handler, fac)
+		}()
+	}
+}
+
+// handleDataFrame decrypts and decompresses the DataFrame from zipper, and runs `Handler` with its carriage.
+func (c *clientImpl) handleDataFrame(dataFrame *frame.DataFrame, handler func(rxstream rx.Stream) rx.Stream, fac rx.Factory) {
+	// the streamed carriage is decrypted and decompressed as the handler reads it.
+	defer core.CloseCarriage(dataFrame)
+	if c.relay != nil {
+		c.forward(dataFrame)
+		return
+	}
+	if dataFrame.KeyID() != "" && c.keyring == nil {
+		logger.Error("[Stream Function Client] the data is encrypted, but the keyring is not set.", "TransactionID", dataFrame.TransactionID())
+		return
+	}
+	if c.keyring != nil {
+		if err := core.DecryptFrame(dataFrame, c.keyring); err != nil {
+			logger.Error("[Stream Function Client] decrypt the data from zipper failed.", "TransactionID", dataFrame.TransactionID(), "err", err)
+			return
+		}
+	}
+	if err := c.DecompressFrame(dataFrame); err != nil {
+		logger.Error("[Stream Function Client] decompress the data from zipper failed.", "err", err)
+		return
+	}
+	if dataFrame.Streamed() {
+		c.runStreamedHandler(dataFrame, handler, fac)
 		return
 	}
 
-	if f.Type() != frame.TagOfDataFrame {
-		logger.Debug("[Stream Function Client] YoMo-Zipper received frame from `stream-fn`, but the frame type is not a DataFrame.", "type", f.Type().String())
+	if c.dedup != nil && c.dedup.Seen(dataFrame.TransactionID(), dataFrame.GetCarriage()) {
+		logger.Debug("[Stream Function Client] drop the duplicated frame.", "TransactionID", dataFrame.TransactionID())
 		return
 	}
 
-	dataFrame := f.(*frame.DataFrame)
+	if c.pipeline != nil {
+		c.pipeline.push(dataFrame)
+		return
+	}
 
 	// tracing
-	span := tracing.NewSpanFromData(string(dataFrame.GetCarriage()), "sfn", "sfn-read-stream-and-run-handler")
+	span := tracing.NewSpanFromFrame(dataFrame, "sfn", "sfn-read-stream-and-run-handler")
 	if span != nil {
 		defer span.End()
 	}
 
 	logger.Debug("[Stream Function Client] received data from zipper.")
 
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := c.handlerContext(dataFrame, span)
 	defer cancel()
 
+	if ctx.Err() != nil {
+		logger.Debug("[Stream Function Client] the frame was expired, won't run the handler.", "TransactionID", dataFrame.TransactionID())
+		return
+	}
+
 	// TODO: remove Rx
-	rxstream := fac.FromItemsWithDecoder([]interface{}{dataFrame.GetCarriage()}, decoder.WithContext(ctx))
+	// the payload is observed by all tags of the frame.
+	tags := append([]byte{dataFrame.GetDataTagID()}, dataFrame.ExtraTags()...)
+	rxstream := fac.FromItemsWithDecoder([]interface{}{dataFrame.GetCarriage()}, decoder.WithContext(ctx), decoder.WithTags(tags...))
 
 	for item := range rxstream.Observe() {
 		if item.Error() {
@@ -140,7 +444,146 @@ func (c *clientImpl) readStreamAndRunHandler(stream quic.ReceiveStream, handler
 
 }
 
-// runHandler runs the `Handler` and sends the result to zipper if the stream function returns a new data.
+// readFrame reads a frame from the stream of zipper, the large frame is reassembled from its chunks.
+func readFrame(stream io.Reader) (frame.Frame, error) {
+	reassembler := core.NewReassembler(core.DefaultMaxFrameSize)
+	for {
+		f, err := core.ParseFrame(stream)
+		if err != nil {
+			return nil, err
+		}
+		chunk, ok := f.(*frame.ChunkFrame)
+		if !ok {
+			return f, nil
+		}
+
+		dataFrame, err := reassembler.Push(chunk)
+		if err != nil {
+			return nil, err
+		}
+		if dataFrame != nil {
+			return dataFrame, nil
+		}
+	}
+}
+
+// pipelineBufferSize is the max count of data waiting for the handler in the long-lived stream.
+const pipelineBufferSize = 100
+
+// pipeline is the long-lived stream which the handler runs on once, so the stateful operators keep their state
+// across the data.
+type pipeline struct {
+	items  chan interface{}
+	latest atomic.Value // latest is the latest DataFrame received, the results inherit its metadata.
+}
+
+// startPipeline runs the handler on a long-lived stream, and sends its results to zipper until the stream ends.
+func (c *clientImpl) startPipeline(handler func(rxstream rx.Stream) rx.Stream, fac rx.Factory) *pipeline {
+	p := &pipeline{items: make(chan interface{}, pipelineBufferSize)}
+	ctx := serde.NewResponseContext(context.Background(), c.contentType, c.schemaID)
+	stream := handler(fac.FromChannel(ctx, p.items))
+
+	go func() {
+		for item := range stream.Observe() {
+			if item.Error() {
+				logger.Error("[Stream Function Client] Handler got the error.", "err", item.E)
+				continue
+			}
+			if item.V == nil {
+				logger.Debug("[Stream Function Client] the returned data of Handler is nil.")
+				continue
+			}
+			if latest, ok := p.latest.Load().(*frame.DataFrame); ok {
+				c.writeResult(item.V, latest)
+			}
+		}
+	}()
+	return p
+}
+
+// push the data to the stream of pipeline, the payload is observed by all tags of the frame.
+func (p *pipeline) push(dataFrame *frame.DataFrame) {
+	p.latest.Store(dataFrame)
+	tags := append([]byte{dataFrame.GetDataTagID()}, dataFrame.ExtraTags()...)
+	p.items <- decoder.FromItems([]interface{}{dataFrame.GetCarriage()}, decoder.WithTags(tags...))
+}
+
+// runStreamedHandler runs `Handler` with the io.Reader of the streamed carriage, which is read from the QUIC
+// stream as the handler consumes it, so the handler should read it before returning.
+func (c *clientImpl) runStreamedHandler(dataFrame *frame.DataFrame, handler func(rxstream rx.Stream) rx.Stream, fac rx.Factory) {
+	logger.Debug("[Stream Function Client] received the streamed data from zipper.", "TransactionID", dataFrame.TransactionID())
+
+	ctx, cancel := c.handlerContext(dataFrame, nil)
+	defer cancel()
+
+	if ctx.Err() != nil {
+		logger.Debug("[Stream Function Client] the frame was expired, won't run the handler.", "TransactionID", dataFrame.TransactionID())
+		return
+	}
+
+	// the handler is cancelled if the stream is reset, e.g. the source abandons the carriage.
+	c.runHandler(ctx, &cancelOnErrorReader{Reader: dataFrame.CarriageReader(), cancel: cancel}, dataFrame, handler, fac)
+}
+
+// cancelOnErrorReader cancels the context of handler when the carriage fails to be read, the handler can't
+// get the rest of carriage anymore.
+type cancelOnErrorReader struct {
+	io.Reader
+	cancel context.CancelFunc
+}
+
+func (r *cancelOnErrorReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if err != nil && err != io.EOF {
+		r.cancel()
+	}
+	return n, err
+}
+
+// handlerContext returns the context of handler with the span of frame, it's cancelled when the deadline of frame
+// is exceeded or the session to YoMo-Zipper is closed, since the result can't be sent back anymore.
+func (c *clientImpl) handlerContext(dataFrame *frame.DataFrame, span trace.Span) (context.Context, context.CancelFunc) {
+	ctx, cancelFrame := frameContext(dataFrame)
+	ctx = serde.NewResponseContext(ctx, c.contentType, c.schemaID)
+	if span != nil {
+		ctx = trace.ContextWithSpan(ctx, span)
+	}
+	// the session is replaced when reconnecting, so its context is read once.
+	session := c.SessionContext()
+	if session == nil {
+		return ctx, cancelFrame
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-session.Done():
+			logger.Debug("[Stream Function Client] the session is closed, cancel the handler.", "TransactionID", dataFrame.TransactionID())
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		cancel()
+		cancelFrame()
+	}
+}
+
+// frameContext returns the context of handler, it's cancelled when the deadline of frame is exceeded.
+// The content type and the schema ID of the carriage, the transaction ID, the timestamp and the hops are carried
+// in the context.
+func frameContext(dataFrame *frame.DataFrame) (context.Context, context.CancelFunc) {
+	ctx := serde.NewContext(context.Background(), dataFrame.ContentType(), dataFrame.SchemaID())
+	ctx = withAccounting(ctx, dataFrame)
+	ctx = context.WithValue(ctx, transactionIDKey{}, dataFrame.TransactionID())
+	if deadline, ok := dataFrame.Deadline(); ok {
+		return context.WithDeadline(ctx, deadline)
+	}
+	return context.WithCancel(ctx)
+}
+
+// runHandler runs the `Handler` and sends each result to zipper in its own frame, so the handler can emit zero to
+// many results per data, e.g. by FlatMapSlice.
 // TODO: remove Rx
 func (c *clientImpl) runHandler(ctx context.Context, data interface{}, dataFrame *frame.DataFrame, handler func(rxstream rx.Stream) rx.Stream, fac rx.Factory) {
 	stream := handler(fac.FromItems(ctx, []interface{}{data}))
@@ -148,31 +591,52 @@ func (c *clientImpl) runHandler(ctx context.Context, data interface{}, dataFrame
 	for item := range stream.Observe() {
 		if item.Error() {
 			logger.Error("[Stream Function Client] Handler got the error.", "err", item.E)
-			break
+			continue
 		}
 
 		if item.V == nil {
 			logger.Debug("[Stream Function Client] the returned data of Handler is nil.")
-			break
+			continue
 		}
 
-		buf, ok := (item.V).([]byte)
-		if !ok {
-			logger.Debug("[Stream Function Client] the data is not a []byte in RxStream, won't send it to YoMo-Zipper.")
-			break
-		}
+		c.writeResult(item.V, dataFrame)
+	}
+}
 
-		// send data to YoMo-Zipper.
-		// TODO: tag id should be set by user.
-		dataFrame.SetCarriage(0x13, buf)
-		_, err := c.Write(dataFrame)
-		if err != nil {
-			logger.Error("[Stream Function Client] ❌ Send data to YoMo-Zipper failed.", "err", err)
-		} else {
-			logger.Debug("[Stream Function Client] Send data to YoMo-Zipper.")
+// writeResult sends a result of `Handler` to zipper, the frame of result inherits the metadata of dataFrame.
+func (c *clientImpl) writeResult(v interface{}, dataFrame *frame.DataFrame) {
+	// TODO: tag id should be set by user.
+	var tag byte = 0x13
+	var buf []byte
+	contentType := c.contentType
+	switch v := v.(type) {
+	case []byte:
+		buf = v
+	case rx.TaggedData:
+		// the tag is selected by the Split or EncodeWith operator.
+		tag, buf = v.Tag, v.Data
+		if v.ContentType != "" {
+			contentType = v.ContentType
 		}
+	default:
+		logger.Debug("[Stream Function Client] the data is not a []byte in RxStream, won't send it to YoMo-Zipper.")
+	}
+	if buf == nil {
+		return
+	}
 
-		// one data per time.
-		break
+	// send data to YoMo-Zipper, each result is sent in a copy of the frame since Write modifies it.
+	result := dataFrame.Clone()
+	result.SetCarriage(tag, buf)
+	// the response is observed by its own tag only.
+	result.SetExtraTags()
+	result.SetContentType(contentType)
+	result.SetSchemaID(c.schemaID)
+	result.SetChecksum(c.checksum)
+	_, err := c.Write(result)
+	if err != nil {
+		logger.Error("[Stream Function Client] ❌ Send data to YoMo-Zipper failed.", "err", err)
+	} else {
+		logger.Debug("[Stream Function Client] Send data to YoMo-Zipper.")
 	}
 }
@@ -1,14 +1,25 @@
 package streamfunction
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
 	"testing"
+	"testing/iotest"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/yomorun/y3-codec-golang"
+	"github.com/yomorun/yomo/core/kms"
 	"github.com/yomorun/yomo/core/rx"
+	"github.com/yomorun/yomo/internal/core"
+	"github.com/yomorun/yomo/internal/frame"
+	"github.com/yomorun/yomo/source"
 	mocksource "github.com/yomorun/yomo/source/mock"
+	"github.com/yomorun/yomo/zipper"
 	mockserver "github.com/yomorun/yomo/zipper/mock"
 )
 
@@ -107,6 +118,181 @@ func TestProcessDataWithY3Codec(t *testing.T) {
 	}
 }
 
+func TestProcessDataWithHandler(t *testing.T) {
+	const port = 8112
+	responses := serveWithCollector(t, port, "test pipe func")
+
+	// test data
+	var dataKey byte = 0x10
+	buf, err := y3.NewCodec(dataKey).Marshal("test")
+	assert.NoError(t, err)
+
+	// handler transforms the data without Rx.
+	handler := func(_ context.Context, payload []byte) ([]byte, error) {
+		data, err := y3.ToUTF8String(payload)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(data), nil
+	}
+
+	// run Stream-Function to process the data in real-time.
+	cli, err := New("test pipe func").Connect(mockserver.IP, port)
+	assert.NoError(t, err)
+	defer cli.Close()
+	go cli.PipeFunc(dataKey, 0x11, handler)
+
+	// the response is encoded with the respond key.
+	expected, err := y3.NewCodec(0x11).Marshal([]byte("test"))
+	assert.NoError(t, err)
+	sendUntilResponded(t, port, buf, responses, func(response []byte) bool {
+		assert.Equal(t, expected, response)
+		return true
+	})
+}
+
+func TestProcessDataWithManyResults(t *testing.T) {
+	const port = 8113
+	responses := serveWithCollector(t, port, "test flat map")
+
+	// the batch is split into one result per line.
+	handler := func(rxstream rx.Stream) rx.Stream {
+		return rxstream.
+			RawBytes().
+			FlatMapSlice(func(_ context.Context, i interface{}) ([]interface{}, error) {
+				var results []interface{}
+				for _, line := range bytes.Split(i.([]byte), []byte("\n")) {
+					results = append(results, line)
+				}
+				return results, nil
+			})
+	}
+
+	cli, err := New("test flat map").Connect(mockserver.IP, port)
+	assert.NoError(t, err)
+	defer cli.Close()
+	go cli.Pipe(handler)
+
+	received := make(map[string]bool)
+	sendUntilResponded(t, port, []byte("a\nb\nc"), responses, func(response []byte) bool {
+		received[string(response)] = true
+		return len(received) == 3
+	})
+	assert.Equal(t, map[string]bool{"a": true, "b": true, "c": true}, received)
+}
+
+func TestProcessDataWithExtraTags(t *testing.T) {
+	const port = 8114
+	responses := serveWithCollector(t, port, "test alerting")
+
+	// the stream function observes the extra tag of frame.
+	handler := func(rxstream rx.Stream) rx.Stream {
+		return rxstream.
+			Subscribe(0x20).
+			OnObserve(func(v []byte) (interface{}, error) {
+				return y3.ToUTF8String(v)
+			}).
+			Encode(0x11)
+	}
+
+	cli, err := New("test alerting").Connect(mockserver.IP, port)
+	assert.NoError(t, err)
+	defer cli.Close()
+	go cli.Pipe(handler)
+
+	// the payload is encoded with the tag of payload, and observed by both tags.
+	buf, err := y3.NewCodec(0x10).Marshal("noise")
+	assert.NoError(t, err)
+	expected, err := y3.NewCodec(0x11).Marshal("noise")
+	assert.NoError(t, err)
+	sendUntilResponded(t, port, buf, responses, func(response []byte) bool {
+		assert.Equal(t, expected, response)
+		return true
+	}, 0x10, 0x20)
+}
+
+func TestProcessDataWithStatefulPipeline(t *testing.T) {
+	const port = 8115
+	responses := serveWithCollector(t, port, "test stateful")
+
+	// the count of data is kept across the data.
+	handler := func(rxstream rx.Stream) rx.Stream {
+		return rxstream.
+			RawBytes().
+			ScanByKey(func(interface{}) string { return "count" }, func(_ context.Context, acc interface{}, _ interface{}) (interface{}, error) {
+				count, _ := acc.(int)
+				return count + 1, nil
+			}).
+			Map(func(_ context.Context, i interface{}) (interface{}, error) {
+				return []byte(fmt.Sprint(i.(rx.KeyedItem).Value)), nil
+			})
+	}
+
+	cli, err := New("test stateful", WithStatefulPipeline()).Connect(mockserver.IP, port)
+	assert.NoError(t, err)
+	defer cli.Close()
+	go cli.Pipe(handler)
+
+	sendUntilResponded(t, port, []byte("noise"), responses, func(response []byte) bool {
+		return string(response) == "3"
+	})
+}
+
+// serveWithCollector serves a YoMo-Zipper whose workflow pipes the stream function into a local stream function,
+// the local stream function collects the responses of the stream function.
+func serveWithCollector(t *testing.T, port int, funcName string) chan []byte {
+	responses := make(chan []byte, 100)
+	collect := func(data []byte) ([]byte, error) {
+		responses <- data
+		return nil, nil
+	}
+
+	svr := zipper.New(&zipper.WorkflowConfig{
+		Workflow: zipper.Workflow{
+			Functions: []zipper.App{{Name: funcName}, {Name: "collector"}},
+		},
+	}, zipper.WithLocalStreamFunc("collector", collect))
+	go svr.Serve(fmt.Sprintf("%s:%d", mockserver.IP, port))
+	t.Cleanup(func() {
+		svr.Close()
+	})
+	return responses
+}
+
+// sendUntilResponded sends the data from a YoMo-Source until done returns true for a response, the data is sent
+// again periodically since the stream function may not be ready when the first data arrives.
+// The data is written with the tags if they're not empty.
+func sendUntilResponded(t *testing.T, port int, data []byte, responses chan []byte, done func(response []byte) bool, tags ...byte) {
+	src, err := source.New("test source").Connect(mockserver.IP, port)
+	assert.NoError(t, err)
+	defer src.Close()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		if len(tags) > 0 {
+			_, err = src.WriteWithTags(data, tags...)
+		} else {
+			_, err = src.Write(data)
+		}
+		assert.NoError(t, err)
+
+		retry := time.After(200 * time.Millisecond)
+	WAIT:
+		for {
+			select {
+			case response := <-responses:
+				if done(response) {
+					return
+				}
+			case <-retry:
+				break WAIT
+			case <-timeout:
+				t.Fatal("the stream function didn't respond")
+			}
+		}
+	}
+}
+
 func TestReceiveDataWithJSON(t *testing.T) {
 	// new a YoMo-Zipper.
 	go mockserver.New()
@@ -149,3 +335,86 @@ func TestReceiveDataWithJSON(t *testing.T) {
 		t.Errorf("[stream-fn] SendDataToYoMoServer expected err is nil, but got %v", err)
 	}
 }
+
+func TestFrameContext(t *testing.T) {
+	f := frame.NewDataFrame("tid")
+	ctx, cancel := frameContext(f)
+	_, ok := ctx.Deadline()
+	assert.False(t, ok)
+	cancel()
+
+	deadline := time.Now().Add(-time.Second)
+	f.SetDeadline(deadline)
+	ctx, cancel = frameContext(f)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, ctx.Err())
+}
+
+func TestReadFrameOfChunks(t *testing.T) {
+	payload := bytes.Repeat([]byte("yomo"), 1024)
+	f := frame.NewDataFrame("tid")
+	f.SetCarriage(0x10, payload)
+
+	var buf bytes.Buffer
+	for _, chunk := range core.SplitFrame(f, 1024) {
+		buf.Write(chunk.Encode())
+	}
+
+	received, err := readFrame(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, payload, received.(*frame.DataFrame).GetCarriage())
+}
+
+func TestCancelOnErrorReader(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &cancelOnErrorReader{Reader: bytes.NewReader([]byte("yomo")), cancel: cancel}
+	_, err := ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	assert.NoError(t, ctx.Err())
+
+	// the stream is reset while the handler is reading the carriage.
+	ctx, cancel = context.WithCancel(context.Background())
+	r = &cancelOnErrorReader{Reader: iotest.ErrReader(errors.New("stream reset")), cancel: cancel}
+	_, err = ioutil.ReadAll(r)
+	assert.Error(t, err)
+	assert.Equal(t, context.Canceled, ctx.Err())
+}
+
+func TestSubscriptionsReplay(t *testing.T) {
+	s := &subscriptions{tags: make(map[byte]bool)}
+	assert.Nil(t, s.frame())
+
+	s.update([]byte{0x11, 0x12}, true)
+	s.update([]byte{0x12, 0x10}, false)
+	f := s.frame()
+	assert.Equal(t, []byte{0x11}, f.Subscribe)
+	assert.ElementsMatch(t, []byte{0x10, 0x12}, f.Unsubscribe)
+}
+
+func TestRejectPlaintextWithKeyring(t *testing.T) {
+	k, err := kms.NewKey(32)
+	assert.NoError(t, err)
+	keyring := kms.NewKeyring(nil)
+	keyring.Rotate(k)
+	c := New("test-encrypted-fn", WithEncryption(keyring)).(*clientImpl)
+
+	var received []string
+	handler := func(rxstream rx.Stream) rx.Stream {
+		return rxstream.RawBytes().Map(func(_ context.Context, i interface{}) (interface{}, error) {
+			received = append(received, string(i.([]byte)))
+			return nil, nil
+		})
+	}
+
+	// the plaintext injected by a YoMo-Zipper in the path is dropped.
+	plaintext := frame.NewDataFrame("1")
+	plaintext.SetCarriage(0x10, []byte("forged"))
+	c.handleDataFrame(plaintext, handler, rx.NewFactory())
+	assert.Empty(t, received)
+
+	encrypted := frame.NewDataFrame("2")
+	encrypted.SetCarriage(0x10, []byte("yomo"))
+	assert.NoError(t, core.EncryptFrame(encrypted, keyring))
+	c.handleDataFrame(encrypted, handler, rx.NewFactory())
+	assert.Equal(t, []string{"yomo"}, received)
+}
@@ -0,0 +1,57 @@
+package streamfunction
+
+import (
+	"context"
+	"time"
+
+	"github.com/yomorun/yomo/internal/frame"
+)
+
+// transactionIDKey is the key of the transaction ID of the data in the context of handler.
+type transactionIDKey struct{}
+
+// TransactionID returns the transaction ID of the data which the handler is processing, the ctx is the context
+// passed to the handler. The tracing span of data is got by trace.SpanFromContext.
+func TransactionID(ctx context.Context) string {
+	id, _ := ctx.Value(transactionIDKey{}).(string)
+	return id
+}
+
+// accountingKey is the key of the latency accounting of the data in the context of handler.
+type accountingKey struct{}
+
+// accounting is the latency accounting of the data which the handler is processing.
+type accounting struct {
+	timestamp time.Time // the time when the source created the data, it's zero if not stamped.
+	hops      uint32    // the count of YoMo-Zippers which the data passed through.
+}
+
+// withAccounting returns a copy of ctx which carries the timestamp and the hops of frame.
+func withAccounting(ctx context.Context, dataFrame *frame.DataFrame) context.Context {
+	timestamp, _ := dataFrame.Timestamp()
+	return context.WithValue(ctx, accountingKey{}, accounting{timestamp: timestamp, hops: dataFrame.Hops()})
+}
+
+// Timestamp returns the time when the source created the data, ok is false when the source didn't stamp it.
+// The ctx is the context passed to the handler.
+func Timestamp(ctx context.Context) (timestamp time.Time, ok bool) {
+	t, _ := ctx.Value(accountingKey{}).(accounting)
+	return t.timestamp, !t.timestamp.IsZero()
+}
+
+// Latency returns the end-to-end latency from the source to now, it's zero when the data is not stamped.
+// The clocks of the source and the stream function should be synchronized, e.g. by NTP.
+func Latency(ctx context.Context) time.Duration {
+	timestamp, ok := Timestamp(ctx)
+	if !ok {
+		return 0
+	}
+	return time.Since(timestamp)
+}
+
+// Hops returns the count of YoMo-Zippers which the data passed through, e.g. 2 in a cascaded mesh of
+// two YoMo-Zippers.
+func Hops(ctx context.Context) uint32 {
+	t, _ := ctx.Value(accountingKey{}).(accounting)
+	return t.hops
+}
@@ -0,0 +1,30 @@
+package streamfunction
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/internal/frame"
+)
+
+func TestFrameContextAccounting(t *testing.T) {
+	f := frame.NewDataFrame("tid")
+	ctx, cancel := frameContext(f)
+	_, ok := Timestamp(ctx)
+	assert.False(t, ok)
+	assert.Equal(t, time.Duration(0), Latency(ctx))
+	assert.Equal(t, "tid", TransactionID(ctx))
+	cancel()
+
+	timestamp := time.Now().Add(-time.Second)
+	f.SetTimestamp(timestamp)
+	f.IncrHops()
+	ctx, cancel = frameContext(f)
+	defer cancel()
+	stamped, ok := Timestamp(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, timestamp.UnixNano(), stamped.UnixNano())
+	assert.GreaterOrEqual(t, Latency(ctx), time.Second)
+	assert.Equal(t, uint32(1), Hops(ctx))
+}
@@ -0,0 +1,30 @@
+package streamfunction
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// HealthHandler serves the probes of Kubernetes for the process of stream function, it's mounted on any HTTP server.
+// GET /healthz always succeeds while the process is running.
+// GET /readyz succeeds when the stream function is connected to YoMo-Zipper, otherwise the status is 503.
+func HealthHandler(c Client) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, true)
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, c.Connected())
+	})
+	return mux
+}
+
+func writeHealth(w http.ResponseWriter, ok bool) {
+	status, body := http.StatusOK, map[string]string{"status": "ok"}
+	if !ok {
+		status, body = http.StatusServiceUnavailable, map[string]string{"status": "unavailable"}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,26 @@
+package streamfunction
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthHandler(t *testing.T) {
+	cli := New("health-fn")
+	server := httptest.NewServer(HealthHandler(cli))
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "/healthz")
+	assert.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	// the stream function isn't connected.
+	res, err = http.Get(server.URL + "/readyz")
+	assert.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+}
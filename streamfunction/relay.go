@@ -0,0 +1,146 @@
+package streamfunction
+
+import (
+	"io"
+	"net"
+	"sync"
+
+	"github.com/yomorun/yomo/core/kms"
+	"github.com/yomorun/yomo/core/rx"
+	"github.com/yomorun/yomo/internal/core"
+	"github.com/yomorun/yomo/internal/frame"
+	"github.com/yomorun/yomo/logger"
+)
+
+// RelayAddrEnv is the environment variable of the address of relay, the stream function connects to the relay
+// instead of YoMo-Zipper if it's set, e.g. yomo dev reloads the stream function without re-registering it.
+const RelayAddrEnv = "YOMO_SFN_RELAY_ADDR"
+
+// relayBufferSize is the max count of data waiting for a process of stream function, e.g. while it's rebuilt.
+const relayBufferSize = 100
+
+// Relay keeps the session to YoMo-Zipper, and forwards the data to the processes of stream function which connect to
+// the listener. The data is held until a process connects, and is shared by the processes connected at the same
+// time, e.g. the old one and the new one while reloading.
+func (c *clientImpl) Relay(listener net.Listener) error {
+	c.relay = make(chan *frame.DataFrame, relayBufferSize)
+	go c.accept(nil, nil)
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		logger.Printf("✅ The stream function connected to the relay from %s.", conn.RemoteAddr())
+		go c.serveWorker(conn)
+	}
+}
+
+// forward sends the data from YoMo-Zipper to the processes of stream function, the encrypted carriage is decrypted by
+// them since the relay doesn't have the keyring.
+func (c *clientImpl) forward(dataFrame *frame.DataFrame) {
+	if dataFrame.Streamed() {
+		logger.Error("[Stream Function Client] the streamed data can't be relayed, drop it.", "TransactionID", dataFrame.TransactionID())
+		return
+	}
+	if err := c.DecompressFrame(dataFrame); err != nil {
+		logger.Error("[Stream Function Client] decompress the data from zipper failed.", "err", err)
+		return
+	}
+	c.relay <- dataFrame
+}
+
+// serveWorker sends the data to the process of stream function on conn, and sends its results and the changes of
+// its subscriptions to YoMo-Zipper until conn is closed.
+func (c *clientImpl) serveWorker(conn net.Conn) {
+	defer conn.Close()
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			f, err := readFrame(conn)
+			if err != nil {
+				if err != io.EOF {
+					logger.Error("[Stream Function Client] receive data from the relayed process failed.", "err", err)
+				}
+				return
+			}
+			switch f := f.(type) {
+			case *frame.DataFrame:
+				if _, err := c.Write(f); err != nil {
+					logger.Error("[Stream Function Client] ❌ Send data to YoMo-Zipper failed.", "err", err)
+				}
+			case *frame.ControlFrame:
+				c.subscriptions.update(f.Subscribe, true)
+				c.subscriptions.update(f.Unsubscribe, false)
+				if err := c.SendControl(f); err != nil {
+					logger.Error("[Stream Function Client] send the subscription to YoMo-Zipper failed.", "err", err)
+				}
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-closed:
+			logger.Printf("The stream function from %s disconnected from the relay.", conn.RemoteAddr())
+			return
+		case dataFrame := <-c.relay:
+			if _, err := conn.Write(dataFrame.Encode()); err != nil {
+				logger.Error("[Stream Function Client] send data to the relayed process failed, drop it.", "TransactionID", dataFrame.TransactionID(), "err", err)
+			}
+		}
+	}
+}
+
+// worker is the connection of the process of stream function to the relay.
+type worker struct {
+	mutex sync.Mutex
+	conn  net.Conn
+}
+
+// connectRelay connects to the relay at addr instead of YoMo-Zipper.
+func (c *clientImpl) connectRelay(addr string) (Client, error) {
+	cli := c.connected(c.Impl)
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		logger.Printf("❌ Connect to the relay %s failed.", addr)
+		return cli, err
+	}
+	cli.worker = &worker{conn: conn}
+	logger.Printf("✅ Connected to the relay %s.", addr)
+	return cli, nil
+}
+
+// write sends the frame to the relay, the data is encrypted by the keyring if it's not nil, the relay compresses
+// and chunks it for YoMo-Zipper.
+func (w *worker) write(f frame.Frame, keyring *kms.Keyring) (int, error) {
+	if data, ok := f.(*frame.DataFrame); ok && keyring != nil {
+		if err := core.EncryptFrame(data, keyring); err != nil {
+			return 0, err
+		}
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.conn.Write(f.Encode())
+}
+
+// run runs `Handler` on the data from the relay until the relay is closed.
+func (w *worker) run(c *clientImpl, handler func(rxstream rx.Stream) rx.Stream, fac rx.Factory) {
+	for {
+		f, err := readFrame(w.conn)
+		if err != nil {
+			logger.Printf("The relay is closed: %v", err)
+			return
+		}
+		dataFrame, ok := f.(*frame.DataFrame)
+		if !ok {
+			continue
+		}
+		done := c.Track()
+		go func() {
+			defer done()
+			c.handleDataFrame(dataFrame, handler, fac)
+		}()
+	}
+}
@@ -0,0 +1,54 @@
+package streamfunction
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/core/rx"
+	mockserver "github.com/yomorun/yomo/zipper/mock"
+)
+
+func TestRelay(t *testing.T) {
+	const port = 8116
+	responses := serveWithCollector(t, port, "test relay")
+
+	relay, err := New("test relay").Connect(mockserver.IP, port)
+	assert.NoError(t, err)
+	defer relay.Close()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	go relay.Relay(listener)
+
+	// the stream function connects to the relay instead of YoMo-Zipper.
+	t.Setenv(RelayAddrEnv, listener.Addr().String())
+	prefix := func(prefix string) func(rxstream rx.Stream) rx.Stream {
+		return func(rxstream rx.Stream) rx.Stream {
+			return rxstream.
+				RawBytes().
+				Map(func(_ context.Context, i interface{}) (interface{}, error) {
+					return append([]byte(prefix), i.([]byte)...), nil
+				})
+		}
+	}
+	v1, err := New("test relay").Connect("", 0)
+	assert.NoError(t, err)
+	go v1.Pipe(prefix("v1:"))
+	sendUntilResponded(t, port, []byte("noise"), responses, func(response []byte) bool {
+		assert.Equal(t, "v1:noise", string(response))
+		return true
+	})
+
+	// the stream function is reloaded without reconnecting the relay to YoMo-Zipper.
+	assert.NoError(t, v1.Close())
+	v2, err := New("test relay").Connect("", 0)
+	assert.NoError(t, err)
+	defer v2.Close()
+	go v2.Pipe(prefix("v2:"))
+	sendUntilResponded(t, port, []byte("noise"), responses, func(response []byte) bool {
+		return string(response) == "v2:noise"
+	})
+	assert.True(t, relay.Connected())
+}
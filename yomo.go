@@ -1,6 +1,7 @@
 package yomo
 
 import (
+	"github.com/yomorun/yomo/core/quic"
 	"github.com/yomorun/yomo/source"
 	"github.com/yomorun/yomo/streamfunction"
 )
@@ -8,11 +9,115 @@ import (
 // NewSource creates a new YoMo-Source client.
 func NewSource(opts ...Option) source.Client {
 	options := newOptions(opts...)
-	return source.New(options.AppName)
+	sourceOpts := make([]source.Option, 0)
+	if options.Datagram {
+		sourceOpts = append(sourceOpts, source.WithDatagram())
+	}
+	if options.FrameTTL > 0 {
+		sourceOpts = append(sourceOpts, source.WithFrameTTL(options.FrameTTL))
+	}
+	if options.TLS != nil {
+		sourceOpts = append(sourceOpts, source.WithTLSConfig(options.TLS))
+	}
+	if options.ZeroRTT {
+		sourceOpts = append(sourceOpts, source.With0RTT())
+	}
+	if options.TCP {
+		sourceOpts = append(sourceOpts, source.WithTCPFallback())
+	}
+	if options.KeepAliveInterval > 0 || options.IdleTimeout > 0 {
+		sourceOpts = append(sourceOpts, source.WithKeepAlive(options.KeepAliveInterval, options.IdleTimeout))
+	}
+	if options.FlowControl != (quic.FlowControl{}) {
+		sourceOpts = append(sourceOpts, source.WithFlowControl(options.FlowControl))
+	}
+	if options.RetryInitial > 0 || options.RetryMax > 0 {
+		sourceOpts = append(sourceOpts, source.WithReconnectBackoff(options.RetryInitial, options.RetryMax))
+	}
+	if options.OnReconnect != nil {
+		sourceOpts = append(sourceOpts, source.WithOnReconnect(options.OnReconnect))
+	}
+	if options.Proxy != "" {
+		sourceOpts = append(sourceOpts, source.WithProxy(options.Proxy))
+	}
+	if options.Qlog != "" {
+		sourceOpts = append(sourceOpts, source.WithQlog(options.Qlog))
+	}
+	if options.Partial > 0 {
+		sourceOpts = append(sourceOpts, source.WithPartialReliability(options.Partial))
+	}
+	if options.Compression {
+		sourceOpts = append(sourceOpts, source.WithCompression(options.CompressionThreshold, options.CompressionCodecs...))
+	}
+	if options.Keyring != nil {
+		sourceOpts = append(sourceOpts, source.WithEncryption(options.Keyring))
+	}
+	if options.Chunking {
+		sourceOpts = append(sourceOpts, source.WithChunking(options.ChunkSize))
+	}
+	if options.ContentType != "" || options.SchemaID != "" {
+		sourceOpts = append(sourceOpts, source.WithContentType(options.ContentType, options.SchemaID))
+	}
+	if options.Checksum {
+		sourceOpts = append(sourceOpts, source.WithChecksum())
+	}
+	if options.Token != "" {
+		sourceOpts = append(sourceOpts, source.WithToken(options.Token))
+	}
+	return source.New(options.AppName, sourceOpts...)
 }
 
 // NewStreamFn creates a new YoMo-Stream-Function client.
 func NewStreamFn(opts ...Option) streamfunction.Client {
 	options := newOptions(opts...)
-	return streamfunction.New(options.AppName)
+	sfnOpts := make([]streamfunction.Option, 0)
+	if options.TLS != nil {
+		sfnOpts = append(sfnOpts, streamfunction.WithTLSConfig(options.TLS))
+	}
+	if options.DedupTTL > 0 {
+		sfnOpts = append(sfnOpts, streamfunction.WithDedup(options.DedupTTL, options.DedupSize))
+	}
+	if options.TCP {
+		sfnOpts = append(sfnOpts, streamfunction.WithTCPFallback())
+	}
+	if options.KeepAliveInterval > 0 || options.IdleTimeout > 0 {
+		sfnOpts = append(sfnOpts, streamfunction.WithKeepAlive(options.KeepAliveInterval, options.IdleTimeout))
+	}
+	if options.FlowControl != (quic.FlowControl{}) {
+		sfnOpts = append(sfnOpts, streamfunction.WithFlowControl(options.FlowControl))
+	}
+	if options.Mux != nil {
+		sfnOpts = append(sfnOpts, streamfunction.WithMux(options.Mux))
+	}
+	if options.RetryInitial > 0 || options.RetryMax > 0 {
+		sfnOpts = append(sfnOpts, streamfunction.WithReconnectBackoff(options.RetryInitial, options.RetryMax))
+	}
+	if options.OnReconnect != nil {
+		sfnOpts = append(sfnOpts, streamfunction.WithOnReconnect(options.OnReconnect))
+	}
+	if options.Proxy != "" {
+		sfnOpts = append(sfnOpts, streamfunction.WithProxy(options.Proxy))
+	}
+	if options.Qlog != "" {
+		sfnOpts = append(sfnOpts, streamfunction.WithQlog(options.Qlog))
+	}
+	if options.Compression {
+		sfnOpts = append(sfnOpts, streamfunction.WithCompression(options.CompressionThreshold, options.CompressionCodecs...))
+	}
+	if options.Keyring != nil {
+		sfnOpts = append(sfnOpts, streamfunction.WithEncryption(options.Keyring))
+	}
+	if options.Chunking {
+		sfnOpts = append(sfnOpts, streamfunction.WithChunking(options.ChunkSize))
+	}
+	if options.ContentType != "" || options.SchemaID != "" {
+		sfnOpts = append(sfnOpts, streamfunction.WithContentType(options.ContentType, options.SchemaID))
+	}
+	if options.Checksum {
+		sfnOpts = append(sfnOpts, streamfunction.WithChecksum())
+	}
+	if options.Token != "" {
+		sfnOpts = append(sfnOpts, streamfunction.WithToken(options.Token))
+	}
+	return streamfunction.New(options.AppName, sfnOpts...)
 }
@@ -0,0 +1,4 @@
+// Package yomotest provides the utilities to test the handlers and the workflows of YoMo deterministically, e.g. the
+// in-memory YoMo-Zipper, the builders of frames, the mock QUIC sessions and streams, and the assertions of the
+// data which flows out of the workflow.
+package yomotest
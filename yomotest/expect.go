@@ -0,0 +1,135 @@
+package yomotest
+
+import (
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yomorun/yomo/internal/core"
+	"github.com/yomorun/yomo/internal/frame"
+)
+
+// Record is the data recorded by Recorder.
+type Record struct {
+	Tag  byte
+	Data []byte
+}
+
+// Recorder records the data which flows out of the workflow or is written to the streams, and asserts the data
+// arrives in time.
+//
+//	r := yomotest.NewRecorder()
+//	e := yomo.NewEmbedded().Pipe("noise", Handler, 0x33).Sink(r.Record)
+//	...
+//	records := r.Expect(t, 2, 0x13, time.Second)
+type Recorder struct {
+	mutex   sync.Mutex
+	records []Record
+	notify  chan struct{}
+}
+
+// NewRecorder creates the recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{notify: make(chan struct{})}
+}
+
+// Record records the data of the tag, it's the sink of the embedded YoMo-Zipper.
+func (r *Recorder) Record(tag byte, data []byte) {
+	r.mutex.Lock()
+	r.records = append(r.records, Record{Tag: tag, Data: append([]byte(nil), data...)})
+	// the waiters are woken up by closing the channel.
+	close(r.notify)
+	r.notify = make(chan struct{})
+	r.mutex.Unlock()
+}
+
+// RecordFrames records the carriages of the data frames read from the stream until it's closed, e.g. the stream
+// of the mock session which the frames are written to. The chunks of large frames are reassembled.
+func (r *Recorder) RecordFrames(stream io.Reader) {
+	go func() {
+		reassembler := core.NewReassembler(core.DefaultMaxFrameSize)
+		for {
+			f, err := core.ParseFrame(stream)
+			if err != nil {
+				return
+			}
+			if chunk, ok := f.(*frame.ChunkFrame); ok {
+				if f, err = reassembler.Push(chunk); err != nil || f == nil {
+					continue
+				}
+			}
+			if data, ok := f.(*frame.DataFrame); ok && !data.Streamed() {
+				r.Record(data.GetDataTagID(), data.GetCarriage())
+			}
+		}
+	}()
+}
+
+// Records returns all data recorded.
+func (r *Recorder) Records() []Record {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]Record(nil), r.records...)
+}
+
+// Reset discards the data recorded.
+func (r *Recorder) Reset() {
+	r.mutex.Lock()
+	r.records = nil
+	r.mutex.Unlock()
+}
+
+// Wait waits until n data of the tag are recorded within the timeout, returns the data of the tag recorded so far.
+func (r *Recorder) Wait(n int, tag byte, timeout time.Duration) ([]Record, bool) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		r.mutex.Lock()
+		records := tagged(r.records, tag)
+		notify := r.notify
+		r.mutex.Unlock()
+		if len(records) >= n {
+			return records, true
+		}
+
+		select {
+		case <-notify:
+		case <-timer.C:
+			return records, false
+		}
+	}
+}
+
+// Expect asserts n data of the tag are recorded within the timeout, and returns them in the order they arrive.
+// The test fails immediately if they aren't, or more than n data of the tag are recorded.
+func (r *Recorder) Expect(t testing.TB, n int, tag byte, timeout time.Duration) []Record {
+	t.Helper()
+	records, ok := r.Wait(n, tag, timeout)
+	if !ok {
+		t.Fatalf("yomotest: expect %d data of tag %#x within %s, but %d are recorded", n, tag, timeout, len(records))
+	}
+	if len(records) > n {
+		t.Fatalf("yomotest: expect %d data of tag %#x, but %d are recorded", n, tag, len(records))
+	}
+	return records
+}
+
+// ExpectNone asserts no data of the tag is recorded within the timeout, e.g. the data is dropped by the handler.
+func (r *Recorder) ExpectNone(t testing.TB, tag byte, timeout time.Duration) {
+	t.Helper()
+	if records, ok := r.Wait(1, tag, timeout); ok {
+		t.Fatalf("yomotest: expect no data of tag %#x, but %d are recorded", tag, len(records))
+	}
+}
+
+// tagged returns the records of the tag.
+func tagged(records []Record, tag byte) []Record {
+	result := make([]Record, 0)
+	for _, record := range records {
+		if record.Tag == tag {
+			result = append(result, record)
+		}
+	}
+	return result
+}
@@ -0,0 +1,137 @@
+package yomotest
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/yomorun/yomo/internal/core"
+	"github.com/yomorun/yomo/internal/frame"
+)
+
+// transactions is the sequence of the transaction IDs of the frames built by FrameBuilder.
+var transactions uint64
+
+// FrameBuilder builds the data frame for the tests, the transaction ID is generated if it isn't specified.
+//
+//	f := yomotest.NewFrame(0x33, []byte("yomo")).Tags(0x34).Deadline(time.Now().Add(time.Second)).Build()
+type FrameBuilder struct {
+	tid      string
+	tag      byte
+	carriage []byte
+	setters  []func(data *frame.DataFrame)
+}
+
+// NewFrame creates the builder of the data frame of the carriage observed by the tag.
+func NewFrame(tag byte, carriage []byte) *FrameBuilder {
+	tid := "yomotest-" + strconv.FormatUint(atomic.AddUint64(&transactions, 1), 10)
+	return &FrameBuilder{tid: tid, tag: tag, carriage: carriage}
+}
+
+// TransactionID sets the transaction ID of the frame.
+func (b *FrameBuilder) TransactionID(tid string) *FrameBuilder {
+	b.tid = tid
+	return b
+}
+
+// Tags sets the extra tags which the carriage is also observed by.
+func (b *FrameBuilder) Tags(tags ...byte) *FrameBuilder {
+	return b.set(func(data *frame.DataFrame) { data.SetExtraTags(tags...) })
+}
+
+// Deadline sets the deadline of the frame, it's dropped after the deadline.
+func (b *FrameBuilder) Deadline(deadline time.Time) *FrameBuilder {
+	return b.set(func(data *frame.DataFrame) { data.SetDeadline(deadline) })
+}
+
+// Sequence sets the sequence of the frame in its source.
+func (b *FrameBuilder) Sequence(sequence uint64) *FrameBuilder {
+	return b.set(func(data *frame.DataFrame) { data.SetSequence(sequence) })
+}
+
+// Timestamp sets the time when the frame was written by its source.
+func (b *FrameBuilder) Timestamp(timestamp time.Time) *FrameBuilder {
+	return b.set(func(data *frame.DataFrame) { data.SetTimestamp(timestamp) })
+}
+
+// ContentType sets the content type and the schema ID of the carriage.
+func (b *FrameBuilder) ContentType(contentType string, schemaID string) *FrameBuilder {
+	return b.set(func(data *frame.DataFrame) {
+		data.SetContentType(contentType)
+		data.SetSchemaID(schemaID)
+	})
+}
+
+// TraceParent sets the W3C trace context of the frame.
+func (b *FrameBuilder) TraceParent(traceParent string, traceState string) *FrameBuilder {
+	return b.set(func(data *frame.DataFrame) {
+		data.SetTraceParent(traceParent)
+		data.SetTraceState(traceState)
+	})
+}
+
+// Checksum appends the checksum of the frame when it's encoded.
+func (b *FrameBuilder) Checksum() *FrameBuilder {
+	return b.set(func(data *frame.DataFrame) { data.SetChecksum(true) })
+}
+
+// Build returns a new frame, so the builder can build more frames.
+func (b *FrameBuilder) Build() *frame.DataFrame {
+	data := frame.NewDataFrame(b.tid)
+	data.SetCarriage(b.tag, b.carriage)
+	for _, set := range b.setters {
+		set(data)
+	}
+	return data
+}
+
+// Encode returns the frame encoded as it's written to the QUIC stream.
+func (b *FrameBuilder) Encode() []byte {
+	return b.Build().Encode()
+}
+
+func (b *FrameBuilder) set(setter func(data *frame.DataFrame)) *FrameBuilder {
+	b.setters = append(b.setters, setter)
+	return b
+}
+
+// Encode encodes the frames in order as they're written to the QUIC stream.
+func Encode(frames ...frame.Frame) []byte {
+	buf := make([]byte, 0)
+	for _, f := range frames {
+		buf = append(buf, f.Encode()...)
+	}
+	return buf
+}
+
+// Decode decodes the frames in order from the bytes of the QUIC stream, the chunks of large frames are
+// reassembled.
+func Decode(buf []byte) ([]frame.Frame, error) {
+	frames := make([]frame.Frame, 0)
+	stream := bytes.NewReader(buf)
+	reassembler := core.NewReassembler(core.DefaultMaxFrameSize)
+	for {
+		f, err := core.ParseFrame(stream)
+		if errors.Is(err, io.EOF) {
+			return frames, nil
+		}
+		if err != nil {
+			return frames, err
+		}
+
+		if chunk, ok := f.(*frame.ChunkFrame); ok {
+			data, err := reassembler.Push(chunk)
+			if err != nil {
+				return frames, err
+			}
+			if data == nil {
+				continue
+			}
+			f = data
+		}
+		frames = append(frames, f)
+	}
+}
@@ -0,0 +1,52 @@
+package yomotest
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/internal/core"
+	"github.com/yomorun/yomo/internal/frame"
+)
+
+func TestFrameBuilder(t *testing.T) {
+	deadline := time.Now().Add(time.Second).Truncate(time.Millisecond)
+	builder := NewFrame(0x33, []byte("yomo")).Tags(0x34).Deadline(deadline).Sequence(3).ContentType("application/json", "v1")
+	a, b := builder.Build(), builder.Build()
+	assert.NotSame(t, a, b)
+	assert.Equal(t, a.TransactionID(), b.TransactionID())
+	assert.NotEqual(t, a.TransactionID(), NewFrame(0x33, nil).Build().TransactionID())
+
+	assert.Equal(t, []byte{0x33, 0x34}, a.Tags())
+	assert.Equal(t, []byte("yomo"), a.GetCarriage())
+	got, ok := a.Deadline()
+	assert.True(t, ok)
+	assert.True(t, deadline.Equal(got))
+	sequence, ok := a.Sequence()
+	assert.True(t, ok)
+	assert.Equal(t, uint64(3), sequence)
+	assert.Equal(t, "application/json", a.ContentType())
+	assert.Equal(t, "v1", a.SchemaID())
+
+	f := NewFrame(0x10, []byte("tid")).TransactionID("tid").Checksum().Build()
+	assert.Equal(t, "tid", f.TransactionID())
+	assert.True(t, f.HasChecksum())
+}
+
+func TestEncodeDecode(t *testing.T) {
+	large := NewFrame(0x10, bytes.Repeat([]byte("yomo"), 1024)).Build()
+	frames := append([]frame.Frame{NewFrame(0x33, []byte("a")).Build(), frame.NewPingFrame()}, core.SplitFrame(large, 1024)...)
+
+	decoded, err := Decode(Encode(frames...))
+	assert.NoError(t, err)
+	if assert.Len(t, decoded, 3) {
+		assert.Equal(t, []byte("a"), decoded[0].(*frame.DataFrame).GetCarriage())
+		assert.Equal(t, frame.TagOfPingFrame, decoded[1].Type())
+		// the chunks are reassembled.
+		assert.Equal(t, large.GetCarriage(), decoded[2].(*frame.DataFrame).GetCarriage())
+	}
+
+	_, err = Decode([]byte{0x01})
+	assert.Error(t, err)
+}
@@ -0,0 +1,403 @@
+package yomotest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	quicGo "github.com/lucas-clemente/quic-go"
+	"github.com/yomorun/yomo/core/quic"
+)
+
+var _ quic.Session = (*Session)(nil)
+var _ quic.Stream = (*Stream)(nil)
+
+// errClosedStream is returned when the data is written to the closed stream.
+var errClosedStream = errors.New("yomotest: write on a closed stream")
+
+// errDeadline is returned when the deadline of stream is exceeded.
+var errDeadline = errors.New("yomotest: deadline exceeded")
+
+// messageBufferSize is the max count of datagrams which aren't received, the newer datagrams are dropped as QUIC.
+const messageBufferSize = 100
+
+// closeError is the error of the session closed by CloseWithError, it reads as the errors of QUIC.
+type closeError struct {
+	code   quicGo.ApplicationErrorCode
+	reason string
+}
+
+func (e *closeError) Error() string {
+	if e.reason == "" {
+		return fmt.Sprintf("Application error %#x", uint64(e.code))
+	}
+	return fmt.Sprintf("Application error %#x: %s", uint64(e.code), e.reason)
+}
+
+// addr is the address of the in-memory session.
+type addr string
+
+func (a addr) Network() string { return "yomotest" }
+func (a addr) String() string  { return string(a) }
+
+// Session is the in-memory quic.Session, the streams opened by a session are accepted by its peer immediately, the
+// datagrams are received by its peer, and both sessions are closed by CloseWithError of either.
+type Session struct {
+	ctx       context.Context
+	cancel    context.CancelFunc
+	local     addr
+	peer      *Session
+	accept    chan *Stream
+	acceptUni chan *Stream
+	messages  chan []byte
+
+	mutex   sync.Mutex
+	nextID  quicGo.StreamID
+	streams []*Stream
+	err     error
+}
+
+// NewSessionPair creates a pair of the connected sessions of the client and the server.
+func NewSessionPair() (client *Session, server *Session) {
+	ctx, cancel := context.WithCancel(context.Background())
+	client = newSession(ctx, cancel, "client", 0)
+	server = newSession(ctx, cancel, "server", 1)
+	client.peer, server.peer = server, client
+	return client, server
+}
+
+func newSession(ctx context.Context, cancel context.CancelFunc, local addr, firstID quicGo.StreamID) *Session {
+	return &Session{
+		ctx:       ctx,
+		cancel:    cancel,
+		local:     local,
+		accept:    make(chan *Stream, 100),
+		acceptUni: make(chan *Stream, 100),
+		messages:  make(chan []byte, messageBufferSize),
+		nextID:    firstID,
+	}
+}
+
+// openStream opens a stream whose peer half is accepted by the peer session.
+func (s *Session) openStream(uni bool) (*Stream, error) {
+	if s.ctx.Err() != nil {
+		return nil, s.closeErr()
+	}
+
+	s.mutex.Lock()
+	id := s.nextID
+	// the streams are numbered as QUIC, the lowest 2 bits are the initiator and the direction.
+	s.nextID += 4
+	s.mutex.Unlock()
+	if uni {
+		id |= 2
+	}
+	stream, peer := NewStreamPair()
+	stream.id, peer.id = id, id
+	s.track(stream)
+	s.peer.track(peer)
+
+	accept := s.peer.accept
+	if uni {
+		accept = s.peer.acceptUni
+	}
+	select {
+	case accept <- peer:
+		return stream, nil
+	case <-s.ctx.Done():
+		return nil, s.closeErr()
+	}
+}
+
+// track closes the stream when the session is closed.
+func (s *Session) track(stream *Stream) {
+	s.mutex.Lock()
+	s.streams = append(s.streams, stream)
+	s.mutex.Unlock()
+}
+
+func (s *Session) closeErr() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.err == nil {
+		return &closeError{}
+	}
+	return s.err
+}
+
+// close closes the session and its streams with err.
+func (s *Session) close(err error) {
+	s.mutex.Lock()
+	if s.err == nil {
+		s.err = err
+	}
+	streams := s.streams
+	s.streams = nil
+	s.mutex.Unlock()
+
+	for _, stream := range streams {
+		stream.in.close(err, true)
+		stream.out.close(err, false)
+		stream.cancel()
+	}
+}
+
+// AcceptStream returns the next bidirectional stream opened by the peer.
+func (s *Session) AcceptStream(ctx context.Context) (quicGo.Stream, error) {
+	select {
+	case stream := <-s.accept:
+		return stream, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-s.ctx.Done():
+		return nil, s.closeErr()
+	}
+}
+
+// AcceptUniStream returns the next unidirectional stream opened by the peer.
+func (s *Session) AcceptUniStream(ctx context.Context) (quicGo.ReceiveStream, error) {
+	select {
+	case stream := <-s.acceptUni:
+		return stream, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-s.ctx.Done():
+		return nil, s.closeErr()
+	}
+}
+
+// OpenStream opens a bidirectional stream.
+func (s *Session) OpenStream() (quicGo.Stream, error) {
+	return s.openStream(false)
+}
+
+// OpenStreamSync opens a bidirectional stream, it doesn't block since the streams are unlimited.
+func (s *Session) OpenStreamSync(ctx context.Context) (quicGo.Stream, error) {
+	return s.openStream(false)
+}
+
+// OpenUniStream opens a unidirectional stream.
+func (s *Session) OpenUniStream() (quicGo.SendStream, error) {
+	return s.openStream(true)
+}
+
+// OpenUniStreamSync opens a unidirectional stream, it doesn't block since the streams are unlimited.
+func (s *Session) OpenUniStreamSync(ctx context.Context) (quicGo.SendStream, error) {
+	return s.openStream(true)
+}
+
+// LocalAddr returns the address of the session, it's "client" or "server".
+func (s *Session) LocalAddr() net.Addr {
+	return s.local
+}
+
+// RemoteAddr returns the address of the peer.
+func (s *Session) RemoteAddr() net.Addr {
+	return s.peer.local
+}
+
+// CloseWithError closes the session and its peer.
+func (s *Session) CloseWithError(code quicGo.ApplicationErrorCode, reason string) error {
+	if s.ctx.Err() != nil {
+		return nil
+	}
+	err := &closeError{code: code, reason: reason}
+	s.close(err)
+	s.peer.close(err)
+	s.cancel()
+	return nil
+}
+
+// Context returns the context which is cancelled when the session is closed.
+func (s *Session) Context() context.Context {
+	return s.ctx
+}
+
+// ConnectionState returns the state of the session, the datagrams are supported.
+func (s *Session) ConnectionState() quicGo.ConnectionState {
+	var state quicGo.ConnectionState
+	state.SupportsDatagrams = true
+	return state
+}
+
+// SendMessage sends the datagram to the peer, it's dropped if the peer doesn't receive the datagrams in time.
+func (s *Session) SendMessage(data []byte) error {
+	if s.ctx.Err() != nil {
+		return s.closeErr()
+	}
+	select {
+	case s.peer.messages <- append([]byte(nil), data...):
+	default:
+	}
+	return nil
+}
+
+// ReceiveMessage receives the next datagram sent by the peer.
+func (s *Session) ReceiveMessage() ([]byte, error) {
+	select {
+	case data := <-s.messages:
+		return data, nil
+	case <-s.ctx.Done():
+		return nil, s.closeErr()
+	}
+}
+
+// Stream is the in-memory quic.Stream, the data written to a stream is buffered until it's read from its peer, and
+// its peer reads io.EOF after the stream is closed.
+type Stream struct {
+	id     quicGo.StreamID
+	ctx    context.Context
+	cancel context.CancelFunc
+	in     *pipe
+	out    *pipe
+}
+
+// NewStreamPair creates a pair of the connected streams, the data written to one is read from the other.
+func NewStreamPair() (*Stream, *Stream) {
+	a, b := &pipe{notify: make(chan struct{}, 1)}, &pipe{notify: make(chan struct{}, 1)}
+	return newStream(a, b), newStream(b, a)
+}
+
+func newStream(in *pipe, out *pipe) *Stream {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Stream{ctx: ctx, cancel: cancel, in: in, out: out}
+}
+
+// StreamID returns the ID of stream.
+func (s *Stream) StreamID() quicGo.StreamID {
+	return s.id
+}
+
+// Read reads the data written by the peer.
+func (s *Stream) Read(p []byte) (int, error) {
+	return s.in.read(p)
+}
+
+// CancelRead discards the data which isn't read.
+func (s *Stream) CancelRead(code quicGo.StreamErrorCode) {
+	s.in.close(fmt.Errorf("yomotest: the read of stream is cancelled by code %d", code), true)
+}
+
+// SetReadDeadline sets the deadline of Read.
+func (s *Stream) SetReadDeadline(t time.Time) error {
+	s.in.setDeadline(t)
+	return nil
+}
+
+// Write writes the data to the peer, it never blocks since the data is buffered.
+func (s *Stream) Write(p []byte) (int, error) {
+	return s.out.write(p)
+}
+
+// Close closes the write direction of stream, the peer reads io.EOF after the data written before.
+func (s *Stream) Close() error {
+	s.out.close(io.EOF, false)
+	s.cancel()
+	return nil
+}
+
+// CancelWrite closes the write direction of stream, the data which isn't read by the peer is discarded.
+func (s *Stream) CancelWrite(code quicGo.StreamErrorCode) {
+	s.out.close(fmt.Errorf("yomotest: the write of stream is cancelled by code %d", code), true)
+	s.cancel()
+}
+
+// Context returns the context which is cancelled when the write direction of stream is closed.
+func (s *Stream) Context() context.Context {
+	return s.ctx
+}
+
+// SetWriteDeadline does nothing, the writes never block.
+func (s *Stream) SetWriteDeadline(t time.Time) error {
+	return nil
+}
+
+// SetDeadline sets the deadline of Read.
+func (s *Stream) SetDeadline(t time.Time) error {
+	return s.SetReadDeadline(t)
+}
+
+// pipe is the buffer of one direction of stream.
+type pipe struct {
+	mutex    sync.Mutex
+	buf      bytes.Buffer
+	err      error
+	deadline time.Time
+	notify   chan struct{}
+}
+
+func (p *pipe) signal() {
+	select {
+	case p.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (p *pipe) read(b []byte) (int, error) {
+	for {
+		p.mutex.Lock()
+		if p.buf.Len() > 0 {
+			n, _ := p.buf.Read(b)
+			p.mutex.Unlock()
+			return n, nil
+		}
+		if p.err != nil {
+			err := p.err
+			p.mutex.Unlock()
+			return 0, err
+		}
+		deadline := p.deadline
+		p.mutex.Unlock()
+
+		if deadline.IsZero() {
+			<-p.notify
+			continue
+		}
+		if !time.Now().Before(deadline) {
+			return 0, errDeadline
+		}
+		timer := time.NewTimer(time.Until(deadline))
+		select {
+		case <-p.notify:
+			timer.Stop()
+		case <-timer.C:
+			return 0, errDeadline
+		}
+	}
+}
+
+func (p *pipe) write(b []byte) (int, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.err != nil {
+		return 0, errClosedStream
+	}
+	p.buf.Write(b)
+	p.signal()
+	return len(b), nil
+}
+
+// close closes the pipe with err which is read after the buffered data, the buffered data is discarded if reset.
+func (p *pipe) close(err error, reset bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.err == nil {
+		p.err = err
+	}
+	if reset {
+		p.buf.Reset()
+	}
+	p.signal()
+}
+
+func (p *pipe) setDeadline(t time.Time) {
+	p.mutex.Lock()
+	p.deadline = t
+	p.mutex.Unlock()
+	p.signal()
+}
@@ -0,0 +1,71 @@
+package yomotest
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/core/quic"
+)
+
+func TestStreamPair(t *testing.T) {
+	a, b := NewStreamPair()
+	_, err := a.Write([]byte("yo"))
+	assert.NoError(t, err)
+	_, err = a.Write([]byte("mo"))
+	assert.NoError(t, err)
+	assert.NoError(t, a.Close())
+	assert.Error(t, a.Context().Err())
+
+	// the data written before is read before io.EOF.
+	buf, err := io.ReadAll(b)
+	assert.NoError(t, err)
+	assert.Equal(t, "yomo", string(buf))
+	_, err = a.Write([]byte("late"))
+	assert.Error(t, err)
+
+	// the read returns when the deadline is exceeded.
+	assert.NoError(t, a.SetReadDeadline(time.Now().Add(10*time.Millisecond)))
+	_, err = a.Read(make([]byte, 1))
+	assert.Error(t, err)
+}
+
+func TestSessionPair(t *testing.T) {
+	client, server := NewSessionPair()
+	assert.Equal(t, "server", client.RemoteAddr().String())
+
+	stream, err := client.OpenStreamSync(context.Background())
+	assert.NoError(t, err)
+	accepted, err := server.AcceptStream(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, stream.StreamID(), accepted.StreamID())
+	_, err = accepted.Write([]byte("yomo"))
+	assert.NoError(t, err)
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(stream, buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "yomo", string(buf))
+
+	uni, err := server.OpenUniStream()
+	assert.NoError(t, err)
+	_, err = client.AcceptUniStream(context.Background())
+	assert.NoError(t, err)
+	assert.NotEqual(t, accepted.StreamID(), uni.StreamID())
+
+	assert.NoError(t, client.SendMessage([]byte("datagram")))
+	message, err := server.ReceiveMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, "datagram", string(message))
+
+	// both sessions and their streams are closed.
+	assert.NoError(t, server.CloseWithError(0, ""))
+	assert.Error(t, client.Context().Err())
+	_, err = stream.Read(buf)
+	assert.EqualError(t, err, quic.ErrConnectionClosed)
+	_, err = client.AcceptStream(context.Background())
+	assert.EqualError(t, err, quic.ErrConnectionClosed)
+	_, err = client.OpenStream()
+	assert.Error(t, err)
+}
@@ -0,0 +1,44 @@
+package yomotest
+
+import (
+	"testing"
+
+	"github.com/yomorun/yomo"
+	"github.com/yomorun/yomo/source"
+)
+
+// Zipper is the in-memory YoMo-Zipper of the tests, it runs the workflow of the embedded YoMo-Zipper and records
+// its output. It's closed when the test finishes.
+//
+//	z := yomotest.NewZipper(t, yomo.NewEmbedded().Pipe("noise", Handler, 0x33))
+//	z.Write("sensor", []byte("yomo"), 0x33)
+//	records := z.Expect(t, 1, 0x13, time.Second)
+type Zipper struct {
+	*yomo.Embedded
+	*Recorder
+	t testing.TB
+}
+
+// NewZipper starts the embedded YoMo-Zipper of the workflow, the test fails if it can't be started.
+func NewZipper(t testing.TB, e *yomo.Embedded) *Zipper {
+	t.Helper()
+	z := &Zipper{Embedded: e, Recorder: NewRecorder(), t: t}
+	e.Sink(z.Record)
+	if err := e.Start(); err != nil {
+		t.Fatalf("yomotest: start the embedded YoMo-Zipper failed: %v", err)
+	}
+	t.Cleanup(func() { e.Close() })
+	return z
+}
+
+// Write writes the data of the source observed by the tags, the default data tag of sources is used if no tag is
+// specified. The test fails if the data can't be written.
+func (z *Zipper) Write(name string, data []byte, tags ...byte) {
+	z.t.Helper()
+	if len(tags) == 0 {
+		tags = []byte{source.DefaultDataTag}
+	}
+	if _, err := z.Source(name).WriteWithTags(data, tags...); err != nil {
+		z.t.Fatalf("yomotest: write the data of source %s failed: %v", name, err)
+	}
+}
@@ -0,0 +1,47 @@
+package yomotest
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo"
+	"github.com/yomorun/yomo/source"
+)
+
+func TestZipper(t *testing.T) {
+	z := NewZipper(t, yomo.NewEmbedded().PipeFunc("yomotest-upper", 0x33, 0x34, func(_ context.Context, data []byte) ([]byte, error) {
+		if string(data) == "drop" {
+			return nil, nil
+		}
+		return []byte(strings.ToUpper(string(data))), nil
+	}))
+
+	z.Write("sensor", []byte("yomo"), 0x33)
+	z.Write("sensor", []byte("drop"), 0x33)
+	z.Write("sensor", []byte("raw"))
+	records := z.Expect(t, 1, 0x34, time.Second)
+	assert.Equal(t, "YOMO", string(records[0].Data))
+	assert.Equal(t, []Record{{Tag: source.DefaultDataTag, Data: []byte("raw")}}, z.Expect(t, 1, source.DefaultDataTag, time.Second))
+	z.ExpectNone(t, 0x33, 10*time.Millisecond)
+
+	z.Reset()
+	assert.Empty(t, z.Records())
+	_, ok := z.Wait(1, 0x34, 10*time.Millisecond)
+	assert.False(t, ok)
+}
+
+func TestRecordFrames(t *testing.T) {
+	a, b := NewStreamPair()
+	r := NewRecorder()
+	r.RecordFrames(b)
+
+	_, err := a.Write(Encode(NewFrame(0x33, []byte("a")).Build(), NewFrame(0x34, []byte("b")).Build()))
+	assert.NoError(t, err)
+	records := r.Expect(t, 1, 0x34, time.Second)
+	assert.Equal(t, "b", string(records[0].Data))
+	assert.Len(t, r.Records(), 2)
+	a.Close()
+}
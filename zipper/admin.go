@@ -0,0 +1,377 @@
+package zipper
+
+import (
+	"crypto/subtle"
+	_ "embed"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/yomorun/yomo/internal/core"
+	"github.com/yomorun/yomo/logger"
+)
+
+// dashboard is the built-in web UI of the feed of data flow, it asks for the admin token to read the feed.
+//
+//go:embed dashboard.html
+var dashboard []byte
+
+// newAdminMux creates the routes of admin API, all of them require the admin token except the page of dashboard.
+func newAdminMux(h *quicHandler) http.Handler {
+	mux := http.NewServeMux()
+	if h.features != nil {
+		mux.Handle("/features", h.features)
+		mux.Handle("/features/", h.features)
+	}
+	mux.HandleFunc("/tls/reload", h.reloadCertificates)
+	mux.HandleFunc("/workflow/reload", h.reloadWorkflow)
+	mux.HandleFunc("/connections", h.listConnections)
+	mux.HandleFunc("/connections/", h.controlConnection)
+	mux.HandleFunc("/stages", h.listStages)
+	mux.HandleFunc("/tenants", listTenants)
+	mux.HandleFunc("/functions/", h.uploadWasm)
+	mux.HandleFunc("/stats", h.stats)
+	mux.HandleFunc("/events", serveEvents)
+	if h.config().Diagnostics {
+		h.mountDiagnostics(mux)
+	}
+	if h.config().Timeline != nil {
+		mux.HandleFunc("/transactions", serveTimelines)
+		mux.HandleFunc("/transactions/", serveTimelines)
+	}
+	if h.capturer != nil {
+		mux.Handle("/captures", h.capturer)
+	}
+	if h.journal != nil {
+		mux.HandleFunc("/replay", h.journal.serveReplay)
+	}
+	api := auditAdmin(h.auditor, requireToken(h.config().AdminToken, mux))
+
+	if !h.config().Dashboard {
+		return api
+	}
+	root := http.NewServeMux()
+	root.Handle("/", api)
+	root.HandleFunc("/dashboard", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(dashboard)
+	})
+	return root
+}
+
+// requireToken authenticates the request by the bearer token, the handler is disabled if token is empty.
+func requireToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "the admin token is not configured"})
+			return
+		}
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "Bearer ") || subtle.ConstantTimeCompare([]byte(auth[len("Bearer "):]), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// reloadCertificates is the admin API of certificate rotation.
+// POST /tls/reload reloads the certificate from the files, the established sessions are not dropped.
+func (h *quicHandler) reloadCertificates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if h.certs == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "TLS is not configured"})
+		return
+	}
+	if err := h.certs.Reload(); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "reloaded"})
+}
+
+// ReloadResult is the result of reloading the workflow in the admin API.
+type ReloadResult struct {
+	Status string `json:"status"`
+	// Restart are the changed fields of config which are not applied until restart.
+	Restart []string `json:"restart,omitempty"`
+}
+
+// reloadWorkflow is the admin API of reloading the workflow.
+// POST /workflow/reload reloads the workflow from the config file, the current workflow is kept if it's invalid.
+func (h *quicHandler) reloadWorkflow(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if h.config().path == "" {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "the workflow config isn't loaded from a file"})
+		return
+	}
+	restart, err := h.reloadFile()
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	logger.Printf("✅ The workflow config %s is reloaded by admin", h.config().path)
+	writeJSON(w, http.StatusOK, ReloadResult{Status: "reloaded", Restart: restart})
+}
+
+// ConnectionInfo is a connected client in the admin API.
+type ConnectionInfo struct {
+	Name        string    `json:"name"`
+	Type        string    `json:"type"`
+	Addr        string    `json:"addr"`
+	RemoteAddr  string    `json:"remote_addr"`
+	Version     uint32    `json:"version"`
+	ConnectedAt time.Time `json:"connected_at"`
+	Uptime      float64   `json:"uptime_seconds"`
+	Frames      uint64    `json:"frames"`
+	Departed    bool      `json:"departed"`
+	// Subject is the identity of client authenticated by its token, it's empty if the client isn't authenticated.
+	Subject string `json:"subject,omitempty"`
+	// Namespace is the namespace which the client is bound to, it's empty for the default workflow.
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// StageInfo is a stage of workflow in the admin API.
+type StageInfo struct {
+	Name  string `json:"name"`
+	Local bool   `json:"local"`
+	// Serverless indicates the stream function is backed by the serverless function.
+	Serverless bool `json:"serverless"`
+	// Wasm indicates the stream function runs as the WASM module in YoMo-Zipper.
+	Wasm      bool     `json:"wasm"`
+	Instances int      `json:"instances"`
+	Shadows   []string `json:"shadows,omitempty"`
+	Tags      []int    `json:"tags,omitempty"`
+	Backlog   int      `json:"backlog"`
+	// Latency is the mean latency in seconds of the stream function during the last check of slow consumers.
+	Latency float64 `json:"latency_seconds"`
+	// Slow indicates the stream function is a slow consumer.
+	Slow bool `json:"slow"`
+	// Bottleneck indicates the stream function is the slow consumer with the largest backlog.
+	Bottleneck bool `json:"bottleneck"`
+}
+
+// TenantUsage is the usage of a tenant limited by the quotas in the admin API, e.g. for billing.
+type TenantUsage struct {
+	Tenant      string `json:"tenant"`
+	Connections int    `json:"connections"`
+	// Frames and Bytes are the frames and the bytes of carriages written by the sources within the quota since
+	// YoMo-Zipper started.
+	Frames uint64 `json:"frames"`
+	Bytes  uint64 `json:"bytes"`
+	// BytesToday are the bytes of carriages written today in UTC, which are limited by bytes_per_day.
+	BytesToday int64 `json:"bytes_today"`
+	// Exceeded are the frames and the connections rejected by the name of quota.
+	Exceeded map[string]uint64 `json:"exceeded,omitempty"`
+}
+
+// Stats is the statistics of YoMo-Zipper in the admin API.
+type Stats struct {
+	Name            string          `json:"name"`
+	StartedAt       time.Time       `json:"started_at"`
+	Uptime          float64         `json:"uptime_seconds"`
+	Connections     int             `json:"connections"`
+	FramesIn        uint64          `json:"frames_in"`
+	FramesOut       uint64          `json:"frames_out"`
+	SheddingDropped map[byte]uint64 `json:"shedding_dropped"`
+	CorruptedFrames uint64          `json:"corrupted_frames"`
+}
+
+// listConnections is the admin API of the connected clients.
+// GET /connections lists the sources, stream functions and upstream YoMo-Zippers.
+func (h *quicHandler) listConnections(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	conns := make([]ConnectionInfo, 0)
+	for _, c := range h.currentConnections() {
+		conns = append(conns, ConnectionInfo{
+			Name:        c.Conn.Name,
+			Type:        c.Conn.Type.String(),
+			Addr:        c.Addr,
+			RemoteAddr:  c.RemoteAddr,
+			Version:     c.Version(),
+			ConnectedAt: c.ConnectedAt,
+			Uptime:      time.Since(c.ConnectedAt).Seconds(),
+			Frames:      c.Frames(),
+			Departed:    c.Departed(),
+			Subject:     c.Subject(),
+			Namespace:   c.Namespace(),
+		})
+	}
+	writeJSON(w, http.StatusOK, conns)
+}
+
+// controlConnection is the admin API of day-2 operations on a client, the addr is escaped in the path.
+// POST /connections/{addr}/drain stops dispatching to the client, its pending responses are still received.
+// POST /connections/{addr}/kick closes the session of the client.
+func (h *quicHandler) controlConnection(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/connections/")
+	i := strings.LastIndex(path, "/")
+	if r.Method != http.MethodPost || i < 0 {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	addr, action := path[:i], path[i+1:]
+	v, ok := h.connMap.Load(addr)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "the connection is not found"})
+		return
+	}
+	c := v.(*Conn)
+
+	switch action {
+	case "drain":
+		logger.Printf("The client %s is drained by admin, addr: %s", c.Conn.Name, c.Addr)
+		c.drain(h.config())
+		writeJSON(w, http.StatusOK, map[string]string{"status": "drained"})
+	case "kick":
+		logger.Printf("The client %s is kicked by admin, addr: %s", c.Conn.Name, c.Addr)
+		// the stream function is removed from the dispatch pool before its session is closed.
+		c.drain(h.config())
+		c.Close()
+		writeJSON(w, http.StatusOK, map[string]string{"status": "kicked"})
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown action " + action})
+	}
+}
+
+// listStages is the admin API of workflow.
+// GET /stages lists the stream functions in the order of workflow with their instances and backlog.
+func (h *quicHandler) listStages(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	_, backlog := h.queues.backlog()
+	bottleneck := h.slow.slowest()
+	apps := h.config().allFunctions()
+	stages := make([]StageInfo, 0, len(apps))
+	for _, app := range apps {
+		stage := StageInfo{
+			Name:       app.Name,
+			Shadows:    app.Shadows,
+			Backlog:    backlog[app.Name],
+			Bottleneck: app.Name == bottleneck,
+		}
+		stage.Slow, stage.Latency = h.slow.state(app.Name)
+		if h.isLocal(app.Name) {
+			stage.Local = true
+			stage.Instances = 1
+		} else if _, ok := h.invokers[app.Name]; ok {
+			stage.Serverless = true
+			stage.Instances = 1
+		} else if fn, ok := h.wasmFuncs[app.Name]; ok {
+			stage.Wasm = true
+			if fn.loaded() {
+				stage.Instances = fn.conf.Instances
+			}
+		} else {
+			stage.Instances = len(findConn(app, &h.connMap, core.ConnTypeStreamFunction))
+		}
+		for _, tag := range app.Tags {
+			stage.Tags = append(stage.Tags, int(tag))
+		}
+		stages = append(stages, stage)
+	}
+	writeJSON(w, http.StatusOK, stages)
+}
+
+// listTenants is the admin API of quotas.
+// GET /tenants lists the usage of the tenants limited by the quotas.
+func listTenants(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	tenants := make([]TenantUsage, 0)
+	for _, tenant := range sortedKeys(&quotaAccounts) {
+		v, _ := quotaAccounts.Load(tenant)
+		tenants = append(tenants, v.(*quotaAccount).usage(tenant, time.Now()))
+	}
+	writeJSON(w, http.StatusOK, tenants)
+}
+
+// uploadWasm is the admin API of WASM functions.
+// PUT /functions/{name}/wasm replaces the module of the WASM function, the frames in flight are processed by the
+// previous module, and the module is kept if the uploaded one is invalid.
+func (h *quicHandler) uploadWasm(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/functions/"), "/wasm")
+	if r.Method != http.MethodPut || !strings.HasSuffix(r.URL.Path, "/wasm") {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	fn, ok := h.wasmFuncs[name]
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "the WASM function is not found"})
+		return
+	}
+
+	buf, err := ioutil.ReadAll(io.LimitReader(r.Body, maxWasmModule+1))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if len(buf) > maxWasmModule {
+		writeJSON(w, http.StatusRequestEntityTooLarge, map[string]string{"error": "the module is larger than 64MB"})
+		return
+	}
+	if err := fn.load(buf); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	logger.Printf("The WASM function %s is uploaded by admin, size: %d", name, len(buf))
+	writeJSON(w, http.StatusOK, map[string]string{"status": "loaded"})
+}
+
+// stats is the admin API of statistics.
+// GET /stats returns the uptime, the count of connections and the frames of YoMo-Zipper.
+func (h *quicHandler) stats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	in, out := pipelineMetrics.totals()
+	writeJSON(w, http.StatusOK, Stats{
+		Name:            h.config().Name,
+		StartedAt:       h.startedAt,
+		Uptime:          time.Since(h.startedAt).Seconds(),
+		Connections:     len(h.currentConnections()),
+		FramesIn:        in,
+		FramesOut:       out,
+		SheddingDropped: h.shedder.dropped(),
+		CorruptedFrames: core.CorruptedFrames(),
+	})
+}
+
+// serveAdmin serves the admin API on addr.
+func serveAdmin(addr string, h *quicHandler) *http.Server {
+	server := &http.Server{
+		Addr:    addr,
+		Handler: newAdminMux(h),
+	}
+
+	go func() {
+		logger.Printf("✅ Admin API is listening on %s", addr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Error("[zipper] serve admin API failed.", "addr", addr, "err", err)
+		}
+	}()
+
+	return server
+}
@@ -0,0 +1,127 @@
+package zipper
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	quicGo "github.com/lucas-clemente/quic-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/core/quic"
+	"github.com/yomorun/yomo/internal/core"
+)
+
+func TestReloadCertificatesRequiresToken(t *testing.T) {
+	reload := func(conf *WorkflowConfig, auth string) int {
+		server := httptest.NewServer(newAdminMux(newServerHandler(conf, "")))
+		defer server.Close()
+
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/tls/reload", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		res, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	// the endpoint is disabled without the admin token.
+	assert.Equal(t, http.StatusForbidden, reload(&WorkflowConfig{}, "Bearer secret"))
+
+	conf := &WorkflowConfig{AdminToken: "secret"}
+	assert.Equal(t, http.StatusUnauthorized, reload(conf, ""))
+	assert.Equal(t, http.StatusUnauthorized, reload(conf, "secret"))
+	assert.Equal(t, http.StatusUnauthorized, reload(conf, "Bearer other"))
+	// the TLS is not configured.
+	assert.Equal(t, http.StatusNotFound, reload(conf, "Bearer secret"))
+}
+
+// adminSession records if the session is closed.
+type adminSession struct {
+	quic.Session
+	closed bool
+}
+
+func (s *adminSession) RemoteAddr() net.Addr {
+	return &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1}
+}
+
+func (s *adminSession) CloseWithError(quicGo.ApplicationErrorCode, string) error {
+	s.closed = true
+	return nil
+}
+
+func TestAdminConnections(t *testing.T) {
+	conf := &WorkflowConfig{Name: "zipper", AdminToken: "secret", Workflow: Workflow{Functions: []App{{Name: "fn", Tags: []byte{0x33}}}}}
+	h := newServerHandler(conf, "")
+	session := &adminSession{}
+	c := newConn("127.0.0.1:1#2", session, nil)
+	c.Conn.Name = "fn"
+	c.Conn.Type = core.ConnTypeStreamFunction
+	h.connMap.Store(c.Addr, c)
+	countFrame(session)
+
+	server := httptest.NewServer(newAdminMux(h))
+	defer server.Close()
+	do := func(method string, path string, v interface{}) int {
+		req, _ := http.NewRequest(method, server.URL+path, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		res, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		if v != nil {
+			assert.NoError(t, json.NewDecoder(res.Body).Decode(v))
+		}
+		return res.StatusCode
+	}
+
+	var conns []ConnectionInfo
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/connections", &conns))
+	assert.Len(t, conns, 1)
+	assert.Equal(t, "fn", conns[0].Name)
+	assert.Equal(t, uint64(1), conns[0].Frames)
+	assert.False(t, conns[0].Departed)
+
+	var stages []StageInfo
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/stages", &stages))
+	assert.Equal(t, []StageInfo{{Name: "fn", Instances: 1, Tags: []int{0x33}}}, stages)
+
+	var stats Stats
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/stats", &stats))
+	assert.Equal(t, "zipper", stats.Name)
+	assert.Equal(t, 1, stats.Connections)
+
+	path := "/connections/" + url.PathEscape(c.Addr)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodPost, "/connections/unknown/drain", nil))
+	assert.Equal(t, http.StatusNotFound, do(http.MethodPost, path+"/unknown", nil))
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, path+"/drain", nil))
+	assert.True(t, c.Departed())
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/stages", &stages))
+	assert.Equal(t, 0, stages[0].Instances)
+
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, path+"/kick", nil))
+	assert.True(t, session.closed)
+}
+
+func TestAdminDashboard(t *testing.T) {
+	get := func(conf *WorkflowConfig, path string) int {
+		server := httptest.NewServer(newAdminMux(newServerHandler(conf, "")))
+		defer server.Close()
+		res, err := http.Get(server.URL + path)
+		assert.NoError(t, err)
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	conf := &WorkflowConfig{AdminToken: "secret"}
+	assert.Equal(t, http.StatusUnauthorized, get(conf, "/dashboard"))
+
+	// the page is served without the token, but the feed requires it.
+	conf.Dashboard = true
+	assert.Equal(t, http.StatusOK, get(conf, "/dashboard"))
+	assert.Equal(t, http.StatusUnauthorized, get(conf, "/events"))
+}
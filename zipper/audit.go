@@ -0,0 +1,298 @@
+package zipper
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yomorun/yomo/logger"
+)
+
+// the events of audit log.
+const (
+	AuditConnect     = "connect"
+	AuditDisconnect  = "disconnect"
+	AuditAuthSuccess = "auth_success"
+	AuditAuthFailure = "auth_failure"
+	AuditAdmin       = "admin"
+)
+
+const (
+	// DefaultAuditMaxSize is the default size in bytes of the audit file before it's rotated.
+	DefaultAuditMaxSize = 100 << 20
+	// auditBuffer is the count of records buffered for the remote sink, the records are dropped when it's full,
+	// so a slow sink never blocks the handshakes.
+	auditBuffer = 1024
+	// auditTimeout is the timeout of sending a record to the remote sink.
+	auditTimeout = 5 * time.Second
+)
+
+// AuditConfig represents the config of audit log, the records are written to the file and the remote sink
+// if they're set.
+type AuditConfig struct {
+	// File is the path of audit file, the records are appended to it as JSON lines.
+	File string `yaml:"file,omitempty"`
+	// MaxSize is the size in bytes of the audit file before it's rotated, the default is 100MB.
+	MaxSize int64 `yaml:"max_size,omitempty"`
+	// MaxAge is the retention of the rotated files, they're kept forever if it's zero.
+	MaxAge time.Duration `yaml:"max_age,omitempty"`
+	// MaxBackups is the max count of the rotated files, all of them are kept if it's zero.
+	MaxBackups int `yaml:"max_backups,omitempty"`
+	// URL is the HTTP endpoint of the remote sink, each record is posted to it as JSON.
+	URL string `yaml:"url,omitempty"`
+	// Headers are sent with each request to the remote sink, such as "Authorization".
+	Headers map[string]string `yaml:"headers,omitempty"`
+}
+
+// AuditRecord is a record of audit log.
+type AuditRecord struct {
+	Time  time.Time `json:"time"`
+	Event string    `json:"event"`
+	// Name is the name of the client.
+	Name string `json:"name,omitempty"`
+	// Type is the type of the client.
+	Type string `json:"type,omitempty"`
+	// Addr is the address of the client or the admin.
+	Addr string `json:"addr,omitempty"`
+	// Identities are the identities in the certificate of client.
+	Identities []string `json:"identities,omitempty"`
+	// Subject is the identity of client authenticated by its token.
+	Subject string `json:"subject,omitempty"`
+	// Action is the method and path of the administrative action.
+	Action string `json:"action,omitempty"`
+	// Status is the HTTP status of the administrative action.
+	Status int `json:"status,omitempty"`
+	// Reason is the reason of the failure or the disconnection.
+	Reason string `json:"reason,omitempty"`
+}
+
+// auditor writes the records of audit log to the sinks, the nil auditor discards the records.
+type auditor struct {
+	conf   *AuditConfig
+	mutex  sync.Mutex
+	file   *os.File
+	size   int64
+	remote chan []byte
+	done   chan struct{}
+	client *http.Client
+	closed bool
+}
+
+func newAuditor(conf *AuditConfig) (*auditor, error) {
+	a := &auditor{conf: conf}
+	if conf.File != "" {
+		if err := a.open(); err != nil {
+			return nil, err
+		}
+	}
+	if conf.URL != "" {
+		a.remote = make(chan []byte, auditBuffer)
+		a.done = make(chan struct{})
+		a.client = &http.Client{Timeout: auditTimeout}
+		go a.send()
+	}
+	return a, nil
+}
+
+// record writes the record to the sinks.
+func (a *auditor) record(r AuditRecord) {
+	if a == nil {
+		return
+	}
+	r.Time = time.Now()
+	buf, err := json.Marshal(r)
+	if err != nil {
+		logger.Error("[audit] marshal the record failed.", "err", err)
+		return
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.closed {
+		return
+	}
+
+	if a.file != nil {
+		a.write(append(buf, '\n'))
+	}
+	if a.remote != nil {
+		select {
+		case a.remote <- buf:
+		default:
+			logger.Error("[audit] the remote sink is too slow, the record is dropped.", "event", r.Event, "name", r.Name)
+		}
+	}
+}
+
+// write appends the line to the audit file, it's rotated when it's full. The mutex is held.
+func (a *auditor) write(line []byte) {
+	maxSize := a.conf.MaxSize
+	if maxSize <= 0 {
+		maxSize = DefaultAuditMaxSize
+	}
+	if a.size > 0 && a.size+int64(len(line)) > maxSize {
+		if err := a.rotate(); err != nil {
+			logger.Error("[audit] rotate the audit file failed.", "file", a.conf.File, "err", err)
+		}
+	}
+
+	if a.file == nil {
+		return
+	}
+	n, err := a.file.Write(line)
+	a.size += int64(n)
+	if err != nil {
+		logger.Error("[audit] write the audit file failed.", "file", a.conf.File, "err", err)
+	}
+}
+
+// open opens the audit file for appending, the mutex is held or the auditor is not shared yet.
+func (a *auditor) open() error {
+	file, err := os.OpenFile(a.conf.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	a.file, a.size = file, info.Size()
+	return nil
+}
+
+// rotate renames the audit file with the timestamp and opens a new one, the expired backups are removed.
+// The mutex is held.
+func (a *auditor) rotate() error {
+	a.file.Close()
+	a.file = nil
+	backup := fmt.Sprintf("%s.%s", a.conf.File, time.Now().UTC().Format("20060102T150405.000000000"))
+	err := os.Rename(a.conf.File, backup)
+	if err == nil {
+		a.prune()
+	}
+	// the file is reopened even if it isn't renamed, so the records are not lost.
+	if openErr := a.open(); err == nil {
+		err = openErr
+	}
+	return err
+}
+
+// prune removes the backups which are older than MaxAge or beyond MaxBackups.
+func (a *auditor) prune() {
+	backups, err := filepath.Glob(a.conf.File + ".*")
+	if err != nil {
+		return
+	}
+	// the timestamps are sorted in time order.
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	for i, backup := range backups {
+		expired := a.conf.MaxBackups > 0 && i >= a.conf.MaxBackups
+		if info, err := os.Stat(backup); err == nil && a.conf.MaxAge > 0 && time.Since(info.ModTime()) > a.conf.MaxAge {
+			expired = true
+		}
+		if expired {
+			os.Remove(backup)
+		}
+	}
+}
+
+// send posts the records to the remote sink until the auditor is closed.
+func (a *auditor) send() {
+	defer close(a.done)
+	for buf := range a.remote {
+		req, err := http.NewRequest(http.MethodPost, a.conf.URL, bytes.NewReader(buf))
+		if err != nil {
+			logger.Error("[audit] create the request of remote sink failed.", "err", err)
+			continue
+		}
+		req.Header.Set("Content-Type", "application/json")
+		for k, v := range a.conf.Headers {
+			req.Header.Set(k, v)
+		}
+		res, err := a.client.Do(req)
+		if err != nil {
+			logger.Error("[audit] send the record to remote sink failed.", "err", err)
+			continue
+		}
+		res.Body.Close()
+		if res.StatusCode >= http.StatusBadRequest {
+			logger.Error("[audit] the remote sink rejected the record.", "status", res.StatusCode)
+		}
+	}
+}
+
+// Close flushes the records to the remote sink and closes the audit file.
+func (a *auditor) Close() error {
+	if a == nil {
+		return nil
+	}
+
+	a.mutex.Lock()
+	if a.closed {
+		a.mutex.Unlock()
+		return nil
+	}
+	a.closed = true
+	var err error
+	if a.file != nil {
+		err = a.file.Close()
+		a.file = nil
+	}
+	a.mutex.Unlock()
+
+	if a.remote != nil {
+		close(a.remote)
+		<-a.done
+	}
+	return err
+}
+
+// statusRecorder records the status of response, it's flushed for the streamed responses.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// auditAdmin records the administrative actions and the rejected requests of admin API,
+// the read-only requests which succeed are not recorded.
+func auditAdmin(a *auditor, next http.Handler) http.Handler {
+	if a == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		if r.Method == http.MethodGet && rec.status < http.StatusBadRequest {
+			return
+		}
+		event := AuditAdmin
+		if rec.status == http.StatusUnauthorized || rec.status == http.StatusForbidden {
+			event = AuditAuthFailure
+		}
+		a.record(AuditRecord{
+			Event:  event,
+			Addr:   r.RemoteAddr,
+			Action: r.Method + " " + r.URL.Path,
+			Status: rec.status,
+		})
+	})
+}
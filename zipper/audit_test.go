@@ -0,0 +1,113 @@
+package zipper
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// readLines reads the JSON lines of file.
+func readLines(t *testing.T, file string) [][]byte {
+	f, err := os.Open(file)
+	assert.NoError(t, err)
+	defer f.Close()
+
+	lines := make([][]byte, 0)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, append([]byte(nil), scanner.Bytes()...))
+	}
+	return lines
+}
+
+func readAudit(t *testing.T, file string) []AuditRecord {
+	records := make([]AuditRecord, 0)
+	for _, line := range readLines(t, file) {
+		var r AuditRecord
+		assert.NoError(t, json.Unmarshal(line, &r))
+		records = append(records, r)
+	}
+	return records
+}
+
+func TestAuditorRotate(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "audit.log")
+	a, err := newAuditor(&AuditConfig{File: file, MaxSize: 100, MaxBackups: 1})
+	assert.NoError(t, err)
+
+	for _, name := range []string{"source-1", "source-2", "source-3"} {
+		a.record(AuditRecord{Event: AuditConnect, Name: name, Addr: "127.0.0.1:1"})
+	}
+	assert.NoError(t, a.Close())
+	// the records after closing are discarded.
+	a.record(AuditRecord{Event: AuditConnect, Name: "source-4"})
+
+	records := readAudit(t, file)
+	assert.Len(t, records, 1)
+	assert.Equal(t, "source-3", records[0].Name)
+	assert.False(t, records[0].Time.IsZero())
+
+	// only one backup is kept.
+	backups, _ := filepath.Glob(file + ".*")
+	assert.Len(t, backups, 1)
+	assert.Equal(t, "source-2", readAudit(t, backups[0])[0].Name)
+}
+
+func TestAuditorRemote(t *testing.T) {
+	received := make(chan AuditRecord, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		var record AuditRecord
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&record))
+		received <- record
+	}))
+	defer server.Close()
+
+	a, err := newAuditor(&AuditConfig{URL: server.URL, Headers: map[string]string{"Authorization": "Bearer secret"}})
+	assert.NoError(t, err)
+	a.record(AuditRecord{Event: AuditAuthFailure, Name: "sfn", Reason: "denied"})
+	// the records are flushed when it's closed.
+	assert.NoError(t, a.Close())
+
+	record := <-received
+	assert.Equal(t, AuditAuthFailure, record.Event)
+	assert.Equal(t, "denied", record.Reason)
+}
+
+func TestAuditAdmin(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "audit.log")
+	a, err := newAuditor(&AuditConfig{File: file})
+	assert.NoError(t, err)
+
+	h := newServerHandler(&WorkflowConfig{AdminToken: "secret"}, "")
+	h.auditor = a
+	server := httptest.NewServer(newAdminMux(h))
+	defer server.Close()
+
+	do := func(method string, path string, token string) {
+		req, _ := http.NewRequest(method, server.URL+path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		res, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		ioutil.ReadAll(res.Body)
+		res.Body.Close()
+	}
+	do(http.MethodGet, "/stats", "secret")
+	do(http.MethodGet, "/stats", "other")
+	do(http.MethodPost, "/tls/reload", "secret")
+	assert.NoError(t, a.Close())
+
+	records := readAudit(t, file)
+	assert.Len(t, records, 2)
+	assert.Equal(t, AuditAuthFailure, records[0].Event)
+	assert.Equal(t, http.StatusUnauthorized, records[0].Status)
+	assert.Equal(t, AuditAdmin, records[1].Event)
+	assert.Equal(t, "POST /tls/reload", records[1].Action)
+}
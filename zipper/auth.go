@@ -0,0 +1,407 @@
+package zipper
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/yomorun/yomo/core/kms"
+)
+
+// errUnauthenticated is the message of rejection sent to the unauthenticated clients, the reason is only logged
+// and audited, so the clients can't probe the credentials.
+const errUnauthenticated = "the credential is missing or invalid"
+
+// rejectedLinger is how long the session of an unauthenticated client is kept after the rejection is sent, so the
+// client reads the rejection before the session is closed.
+const rejectedLinger = 500 * time.Millisecond
+
+// AuthConfig authenticates the sources and stream functions by the token which they present in the handshake,
+// the clients without a valid token are rejected before any data flows. A token is checked against the static
+// tokens, and it's verified as a JWT if it's not one of them.
+type AuthConfig struct {
+	// Tokens are the static secrets of clients.
+	Tokens []StaticToken `yaml:"tokens,omitempty"`
+	// JWT verifies the tokens which are JSON Web Tokens.
+	JWT *JWTConfig `yaml:"jwt,omitempty"`
+}
+
+// StaticToken is a static secret of clients.
+type StaticToken struct {
+	// Subject is the identity of the clients which present the token, the default is the name of client.
+	Subject string `yaml:"subject,omitempty"`
+	// Token is the secret.
+	Token string `yaml:"token"`
+	// Roles are the roles of the clients which present the token, they're granted by the policies of authz.
+	Roles []string `yaml:"roles,omitempty"`
+}
+
+// JWTConfig verifies the JSON Web Tokens, they're signed by the HMAC secret (HS256, HS384, HS512), or the private
+// key of the public key (RS256, RS384, RS512, ES256, ES384, ES512). The HMAC secrets can be provided by the keys
+// instead of the config, the token is verified by the key of its "kid" header, or by any valid key without it. The "exp" and "nbf" claims are checked if
+// they're present, the subject of client is the "sub" claim, and its roles are the "roles" claim and the scopes of
+// the "scope" claim.
+type JWTConfig struct {
+	// Secret is the secret of HMAC.
+	Secret string `yaml:"secret,omitempty"`
+	// Keys provides the HMAC secrets from the environment variable, the file or Vault, they're rotated at runtime.
+	Keys *KeysConfig `yaml:"keys,omitempty"`
+	// PublicKeyFile is the path of the PEM encoded RSA or ECDSA public key, or the certificate.
+	PublicKeyFile string `yaml:"public_key_file,omitempty"`
+	// Issuer is the required "iss" claim, it's not checked if it's empty.
+	Issuer string `yaml:"issuer,omitempty"`
+	// Audience is the required value of the "aud" claim, it's not checked if it's empty.
+	Audience string `yaml:"audience,omitempty"`
+	// Leeway is the tolerance of the clock skew when the "exp" and "nbf" claims are checked.
+	Leeway time.Duration `yaml:"leeway,omitempty"`
+}
+
+// validate returns the problems of the config in the message of Validate.
+func (c *AuthConfig) validate() string {
+	errMsg := ""
+	if len(c.Tokens) == 0 && c.JWT == nil {
+		errMsg += "The auth requires the tokens or the JWT. "
+	}
+	for _, t := range c.Tokens {
+		if t.Token == "" {
+			errMsg += "The static tokens of auth must not be empty. "
+			break
+		}
+	}
+	if c.JWT != nil {
+		keys := 0
+		for _, set := range []bool{c.JWT.Secret != "", c.JWT.Keys != nil, c.JWT.PublicKeyFile != ""} {
+			if set {
+				keys++
+			}
+		}
+		if keys != 1 {
+			errMsg += "The JWT of auth requires one of the secret, the keys and the public key file. "
+		}
+		if c.JWT.Keys != nil {
+			errMsg += c.JWT.Keys.validate("JWT")
+		}
+		if c.JWT.Leeway < 0 {
+			errMsg += "The leeway of JWT must not be negative. "
+		}
+	}
+	return errMsg
+}
+
+// Credential is what a client presents in the handshake.
+type Credential struct {
+	// Name is the name of client.
+	Name string
+	// Type is the type of client, e.g. "Source" or "Stream Function".
+	Type string
+	// Token is the token of client, it's empty if the client doesn't send one.
+	Token string
+	// Addr is the address of client.
+	Addr string
+	// Identities are the identities in the certificate of client.
+	Identities []string
+}
+
+// Principal is the authenticated identity of client.
+type Principal struct {
+	// Subject is the identity of client.
+	Subject string
+	// Roles are the roles or scopes of client.
+	Roles []string
+	// Claims are the claims of JWT, it's nil if the client is authenticated by the other credentials.
+	Claims map[string]interface{}
+}
+
+// Authenticator authenticates the clients in the handshake, the client is rejected if it returns an error.
+type Authenticator interface {
+	Authenticate(cred Credential) (*Principal, error)
+}
+
+// AuthenticatorFunc is the function which authenticates the clients.
+type AuthenticatorFunc func(cred Credential) (*Principal, error)
+
+// Authenticate calls f(cred).
+func (f AuthenticatorFunc) Authenticate(cred Credential) (*Principal, error) {
+	return f(cred)
+}
+
+// tokenAuthenticator authenticates the clients by the static tokens and the JWTs.
+type tokenAuthenticator struct {
+	tokens []StaticToken
+	jwt    *jwtVerifier
+}
+
+// newAuthenticator creates the authenticator of config, the public key or the keys of JWT are loaded.
+func newAuthenticator(conf *AuthConfig) (Authenticator, error) {
+	a := &tokenAuthenticator{tokens: conf.Tokens}
+	if conf.JWT != nil {
+		var err error
+		if a.jwt, err = newJWTVerifier(conf.JWT); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+func (a *tokenAuthenticator) Authenticate(cred Credential) (*Principal, error) {
+	if cred.Token == "" {
+		return nil, errors.New("the token is missing")
+	}
+	// all tokens are compared, so the time doesn't tell which one matches.
+	var matched *StaticToken
+	for i, t := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(cred.Token), []byte(t.Token)) == 1 && matched == nil {
+			matched = &a.tokens[i]
+		}
+	}
+	if matched != nil {
+		subject := matched.Subject
+		if subject == "" {
+			subject = cred.Name
+		}
+		return &Principal{Subject: subject, Roles: matched.Roles}, nil
+	}
+	if a.jwt != nil && strings.Count(cred.Token, ".") == 2 {
+		claims, err := a.jwt.verify(cred.Token, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		subject, _ := claims["sub"].(string)
+		return &Principal{Subject: subject, Roles: jwtRoles(claims), Claims: claims}, nil
+	}
+	return nil, errors.New("the token is invalid")
+}
+
+// jwtVerifier verifies the signature and the claims of JWT.
+type jwtVerifier struct {
+	conf    *JWTConfig
+	secret  []byte
+	keyring *kms.Keyring // keyring holds the HMAC secrets provided by the keys, it's nil if they're not configured.
+	key     crypto.PublicKey
+}
+
+func newJWTVerifier(conf *JWTConfig) (*jwtVerifier, error) {
+	v := &jwtVerifier{conf: conf, secret: []byte(conf.Secret)}
+	if conf.Keys != nil {
+		keyring, err := loadKeys(conf.Keys)
+		if err != nil {
+			return nil, fmt.Errorf("load the keys of JWT failed: %v", err)
+		}
+		v.keyring = keyring
+	}
+	if conf.PublicKeyFile == "" {
+		return v, nil
+	}
+	buf, err := ioutil.ReadFile(conf.PublicKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(buf)
+	if block == nil {
+		return nil, fmt.Errorf("the public key of JWT is not PEM encoded: %s", conf.PublicKeyFile)
+	}
+	switch block.Type {
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		v.key = cert.PublicKey
+	case "RSA PUBLIC KEY":
+		if v.key, err = x509.ParsePKCS1PublicKey(block.Bytes); err != nil {
+			return nil, err
+		}
+	default:
+		if v.key, err = x509.ParsePKIXPublicKey(block.Bytes); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// verify returns the claims of the token if its signature and claims are valid at now.
+func (v *jwtVerifier) verify(token string, now time.Time) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("the signature of JWT is malformed")
+	}
+	if err := v.verifySignature(header.Alg, header.Kid, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]interface{}
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	leeway := v.conf.Leeway
+	if exp, ok := claims["exp"].(json.Number); ok {
+		t, err := exp.Float64()
+		if err != nil || now.Add(-leeway).After(time.Unix(int64(t), 0)) {
+			return nil, errors.New("the JWT is expired")
+		}
+	}
+	if nbf, ok := claims["nbf"].(json.Number); ok {
+		t, err := nbf.Float64()
+		if err != nil || now.Add(leeway).Before(time.Unix(int64(t), 0)) {
+			return nil, errors.New("the JWT is not valid yet")
+		}
+	}
+	if v.conf.Issuer != "" && claims["iss"] != v.conf.Issuer {
+		return nil, errors.New("the issuer of JWT is not trusted")
+	}
+	if v.conf.Audience != "" && !jwtAudience(claims["aud"], v.conf.Audience) {
+		return nil, errors.New("the JWT is not issued for this audience")
+	}
+	return claims, nil
+}
+
+// verifySignature verifies the signature of the signing input by the algorithm and the key of kid, the algorithm
+// must match the key, so the tokens signed by the public key as the HMAC secret are rejected.
+func (v *jwtVerifier) verifySignature(alg string, kid string, input []byte, signature []byte) error {
+	if len(alg) != 5 {
+		return fmt.Errorf("the algorithm %q of JWT is not supported", alg)
+	}
+	var newHash func() hash.Hash
+	var h crypto.Hash
+	switch alg[2:] {
+	case "256":
+		newHash, h = sha256.New, crypto.SHA256
+	case "384":
+		newHash, h = sha512.New384, crypto.SHA384
+	case "512":
+		newHash, h = sha512.New, crypto.SHA512
+	default:
+		return fmt.Errorf("the algorithm %q of JWT is not supported", alg)
+	}
+
+	switch {
+	case strings.HasPrefix(alg, "HS") && (len(v.secret) > 0 || v.keyring != nil):
+		for _, secret := range v.secrets(kid) {
+			mac := hmac.New(newHash, secret)
+			mac.Write(input)
+			if hmac.Equal(mac.Sum(nil), signature) {
+				return nil
+			}
+		}
+		return errors.New("the signature of JWT is invalid")
+	case strings.HasPrefix(alg, "RS"):
+		key, ok := v.key.(*rsa.PublicKey)
+		if !ok {
+			break
+		}
+		digest := newHash()
+		digest.Write(input)
+		if rsa.VerifyPKCS1v15(key, h, digest.Sum(nil), signature) != nil {
+			return errors.New("the signature of JWT is invalid")
+		}
+		return nil
+	case strings.HasPrefix(alg, "ES"):
+		key, ok := v.key.(*ecdsa.PublicKey)
+		if !ok {
+			break
+		}
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return errors.New("the signature of JWT is invalid")
+		}
+		digest := newHash()
+		digest.Write(input)
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(key, digest.Sum(nil), r, s) {
+			return errors.New("the signature of JWT is invalid")
+		}
+		return nil
+	}
+	return fmt.Errorf("the algorithm %q of JWT doesn't match the key", alg)
+}
+
+// secrets returns the HMAC secrets which may sign the JWT of kid, they're all valid keys if kid is empty, so the
+// tokens signed by the previous key are still accepted in the grace period of rotation.
+func (v *jwtVerifier) secrets(kid string) [][]byte {
+	if v.keyring == nil {
+		return [][]byte{v.secret}
+	}
+	if kid != "" {
+		k, err := v.keyring.Lookup(kid)
+		if err != nil {
+			return nil
+		}
+		return [][]byte{k.Material}
+	}
+	keys := v.keyring.Keys()
+	secrets := make([][]byte, 0, len(keys))
+	for _, k := range keys {
+		secrets = append(secrets, k.Material)
+	}
+	return secrets
+}
+
+// decodeJWTPart decodes the base64url encoded JSON of header or claims, the numbers are kept as json.Number.
+func decodeJWTPart(part string, v interface{}) error {
+	buf, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return errors.New("the JWT is malformed")
+	}
+	decoder := json.NewDecoder(bytes.NewReader(buf))
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return errors.New("the JWT is malformed")
+	}
+	return nil
+}
+
+// jwtAudience indicates if the "aud" claim, which is a string or an array of strings, contains the audience.
+func jwtAudience(aud interface{}, audience string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == audience
+	case []interface{}:
+		for _, a := range aud {
+			if a == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// jwtRoles returns the roles of the "roles" claim and the space-separated scopes of the "scope" claim.
+func jwtRoles(claims map[string]interface{}) []string {
+	var roles []string
+	if list, ok := claims["roles"].([]interface{}); ok {
+		for _, role := range list {
+			if role, ok := role.(string); ok {
+				roles = append(roles, role)
+			}
+		}
+	}
+	if scope, ok := claims["scope"].(string); ok {
+		roles = append(roles, strings.Fields(scope)...)
+	}
+	return roles
+}
@@ -0,0 +1,224 @@
+package zipper
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/core/quic"
+	"github.com/yomorun/yomo/internal/core"
+	"github.com/yomorun/yomo/internal/frame"
+)
+
+// signJWT signs the claims by the algorithm, key is the HMAC secret, or the RSA or ECDSA private key.
+func signJWT(t *testing.T, alg string, key interface{}, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(input))
+
+	var signature []byte
+	switch key := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(input))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		var err error
+		signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		assert.NoError(t, err)
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		assert.NoError(t, err)
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// writePublicKey writes the PEM encoded public key to a file.
+func writePublicKey(t *testing.T, key interface{}) string {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	assert.NoError(t, err)
+	file := filepath.Join(t.TempDir(), "key.pem")
+	assert.NoError(t, ioutil.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644))
+	return file
+}
+
+func TestStaticTokens(t *testing.T) {
+	a, err := newAuthenticator(&AuthConfig{Tokens: []StaticToken{{Subject: "sensors", Token: "s3cret"}, {Token: "other"}}})
+	assert.NoError(t, err)
+
+	principal, err := a.Authenticate(Credential{Name: "sensor-1", Token: "s3cret"})
+	assert.NoError(t, err)
+	assert.Equal(t, &Principal{Subject: "sensors"}, principal)
+	// the subject is the name of client by default.
+	principal, err = a.Authenticate(Credential{Name: "sensor-2", Token: "other"})
+	assert.NoError(t, err)
+	assert.Equal(t, "sensor-2", principal.Subject)
+
+	_, err = a.Authenticate(Credential{Name: "sensor-1"})
+	assert.EqualError(t, err, "the token is missing")
+	_, err = a.Authenticate(Credential{Name: "sensor-1", Token: "guessed"})
+	assert.EqualError(t, err, "the token is invalid")
+}
+
+func TestJWTSecret(t *testing.T) {
+	secret := []byte("jwt-secret")
+	a, err := newAuthenticator(&AuthConfig{JWT: &JWTConfig{Secret: string(secret), Issuer: "yomo", Audience: "zipper", Leeway: time.Minute}})
+	assert.NoError(t, err)
+	now := time.Now().Unix()
+
+	token := signJWT(t, "HS256", secret, map[string]interface{}{"sub": "alerting", "iss": "yomo", "aud": []string{"zipper"}, "exp": now + 60})
+	principal, err := a.Authenticate(Credential{Name: "alerting", Token: token})
+	assert.NoError(t, err)
+	assert.Equal(t, "alerting", principal.Subject)
+	assert.Equal(t, "yomo", principal.Claims["iss"])
+
+	// the clock skew is tolerated.
+	token = signJWT(t, "HS256", secret, map[string]interface{}{"iss": "yomo", "aud": "zipper", "exp": now - 30, "nbf": now + 30})
+	_, err = a.Authenticate(Credential{Token: token})
+	assert.NoError(t, err)
+
+	cases := map[string]string{
+		"the JWT is expired":                           signJWT(t, "HS256", secret, map[string]interface{}{"iss": "yomo", "aud": "zipper", "exp": now - 120}),
+		"the JWT is not valid yet":                     signJWT(t, "HS256", secret, map[string]interface{}{"iss": "yomo", "aud": "zipper", "nbf": now + 120}),
+		"the issuer of JWT is not trusted":             signJWT(t, "HS256", secret, map[string]interface{}{"iss": "other", "aud": "zipper"}),
+		"the JWT is not issued for this audience":      signJWT(t, "HS256", secret, map[string]interface{}{"iss": "yomo", "aud": "other"}),
+		"the signature of JWT is invalid":              signJWT(t, "HS256", []byte("guessed"), map[string]interface{}{"iss": "yomo", "aud": "zipper"}),
+		`the algorithm "none" of JWT is not supported`: signJWT(t, "none", nil, map[string]interface{}{"iss": "yomo", "aud": "zipper"}),
+		"the JWT is malformed":                         "!.e30.sig",
+	}
+	for reason, token := range cases {
+		_, err := a.Authenticate(Credential{Token: token})
+		assert.EqualError(t, err, reason)
+	}
+}
+
+func TestJWTPublicKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	a, err := newAuthenticator(&AuthConfig{JWT: &JWTConfig{PublicKeyFile: writePublicKey(t, &rsaKey.PublicKey)}})
+	assert.NoError(t, err)
+
+	principal, err := a.Authenticate(Credential{Token: signJWT(t, "RS256", rsaKey, map[string]interface{}{"sub": "sensor-1"})})
+	assert.NoError(t, err)
+	assert.Equal(t, "sensor-1", principal.Subject)
+
+	// the token signed by the public key as the HMAC secret is rejected.
+	der, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	_, err = a.Authenticate(Credential{Token: signJWT(t, "HS256", der, map[string]interface{}{"sub": "sensor-1"})})
+	assert.EqualError(t, err, `the algorithm "HS256" of JWT doesn't match the key`)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	a, err = newAuthenticator(&AuthConfig{JWT: &JWTConfig{PublicKeyFile: writePublicKey(t, &ecKey.PublicKey)}})
+	assert.NoError(t, err)
+	principal, err = a.Authenticate(Credential{Token: signJWT(t, "ES256", ecKey, map[string]interface{}{"sub": "sensor-2"})})
+	assert.NoError(t, err)
+	assert.Equal(t, "sensor-2", principal.Subject)
+	_, err = a.Authenticate(Credential{Token: signJWT(t, "RS256", rsaKey, map[string]interface{}{"sub": "sensor-2"})})
+	assert.EqualError(t, err, `the algorithm "RS256" of JWT doesn't match the key`)
+
+	_, err = newAuthenticator(&AuthConfig{JWT: &JWTConfig{PublicKeyFile: filepath.Join(t.TempDir(), "none.pem")}})
+	assert.Error(t, err)
+}
+
+func TestConnAuthenticate(t *testing.T) {
+	p := newProber(&SLIConfig{}, "test")
+	var received Credential
+	c := &Conn{Addr: "127.0.0.1:1", Conn: quic.NewConn("", core.ConnTypeNone), prober: p}
+	c.authenticator = AuthenticatorFunc(func(cred Credential) (*Principal, error) {
+		received = cred
+		if cred.Token != "s3cret" {
+			return nil, errors.New("the token is invalid")
+		}
+		return nil, nil
+	})
+
+	handshake := frame.NewHandshakeFrame("alerting", byte(core.ConnTypeStreamFunction))
+	assert.EqualError(t, c.authenticate(handshake), "the token is invalid")
+	assert.Equal(t, "", c.Subject())
+
+	handshake.Token = "s3cret"
+	assert.NoError(t, c.authenticate(handshake))
+	assert.Equal(t, Credential{Name: "alerting", Type: "Stream Function", Token: "s3cret", Addr: "127.0.0.1:1"}, received)
+	// the subject is the name of client if the authenticator doesn't tell it.
+	assert.Equal(t, "alerting", c.Subject())
+
+	// the probes of this YoMo-Zipper are authenticated by their own token.
+	handshake = frame.NewHandshakeFrame(probeTransactionPrefix+"p1", byte(core.ConnTypeSource))
+	handshake.Token = p.token
+	assert.NoError(t, c.authenticate(handshake))
+	assert.Equal(t, "", c.Subject())
+}
+
+// TestCloseUnauthenticatedSession closes the session after the handshake is rejected, so the tokens can't be
+// guessed by the handshakes on one session.
+func TestCloseUnauthenticatedSession(t *testing.T) {
+	conf := *testConfig
+	conf.Auth = &AuthConfig{Tokens: []StaticToken{{Token: "s3cret"}}}
+	server := New(&conf)
+	go func() {
+		server.Serve(fmt.Sprintf("%s:%d", conf.Host, conf.Port+3))
+	}()
+	defer server.Close()
+
+	session, err := quic.NewClient(fmt.Sprintf("%s:%d", conf.Host, conf.Port+3))
+	assert.NoError(t, err)
+	defer session.Close()
+	stream, err := session.CreateStream(context.Background())
+	assert.NoError(t, err)
+	signal := core.NewFrameStream(stream)
+
+	handshake := frame.NewHandshakeFrame("guesser", byte(core.ConnTypeSource))
+	handshake.Version = frame.Version
+	handshake.Token = "guess"
+	_, err = signal.WriteFrame(handshake)
+	assert.NoError(t, err)
+	f, err := signal.ReadFrame()
+	assert.NoError(t, err)
+	assert.Equal(t, frame.RejectUnauthenticated, f.(*frame.RejectedFrame).Code)
+
+	// the next handshake isn't answered even if its token is valid.
+	handshake.Token = "s3cret"
+	signal.WriteFrame(handshake)
+	stream.SetReadDeadline(time.Now().Add(3 * time.Second))
+	_, err = signal.ReadFrame()
+	assert.Error(t, err)
+}
+
+func TestValidateAuth(t *testing.T) {
+	conf := &WorkflowConfig{Name: "zipper", Host: "localhost", Port: 9000, Auth: &AuthConfig{
+		Tokens: []StaticToken{{Subject: "sensors"}},
+		JWT:    &JWTConfig{Secret: "secret", PublicKeyFile: "key.pem"},
+	}}
+	err := Validate(conf)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "The static tokens of auth must not be empty.")
+		assert.Contains(t, err.Error(), "The JWT of auth requires one of the secret, the keys and the public key file.")
+	}
+
+	conf.Auth = &AuthConfig{}
+	err = Validate(conf)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "The auth requires the tokens or the JWT.")
+	}
+}
@@ -0,0 +1,198 @@
+package zipper
+
+import (
+	"errors"
+	"io/ioutil"
+	"path"
+
+	"github.com/yomorun/yomo/core/quic"
+	"github.com/yomorun/yomo/internal/core"
+	"github.com/yomorun/yomo/internal/frame"
+	"github.com/yomorun/yomo/logger"
+	"gopkg.in/yaml.v2"
+)
+
+// errUnauthorized is the message of rejection sent to the clients which are not authorized by the policies.
+const errUnauthorized = "the client is not authorized"
+
+// AuthzConfig authorizes the clients by the policies, e.g. which sources may emit which tags and which stream
+// functions may be registered, so a leaked credential of sensor can't register itself as the "alerting" function.
+// The clients are denied unless a policy grants them, the unauthenticated clients have the empty subject and no
+// roles.
+type AuthzConfig struct {
+	// Policies grant the permissions to the principals.
+	Policies []Policy `yaml:"policies,omitempty"`
+	// File is the path of the YAML file of policies, e.g. the one managed by the security team, they're added to
+	// the policies of config.
+	File string `yaml:"file,omitempty"`
+}
+
+// Policy grants the principals of the subjects or roles to connect as the clients of the names, the names are
+// the patterns of path.Match, e.g. "sensor-*".
+type Policy struct {
+	// Subjects are the patterns of the subjects of principals, "*" matches all principals.
+	Subjects []string `yaml:"subjects,omitempty"`
+	// Roles are the roles of principals, e.g. the "roles" or "scope" claims of JWT.
+	Roles []string `yaml:"roles,omitempty"`
+	// Sources are the patterns of the names which the principals may connect as sources.
+	Sources []string `yaml:"sources,omitempty"`
+	// Functions are the patterns of the names which the principals may register as stream functions.
+	Functions []string `yaml:"functions,omitempty"`
+	// Zippers are the patterns of the names which the principals may connect as upstream YoMo-Zippers.
+	Zippers []string `yaml:"zippers,omitempty"`
+	// Tags are the data tags which the sources of this policy may emit, all tags are allowed if it's empty.
+	Tags []byte `yaml:"tags,omitempty"`
+}
+
+// validate returns the problems of the config in the message of Validate.
+func (c *AuthzConfig) validate() string {
+	errMsg := ""
+	for _, p := range c.Policies {
+		if len(p.Subjects) == 0 && len(p.Roles) == 0 {
+			errMsg += "The policies of authz require the subjects or the roles. "
+			break
+		}
+	}
+	for _, p := range c.Policies {
+		for _, patterns := range [][]string{p.Subjects, p.Sources, p.Functions, p.Zippers} {
+			for _, pattern := range patterns {
+				if _, err := path.Match(pattern, ""); err != nil {
+					errMsg += "The pattern " + pattern + " of authz is malformed. "
+				}
+			}
+		}
+	}
+	return errMsg
+}
+
+// Authorizer authorizes the authenticated clients in the handshake. The tags of sources are authorized once in
+// the handshake, the frames of the other tags are dropped.
+type Authorizer interface {
+	// AuthorizeClient reports if the principal may connect as the client of the name and type, e.g. "Source" or
+	// "Stream Function".
+	AuthorizeClient(p *Principal, name string, clientType string) bool
+	// AuthorizeTag reports if the principal may emit the data tag as the source of the name.
+	AuthorizeTag(p *Principal, name string, tag byte) bool
+}
+
+// policyAuthorizer authorizes the clients by the policies.
+type policyAuthorizer struct {
+	policies []Policy
+}
+
+// newAuthorizer creates the authorizer of config, the policies of file are loaded.
+func newAuthorizer(conf *AuthzConfig) (Authorizer, error) {
+	a := &policyAuthorizer{policies: append([]Policy(nil), conf.Policies...)}
+	if conf.File == "" {
+		return a, nil
+	}
+	buf, err := ioutil.ReadFile(conf.File)
+	if err != nil {
+		return nil, err
+	}
+	var file AuthzConfig
+	if err := yaml.Unmarshal(buf, &file); err != nil {
+		return nil, err
+	}
+	if errMsg := file.validate(); errMsg != "" {
+		return nil, errors.New(errMsg)
+	}
+	a.policies = append(a.policies, file.Policies...)
+	return a, nil
+}
+
+func (a *policyAuthorizer) AuthorizeClient(p *Principal, name string, clientType string) bool {
+	for _, policy := range a.policies {
+		if policy.appliesTo(p) && matchName(policy.namesOf(clientType), name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *policyAuthorizer) AuthorizeTag(p *Principal, name string, tag byte) bool {
+	for _, policy := range a.policies {
+		if !policy.appliesTo(p) || !matchName(policy.Sources, name) {
+			continue
+		}
+		if len(policy.Tags) == 0 {
+			return true
+		}
+		for _, t := range policy.Tags {
+			if t == tag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// appliesTo indicates if the policy grants the principal by its subject or roles.
+func (p Policy) appliesTo(principal *Principal) bool {
+	if matchName(p.Subjects, principal.Subject) {
+		return true
+	}
+	for _, role := range p.Roles {
+		for _, r := range principal.Roles {
+			if r == role {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// namesOf returns the patterns of the names of the type of client.
+func (p Policy) namesOf(clientType string) []string {
+	switch clientType {
+	case core.ConnTypeSource.String():
+		return p.Sources
+	case core.ConnTypeStreamFunction.String():
+		return p.Functions
+	case core.ConnTypeUpstreamZipper.String():
+		return p.Zippers
+	default:
+		return nil
+	}
+}
+
+// matchName indicates if the name matches any of the patterns.
+func matchName(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// tagGrant is the data tags which a source may emit.
+type tagGrant [256]bool
+
+// newTagGrant authorizes all tags of the source in the handshake, so the frames are checked without calling the
+// authorizer.
+func newTagGrant(a Authorizer, p *Principal, name string) *tagGrant {
+	grant := new(tagGrant)
+	for tag := 0; tag < len(grant); tag++ {
+		grant[tag] = a.AuthorizeTag(p, name, byte(tag))
+	}
+	return grant
+}
+
+// permitted indicates if the client of session may emit the tags of frame, all tags are permitted if the client
+// isn't authorized by tags. The frame which isn't permitted is logged.
+func permitted(session quic.Session, data *frame.DataFrame) bool {
+	v, ok := sessionGrants.Load(session)
+	if !ok {
+		return true
+	}
+	grant := v.(*tagGrant)
+	for _, tag := range data.Tags() {
+		if !grant[tag] {
+			logger.Error("[zipper] drop the frame of the tag which the source is not authorized to emit.", "TransactionID", data.TransactionID(), "tag", tag)
+			pipelineMetrics.failed(errorUnauthorized)
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,96 @@
+package zipper
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/core/quic"
+	"github.com/yomorun/yomo/internal/core"
+	"github.com/yomorun/yomo/internal/frame"
+)
+
+func TestPolicyAuthorizer(t *testing.T) {
+	a, err := newAuthorizer(&AuthzConfig{Policies: []Policy{
+		{Subjects: []string{"sensors"}, Sources: []string{"sensor-*"}, Tags: []byte{0x33}},
+		{Roles: []string{"ops"}, Functions: []string{"alerting"}},
+		{Subjects: []string{"*"}, Functions: []string{"public-*"}},
+	}})
+	assert.NoError(t, err)
+
+	sensors := &Principal{Subject: "sensors"}
+	assert.True(t, a.AuthorizeClient(sensors, "sensor-1", core.ConnTypeSource.String()))
+	// a leaked credential of sensor can't register itself as the alerting function.
+	assert.False(t, a.AuthorizeClient(sensors, "alerting", core.ConnTypeStreamFunction.String()))
+	assert.False(t, a.AuthorizeClient(sensors, "sensor-1", core.ConnTypeStreamFunction.String()))
+	assert.True(t, a.AuthorizeClient(sensors, "public-echo", core.ConnTypeStreamFunction.String()))
+	assert.True(t, a.AuthorizeTag(sensors, "sensor-1", 0x33))
+	assert.False(t, a.AuthorizeTag(sensors, "sensor-1", 0x34))
+
+	ops := &Principal{Subject: "alice", Roles: []string{"ops"}}
+	assert.True(t, a.AuthorizeClient(ops, "alerting", core.ConnTypeStreamFunction.String()))
+	assert.False(t, a.AuthorizeClient(ops, "sensor-1", core.ConnTypeSource.String()))
+	assert.False(t, a.AuthorizeClient(ops, "zipper-2", core.ConnTypeUpstreamZipper.String()))
+}
+
+func TestAuthzFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "policies.yaml")
+	assert.NoError(t, ioutil.WriteFile(file, []byte("policies:\n  - subjects: [mesh]\n    zippers: [\"*\"]\n"), 0644))
+	a, err := newAuthorizer(&AuthzConfig{File: file, Policies: []Policy{{Subjects: []string{"sensors"}, Sources: []string{"*"}}}})
+	assert.NoError(t, err)
+	assert.True(t, a.AuthorizeClient(&Principal{Subject: "mesh"}, "zipper-2", core.ConnTypeUpstreamZipper.String()))
+	assert.True(t, a.AuthorizeClient(&Principal{Subject: "sensors"}, "sensor-1", core.ConnTypeSource.String()))
+	// all tags are authorized if the policy doesn't restrict them.
+	assert.True(t, a.AuthorizeTag(&Principal{Subject: "sensors"}, "sensor-1", 0x7f))
+
+	assert.NoError(t, ioutil.WriteFile(file, []byte("policies:\n  - zippers: [\"*\"]\n"), 0644))
+	_, err = newAuthorizer(&AuthzConfig{File: file})
+	assert.EqualError(t, err, "The policies of authz require the subjects or the roles. ")
+
+	_, err = newAuthorizer(&AuthzConfig{File: filepath.Join(t.TempDir(), "none.yaml")})
+	assert.Error(t, err)
+}
+
+type authorizedSession struct {
+	quic.Session
+}
+
+func TestConnAuthorizeClient(t *testing.T) {
+	session := &authorizedSession{}
+	defer sessionGrants.Delete(session)
+	a, _ := newAuthorizer(&AuthzConfig{Policies: []Policy{{Subjects: []string{"sensors"}, Sources: []string{"sensor-*"}, Tags: []byte{0x33}}}})
+	p := newProber(&SLIConfig{}, "test")
+	c := &Conn{Session: session, Conn: quic.NewConn("", core.ConnTypeNone), prober: p, authorizer: a}
+
+	// the unauthenticated clients have no grants.
+	assert.False(t, c.authorizeClient(frame.NewHandshakeFrame("sensor-1", byte(core.ConnTypeSource)), core.ConnTypeSource))
+
+	c.principal = &Principal{Subject: "sensors"}
+	assert.False(t, c.authorizeClient(frame.NewHandshakeFrame("alerting", byte(core.ConnTypeStreamFunction)), core.ConnTypeStreamFunction))
+	assert.True(t, c.authorizeClient(frame.NewHandshakeFrame("sensor-1", byte(core.ConnTypeSource)), core.ConnTypeSource))
+
+	data := frame.NewDataFrame("tid")
+	data.SetCarriage(0x33, []byte("yomo"))
+	assert.True(t, permitted(session, data))
+	data.SetCarriage(0x34, []byte("yomo"))
+	assert.False(t, permitted(session, data))
+
+	// the probes of this YoMo-Zipper are always authorized.
+	handshake := frame.NewHandshakeFrame(probeTransactionPrefix+"p1", byte(core.ConnTypeSource))
+	handshake.Token = p.token
+	assert.True(t, c.authorizeClient(handshake, core.ConnTypeSource))
+	assert.True(t, permitted(session, data))
+}
+
+func TestValidateAuthz(t *testing.T) {
+	conf := &WorkflowConfig{Name: "zipper", Host: "localhost", Port: 9000, Authz: &AuthzConfig{Policies: []Policy{
+		{Sources: []string{"sensor-*"}},
+		{Subjects: []string{"sensors"}, Functions: []string{"[alerting"}},
+	}}}
+	err := Validate(conf)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "The policies of authz require the subjects or the roles.")
+		assert.Contains(t, err.Error(), "The pattern [alerting of authz is malformed.")
+	}
+}
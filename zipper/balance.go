@@ -0,0 +1,51 @@
+package zipper
+
+import (
+	"github.com/yomorun/yomo/core/quic"
+)
+
+// LoadBalance is the strategy of choosing an instance of a stream function.
+type LoadBalance string
+
+const (
+	// LoadBalanceRoundRobin chooses the instances in turn.
+	LoadBalanceRoundRobin LoadBalance = "round_robin"
+	// LoadBalanceLatency chooses the instance with the lowest smoothed RTT of its QUIC session,
+	// it falls back to round-robin if the RTT of any instance is unknown, e.g. it connects by TCP.
+	LoadBalanceLatency LoadBalance = "latency"
+)
+
+// valid indicates if the strategy is supported.
+func (lb LoadBalance) valid() bool {
+	return lb == "" || lb == LoadBalanceRoundRobin || lb == LoadBalanceLatency
+}
+
+// loadBalanceOf returns the strategy of the stream function.
+func loadBalanceOf(name string) LoadBalance {
+	app, ok := appCache.Load(name)
+	if !ok {
+		return LoadBalanceRoundRobin
+	}
+	return app.(App).LoadBalance
+}
+
+// lowestLatency returns the index of the instance with the lowest smoothed RTT,
+// false is returned if the RTT of any instance is unknown.
+func lowestLatency(funcs []streamFuncWithCancel) (int, bool) {
+	index := -1
+	var lowest quic.Stats
+	for i, f := range funcs {
+		if f.session == nil {
+			return 0, false
+		}
+		stats, ok := quic.StatsOf(f.session)
+		if !ok || stats.SmoothedRTT <= 0 {
+			return 0, false
+		}
+		if index < 0 || stats.SmoothedRTT < lowest.SmoothedRTT {
+			index = i
+			lowest = stats
+		}
+	}
+	return index, index >= 0
+}
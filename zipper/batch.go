@@ -0,0 +1,102 @@
+package zipper
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/yomorun/yomo/core/quic"
+	"github.com/yomorun/yomo/logger"
+)
+
+const (
+	// batchSize is the max count of frames coalesced into one stream.
+	batchSize = 32
+	// batchWindow is the max time which the first frame of a batch waits for the others.
+	batchWindow = time.Millisecond
+)
+
+// sessionBatchers are the batchers of the sessions of stream functions.
+var sessionBatchers = sync.Map{}
+
+// batcher coalesces the frames sent to a session of stream function into one unidirectional stream, it saves
+// opening a stream for each small frame, the stream function reads the frames until the end of stream.
+type batcher struct {
+	name    string
+	session quic.Session
+	cancel  CancelFunc
+	frames  chan []byte
+	ctx     context.Context // ctx is done after the session is closed.
+}
+
+// batcherOf returns the batcher of the session, it's created at the first call and stopped after the session
+// is closed.
+func batcherOf(name string, session quic.Session, cancel CancelFunc) *batcher {
+	if b, ok := sessionBatchers.Load(session); ok {
+		return b.(*batcher)
+	}
+
+	b := &batcher{name: name, session: session, cancel: cancel, frames: make(chan []byte, bufferSize), ctx: session.Context()}
+	if actual, loaded := sessionBatchers.LoadOrStore(session, b); loaded {
+		return actual.(*batcher)
+	}
+	go b.run()
+	return b
+}
+
+// push the encoded frame to the next batch, it's dropped if the session is closed.
+func (b *batcher) push(buf []byte) {
+	select {
+	case b.frames <- buf:
+	case <-b.ctx.Done():
+		logger.Debug("[MergeStreamFunc] drop the frame of batch, the session of `stream-fn` is closed.", "stream-fn", b.name)
+	}
+}
+
+// run sends a batch when it's full or the window is over, until the session is closed.
+func (b *batcher) run() {
+	defer sessionBatchers.Delete(b.session)
+
+	for {
+		var buf []byte
+		select {
+		case <-b.ctx.Done():
+			return
+		case item := <-b.frames:
+			buf = append(buf, item...)
+		}
+
+		timer := time.NewTimer(batchWindow)
+	COLLECT:
+		for n := 1; n < batchSize; n++ {
+			select {
+			case item := <-b.frames:
+				buf = append(buf, item...)
+			case <-timer.C:
+				break COLLECT
+			}
+		}
+		timer.Stop()
+
+		b.flush(buf)
+	}
+}
+
+// flush writes the batch in a new unidirectional stream.
+func (b *batcher) flush(buf []byte) {
+	stream, err := b.session.OpenUniStream()
+	if err != nil {
+		logger.Error("[MergeStreamFunc] session.OpenUniStream of batch failed", "stream-fn", b.name, "err", err)
+		b.cancel()
+		return
+	}
+
+	if _, err := stream.Write(buf); err != nil {
+		logger.Error("[MergeStreamFunc] YoMo-Zipper sent the batch to `stream-fn` failed.", "stream-fn", b.name, "err", err)
+		stream.CancelWrite(0)
+		b.cancel()
+		return
+	}
+	stream.Close()
+	logger.Debug("[MergeStreamFunc] YoMo-Zipper sent the batch to `stream-fn`.", "stream-fn", b.name, "size", len(buf))
+}
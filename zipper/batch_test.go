@@ -0,0 +1,77 @@
+package zipper
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	quicGo "github.com/lucas-clemente/quic-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/core/quic"
+	"github.com/yomorun/yomo/internal/core"
+	"github.com/yomorun/yomo/internal/frame"
+)
+
+// batchSession records the unidirectional streams which are closed.
+type batchSession struct {
+	quic.Session
+	ctx     context.Context
+	streams chan *bytes.Buffer
+}
+
+func (s *batchSession) Context() context.Context {
+	return s.ctx
+}
+
+func (s *batchSession) OpenUniStream() (quicGo.SendStream, error) {
+	return &batchStream{closed: s.streams}, nil
+}
+
+type batchStream struct {
+	quicGo.SendStream
+	bytes.Buffer
+	closed chan *bytes.Buffer
+}
+
+func (s *batchStream) Write(p []byte) (int, error) {
+	return s.Buffer.Write(p)
+}
+
+func (s *batchStream) Close() error {
+	s.closed <- &s.Buffer
+	return nil
+}
+
+func TestBatcher(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	session := &batchSession{ctx: ctx, streams: make(chan *bytes.Buffer, 10)}
+
+	b := batcherOf("batch-fn", session, func() {})
+	assert.Same(t, b, batcherOf("batch-fn", session, func() {}))
+	for _, tid := range []string{"1", "2", "3"} {
+		f := frame.NewDataFrame(tid)
+		f.SetCarriage(0x10, []byte(tid))
+		b.push(f.Encode())
+	}
+
+	// the frames are coalesced into one stream.
+	var stream *bytes.Buffer
+	select {
+	case stream = <-session.streams:
+	case <-time.After(time.Second):
+		t.Fatal("the batch is not sent")
+	}
+	for _, tid := range []string{"1", "2", "3"} {
+		f, err := core.ParseFrame(stream)
+		assert.NoError(t, err)
+		assert.Equal(t, tid, f.(*frame.DataFrame).TransactionID())
+	}
+
+	// the batcher is stopped after the session is closed.
+	cancel()
+	assert.Eventually(t, func() bool {
+		_, ok := sessionBatchers.Load(session)
+		return !ok
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,282 @@
+package zipper
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/yomorun/yomo/internal/frame"
+	"github.com/yomorun/yomo/logger"
+)
+
+const (
+	// DefaultCaptureCount is the default count of frames kept for each data tag.
+	DefaultCaptureCount = 10
+	// DefaultCapturePayload is the default size in bytes of the payload kept for each frame.
+	DefaultCapturePayload = 256
+	// DefaultCaptureMaxSize is the default size in bytes of the capture file before it's rotated.
+	DefaultCaptureMaxSize = 10 << 20
+)
+
+// the points of pipeline where the frames are captured.
+const (
+	captureIngress = "ingress"
+	captureEgress  = "egress"
+)
+
+// CaptureConfig represents the config of payload capture, the sampled frames are kept in memory for the admin API,
+// and written to the capture file if it's set. It's for debugging, the payloads may contain sensitive data.
+type CaptureConfig struct {
+	// Sampling decides which frames are captured, all frames are captured if it's empty.
+	Sampling `yaml:",inline"`
+	// Tags are the data tags which are captured, all tags are captured if it's empty.
+	Tags []byte `yaml:"tags,omitempty"`
+	// Count is the count of the latest frames kept in memory for each data tag, the default is 10.
+	Count int `yaml:"count,omitempty"`
+	// MaxPayload is the size in bytes of the payload kept for each frame, the rest is truncated, the default is 256.
+	MaxPayload int `yaml:"max_payload,omitempty"`
+	// File is the path of capture file, the frames are appended to it as JSON lines.
+	File string `yaml:"file,omitempty"`
+	// MaxSize is the size in bytes of the capture file before it's rotated to File.1, the default is 10MB.
+	MaxSize int64 `yaml:"max_size,omitempty"`
+}
+
+// CapturedFrame is the headers and the truncated payload of a captured frame.
+type CapturedFrame struct {
+	Time          time.Time `json:"time"`
+	Point         string    `json:"point"`
+	TransactionID string    `json:"tid"`
+	Tag           int       `json:"tag"`
+	ExtraTags     []int     `json:"extra_tags,omitempty"`
+	ContentType   string    `json:"content_type,omitempty"`
+	SchemaID      string    `json:"schema_id,omitempty"`
+	Hops          uint32    `json:"hops"`
+	KeyID         string    `json:"key_id,omitempty"`
+	Streamed      bool      `json:"streamed,omitempty"`
+	Size          int       `json:"size"`
+	Payload       []byte    `json:"payload,omitempty"`
+	Truncated     bool      `json:"truncated,omitempty"`
+}
+
+// capturer keeps the latest sampled frames of each data tag.
+type capturer struct {
+	conf    *CaptureConfig
+	sampler *sampler
+	tags    map[byte]bool
+	mutex   sync.Mutex
+	frames  map[byte][]CapturedFrame
+	file    *os.File
+	size    int64
+}
+
+func newCapturer(conf *CaptureConfig) (*capturer, error) {
+	c := &capturer{
+		conf:    conf,
+		sampler: newSampler(conf.Sampling),
+		frames:  make(map[byte][]CapturedFrame),
+	}
+	if len(conf.Tags) > 0 {
+		c.tags = make(map[byte]bool)
+		for _, tag := range conf.Tags {
+			c.tags[tag] = true
+		}
+	}
+	if conf.File != "" {
+		if err := c.open(); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// capture keeps the frame at the point of pipeline if it's sampled.
+func (c *capturer) capture(point string, data *frame.DataFrame) {
+	if c == nil {
+		return
+	}
+	tag := data.GetDataTagID()
+	if c.tags != nil && !c.tags[tag] {
+		return
+	}
+	if !c.sampler.sample() {
+		return
+	}
+
+	f := CapturedFrame{
+		Time:          time.Now(),
+		Point:         point,
+		TransactionID: data.TransactionID(),
+		Tag:           int(tag),
+		ContentType:   data.ContentType(),
+		SchemaID:      data.SchemaID(),
+		Hops:          data.Hops(),
+		KeyID:         data.KeyID(),
+		Streamed:      data.Streamed(),
+	}
+	for _, t := range data.ExtraTags() {
+		f.ExtraTags = append(f.ExtraTags, int(t))
+	}
+	// the streamed carriage is read once by the stream function, so it isn't captured.
+	if !f.Streamed {
+		payload := data.GetCarriage()
+		f.Size = len(payload)
+		maxPayload := c.conf.MaxPayload
+		if maxPayload <= 0 {
+			maxPayload = DefaultCapturePayload
+		}
+		if len(payload) > maxPayload {
+			payload, f.Truncated = payload[:maxPayload], true
+		}
+		f.Payload = append([]byte(nil), payload...)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	count := c.conf.Count
+	if count <= 0 {
+		count = DefaultCaptureCount
+	}
+	frames := append(c.frames[tag], f)
+	if len(frames) > count {
+		frames = frames[len(frames)-count:]
+	}
+	c.frames[tag] = frames
+
+	if c.file != nil {
+		c.write(f)
+	}
+}
+
+// tap captures the frames which come into the pipeline.
+func (c *capturer) tap(ctx context.Context, upstream chan *frame.DataFrame) chan *frame.DataFrame {
+	next := make(chan *frame.DataFrame, bufferSize)
+
+	go func() {
+		defer close(next)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case item, ok := <-upstream:
+				if !ok {
+					return
+				}
+				c.capture(captureIngress, item)
+				next <- item
+			}
+		}
+	}()
+
+	return next
+}
+
+// list returns the captured frames of the tag, or of all tags if tag is negative, in time order.
+func (c *capturer) list(tag int) []CapturedFrame {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	result := make([]CapturedFrame, 0)
+	for t, frames := range c.frames {
+		if tag < 0 || int(t) == tag {
+			result = append(result, frames...)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Time.Before(result[j].Time)
+	})
+	return result
+}
+
+// write appends the frame to the capture file, it's rotated when it's full. The mutex is held.
+func (c *capturer) write(f CapturedFrame) {
+	buf, err := json.Marshal(f)
+	if err != nil {
+		return
+	}
+	buf = append(buf, '\n')
+
+	maxSize := c.conf.MaxSize
+	if maxSize <= 0 {
+		maxSize = DefaultCaptureMaxSize
+	}
+	if c.size > 0 && c.size+int64(len(buf)) > maxSize {
+		c.file.Close()
+		c.file = nil
+		if err := os.Rename(c.conf.File, c.conf.File+".1"); err != nil {
+			logger.Error("[zipper] rotate the capture file failed.", "file", c.conf.File, "err", err)
+		}
+		if err := c.open(); err != nil {
+			logger.Error("[zipper] open the capture file failed, the frames are not written to it.", "file", c.conf.File, "err", err)
+			return
+		}
+	}
+
+	n, err := c.file.Write(buf)
+	c.size += int64(n)
+	if err != nil {
+		logger.Error("[zipper] write the capture file failed.", "file", c.conf.File, "err", err)
+	}
+}
+
+// open opens the capture file for appending.
+func (c *capturer) open() error {
+	file, err := os.OpenFile(c.conf.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	c.file, c.size = file, info.Size()
+	return nil
+}
+
+// Close closes the capture file.
+func (c *capturer) Close() error {
+	if c == nil {
+		return nil
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.file == nil {
+		return nil
+	}
+	err := c.file.Close()
+	c.file = nil
+	return err
+}
+
+// ServeHTTP is the admin API of payload capture.
+// GET /captures lists the captured frames, they're filtered by the query of tag, e.g. "?tag=0x33".
+// DELETE /captures clears the captured frames.
+func (c *capturer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		tag := -1
+		if v := r.URL.Query().Get("tag"); v != "" {
+			t, err := strconv.ParseUint(v, 0, 8)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid tag " + v})
+				return
+			}
+			tag = int(t)
+		}
+		writeJSON(w, http.StatusOK, c.list(tag))
+	case http.MethodDelete:
+		c.mutex.Lock()
+		c.frames = make(map[byte][]CapturedFrame)
+		c.mutex.Unlock()
+		writeJSON(w, http.StatusOK, map[string]string{"status": "cleared"})
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
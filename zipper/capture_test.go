@@ -0,0 +1,83 @@
+package zipper
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/internal/frame"
+)
+
+func TestCapturer(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "capture.log")
+	c, err := newCapturer(&CaptureConfig{Tags: []byte{0x33}, Count: 2, MaxPayload: 4, File: file})
+	assert.NoError(t, err)
+	defer c.Close()
+
+	for _, tid := range []string{"1", "2", "3"} {
+		data := frame.NewDataFrame(tid)
+		data.SetCarriage(0x33, []byte("payload"))
+		c.capture(captureIngress, data)
+	}
+	// the tag which isn't captured.
+	data := frame.NewDataFrame("4")
+	data.SetCarriage(0x10, []byte("yomo"))
+	c.capture(captureIngress, data)
+
+	frames := c.list(-1)
+	assert.Len(t, frames, 2)
+	assert.Equal(t, "2", frames[0].TransactionID)
+	assert.Equal(t, "3", frames[1].TransactionID)
+	assert.Equal(t, []byte("payl"), frames[1].Payload)
+	assert.Equal(t, 7, frames[1].Size)
+	assert.True(t, frames[1].Truncated)
+	assert.Empty(t, c.list(0x10))
+
+	// all the captured frames are written to the file.
+	assert.Len(t, readCaptures(t, file), 3)
+}
+
+func readCaptures(t *testing.T, file string) []CapturedFrame {
+	frames := make([]CapturedFrame, 0)
+	for _, line := range readLines(t, file) {
+		var f CapturedFrame
+		assert.NoError(t, json.Unmarshal(line, &f))
+		frames = append(frames, f)
+	}
+	return frames
+}
+
+func TestCapturerAPI(t *testing.T) {
+	c, err := newCapturer(&CaptureConfig{})
+	assert.NoError(t, err)
+	data := frame.NewDataFrame("tid")
+	data.SetCarriage(0x33, []byte("yomo"))
+	c.capture(captureEgress, data)
+
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	var frames []CapturedFrame
+	res, err := http.Get(server.URL + "?tag=0x33")
+	assert.NoError(t, err)
+	assert.NoError(t, json.NewDecoder(res.Body).Decode(&frames))
+	res.Body.Close()
+	assert.Len(t, frames, 1)
+	assert.Equal(t, captureEgress, frames[0].Point)
+	assert.Equal(t, []byte("yomo"), frames[0].Payload)
+
+	res, err = http.Get(server.URL + "?tag=x")
+	assert.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	req, _ := http.NewRequest(http.MethodDelete, server.URL, strings.NewReader(""))
+	res, err = http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	res.Body.Close()
+	assert.Empty(t, c.list(-1))
+}
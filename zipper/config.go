@@ -2,8 +2,13 @@ package zipper
 
 import (
 	"errors"
+	"net"
 	"os"
 	"strings"
+	"time"
+
+	"github.com/yomorun/yomo/internal/core"
+	"github.com/yomorun/yomo/logger"
 
 	"gopkg.in/yaml.v2"
 )
@@ -11,19 +16,257 @@ import (
 // App represents a YoMo Application.
 type App struct {
 	Name string `yaml:"name"`
+	// Shadows are the names of stream functions which receive a copy of the data of this function,
+	// but their responses are discarded. It's used to test the canary version of a function with the production traffic.
+	Shadows []string `yaml:"shadows,omitempty"`
+	// Sampling makes this function process only a subset of frames, the rest pass through untouched.
+	Sampling *Sampling `yaml:"sampling,omitempty"`
+	// Tags are the data tags this function subscribes to, the frames with other tags pass through untouched.
+	// All frames are sent to this function if it's empty.
+	Tags []byte `yaml:"tags,omitempty"`
+	// Identities are the identities in client certificates which are allowed to connect as this app,
+	// they're matched against the common name, the DNS names and the URIs. Any client is allowed if it's empty.
+	Identities []string `yaml:"identities,omitempty"`
+	// Weight is the share of a source when the frames from many sources are merged into the pipeline,
+	// the sources are scheduled by weighted round-robin if any source has weight, the default weight is 1.
+	Weight int `yaml:"weight,omitempty"`
+	// Bandwidth is the max bytes per second received from this source, it overrides the source bandwidth
+	// of the workflow config.
+	Bandwidth int `yaml:"bandwidth,omitempty"`
+	// LoadBalance is the strategy of choosing an instance when this function has many instances,
+	// the default strategy is round-robin.
+	LoadBalance LoadBalance `yaml:"load_balance,omitempty"`
+	// MinInstances is the count of connected instances of this function below which YoMo-Zipper isn't ready,
+	// the default is 0, so the functions which connect through the readiness-gated service aren't deadlocked.
+	MinInstances int `yaml:"min_instances,omitempty"`
+	// Invoke backs this function by the serverless function, e.g. AWS Lambda or Knative, YoMo-Zipper invokes it
+	// with the data instead of sending the data to the connected instances.
+	Invoke *InvokeConfig `yaml:"invoke,omitempty"`
+	// Wasm runs this function as the WebAssembly module in the process of YoMo-Zipper instead of sending the data
+	// to the connected instances.
+	Wasm *WasmConfig `yaml:"wasm,omitempty"`
+}
+
+// accepts indicates if the app subscribes to the data tag.
+func (app App) accepts(tag byte) bool {
+	if len(app.Tags) == 0 {
+		return true
+	}
+	for _, t := range app.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
 }
 
 // Workflow represents a YoMo Workflow.
 type Workflow struct {
+	// Sources are the YoMo-Sources which are allowed to connect, all sources are allowed if it's empty.
+	Sources   []App `yaml:"sources,omitempty"`
 	Functions []App `yaml:"functions"`
 }
 
+// shadowOf returns the function which is mirrored by the shadow function.
+func (w Workflow) shadowOf(name string) (App, bool) {
+	for _, app := range w.Functions {
+		for _, shadow := range app.Shadows {
+			if shadow == name {
+				return app, true
+			}
+		}
+	}
+	return App{}, false
+}
+
 // WorkflowConfig represents a YoMo Workflow config.
 type WorkflowConfig struct {
 	Name     string `yaml:"name"`
 	Host     string `yaml:"host"`
 	Port     int    `yaml:"port"`
 	Workflow `yaml:",inline"`
+	// Listen are the other addresses which YoMo-Zipper listens on besides host and port, e.g. "[::1]:9000",
+	// the sessions accepted on all addresses are merged into the same workflow.
+	Listen []string `yaml:"listen,omitempty"`
+	// Shedding is the load shedding policy when the zipper is overloaded.
+	Shedding SheddingConfig `yaml:"shedding,omitempty"`
+	// SLI sends synthetic probe frames through the pipeline and exports the availability and latency.
+	SLI *SLIConfig `yaml:"sli,omitempty"`
+	// TLS enables the mutual TLS authentication between YoMo-Zipper and the clients.
+	TLS *TLSConfig `yaml:"tls,omitempty"`
+	// Auth authenticates the clients by the tokens in the handshake, e.g. the static secrets or the JWTs, all
+	// clients are accepted if it's nil.
+	Auth *AuthConfig `yaml:"auth,omitempty"`
+	// Authz authorizes the clients by the policies, e.g. which sources may emit which tags, all clients are
+	// authorized if it's nil.
+	Authz *AuthzConfig `yaml:"authz,omitempty"`
+	// Namespaces are the isolated workflows hosted besides the default workflow, e.g. of the tenants of a shared
+	// deployment, the clients are bound to them by their identities authenticated by the auth.
+	Namespaces []Namespace `yaml:"namespaces,omitempty"`
+	// Quotas limit the connections, the frames and the bytes of the tenants, the first quota which applies to a
+	// client limits it, and the clients which no quota applies to are unlimited.
+	Quotas []Quota `yaml:"quotas,omitempty"`
+	// MeshToken is the token which this YoMo-Zipper presents to the downstream YoMo-Zippers of edge-mesh.
+	MeshToken string `yaml:"mesh_token,omitempty"`
+	// Features are the flags of experimental features, they can be changed at runtime by the admin API.
+	Features []FeatureFlag `yaml:"features,omitempty"`
+	// Admin is the address of admin API, e.g. "localhost:9001", the admin API is disabled if it's empty.
+	Admin string `yaml:"admin,omitempty"`
+	// AdminToken is the bearer token required by all the endpoints of admin API, it's required if Admin is set.
+	AdminToken string `yaml:"admin_token,omitempty"`
+	// Audit records the connections, the authorizations and the administrative actions.
+	Audit *AuditConfig `yaml:"audit,omitempty"`
+	// Capture keeps the sampled frames for debugging, they're listed by the admin API or written to a file.
+	Capture *CaptureConfig `yaml:"capture,omitempty"`
+	// SlowConsumer is the thresholds of detecting the slow stream functions.
+	SlowConsumer SlowConsumerConfig `yaml:"slow_consumer,omitempty"`
+	// Diagnostics serves the profiles of pprof, the goroutine dumps and the depth of queues at /debug of admin API,
+	// it's disabled by default since the profiles expose the internals of process.
+	Diagnostics bool `yaml:"diagnostics,omitempty"`
+	// Dashboard serves the built-in web UI of the feed of data flow at /dashboard of admin API.
+	Dashboard bool `yaml:"dashboard,omitempty"`
+	// Metrics is the address of the Prometheus metrics endpoint, e.g. "localhost:9090", the metrics are served
+	// at /metrics without authentication, and they're disabled if it's empty.
+	Metrics string `yaml:"metrics,omitempty"`
+	// LogSampling samples the repeated log messages of the process, e.g. the errors of a broken client,
+	// all messages are logged if it's nil.
+	LogSampling *logger.SamplingConfig `yaml:"log_sampling,omitempty"`
+	// Timeline keeps the steps of the recent transactions through the pipeline for the admin API,
+	// it's disabled if it's nil.
+	Timeline *TimelineConfig `yaml:"timeline,omitempty"`
+	// JetStream buffers the frames of sources in NATS JetStream durably, they're dispatched from the stream,
+	// so they survive the restarts of YoMo-Zipper and they can be replayed. It's disabled if it's nil.
+	JetStream *JetStreamConfig `yaml:"jetstream,omitempty"`
+	// Health is the address of the health checks for Kubernetes probes, e.g. "0.0.0.0:8081", they're served at
+	// /healthz and /readyz without authentication, and they're disabled if it's empty.
+	Health string `yaml:"health,omitempty"`
+	// WebTransport is the address of WebTransport endpoint, e.g. "0.0.0.0:9443", so the browsers can act as
+	// sources and stream functions. The endpoint is disabled if it's empty, and it requires the certificate of TLS.
+	WebTransport string `yaml:"webtransport,omitempty"`
+	// WebTransportPath is the path of WebTransport endpoint, the default is "/".
+	WebTransportPath string `yaml:"webtransport_path,omitempty"`
+	// WebTransportOrigins are the origins of web pages allowed to connect the WebTransport endpoint,
+	// e.g. "https://app.example.com", only the same origin as the endpoint is allowed if it's empty.
+	WebTransportOrigins []string `yaml:"webtransport_origins,omitempty"`
+	// TagRemap renumbers the data tags at ingress, egress and for each downstream YoMo-Zipper.
+	TagRemap TagRemapConfig `yaml:"tag_remap,omitempty"`
+	// KeepAlive tunes the keep-alive for each class of connections.
+	KeepAlive KeepAliveConfig `yaml:"keepalive,omitempty"`
+	// SourceBandwidth is the max bytes per second received from each source session, the reads of the session
+	// are paused after it's exceeded, so the QUIC flow control slows down the source. It's unlimited if zero.
+	SourceBandwidth int `yaml:"source_bandwidth,omitempty"`
+	// Qlog is the directory where the qlog traces of QUIC connections are written, one file for each connection.
+	// The connections are not traced if it's empty.
+	Qlog string `yaml:"qlog,omitempty"`
+	// FlowControl tunes the flow control windows of the QUIC connections, the defaults are used if it's empty.
+	FlowControl FlowControl `yaml:"flow_control,omitempty"`
+	// MaxFrameSize is the max size in bytes of a DataFrame received at once or reassembled from the chunks,
+	// the larger frames are dropped. The default is 64MB if it's zero.
+	MaxFrameSize int `yaml:"max_frame_size,omitempty"`
+	// ResetOnCorruption resets the stream when a frame fails the checksum, otherwise the corrupted frame is skipped.
+	ResetOnCorruption bool `yaml:"reset_on_corruption,omitempty"`
+	// MaxHops is the max count of YoMo-Zippers which a DataFrame passes through, the frames exceeding it are
+	// dropped as they're looping in the cascaded meshes. The default is 16 if it's zero.
+	MaxHops int `yaml:"max_hops,omitempty"`
+	// ChunkSize is the max size in bytes of each chunk of the frames which are sent to the stream functions and
+	// the downstream YoMo-Zippers, the larger frames are split into chunks. The default is 1MB if it's zero.
+	ChunkSize int `yaml:"chunk_size,omitempty"`
+	// Reload reloads the workflow from the config file when it's modified or on SIGHUP, e.g. the order and the tags
+	// of stages, the changes of the other fields are logged as they require restart. The workflow can still be
+	// reloaded by the admin API if it's nil.
+	Reload *ReloadConfig `yaml:"reload,omitempty"`
+
+	path string // the path of config file, it's empty if the config isn't loaded by Load.
+}
+
+// ReloadConfig is how the workflow is reloaded from the config file at runtime.
+type ReloadConfig struct {
+	// WatchInterval is the interval of checking the config file, the workflow is reloaded after the file is
+	// modified. The file is not watched if it's zero.
+	WatchInterval time.Duration `yaml:"watch_interval,omitempty"`
+	// OnSIGHUP reloads the workflow when the process receives SIGHUP, it's opt-in because the signal handler is
+	// process-wide.
+	OnSIGHUP bool `yaml:"on_sighup,omitempty"`
+}
+
+// FlowControl represents the flow control windows in bytes, the receive windows start at the initial sizes
+// and are auto-tuned up to the max sizes. The large windows are needed by the pipelines of raw-binary payloads.
+type FlowControl struct {
+	InitialStreamWindow     uint64 `yaml:"initial_stream_window,omitempty"`
+	MaxStreamWindow         uint64 `yaml:"max_stream_window,omitempty"`
+	InitialConnectionWindow uint64 `yaml:"initial_connection_window,omitempty"`
+	MaxConnectionWindow     uint64 `yaml:"max_connection_window,omitempty"`
+}
+
+// KeepAlive represents the keep-alive of a class of connections.
+type KeepAlive struct {
+	// Interval is the interval of sending Ping, it only applies to the zipper-to-zipper links which YoMo-Zipper
+	// dials, the clients choose their own interval by WithKeepAlive.
+	Interval time.Duration `yaml:"interval,omitempty"`
+	// IdleTimeout is the timeout when no Ping is received, the connection is closed after it.
+	IdleTimeout time.Duration `yaml:"idle_timeout,omitempty"`
+}
+
+// KeepAliveConfig represents the keep-alive for sources, stream functions and zipper-to-zipper links,
+// since the IoT sources need aggressive keep-alives while the server links don't. The defaults are used if it's empty.
+//
+// The QUIC idle timeout of the listener is shared by all the accepted connections, so the idle timeout of
+// sources and stream functions is enforced by the heartbeat of the application, i.e. the connection is closed
+// if no Ping frame is received within it. The QUIC connection itself is kept alive by the idle timeout which
+// the client sets, since the smaller one of both ends is used.
+type KeepAliveConfig struct {
+	Source         KeepAlive `yaml:"source,omitempty"`
+	StreamFunction KeepAlive `yaml:"stream_function,omitempty"`
+	Zipper         KeepAlive `yaml:"zipper,omitempty"`
+}
+
+// bandwidthOf returns the max bytes per second received from the source, it's unlimited if zero.
+func (c *WorkflowConfig) bandwidthOf(name string) int {
+	for _, app := range c.Sources {
+		if app.Name == name && app.Bandwidth > 0 {
+			return app.Bandwidth
+		}
+	}
+	return c.SourceBandwidth
+}
+
+// chunkSize returns the max size of each chunk of the frames sent by YoMo-Zipper.
+func (c *WorkflowConfig) chunkSize() int {
+	if c.ChunkSize <= 0 {
+		return core.DefaultChunkSize
+	}
+	return c.ChunkSize
+}
+
+// of returns the keep-alive of the connection type.
+func (c KeepAliveConfig) of(connType core.ConnectionType) KeepAlive {
+	switch connType {
+	case core.ConnTypeSource:
+		return c.Source
+	case core.ConnTypeStreamFunction:
+		return c.StreamFunction
+	case core.ConnTypeUpstreamZipper:
+		return c.Zipper
+	default:
+		return KeepAlive{}
+	}
+}
+
+// TLSConfig represents the certificates of YoMo-Zipper.
+type TLSConfig struct {
+	// CertFile is the certificate of YoMo-Zipper, it's also presented as client certificate by probes and edge-mesh senders.
+	CertFile string `yaml:"cert"`
+	// KeyFile is the private key of the certificate.
+	KeyFile string `yaml:"key"`
+	// CAFile is the CA which signs the client certificates, the client certificates are not required if it's empty.
+	CAFile string `yaml:"ca,omitempty"`
+	// WatchInterval is the interval of checking the certificate files, the certificate is reloaded after the files
+	// are modified, e.g. renewed by certbot. The files are not watched if it's zero, the certificate can still be
+	// reloaded by the admin API.
+	WatchInterval time.Duration `yaml:"watch_interval,omitempty"`
+	// ReloadOnSIGHUP reloads the certificate when the process receives SIGHUP, it's opt-in because the signal
+	// handler is process-wide, e.g. the application embedding YoMo-Zipper may handle SIGHUP itself.
+	ReloadOnSIGHUP bool `yaml:"reload_on_sighup,omitempty"`
 }
 
 // Load the WorkflowConfig by path.
@@ -47,12 +290,18 @@ func Load(path string) (*WorkflowConfig, error) {
 		return nil, err
 	}
 
-	return load(buffer)
+	config, err := load(buffer)
+	if err != nil {
+		return nil, err
+	}
+	config.path = path
+	return config, nil
 }
 
+// load parses the config strictly, so the misspelled fields are reported with their lines instead of being ignored.
 func load(data []byte) (*WorkflowConfig, error) {
 	var config = &WorkflowConfig{}
-	err := yaml.Unmarshal(data, config)
+	err := yaml.UnmarshalStrict(data, config)
 	if err != nil {
 		return nil, err
 	}
@@ -72,7 +321,7 @@ func ParseConfig(config string) (*WorkflowConfig, error) {
 	}
 
 	// validate
-	err = validateConfig(wfConf)
+	err = Validate(wfConf)
 	if err != nil {
 		return nil, err
 	}
@@ -80,7 +329,8 @@ func ParseConfig(config string) (*WorkflowConfig, error) {
 	return wfConf, nil
 }
 
-func validateConfig(wfConf *WorkflowConfig) error {
+// Validate the workflow config.
+func Validate(wfConf *WorkflowConfig) error {
 	if wfConf == nil {
 		return errors.New("conf is nil")
 	}
@@ -107,9 +357,205 @@ func validateConfig(wfConf *WorkflowConfig) error {
 		errMsg += "Missing name, host or port in " + strings.Join(missingParams, ", "+". ")
 	}
 
+	errMsg += duplicatedApps("function", wfConf.Functions) + duplicatedApps("source", wfConf.Sources)
+
+	for _, app := range wfConf.Functions {
+		for _, shadow := range app.Shadows {
+			if shadow == "" || shadow == app.Name {
+				errMsg += "The shadow of function " + app.Name + " must have a different name. "
+			}
+		}
+	}
+
+	for _, app := range wfConf.Functions {
+		if app.Sampling == nil {
+			continue
+		}
+		if app.Sampling.Rate < 0 || app.Sampling.Rate > 1 {
+			errMsg += "The sampling rate of function " + app.Name + " must be in the range (0, 1]. "
+		}
+		if app.Sampling.Rate > 0 && app.Sampling.Every > 0 {
+			errMsg += "The sampling of function " + app.Name + " can't have both rate and every. "
+		}
+	}
+
+	if wfConf.SLI != nil {
+		for _, probe := range wfConf.SLI.Probes {
+			if probe.Name == "" {
+				errMsg += "Missing name in probes. "
+			}
+		}
+	}
+
+	if wfConf.TLS != nil && (wfConf.TLS.CertFile == "" || wfConf.TLS.KeyFile == "") {
+		errMsg += "Missing cert or key in tls. "
+	}
+	if wfConf.Auth != nil {
+		errMsg += wfConf.Auth.validate()
+	}
+	if wfConf.Authz != nil {
+		errMsg += wfConf.Authz.validate()
+	}
+	errMsg += validateNamespaces(wfConf)
+	errMsg += validateQuotas(wfConf)
+
+	for _, app := range wfConf.Functions {
+		if !app.LoadBalance.valid() {
+			errMsg += "The load balance of function " + app.Name + " must be round_robin or latency. "
+		}
+		if app.Invoke != nil {
+			errMsg += app.Invoke.validate(app.Name)
+		}
+		if app.Wasm != nil {
+			errMsg += app.Wasm.validate(app.Name)
+			if app.Invoke != nil {
+				errMsg += "The function " + app.Name + " can't be both invoked and run as WASM. "
+			}
+		}
+	}
+
+	for _, addr := range wfConf.Listen {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			errMsg += "The listen address " + addr + " must be in the form of host:port. "
+		}
+	}
+
+	fc := wfConf.FlowControl
+	if fc.MaxStreamWindow > 0 && fc.InitialStreamWindow > fc.MaxStreamWindow {
+		errMsg += "The initial stream window must not be greater than the max stream window. "
+	}
+	if fc.MaxConnectionWindow > 0 && fc.InitialConnectionWindow > fc.MaxConnectionWindow {
+		errMsg += "The initial connection window must not be greater than the max connection window. "
+	}
+
+	if wfConf.SourceBandwidth < 0 {
+		errMsg += "The source bandwidth must not be negative. "
+	}
+	if wfConf.MaxFrameSize < 0 {
+		errMsg += "The max frame size must not be negative. "
+	}
+	if wfConf.Admin != "" && wfConf.AdminToken == "" {
+		errMsg += "The admin token is required by the admin API. "
+	}
+	if audit := wfConf.Audit; audit != nil {
+		if audit.File == "" && audit.URL == "" {
+			errMsg += "The audit log requires a file or a URL. "
+		}
+		if audit.MaxSize < 0 || audit.MaxAge < 0 || audit.MaxBackups < 0 {
+			errMsg += "The retention of audit log must not be negative. "
+		}
+	}
+	if capture := wfConf.Capture; capture != nil {
+		if capture.Count < 0 || capture.MaxPayload < 0 || capture.MaxSize < 0 {
+			errMsg += "The limits of capture must not be negative. "
+		}
+		if capture.Rate < 0 || capture.Rate > 1 {
+			errMsg += "The capture rate must be in the range [0, 1]. "
+		}
+	}
+	if sampling := wfConf.LogSampling; sampling != nil && (sampling.Interval < 0 || sampling.First < 0) {
+		errMsg += "The log sampling must not be negative. "
+	}
+	if timeline := wfConf.Timeline; timeline != nil && (timeline.Size < 0 || timeline.MaxSteps < 0) {
+		errMsg += "The limits of timeline must not be negative. "
+	}
+	if js := wfConf.JetStream; js != nil {
+		if js.URL == "" {
+			errMsg += "The URL of NATS server is required by JetStream. "
+		}
+		if js.MaxAge < 0 || js.MaxBytes < 0 || js.AckWait < 0 || js.Batch < 0 {
+			errMsg += "The limits of JetStream must not be negative. "
+		}
+		if strings.ContainsAny(js.Stream+js.Durable, ".*> ") {
+			errMsg += "The names of JetStream stream and consumer must not contain '.', '*', '>' or spaces. "
+		}
+	}
+	if slow := wfConf.SlowConsumer; slow.Latency < 0 || slow.Backlog < 0 || slow.Interval < 0 {
+		errMsg += "The thresholds of slow consumer must not be negative. "
+	}
+	if wfConf.Dashboard && wfConf.Admin == "" {
+		errMsg += "The dashboard requires the admin API. "
+	}
+	if wfConf.Diagnostics && wfConf.Admin == "" {
+		errMsg += "The diagnostics require the admin API. "
+	}
+	if wfConf.ChunkSize < 0 {
+		errMsg += "The chunk size must not be negative. "
+	}
+	if wfConf.MaxHops < 0 {
+		errMsg += "The max hops must not be negative. "
+	}
+	if wfConf.Reload != nil && wfConf.Reload.WatchInterval < 0 {
+		errMsg += "The watch interval of reload must not be negative. "
+	}
+
+	for _, app := range wfConf.Functions {
+		if app.MinInstances < 0 {
+			errMsg += "The min instances of stream function " + app.Name + " must not be negative. "
+		}
+	}
+
+	for _, app := range wfConf.Sources {
+		if app.Weight < 0 {
+			errMsg += "The weight of source " + app.Name + " must not be negative. "
+		}
+		if app.Weight > 0 && wfConf.JetStream != nil {
+			errMsg += "The weight of source " + app.Name + " can't be used with JetStream, the frames are merged in the stream. "
+		}
+		if app.Bandwidth < 0 {
+			errMsg += "The bandwidth of source " + app.Name + " must not be negative. "
+		}
+	}
+
+	for _, flag := range wfConf.Features {
+		if flag.Name == "" || flag.Percentage < 0 || flag.Percentage > 100 {
+			errMsg += "The feature flag must have a name and a percentage in the range [0, 100]. "
+		}
+	}
+
+	remaps := []TagRemap{wfConf.TagRemap.Ingress, wfConf.TagRemap.Egress}
+	for _, remap := range wfConf.TagRemap.Downstreams {
+		remaps = append(remaps, remap)
+	}
+	for _, remap := range remaps {
+		if err := remap.validate(); err != nil {
+			errMsg += "Invalid tag remapping: " + err.Error() + ". "
+			break
+		}
+	}
+
+	for _, keepAlive := range []KeepAlive{wfConf.KeepAlive.Source, wfConf.KeepAlive.StreamFunction, wfConf.KeepAlive.Zipper} {
+		if keepAlive.Interval < 0 || keepAlive.IdleTimeout < 0 || (keepAlive.IdleTimeout > 0 && keepAlive.Interval >= keepAlive.IdleTimeout) {
+			errMsg += "The keepalive interval must be less than the idle timeout. "
+			break
+		}
+	}
+	if wfConf.KeepAlive.Source.Interval != 0 || wfConf.KeepAlive.StreamFunction.Interval != 0 {
+		errMsg += "The keepalive interval only applies to zipper, the clients set it by WithKeepAlive. "
+	}
+
+	switch wfConf.Shedding.Policy {
+	case "", SheddingPolicyBlock, SheddingPolicyDrop, SheddingPolicyPriority:
+	default:
+		errMsg += "Unknown shedding policy " + wfConf.Shedding.Policy + ". "
+	}
+
 	if errMsg != "" {
 		return errors.New(errMsg)
 	}
 
 	return nil
 }
+
+// duplicatedApps returns the problems of the apps which are declared more than once in the message of Validate.
+func duplicatedApps(kind string, apps []App) string {
+	errMsg := ""
+	declared := make(map[string]bool, len(apps))
+	for _, app := range apps {
+		if declared[app.Name] && app.Name != "" {
+			errMsg += "The " + kind + " " + app.Name + " is declared more than once. "
+		}
+		declared[app.Name] = true
+	}
+	return errMsg
+}
@@ -3,8 +3,11 @@ package zipper
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/internal/core"
+	"github.com/yomorun/yomo/logger"
 )
 
 func TestParseConfig(t *testing.T) {
@@ -20,3 +23,201 @@ func TestParseConfig(t *testing.T) {
 		assert.Equal(t, expected, fn.Name)
 	}
 }
+
+func TestShadowOf(t *testing.T) {
+	app, ok := testConfig.shadowOf("func1-canary")
+	assert.True(t, ok)
+	assert.Equal(t, "func1", app.Name)
+
+	_, ok = testConfig.shadowOf("func2")
+	assert.False(t, ok)
+}
+
+func TestValidateShadowName(t *testing.T) {
+	conf := &WorkflowConfig{Name: "test", Host: "localhost", Port: 9000}
+	conf.Functions = []App{{Name: "func1", Shadows: []string{"func1"}}}
+	assert.Error(t, Validate(conf))
+}
+
+func TestAppAccepts(t *testing.T) {
+	assert.True(t, App{Name: "func1"}.accepts(0x10))
+	assert.True(t, App{Name: "func1", Tags: []byte{0x10}}.accepts(0x10))
+	assert.False(t, App{Name: "func1", Tags: []byte{0x10}}.accepts(0x11))
+}
+
+func TestKeepAliveConfig(t *testing.T) {
+	conf, err := load([]byte(`
+name: test
+host: localhost
+port: 9000
+functions:
+  - name: func1
+keepalive:
+  source:
+    idle_timeout: 5s
+  zipper:
+    interval: 30s
+    idle_timeout: 2m
+`))
+	assert.NoError(t, err)
+	assert.NoError(t, Validate(conf))
+	assert.Equal(t, KeepAlive{IdleTimeout: 5 * time.Second}, conf.KeepAlive.of(core.ConnTypeSource))
+	assert.Equal(t, KeepAlive{}, conf.KeepAlive.of(core.ConnTypeStreamFunction))
+	assert.Equal(t, KeepAlive{Interval: 30 * time.Second, IdleTimeout: 2 * time.Minute}, conf.KeepAlive.of(core.ConnTypeUpstreamZipper))
+
+	conf.KeepAlive.Zipper.Interval = 3 * time.Minute
+	assert.Error(t, Validate(conf))
+
+	// the interval of the accepted connections is chosen by the clients.
+	conf.KeepAlive.Zipper.Interval = 30 * time.Second
+	conf.KeepAlive.Source.Interval = time.Second
+	assert.Error(t, Validate(conf))
+}
+
+func TestValidateLoadBalance(t *testing.T) {
+	conf := &WorkflowConfig{Name: "test", Host: "localhost", Port: 9000}
+	conf.Functions = []App{{Name: "func1", LoadBalance: LoadBalanceLatency}}
+	assert.NoError(t, Validate(conf))
+
+	conf.Functions = []App{{Name: "func1", LoadBalance: "random"}}
+	assert.Error(t, Validate(conf))
+}
+
+func TestFlowControlConfig(t *testing.T) {
+	conf, err := load([]byte(`
+name: test
+host: localhost
+port: 9000
+functions:
+  - name: func1
+flow_control:
+  initial_stream_window: 1048576
+  max_stream_window: 16777216
+`))
+	assert.NoError(t, err)
+	assert.NoError(t, Validate(conf))
+	assert.Equal(t, uint64(16777216), conf.FlowControl.MaxStreamWindow)
+
+	conf.FlowControl.InitialStreamWindow = 32 << 20
+	assert.Error(t, Validate(conf))
+}
+
+func TestValidateListen(t *testing.T) {
+	conf := &WorkflowConfig{Name: "test", Host: "0.0.0.0", Port: 9000}
+	conf.Listen = []string{"[::]:9000", "192.168.1.1:9000"}
+	assert.NoError(t, Validate(conf))
+
+	conf.Listen = []string{"192.168.1.1"}
+	assert.Error(t, Validate(conf))
+}
+
+func TestBandwidthOf(t *testing.T) {
+	conf := &WorkflowConfig{Name: "test", Host: "localhost", Port: 9000, SourceBandwidth: 1 << 20}
+	conf.Sources = []App{{Name: "camera", Bandwidth: 4 << 20}, {Name: "sensor"}}
+	assert.NoError(t, Validate(conf))
+	assert.Equal(t, 4<<20, conf.bandwidthOf("camera"))
+	assert.Equal(t, 1<<20, conf.bandwidthOf("sensor"))
+	assert.Equal(t, 1<<20, conf.bandwidthOf("other"))
+
+	conf.Sources[1].Bandwidth = -1
+	assert.Error(t, Validate(conf))
+}
+
+func TestValidateMaxFrameSize(t *testing.T) {
+	conf := &WorkflowConfig{Name: "test", Host: "localhost", Port: 9000, MaxFrameSize: 16 << 20}
+	assert.NoError(t, Validate(conf))
+
+	conf.MaxFrameSize = -1
+	assert.Error(t, Validate(conf))
+}
+
+func TestValidateMaxHops(t *testing.T) {
+	conf := &WorkflowConfig{Name: "test", Host: "localhost", Port: 9000, MaxHops: 4}
+	assert.NoError(t, Validate(conf))
+
+	conf.MaxHops = -1
+	assert.Error(t, Validate(conf))
+}
+
+func TestValidateChunkSize(t *testing.T) {
+	conf := &WorkflowConfig{Name: "test", Host: "localhost", Port: 9000}
+	assert.NoError(t, Validate(conf))
+	assert.Equal(t, core.DefaultChunkSize, conf.chunkSize())
+
+	conf.ChunkSize = 1024
+	assert.Equal(t, 1024, conf.chunkSize())
+
+	conf.ChunkSize = -1
+	assert.Error(t, Validate(conf))
+}
+
+func TestValidateDashboard(t *testing.T) {
+	conf := &WorkflowConfig{Name: "test", Host: "localhost", Port: 9000, Dashboard: true}
+	assert.Error(t, Validate(conf))
+
+	conf.Admin, conf.AdminToken = "localhost:9001", "secret"
+	assert.NoError(t, Validate(conf))
+}
+
+func TestValidateAudit(t *testing.T) {
+	conf := &WorkflowConfig{Name: "test", Host: "localhost", Port: 9000, Audit: &AuditConfig{}}
+	assert.Error(t, Validate(conf))
+
+	conf.Audit.File = "audit.log"
+	assert.NoError(t, Validate(conf))
+
+	conf.Audit.MaxBackups = -1
+	assert.Error(t, Validate(conf))
+}
+
+func TestValidateCapture(t *testing.T) {
+	conf := &WorkflowConfig{Name: "test", Host: "localhost", Port: 9000, Capture: &CaptureConfig{Sampling: Sampling{Rate: 0.1}}}
+	assert.NoError(t, Validate(conf))
+
+	conf.Capture.Rate = 2
+	assert.Error(t, Validate(conf))
+}
+
+func TestValidateMinInstances(t *testing.T) {
+	conf := &WorkflowConfig{Name: "test", Host: "localhost", Port: 9000, Workflow: Workflow{Functions: []App{{Name: "fn", MinInstances: 2}}}}
+	assert.NoError(t, Validate(conf))
+
+	conf.Functions[0].MinInstances = -1
+	assert.Error(t, Validate(conf))
+}
+
+func TestValidateTimeline(t *testing.T) {
+	conf := &WorkflowConfig{Name: "test", Host: "localhost", Port: 9000, Timeline: &TimelineConfig{Size: 100}}
+	assert.NoError(t, Validate(conf))
+
+	conf.Timeline.MaxSteps = -1
+	assert.Error(t, Validate(conf))
+}
+
+func TestValidateDiagnostics(t *testing.T) {
+	conf := &WorkflowConfig{Name: "test", Host: "localhost", Port: 9000, Diagnostics: true}
+	assert.Error(t, Validate(conf))
+
+	conf.Admin, conf.AdminToken = "localhost:9001", "secret"
+	assert.NoError(t, Validate(conf))
+}
+
+func TestValidateLogSampling(t *testing.T) {
+	conf := &WorkflowConfig{Name: "test", Host: "localhost", Port: 9000, LogSampling: &logger.SamplingConfig{First: 10}}
+	assert.NoError(t, Validate(conf))
+
+	conf.LogSampling.Interval = -time.Second
+	assert.Error(t, Validate(conf))
+}
+
+func TestValidateJetStream(t *testing.T) {
+	conf := &WorkflowConfig{Name: "test", Host: "localhost", Port: 9000, JetStream: &JetStreamConfig{URL: "nats://localhost:4222"}}
+	assert.NoError(t, Validate(conf))
+
+	conf.JetStream.Stream = "yomo.frames"
+	assert.Error(t, Validate(conf))
+
+	conf.JetStream.Stream = ""
+	conf.Sources = []App{{Name: "source", Weight: 2}}
+	assert.Error(t, Validate(conf))
+}
@@ -1,9 +1,15 @@
 package zipper
 
 import (
+	"context"
 	"errors"
+	"io"
+	"io/ioutil"
 	"net"
+	"sync/atomic"
+	"time"
 
+	"github.com/yomorun/yomo/core/compress"
 	"github.com/yomorun/yomo/core/quic"
 	"github.com/yomorun/yomo/internal/core"
 	"github.com/yomorun/yomo/internal/frame"
@@ -12,27 +18,75 @@ import (
 
 // Conn represents the YoMo Zipper connection.
 type Conn struct {
-	// Addr is the peer's address.
+	// Addr is the peer's address, it's the key of the connection.
 	Addr string
+	// RemoteAddr is the network address of the peer, it's Addr without the channel ID of multiplexed sessions.
+	RemoteAddr string
 	// Conn is a QUIC connection.
 	Conn *quic.Conn
 	// Session is a QUIC connection.
 	Session quic.Session
 	// onClosed is the callback when the connection is closed.
 	onClosed func()
+	// limiter is the *quic.RateLimiter which caps the bandwidth of the source, it's set in the handshake and read
+	// by the goroutines of data streams.
+	limiter atomic.Value
+	// onPartialFrame is the callback when a DataFrame is received in a partially reliable stream.
+	onPartialFrame func(*frame.DataFrame)
+	// onStreamedFrame is the callback when a DataFrame whose carriage is streamed is received, the carriage
+	// is read from the stream by the callback.
+	onStreamedFrame func(*frame.DataFrame)
+	// lastSequence is the sequence of the latest DataFrame received in partially reliable streams.
+	lastSequence uint64
+	// version is the version of wire protocol negotiated in the handshake.
+	version uint32
+	// prober authenticates the probe clients of this YoMo-Zipper, it's nil if the SLIs are not configured.
+	prober *prober
+	// departed is set to 1 when the client says goodbye or it's drained, the streams opened after it are ignored.
+	departed uint32
+	// ConnectedAt is the time when the connection is accepted.
+	ConnectedAt time.Time
+	// closed is set to 1 when the connection is closed, the event of leaving is emitted once.
+	closed uint32
+	// auditor records the authorization and the disconnection of the client, it's nil if the audit log is disabled.
+	auditor *auditor
+	// authenticator authenticates the client by its token in the handshake, it's nil if the clients aren't authenticated.
+	authenticator Authenticator
+	// principal is the identity of client authenticated by its token, it's nil if the client isn't authenticated.
+	principal *Principal
+	// authorizer authorizes the client by its principal in the handshake, it's nil if the clients aren't authorized.
+	authorizer Authorizer
+	// namespace is the namespace which the client is bound to by its principal, it's empty for the default workflow.
+	namespace string
 }
 
 // NewConn inits a new YoMo Zipper connection.
 func NewConn(addr string, sess quic.Session, st quic.Stream, conf *WorkflowConfig) *Conn {
+	c := newConn(addr, sess, st)
+	c.handleSignal(conf)
+	return c
+}
+
+// newConn inits a new YoMo Zipper connection without handling the signals, so the callbacks can be set before
+// the handshake is received.
+func newConn(addr string, sess quic.Session, st quic.Stream) *Conn {
 	logger.Debug("[zipper] inits a new connection.")
 	c := &Conn{
 		Conn: quic.NewConn("", core.ConnTypeNone),
 	}
 
 	c.Addr = addr
+	c.RemoteAddr = addr
+	if sess != nil {
+		// the addr has the channel ID suffix if the session is multiplexed.
+		c.RemoteAddr = sess.RemoteAddr().String()
+	}
 	c.Session = sess
+	c.ConnectedAt = time.Now()
+	if sess != nil {
+		sessionFrames.Store(sess, new(uint64))
+	}
 	c.Conn.Signal = core.NewFrameStream(st)
-	c.handleSignal(conf)
 	c.Conn.OnClosed = c.Close
 	c.Conn.OnHeartbeatReceived = func() {
 		logger.Debug("Received Ping from client, will send Pong to client.", "name", c.Conn.Name, "addr", c.Addr)
@@ -49,6 +103,12 @@ func NewConn(addr string, sess quic.Session, st quic.Stream, conf *WorkflowConfi
 	return c
 }
 
+// rateLimiter returns the limiter of bandwidth, it's nil if the bandwidth is unlimited.
+func (c *Conn) rateLimiter() *quic.RateLimiter {
+	limiter, _ := c.limiter.Load().(*quic.RateLimiter)
+	return limiter
+}
+
 // handleSignal handles the logic when receiving signal from client.
 func (c *Conn) handleSignal(conf *WorkflowConfig) {
 	go func() {
@@ -81,16 +141,72 @@ func (c *Conn) handleSignal(conf *WorkflowConfig) {
 					return
 				}
 
-				c.Conn.Name = payload.Name
-				c.Conn.Type = c.getConnType(payload, conf)
-				if c.Conn.Type == core.ConnTypeNone {
+				// the older YoMo SDKs don't send the version, they're the version 1 whose frames are
+				// decoded as is, and the frames sent to them are downgraded by frame.Downgrade.
+				version, err := frame.NegotiateVersion(payload.Version)
+				if err != nil {
+					logger.Printf("The %s %s is rejected: %v, addr: %s", payload.ClientType, payload.Name, err, c.Addr)
+					c.audit(AuditAuthFailure, payload.Name, core.ConnectionType(payload.ClientType), err.Error())
+					rejected := frame.NewRejectedFrame()
+					rejected.Message = err.Error()
+					rejected.Code = frame.RejectVersion
+					c.Conn.SendSignal(rejected)
+					continue
+				}
+				c.version = version
+				sessionVersions.Store(c.Session, version)
+
+				// the client is authenticated before it's matched with the workflow, so the name of an
+				// unauthenticated client isn't taken.
+				if err := c.authenticate(payload); err != nil {
+					logger.Printf("The %s %s is not authenticated: %v, addr: %s", core.ConnectionType(payload.ClientType), payload.Name, err, c.Addr)
+					c.audit(AuditAuthFailure, payload.Name, core.ConnectionType(payload.ClientType), err.Error())
+					rejected := frame.NewRejectedFrame()
+					rejected.Message = errUnauthenticated
+					rejected.Code = frame.RejectUnauthenticated
+					c.Conn.SendSignal(rejected)
+					// no more handshake is read, so the tokens can't be guessed on the session.
+					time.AfterFunc(rejectedLinger, func() { c.Close() })
+					return
+				}
+
+				// the client is matched with the workflow of its namespace by the qualified name.
+				c.namespace = conf.namespaceOf(c.principal)
+				view := conf.namespaceConfig(c.namespace)
+				c.Conn.Name = qualifiedName(c.namespace, payload.Name)
+				connType := c.getConnType(namespaced(payload, c.namespace), view)
+				if connType == core.ConnTypeNone {
 					logger.Printf("The %s name %s is mismatched with the name of Stream Function in zipper config.", payload.ClientType, payload.Name)
-					c.Conn.SendSignal(frame.NewRejectedFrame())
+					c.audit(AuditAuthFailure, payload.Name, core.ConnectionType(payload.ClientType), "the client is not allowed by the workflow")
+					rejected := frame.NewRejectedFrame()
+					rejected.Code = frame.RejectNotAllowed
+					c.Conn.SendSignal(rejected)
+					continue
+				}
+				// the type is set after the client is authorized, so the unauthorized stream function isn't dispatched.
+				if !c.authorizeClient(payload, connType) {
+					logger.Printf("The %s %s is not authorized, subject: %s, addr: %s", connType, c.Conn.Name, c.Subject(), c.Addr)
+					c.audit(AuditAuthFailure, payload.Name, connType, errUnauthorized)
+					rejected := frame.NewRejectedFrame()
+					rejected.Message = errUnauthorized
+					rejected.Code = frame.RejectUnauthorized
+					c.Conn.SendSignal(rejected)
 					continue
 				}
+				if !c.admit(conf) {
+					c.rejectQuota(payload.Name, connType)
+					continue
+				}
+				c.Conn.Type = connType
 				logger.Printf("Receive App %s, type: %s, addr: %s", c.Conn.Name, c.Conn.Type, c.Addr)
+				c.audit(AuditAuthSuccess, c.Conn.Name, c.Conn.Type, "")
 
-				if c.Conn.Type == core.ConnTypeStreamFunction {
+				if app, ok := view.shadowOf(c.Conn.Name); c.Conn.Type == core.ConnTypeStreamFunction && ok {
+					// the shadow function shares the cache of the function it mirrors.
+					clearStreamFuncCache(app.Name)
+					logger.Printf("The stream function %s is the shadow of %s, its responses will be discarded.", c.Conn.Name, app.Name)
+					go c.discardResponses()
+				} else if c.Conn.Type == core.ConnTypeStreamFunction {
 					// clear local cache when zipper has a new stream-fn connection.
 					clearStreamFuncCache(c.Conn.Name)
 
@@ -104,16 +220,202 @@ func (c *Conn) handleSignal(conf *WorkflowConfig) {
 					}
 				}
 
-				c.Conn.SendSignal(frame.NewAcceptedFrame())
+				if bandwidth := view.bandwidthOf(c.Conn.Name); c.Conn.Type == core.ConnTypeSource && bandwidth > 0 {
+					c.limiter.Store(quic.NewRateLimiter(bandwidth))
+				}
+
+				if c.Conn.Type == core.ConnTypeSource && c.onPartialFrame != nil {
+					go c.readPartialStreams(view)
+				}
+
+				accepted := frame.NewAcceptedFrame()
+				if payload.Version > 0 {
+					// the clients before the version negotiation don't understand the version.
+					accepted.Version = version
+				}
+				if codec, ok := compress.Negotiate(payload.Codecs); ok {
+					accepted.Codec = codec.Name()
+					sessionCodecs.Store(c.Session, codec)
+				}
+
+				c.Conn.SendSignal(accepted)
+				pipelineEvents.sessionEvent(EventSessionJoined, c)
+				c.Conn.HeartbeatTimeout = conf.KeepAlive.of(c.Conn.Type).IdleTimeout
 				c.Conn.Healthcheck()
 
 			case frame.TagOfPingFrame:
 				c.Conn.Heartbeat <- true
+
+			case frame.TagOfControlFrame:
+				c.control(f.(*frame.ControlFrame), conf)
+
+			case frame.TagOfGoodbyeFrame:
+				c.goodbye(f.(*frame.GoodbyeFrame), conf)
 			}
 		}
 	}()
 }
 
+// control changes the data tags observed by the stream function, the routing table is updated live.
+func (c *Conn) control(f *frame.ControlFrame, conf *WorkflowConfig) {
+	if c.Conn.Type != core.ConnTypeStreamFunction {
+		logger.Debug("[zipper] only the stream functions can send the control frames.", "name", c.Conn.Name)
+		return
+	}
+
+	for _, app := range conf.namespaceConfig(c.namespace).Functions {
+		if app.Name == c.Conn.Name {
+			tags := updateSubscription(app, f.Subscribe, f.Unsubscribe)
+			logger.Printf("The stream function %s observes the tags %# x", c.Conn.Name, tags)
+			return
+		}
+	}
+	logger.Debug("[zipper] the shadow functions can't change the observed tags.", "name", c.Conn.Name)
+}
+
+// goodbye removes the departing client from the dispatch pool at once, the session is kept until the client
+// closes it, so the pending responses of the stream function are still received.
+func (c *Conn) goodbye(f *frame.GoodbyeFrame, conf *WorkflowConfig) {
+	logger.Printf("The client %s says goodbye: %s, addr: %s", c.Conn.Name, f.Reason, c.Addr)
+	c.drain(conf)
+}
+
+// drain removes the client from the dispatch pool, the new streams of it are ignored, but the session is kept so
+// the pending responses of the stream function are still received.
+func (c *Conn) drain(conf *WorkflowConfig) {
+	// the connection stays in the pool of connections until the session is closed, so the streams which are
+	// still arriving are not taken as new connections.
+	atomic.StoreUint32(&c.departed, 1)
+
+	if c.Conn.Type != core.ConnTypeStreamFunction {
+		return
+	}
+	if app, ok := conf.namespaceConfig(c.namespace).shadowOf(c.Conn.Name); ok {
+		clearStreamFuncCache(app.Name)
+	} else {
+		clearStreamFuncCache(c.Conn.Name)
+	}
+}
+
+// Departed returns true if the client has said goodbye.
+func (c *Conn) Departed() bool {
+	return atomic.LoadUint32(&c.departed) == 1
+}
+
+// audit records the event of the client.
+func (c *Conn) audit(event string, name string, connType core.ConnectionType, reason string) {
+	if c.auditor == nil {
+		return
+	}
+	record := AuditRecord{Event: event, Name: name, Addr: c.Addr, Reason: reason}
+	if connType != core.ConnTypeNone {
+		record.Type = connType.String()
+	}
+	if c.Session != nil {
+		record.Identities = quic.PeerIdentities(c.Session)
+	}
+	if c.principal != nil {
+		record.Subject = c.principal.Subject
+	}
+	c.auditor.record(record)
+}
+
+// Frames returns the count of frames received from the source, or sent to the stream function.
+func (c *Conn) Frames() uint64 {
+	if frames, ok := sessionFrames.Load(c.Session); ok {
+		return atomic.LoadUint64(frames.(*uint64))
+	}
+	return 0
+}
+
+// Subject returns the identity of client authenticated by its token, it's empty if the client isn't authenticated.
+func (c *Conn) Subject() string {
+	if c.principal == nil {
+		return ""
+	}
+	return c.principal.Subject
+}
+
+// Namespace returns the namespace which the client is bound to, it's empty for the default workflow.
+func (c *Conn) Namespace() string {
+	return c.namespace
+}
+
+// Version returns the version of wire protocol negotiated with the client.
+func (c *Conn) Version() uint32 {
+	return c.version
+}
+
+// authenticate authenticates the client by the token in the handshake, all clients are accepted if the
+// authenticator is nil. The probes of this YoMo-Zipper are authenticated by their own token.
+func (c *Conn) authenticate(payload *frame.HandshakeFrame) error {
+	c.principal = nil
+	if c.authenticator == nil || c.prober.authenticate(payload) {
+		return nil
+	}
+
+	cred := Credential{
+		Name:  payload.Name,
+		Type:  core.ConnectionType(payload.ClientType).String(),
+		Token: payload.Token,
+		Addr:  c.Addr,
+	}
+	if c.Session != nil {
+		cred.Identities = quic.PeerIdentities(c.Session)
+	}
+	principal, err := c.authenticator.Authenticate(cred)
+	if err != nil {
+		return err
+	}
+	if principal == nil {
+		principal = &Principal{Subject: payload.Name}
+	}
+	c.principal = principal
+	return nil
+}
+
+// authorizeClient indicates if the principal of client is authorized to connect as the name and type of handshake,
+// the tags which the source may emit are authorized at the same time. All clients are authorized if the authorizer
+// is nil, and the probes of this YoMo-Zipper are always authorized.
+func (c *Conn) authorizeClient(payload *frame.HandshakeFrame, connType core.ConnectionType) bool {
+	sessionGrants.Delete(c.Session)
+	if c.authorizer == nil || c.prober.authenticate(payload) {
+		return true
+	}
+
+	principal := c.principal
+	if principal == nil {
+		principal = &Principal{}
+	}
+	if !c.authorizer.AuthorizeClient(principal, payload.Name, connType.String()) {
+		return false
+	}
+	if connType == core.ConnTypeSource && c.Session != nil {
+		sessionGrants.Store(c.Session, newTagGrant(c.authorizer, principal, payload.Name))
+	}
+	return true
+}
+
+// authorize returns the connType if the identity of peer certificate matches the app, otherwise returns ConnTypeNone.
+func (c *Conn) authorize(app App, connType core.ConnectionType) core.ConnectionType {
+	if len(app.Identities) == 0 {
+		return connType
+	}
+
+	if c.Session != nil {
+		for _, identity := range quic.PeerIdentities(c.Session) {
+			for _, allowed := range app.Identities {
+				if identity == allowed {
+					return connType
+				}
+			}
+		}
+	}
+
+	logger.Printf("The client %s is not authorized by the identities in its certificate, addr: %s", app.Name, c.Addr)
+	return core.ConnTypeNone
+}
+
 func (c *Conn) getConnType(payload *frame.HandshakeFrame, conf *WorkflowConfig) core.ConnectionType {
 	clientType := core.ConnectionType(payload.ClientType)
 	switch clientType {
@@ -125,19 +427,169 @@ func (c *Conn) getConnType(payload *frame.HandshakeFrame, conf *WorkflowConfig)
 
 		for _, app := range conf.Functions {
 			if app.Name == payload.Name {
-				return core.ConnTypeStreamFunction
+				return c.authorize(app, core.ConnTypeStreamFunction)
 			}
 		}
+
+		// the shadow of a function.
+		if app, ok := conf.shadowOf(payload.Name); ok {
+			return c.authorize(app, core.ConnTypeStreamFunction)
+		}
 		// name is not found
 		return core.ConnTypeNone
+	case core.ConnTypeSource:
+		// the probes of this YoMo-Zipper are authenticated by the token instead of the allowlist.
+		if c.prober.authenticate(payload) {
+			return core.ConnTypeSource
+		}
+		// all sources are accepted if the sources are not specified in config.
+		if len(conf.Sources) == 0 {
+			return core.ConnTypeSource
+		}
+
+		for _, app := range conf.Sources {
+			if app.Name == payload.Name {
+				return c.authorize(app, core.ConnTypeSource)
+			}
+		}
+		return core.ConnTypeNone
 	default:
 		return clientType
 	}
 }
 
+// discardResponses drains the responses from a shadow stream function.
+func (c *Conn) discardResponses() {
+	for {
+		stream, err := c.Session.AcceptUniStream(context.Background())
+		if err != nil {
+			if err.Error() != quic.ErrConnectionClosed {
+				logger.Error("[zipper] session.AcceptUniStream of shadow failed", "stream-fn", c.Conn.Name, "err", err)
+			}
+			return
+		}
+
+		go func() {
+			n, _ := io.Copy(ioutil.Discard, stream)
+			logger.Debug("[zipper] discarded the response from shadow.", "stream-fn", c.Conn.Name, "len", n)
+		}()
+	}
+}
+
+// readPartialStreams reads the DataFrames which the source sent in partially reliable mode, each frame is sent
+// in its own unidirectional stream, the streams reset by the source after its deadline are abandoned.
+// The streamed carriages are also sent in unidirectional streams.
+func (c *Conn) readPartialStreams(conf *WorkflowConfig) {
+	for {
+		stream, err := c.Session.AcceptUniStream(context.Background())
+		if err != nil {
+			if err.Error() != quic.ErrConnectionClosed {
+				logger.Error("[zipper] session.AcceptUniStream of source failed", "source", c.Conn.Name, "err", err)
+			}
+			return
+		}
+		if c.Departed() {
+			logger.Debug("[zipper] ignore the stream of the departed source.", "source", c.Conn.Name)
+			stream.CancelRead(0)
+			continue
+		}
+
+		go func() {
+			var r io.Reader = stream
+			if limiter := c.rateLimiter(); limiter != nil {
+				r = quic.NewRateLimitedReader(stream, limiter)
+			}
+
+			f, err := core.ParseFrame(r)
+			if errors.Is(err, frame.ErrChecksumMismatch) {
+				logger.Error("[zipper] drop the corrupted frame of source.", "source", c.Conn.Name, "addr", c.Addr, "err", err)
+				return
+			}
+			if err != nil {
+				logger.Debug("[zipper] the frame is abandoned by source.", "source", c.Conn.Name, "err", err)
+				return
+			}
+			dataFrame, ok := f.(*frame.DataFrame)
+			if !ok {
+				logger.Debug("Only dispatch data frame to stream functions.", "type", f.Type())
+				return
+			}
+			if dataFrame.Streamed() {
+				if c.onStreamedFrame == nil {
+					stream.CancelRead(0)
+					return
+				}
+				// the stream is cancelled if the carriage is abandoned, the carriage is decompressed as it's read.
+				dataFrame.SetCarriageReader(dataFrame.GetDataTagID(), &streamCarriage{Reader: dataFrame.CarriageReader(), stream: stream})
+				if err := core.DecompressFrame(dataFrame, codecOf(c.Session), conf.MaxFrameSize); err != nil {
+					logger.Error("[zipper] decompress the streamed carriage of source failed", "source", c.Conn.Name, "err", err)
+					core.CloseCarriage(dataFrame)
+					return
+				}
+				if !permitted(c.Session, dataFrame) || !withinQuota(c.Session, dataFrame) {
+					core.CloseCarriage(dataFrame)
+					return
+				}
+				countFrame(c.Session)
+				c.onStreamedFrame(dataFrame)
+				return
+			}
+			if err := core.DecompressFrame(dataFrame, codecOf(c.Session), conf.MaxFrameSize); err != nil {
+				logger.Error("[zipper] decompress the frame of partially reliable stream failed", "source", c.Conn.Name, "err", err)
+				return
+			}
+
+			if sequence, ok := dataFrame.Sequence(); ok && !c.advanceSequence(sequence) {
+				logger.Debug("[zipper] drop the late frame of source.", "source", c.Conn.Name, "sequence", sequence)
+				return
+			}
+			if !permitted(c.Session, dataFrame) || !withinQuota(c.Session, dataFrame) {
+				return
+			}
+			countFrame(c.Session)
+			c.onPartialFrame(dataFrame)
+		}()
+	}
+}
+
+// streamCarriage is the streamed carriage of a unidirectional stream, closing it cancels the stream.
+type streamCarriage struct {
+	io.Reader
+	stream quic.ReceiveStream
+}
+
+func (c *streamCarriage) Close() error {
+	c.stream.CancelRead(0)
+	return nil
+}
+
+// advanceSequence returns false if a newer frame has been received, so the frames are kept in order.
+func (c *Conn) advanceSequence(sequence uint64) bool {
+	for {
+		last := atomic.LoadUint64(&c.lastSequence)
+		if sequence <= last {
+			return false
+		}
+		if atomic.CompareAndSwapUint64(&c.lastSequence, last, sequence) {
+			return true
+		}
+	}
+}
+
 // Close the QUIC connection.
 func (c *Conn) Close() error {
 	err := c.Session.CloseWithError(0, "")
+	sessionCodecs.Delete(c.Session)
+	sessionVersions.Delete(c.Session)
+	sessionFrames.Delete(c.Session)
+	sessionGrants.Delete(c.Session)
+	c.releaseQuota()
+	if atomic.CompareAndSwapUint32(&c.closed, 0, 1) {
+		c.audit(AuditDisconnect, c.Conn.Name, c.Conn.Type, "")
+		if c.Conn.Type != core.ConnTypeNone {
+			pipelineEvents.sessionEvent(EventSessionLeft, c)
+		}
+	}
 
 	if c.onClosed != nil {
 		c.onClosed()
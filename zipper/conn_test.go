@@ -0,0 +1,129 @@
+package zipper
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/core/quic"
+	"github.com/yomorun/yomo/internal/core"
+	"github.com/yomorun/yomo/internal/frame"
+)
+
+func TestGetConnTypeWithIdentities(t *testing.T) {
+	conf := &WorkflowConfig{}
+	conf.Sources = []App{{Name: "source"}}
+	conf.Functions = []App{
+		{Name: "func1"},
+		{Name: "func2", Identities: []string{"func2.yomo.run"}},
+	}
+
+	// the connection without client certificate.
+	c := &Conn{Addr: "127.0.0.1:1", Conn: quic.NewConn("", core.ConnTypeNone)}
+	assert.Equal(t, core.ConnTypeStreamFunction, c.getConnType(frame.NewHandshakeFrame("func1", byte(core.ConnTypeStreamFunction)), conf))
+	assert.Equal(t, core.ConnTypeNone, c.getConnType(frame.NewHandshakeFrame("func2", byte(core.ConnTypeStreamFunction)), conf))
+	assert.Equal(t, core.ConnTypeSource, c.getConnType(frame.NewHandshakeFrame("source", byte(core.ConnTypeSource)), conf))
+	assert.Equal(t, core.ConnTypeNone, c.getConnType(frame.NewHandshakeFrame("unknown", byte(core.ConnTypeSource)), conf))
+}
+
+func TestGetConnTypeOfProbe(t *testing.T) {
+	conf := &WorkflowConfig{}
+	conf.Sources = []App{{Name: "source"}}
+	p := newProber(&SLIConfig{}, "test")
+
+	// the clients named as probes are not accepted without the token.
+	c := &Conn{Addr: "127.0.0.1:1", Conn: quic.NewConn("", core.ConnTypeNone), prober: p}
+	handshake := frame.NewHandshakeFrame(probeTransactionPrefix+"p1", byte(core.ConnTypeSource))
+	assert.Equal(t, core.ConnTypeNone, c.getConnType(handshake, conf))
+	handshake.Token = "guessed"
+	assert.Equal(t, core.ConnTypeNone, c.getConnType(handshake, conf))
+
+	handshake.Token = p.token
+	assert.Equal(t, core.ConnTypeSource, c.getConnType(handshake, conf))
+
+	// the token of probes is only valid with the name of probes.
+	handshake = frame.NewHandshakeFrame("unknown", byte(core.ConnTypeSource))
+	handshake.Token = p.token
+	assert.Equal(t, core.ConnTypeNone, c.getConnType(handshake, conf))
+}
+
+func TestGoodbye(t *testing.T) {
+	conf := &WorkflowConfig{}
+	conf.Functions = []App{{Name: "func1"}}
+	streamFuncCache.Store("func1", []streamFuncWithCancel{{addr: "127.0.0.1:1"}})
+
+	var connMap sync.Map
+	c := &Conn{Addr: "127.0.0.1:1", Conn: quic.NewConn("func1", core.ConnTypeStreamFunction)}
+	connMap.Store(c.Addr, c)
+	assert.Len(t, findConn(App{Name: "func1"}, &connMap, core.ConnTypeStreamFunction), 1)
+
+	c.goodbye(frame.NewGoodbyeFrame("shutdown"), conf)
+	_, ok := streamFuncCache.Load("func1")
+	assert.False(t, ok)
+
+	// the departed connection is kept until the session is closed, but it's removed from the dispatch pool.
+	assert.True(t, c.Departed())
+	_, ok = connMap.Load(c.Addr)
+	assert.True(t, ok)
+	assert.Empty(t, findConn(App{Name: "func1"}, &connMap, core.ConnTypeStreamFunction))
+}
+
+func TestAdvanceSequence(t *testing.T) {
+	c := &Conn{}
+	assert.True(t, c.advanceSequence(1))
+	assert.True(t, c.advanceSequence(3))
+	// the late frame is dropped.
+	assert.False(t, c.advanceSequence(2))
+	assert.False(t, c.advanceSequence(3))
+	assert.True(t, c.advanceSequence(4))
+}
+
+type versionedSession struct {
+	quic.Session
+}
+
+func TestFrameForVersion1(t *testing.T) {
+	// the stream function of an older YoMo SDK.
+	session := &versionedSession{}
+	sessionVersions.Store(session, frame.Version1)
+	defer sessionVersions.Delete(session)
+
+	data := frame.NewDataFrame("tid")
+	data.SetCarriage(0x10, []byte("yomo"))
+	data.SetContentType("application/json")
+	data.SetChecksum(true)
+
+	f, err := frameFor(session, data)
+	assert.NoError(t, err)
+	assert.Empty(t, f.ContentType())
+	assert.False(t, f.HasChecksum())
+	assert.Equal(t, []byte("yomo"), f.GetCarriage())
+
+	// the current stream functions receive all the fields.
+	f, err = frameFor(&versionedSession{}, data)
+	assert.NoError(t, err)
+	assert.Equal(t, "application/json", f.ContentType())
+
+	// the encrypted carriage can't be read by the older YoMo SDKs.
+	data.SetKeyID("key")
+	_, err = frameFor(session, data)
+	assert.ErrorIs(t, err, frame.ErrUnsupportedVersion)
+}
+
+func TestEncodeForChunks(t *testing.T) {
+	data := frame.NewDataFrame("tid")
+	data.SetCarriage(0x10, bytes.Repeat([]byte("yomo"), 1024))
+
+	// the large frame is split into chunks for the current stream functions.
+	buf := encodeFor(&versionedSession{}, data, 1024)
+	f, err := core.ParseFrame(bytes.NewReader(buf))
+	assert.NoError(t, err)
+	assert.Equal(t, frame.TagOfChunkFrame, f.Type())
+
+	// the older YoMo SDKs can't reassemble the chunks.
+	session := &versionedSession{}
+	sessionVersions.Store(session, frame.Version1)
+	defer sessionVersions.Delete(session)
+	assert.Equal(t, data.Encode(), encodeFor(session, data, 1024))
+}
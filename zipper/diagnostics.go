@@ -0,0 +1,107 @@
+package zipper
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	rpprof "runtime/pprof"
+	"sort"
+	"sync/atomic"
+)
+
+// QueueDepth is the count of frames waiting in a queue of pipeline.
+type QueueDepth struct {
+	// Name is the name of queue, the queues of stages are named by the stream functions which consume them.
+	Name     string `json:"name"`
+	Length   int    `json:"length"`
+	Capacity int    `json:"capacity"`
+}
+
+// RuntimeInfo is the snapshot of Go runtime and the queues of pipeline in the diagnostics.
+type RuntimeInfo struct {
+	Goroutines  int          `json:"goroutines"`
+	GOMAXPROCS  int          `json:"gomaxprocs"`
+	HeapAlloc   uint64       `json:"heap_alloc_bytes"`
+	HeapObjects uint64       `json:"heap_objects"`
+	NumGC       uint32       `json:"num_gc"`
+	Sessions    int          `json:"sessions"`
+	Subscribers int32        `json:"event_subscribers"`
+	Queues      []QueueDepth `json:"queues"`
+}
+
+// mountDiagnostics mounts the profiles and the runtime diagnostics on the admin API.
+// GET /debug/pprof/ serves the profiles of net/http/pprof.
+// GET /debug/goroutines dumps the stacks of all goroutines as text.
+// GET /debug/runtime returns the count of goroutines, the heap and the depth of each queue of pipeline.
+func (h *quicHandler) mountDiagnostics(mux *http.ServeMux) {
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/debug/goroutines", dumpGoroutines)
+	mux.HandleFunc("/debug/runtime", h.runtimeInfo)
+}
+
+// dumpGoroutines writes the stacks of all goroutines in the format of panic.
+func dumpGoroutines(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	rpprof.Lookup("goroutine").WriteTo(w, 2)
+}
+
+// runtimeInfo is the admin API of the snapshot of runtime.
+func (h *quicHandler) runtimeInfo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	writeJSON(w, http.StatusOK, RuntimeInfo{
+		Goroutines:  runtime.NumGoroutine(),
+		GOMAXPROCS:  runtime.GOMAXPROCS(0),
+		HeapAlloc:   mem.HeapAlloc,
+		HeapObjects: mem.HeapObjects,
+		NumGC:       mem.NumGC,
+		Sessions:    len(h.currentConnections()),
+		Subscribers: atomic.LoadInt32(&pipelineEvents.count),
+		Queues:      h.queueDepths(),
+	})
+}
+
+// queueDepths returns the depth of the queues of pipelines and the queues of datagrams and partially reliable frames.
+func (h *quicHandler) queueDepths() []QueueDepth {
+	depths := h.queues.depths()
+	depths = append(depths,
+		QueueDepth{Name: "datagrams", Length: len(h.datagrams), Capacity: cap(h.datagrams)},
+		QueueDepth{Name: "datagram-ring", Length: h.datagramRing.len(), Capacity: len(h.datagramRing.slots)},
+		QueueDepth{Name: "partials", Length: len(h.partials), Capacity: cap(h.partials)},
+		QueueDepth{Name: "partial-ring", Length: h.partialRing.len(), Capacity: len(h.partialRing.slots)},
+	)
+	return depths
+}
+
+// depths returns the depth of each tracked queue, they're sorted by name.
+func (t *queueTracker) depths() []QueueDepth {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.prune()
+	depths := make([]QueueDepth, 0, len(t.queues))
+	for queue, q := range t.queues {
+		name := q.stage
+		if name == "" {
+			name = "output"
+		}
+		depths = append(depths, QueueDepth{Name: name, Length: len(queue), Capacity: cap(queue)})
+	}
+	sort.SliceStable(depths, func(i, j int) bool {
+		return depths[i].Name < depths[j].Name
+	})
+	return depths
+}
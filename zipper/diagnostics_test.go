@@ -0,0 +1,61 @@
+package zipper
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/internal/frame"
+)
+
+func TestDiagnostics(t *testing.T) {
+	h := newServerHandler(&WorkflowConfig{AdminToken: "secret", Diagnostics: true}, "")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	queue := make(chan *frame.DataFrame, 4)
+	queue <- frame.NewDataFrame("tid")
+	h.queues.track(ctx, "fn", queue)
+
+	server := httptest.NewServer(newAdminMux(h))
+	defer server.Close()
+
+	get := func(path string) *http.Response {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		res, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		return res
+	}
+
+	res := get("/debug/runtime")
+	var info RuntimeInfo
+	assert.NoError(t, json.NewDecoder(res.Body).Decode(&info))
+	res.Body.Close()
+	assert.Greater(t, info.Goroutines, 0)
+	assert.Contains(t, info.Queues, QueueDepth{Name: "fn", Length: 1, Capacity: 4})
+	assert.Contains(t, info.Queues, QueueDepth{Name: "datagrams", Capacity: bufferSize})
+
+	res = get("/debug/goroutines")
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	assert.Contains(t, string(body), "goroutine ")
+
+	res = get("/debug/pprof/")
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	// the diagnostics are not served by default.
+	h = newServerHandler(&WorkflowConfig{AdminToken: "secret"}, "")
+	server2 := httptest.NewServer(newAdminMux(h))
+	defer server2.Close()
+	req, _ := http.NewRequest(http.MethodGet, server2.URL+"/debug/runtime", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	res, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+}
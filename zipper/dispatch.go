@@ -2,9 +2,13 @@ package zipper
 
 import (
 	"context"
+	"errors"
+	"io"
+	"io/ioutil"
 	"sync/atomic"
 	"time"
 
+	"github.com/yomorun/yomo/core/compress"
 	"github.com/yomorun/yomo/core/quic"
 	"github.com/yomorun/yomo/internal/core"
 	"github.com/yomorun/yomo/internal/frame"
@@ -14,9 +18,10 @@ import (
 
 // DispatcherWithFunc dispatches the input stream to downstreams.
 func DispatcherWithFunc(ctx context.Context, sfns []GetStreamFunc, stream quic.Stream) chan *frame.DataFrame {
-	next := readDataFromSource(ctx, stream)
+	conf := &WorkflowConfig{}
+	next := readDataFromSource(ctx, "", nil, stream, newShedder(SheddingConfig{}), conf)
 	for _, sfn := range sfns {
-		next = pipeStreamFn(ctx, next, sfn)
+		next = pipeStreamFn(ctx, next, sfn, conf, nil)
 	}
 
 	return next
@@ -24,9 +29,26 @@ func DispatcherWithFunc(ctx context.Context, sfns []GetStreamFunc, stream quic.S
 
 const bufferSize int = 100
 
-// readDataFromSource reads data from source QUIC stream.
-func readDataFromSource(ctx context.Context, stream quic.Stream) chan *frame.DataFrame {
+// corruptedCode is the error code of the stream reset when a corrupted frame is received.
+const corruptedCode = 0xCF
+
+// skipCorruption logs the corrupted frame with the peer, and resets the stream if it's configured.
+// It returns true if the corrupted frame is skipped and the stream can be read further.
+func skipCorruption(peer string, stream quic.ReceiveStream, err error, conf *WorkflowConfig) bool {
+	logger.Error("[zipper] received a corrupted frame.", "peer", peer, "stream", stream.StreamID(), "err", err)
+	if conf.ResetOnCorruption {
+		stream.CancelRead(corruptedCode)
+		return false
+	}
+	return true
+}
+
+// readDataFromSource reads data from source QUIC stream, the chunks are reassembled up to the max frame size.
+// The data is decompressed by the codec negotiated with the session of source, the session is nil in tests.
+func readDataFromSource(ctx context.Context, peer string, session quic.Session, stream quic.Stream, shedder *shedder, conf *WorkflowConfig) chan *frame.DataFrame {
 	next := make(chan *frame.DataFrame, bufferSize)
+	reassembler := core.NewReassembler(conf.MaxFrameSize)
+	codec := codecOf(session)
 
 	go func() {
 		defer close(next)
@@ -37,17 +59,42 @@ func readDataFromSource(ctx context.Context, stream quic.Stream) chan *frame.Dat
 			case <-ctx.Done():
 				return
 			default:
-				f, err := core.ParseFrame(stream)
+				f, err := core.ParseFrameWithLimit(stream, conf.MaxFrameSize)
+				if errors.Is(err, frame.ErrChecksumMismatch) && skipCorruption(peer, stream, err, conf) {
+					continue
+				}
 				if err != nil {
 					logger.Error("Parse the frame failed", "err", err)
 					break LOOP
 				}
 
+				if chunk, ok := f.(*frame.ChunkFrame); ok {
+					dataFrame, err := reassembler.Push(chunk)
+					if err != nil {
+						logger.Error("Reassemble the data frame failed", "TransactionID", chunk.ID, "err", err)
+						continue
+					}
+					if dataFrame == nil {
+						continue
+					}
+					f = dataFrame
+				}
+
 				switch f.Type() {
 				case frame.TagOfDataFrame:
 					dataFrame := f.(*frame.DataFrame)
 					logger.Debug("Receive data frame from source.", "TransactionID", dataFrame.TransactionID())
-					next <- dataFrame
+					if err := core.DecompressFrame(dataFrame, codec, conf.MaxFrameSize); err != nil {
+						logger.Error("Decompress the data frame failed", "TransactionID", dataFrame.TransactionID(), "err", err)
+						pipelineMetrics.failed(errorDecompress)
+						continue
+					}
+					countFrame(session)
+					if !permitted(session, dataFrame) || !withinQuota(session, dataFrame) {
+						core.CloseCarriage(dataFrame)
+						continue
+					}
+					shedder.push(next, dataFrame)
 				default:
 					logger.Debug("Only dispatch data frame to stream functions.", "type", f.Type())
 				}
@@ -59,7 +106,8 @@ func readDataFromSource(ctx context.Context, stream quic.Stream) chan *frame.Dat
 }
 
 // pipeStreamFn sends the raw data to `stream-fn`, receives the new raw data and send it to next `stream-fn`.
-func pipeStreamFn(ctx context.Context, upstream chan *frame.DataFrame, sfn GetStreamFunc) chan *frame.DataFrame {
+// The frames are batched for the stream function if the feature is enabled for them.
+func pipeStreamFn(ctx context.Context, upstream chan *frame.DataFrame, sfn GetStreamFunc, conf *WorkflowConfig, features *Features) chan *frame.DataFrame {
 	next := make(chan *frame.DataFrame, bufferSize)
 
 	go func() {
@@ -76,48 +124,94 @@ func pipeStreamFn(ctx context.Context, upstream chan *frame.DataFrame, sfn GetSt
 						return
 					}
 
-					go dispatchToStreamFn(sfn, item, next)
+					// the frames which are not subscribed or sampled pass through this stream function.
+					if name, _ := sfn(); !subscribedTo(name, item) || !sampled(name) {
+						next <- item
+						continue
+					}
+
+					go dispatchToStreamFn(sfn, item, next, conf, features.Enabled(FeatureBatching, item.TransactionID()))
 				}
 			}
 		}()
 
 		// receive the response from flow  (flow/sink -> zipper)
-		receiveResponseFromStreamFn(ctx, sfn, next)
+		receiveResponseFromStreamFn(ctx, sfn, next, conf)
 	}()
 
 	return next
 }
 
-// dispatchToStreamFn dispatch the data from `upstream` to next `stream-fn` by Round Robin.
-func dispatchToStreamFn(sfn GetStreamFunc, data *frame.DataFrame, next chan *frame.DataFrame) {
+// dispatchToStreamFn dispatch the data from `upstream` to next `stream-fn` by Round Robin. The streamed carriage
+// is read once, so it's not mirrored to the shadow functions, and it's sent to the sessions which can read it,
+// it's buffered up to the max frame size if none of them can.
+func dispatchToStreamFn(sfn GetStreamFunc, data *frame.DataFrame, next chan *frame.DataFrame, conf *WorkflowConfig, batch bool) {
 	var nextNum uint32
 
-	name, funcs := sfn()
+	name, all := sfn()
+	funcs := make([]streamFuncWithCancel, 0, len(all))
+	streamed := make([]streamFuncWithCancel, 0, len(all))
+	for _, f := range all {
+		if f.shadow {
+			// mirror the data to shadow function.
+			if !data.Streamed() {
+				go sendDataToShadowFn(name, f, data, conf.chunkSize())
+			}
+			continue
+		}
+		funcs = append(funcs, f)
+		// the stream functions of the version 1 can't read the streamed carriage.
+		if f.session == nil || versionOf(f.session) != frame.Version1 {
+			streamed = append(streamed, f)
+		}
+	}
+
+	if data.Streamed() && len(funcs) > 0 {
+		if len(streamed) > 0 {
+			funcs = streamed
+		} else if err := materialize(data, conf.MaxFrameSize); err != nil {
+			logger.Error("[MergeStreamFunc] the streamed carriage can't be sent to `stream-fn`.", "stream-fn", name, "TransactionID", data.TransactionID(), "err", err)
+			return
+		}
+	}
+
 	len := len(funcs)
 	// no available sessions in this stream-fn.
 	if len == 0 {
 		logger.Info("no available sessions in stream fn.", "name", name)
+		core.CloseCarriage(data)
 		return
 	}
 
 	// only one session in this stream-fn.
 	if len == 1 {
-		go sendDataToStreamFn(name, funcs[0].session, funcs[0].cancel, data, next)
+		go sendDataToStreamFn(name, funcs[0].session, funcs[0].cancel, data, next, conf.chunkSize(), batch)
 		return
 	}
 
+	// get the session with the lowest RTT.
+	if loadBalanceOf(name) == LoadBalanceLatency {
+		if i, ok := lowestLatency(funcs); ok {
+			logger.Debug("[MergeStreamFunc] dispatch data to the stream-function with the lowest latency", "name", name, "index", i)
+			go sendDataToStreamFn(name, funcs[i].session, funcs[i].cancel, data, next, conf.chunkSize(), batch)
+			return
+		}
+	}
+
 	// get next session by Round Robin when has more sessions in this stream-fn.
 	n := atomic.AddUint32(&nextNum, 1)
 	i := (int(n) - 1) % len
 	logger.Debug("[MergeStreamFunc] dispatch data to next stream-function", "name", name, "index", i)
 
-	go sendDataToStreamFn(name, funcs[i].session, funcs[i].cancel, data, next)
+	go sendDataToStreamFn(name, funcs[i].session, funcs[i].cancel, data, next, conf.chunkSize(), batch)
 }
 
 // sendDataToStreamFn send the data to a specified `stream-fn` by QUIC Stream.
-func sendDataToStreamFn(name string, session quic.Session, cancel CancelFunc, data *frame.DataFrame, next chan *frame.DataFrame) {
+// The frame is coalesced with the other frames to the session in one stream if batch is true.
+func sendDataToStreamFn(name string, session quic.Session, cancel CancelFunc, data *frame.DataFrame, next chan *frame.DataFrame, chunkSize int, batch bool) {
 	if session == nil {
 		logger.Error("[MergeStreamFunc] the session of the stream-function is nil", "stream-fn", name)
+		pipelineTimelines.record(StepFailed, name, data, "the session of the stream function is nil")
 		// pass the data to next stream function if the current stream function is nil
 		next <- data
 		// cancel the current session when error.
@@ -125,16 +219,40 @@ func sendDataToStreamFn(name string, session quic.Session, cancel CancelFunc, da
 		return
 	}
 
-	// tracing
-	span := tracing.NewSpanFromData(string(data.GetCarriage()), name, "zipper-send-to-"+name)
-	if span != nil {
-		defer span.End()
+	// tracing, the span is written to a copy of frame because the frame is shared with the shadow functions,
+	// the frame isn't copied if the tracing is disabled.
+	traced := data
+	spanCtx, span := tracing.StartSpanFromFrame(data, name, "zipper-send-to-"+name)
+	defer span.End()
+	if span.IsRecording() {
+		traced = data.Clone()
+		tracing.InjectToFrame(spanCtx, traced)
+	}
+
+	// the stream functions of the version 1 read one frame in each stream, and the streamed carriage must be
+	// the last in its stream.
+	if batch && !data.Streamed() && versionOf(session) != frame.Version1 {
+		f, err := frameFor(session, traced)
+		if err != nil {
+			logger.Error("[MergeStreamFunc] the data can't be sent to `stream-fn`.", "stream-fn", name, "err", err)
+			pipelineMetrics.failed(errorSend)
+			pipelineTimelines.record(StepFailed, name, data, err.Error())
+			return
+		}
+		pipelineMetrics.dispatched(name, data.TransactionID())
+		pipelineEvents.frameEvent(EventFrameRouted, name, data, 0)
+		pipelineTimelines.record(StepRouted, name, data, "")
+		countFrame(session)
+		batcherOf(name, session, cancel).push(encodeFor(session, f, chunkSize))
+		return
 	}
 
 	// send data to downstream.
 	stream, err := session.OpenUniStream()
 	if err != nil {
 		logger.Error("[MergeStreamFunc] session.OpenUniStream failed", "stream-fn", name, "err", err)
+		pipelineMetrics.failed(errorSend)
+		pipelineTimelines.record(StepFailed, name, data, err.Error())
 		// pass the data to next `stream function` if the current stream has error.
 		next <- data
 		// cancel the current session when error.
@@ -142,10 +260,32 @@ func sendDataToStreamFn(name string, session quic.Session, cancel CancelFunc, da
 		return
 	}
 
-	_, err = stream.Write(data.Encode())
+	f, err := frameFor(session, traced)
+	if err != nil {
+		logger.Error("[MergeStreamFunc] the data can't be sent to `stream-fn`.", "stream-fn", name, "err", err)
+		pipelineMetrics.failed(errorSend)
+		pipelineTimelines.record(StepFailed, name, data, err.Error())
+		stream.CancelWrite(0)
+		core.CloseCarriage(data)
+		return
+	}
+	pipelineMetrics.dispatched(name, data.TransactionID())
+	pipelineEvents.frameEvent(EventFrameRouted, name, data, 0)
+	pipelineTimelines.record(StepRouted, name, data, "")
+	countFrame(session)
+	if f.Streamed() {
+		// the streamed carriage is piped from the stream of source as it's read.
+		if _, err = core.WriteStreamedFrame(stream, f); err != nil {
+			stream.CancelWrite(0)
+		}
+	} else {
+		_, err = stream.Write(encodeFor(session, f, chunkSize))
+	}
 	stream.Close()
 	if err != nil {
 		logger.Error("[MergeStreamFunc] YoMo-Zipper sent data to `stream-fn` failed.", "stream-fn", name, "err", err)
+		pipelineMetrics.failed(errorSend)
+		pipelineTimelines.record(StepFailed, name, data, err.Error())
 		// cancel the current session when error.
 		cancel()
 		return
@@ -154,8 +294,132 @@ func sendDataToStreamFn(name string, session quic.Session, cancel CancelFunc, da
 	logger.Debug("[MergeStreamFunc] YoMo-Zipper sent data to `stream-fn`.", "stream-fn", name)
 }
 
+// errStreamedTooLarge is returned when the streamed carriage which is buffered exceeds the max frame size.
+var errStreamedTooLarge = errors.New("the streamed carriage exceeds the max frame size")
+
+// materialize reads the streamed carriage into memory up to maxSize, so the data goes where the carriage can't be
+// streamed, e.g. the local stream functions and the stream functions of the version 1. The encrypted carriage
+// can't be buffered, since it's sealed in segments which only the streamed frame carries.
+func materialize(data *frame.DataFrame, maxSize int) error {
+	if !data.Streamed() {
+		return nil
+	}
+	defer core.CloseCarriage(data)
+
+	if data.KeyID() != "" {
+		return errors.New("the encrypted streamed carriage can't be buffered")
+	}
+	if maxSize <= 0 {
+		maxSize = core.DefaultMaxFrameSize
+	}
+	// one more byte is read to tell if the carriage exceeds the max size.
+	buf, err := ioutil.ReadAll(io.LimitReader(data.CarriageReader(), int64(maxSize)+1))
+	if err != nil {
+		return err
+	}
+	if len(buf) > maxSize {
+		return errStreamedTooLarge
+	}
+
+	data.SetCarriage(data.GetDataTagID(), buf)
+	return nil
+}
+
+// countFrame counts the frame exchanged with the client of session.
+func countFrame(session quic.Session) {
+	if session == nil {
+		return
+	}
+	if frames, ok := sessionFrames.Load(session); ok {
+		atomic.AddUint64(frames.(*uint64), 1)
+	}
+}
+
+// codecOf returns the compression codec negotiated with the session, it's nil if no codec is negotiated.
+func codecOf(session quic.Session) compress.Codec {
+	if codec, ok := sessionCodecs.Load(session); ok {
+		return codec.(compress.Codec)
+	}
+	return nil
+}
+
+// versionOf returns the version of wire protocol negotiated with the session, it's the current version if the
+// session isn't a handshaked client, e.g. in tests.
+func versionOf(session quic.Session) uint32 {
+	if version, ok := sessionVersions.Load(session); ok {
+		return version.(uint32)
+	}
+	return frame.Version
+}
+
+// frameFor returns the data in the layout of the version negotiated with the stream function of session, and
+// compresses it by the negotiated codec. The data is shared by the stream functions so it's not modified.
+func frameFor(session quic.Session, data *frame.DataFrame) (*frame.DataFrame, error) {
+	data, err := frame.Downgrade(data, versionOf(session))
+	if err != nil {
+		return nil, err
+	}
+
+	codec := codecOf(session)
+	if codec == nil {
+		return data, nil
+	}
+
+	compressed, err := core.CompressFrame(data, codec, compress.DefaultThreshold)
+	if err != nil {
+		logger.Error("[MergeStreamFunc] compress the data failed, it's sent without compression.", "err", err)
+		return data, nil
+	}
+	return compressed, nil
+}
+
+// encodeFor encodes the frame for the stream function of session, the frame larger than chunkSize is split into
+// chunks if the version negotiated with the session supports them, the stream function reassembles them.
+func encodeFor(session quic.Session, f *frame.DataFrame, chunkSize int) []byte {
+	if !frame.Supports(frame.TagOfChunkFrame, versionOf(session)) {
+		return f.Encode()
+	}
+
+	var buf []byte
+	for _, c := range core.SplitFrame(f, chunkSize) {
+		buf = append(buf, c.Encode()...)
+	}
+	return buf
+}
+
+// sendDataToShadowFn send a copy of data to the shadow of `stream-fn`, the response of shadow will be discarded.
+func sendDataToShadowFn(name string, sfn streamFuncWithCancel, data *frame.DataFrame, chunkSize int) {
+	if sfn.session == nil {
+		sfn.cancel()
+		return
+	}
+
+	stream, err := sfn.session.OpenUniStream()
+	if err != nil {
+		logger.Error("[MergeStreamFunc] session.OpenUniStream of shadow failed", "stream-fn", name, "addr", sfn.addr, "err", err)
+		sfn.cancel()
+		return
+	}
+
+	f, err := frameFor(sfn.session, data)
+	if err != nil {
+		logger.Error("[MergeStreamFunc] the data can't be sent to the shadow of `stream-fn`.", "stream-fn", name, "addr", sfn.addr, "err", err)
+		stream.CancelWrite(0)
+		return
+	}
+	_, err = stream.Write(encodeFor(sfn.session, f, chunkSize))
+	stream.Close()
+	if err != nil {
+		logger.Error("[MergeStreamFunc] YoMo-Zipper sent data to the shadow of `stream-fn` failed.", "stream-fn", name, "addr", sfn.addr, "err", err)
+		sfn.cancel()
+		return
+	}
+
+	logger.Debug("[MergeStreamFunc] YoMo-Zipper sent data to the shadow of `stream-fn`.", "stream-fn", name, "addr", sfn.addr)
+}
+
 // receiveResponseFromStreamFn receives the response from `stream-fn`.
-func receiveResponseFromStreamFn(ctx context.Context, sfn GetStreamFunc, next chan *frame.DataFrame) {
+func receiveResponseFromStreamFn(ctx context.Context, sfn GetStreamFunc, next chan *frame.DataFrame, conf *WorkflowConfig) {
 	name, _ := sfn()
 	ch, _ := newStreamFuncSessionCache.LoadOrStore(name, make(chan quic.Session, 5))
 
@@ -183,15 +447,16 @@ func receiveResponseFromStreamFn(ctx context.Context, sfn GetStreamFunc, next ch
 						break LOOP_ACCP_STREAM
 					}
 
-					go readDataFromStreamFn(ctx, name, stream, next)
+					go readDataFromStreamFn(ctx, name, session, stream, next, conf)
 				}
 			}()
 		}
 	}
 }
 
-// readDataFromStreamFn reads the data from `stream-fn`.
-func readDataFromStreamFn(ctx context.Context, name string, stream quic.ReceiveStream, next chan *frame.DataFrame) {
+// readDataFromStreamFn reads the data from `stream-fn`, the chunks are reassembled up to the max frame size.
+func readDataFromStreamFn(ctx context.Context, name string, session quic.Session, stream quic.ReceiveStream, next chan *frame.DataFrame, conf *WorkflowConfig) {
+	reassembler := core.NewReassembler(conf.MaxFrameSize)
 	for {
 		select {
 		case <-ctx.Done():
@@ -202,12 +467,28 @@ func readDataFromStreamFn(ctx context.Context, name string, stream quic.ReceiveS
 			logger.Printf("💚 waiting read next..")
 
 			// 开始接收数据
-			f, err := core.ParseFrame(stream)
+			f, err := core.ParseFrameWithLimit(stream, conf.MaxFrameSize)
+			if errors.Is(err, frame.ErrChecksumMismatch) && skipCorruption(name, stream, err, conf) {
+				continue
+			}
 			if err != nil {
 				logger.Debug("[MergeStreamFunc] YoMo-Zipper received data from `stream-fn` failed.", "stream-fn", name, "err", err)
 				return
 			}
 
+			if chunk, ok := f.(*frame.ChunkFrame); ok {
+				dataFrame, err := reassembler.Push(chunk)
+				if err != nil {
+					logger.Error("[MergeStreamFunc] reassemble the data from `stream-fn` failed.", "stream-fn", name, "err", err)
+					pipelineMetrics.failed(errorReceive)
+					return
+				}
+				if dataFrame == nil {
+					continue
+				}
+				f = dataFrame
+			}
+
 			logger.Debug("[MergeStreamFunc] YoMo-Zipper received data from `stream-fn`.", "stream-fn", name)
 
 			// 完成接收
@@ -217,6 +498,15 @@ func readDataFromStreamFn(ctx context.Context, name string, stream quic.ReceiveS
 			}
 
 			data := f.(*frame.DataFrame)
+			if err := core.DecompressFrame(data, codecOf(session), conf.MaxFrameSize); err != nil {
+				logger.Error("[MergeStreamFunc] decompress the data from `stream-fn` failed.", "stream-fn", name, "err", err)
+				pipelineMetrics.failed(errorDecompress)
+				return
+			}
+			if latency, ok := pipelineMetrics.responded(name, data.TransactionID()); ok {
+				pipelineEvents.frameEvent(EventStageLatency, name, data, latency)
+			}
+			pipelineTimelines.record(StepResponded, name, data, "")
 
 			logger.Printf("💚 receive complete data(%d), duration=%d", len(data.GetCarriage()), time.Since(t1).Milliseconds())
 
@@ -227,7 +517,7 @@ func readDataFromStreamFn(ctx context.Context, name string, stream quic.ReceiveS
 			// }
 
 			// tracing
-			span := tracing.NewSpanFromData(string(data.GetCarriage()), name, "zipper-receive-from-"+name)
+			span := tracing.NewSpanFromFrame(data, name, "zipper-receive-from-"+name)
 			if span != nil {
 				defer span.End()
 			}
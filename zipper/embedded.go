@@ -0,0 +1,207 @@
+package zipper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/yomorun/yomo/internal/frame"
+	"github.com/yomorun/yomo/logger"
+)
+
+// ErrEmbeddedClosed is returned by Write when the embedded YoMo-Zipper is closed.
+var ErrEmbeddedClosed = errors.New("[zipper] the embedded YoMo-Zipper is closed")
+
+// LocalHandler is a stream function which runs in the process of YoMo-Zipper on the data with its tag, each of the
+// results is passed to the next function in its own frame, the data is dropped if there's no result. The data is
+// handled one by one in the order it arrives.
+type LocalHandler func(ctx context.Context, tag byte, data []byte) ([]LocalResult, error)
+
+// LocalResult is a result of LocalHandler, it's observed by the next functions by its tag.
+type LocalResult struct {
+	Tag  byte
+	Data []byte
+}
+
+// pipeLocalHandler runs the local handler on the data from upstream, the results are sent to next.
+func pipeLocalHandler(ctx context.Context, upstream chan *frame.DataFrame, name string, fn LocalHandler, maxSize int) chan *frame.DataFrame {
+	next := make(chan *frame.DataFrame, bufferSize)
+
+	go func() {
+		defer close(next)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case item, ok := <-upstream:
+				if !ok {
+					return
+				}
+
+				// the encrypted frames can't be read in YoMo-Zipper, they pass through the local handler.
+				if !subscribedTo(name, item) || !sampled(name) || item.KeyID() != "" {
+					next <- item
+					continue
+				}
+				for _, data := range runLocalHandler(ctx, name, fn, item, maxSize) {
+					select {
+					case next <- data:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	return next
+}
+
+// runLocalHandler runs the local handler on the frame, returns the frames of results, it's empty if the data was
+// dropped.
+func runLocalHandler(ctx context.Context, name string, fn LocalHandler, data *frame.DataFrame, maxSize int) []*frame.DataFrame {
+	if err := materialize(data, maxSize); err != nil {
+		logger.Error("[zipper] the streamed carriage can't be read by the local handler.", "stream-fn", name, "TransactionID", data.TransactionID(), "err", err)
+		return nil
+	}
+
+	pipelineTimelines.record(StepRouted, name, data, "")
+	results, err := fn(ctx, data.GetDataTagID(), data.GetCarriage())
+	if err != nil {
+		logger.Error("[zipper] the local handler got an error.", "stream-fn", name, "TransactionID", data.TransactionID(), "err", err)
+		pipelineTimelines.record(StepFailed, name, data, err.Error())
+		return nil
+	}
+	if len(results) == 0 {
+		logger.Debug("[zipper] the local handler returns no result.", "stream-fn", name, "TransactionID", data.TransactionID())
+		pipelineTimelines.record(StepDropped, name, data, "no result")
+		return nil
+	}
+
+	frames := make([]*frame.DataFrame, 0, len(results))
+	for _, result := range results {
+		// each result is sent in a copy of the frame, it's observed by its own tag only.
+		f := data.Clone()
+		f.SetCarriage(result.Tag, result.Data)
+		f.SetExtraTags()
+		frames = append(frames, f)
+	}
+	pipelineTimelines.record(StepResponded, name, data, "")
+	return frames
+}
+
+// Embedded runs the workflow of YoMo-Zipper in the process without QUIC, the data written by Write flows through
+// the stream functions which run in the process, e.g. the local handlers, the local stream functions, the
+// serverless functions and the WASM modules, and the output of the workflow is passed to the output handler. It's
+// for the unit tests, the local development and the small edge gateways where the network hops are unnecessary.
+type Embedded struct {
+	sequence uint64 // sequence is the first field, so it's 64-bit aligned for the atomic operations.
+	handler  *quicHandler
+	sources  chan *frame.DataFrame
+	onOutput func(tag byte, data []byte)
+	cancel   context.CancelFunc
+	done     chan struct{}
+	closed   int32
+}
+
+// NewEmbedded creates and starts the embedded YoMo-Zipper of the workflow, all stream functions of the workflow
+// must run in the process. The namespaces, the auth and the downstream YoMo-Zippers don't apply to it.
+func NewEmbedded(conf *WorkflowConfig, opts ...Option) (*Embedded, error) {
+	options := newOptions(opts...)
+	handler := newServerHandler(conf, "")
+	handler.localFuncs = options.localFuncs
+	handler.localHandlers = options.handlers
+	handler.features = NewFeatures(conf.Features)
+
+	var err error
+	if handler.invokers, err = newInvokers(conf.Functions); err != nil {
+		return nil, err
+	}
+	if handler.wasmFuncs, err = newWasmFuncs(conf.Functions); err != nil {
+		return nil, err
+	}
+	for _, app := range conf.Functions {
+		_, inv := handler.invokers[app.Name]
+		_, wasm := handler.wasmFuncs[app.Name]
+		if !handler.isLocal(app.Name) && !inv && !wasm {
+			return nil, fmt.Errorf("[zipper] the stream function %s doesn't run in the process of the embedded YoMo-Zipper", app.Name)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e := &Embedded{
+		handler:  handler,
+		sources:  make(chan *frame.DataFrame, bufferSize),
+		onOutput: options.onOutput,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	go e.run(ctx)
+	return e, nil
+}
+
+// run pipes the data of sources through the workflow until the ctx is done.
+func (e *Embedded) run(ctx context.Context) {
+	defer close(e.done)
+
+	for data := range e.handler.pipe(ctx, e.sources) {
+		// the streamed carriage is buffered for the output handler.
+		if err := materialize(data, e.handler.config().MaxFrameSize); err != nil {
+			logger.Error("[zipper] drop the streamed carriage of output.", "TransactionID", data.TransactionID(), "err", err)
+			continue
+		}
+		e.handler.handleOutput(data)
+		if e.onOutput != nil {
+			e.onOutput(data.GetDataTagID(), data.GetCarriage())
+		}
+	}
+}
+
+// Write writes the data of the source to the workflow, the first tag is the tag of payload, and the data is
+// observed by all tags. The data is dropped by the load shedding if the workflow is overloaded.
+func (e *Embedded) Write(source string, data []byte, tags ...byte) error {
+	if atomic.LoadInt32(&e.closed) == 1 {
+		return ErrEmbeddedClosed
+	}
+	if len(tags) == 0 {
+		return errors.New("[zipper] the data tag is required")
+	}
+	if sources := e.handler.config().Sources; len(sources) > 0 && !containsApp(sources, source) {
+		return fmt.Errorf("[zipper] the source %s is not in the workflow", source)
+	}
+
+	tid := strconv.FormatInt(time.Now().UnixNano(), 10) + "-" + strconv.FormatUint(atomic.AddUint64(&e.sequence, 1), 10)
+	f := frame.NewDataFrame(tid)
+	f.SetCarriage(tags[0], data)
+	f.SetExtraTags(tags[1:]...)
+	f.SetTimestamp(time.Now())
+	logger.Debug("[zipper] receive data frame from embedded source.", "source", source, "TransactionID", tid)
+	if !e.handler.shedder.push(e.sources, f) {
+		return fmt.Errorf("[zipper] the data of source %s is dropped by the load shedding", source)
+	}
+	return nil
+}
+
+// Close stops the workflow, the data in flight is discarded.
+func (e *Embedded) Close() error {
+	if !atomic.CompareAndSwapInt32(&e.closed, 0, 1) {
+		return nil
+	}
+	e.cancel()
+	<-e.done
+	return nil
+}
+
+// containsApp indicates if the app of name is in apps.
+func containsApp(apps []App, name string) bool {
+	for _, app := range apps {
+		if app.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package zipper
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/internal/frame"
+)
+
+func TestPipeLocalHandler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	split := func(_ context.Context, tag byte, data []byte) ([]LocalResult, error) {
+		switch string(data) {
+		case "drop":
+			return nil, nil
+		case "fail":
+			return nil, errors.New("failed")
+		}
+		results := make([]LocalResult, 0)
+		for _, word := range strings.Fields(string(data)) {
+			results = append(results, LocalResult{Tag: tag + 1, Data: []byte(word)})
+		}
+		return results, nil
+	}
+
+	upstream := make(chan *frame.DataFrame, 3)
+	for _, s := range []string{"drop", "fail", "hello yomo"} {
+		f := frame.NewDataFrame(s)
+		f.SetCarriage(0x10, []byte(s))
+		f.SetExtraTags(0x20)
+		upstream <- f
+	}
+	close(upstream)
+
+	results := make([]string, 0)
+	for f := range pipeLocalHandler(ctx, upstream, "local-handler-split", split, 0) {
+		assert.Equal(t, []byte{0x11}, f.Tags())
+		assert.Equal(t, "hello yomo", f.TransactionID())
+		results = append(results, string(f.GetCarriage()))
+	}
+	assert.Equal(t, []string{"hello", "yomo"}, results)
+}
+
+func TestEmbedded(t *testing.T) {
+	conf := &WorkflowConfig{
+		Name: "embedded",
+		Workflow: Workflow{
+			Sources: []App{{Name: "embedded-sensor"}},
+			Functions: []App{
+				{Name: "embedded-double", Tags: []byte{0x33}},
+				{Name: "embedded-upper"},
+			},
+		},
+	}
+	outputs := make(chan string, 10)
+	e, err := NewEmbedded(conf,
+		WithLocalHandler("embedded-double", func(_ context.Context, _ byte, data []byte) ([]LocalResult, error) {
+			return []LocalResult{{Tag: 0x34, Data: data}, {Tag: 0x34, Data: data}}, nil
+		}),
+		WithLocalStreamFunc("embedded-upper", func(data []byte) ([]byte, error) {
+			return []byte(strings.ToUpper(string(data))), nil
+		}),
+		WithOutputHandler(func(tag byte, data []byte) {
+			outputs <- string([]byte{tag}) + string(data)
+		}),
+	)
+	assert.NoError(t, err)
+
+	assert.NoError(t, e.Write("embedded-sensor", []byte("a"), 0x33))
+	// the data of other tags passes through the function.
+	assert.NoError(t, e.Write("embedded-sensor", []byte("b"), 0x35))
+	received := make([]string, 0)
+	for len(received) < 3 {
+		select {
+		case output := <-outputs:
+			received = append(received, output)
+		case <-time.After(time.Second):
+			t.Fatal("the output of workflow isn't received")
+		}
+	}
+	assert.ElementsMatch(t, []string{"\x34A", "\x34A", "\x35B"}, received)
+
+	assert.EqualError(t, e.Write("unknown", []byte("a"), 0x33), "[zipper] the source unknown is not in the workflow")
+	assert.EqualError(t, e.Write("embedded-sensor", []byte("a")), "[zipper] the data tag is required")
+	assert.NoError(t, e.Close())
+	assert.Equal(t, ErrEmbeddedClosed, e.Write("embedded-sensor", []byte("a"), 0x33))
+
+	// the stream functions must run in the process.
+	_, err = NewEmbedded(&WorkflowConfig{Name: "embedded", Workflow: Workflow{Functions: []App{{Name: "embedded-remote"}}}})
+	assert.EqualError(t, err, "[zipper] the stream function embedded-remote doesn't run in the process of the embedded YoMo-Zipper")
+}
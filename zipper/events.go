@@ -0,0 +1,210 @@
+package zipper
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yomorun/yomo/internal/frame"
+)
+
+// the types of events in the feed of data flow.
+const (
+	EventSessionJoined = "session_joined"
+	EventSessionLeft   = "session_left"
+	EventFrameRouted   = "frame_routed"
+	EventStageLatency  = "stage_latency"
+)
+
+// eventBuffer is the count of events which are buffered for a slow subscriber, the events are dropped
+// for it when its buffer is full, so the subscribers never block the pipeline.
+const eventBuffer = 256
+
+// eventKeepAlive is the interval of the comments which keep the idle feed alive through the proxies.
+const eventKeepAlive = 15 * time.Second
+
+// Event is an event of data flow in YoMo-Zipper, it's emitted to the subscribers of the feed.
+type Event struct {
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	// Name is the name of the client for the session events.
+	Name string `json:"name,omitempty"`
+	// ConnType is the type of the client for the session events.
+	ConnType string `json:"conn_type,omitempty"`
+	// Addr is the address of the client for the session events.
+	Addr string `json:"addr,omitempty"`
+	// Stage is the stream function which the frame is routed to, or which responded it.
+	Stage string `json:"stage,omitempty"`
+	// Tag is the data tag of the frame for the frame events.
+	Tag *byte `json:"tag,omitempty"`
+	// TransactionID is the transaction of the frame for the frame events.
+	TransactionID string `json:"tid,omitempty"`
+	// Latency is the seconds from routing the frame to the stage till its response.
+	Latency float64 `json:"latency,omitempty"`
+}
+
+// pipelineEvents is the feed of data flow of all pipelines of YoMo-Zipper.
+var pipelineEvents = newEventBus()
+
+// eventBus fans out the events to the subscribers, the events are not built if nobody subscribes.
+type eventBus struct {
+	mutex       sync.RWMutex
+	subscribers map[chan Event]struct{}
+	count       int32
+	dropped     uint64
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{subscribers: make(map[chan Event]struct{})}
+}
+
+// active indicates if anyone subscribes to the events.
+func (b *eventBus) active() bool {
+	return atomic.LoadInt32(&b.count) > 0
+}
+
+// subscribe returns the channel of events and the function to unsubscribe.
+func (b *eventBus) subscribe() (chan Event, func()) {
+	ch := make(chan Event, eventBuffer)
+	b.mutex.Lock()
+	b.subscribers[ch] = struct{}{}
+	atomic.AddInt32(&b.count, 1)
+	b.mutex.Unlock()
+
+	return ch, func() {
+		b.mutex.Lock()
+		defer b.mutex.Unlock()
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			atomic.AddInt32(&b.count, -1)
+		}
+	}
+}
+
+// publish sends the event to the subscribers without blocking.
+func (b *eventBus) publish(e Event) {
+	if !b.active() {
+		return
+	}
+	e.Time = time.Now()
+
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	for ch := range b.subscribers {
+		select {
+		case ch <- e:
+		default:
+			atomic.AddUint64(&b.dropped, 1)
+		}
+	}
+}
+
+// sessionEvent emits the event of the client joining or leaving.
+func (b *eventBus) sessionEvent(typ string, c *Conn) {
+	if !b.active() {
+		return
+	}
+	b.publish(Event{Type: typ, Name: c.Conn.Name, ConnType: c.Conn.Type.String(), Addr: c.Addr})
+}
+
+// frameEvent emits the event of the frame at the stage.
+func (b *eventBus) frameEvent(typ string, stage string, data *frame.DataFrame, latency time.Duration) {
+	if !b.active() {
+		return
+	}
+	tag := data.GetDataTagID()
+	b.publish(Event{Type: typ, Stage: stage, Tag: &tag, TransactionID: data.TransactionID(), Latency: latency.Seconds()})
+}
+
+// eventFilter selects the events of a subscriber by the query, e.g. "?types=frame_routed&tags=0x33,52".
+// The session events have no tag, so they're not filtered by tags.
+type eventFilter struct {
+	types map[string]bool
+	tags  map[byte]bool
+}
+
+func parseEventFilter(r *http.Request) (eventFilter, error) {
+	f := eventFilter{}
+	if v := r.URL.Query().Get("types"); v != "" {
+		f.types = make(map[string]bool)
+		for _, typ := range strings.Split(v, ",") {
+			f.types[strings.TrimSpace(typ)] = true
+		}
+	}
+	if v := r.URL.Query().Get("tags"); v != "" {
+		f.tags = make(map[byte]bool)
+		for _, s := range strings.Split(v, ",") {
+			tag, err := strconv.ParseUint(strings.TrimSpace(s), 0, 8)
+			if err != nil {
+				return f, fmt.Errorf("invalid tag %q", s)
+			}
+			f.tags[byte(tag)] = true
+		}
+	}
+	return f, nil
+}
+
+func (f eventFilter) matches(e Event) bool {
+	if f.types != nil && !f.types[e.Type] {
+		return false
+	}
+	if f.tags != nil && e.Tag != nil && !f.tags[*e.Tag] {
+		return false
+	}
+	return true
+}
+
+// serveEvents is the admin API of the feed of data flow.
+// GET /events streams the events as Server-Sent Events, they're filtered by the query of types and tags.
+func serveEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "streaming is not supported"})
+		return
+	}
+	filter, err := parseEventFilter(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	events, unsubscribe := pipelineEvents.subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ticker := time.NewTicker(eventKeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+			flusher.Flush()
+		case e := <-events:
+			if !filter.matches(e) {
+				continue
+			}
+			buf, err := json.Marshal(e)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, buf)
+			flusher.Flush()
+		}
+	}
+}
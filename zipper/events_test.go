@@ -0,0 +1,75 @@
+package zipper
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/internal/frame"
+)
+
+func TestEventFilter(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/events?types=frame_routed,session_joined&tags=0x33,52", nil)
+	f, err := parseEventFilter(r)
+	assert.NoError(t, err)
+
+	tag, other := byte(0x33), byte(0x10)
+	assert.True(t, f.matches(Event{Type: EventFrameRouted, Tag: &tag}))
+	assert.False(t, f.matches(Event{Type: EventFrameRouted, Tag: &other}))
+	assert.False(t, f.matches(Event{Type: EventStageLatency, Tag: &tag}))
+	// the session events are not filtered by tags.
+	assert.True(t, f.matches(Event{Type: EventSessionJoined}))
+
+	_, err = parseEventFilter(httptest.NewRequest(http.MethodGet, "/events?tags=256", nil))
+	assert.Error(t, err)
+}
+
+func TestEventBusSlowSubscriber(t *testing.T) {
+	b := newEventBus()
+	// the events are not published without subscribers.
+	b.publish(Event{Type: EventFrameRouted})
+	assert.Equal(t, uint64(0), b.dropped)
+
+	ch, unsubscribe := b.subscribe()
+	for i := 0; i < eventBuffer+1; i++ {
+		b.publish(Event{Type: EventFrameRouted})
+	}
+	assert.Len(t, ch, eventBuffer)
+	assert.Equal(t, uint64(1), b.dropped)
+
+	unsubscribe()
+	assert.False(t, b.active())
+}
+
+func TestServeEvents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(serveEvents))
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "?tags=0x33")
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+	// the subscriber is registered before the headers are flushed.
+	data := frame.NewDataFrame("skipped")
+	data.SetCarriage(0x10, []byte("yomo"))
+	pipelineEvents.frameEvent(EventFrameRouted, "fn", data, 0)
+	data = frame.NewDataFrame("tid")
+	data.SetCarriage(0x33, []byte("yomo"))
+	pipelineEvents.frameEvent(EventStageLatency, "fn", data, 10*time.Millisecond)
+
+	reader := bufio.NewReader(res.Body)
+	line, err := reader.ReadString('\n')
+	assert.NoError(t, err)
+	assert.Equal(t, "event: stage_latency\n", line)
+	line, err = reader.ReadString('\n')
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(line, "data: {"))
+	assert.Contains(t, line, `"tid":"tid"`)
+	assert.Contains(t, line, `"tag":51`)
+	assert.Contains(t, line, `"latency":0.01`)
+}
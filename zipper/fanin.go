@@ -0,0 +1,127 @@
+package zipper
+
+import (
+	"context"
+	"sync"
+
+	"github.com/yomorun/yomo/core/quic"
+	"github.com/yomorun/yomo/internal/frame"
+)
+
+// fanIn merges the frames from many sources into one pipeline by the smooth weighted round-robin,
+// each source has its own queue, so a chatty source only fills its own queue and can't starve the quieter ones.
+type fanIn struct {
+	mutex   sync.Mutex
+	weights map[string]int         // the weights of sources by name, the default weight is 1.
+	queues  map[string]*fanInQueue // the queues of sources by name.
+	ready   chan struct{}          // ready is notified when a frame is pushed to the queues.
+	out     chan *frame.DataFrame
+}
+
+// fanInQueue is the queue of a source.
+type fanInQueue struct {
+	frames  chan *frame.DataFrame
+	weight  int
+	current int // current is the current weight of smooth weighted round-robin.
+}
+
+// newFanIn returns the fan-in of sources if any source has weight in the workflow config, otherwise returns nil.
+func newFanIn(sources []App) *fanIn {
+	weights := make(map[string]int)
+	for _, app := range sources {
+		if app.Weight > 0 {
+			weights[app.Name] = app.Weight
+		}
+	}
+	if len(weights) == 0 {
+		return nil
+	}
+
+	return &fanIn{
+		weights: weights,
+		queues:  make(map[string]*fanInQueue),
+		ready:   make(chan struct{}, 1),
+		out:     make(chan *frame.DataFrame, bufferSize),
+	}
+}
+
+// queue returns the queue of the source.
+func (f *fanIn) queue(name string) *fanInQueue {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	q, ok := f.queues[name]
+	if !ok {
+		weight := f.weights[name]
+		if weight <= 0 {
+			weight = 1
+		}
+		q = &fanInQueue{
+			frames: make(chan *frame.DataFrame, bufferSize),
+			weight: weight,
+		}
+		f.queues[name] = q
+	}
+	return q
+}
+
+// read the frames from the stream of source to its queue until the stream is closed.
+func (f *fanIn) read(ctx context.Context, name string, session quic.Session, stream quic.Stream, shedder *shedder, conf *WorkflowConfig) {
+	q := f.queue(name)
+	for data := range readDataFromSource(ctx, name, session, stream, shedder, conf) {
+		if !shedder.push(q.frames, data) {
+			continue
+		}
+		select {
+		case f.ready <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// run schedules the frames in queues to the output until ctx is done.
+func (f *fanIn) run(ctx context.Context) {
+	for {
+		data, ok := f.next()
+		if !ok {
+			select {
+			case <-ctx.Done():
+				return
+			case <-f.ready:
+				continue
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case f.out <- data:
+		}
+	}
+}
+
+// next picks a frame from the non-empty queue with the highest current weight, returns false if all queues are empty.
+func (f *fanIn) next() (*frame.DataFrame, bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	var best *fanInQueue
+	total := 0
+	for _, q := range f.queues {
+		if len(q.frames) == 0 {
+			continue
+		}
+		q.current += q.weight
+		total += q.weight
+		if best == nil || q.current > best.current {
+			best = q
+		}
+	}
+	if best == nil {
+		return nil, false
+	}
+	best.current -= total
+
+	// the queue is not empty because run is the only consumer.
+	return <-best.frames, true
+}
@@ -0,0 +1,54 @@
+package zipper
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/internal/frame"
+)
+
+func TestNewFanIn(t *testing.T) {
+	assert.Nil(t, newFanIn([]App{{Name: "source"}}))
+	assert.NotNil(t, newFanIn([]App{{Name: "source", Weight: 2}}))
+}
+
+func TestFanInWeights(t *testing.T) {
+	f := newFanIn([]App{{Name: "gateway", Weight: 1}, {Name: "device", Weight: 3}})
+
+	gateway := f.queue("gateway")
+	device := f.queue("device")
+	for i := 0; i < 40; i++ {
+		gateway.frames <- frame.NewDataFrame("gateway")
+		device.frames <- frame.NewDataFrame("device")
+	}
+
+	counts := make(map[string]int)
+	for i := 0; i < 40; i++ {
+		data, ok := f.next()
+		assert.True(t, ok)
+		counts[data.TransactionID()]++
+	}
+	assert.Equal(t, 10, counts["gateway"])
+	assert.Equal(t, 30, counts["device"])
+}
+
+func TestFanInRun(t *testing.T) {
+	f := newFanIn([]App{{Name: "source", Weight: 1}})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go f.run(ctx)
+
+	// the unknown source has the default weight.
+	q := f.queue("unknown")
+	q.frames <- frame.NewDataFrame("1")
+	f.ready <- struct{}{}
+
+	select {
+	case data := <-f.out:
+		assert.Equal(t, "1", data.TransactionID())
+	case <-time.After(time.Second):
+		t.Fatal("the frame is not scheduled")
+	}
+}
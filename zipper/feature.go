@@ -0,0 +1,135 @@
+package zipper
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// the experimental features which can be gated by feature flags.
+const (
+	// FeatureDatagram accepts the DataFrames which are sent in QUIC DATAGRAM frames.
+	FeatureDatagram = "datagram"
+	// FeatureBatching coalesces the DataFrames sent to the same session of stream function into one stream.
+	FeatureBatching = "batching"
+	// FeatureLockFreeQueue queues the DataFrames received in datagrams and partially reliable streams in a lock-free
+	// ring instead of the channel, the frames are dropped when the ring is full whatever the shedding policy.
+	FeatureLockFreeQueue = "lockfree_queue"
+)
+
+// defaultFeatures are the states of features when they're not configured.
+var defaultFeatures = map[string]bool{
+	FeatureDatagram:      true,
+	FeatureBatching:      false,
+	FeatureLockFreeQueue: false,
+}
+
+// FeatureFlag represents the state of an experimental feature.
+type FeatureFlag struct {
+	Name    string `yaml:"name" json:"name"`
+	Enabled bool   `yaml:"enabled" json:"enabled"`
+	// Percentage is the percentage of traffic which the feature is enabled for, in the range [0, 100].
+	// The feature is enabled for all traffic if it's 0 and Enabled is true.
+	Percentage float64 `yaml:"percentage,omitempty" json:"percentage,omitempty"`
+}
+
+// Features holds the feature flags which can be changed at runtime.
+type Features struct {
+	mu    sync.RWMutex
+	flags map[string]FeatureFlag
+}
+
+// NewFeatures creates the feature flags by config.
+func NewFeatures(flags []FeatureFlag) *Features {
+	f := &Features{
+		flags: make(map[string]FeatureFlag),
+	}
+	for _, flag := range flags {
+		f.flags[flag.Name] = flag
+	}
+	return f
+}
+
+// Enabled indicates if the feature is enabled for the traffic with key, e.g. the TransactionID.
+// The same key always gets the same result when the percentage is not changed. The features are in their
+// default states if f is nil.
+func (f *Features) Enabled(name string, key string) bool {
+	if f == nil {
+		return defaultFeatures[name]
+	}
+
+	f.mu.RLock()
+	flag, ok := f.flags[name]
+	f.mu.RUnlock()
+
+	if !ok {
+		return defaultFeatures[name]
+	}
+	if !flag.Enabled {
+		return false
+	}
+	if flag.Percentage <= 0 || flag.Percentage >= 100 {
+		return true
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return float64(h.Sum32()%10000) < flag.Percentage*100
+}
+
+// Set the feature flag.
+func (f *Features) Set(flag FeatureFlag) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.flags[flag.Name] = flag
+}
+
+// List the configured feature flags, sorted by name.
+func (f *Features) List() []FeatureFlag {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	flags := make([]FeatureFlag, 0, len(f.flags))
+	for _, flag := range f.flags {
+		flags = append(flags, flag)
+	}
+	sort.Slice(flags, func(i, j int) bool {
+		return flags[i].Name < flags[j].Name
+	})
+	return flags
+}
+
+// ServeHTTP is the admin API of feature flags.
+// GET /features lists the feature flags, PUT /features/{name} sets the feature flag by JSON body.
+func (f *Features) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/features"), "/")
+
+	switch {
+	case r.Method == http.MethodGet && name == "":
+		writeJSON(w, http.StatusOK, f.List())
+	case r.Method == http.MethodPut && name != "":
+		var flag FeatureFlag
+		if err := json.NewDecoder(r.Body).Decode(&flag); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		if flag.Percentage < 0 || flag.Percentage > 100 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "percentage must be in the range [0, 100]"})
+			return
+		}
+		flag.Name = name
+		f.Set(flag)
+		writeJSON(w, http.StatusOK, flag)
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
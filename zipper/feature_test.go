@@ -0,0 +1,70 @@
+package zipper
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeaturesEnabled(t *testing.T) {
+	f := NewFeatures([]FeatureFlag{{Name: FeatureBatching, Enabled: true, Percentage: 30}})
+
+	// the default states.
+	assert.True(t, f.Enabled(FeatureDatagram, "tid"))
+	assert.False(t, f.Enabled("unknown", "tid"))
+
+	n := 0
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("tid-%d", i)
+		if f.Enabled(FeatureBatching, key) {
+			n++
+		}
+		// the same key gets the same result.
+		assert.Equal(t, f.Enabled(FeatureBatching, key), f.Enabled(FeatureBatching, key))
+	}
+	assert.InDelta(t, 300, n, 60)
+
+	f.Set(FeatureFlag{Name: FeatureDatagram})
+	assert.False(t, f.Enabled(FeatureDatagram, "tid"))
+
+	// the nil flags are in the default states.
+	var none *Features
+	assert.True(t, none.Enabled(FeatureDatagram, "tid"))
+	assert.False(t, none.Enabled(FeatureBatching, "tid"))
+}
+
+func TestFeaturesAdminAPI(t *testing.T) {
+	h := newServerHandler(&WorkflowConfig{AdminToken: "secret"}, "")
+	h.features = NewFeatures(nil)
+	server := httptest.NewServer(newAdminMux(h))
+	defer server.Close()
+
+	// the flags can't be toggled without the admin token.
+	req, _ := http.NewRequest(http.MethodPut, server.URL+"/features/datagram", strings.NewReader(`{"enabled":false}`))
+	res, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	res.Body.Close()
+
+	req, _ = http.NewRequest(http.MethodPut, server.URL+"/features/datagram", strings.NewReader(`{"enabled":true,"percentage":50}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	res, err = http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	res.Body.Close()
+
+	req, _ = http.NewRequest(http.MethodGet, server.URL+"/features", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	res, err = http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer res.Body.Close()
+
+	var flags []FeatureFlag
+	assert.NoError(t, json.NewDecoder(res.Body).Decode(&flags))
+	assert.Equal(t, []FeatureFlag{{Name: FeatureDatagram, Enabled: true, Percentage: 50}}, flags)
+}
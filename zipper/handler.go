@@ -1,22 +1,29 @@
 package zipper
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/yomorun/yomo/core/quic"
 	"github.com/yomorun/yomo/internal/core"
 	"github.com/yomorun/yomo/internal/frame"
 	"github.com/yomorun/yomo/logger"
+	"github.com/yomorun/yomo/zipper/tracing"
 )
 
 type streamFuncWithCancel struct {
 	addr    string
 	session quic.Session
 	cancel  CancelFunc
+	shadow  bool // shadow indicates the stream function only receives a copy of data, its responses are discarded.
 }
 
 type (
@@ -32,30 +39,70 @@ type (
 
 // newServerHandler inits a new ServerHandler
 func newServerHandler(conf *WorkflowConfig, meshConfURL string) *quicHandler {
-	return &quicHandler{
-		serverlessConfig: conf,
-		meshConfigURL:    meshConfURL,
-		connMap:          sync.Map{},
-		source:           make(chan quic.Stream),
-		zipperMap:        sync.Map{},
-		zipperSenders:    make([]GetSenderFunc, 0),
-		zipperReceiver:   make(chan quic.Stream),
+	h := &quicHandler{
+		meshConfigURL:  meshConfURL,
+		connMap:        sync.Map{},
+		source:         make(chan sourceStream),
+		zipperMap:      sync.Map{},
+		zipperSenders:  make([]GetSenderFunc, 0),
+		zipperReceiver: make(chan sourceStream),
+		shedder:        newShedder(conf.Shedding),
+		datagrams:      make(chan *frame.DataFrame, bufferSize),
+		partials:       make(chan *frame.DataFrame, bufferSize),
+		datagramRing:   newRingQueue(bufferSize),
+		partialRing:    newRingQueue(bufferSize),
+		queues:         newQueueTracker(),
+		fanIn:          newFanIn(conf.Sources),
+		startedAt:      time.Now(),
+		slow:           newSlowDetector(conf.SlowConsumer),
 	}
+	h.serverlessConfig.Store(conf)
+	return h
 }
 
 type quicHandler struct {
-	serverlessConfig *WorkflowConfig
+	serverlessConfig atomic.Value // the *WorkflowConfig, it's replaced when the workflow is reloaded.
 	meshConfigURL    string
 	connMap          sync.Map
-	source           chan quic.Stream
+	source           chan sourceStream
 	zipperMap        sync.Map // the stream map for downstream YoMo-Zippers.
 	zipperSenders    []GetSenderFunc
-	zipperReceiver   chan quic.Stream
+	zipperReceiver   chan sourceStream
 	mutex            sync.RWMutex
-	onReceivedData   func(buf []byte) // the callback function when the data is received.
+	onReceivedData   func(buf []byte)           // the callback function when the data is received.
+	shedder          *shedder                   // the load shedding policy when overloaded.
+	prober           *prober                    // the synthetic probes of SLIs.
+	localFuncs       map[string]LocalStreamFunc // the stream functions which run in the process of zipper.
+	localHandlers    map[string]LocalHandler    // the handlers of data with tags which run in the process of zipper.
+	invokers         map[string]*invoker        // the serverless functions which back the stream functions by name.
+	wasmFuncs        map[string]*wasmFunc       // the WASM modules which run the stream functions by name.
+	datagrams        chan *frame.DataFrame      // the data frames which are received in QUIC DATAGRAM frames.
+	partials         chan *frame.DataFrame      // the data frames which are received in partially reliable streams.
+	datagramRing     *ringQueue                 // the lock-free queue of datagrams, it's drained to datagrams.
+	partialRing      *ringQueue                 // the lock-free queue of partially reliable frames, it's drained to partials.
+	clientTLS        *tls.Config                // the TLS config for connecting to other YoMo-Zippers.
+	features         *Features                  // the flags of experimental features, they're shared with the zipper.
+	queues           *queueTracker              // the queues of pipeline, they're reported at shutdown.
+	fanIn            *fanIn                     // the weighted fan-in of sources, it's nil if no source has weight.
+	certs            *quic.CertReloader         // the reloadable certificate of server, it's nil if TLS is not configured.
+	startedAt        time.Time                  // the time when the handler is created.
+	auditor          *auditor                   // the audit log of clients and admin, it's nil if it's disabled.
+	authenticator    Authenticator              // the authentication of clients, it's nil if the clients aren't authenticated.
+	authorizer       Authorizer                 // the authorization of clients, it's nil if the clients aren't authorized.
+	capturer         *capturer                  // the capture of sampled frames for debugging, it's nil if it's disabled.
+	slow             *slowDetector              // the detector of slow stream functions.
+	listening        int32                      // listening is 1 while the QUIC listener is serving.
+	journal          *journal                   // the buffering of the frames of sources in JetStream, it's nil if it's disabled.
+}
+
+// config returns the current workflow config.
+func (s *quicHandler) config() *WorkflowConfig {
+	return s.serverlessConfig.Load().(*WorkflowConfig)
 }
 
 func (s *quicHandler) Listen() error {
+	atomic.StoreInt32(&s.listening, 1)
+
 	go func() {
 		s.receiveDataFromSources()
 	}()
@@ -64,6 +111,30 @@ func (s *quicHandler) Listen() error {
 		s.receiveDataFromZipperSenders()
 	}()
 
+	go func() {
+		s.receiveDataFromDatagrams()
+	}()
+
+	go func() {
+		s.receiveDataFromPartialStreams()
+	}()
+
+	// the lock-free queues are drained to the pipelines, the frames are queued in them if the feature is enabled.
+	go s.datagramRing.drain(context.Background(), s.datagrams)
+	go s.partialRing.drain(context.Background(), s.partials)
+
+	if s.fanIn != nil {
+		go func() {
+			s.receiveDataFromFanIn()
+		}()
+	}
+
+	if s.journal != nil {
+		go func() {
+			s.receiveDataFromJournal()
+		}()
+	}
+
 	if s.meshConfigURL != "" {
 		go func() {
 			err := s.buildZipperSenders()
@@ -83,20 +154,65 @@ func (s *quicHandler) Read(addr string, sess quic.Session, st quic.Stream) error
 	// the connection exists
 	if c, ok := s.connMap.Load(addr); ok {
 		c := c.(*Conn)
-		if c.Conn.Type == core.ConnTypeSource {
-			s.source <- st
+		if c.Departed() {
+			logger.Debug("[zipper] ignore the stream of the departed client.", "name", c.Conn.Name, "addr", addr)
+			st.CancelRead(0)
+			return nil
+		}
+		if limiter := c.rateLimiter(); limiter != nil {
+			st = quic.NewRateLimitedStream(st, limiter)
+		}
+		// the sources of namespaces are dispatched to their own workflows, not merged into the default one.
+		if c.Conn.Type == core.ConnTypeSource && c.Namespace() != "" {
+			s.source <- sourceStream{name: c.Conn.Name, session: sess, stream: st, namespace: c.Namespace()}
+		} else if c.Conn.Type == core.ConnTypeSource && s.journal != nil {
+			go s.journal.read(context.Background(), c.Conn.Name, sess, st, s.config())
+		} else if c.Conn.Type == core.ConnTypeSource && s.fanIn != nil {
+			go s.fanIn.read(context.Background(), c.Conn.Name, sess, st, s.shedder, s.config())
+		} else if c.Conn.Type == core.ConnTypeSource {
+			s.source <- sourceStream{name: c.Conn.Name, session: sess, stream: st}
 		} else if c.Conn.Type == core.ConnTypeUpstreamZipper {
-			s.zipperReceiver <- st
+			s.zipperReceiver <- sourceStream{name: c.Conn.Name, session: sess, stream: st}
 		}
 
 		return nil
 	}
 
 	// init a new connection.
-	svrConn := NewConn(addr, sess, st, s.serverlessConfig)
+	svrConn := newConn(addr, sess, st)
+	svrConn.prober = s.prober
+	svrConn.auditor = s.auditor
+	svrConn.authenticator = s.authenticator
+	svrConn.authorizer = s.authorizer
+	svrConn.audit(AuditConnect, "", core.ConnTypeNone, "")
 	svrConn.onClosed = func() {
 		s.connMap.Delete(addr)
+		if svrConn.Conn.Type == core.ConnTypeStreamFunction {
+			clearSubscription(svrConn.Conn.Name, &s.connMap)
+		}
+	}
+	svrConn.onPartialFrame = func(dataFrame *frame.DataFrame) {
+		if svrConn.Namespace() != "" {
+			logger.Error("[zipper] drop the partially reliable frame of the source in namespace, it's dispatched to the default workflow only.", "source", svrConn.Conn.Name, "TransactionID", dataFrame.TransactionID())
+			return
+		}
+		logger.Debug("Receive data frame from source in partially reliable stream.", "TransactionID", dataFrame.TransactionID())
+		s.enqueue(s.partials, s.partialRing, dataFrame)
+	}
+	svrConn.onStreamedFrame = func(dataFrame *frame.DataFrame) {
+		// the streamed carriage goes through the pipeline like the partially reliable frames, it's piped to
+		// the first stream function which observes it, or buffered for the local stream functions.
+		logger.Debug("Receive data frame from source with the streamed carriage.", "TransactionID", dataFrame.TransactionID())
+		if svrConn.Namespace() != "" {
+			logger.Error("[zipper] drop the streamed frame of the source in namespace, it's dispatched to the default workflow only.", "source", svrConn.Conn.Name, "TransactionID", dataFrame.TransactionID())
+			core.CloseCarriage(dataFrame)
+			return
+		}
+		if !s.enqueue(s.partials, s.partialRing, dataFrame) {
+			core.CloseCarriage(dataFrame)
+		}
 	}
+	svrConn.handleSignal(s.config())
 	s.connMap.Store(addr, svrConn)
 	return nil
 }
@@ -111,33 +227,172 @@ func (s *quicHandler) receiveDataFromSources() {
 			}
 
 			ctx, cancel := context.WithCancel(context.Background())
-			sfns := getStreamFuncs(s.serverlessConfig, &s.connMap)
-			dataCh := DispatcherWithFunc(ctx, sfns, item)
+			if item.namespace != "" {
+				dataCh := s.dispatchNamespace(ctx, item)
+				go func(namespace string) {
+					defer cancel()
+
+					for data := range dataCh {
+						s.handleNamespaceOutput(namespace, data)
+					}
+				}(item.namespace)
+				continue
+			}
+			dataCh := s.dispatch(ctx, item.name, item.session, item.stream)
 
 			go func() {
 				defer cancel()
 
 				for data := range dataCh {
-					logger.Debug("[zipper] receive data after running all Stream Functions, will drop it.", "data", logger.BytesString(data.GetCarriage()))
-					// call the `onReceivedData` callback function.
-					if s.onReceivedData != nil {
-						s.onReceivedData(data.GetCarriage())
-					}
-
-					// Upstream YoMo-Zippers
-					for _, sender := range s.zipperSenders {
-						if sender == nil {
-							continue
-						}
-
-						go sendDataToDownstream(sender, data, "[Upstream YoMo-Zipper] sent frame to downstream YoMo-Zipper Receiver.", "❌ [Upstream YoMo-Zipper] sent frame to downstream YoMo-Zipper Receiver failed.")
-					}
+					s.handleOutput(data)
 				}
 			}()
 		}
 	}
 }
 
+// receiveDataFromFanIn receives the data which are merged from `YoMo-Sources` by weights.
+func (s *quicHandler) receiveDataFromFanIn() {
+	ctx := context.Background()
+	go s.fanIn.run(ctx)
+
+	dataCh := s.pipe(ctx, s.fanIn.out)
+	for data := range dataCh {
+		s.handleOutput(data)
+	}
+}
+
+// receiveDataFromJournal receives the data of `YoMo-Sources` which are consumed from JetStream.
+func (s *quicHandler) receiveDataFromJournal() {
+	dataCh := s.pipe(context.Background(), s.journal.out)
+	for data := range dataCh {
+		s.handleOutput(data)
+	}
+}
+
+// receiveDataFromDatagrams receives the data which the `YoMo-Sources` sent in QUIC DATAGRAM frames.
+func (s *quicHandler) receiveDataFromDatagrams() {
+	dataCh := s.pipe(context.Background(), s.datagrams)
+	for data := range dataCh {
+		s.handleOutput(data)
+	}
+}
+
+// receiveDataFromPartialStreams receives the data which the `YoMo-Sources` sent in partially reliable mode.
+func (s *quicHandler) receiveDataFromPartialStreams() {
+	dataCh := s.pipe(context.Background(), s.partials)
+	for data := range dataCh {
+		s.handleOutput(data)
+	}
+}
+
+// ReadDatagram receives the DataFrame from the QUIC DATAGRAM frame of `YoMo-Sources`.
+func (s *quicHandler) ReadDatagram(addr string, sess quic.Session, data []byte) error {
+	c, ok := s.connMap.Load(addr)
+	if !ok || c.(*Conn).Conn.Type != core.ConnTypeSource {
+		return errors.New("[zipper] the datagram is not from a source")
+	}
+	if c.(*Conn).Namespace() != "" {
+		return errors.New("[zipper] the datagrams of the sources in namespaces are not supported")
+	}
+
+	f, err := core.ParseFrame(bytes.NewReader(data))
+	if errors.Is(err, frame.ErrChecksumMismatch) {
+		logger.Error("[zipper] drop the corrupted datagram of source.", "source", c.(*Conn).Conn.Name, "addr", addr, "err", err)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	dataFrame, ok := f.(*frame.DataFrame)
+	if !ok {
+		return errors.New("[zipper] only the data frame can be sent in datagram")
+	}
+	if !s.features.Enabled(FeatureDatagram, dataFrame.TransactionID()) {
+		return errors.New("[zipper] the datagram feature is disabled")
+	}
+	if err := core.DecompressFrame(dataFrame, codecOf(sess), s.config().MaxFrameSize); err != nil {
+		return err
+	}
+
+	// the datagrams of the session are not read until the bandwidth is available.
+	if limiter := c.(*Conn).rateLimiter(); limiter != nil {
+		limiter.Wait(len(data))
+	}
+
+	if !permitted(sess, dataFrame) {
+		return errors.New("[zipper] the source is not authorized to emit the tag")
+	}
+	if !withinQuota(sess, dataFrame) {
+		return errors.New("[zipper] the quota of the source is exceeded")
+	}
+
+	logger.Debug("Receive data frame from source in datagram.", "TransactionID", dataFrame.TransactionID())
+	countFrame(sess)
+	s.enqueue(s.datagrams, s.datagramRing, dataFrame)
+	return nil
+}
+
+// enqueue pushes the frame to the queue by the shedding policy, or to the lock-free ring if the feature is enabled
+// for the frame, returns false if the frame was dropped.
+func (s *quicHandler) enqueue(queue chan *frame.DataFrame, ring *ringQueue, f *frame.DataFrame) bool {
+	if !s.features.Enabled(FeatureLockFreeQueue, f.TransactionID()) {
+		return s.shedder.push(queue, f)
+	}
+	if !ring.push(f) {
+		s.shedder.drop(f)
+		return false
+	}
+	return true
+}
+
+// handleOutput handles the data after running all Stream Functions.
+func (s *quicHandler) handleOutput(data *frame.DataFrame) {
+	// the probe frames end here.
+	if s.prober != nil && s.prober.complete(data.TransactionID()) {
+		return
+	}
+	pipelineMetrics.sent(data)
+	s.capturer.capture(captureEgress, data)
+	pipelineTimelines.record(StepSent, "", data, "")
+
+	// the streamed carriage which no stream function consumed is buffered for the sinks.
+	if data.Streamed() {
+		if s.onReceivedData == nil && len(s.zipperSenders) == 0 {
+			core.CloseCarriage(data)
+			return
+		}
+		if err := materialize(data, s.config().MaxFrameSize); err != nil {
+			logger.Error("[zipper] drop the streamed carriage of output.", "TransactionID", data.TransactionID(), "err", err)
+			return
+		}
+	}
+
+	if remap := s.config().TagRemap.Egress; len(remap) > 0 {
+		remap.apply(data)
+	}
+
+	// tracing, the sinks and the upstream YoMo-Zippers continue the trace of data.
+	span := tracing.NewSpanFromFrame(data, "zipper", "zipper-output")
+	defer span.End()
+
+	logger.Debug("[zipper] receive data after running all Stream Functions, will drop it.", "data", logger.BytesString(data.GetCarriage()))
+	// call the `onReceivedData` callback function.
+	if s.onReceivedData != nil {
+		s.onReceivedData(data.GetCarriage())
+	}
+
+	// Upstream YoMo-Zippers
+	for _, sender := range s.zipperSenders {
+		if sender == nil {
+			continue
+		}
+
+		go sendDataToDownstream(sender, data, s.config().TagRemap.Downstreams, "[Upstream YoMo-Zipper] sent frame to downstream YoMo-Zipper Receiver.", "❌ [Upstream YoMo-Zipper] sent frame to downstream YoMo-Zipper Receiver failed.")
+	}
+}
+
 // receiveDataFromZipperSenders receives data from `Upstream YoMo-Zippers`.
 func (s *quicHandler) receiveDataFromZipperSenders() {
 	for {
@@ -148,8 +403,7 @@ func (s *quicHandler) receiveDataFromZipperSenders() {
 			}
 
 			ctx, cancel := context.WithCancel(context.Background())
-			sfns := getStreamFuncs(s.serverlessConfig, &s.connMap)
-			dataCh := DispatcherWithFunc(ctx, sfns, receiver)
+			dataCh := s.dispatch(ctx, receiver.name, receiver.session, receiver.stream)
 
 			go func() {
 				defer cancel()
@@ -162,15 +416,94 @@ func (s *quicHandler) receiveDataFromZipperSenders() {
 	}
 }
 
-// sendDataToDownstream sends data to `downstream`.
-func sendDataToDownstream(sf GetSenderFunc, frame frame.Frame, succssMsg string, errMsg string) {
+// sourceStream is a data stream of the source.
+type sourceStream struct {
+	name      string
+	session   quic.Session
+	stream    quic.Stream
+	namespace string // namespace is the namespace of the source, it's empty for the default workflow.
+}
+
+// dispatch dispatches the stream of source to the stream functions in workflow,
+// the adjacent local stream functions are fused into one stage, the remote ones are piped over QUIC,
+// and the serverless ones are invoked by HTTP.
+func (s *quicHandler) dispatch(ctx context.Context, name string, session quic.Session, stream quic.Stream) chan *frame.DataFrame {
+	return s.pipe(ctx, readDataFromSource(ctx, name, session, stream, s.shedder, s.config()))
+}
+
+// pipe the data through the stream functions in workflow, the stages of the pipeline are the ones of the current
+// workflow, so the reloaded stages are applied to the pipelines created after the reload.
+func (s *quicHandler) pipe(ctx context.Context, next chan *frame.DataFrame) chan *frame.DataFrame {
+	return s.pipeWorkflow(ctx, next, s.config())
+}
+
+// pipeWorkflow pipes the data through the stream functions in the workflow of conf, e.g. the workflow of a namespace.
+func (s *quicHandler) pipeWorkflow(ctx context.Context, next chan *frame.DataFrame, conf *WorkflowConfig) chan *frame.DataFrame {
+	sfns := getStreamFuncs(conf, &s.connMap)
+	next = countHops(ctx, next, conf.MaxHops)
+	if s.capturer != nil {
+		next = s.capturer.tap(ctx, next)
+	}
+	if remap := conf.TagRemap.Ingress; len(remap) > 0 {
+		next = remapTags(ctx, next, remap)
+	}
+
+	locals := make([]localStreamFunc, 0)
+	for i, app := range conf.Functions {
+		if fn, ok := s.localFuncs[app.Name]; ok {
+			locals = append(locals, localStreamFunc{name: app.Name, fn: fn})
+			continue
+		}
+
+		if len(locals) > 0 {
+			s.queues.track(ctx, locals[0].name, next)
+			next = pipeLocalFns(ctx, next, locals, conf.MaxFrameSize)
+			locals = make([]localStreamFunc, 0)
+		}
+		s.queues.track(ctx, app.Name, next)
+		if fn, ok := s.localHandlers[app.Name]; ok {
+			next = pipeLocalHandler(ctx, next, app.Name, fn, conf.MaxFrameSize)
+			continue
+		}
+		if inv, ok := s.invokers[app.Name]; ok {
+			next = pipeInvoker(ctx, next, inv, conf.MaxFrameSize)
+			continue
+		}
+		if fn, ok := s.wasmFuncs[app.Name]; ok {
+			next = pipeWasm(ctx, next, fn, conf.MaxFrameSize)
+			continue
+		}
+		next = pipeStreamFn(ctx, next, sfns[i], conf, s.features)
+	}
+
+	if len(locals) > 0 {
+		s.queues.track(ctx, locals[0].name, next)
+		next = pipeLocalFns(ctx, next, locals, conf.MaxFrameSize)
+	}
+
+	// the output of pipeline.
+	s.queues.track(ctx, "", next)
+	return next
+}
+
+// isLocal indicates if the stream function of name runs in the process of zipper.
+func (s *quicHandler) isLocal(name string) bool {
+	if _, ok := s.localFuncs[name]; ok {
+		return true
+	}
+	_, ok := s.localHandlers[name]
+	return ok
+}
+
+// sendDataToDownstream sends data to `downstream`, the tag is renumbered by the remapping table of the downstream.
+func sendDataToDownstream(sf GetSenderFunc, frame *frame.DataFrame, remaps map[string]TagRemap, succssMsg string, errMsg string) {
 	for {
 		name, writer, cancel := sf()
 		if writer == nil {
 			logger.Debug("[zipper] the downstream writer is nil", "name", name)
 			break
 		} else {
-			data := frame.Encode()
+			data := remapDownstream(frame, remaps[name]).Encode()
 			_, err := writer.Write(data)
 			if err != nil {
 				logger.Error(errMsg, "name", name, "frame", logger.BytesString(data), "err", err)
@@ -190,6 +523,8 @@ func getStreamFuncs(wfConf *WorkflowConfig, connMap *sync.Map) []GetStreamFunc {
 	funcs := make([]GetStreamFunc, 0)
 
 	for _, app := range wfConf.Functions {
+		storeSampler(app)
+		appCache.Store(app.Name, app)
 		funcs = append(funcs, createStreamFunc(app, connMap, core.ConnTypeStreamFunction))
 	}
 
@@ -198,6 +533,36 @@ func getStreamFuncs(wfConf *WorkflowConfig, connMap *sync.Map) []GetStreamFunc {
 
 var streamFuncCache = sync.Map{}           // the cache for all connections by name.
 var newStreamFuncSessionCache = sync.Map{} // the cache for new connection channel by name.
+var appCache = sync.Map{}                  // the cache for the config of stream functions by name.
+var sessionCodecs = sync.Map{}             // the compression codecs negotiated with the clients by session.
+var sessionVersions = sync.Map{}           // the versions of wire protocol negotiated with the clients by session.
+var sessionFrames = sync.Map{}             // the count of frames exchanged with the clients by session, the value is *uint64.
+var sessionGrants = sync.Map{}             // the tags which the sources may emit by session, the value is *tagGrant.
+
+// subscribed indicates if the stream function subscribes to the data tag, the tags changed at runtime
+// by the stream function take precedence over the config.
+func subscribed(name string, tag byte) bool {
+	if s, ok := subscriptionCache.Load(name); ok {
+		return s.(*tagSet).contains(tag)
+	}
+
+	app, ok := appCache.Load(name)
+	if !ok {
+		return true
+	}
+	return app.(App).accepts(tag)
+}
+
+// subscribedTo indicates if the stream function subscribes to any data tag of the frame,
+// the frame observed by several tags is sent to the stream function once.
+func subscribedTo(name string, data *frame.DataFrame) bool {
+	for _, tag := range data.Tags() {
+		if subscribed(name, tag) {
+			return true
+		}
+	}
+	return false
+}
 
 // createStreamFunc creates a `GetStreamFunc` for `Stream Function`.
 func createStreamFunc(app App, connMap *sync.Map, connType core.ConnectionType) GetStreamFunc {
@@ -209,21 +574,30 @@ func createStreamFunc(app App, connMap *sync.Map, connType core.ConnectionType)
 
 		// get from connMap.
 		conns := findConn(app, connMap, connType)
-		funcs := make([]streamFuncWithCancel, len(conns))
-
-		if len(conns) == 0 {
-			streamFuncCache.Store(app.Name, funcs)
-			return app.Name, funcs
-		}
+		funcs := make([]streamFuncWithCancel, 0, len(conns))
 
-		i := 0
 		for id, conn := range conns {
-			funcs[i] = streamFuncWithCancel{
+			funcs = append(funcs, streamFuncWithCancel{
 				addr:    conn.Addr,
 				session: conn.Session,
 				cancel:  cancelStreamFunc(app.Name, conn, connMap, id),
+			})
+		}
+
+		// the shadow functions of this stream function, they're changed when the workflow is reloaded.
+		shadows := app.Shadows
+		if current, ok := appCache.Load(app.Name); ok {
+			shadows = current.(App).Shadows
+		}
+		for _, shadow := range shadows {
+			for id, conn := range findConn(App{Name: shadow}, connMap, connType) {
+				funcs = append(funcs, streamFuncWithCancel{
+					addr:    conn.Addr,
+					session: conn.Session,
+					cancel:  cancelStreamFunc(app.Name, conn, connMap, id),
+					shadow:  true,
+				})
 			}
-			i++
 		}
 
 		streamFuncCache.Store(app.Name, funcs)
@@ -253,7 +627,7 @@ func findConn(app App, connMap *sync.Map, connType core.ConnectionType) map[stri
 	results := make(map[string]*Conn)
 	connMap.Range(func(key, value interface{}) bool {
 		c := value.(*Conn)
-		if c.Conn.Name == app.Name && c.Conn.Type == connType {
+		if c.Conn.Name == app.Name && c.Conn.Type == connType && !c.Departed() {
 			results[key.(string)] = c
 		}
 		return true
@@ -320,8 +694,15 @@ func (s *quicHandler) createZipperSender(conf zipperConf) GetSenderFunc {
 		s.zipperMap.Store(conf.Name, nil)
 
 		// connect to downstream YoMo-Zipper
-		cli, err := NewSender(s.serverlessConfig.Name).
-			Connect(conf.Host, conf.Port)
+		sender := NewSender(s.config().Name)
+		sender.(*senderClientImpl).SetTLSConfig(s.clientTLS)
+		sender.(*senderClientImpl).SetToken(s.config().MeshToken)
+		keepAlive := s.config().KeepAlive.Zipper
+		sender.(*senderClientImpl).SetKeepAlive(keepAlive.Interval, keepAlive.IdleTimeout)
+		sender.(*senderClientImpl).SetFlowControl(quic.FlowControl(s.config().FlowControl))
+		sender.(*senderClientImpl).SetQlog(s.config().Qlog)
+		sender.(*senderClientImpl).SetChunking(s.config().chunkSize())
+		cli, err := sender.Connect(conf.Host, conf.Port)
 		if err != nil {
 			logger.Error("[Upstream YoMo-Zipper] connect to downstream YoMo-Zipper failed, will retry...", "conf", conf, "err", err)
 			cli.Retry()
@@ -6,6 +6,9 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/core/quic"
+	"github.com/yomorun/yomo/internal/core"
+	"github.com/yomorun/yomo/internal/frame"
 	"github.com/yomorun/yomo/source"
 )
 
@@ -63,3 +66,20 @@ func TestServerHandlerRead(t *testing.T) {
 	server.Close()
 	c <- true
 }
+
+func TestServerHandlerReadDatagram(t *testing.T) {
+	serverHandler := newServerHandler(testConfig, testMeshURL)
+
+	f := frame.NewDataFrame("datagram")
+	f.SetCarriage(0x10, []byte("test"))
+
+	// the datagram from unknown connection.
+	assert.Error(t, serverHandler.ReadDatagram("127.0.0.1:1", nil, f.Encode()))
+
+	serverHandler.connMap.Store("127.0.0.1:1", &Conn{Addr: "127.0.0.1:1", Conn: quic.NewConn("source", core.ConnTypeSource)})
+	assert.NoError(t, serverHandler.ReadDatagram("127.0.0.1:1", nil, f.Encode()))
+
+	data := <-serverHandler.datagrams
+	assert.Equal(t, "datagram", data.TransactionID())
+	assert.Equal(t, []byte("test"), data.GetCarriage())
+}
@@ -0,0 +1,127 @@
+package zipper
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync/atomic"
+
+	"github.com/yomorun/yomo/internal/core"
+	"github.com/yomorun/yomo/logger"
+)
+
+// the status of health checks.
+const (
+	HealthOK          = "ok"
+	HealthUnavailable = "unavailable"
+)
+
+// HealthCheck is the result of a check of liveness or readiness.
+type HealthCheck struct {
+	Name    string `json:"name"`
+	OK      bool   `json:"ok"`
+	Message string `json:"message,omitempty"`
+}
+
+// HealthReport is the response of /healthz and /readyz, the status is unavailable if any check fails.
+type HealthReport struct {
+	Status string        `json:"status"`
+	Checks []HealthCheck `json:"checks"`
+}
+
+func newHealthReport(checks []HealthCheck) HealthReport {
+	report := HealthReport{Status: HealthOK, Checks: checks}
+	for _, check := range checks {
+		if !check.OK {
+			report.Status = HealthUnavailable
+		}
+	}
+	return report
+}
+
+// liveness checks if the QUIC listener is serving.
+func (s *quicHandler) liveness() []HealthCheck {
+	check := HealthCheck{Name: "listener", OK: atomic.LoadInt32(&s.listening) == 1}
+	if !check.OK {
+		check.Message = "the listener is not serving"
+	}
+	return []HealthCheck{check}
+}
+
+// readiness checks the listener, the min instances of each stream function, and the downstream YoMo-Zippers
+// which have been connected.
+func (s *quicHandler) readiness() []HealthCheck {
+	checks := s.liveness()
+
+	for _, app := range s.config().allFunctions() {
+		instances := len(findConn(app, &s.connMap, core.ConnTypeStreamFunction))
+		if s.isLocal(app.Name) {
+			instances++
+		}
+		if _, ok := s.invokers[app.Name]; ok {
+			instances++
+		}
+		if fn, ok := s.wasmFuncs[app.Name]; ok && fn.loaded() {
+			instances++
+		}
+		check := HealthCheck{Name: "stream-fn:" + app.Name, OK: instances >= app.MinInstances}
+		if !check.OK {
+			check.Message = fmt.Sprintf("%d of %d instances are connected", instances, app.MinInstances)
+		}
+		checks = append(checks, check)
+	}
+
+	downstreams := make([]HealthCheck, 0)
+	s.zipperMap.Range(func(key, value interface{}) bool {
+		cli, _ := value.(SenderClient)
+		check := HealthCheck{Name: "zipper:" + key.(string), OK: cli != nil && cli.Connected()}
+		if !check.OK {
+			check.Message = "the downstream YoMo-Zipper is unreachable"
+		}
+		downstreams = append(downstreams, check)
+		return true
+	})
+	sort.Slice(downstreams, func(i, j int) bool {
+		return downstreams[i].Name < downstreams[j].Name
+	})
+
+	return append(checks, downstreams...)
+}
+
+// healthHandler serves the report of checks, the status is 503 if any check fails.
+func healthHandler(checks func() []HealthCheck) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			return
+		}
+		report := newHealthReport(checks())
+		status := http.StatusOK
+		if report.Status != HealthOK {
+			status = http.StatusServiceUnavailable
+		}
+		writeJSON(w, status, report)
+	})
+}
+
+// serveHealth serves the probes of Kubernetes without authentication.
+// GET /healthz checks the liveness of YoMo-Zipper.
+// GET /readyz checks if YoMo-Zipper is ready to serve the workflow.
+func serveHealth(addr string, h *quicHandler) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", healthHandler(h.liveness))
+	mux.Handle("/readyz", healthHandler(h.readiness))
+	server := &http.Server{
+		Addr:    addr,
+		Handler: mux,
+	}
+
+	go func() {
+		logger.Printf("✅ Health checks are exposed on %s/healthz and %s/readyz", addr, addr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Error("[zipper] serve health checks failed.", "addr", addr, "err", err)
+		}
+	}()
+
+	return server
+}
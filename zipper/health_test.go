@@ -0,0 +1,62 @@
+package zipper
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getHealth(t *testing.T, url string) (int, HealthReport) {
+	res, err := http.Get(url)
+	assert.NoError(t, err)
+	defer res.Body.Close()
+
+	var report HealthReport
+	assert.NoError(t, json.NewDecoder(res.Body).Decode(&report))
+	return res.StatusCode, report
+}
+
+func TestHealth(t *testing.T) {
+	conf := &WorkflowConfig{Workflow: Workflow{Functions: []App{
+		{Name: "fn-1", MinInstances: 1},
+		{Name: "fn-2"},
+	}}}
+	h := newServerHandler(conf, "")
+
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", healthHandler(h.liveness))
+	mux.Handle("/readyz", healthHandler(h.readiness))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	// the listener is not serving yet.
+	status, report := getHealth(t, server.URL+"/healthz")
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, HealthUnavailable, report.Status)
+
+	h.listening = 1
+	status, report = getHealth(t, server.URL+"/healthz")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, HealthOK, report.Status)
+
+	// fn-1 has no instance, and the downstream YoMo-Zipper isn't connected.
+	h.zipperMap.Store("downstream", nil)
+	status, report = getHealth(t, server.URL+"/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, []HealthCheck{
+		{Name: "listener", OK: true},
+		{Name: "stream-fn:fn-1", Message: "0 of 1 instances are connected"},
+		{Name: "stream-fn:fn-2", OK: true},
+		{Name: "zipper:downstream", Message: "the downstream YoMo-Zipper is unreachable"},
+	}, report.Checks)
+
+	// the local function is an instance.
+	h.localFuncs = map[string]LocalStreamFunc{"fn-1": nil}
+	h.zipperMap.Delete("downstream")
+	status, report = getHealth(t, server.URL+"/readyz")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, HealthOK, report.Status)
+}
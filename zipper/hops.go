@@ -0,0 +1,54 @@
+package zipper
+
+import (
+	"context"
+
+	"github.com/yomorun/yomo/internal/core"
+	"github.com/yomorun/yomo/internal/frame"
+	"github.com/yomorun/yomo/logger"
+)
+
+// DefaultMaxHops is the default max count of YoMo-Zippers which a DataFrame passes through.
+const DefaultMaxHops = 16
+
+// passHop counts the zipper in the hops of frame, it returns false if the frame exceeds the max hops,
+// which means it's looping in the cascaded meshes.
+func passHop(data *frame.DataFrame, maxHops int) bool {
+	if maxHops <= 0 {
+		maxHops = DefaultMaxHops
+	}
+	return int(data.IncrHops()) <= maxHops
+}
+
+// countHops counts this zipper in the hops of the frames from upstream, the looping frames are dropped.
+func countHops(ctx context.Context, upstream chan *frame.DataFrame, maxHops int) chan *frame.DataFrame {
+	next := make(chan *frame.DataFrame, bufferSize)
+
+	go func() {
+		defer close(next)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case item, ok := <-upstream:
+				if !ok {
+					return
+				}
+
+				if !passHop(item, maxHops) {
+					logger.Error("[zipper] drop the frame exceeding the max hops, there may be a routing loop.", "TransactionID", item.TransactionID(), "hops", item.Hops())
+					core.CloseCarriage(item)
+					pipelineMetrics.failed(errorHops)
+					pipelineTimelines.record(StepDropped, "", item, "exceeding the max hops")
+					continue
+				}
+				pipelineMetrics.received(item)
+				pipelineTimelines.record(StepReceived, "", item, "")
+				next <- item
+			}
+		}
+	}()
+
+	return next
+}
@@ -0,0 +1,28 @@
+package zipper
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/internal/frame"
+)
+
+func TestCountHops(t *testing.T) {
+	upstream := make(chan *frame.DataFrame, 2)
+	fresh := frame.NewDataFrame("fresh")
+	looping := frame.NewDataFrame("looping")
+	looping.IncrHops()
+	looping.IncrHops()
+	upstream <- fresh
+	upstream <- looping
+	close(upstream)
+
+	var received []*frame.DataFrame
+	for data := range countHops(context.Background(), upstream, 2) {
+		received = append(received, data)
+	}
+	assert.Len(t, received, 1)
+	assert.Equal(t, "fresh", received[0].TransactionID())
+	assert.Equal(t, uint32(1), received[0].Hops())
+}
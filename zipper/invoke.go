@@ -0,0 +1,310 @@
+package zipper
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/yomorun/yomo/internal/frame"
+	"github.com/yomorun/yomo/internal/sigv4"
+	"github.com/yomorun/yomo/logger"
+)
+
+// the providers of serverless functions.
+const (
+	// InvokeHTTP posts the data to the URL of function, e.g. the Knative service or the Cloud Run service.
+	InvokeHTTP = "http"
+	// InvokeLambda invokes the AWS Lambda function synchronously.
+	InvokeLambda = "lambda"
+)
+
+const (
+	// DefaultInvokeConcurrency is the default max count of the invocations in flight of a function.
+	DefaultInvokeConcurrency = 16
+	// DefaultInvokeTimeout is the default timeout of each invocation.
+	DefaultInvokeTimeout = 30 * time.Second
+	// maxInvokeResponse is the max size in bytes of the response of invocation, it's the limit of AWS Lambda.
+	maxInvokeResponse = 6 << 20
+)
+
+// the headers of the HTTP invocations.
+const (
+	invokeHeaderTag           = "X-Yomo-Tag"
+	invokeHeaderTransactionID = "X-Yomo-Transaction-Id"
+)
+
+// InvokeConfig represents the serverless function which backs a stage of workflow instead of the connected stream
+// functions. YoMo-Zipper invokes it with the data of each frame and passes its response to the next stage, the
+// empty response drops the frame, and the frames are dropped if the invocation fails.
+type InvokeConfig struct {
+	// Provider is the provider of function, "http" or "lambda", the default is "http".
+	Provider string `yaml:"provider,omitempty"`
+	// URL is the URL of the HTTP function, the data is posted to it with the headers of the data tag and the
+	// transaction ID, and the body of 2xx response is the new data.
+	URL string `yaml:"url,omitempty"`
+	// Headers are sent with each HTTP invocation, such as "Authorization".
+	Headers map[string]string `yaml:"headers,omitempty"`
+	// Function is the name or ARN of the Lambda function, it's invoked with the data as the JSON event, and its
+	// result is the new data, the data tag and the transaction ID are in the custom fields of the client context.
+	Function string `yaml:"function,omitempty"`
+	// Qualifier is the version or alias of the Lambda function, the latest version is invoked if it's empty.
+	Qualifier string `yaml:"qualifier,omitempty"`
+	// Region is the AWS region of the Lambda function, the default is the environment variable AWS_REGION.
+	Region string `yaml:"region,omitempty"`
+	// Endpoint is the endpoint of Lambda API, the default is the endpoint of region, e.g. the LocalStack endpoint.
+	Endpoint string `yaml:"endpoint,omitempty"`
+	// AccessKeyID, SecretAccessKey and SessionToken are the AWS credentials, the environment variables
+	// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN are used if they're empty.
+	AccessKeyID     string `yaml:"access_key_id,omitempty"`
+	SecretAccessKey string `yaml:"secret_access_key,omitempty"`
+	SessionToken    string `yaml:"session_token,omitempty"`
+	// Concurrency is the max count of the invocations in flight, the next frames wait for a slot, so the
+	// function isn't flooded and the backpressure slows down the sources. The default is 16.
+	Concurrency int `yaml:"concurrency,omitempty"`
+	// Timeout is the timeout of each invocation, the default is 30s.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+// validate returns the problems of the config of function in the message of Validate.
+func (c *InvokeConfig) validate(name string) string {
+	msg := ""
+	switch c.Provider {
+	case "", InvokeHTTP:
+		if u, err := url.Parse(c.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			msg += "The invocation of function " + name + " requires an HTTP URL. "
+		}
+	case InvokeLambda:
+		if c.Function == "" {
+			msg += "The invocation of function " + name + " requires the name of Lambda function. "
+		}
+	default:
+		msg += "The invocation provider of function " + name + " must be http or lambda. "
+	}
+	if c.Concurrency < 0 || c.Timeout < 0 {
+		msg += "The concurrency and timeout of invocation of function " + name + " must not be negative. "
+	}
+	return msg
+}
+
+// invoker invokes a serverless function with the bounded concurrency.
+type invoker struct {
+	name   string
+	conf   InvokeConfig
+	client *http.Client
+	creds  sigv4.Credentials
+	// slots are the tokens of the invocations in flight.
+	slots chan struct{}
+}
+
+// newInvoker creates the invoker of the function, the defaults of config are applied.
+func newInvoker(name string, conf InvokeConfig) (*invoker, error) {
+	if conf.Provider == "" {
+		conf.Provider = InvokeHTTP
+	}
+	if conf.Concurrency <= 0 {
+		conf.Concurrency = DefaultInvokeConcurrency
+	}
+	if conf.Timeout <= 0 {
+		conf.Timeout = DefaultInvokeTimeout
+	}
+	var creds sigv4.Credentials
+	if conf.Provider == InvokeLambda {
+		if conf.Region == "" {
+			conf.Region = os.Getenv("AWS_REGION")
+		}
+		if conf.Region == "" {
+			return nil, fmt.Errorf("the region of Lambda function %s is required", conf.Function)
+		}
+		if conf.Endpoint == "" {
+			conf.Endpoint = "https://lambda." + conf.Region + ".amazonaws.com"
+		}
+		creds = sigv4.Credentials{AccessKeyID: conf.AccessKeyID, SecretAccessKey: conf.SecretAccessKey, SessionToken: conf.SessionToken}
+		if creds.AccessKeyID == "" {
+			creds = sigv4.FromEnv()
+		}
+	}
+	inv := &invoker{name: name, conf: conf, client: &http.Client{}, creds: creds, slots: make(chan struct{}, conf.Concurrency)}
+	return inv, nil
+}
+
+// newInvokers creates the invokers of the functions which are backed by the serverless functions.
+func newInvokers(apps []App) (map[string]*invoker, error) {
+	invokers := make(map[string]*invoker)
+	for _, app := range apps {
+		if app.Invoke == nil {
+			continue
+		}
+		inv, err := newInvoker(app.Name, *app.Invoke)
+		if err != nil {
+			return nil, err
+		}
+		invokers[app.Name] = inv
+	}
+	return invokers, nil
+}
+
+// invoke invokes the function with the data, it returns the response of function.
+func (inv *invoker) invoke(ctx context.Context, tag byte, tid string, data []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, inv.conf.Timeout)
+	defer cancel()
+
+	var req *http.Request
+	var err error
+	if inv.conf.Provider == InvokeLambda {
+		req, err = inv.lambdaRequest(ctx, tag, tid, data)
+	} else {
+		req, err = inv.httpRequest(ctx, tag, tid, data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := inv.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(io.LimitReader(res.Body, maxInvokeResponse+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxInvokeResponse {
+		return nil, errors.New("the response is larger than 6MB")
+	}
+	if res.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("status %d: %s", res.StatusCode, bytes.TrimSpace(body))
+	}
+	// the Lambda function throws the error with the status 200.
+	if kind := res.Header.Get("X-Amz-Function-Error"); kind != "" {
+		return nil, fmt.Errorf("the function error %s: %s", kind, bytes.TrimSpace(body))
+	}
+	if inv.conf.Provider == InvokeLambda && string(bytes.TrimSpace(body)) == "null" {
+		return nil, nil
+	}
+	return body, nil
+}
+
+func (inv *invoker) httpRequest(ctx context.Context, tag byte, tid string, data []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, inv.conf.URL, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set(invokeHeaderTag, strconv.Itoa(int(tag)))
+	req.Header.Set(invokeHeaderTransactionID, tid)
+	for k, v := range inv.conf.Headers {
+		req.Header.Set(k, v)
+	}
+	return req, nil
+}
+
+func (inv *invoker) lambdaRequest(ctx context.Context, tag byte, tid string, data []byte) (*http.Request, error) {
+	if !json.Valid(data) {
+		return nil, errors.New("the data isn't a JSON event of Lambda")
+	}
+	u := inv.conf.Endpoint + "/2015-03-31/functions/" + url.PathEscape(inv.conf.Function) + "/invocations"
+	if inv.conf.Qualifier != "" {
+		u += "?Qualifier=" + url.QueryEscape(inv.conf.Qualifier)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	clientContext, _ := json.Marshal(map[string]map[string]string{
+		"custom": {"tag": strconv.Itoa(int(tag)), "tid": tid},
+	})
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Amz-Invocation-Type", "RequestResponse")
+	req.Header.Set("X-Amz-Client-Context", base64.StdEncoding.EncodeToString(clientContext))
+	sigv4.Sign(req, inv.creds, inv.conf.Region, "lambda", sigv4.PayloadHash(data), time.Now())
+	return req, nil
+}
+
+// pipeInvoker invokes the serverless function with the frames of upstream concurrently, the frames which are not
+// subscribed, sampled or readable pass through it. The order of frames isn't kept like the remote stream functions.
+func pipeInvoker(ctx context.Context, upstream chan *frame.DataFrame, inv *invoker, maxSize int) chan *frame.DataFrame {
+	next := make(chan *frame.DataFrame, bufferSize)
+
+	go func() {
+		var wg sync.WaitGroup
+		defer func() {
+			wg.Wait()
+			close(next)
+		}()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case item, ok := <-upstream:
+				if !ok {
+					return
+				}
+
+				// the encrypted frames can't be read in YoMo-Zipper, they pass through the function.
+				if !subscribedTo(inv.name, item) || !sampled(inv.name) || item.KeyID() != "" {
+					next <- item
+					continue
+				}
+
+				// wait for a slot of invocation.
+				select {
+				case <-ctx.Done():
+					return
+				case inv.slots <- struct{}{}:
+				}
+				wg.Add(1)
+				go func(data *frame.DataFrame) {
+					defer func() {
+						<-inv.slots
+						wg.Done()
+					}()
+					if data, ok := runInvoker(ctx, inv, data, maxSize); ok {
+						select {
+						case next <- data:
+						case <-ctx.Done():
+						}
+					}
+				}(item)
+			}
+		}
+	}()
+
+	return next
+}
+
+// runInvoker invokes the function with the frame, returns false if the data was dropped.
+func runInvoker(ctx context.Context, inv *invoker, data *frame.DataFrame, maxSize int) (*frame.DataFrame, bool) {
+	if err := materialize(data, maxSize); err != nil {
+		logger.Error("[zipper] the streamed carriage can't be read by the serverless function.", "stream-fn", inv.name, "TransactionID", data.TransactionID(), "err", err)
+		return nil, false
+	}
+
+	pipelineTimelines.record(StepRouted, inv.name, data, "")
+	buf, err := inv.invoke(ctx, data.GetDataTagID(), data.TransactionID(), data.GetCarriage())
+	if err != nil {
+		logger.Error("[zipper] the invocation of serverless function failed.", "stream-fn", inv.name, "TransactionID", data.TransactionID(), "err", err)
+		pipelineTimelines.record(StepFailed, inv.name, data, err.Error())
+		return nil, false
+	}
+	if len(buf) == 0 {
+		logger.Debug("[zipper] the response of serverless function is empty.", "stream-fn", inv.name, "TransactionID", data.TransactionID())
+		pipelineTimelines.record(StepDropped, inv.name, data, "the response is empty")
+		return nil, false
+	}
+
+	data.SetCarriage(data.GetDataTagID(), buf)
+	pipelineTimelines.record(StepResponded, inv.name, data, "")
+	return data, true
+}
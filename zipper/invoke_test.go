@@ -0,0 +1,162 @@
+package zipper
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/internal/frame"
+)
+
+func TestPipeInvoker(t *testing.T) {
+	var inflight, maxInflight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inflight, 1)
+		defer atomic.AddInt32(&inflight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInflight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInflight, max, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		assert.Equal(t, "16", r.Header.Get(invokeHeaderTag))
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.Equal(t, string(body), r.Header.Get(invokeHeaderTransactionID))
+		switch string(body) {
+		case "drop":
+			w.WriteHeader(http.StatusNoContent)
+		case "fail":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.Write([]byte(strings.ToUpper(string(body))))
+		}
+	}))
+	defer server.Close()
+
+	inv, err := newInvoker("serverless-1", InvokeConfig{
+		URL:         server.URL,
+		Headers:     map[string]string{"Authorization": "Bearer token"},
+		Concurrency: 2,
+	})
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	inputs := []string{"a", "b", "c", "d", "drop", "fail"}
+	upstream := make(chan *frame.DataFrame, len(inputs)+1)
+	for _, s := range inputs {
+		f := frame.NewDataFrame(s)
+		f.SetCarriage(0x10, []byte(s))
+		upstream <- f
+	}
+	// the encrypted carriage passes through.
+	encrypted := frame.NewDataFrame("encrypted")
+	encrypted.SetCarriage(0x10, []byte("sealed"))
+	encrypted.SetKeyID("k1")
+	upstream <- encrypted
+	close(upstream)
+
+	results := make([]string, 0)
+	for f := range pipeInvoker(ctx, upstream, inv, 0) {
+		assert.Equal(t, byte(0x10), f.GetDataTagID())
+		results = append(results, string(f.GetCarriage()))
+	}
+	assert.ElementsMatch(t, []string{"A", "B", "C", "D", "sealed"}, results)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&maxInflight))
+}
+
+func TestInvokerLambda(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2015-03-31/functions/noise-fn/invocations", r.URL.Path)
+		assert.Equal(t, "live", r.URL.Query().Get("Qualifier"))
+		assert.Equal(t, "RequestResponse", r.Header.Get("X-Amz-Invocation-Type"))
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/"))
+		assert.Contains(t, r.Header.Get("Authorization"), "/ap-east-1/lambda/aws4_request")
+
+		buf, err := base64.StdEncoding.DecodeString(r.Header.Get("X-Amz-Client-Context"))
+		assert.NoError(t, err)
+		var clientContext struct {
+			Custom map[string]string `json:"custom"`
+		}
+		assert.NoError(t, json.Unmarshal(buf, &clientContext))
+		assert.Equal(t, map[string]string{"tag": "51", "tid": "tid-1"}, clientContext.Custom)
+
+		var event map[string]int
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.NoError(t, json.Unmarshal(body, &event))
+		switch event["noise"] {
+		case 0:
+			w.Write([]byte("null"))
+		case 1:
+			w.Header().Set("X-Amz-Function-Error", "Unhandled")
+			w.Write([]byte(`{"errorMessage":"boom"}`))
+		case 2:
+			time.Sleep(200 * time.Millisecond)
+		default:
+			json.NewEncoder(w).Encode(map[string]int{"level": event["noise"] / 10})
+		}
+	}))
+	defer server.Close()
+
+	inv, err := newInvoker("serverless-1", InvokeConfig{
+		Provider:        InvokeLambda,
+		Function:        "noise-fn",
+		Qualifier:       "live",
+		Region:          "ap-east-1",
+		Endpoint:        server.URL,
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+		Timeout:         100 * time.Millisecond,
+	})
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	res, err := inv.invoke(ctx, 0x33, "tid-1", []byte(`{"noise":42}`))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"level":4}`, string(res))
+
+	// the null result drops the data.
+	res, err = inv.invoke(ctx, 0x33, "tid-1", []byte(`{"noise":0}`))
+	assert.NoError(t, err)
+	assert.Nil(t, res)
+
+	_, err = inv.invoke(ctx, 0x33, "tid-1", []byte(`{"noise":1}`))
+	assert.EqualError(t, err, `the function error Unhandled: {"errorMessage":"boom"}`)
+	_, err = inv.invoke(ctx, 0x33, "tid-1", []byte(`{"noise":2}`))
+	assert.Error(t, err)
+	_, err = inv.invoke(ctx, 0x33, "tid-1", []byte("raw"))
+	assert.EqualError(t, err, "the data isn't a JSON event of Lambda")
+
+	// the region is required.
+	t.Setenv("AWS_REGION", "")
+	_, err = newInvoker("serverless-1", InvokeConfig{Provider: InvokeLambda, Function: "noise-fn"})
+	assert.Error(t, err)
+}
+
+func TestValidateInvoke(t *testing.T) {
+	for _, c := range []struct {
+		conf InvokeConfig
+		ok   bool
+	}{
+		{InvokeConfig{URL: "http://fn.default.svc.cluster.local"}, true},
+		{InvokeConfig{Provider: InvokeLambda, Function: "arn:aws:lambda:us-east-1:123456789012:function:fn"}, true},
+		{InvokeConfig{}, false},
+		{InvokeConfig{URL: "fn.default.svc.cluster.local"}, false},
+		{InvokeConfig{Provider: InvokeLambda}, false},
+		{InvokeConfig{Provider: "openwhisk", URL: "http://fn"}, false},
+		{InvokeConfig{URL: "http://fn", Concurrency: -1}, false},
+	} {
+		assert.Equal(t, c.ok, c.conf.validate("fn") == "", c.conf)
+	}
+}
@@ -0,0 +1,71 @@
+package zipper
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/internal/frame"
+	"github.com/yomorun/yomo/internal/nats/mock"
+)
+
+func TestJournal(t *testing.T) {
+	server, err := mock.NewServer()
+	assert.NoError(t, err)
+	defer server.Close()
+
+	j := newJournal(&JetStreamConfig{URL: server.URL(), Batch: 2}, "test", newShedder(SheddingConfig{}))
+	defer j.close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	start := time.Now()
+	for _, tid := range []string{"tid-1", "tid-2", "tid-1", "tid-3"} {
+		f := frame.NewDataFrame(tid)
+		f.SetCarriage(0x33, []byte("data-"+tid))
+		assert.NoError(t, j.append(ctx, f))
+	}
+	// the resent frame is deduplicated.
+	assert.Len(t, server.Messages(), 3)
+	assert.Equal(t, DefaultJetStreamSubject, server.Messages()[0].Subject)
+
+	go j.run(ctx)
+	for _, tid := range []string{"tid-1", "tid-2", "tid-3"} {
+		select {
+		case f := <-j.out:
+			assert.Equal(t, tid, f.TransactionID())
+			assert.Equal(t, []byte("data-"+tid), f.GetCarriage())
+		case <-time.After(5 * time.Second):
+			t.Fatal("the frame isn't consumed")
+		}
+	}
+	assert.Eventually(t, func() bool { return server.Acked() == 3 }, 5*time.Second, 10*time.Millisecond)
+
+	// the frames are replayed.
+	done := make(chan error)
+	go func() { done <- j.replay(ctx, start) }()
+	for _, tid := range []string{"tid-1", "tid-2", "tid-3"} {
+		select {
+		case f := <-j.out:
+			assert.Equal(t, tid, f.TransactionID())
+		case <-time.After(5 * time.Second):
+			t.Fatal("the frame isn't replayed")
+		}
+	}
+	assert.NoError(t, <-done)
+}
+
+func TestJournalUnavailable(t *testing.T) {
+	j := newJournal(&JetStreamConfig{URL: "nats://127.0.0.1:1"}, "test", newShedder(SheddingConfig{}))
+	assert.Error(t, j.append(context.Background(), frame.NewDataFrame("tid")))
+
+	rec := httptest.NewRecorder()
+	j.serveReplay(rec, httptest.NewRequest(http.MethodPost, "/replay?since=yesterday", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = httptest.NewRecorder()
+	j.serveReplay(rec, httptest.NewRequest(http.MethodGet, "/replay", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
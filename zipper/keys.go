@@ -0,0 +1,131 @@
+package zipper
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/yomorun/yomo/core/kms"
+)
+
+// DefaultKeysRefreshInterval is the default interval of reloading the keys from their provider.
+const DefaultKeysRefreshInterval = time.Minute
+
+// KeysConfig provides the keys from the environment variable, the file or Vault instead of the config, so the keys
+// aren't written in the config or the source. The keys are reloaded in every refresh interval, the new key is used
+// once it's published, and the removed keys are still accepted in the grace period, so the keys are rotated
+// without restarting YoMo-Zipper or breaking the clients which still use the previous key.
+type KeysConfig struct {
+	// Env is the environment variable of the keys, its value is the base64 encoded key, or an array of keys in JSON.
+	Env string `yaml:"env,omitempty"`
+	// File is the path of JSON file of the keys, the content is an array of keys, e.g.
+	// [{"id": "2022-03", "material": "base64 encoded key", "not_before": "2022-03-01T00:00:00Z"}].
+	File string `yaml:"file,omitempty"`
+	// Vault reads the keys from the field "keys" of a KV v2 secret in Vault, it's an array of keys as the file.
+	Vault *VaultKeysConfig `yaml:"vault,omitempty"`
+	// AWSKMS decrypts the material of the keys by AWS KMS, so only the ciphertext of keys is kept in the env, the
+	// file or Vault.
+	AWSKMS *AWSKMSConfig `yaml:"aws_kms,omitempty"`
+	// RefreshInterval is the interval of reloading the keys, the default is 1m.
+	RefreshInterval time.Duration `yaml:"refresh_interval,omitempty"`
+	// GracePeriod is how long the removed keys are still accepted, the default is 10m.
+	GracePeriod time.Duration `yaml:"grace_period,omitempty"`
+}
+
+// VaultKeysConfig reads the keys from a KV v2 secret in Vault.
+type VaultKeysConfig struct {
+	// Addr is the address of Vault, the default is the environment variable VAULT_ADDR.
+	Addr string `yaml:"addr,omitempty"`
+	// Path is the path of secret, e.g. "secret/data/yomo/jwt".
+	Path string `yaml:"path"`
+	// TokenEnv is the environment variable of the token of Vault, the default is VAULT_TOKEN.
+	TokenEnv string `yaml:"token_env,omitempty"`
+}
+
+// AWSKMSConfig decrypts the keys by AWS KMS, the credentials are the environment variables AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN.
+type AWSKMSConfig struct {
+	// Region is the AWS region of KMS, the default is the environment variable AWS_REGION.
+	Region string `yaml:"region,omitempty"`
+	// Endpoint is the endpoint of KMS API, the default is the endpoint of region, e.g. the LocalStack endpoint.
+	Endpoint string `yaml:"endpoint,omitempty"`
+}
+
+// validate returns the problems of the config of the keys of owner in the message of Validate.
+func (c *KeysConfig) validate(owner string) string {
+	errMsg := ""
+	sources := 0
+	if c.Env != "" {
+		sources++
+	}
+	if c.File != "" {
+		sources++
+	}
+	if c.Vault != nil {
+		sources++
+		if c.Vault.Path == "" {
+			errMsg += "The vault of the keys of " + owner + " requires the path. "
+		}
+	}
+	if sources != 1 {
+		errMsg += "The keys of " + owner + " require one of the env, the file and the vault. "
+	}
+	if c.RefreshInterval < 0 || c.GracePeriod < 0 {
+		errMsg += "The refresh interval and the grace period of the keys of " + owner + " must not be negative. "
+	}
+	return errMsg
+}
+
+// provider returns the provider of the keys.
+func (c *KeysConfig) provider() kms.KeyProvider {
+	var provider kms.KeyProvider
+	switch {
+	case c.Env != "":
+		provider = kms.NewEnvProvider(c.Env)
+	case c.File != "":
+		provider = kms.NewFileProvider(c.File)
+	case c.Vault != nil:
+		addr := c.Vault.Addr
+		if addr == "" {
+			addr = os.Getenv("VAULT_ADDR")
+		}
+		tokenEnv := c.Vault.TokenEnv
+		if tokenEnv == "" {
+			tokenEnv = "VAULT_TOKEN"
+		}
+		provider = kms.NewVaultProvider(addr, os.Getenv(tokenEnv), c.Vault.Path)
+	}
+	if c.AWSKMS != nil {
+		provider = kms.NewKMSProvider(provider, kms.NewAWSDecrypter(c.AWSKMS.Region, c.AWSKMS.Endpoint))
+	}
+	return provider
+}
+
+// loadKeys loads the keyring of the keys, it fails if the keys can't be loaded.
+func loadKeys(c *KeysConfig) (*kms.Keyring, error) {
+	gracePeriod := c.GracePeriod
+	if gracePeriod == 0 {
+		gracePeriod = kms.DefaultGracePeriod
+	}
+	keyring := kms.NewKeyring(c.provider(), kms.WithGracePeriod(gracePeriod))
+	if err := keyring.Refresh(context.Background()); err != nil {
+		return nil, err
+	}
+	return keyring, nil
+}
+
+// watchKeys reloads the keys of the authenticator of config in every refresh interval until it's stopped, it
+// returns nil if the authenticator has no keys to reload.
+func watchKeys(a Authenticator, conf *AuthConfig) context.CancelFunc {
+	ta, ok := a.(*tokenAuthenticator)
+	if !ok || ta.jwt == nil || ta.jwt.keyring == nil {
+		return nil
+	}
+	interval := conf.JWT.Keys.RefreshInterval
+	if interval == 0 {
+		interval = DefaultKeysRefreshInterval
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go ta.jwt.keyring.Watch(ctx, interval)
+	return cancel
+}
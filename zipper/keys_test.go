@@ -0,0 +1,80 @@
+package zipper
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// signJWTByKey signs the claims by the HMAC secret of the key, the ID of key is the "kid" header.
+func signJWTByKey(kid string, secret []byte, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT", "kid": kid})
+	payload, _ := json.Marshal(claims)
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(input))
+	return input + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestValidateKeys(t *testing.T) {
+	conf := &WorkflowConfig{Name: "zipper", Host: "localhost", Port: 9000, Auth: &AuthConfig{
+		JWT: &JWTConfig{Secret: "secret", Keys: &KeysConfig{Env: "JWT_KEYS", Vault: &VaultKeysConfig{}, GracePeriod: -time.Minute}},
+	}}
+	err := Validate(conf)
+	if assert.Error(t, err) {
+		for _, msg := range []string{
+			"The JWT of auth requires one of the secret, the keys and the public key file.",
+			"The vault of the keys of JWT requires the path.",
+			"The keys of JWT require one of the env, the file and the vault.",
+			"The refresh interval and the grace period of the keys of JWT must not be negative.",
+		} {
+			assert.Contains(t, err.Error(), msg)
+		}
+	}
+
+	conf.Auth.JWT = &JWTConfig{Keys: &KeysConfig{Env: "JWT_KEYS"}}
+	assert.NoError(t, Validate(conf))
+}
+
+func TestJWTKeys(t *testing.T) {
+	_, err := newAuthenticator(&AuthConfig{JWT: &JWTConfig{Keys: &KeysConfig{Env: "YOMO_TEST_JWT_KEYS"}}})
+	assert.EqualError(t, err, "load the keys of JWT failed: kms: the environment variable YOMO_TEST_JWT_KEYS is not set")
+
+	file := filepath.Join(t.TempDir(), "keys.json")
+	assert.NoError(t, ioutil.WriteFile(file, []byte(`[{"id":"k1","material":"c2VjcmV0LTE="}]`), 0600))
+	a, err := newAuthenticator(&AuthConfig{JWT: &JWTConfig{Keys: &KeysConfig{File: file, GracePeriod: time.Hour}}})
+	assert.NoError(t, err)
+
+	claims := map[string]interface{}{"sub": "sensor-1"}
+	principal, err := a.Authenticate(Credential{Token: signJWTByKey("k1", []byte("secret-1"), claims)})
+	assert.NoError(t, err)
+	assert.Equal(t, "sensor-1", principal.Subject)
+
+	// the key is rotated, the tokens of the previous key are accepted in the grace period.
+	assert.NoError(t, ioutil.WriteFile(file, []byte(`[{"id":"k2","material":"c2VjcmV0LTI="}]`), 0600))
+	keyring := a.(*tokenAuthenticator).jwt.keyring
+	assert.NoError(t, keyring.Refresh(context.Background()))
+	for _, token := range []string{
+		signJWTByKey("k1", []byte("secret-1"), claims),
+		signJWTByKey("k2", []byte("secret-2"), claims),
+		signJWTByKey("", []byte("secret-1"), claims),
+		signJWTByKey("", []byte("secret-2"), claims),
+	} {
+		_, err = a.Authenticate(Credential{Token: token})
+		assert.NoError(t, err)
+	}
+
+	// the token must be signed by the key of its kid.
+	_, err = a.Authenticate(Credential{Token: signJWTByKey("k2", []byte("secret-1"), claims)})
+	assert.EqualError(t, err, "the signature of JWT is invalid")
+	_, err = a.Authenticate(Credential{Token: signJWTByKey("k3", []byte("secret-3"), claims)})
+	assert.EqualError(t, err, "the signature of JWT is invalid")
+}
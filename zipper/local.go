@@ -0,0 +1,84 @@
+package zipper
+
+import (
+	"context"
+
+	"github.com/yomorun/yomo/internal/frame"
+	"github.com/yomorun/yomo/logger"
+)
+
+// LocalStreamFunc is a stream function which runs in the process of YoMo-Zipper instead of connecting over QUIC.
+// It returns the new data which is passed to the next function, or nil to drop the data.
+type LocalStreamFunc func(data []byte) ([]byte, error)
+
+// localStreamFunc is a local stream function with its name in workflow.
+type localStreamFunc struct {
+	name string
+	fn   LocalStreamFunc
+}
+
+// pipeLocalFns runs the adjacent local stream functions in one goroutine,
+// the data is passed between them without channel hop and re-encoding.
+func pipeLocalFns(ctx context.Context, upstream chan *frame.DataFrame, fns []localStreamFunc, maxSize int) chan *frame.DataFrame {
+	next := make(chan *frame.DataFrame, bufferSize)
+
+	go func() {
+		defer close(next)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case item, ok := <-upstream:
+				if !ok {
+					return
+				}
+
+				if data, ok := runLocalFns(fns, item, maxSize); ok {
+					next <- data
+				}
+			}
+		}
+	}()
+
+	return next
+}
+
+// runLocalFns runs the local stream functions one by one, returns false if the data was dropped by any function.
+// The streamed carriage is buffered up to maxSize before it's read by a local stream function.
+func runLocalFns(fns []localStreamFunc, data *frame.DataFrame, maxSize int) (*frame.DataFrame, bool) {
+	for _, f := range fns {
+		// the frames which are not subscribed or sampled pass through this stream function.
+		if !subscribedTo(f.name, data) || !sampled(f.name) {
+			continue
+		}
+		// the encrypted frames can't be read in YoMo-Zipper, they pass through the local stream functions.
+		if data.KeyID() != "" {
+			continue
+		}
+
+		if err := materialize(data, maxSize); err != nil {
+			logger.Error("[zipper] the streamed carriage can't be read by the local stream function.", "stream-fn", f.name, "TransactionID", data.TransactionID(), "err", err)
+			return nil, false
+		}
+
+		pipelineTimelines.record(StepRouted, f.name, data, "")
+		buf, err := f.fn(data.GetCarriage())
+		if err != nil {
+			logger.Error("[zipper] the local stream function got an error.", "stream-fn", f.name, "TransactionID", data.TransactionID(), "err", err)
+			pipelineTimelines.record(StepFailed, f.name, data, err.Error())
+			return nil, false
+		}
+
+		if buf == nil {
+			logger.Debug("[zipper] the returned data of local stream function is nil.", "stream-fn", f.name, "TransactionID", data.TransactionID())
+			pipelineTimelines.record(StepDropped, f.name, data, "the response is nil")
+			return nil, false
+		}
+
+		data.SetCarriage(data.GetDataTagID(), buf)
+		pipelineTimelines.record(StepResponded, f.name, data, "")
+	}
+
+	return data, true
+}
@@ -0,0 +1,85 @@
+package zipper
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/internal/frame"
+)
+
+func TestPipeLocalFns(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	upper := func(data []byte) ([]byte, error) {
+		return append(data, '!'), nil
+	}
+	drop := func(data []byte) ([]byte, error) {
+		if string(data) == "drop!" {
+			return nil, nil
+		}
+		return data, nil
+	}
+	fail := func(data []byte) ([]byte, error) {
+		if string(data) == "fail!" {
+			return nil, errors.New("failed")
+		}
+		return data, nil
+	}
+
+	upstream := make(chan *frame.DataFrame, 3)
+	for _, s := range []string{"drop", "fail", "ok"} {
+		f := frame.NewDataFrame(s)
+		f.SetCarriage(0x10, []byte(s))
+		upstream <- f
+	}
+	close(upstream)
+
+	next := pipeLocalFns(ctx, upstream, []localStreamFunc{
+		{name: "local-1", fn: upper},
+		{name: "local-2", fn: drop},
+		{name: "local-3", fn: fail},
+	}, 0)
+
+	results := make([]string, 0)
+	for f := range next {
+		assert.Equal(t, byte(0x10), f.GetDataTagID())
+		results = append(results, string(f.GetCarriage()))
+	}
+	assert.Equal(t, []string{"ok!"}, results)
+}
+
+func TestPipeLocalFnsStreamed(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	upstream := make(chan *frame.DataFrame, 3)
+	for _, s := range []string{"ok", "too large"} {
+		f := frame.NewDataFrame(s)
+		f.SetCarriageReader(0x10, strings.NewReader(s))
+		upstream <- f
+	}
+	// the encrypted carriage passes through.
+	encrypted := frame.NewDataFrame("encrypted")
+	encrypted.SetCarriageReader(0x10, strings.NewReader("sealed"))
+	encrypted.SetKeyID("k1")
+	upstream <- encrypted
+	close(upstream)
+
+	upper := func(data []byte) ([]byte, error) {
+		return append(data, '!'), nil
+	}
+	next := pipeLocalFns(ctx, upstream, []localStreamFunc{{name: "local-1", fn: upper}}, len("ok"))
+
+	f := <-next
+	assert.False(t, f.Streamed())
+	assert.Equal(t, "ok!", string(f.GetCarriage()))
+	f = <-next
+	assert.Equal(t, "encrypted", f.TransactionID())
+	assert.True(t, f.Streamed())
+	_, ok := <-next
+	assert.False(t, ok)
+}
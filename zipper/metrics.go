@@ -0,0 +1,361 @@
+package zipper
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yomorun/yomo/core/quic"
+	"github.com/yomorun/yomo/internal/core"
+	"github.com/yomorun/yomo/internal/frame"
+	"github.com/yomorun/yomo/logger"
+)
+
+// the kinds of errors which are counted by the metrics.
+const (
+	errorHops       = "max_hops_exceeded"
+	errorSend       = "send_to_stream_fn"
+	errorReceive    = "receive_from_stream_fn"
+	errorDecompress = "decompress"
+	// errorUnauthorized is the frame of the tag which the source is not authorized to emit.
+	errorUnauthorized = "unauthorized_tag"
+)
+
+// latencyBuckets are the upper bounds in seconds of the histogram of stage latency.
+var latencyBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// maxPending is the max count of frames whose latency is being measured, the frames which stream functions
+// never respond to are pruned after pendingTTL when it's exceeded.
+const (
+	maxPending = 10000
+	pendingTTL = time.Minute
+)
+
+// pipelineMetrics is the metrics of all pipelines of YoMo-Zipper, the stream functions are shared by pipelines.
+var pipelineMetrics = newMetrics()
+
+type pendingKey struct {
+	stage string
+	tid   string
+}
+
+// histogram counts the observations in cumulative buckets.
+type histogram struct {
+	mutex   sync.Mutex
+	buckets []uint64
+	count   uint64
+	sum     float64
+}
+
+// snapshot returns the count and the sum of observations.
+func (h *histogram) snapshot() (uint64, float64) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.count, h.sum
+}
+
+func (h *histogram) observe(v float64) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.buckets == nil {
+		h.buckets = make([]uint64, len(latencyBuckets))
+	}
+	for i, le := range latencyBuckets {
+		if v <= le {
+			h.buckets[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+// metrics counts the frames and errors of pipelines, the gauges are read from the handler when it's scraped.
+type metrics struct {
+	in      [256]uint64 // the frames which come into the pipelines by data tag.
+	out     [256]uint64 // the frames which come out of the pipelines by data tag.
+	errors  sync.Map    // the count of errors by kind, the value is *uint64.
+	latency sync.Map    // the histogram of latency by stage, the value is *histogram.
+	// namespaces are the frames which come into and out of the pipelines by namespace, the value is *namespaceCounters.
+	namespaces sync.Map
+
+	mutex   sync.Mutex
+	pending map[pendingKey]time.Time // the time when the frame was sent to the stage.
+}
+
+func newMetrics() *metrics {
+	return &metrics{pending: make(map[pendingKey]time.Time)}
+}
+
+// received counts the frame which comes into the pipeline.
+func (m *metrics) received(data *frame.DataFrame) {
+	atomic.AddUint64(&m.in[data.GetDataTagID()], 1)
+}
+
+// sent counts the frame which comes out of the pipeline.
+func (m *metrics) sent(data *frame.DataFrame) {
+	atomic.AddUint64(&m.out[data.GetDataTagID()], 1)
+}
+
+// totals returns the count of frames which come into and out of the pipelines.
+func (m *metrics) totals() (in uint64, out uint64) {
+	for tag := range m.in {
+		in += atomic.LoadUint64(&m.in[tag])
+		out += atomic.LoadUint64(&m.out[tag])
+	}
+	return in, out
+}
+
+// failed counts the error of kind.
+func (m *metrics) failed(kind string) {
+	counter, _ := m.errors.LoadOrStore(kind, new(uint64))
+	atomic.AddUint64(counter.(*uint64), 1)
+}
+
+// dispatched records the time when the frame is sent to the stage.
+func (m *metrics) dispatched(stage string, tid string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if len(m.pending) >= maxPending {
+		now := time.Now()
+		for key, at := range m.pending {
+			if now.Sub(at) > pendingTTL {
+				delete(m.pending, key)
+			}
+		}
+		if len(m.pending) >= maxPending {
+			return
+		}
+	}
+	m.pending[pendingKey{stage, tid}] = time.Now()
+}
+
+// responded observes the latency of the stage when its response of the frame is received, it returns false if
+// the frame wasn't dispatched to the stage.
+func (m *metrics) responded(stage string, tid string) (time.Duration, bool) {
+	m.mutex.Lock()
+	at, ok := m.pending[pendingKey{stage, tid}]
+	delete(m.pending, pendingKey{stage, tid})
+	m.mutex.Unlock()
+	if !ok {
+		return 0, false
+	}
+
+	latency := time.Since(at)
+	h, _ := m.latency.LoadOrStore(stage, &histogram{})
+	h.(*histogram).observe(latency.Seconds())
+	return latency, true
+}
+
+// write writes the metrics in the Prometheus text format, the gauges are read from the handler.
+func (m *metrics) write(w *bufio.Writer, h *quicHandler) {
+	family(w, "yomo_zipper_frames_in_total", "counter", "The frames which come into the pipelines.")
+	for tag := range m.in {
+		if n := atomic.LoadUint64(&m.in[tag]); n > 0 {
+			fmt.Fprintf(w, "yomo_zipper_frames_in_total{tag=\"%d\"} %d\n", tag, n)
+		}
+	}
+	family(w, "yomo_zipper_frames_out_total", "counter", "The frames which come out of the pipelines.")
+	for tag := range m.out {
+		if n := atomic.LoadUint64(&m.out[tag]); n > 0 {
+			fmt.Fprintf(w, "yomo_zipper_frames_out_total{tag=\"%d\"} %d\n", tag, n)
+		}
+	}
+
+	family(w, "yomo_zipper_namespace_frames_in_total", "counter", "The frames which come into the pipelines of namespaces.")
+	for _, namespace := range sortedKeys(&m.namespaces) {
+		v, _ := m.namespaces.Load(namespace)
+		fmt.Fprintf(w, "yomo_zipper_namespace_frames_in_total{namespace=%q} %d\n", namespace, atomic.LoadUint64(&v.(*namespaceCounters).in))
+	}
+	family(w, "yomo_zipper_namespace_frames_out_total", "counter", "The frames which come out of the pipelines of namespaces.")
+	for _, namespace := range sortedKeys(&m.namespaces) {
+		v, _ := m.namespaces.Load(namespace)
+		fmt.Fprintf(w, "yomo_zipper_namespace_frames_out_total{namespace=%q} %d\n", namespace, atomic.LoadUint64(&v.(*namespaceCounters).out))
+	}
+
+	// the usage of tenants limited by the quotas, e.g. for billing.
+	tenants := sortedKeys(&quotaAccounts)
+	family(w, "yomo_zipper_tenant_frames_total", "counter", "The frames written by the sources of tenants within their quotas.")
+	for _, tenant := range tenants {
+		v, _ := quotaAccounts.Load(tenant)
+		fmt.Fprintf(w, "yomo_zipper_tenant_frames_total{tenant=%q} %d\n", tenant, atomic.LoadUint64(&v.(*quotaAccount).framesTotal))
+	}
+	family(w, "yomo_zipper_tenant_bytes_total", "counter", "The bytes of carriages written by the sources of tenants within their quotas.")
+	for _, tenant := range tenants {
+		v, _ := quotaAccounts.Load(tenant)
+		fmt.Fprintf(w, "yomo_zipper_tenant_bytes_total{tenant=%q} %d\n", tenant, atomic.LoadUint64(&v.(*quotaAccount).bytesTotal))
+	}
+	family(w, "yomo_zipper_tenant_connections", "gauge", "The connected clients of tenants.")
+	for _, tenant := range tenants {
+		v, _ := quotaAccounts.Load(tenant)
+		fmt.Fprintf(w, "yomo_zipper_tenant_connections{tenant=%q} %d\n", tenant, v.(*quotaAccount).connected())
+	}
+	family(w, "yomo_zipper_quota_exceeded_total", "counter", "The frames and the connections rejected by the quotas of tenants.")
+	for _, tenant := range tenants {
+		v, _ := quotaAccounts.Load(tenant)
+		account := v.(*quotaAccount)
+		for _, quota := range sortedKeys(&account.exceeded) {
+			n, _ := account.exceeded.Load(quota)
+			fmt.Fprintf(w, "yomo_zipper_quota_exceeded_total{tenant=%q,quota=%q} %d\n", tenant, quota, atomic.LoadUint64(n.(*uint64)))
+		}
+	}
+
+	family(w, "yomo_zipper_stage_latency_seconds", "histogram", "The latency of the stream functions.")
+	for _, stage := range sortedKeys(&m.latency) {
+		v, _ := m.latency.Load(stage)
+		hist := v.(*histogram)
+		hist.mutex.Lock()
+		for i, le := range latencyBuckets {
+			fmt.Fprintf(w, "yomo_zipper_stage_latency_seconds_bucket{stage=%q,le=\"%g\"} %d\n", stage, le, hist.buckets[i])
+		}
+		fmt.Fprintf(w, "yomo_zipper_stage_latency_seconds_bucket{stage=%q,le=\"+Inf\"} %d\n", stage, hist.count)
+		fmt.Fprintf(w, "yomo_zipper_stage_latency_seconds_sum{stage=%q} %g\n", stage, hist.sum)
+		fmt.Fprintf(w, "yomo_zipper_stage_latency_seconds_count{stage=%q} %d\n", stage, hist.count)
+		hist.mutex.Unlock()
+	}
+
+	family(w, "yomo_zipper_errors_total", "counter", "The errors of the pipelines by kind.")
+	for _, kind := range sortedKeys(&m.errors) {
+		v, _ := m.errors.Load(kind)
+		fmt.Fprintf(w, "yomo_zipper_errors_total{kind=%q} %d\n", kind, atomic.LoadUint64(v.(*uint64)))
+	}
+	family(w, "yomo_zipper_corrupted_frames_total", "counter", "The frames dropped because their checksum mismatched.")
+	fmt.Fprintf(w, "yomo_zipper_corrupted_frames_total %d\n", core.CorruptedFrames())
+	family(w, "yomo_zipper_events_dropped_total", "counter", "The events of data flow dropped for the slow subscribers.")
+	fmt.Fprintf(w, "yomo_zipper_events_dropped_total %d\n", atomic.LoadUint64(&pipelineEvents.dropped))
+	family(w, "yomo_log_suppressed_total", "counter", "The repeated log messages suppressed by sampling.")
+	fmt.Fprintf(w, "yomo_log_suppressed_total %d\n", logger.Suppressed())
+
+	if h == nil {
+		return
+	}
+
+	family(w, "yomo_zipper_shedding_dropped_total", "counter", "The frames dropped by load shedding.")
+	dropped := h.shedder.dropped()
+	tags := make([]int, 0, len(dropped))
+	for tag := range dropped {
+		tags = append(tags, int(tag))
+	}
+	sort.Ints(tags)
+	for _, tag := range tags {
+		fmt.Fprintf(w, "yomo_zipper_shedding_dropped_total{tag=\"%d\"} %d\n", tag, dropped[byte(tag)])
+	}
+
+	conns := h.currentConnections()
+	counts := make(map[string]int)
+	namespaces := make(map[string]int)
+	for _, c := range conns {
+		counts[c.Conn.Type.String()]++
+		if c.Namespace() != "" {
+			namespaces[c.Namespace()]++
+		}
+	}
+	family(w, "yomo_zipper_connections", "gauge", "The connected clients by type.")
+	for _, typ := range sortedStrings(counts) {
+		fmt.Fprintf(w, "yomo_zipper_connections{type=%q} %d\n", typ, counts[typ])
+	}
+	family(w, "yomo_zipper_namespace_connections", "gauge", "The connected clients by namespace.")
+	for _, namespace := range sortedStrings(namespaces) {
+		fmt.Fprintf(w, "yomo_zipper_namespace_connections{namespace=%q} %d\n", namespace, namespaces[namespace])
+	}
+
+	_, backlog := h.queues.backlog()
+	family(w, "yomo_zipper_queue_depth", "gauge", "The frames which are waiting for each stage, the empty stage is the output.")
+	for _, stage := range sortedStrings(backlog) {
+		fmt.Fprintf(w, "yomo_zipper_queue_depth{stage=%q} %d\n", stage, backlog[stage])
+	}
+
+	family(w, "yomo_zipper_slow_consumer", "gauge", "The stream functions which are slow consumers, 2 is the bottleneck of workflow.")
+	bottleneck := h.slow.slowest()
+	for _, app := range h.config().allFunctions() {
+		slow, _ := h.slow.state(app.Name)
+		v := 0
+		if app.Name == bottleneck {
+			v = 2
+		} else if slow {
+			v = 1
+		}
+		fmt.Fprintf(w, "yomo_zipper_slow_consumer{stage=%q} %d\n", app.Name, v)
+	}
+
+	// the sessions which fall back to TCP have no stats.
+	labels := make([]string, 0, len(conns))
+	stats := make([]quic.Stats, 0, len(conns))
+	for _, c := range conns {
+		if s, ok := quic.StatsOf(c.Session); ok {
+			labels = append(labels, fmt.Sprintf("name=%q,addr=%q", c.Conn.Name, c.Addr))
+			stats = append(stats, s)
+		}
+	}
+	family(w, "yomo_zipper_quic_rtt_seconds", "gauge", "The smoothed round-trip time of the QUIC sessions.")
+	for i, s := range stats {
+		fmt.Fprintf(w, "yomo_zipper_quic_rtt_seconds{%s} %g\n", labels[i], s.SmoothedRTT.Seconds())
+	}
+	family(w, "yomo_zipper_quic_loss_ratio", "gauge", "The ratio of the lost packets to the sent packets of the QUIC sessions.")
+	for i, s := range stats {
+		fmt.Fprintf(w, "yomo_zipper_quic_loss_ratio{%s} %g\n", labels[i], s.LossRate)
+	}
+}
+
+// family writes the help and type of the metric family.
+func family(w *bufio.Writer, name string, typ string, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+func sortedKeys(m *sync.Map) []string {
+	keys := make([]string, 0)
+	m.Range(func(key, _ interface{}) bool {
+		keys = append(keys, key.(string))
+		return true
+	})
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedStrings(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// metricsHandler serves the metrics of the handler in the Prometheus text format.
+func metricsHandler(h *quicHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		buf := bufio.NewWriter(w)
+		pipelineMetrics.write(buf, h)
+		buf.Flush()
+	})
+}
+
+// serveMetrics serves the metrics on addr at /metrics, it's not authenticated so it should be bound to
+// the internal network.
+func serveMetrics(addr string, h *quicHandler) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metricsHandler(h))
+	server := &http.Server{
+		Addr:    addr,
+		Handler: mux,
+	}
+
+	go func() {
+		logger.Printf("✅ Metrics are exposed on %s/metrics", addr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Error("[zipper] serve metrics failed.", "addr", addr, "err", err)
+		}
+	}()
+
+	return server
+}
@@ -0,0 +1,58 @@
+package zipper
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/internal/frame"
+)
+
+func TestMetricsLatency(t *testing.T) {
+	m := newMetrics()
+	m.dispatched("fn", "tid")
+	m.responded("fn", "tid")
+	// the response which isn't dispatched is ignored.
+	m.responded("fn", "other")
+
+	v, ok := m.latency.Load("fn")
+	assert.True(t, ok)
+	h := v.(*histogram)
+	assert.Equal(t, uint64(1), h.count)
+	assert.Equal(t, uint64(1), h.buckets[len(latencyBuckets)-1])
+	assert.Empty(t, m.pending)
+}
+
+func TestMetricsHandler(t *testing.T) {
+	h := newServerHandler(&WorkflowConfig{}, "")
+	f := frame.NewDataFrame("tid")
+	f.SetCarriage(0x33, []byte("data"))
+	pipelineMetrics.received(f)
+	pipelineMetrics.sent(f)
+	pipelineMetrics.failed(errorSend)
+
+	server := httptest.NewServer(metricsHandler(h))
+	defer server.Close()
+
+	res, err := http.Get(server.URL)
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Contains(t, res.Header.Get("Content-Type"), "text/plain")
+
+	body, err := ioutil.ReadAll(res.Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), "# TYPE yomo_zipper_frames_in_total counter\n")
+	assert.Contains(t, string(body), "yomo_zipper_frames_in_total{tag=\"51\"}")
+	assert.Contains(t, string(body), "yomo_zipper_frames_out_total{tag=\"51\"}")
+	assert.Contains(t, string(body), "yomo_zipper_errors_total{kind=\"send_to_stream_fn\"}")
+	assert.Contains(t, string(body), "# TYPE yomo_zipper_connections gauge\n")
+	assert.Contains(t, string(body), "yomo_log_suppressed_total ")
+
+	res, err = http.Post(server.URL, "text/plain", nil)
+	assert.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, res.StatusCode)
+}
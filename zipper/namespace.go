@@ -0,0 +1,248 @@
+package zipper
+
+import (
+	"context"
+	"path"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"github.com/yomorun/yomo/internal/core"
+	"github.com/yomorun/yomo/internal/frame"
+	"github.com/yomorun/yomo/logger"
+)
+
+// namespaceSeparator separates the namespace and the name of its clients, e.g. "acme/noise". The qualified names
+// key the routing table, the caches of stream functions and the metrics of stages, so the namespaces are isolated.
+const namespaceSeparator = "/"
+
+// validNamespace is the pattern of the names of namespaces.
+var validNamespace = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// Namespace is an isolated workflow hosted by YoMo-Zipper, e.g. of a tenant of the shared deployment. The clients
+// are bound to the namespace by their authenticated identity, the data of its sources is dispatched to its own stream
+// functions only, and it doesn't reach the sinks and the downstream YoMo-Zippers of the default workflow.
+type Namespace struct {
+	// Name is the name of namespace, it prefixes the names of its clients and stages in the admin API and metrics.
+	Name string `yaml:"name"`
+	// Subjects are the patterns of the subjects of principals which are bound to this namespace, e.g. "acme-*".
+	Subjects []string `yaml:"subjects,omitempty"`
+	// Roles are the roles of principals which are bound to this namespace, e.g. the "tenant:acme" scope of JWT.
+	Roles []string `yaml:"roles,omitempty"`
+	// Workflow is the sources and the stream functions of this namespace, all sources of the namespace are allowed
+	// if the sources are empty.
+	Workflow `yaml:",inline"`
+}
+
+// binds indicates if the principal is bound to the namespace by its subject or roles.
+func (n Namespace) binds(p *Principal) bool {
+	return Policy{Subjects: n.Subjects, Roles: n.Roles}.appliesTo(p)
+}
+
+// qualified returns the workflow of namespace whose names of sources, stream functions and shadows are qualified.
+func (n Namespace) qualified() Workflow {
+	qualify := func(apps []App) []App {
+		qualified := make([]App, 0, len(apps))
+		for _, app := range apps {
+			app.Name = qualifiedName(n.Name, app.Name)
+			shadows := make([]string, 0, len(app.Shadows))
+			for _, shadow := range app.Shadows {
+				shadows = append(shadows, qualifiedName(n.Name, shadow))
+			}
+			if len(shadows) > 0 {
+				app.Shadows = shadows
+			}
+			qualified = append(qualified, app)
+		}
+		return qualified
+	}
+	return Workflow{Sources: qualify(n.Sources), Functions: qualify(n.Functions)}
+}
+
+// qualifiedName returns the name of client in the namespace, it's the name itself in the default workflow.
+func qualifiedName(namespace string, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + namespaceSeparator + name
+}
+
+// validateNamespaces returns the problems of the namespaces in the message of Validate.
+func validateNamespaces(conf *WorkflowConfig) string {
+	if len(conf.Namespaces) == 0 {
+		return ""
+	}
+
+	errMsg := ""
+	if conf.Auth == nil {
+		errMsg += "The namespaces require the auth, the clients are bound to them by their identities. "
+	}
+	for _, app := range append(append([]App(nil), conf.Sources...), conf.Functions...) {
+		if strings.Contains(app.Name, namespaceSeparator) {
+			errMsg += "The name " + app.Name + " must not contain '" + namespaceSeparator + "' when the namespaces are configured. "
+		}
+	}
+
+	declared := make(map[string]bool, len(conf.Namespaces))
+	for _, ns := range conf.Namespaces {
+		if !validNamespace.MatchString(ns.Name) {
+			errMsg += "The name of namespace " + ns.Name + " must be letters, digits, '_', '.' or '-'. "
+		}
+		if declared[ns.Name] {
+			errMsg += "The namespace " + ns.Name + " is declared more than once. "
+		}
+		declared[ns.Name] = true
+
+		if len(ns.Subjects) == 0 && len(ns.Roles) == 0 {
+			errMsg += "The namespace " + ns.Name + " requires the subjects or the roles. "
+		}
+		for _, pattern := range ns.Subjects {
+			if _, err := path.Match(pattern, ""); err != nil {
+				errMsg += "The pattern " + pattern + " of namespace " + ns.Name + " is malformed. "
+			}
+		}
+		if len(ns.Functions) == 0 {
+			errMsg += "The namespace " + ns.Name + " requires the functions. "
+		}
+		for _, app := range append(append([]App(nil), ns.Sources...), ns.Functions...) {
+			if app.Name == "" || strings.Contains(app.Name, namespaceSeparator) {
+				errMsg += "The names of the clients of namespace " + ns.Name + " must not be empty or contain '" + namespaceSeparator + "'. "
+				break
+			}
+		}
+		// the serverless and WASM functions are shared by the name in the process of YoMo-Zipper.
+		for _, app := range ns.Functions {
+			if app.Invoke != nil || app.Wasm != nil {
+				errMsg += "The function " + app.Name + " of namespace " + ns.Name + " can't be invoked or run as WASM. "
+			}
+			if !app.LoadBalance.valid() {
+				errMsg += "The load balance of function " + app.Name + " of namespace " + ns.Name + " must be round_robin or latency. "
+			}
+		}
+		errMsg += duplicatedApps("function of namespace "+ns.Name, ns.Functions) + duplicatedApps("source of namespace "+ns.Name, ns.Sources)
+	}
+	return errMsg
+}
+
+// namespaceOf returns the namespace which the principal is bound to, the first namespace which binds it is chosen.
+// It's empty for the default workflow, e.g. the client isn't authenticated.
+func (c *WorkflowConfig) namespaceOf(p *Principal) string {
+	if p == nil {
+		return ""
+	}
+	for _, ns := range c.Namespaces {
+		if ns.binds(p) {
+			return ns.Name
+		}
+	}
+	return ""
+}
+
+// namespaceConfig returns the config of the clients of namespace, it's the config whose workflow is the qualified
+// workflow of namespace, or the config itself for the default workflow.
+func (c *WorkflowConfig) namespaceConfig(namespace string) *WorkflowConfig {
+	if namespace == "" {
+		return c
+	}
+	for _, ns := range c.Namespaces {
+		if ns.Name == namespace {
+			conf := *c
+			conf.Workflow = ns.qualified()
+			return &conf
+		}
+	}
+	// the namespace is removed, its clients match nothing.
+	conf := *c
+	conf.Workflow = Workflow{}
+	return &conf
+}
+
+// declaresNamespace indicates if the namespace is declared.
+func (c *WorkflowConfig) declaresNamespace(namespace string) bool {
+	for _, ns := range c.Namespaces {
+		if ns.Name == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// allFunctions returns the stream functions of the workflow and of the namespaces, the names of the latter are
+// qualified.
+func (c *WorkflowConfig) allFunctions() []App {
+	apps := append([]App(nil), c.Functions...)
+	for _, ns := range c.Namespaces {
+		apps = append(apps, ns.qualified().Functions...)
+	}
+	return apps
+}
+
+// namespaced returns the handshake of the client in the namespace, its name is qualified.
+func namespaced(payload *frame.HandshakeFrame, namespace string) *frame.HandshakeFrame {
+	if namespace == "" {
+		return payload
+	}
+	handshake := *payload
+	handshake.Name = qualifiedName(namespace, payload.Name)
+	return &handshake
+}
+
+// dispatchNamespace dispatches the stream of source to the stream functions of its namespace.
+func (s *quicHandler) dispatchNamespace(ctx context.Context, item sourceStream) chan *frame.DataFrame {
+	conf := s.config().namespaceConfig(item.namespace)
+	next := readDataFromSource(ctx, item.name, item.session, item.stream, s.shedder, conf)
+	return s.pipeWorkflow(ctx, countNamespace(ctx, next, item.namespace), conf)
+}
+
+// countNamespace counts the frames which come into the pipeline of namespace.
+func countNamespace(ctx context.Context, upstream chan *frame.DataFrame, namespace string) chan *frame.DataFrame {
+	next := make(chan *frame.DataFrame, bufferSize)
+
+	go func() {
+		defer close(next)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case item, ok := <-upstream:
+				if !ok {
+					return
+				}
+				pipelineMetrics.namespaceReceived(namespace)
+				next <- item
+			}
+		}
+	}()
+
+	return next
+}
+
+// handleNamespaceOutput handles the data after running all stream functions of the namespace, the data isn't sent
+// to the sinks and the downstream YoMo-Zippers of the default workflow.
+func (s *quicHandler) handleNamespaceOutput(namespace string, data *frame.DataFrame) {
+	pipelineMetrics.sent(data)
+	pipelineMetrics.namespaceSent(namespace)
+	s.capturer.capture(captureEgress, data)
+	pipelineTimelines.record(StepSent, "", data, "")
+	core.CloseCarriage(data)
+	logger.Debug("[zipper] receive data after running all Stream Functions of namespace, will drop it.", "namespace", namespace, "TransactionID", data.TransactionID())
+}
+
+// namespaceCounters are the frames which come into and out of the pipelines of a namespace.
+type namespaceCounters struct {
+	in  uint64
+	out uint64
+}
+
+// namespaceReceived counts the frame which comes into the pipeline of namespace.
+func (m *metrics) namespaceReceived(namespace string) {
+	counters, _ := m.namespaces.LoadOrStore(namespace, &namespaceCounters{})
+	atomic.AddUint64(&counters.(*namespaceCounters).in, 1)
+}
+
+// namespaceSent counts the frame which comes out of the pipeline of namespace.
+func (m *metrics) namespaceSent(namespace string) {
+	counters, _ := m.namespaces.LoadOrStore(namespace, &namespaceCounters{})
+	atomic.AddUint64(&counters.(*namespaceCounters).out, 1)
+}
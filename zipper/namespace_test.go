@@ -0,0 +1,152 @@
+package zipper
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/core/rx"
+	"github.com/yomorun/yomo/internal/core"
+	"github.com/yomorun/yomo/internal/frame"
+	"github.com/yomorun/yomo/source"
+	"github.com/yomorun/yomo/streamfunction"
+)
+
+func TestValidateNamespaces(t *testing.T) {
+	conf := &WorkflowConfig{Name: "zipper", Host: "localhost", Port: 9000, Workflow: Workflow{Functions: []App{{Name: "a/b"}}}, Namespaces: []Namespace{
+		{Name: "acme", Subjects: []string{"[acme"}, Workflow: Workflow{Functions: []App{{Name: "noise", Wasm: &WasmConfig{File: "noise.wasm"}}}}},
+		{Name: "acme", Roles: []string{"tenant:acme"}},
+		{Name: "glo/bex", Workflow: Workflow{Functions: []App{{Name: "noise/1"}, {Name: "echo"}, {Name: "echo"}}}},
+	}}
+	err := Validate(conf)
+	if assert.Error(t, err) {
+		for _, msg := range []string{
+			"The namespaces require the auth, the clients are bound to them by their identities.",
+			"The name a/b must not contain '/' when the namespaces are configured.",
+			"The pattern [acme of namespace acme is malformed.",
+			"The function noise of namespace acme can't be invoked or run as WASM.",
+			"The namespace acme is declared more than once.",
+			"The namespace acme requires the functions.",
+			"The name of namespace glo/bex must be letters, digits, '_', '.' or '-'.",
+			"The namespace glo/bex requires the subjects or the roles.",
+			"The names of the clients of namespace glo/bex must not be empty or contain '/'.",
+			"The function of namespace glo/bex echo is declared more than once.",
+		} {
+			assert.Contains(t, err.Error(), msg)
+		}
+	}
+}
+
+func TestNamespaceConfig(t *testing.T) {
+	conf := &WorkflowConfig{Name: "zipper", Workflow: Workflow{Functions: []App{{Name: "noise"}}}, MaxHops: 4, Namespaces: []Namespace{
+		{Name: "acme", Subjects: []string{"acme-*"}, Workflow: Workflow{
+			Sources:   []App{{Name: "sensor"}},
+			Functions: []App{{Name: "noise", Shadows: []string{"noise-canary"}}},
+		}},
+		{Name: "globex", Roles: []string{"tenant:globex"}, Workflow: Workflow{Functions: []App{{Name: "echo"}}}},
+	}}
+
+	assert.Equal(t, "", conf.namespaceOf(nil))
+	assert.Equal(t, "acme", conf.namespaceOf(&Principal{Subject: "acme-sensors"}))
+	assert.Equal(t, "globex", conf.namespaceOf(&Principal{Subject: "bob", Roles: []string{"tenant:globex"}}))
+	assert.Equal(t, "", conf.namespaceOf(&Principal{Subject: "ops"}))
+
+	// the workflow of namespace is qualified, the other settings are shared.
+	assert.Same(t, conf, conf.namespaceConfig(""))
+	acme := conf.namespaceConfig("acme")
+	assert.Equal(t, []App{{Name: "acme/sensor"}}, acme.Sources)
+	assert.Equal(t, []App{{Name: "acme/noise", Shadows: []string{"acme/noise-canary"}}}, acme.Functions)
+	assert.Equal(t, 4, acme.MaxHops)
+	assert.Equal(t, []App{{Name: "noise", Shadows: []string{"noise-canary"}}}, conf.Namespaces[0].Functions)
+	assert.Empty(t, conf.namespaceConfig("removed").Functions)
+
+	names := make([]string, 0)
+	for _, app := range conf.allFunctions() {
+		names = append(names, app.Name)
+	}
+	assert.Equal(t, []string{"noise", "acme/noise", "globex/echo"}, names)
+
+	handshake := frame.NewHandshakeFrame("noise", byte(core.ConnTypeStreamFunction))
+	assert.Equal(t, "acme/noise", namespaced(handshake, "acme").Name)
+	assert.Equal(t, "noise", handshake.Name)
+}
+
+func TestNamespaceIsolation(t *testing.T) {
+	conf := &WorkflowConfig{
+		Name: "zipper",
+		Host: "localhost",
+		Port: 9031,
+		Auth: &AuthConfig{Tokens: []StaticToken{
+			{Subject: "acme", Token: "acme-token"},
+			{Subject: "globex", Token: "globex-token"},
+			{Subject: "ops", Token: "ops-token"},
+		}},
+		Workflow: Workflow{Functions: []App{{Name: "noise"}}},
+		Namespaces: []Namespace{
+			{Name: "acme", Subjects: []string{"acme"}, Workflow: Workflow{Functions: []App{{Name: "noise"}}}},
+			{Name: "globex", Subjects: []string{"globex"}, Workflow: Workflow{Functions: []App{{Name: "noise"}}}},
+		},
+	}
+	assert.NoError(t, Validate(conf))
+	handler := newServerHandler(conf, "")
+	authenticator, err := newAuthenticator(conf.Auth)
+	assert.NoError(t, err)
+	handler.authenticator = authenticator
+	svr := New(conf)
+	go svr.ServeWithHandler(fmt.Sprintf("%s:%d", conf.Host, conf.Port), handler)
+	defer svr.Close()
+
+	// the stream function of the same name is registered in each namespace by its token.
+	received := make(map[string]chan string)
+	for _, token := range []string{"acme-token", "globex-token", "ops-token"} {
+		ch := make(chan string, 10)
+		received[token] = ch
+		sfn, err := streamfunction.New("noise", streamfunction.WithToken(token)).Connect(conf.Host, conf.Port)
+		assert.NoError(t, err)
+		defer sfn.Close()
+		go sfn.Pipe(func(rxstream rx.Stream) rx.Stream {
+			return rxstream.RawBytes().Map(func(_ context.Context, i interface{}) (interface{}, error) {
+				select {
+				case ch <- string(i.([]byte)):
+				default:
+				}
+				return nil, nil
+			})
+		})
+	}
+	names := make([]string, 0)
+	for _, c := range handler.currentConnections() {
+		names = append(names, c.Conn.Name+":"+c.Namespace())
+	}
+	assert.ElementsMatch(t, []string{"acme/noise:acme", "globex/noise:globex", "noise:"}, names)
+
+	// the data of source is dispatched to the stream function of its namespace only.
+	src, err := source.New("sensor", source.WithToken("acme-token")).Connect(conf.Host, conf.Port)
+	assert.NoError(t, err)
+	defer src.Close()
+	for {
+		_, err := src.Write([]byte("acme data"))
+		assert.NoError(t, err)
+		select {
+		case data := <-received["acme-token"]:
+			assert.Equal(t, "acme data", data)
+		case <-time.After(200 * time.Millisecond):
+			continue
+		}
+		break
+	}
+	time.Sleep(200 * time.Millisecond)
+	assert.Empty(t, received["globex-token"])
+	assert.Empty(t, received["ops-token"])
+
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	pipelineMetrics.write(w, handler)
+	w.Flush()
+	assert.Contains(t, buf.String(), `yomo_zipper_namespace_frames_in_total{namespace="acme"}`)
+	assert.Contains(t, buf.String(), `yomo_zipper_namespace_connections{namespace="globex"} 1`)
+}
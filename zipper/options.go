@@ -1,11 +1,22 @@
 package zipper
 
+import "io"
+
 // Option is a function that applies a YoMo-Zipper option.
 type Option func(o *options)
 
 // options are the options for YoMo-Zipper.
 type options struct {
-	meshConfURL string // meshConfURL is the URL of edge-mesh config.
+	meshConfURL string                       // meshConfURL is the URL of edge-mesh config.
+	onDropped   func(tag byte, total uint64) // onDropped is the callback when a frame is dropped by load shedding.
+	localFuncs  map[string]LocalStreamFunc   // localFuncs are the stream functions which run in the process of zipper.
+	handlers    map[string]LocalHandler      // handlers are the handlers of data with tags which run in the process of zipper.
+	onOutput    func(tag byte, data []byte)  // onOutput is called with the output of workflow in the embedded YoMo-Zipper.
+	zeroRTT     bool                         // zeroRTT indicates if the 0-RTT data of clients is accepted.
+	report      io.Writer                    // report is the writer of shutdown report.
+	tcp         bool                         // tcp enables the TCP fallback for the clients whose UDP is blocked.
+	auth        Authenticator                // auth authenticates the clients, it overrides the auth of config.
+	authz       Authorizer                   // authz authorizes the clients, it overrides the authz of config.
 }
 
 // WithMeshConfURL sets the initial edge-mesh config URL for the YoMo-Zipper.
@@ -15,6 +26,82 @@ func WithMeshConfURL(url string) Option {
 	}
 }
 
+// WithDroppedHandler sets the callback which is called when a frame is dropped by load shedding,
+// tag is the data tag of the dropped frame and total is the count of dropped frames with this tag.
+func WithDroppedHandler(f func(tag byte, total uint64)) Option {
+	return func(o *options) {
+		o.onDropped = f
+	}
+}
+
+// WithLocalStreamFunc registers a stream function which runs in the process of YoMo-Zipper,
+// the name should match the name of functions in workflow.yaml.
+func WithLocalStreamFunc(name string, fn LocalStreamFunc) Option {
+	return func(o *options) {
+		if o.localFuncs == nil {
+			o.localFuncs = make(map[string]LocalStreamFunc)
+		}
+		o.localFuncs[name] = fn
+	}
+}
+
+// WithLocalHandler registers a handler which runs in the process of YoMo-Zipper as the stream function of name,
+// unlike WithLocalStreamFunc, it observes the tag of data and may respond many results with their own tags.
+func WithLocalHandler(name string, fn LocalHandler) Option {
+	return func(o *options) {
+		if o.handlers == nil {
+			o.handlers = make(map[string]LocalHandler)
+		}
+		o.handlers[name] = fn
+	}
+}
+
+// WithOutputHandler sets the handler of the output of workflow in the embedded YoMo-Zipper, it's called with the
+// data which has passed all stream functions.
+func WithOutputHandler(fn func(tag byte, data []byte)) Option {
+	return func(o *options) {
+		o.onOutput = fn
+	}
+}
+
+// With0RTT accepts the 0-RTT data of reconnecting clients, note that the 0-RTT data can be replayed by attackers.
+func With0RTT() Option {
+	return func(o *options) {
+		o.zeroRTT = true
+	}
+}
+
+// WithTCPFallback listens TLS over TCP on the same address, so the clients whose UDP is blocked can connect.
+func WithTCPFallback() Option {
+	return func(o *options) {
+		o.tcp = true
+	}
+}
+
+// WithShutdownReport writes the shutdown report as a JSON line to w when YoMo-Zipper is closed,
+// the report is logged by default.
+func WithShutdownReport(w io.Writer) Option {
+	return func(o *options) {
+		o.report = w
+	}
+}
+
+// WithAuthenticator authenticates the clients in the handshake by the authenticator instead of the auth of
+// workflow config, e.g. by the tokens of an identity provider.
+func WithAuthenticator(a Authenticator) Option {
+	return func(o *options) {
+		o.auth = a
+	}
+}
+
+// WithAuthorizer authorizes the authenticated clients by the authorizer instead of the authz of workflow config,
+// e.g. by the policies of an external policy engine.
+func WithAuthorizer(a Authorizer) Option {
+	return func(o *options) {
+		o.authz = a
+	}
+}
+
 // newOptions creates a new options for YoMo-Zipper.
 func newOptions(opts ...Option) *options {
 	options := &options{}
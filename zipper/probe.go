@@ -0,0 +1,261 @@
+package zipper
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yomorun/yomo/internal/client"
+	"github.com/yomorun/yomo/internal/core"
+	"github.com/yomorun/yomo/internal/frame"
+	"github.com/yomorun/yomo/logger"
+	"github.com/yomorun/yomo/zipper/sli"
+)
+
+const (
+	// probeTransactionPrefix is the prefix of the transaction ID of probe frames.
+	probeTransactionPrefix = "yomo-probe-"
+	// DefaultProbeInterval is the default interval of sending probe frames.
+	DefaultProbeInterval = 10 * time.Second
+	// DefaultProbeTimeout is the default timeout of a probe frame going through the pipeline.
+	DefaultProbeTimeout = 5 * time.Second
+	// DefaultSLIExportInterval is the default interval of exporting SLIs.
+	DefaultSLIExportInterval = 30 * time.Second
+)
+
+// SLIConfig represents the config of synthetic end-to-end SLIs.
+type SLIConfig struct {
+	// Endpoint is the OTLP/HTTP endpoint which the SLIs are exported to, e.g. the OTLP gateway of Grafana Cloud.
+	// The SLIs are only recorded if the endpoint is empty.
+	Endpoint string `yaml:"endpoint,omitempty"`
+	// Headers are sent with each export request, such as "Authorization".
+	Headers map[string]string `yaml:"headers,omitempty"`
+	// Interval is the interval of exporting SLIs.
+	Interval time.Duration `yaml:"interval,omitempty"`
+	// Probes send synthetic frames through the pipeline.
+	Probes []Probe `yaml:"probes"`
+}
+
+// Probe represents a synthetic frame which is sent through the whole pipeline periodically,
+// the stream functions should pass the payload of probe through to measure the availability and latency.
+type Probe struct {
+	Name     string        `yaml:"name"`
+	Interval time.Duration `yaml:"interval,omitempty"`
+	Timeout  time.Duration `yaml:"timeout,omitempty"`
+	Tag      byte          `yaml:"tag,omitempty"`
+	Payload  string        `yaml:"payload,omitempty"`
+}
+
+// prober sends the probe frames and records the SLIs.
+type prober struct {
+	conf     *SLIConfig
+	recorder *sli.Recorder
+	exporter sli.Exporter
+	pending  sync.Map // pending is the done channel of probe frames by transaction ID.
+	// tlsConfig is the TLS config for connecting to YoMo-Zipper.
+	tlsConfig *tls.Config
+	// token authenticates the probe clients of this YoMo-Zipper, it's generated at start and never leaves the process,
+	// so the other clients can't pass the allowlist of sources by the name of probes.
+	token string
+}
+
+func newProber(conf *SLIConfig, service string) *prober {
+	p := &prober{
+		conf:     conf,
+		recorder: sli.NewRecorder(),
+		token:    newProbeToken(),
+	}
+	if conf.Endpoint != "" {
+		p.exporter = sli.NewOTLPExporter(conf.Endpoint, conf.Headers, service)
+	}
+	return p
+}
+
+// run the probes against the zipper which listens on endpoint.
+func (p *prober) run(ctx context.Context, endpoint string) {
+	host, portStr, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		logger.Error("[zipper] the endpoint of probes is invalid.", "endpoint", endpoint, "err", err)
+		return
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		logger.Error("[zipper] the port of probes is invalid.", "endpoint", endpoint, "err", err)
+		return
+	}
+
+	for _, probe := range p.conf.Probes {
+		go p.runProbe(ctx, probe, probeHost(host), port)
+	}
+
+	if p.exporter != nil {
+		go p.export(ctx)
+	}
+}
+
+// probeTLSConfig returns the TLS config of probes, the probes connect to this YoMo-Zipper by the loopback address,
+// so the server name is the first name in its own certificate.
+func probeTLSConfig(conf *tls.Config) *tls.Config {
+	if conf == nil || len(conf.Certificates) == 0 || len(conf.Certificates[0].Certificate) == 0 {
+		return conf
+	}
+	leaf, err := x509.ParseCertificate(conf.Certificates[0].Certificate[0])
+	if err != nil {
+		return conf
+	}
+
+	conf = conf.Clone()
+	if len(leaf.DNSNames) > 0 {
+		conf.ServerName = leaf.DNSNames[0]
+	} else if len(leaf.IPAddresses) > 0 {
+		conf.ServerName = leaf.IPAddresses[0].String()
+	}
+	return conf
+}
+
+// probeHost returns the host which the probes dial, the unspecified host of the endpoint is replaced by loopback.
+func probeHost(host string) string {
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		return "127.0.0.1"
+	}
+	return host
+}
+
+// runProbe sends the probe frames periodically by a YoMo-Source client.
+func (p *prober) runProbe(ctx context.Context, probe Probe, host string, port int) {
+	interval := probe.Interval
+	if interval <= 0 {
+		interval = DefaultProbeInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var cli *client.Impl
+	defer func() {
+		if cli != nil {
+			cli.Close()
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if cli == nil || cli.Stream == nil {
+				if cli != nil {
+					cli.Close()
+				}
+				cli = client.New(probeTransactionPrefix+probe.Name, core.ConnTypeSource)
+				cli.SetTLSConfig(p.tlsConfig)
+				cli.SetToken(p.token)
+				cli, _ = cli.BaseConnect(host, port)
+			}
+			if cli == nil || cli.Stream == nil {
+				logger.Error("[zipper] the probe can't connect to zipper.", "probe", probe.Name)
+				p.recorder.Record(sli.Result{Probe: probe.Name})
+				continue
+			}
+
+			res := p.probe(ctx, probe, cli.Stream)
+			p.recorder.Record(res)
+			logger.Debug("[zipper] probe result.", "probe", probe.Name, "success", res.Success, "latency", res.Latency)
+		}
+	}
+}
+
+// probe sends a probe frame and waits for it going through the pipeline.
+func (p *prober) probe(ctx context.Context, probe Probe, stream *core.FrameStream) sli.Result {
+	timeout := probe.Timeout
+	if timeout <= 0 {
+		timeout = DefaultProbeTimeout
+	}
+	tag := probe.Tag
+	if tag == 0 {
+		tag = 0x10
+	}
+
+	txid := probeTransactionPrefix + probe.Name + "-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	done := make(chan struct{})
+	p.pending.Store(txid, done)
+	defer p.pending.Delete(txid)
+
+	f := frame.NewDataFrame(txid)
+	f.SetCarriage(tag, []byte(probe.Payload))
+
+	start := time.Now()
+	if _, err := stream.WriteFrame(f); err != nil {
+		logger.Error("[zipper] send the probe frame failed.", "probe", probe.Name, "err", err)
+		return sli.Result{Probe: probe.Name}
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		return sli.Result{Probe: probe.Name, Success: true, Latency: time.Since(start)}
+	case <-timer.C:
+		return sli.Result{Probe: probe.Name}
+	case <-ctx.Done():
+		return sli.Result{Probe: probe.Name}
+	}
+}
+
+// complete marks the probe frame as done, returns false if it's not a probe frame.
+func (p *prober) complete(txid string) bool {
+	if !strings.HasPrefix(txid, probeTransactionPrefix) {
+		return false
+	}
+
+	if done, ok := p.pending.LoadAndDelete(txid); ok {
+		close(done.(chan struct{}))
+	}
+	return true
+}
+
+// authenticate indicates if the handshake is from a probe client of this YoMo-Zipper.
+func (p *prober) authenticate(handshake *frame.HandshakeFrame) bool {
+	if p == nil || !strings.HasPrefix(handshake.Name, probeTransactionPrefix) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(handshake.Token), []byte(p.token)) == 1
+}
+
+// newProbeToken generates a random token for the probe clients.
+func newProbeToken() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// export the SLIs periodically.
+func (p *prober) export(ctx context.Context) {
+	interval := p.conf.Interval
+	if interval <= 0 {
+		interval = DefaultSLIExportInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.exporter.Export(ctx, p.recorder.Snapshots()); err != nil {
+				logger.Error("[zipper] export the SLIs failed.", "endpoint", p.conf.Endpoint, "err", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package zipper
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestProberComplete(t *testing.T) {
+	p := newProber(&SLIConfig{}, "test")
+	done := make(chan struct{})
+	p.pending.Store(probeTransactionPrefix+"p1-1", done)
+
+	assert.False(t, p.complete("1234"))
+	assert.True(t, p.complete(probeTransactionPrefix+"p1-1"))
+	_, ok := <-done
+	assert.False(t, ok)
+}
+
+func TestParseSLIConfig(t *testing.T) {
+	conf := &WorkflowConfig{}
+	err := yaml.Unmarshal([]byte(`
+sli:
+  endpoint: https://otlp.example.com/otlp
+  interval: 1m
+  probes:
+    - name: p1
+      interval: 5s
+      timeout: 2s
+      payload: "{}"
+`), conf)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute, conf.SLI.Interval)
+	assert.Equal(t, Probe{Name: "p1", Interval: 5 * time.Second, Timeout: 2 * time.Second, Payload: "{}"}, conf.SLI.Probes[0])
+}
+
+func TestProbeTLSConfig(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		DNSNames:     []string{"zipper.yomo.run"},
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+
+	conf := &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+	assert.Equal(t, "zipper.yomo.run", probeTLSConfig(conf).ServerName)
+	// the TLS config of edge-mesh senders is not modified.
+	assert.Equal(t, "", conf.ServerName)
+
+	assert.Equal(t, "127.0.0.1", probeHost("0.0.0.0"))
+	assert.Equal(t, "127.0.0.1", probeHost("::"))
+	assert.Equal(t, "10.0.0.1", probeHost("10.0.0.1"))
+}
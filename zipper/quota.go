@@ -0,0 +1,298 @@
+package zipper
+
+import (
+	"fmt"
+	"path"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yomorun/yomo/core/quic"
+	"github.com/yomorun/yomo/internal/core"
+	"github.com/yomorun/yomo/internal/frame"
+	"github.com/yomorun/yomo/logger"
+)
+
+// The names of quotas, they're told to the clients and label the metrics of exceeded quotas.
+const (
+	QuotaMaxConnections  = "max_connections"
+	QuotaFramesPerSecond = "frames_per_second"
+	QuotaBytesPerDay     = "bytes_per_day"
+)
+
+// errorQuota is the frame dropped because the quota of the tenant of source is exceeded.
+const errorQuota = "quota_exceeded"
+
+// errQuotaExceeded is the message of rejection when the max connections of the tenant are reached.
+const errQuotaExceeded = "the max connections of the tenant are reached"
+
+// secondsPerDay is the window of the quota of bytes, the days are in UTC.
+const secondsPerDay = 24 * 60 * 60
+
+// Quota limits the usage of a tenant, the connections are limited in the handshake, and the frames and the bytes of
+// carriages written by the sources are limited at runtime. The usage is accounted by the namespace for the quota of
+// namespace, or by the subject of each principal for the quota of subjects and roles, so each app of a tenant has
+// its own usage.
+type Quota struct {
+	// Subjects are the patterns of the subjects of principals which the quota applies to, e.g. "acme-*".
+	Subjects []string `yaml:"subjects,omitempty"`
+	// Roles are the roles of principals which the quota applies to.
+	Roles []string `yaml:"roles,omitempty"`
+	// Namespace applies the quota to all clients of the namespace, they share the usage.
+	Namespace string `yaml:"namespace,omitempty"`
+	// MaxConnections is the max concurrent connections of the tenant, the handshake is rejected when it's reached.
+	MaxConnections int `yaml:"max_connections,omitempty"`
+	// FramesPerSecond is the max frames written by the sources of the tenant in each second.
+	FramesPerSecond int `yaml:"frames_per_second,omitempty"`
+	// BytesPerDay is the max bytes of carriages written by the sources of the tenant in each day of UTC, the
+	// streamed carriages are counted by the frames only since their sizes are unknown when they arrive.
+	BytesPerDay int64 `yaml:"bytes_per_day,omitempty"`
+}
+
+// tenantOf returns the tenant whose usage is limited by the quota, it's empty if the quota doesn't apply to the
+// client of the principal in the namespace.
+func (q Quota) tenantOf(p *Principal, namespace string) string {
+	if q.Namespace != "" {
+		if q.Namespace == namespace {
+			return namespace
+		}
+		return ""
+	}
+	if (Policy{Subjects: q.Subjects, Roles: q.Roles}).appliesTo(p) {
+		return p.Subject
+	}
+	return ""
+}
+
+func (q Quota) validate(conf *WorkflowConfig) string {
+	errMsg := ""
+	if q.Namespace == "" && len(q.Subjects) == 0 && len(q.Roles) == 0 {
+		errMsg += "The quota requires the subjects, the roles or the namespace. "
+	}
+	if q.Namespace != "" && (len(q.Subjects) > 0 || len(q.Roles) > 0) {
+		errMsg += "The quota of namespace " + q.Namespace + " must not have the subjects or the roles. "
+	}
+	if q.Namespace != "" && !conf.declaresNamespace(q.Namespace) {
+		errMsg += "The namespace " + q.Namespace + " of quota is not declared. "
+	}
+	for _, pattern := range q.Subjects {
+		if _, err := path.Match(pattern, ""); err != nil {
+			errMsg += "The pattern " + pattern + " of quota is malformed. "
+		}
+	}
+	if q.MaxConnections < 0 || q.FramesPerSecond < 0 || q.BytesPerDay < 0 {
+		errMsg += "The limits of quota must not be negative. "
+	}
+	if q.MaxConnections == 0 && q.FramesPerSecond == 0 && q.BytesPerDay == 0 {
+		errMsg += "The quota requires max_connections, frames_per_second or bytes_per_day. "
+	}
+	return errMsg
+}
+
+// validateQuotas returns the problems of the quotas in the message of Validate.
+func validateQuotas(conf *WorkflowConfig) string {
+	if len(conf.Quotas) == 0 {
+		return ""
+	}
+
+	errMsg := ""
+	if conf.Auth == nil {
+		errMsg += "The quotas require the auth, the usage is accounted by the identities of clients. "
+	}
+	for _, q := range conf.Quotas {
+		errMsg += q.validate(conf)
+	}
+	return errMsg
+}
+
+// quotaOf returns the first quota which applies to the client of the principal in the namespace, and the tenant
+// whose usage it limits. ok is false if the client isn't limited, e.g. it isn't authenticated.
+func (c *WorkflowConfig) quotaOf(p *Principal, namespace string) (quota Quota, tenant string, ok bool) {
+	if p == nil {
+		return Quota{}, "", false
+	}
+	for _, q := range c.Quotas {
+		if tenant := q.tenantOf(p, namespace); tenant != "" {
+			return q, tenant, true
+		}
+	}
+	return Quota{}, "", false
+}
+
+// quotaAccounts are the usage of tenants, the value is *quotaAccount. They're kept when the clients disconnect, so
+// the usage of the day and the counters for billing survive the reconnections.
+var quotaAccounts = sync.Map{}
+
+// sessionQuotas are the quotas of the sessions of limited clients, the value is *sessionQuota.
+var sessionQuotas = sync.Map{}
+
+// quotaAccount is the usage of a tenant.
+type quotaAccount struct {
+	mutex       sync.Mutex
+	connections int
+	second      int64 // second is the unix second of the window of frames.
+	frames      int   // frames are the frames in the window of second.
+	day         int64 // day is the count of days since the unix epoch of the window of bytes.
+	bytes       int64 // bytes are the bytes in the window of day.
+
+	// the counters for billing, they're never reset.
+	framesTotal uint64
+	bytesTotal  uint64
+	exceeded    sync.Map // exceeded is the count of frames and connections rejected by the name of quota, the value is *uint64.
+}
+
+// accountOf returns the usage of the tenant.
+func accountOf(tenant string) *quotaAccount {
+	account, _ := quotaAccounts.LoadOrStore(tenant, &quotaAccount{})
+	return account.(*quotaAccount)
+}
+
+// connect takes a connection of the tenant, returns false if the max connections are reached.
+func (a *quotaAccount) connect(q Quota) bool {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if q.MaxConnections > 0 && a.connections >= q.MaxConnections {
+		a.exceed(QuotaMaxConnections)
+		return false
+	}
+	a.connections++
+	return true
+}
+
+// disconnect releases a connection of the tenant.
+func (a *quotaAccount) disconnect() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.connections--
+}
+
+// connected returns the count of connections of the tenant.
+func (a *quotaAccount) connected() int {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.connections
+}
+
+// usage returns the usage of the tenant at now.
+func (a *quotaAccount) usage(tenant string, now time.Time) TenantUsage {
+	a.mutex.Lock()
+	usage := TenantUsage{Tenant: tenant, Connections: a.connections}
+	if a.day == now.Unix()/secondsPerDay {
+		usage.BytesToday = a.bytes
+	}
+	a.mutex.Unlock()
+
+	usage.Frames = atomic.LoadUint64(&a.framesTotal)
+	usage.Bytes = atomic.LoadUint64(&a.bytesTotal)
+	a.exceeded.Range(func(key, value interface{}) bool {
+		if usage.Exceeded == nil {
+			usage.Exceeded = make(map[string]uint64)
+		}
+		usage.Exceeded[key.(string)] = atomic.LoadUint64(value.(*uint64))
+		return true
+	})
+	return usage
+}
+
+// use takes a frame of size bytes at now, the name of exceeded quota and when it's available again are returned if
+// the frame exceeds the quota, the exceeded frame isn't counted.
+func (a *quotaAccount) use(q Quota, size int, now time.Time) (exceeded string, retryAfter time.Duration) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if second := now.Unix(); second != a.second {
+		a.second, a.frames = second, 0
+	}
+	if q.FramesPerSecond > 0 && a.frames >= q.FramesPerSecond {
+		a.exceed(QuotaFramesPerSecond)
+		return QuotaFramesPerSecond, time.Unix(a.second+1, 0).Sub(now)
+	}
+	if today := now.Unix() / secondsPerDay; today != a.day {
+		a.day, a.bytes = today, 0
+	}
+	if q.BytesPerDay > 0 && a.bytes+int64(size) > q.BytesPerDay {
+		a.exceed(QuotaBytesPerDay)
+		return QuotaBytesPerDay, time.Unix((a.day+1)*secondsPerDay, 0).Sub(now)
+	}
+
+	a.frames++
+	a.bytes += int64(size)
+	atomic.AddUint64(&a.framesTotal, 1)
+	atomic.AddUint64(&a.bytesTotal, uint64(size))
+	return "", 0
+}
+
+// exceed counts the frame or the connection rejected by the quota.
+func (a *quotaAccount) exceed(quota string) {
+	v, _ := a.exceeded.LoadOrStore(quota, new(uint64))
+	atomic.AddUint64(v.(*uint64), 1)
+}
+
+// sessionQuota is the quota of the session of a limited client.
+type sessionQuota struct {
+	quota   Quota
+	tenant  string
+	account *quotaAccount
+	conn    *Conn
+}
+
+// admit takes a connection of the tenant of client in the handshake, returns false if the max connections of the
+// tenant are reached. The clients which no quota applies to are always admitted.
+func (c *Conn) admit(conf *WorkflowConfig) bool {
+	c.releaseQuota()
+	quota, tenant, ok := conf.quotaOf(c.principal, c.namespace)
+	if !ok || c.Session == nil {
+		return true
+	}
+	account := accountOf(tenant)
+	if !account.connect(quota) {
+		return false
+	}
+	sessionQuotas.Store(c.Session, &sessionQuota{quota: quota, tenant: tenant, account: account, conn: c})
+	return true
+}
+
+// releaseQuota releases the connection taken by admit, it's safe to call more than once.
+func (c *Conn) releaseQuota() {
+	if c.Session == nil {
+		return
+	}
+	if v, ok := sessionQuotas.LoadAndDelete(c.Session); ok {
+		v.(*sessionQuota).account.disconnect()
+	}
+}
+
+// withinQuota indicates if the data of the session is within the quota of its tenant, all data is within the quota
+// if no quota applies to the client. The data which exceeds the quota is told to the source by a QuotaFrame.
+func withinQuota(session quic.Session, data *frame.DataFrame) bool {
+	v, ok := sessionQuotas.Load(session)
+	if !ok {
+		return true
+	}
+	sq := v.(*sessionQuota)
+	quota, retryAfter := sq.account.use(sq.quota, len(data.GetCarriage()), time.Now())
+	if quota == "" {
+		return true
+	}
+
+	logger.Debug("[zipper] drop the frame which exceeds the quota.", "tenant", sq.tenant, "quota", quota, "TransactionID", data.TransactionID())
+	pipelineMetrics.failed(errorQuota)
+	pipelineTimelines.record(StepDropped, "", data, fmt.Sprintf("the quota %s of %s is exceeded", quota, sq.tenant))
+	// the sources before the version 3 don't understand the QuotaFrame.
+	if frame.Supports(frame.TagOfQuotaFrame, versionOf(session)) {
+		if err := sq.conn.Conn.SendSignal(frame.NewQuotaFrame(data.TransactionID(), quota, retryAfter)); err != nil {
+			logger.Debug("[zipper] tell the source the quota is exceeded failed.", "source", sq.conn.Conn.Name, "err", err)
+		}
+	}
+	return false
+}
+
+// rejectQuota rejects the handshake of client whose tenant reaches the max connections.
+func (c *Conn) rejectQuota(name string, connType core.ConnectionType) {
+	logger.Printf("The %s %s is rejected, the max connections of its tenant are reached, subject: %s, addr: %s", connType, c.Conn.Name, c.Subject(), c.Addr)
+	c.audit(AuditAuthFailure, name, connType, errQuotaExceeded)
+	rejected := frame.NewRejectedFrame()
+	rejected.Message = errQuotaExceeded
+	rejected.Code = frame.RejectQuotaExceeded
+	c.Conn.SendSignal(rejected)
+}
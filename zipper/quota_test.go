@@ -0,0 +1,150 @@
+package zipper
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/source"
+)
+
+func TestValidateQuotas(t *testing.T) {
+	conf := &WorkflowConfig{Name: "zipper", Host: "localhost", Port: 9000, Quotas: []Quota{
+		{MaxConnections: 1},
+		{Namespace: "acme", Subjects: []string{"[acme"}, FramesPerSecond: -1},
+		{Roles: []string{"tenant"}},
+	}}
+	err := Validate(conf)
+	if assert.Error(t, err) {
+		for _, msg := range []string{
+			"The quotas require the auth, the usage is accounted by the identities of clients.",
+			"The quota requires the subjects, the roles or the namespace.",
+			"The quota of namespace acme must not have the subjects or the roles.",
+			"The namespace acme of quota is not declared.",
+			"The pattern [acme of quota is malformed.",
+			"The limits of quota must not be negative.",
+			"The quota requires max_connections, frames_per_second or bytes_per_day.",
+		} {
+			assert.Contains(t, err.Error(), msg)
+		}
+	}
+}
+
+func TestQuotaOf(t *testing.T) {
+	conf := &WorkflowConfig{Quotas: []Quota{
+		{Namespace: "acme", MaxConnections: 10},
+		{Subjects: []string{"app-*"}, FramesPerSecond: 100},
+	}}
+
+	_, _, ok := conf.quotaOf(nil, "")
+	assert.False(t, ok)
+	// the clients of namespace share the usage of the namespace.
+	quota, tenant, ok := conf.quotaOf(&Principal{Subject: "app-1"}, "acme")
+	assert.True(t, ok)
+	assert.Equal(t, "acme", tenant)
+	assert.Equal(t, 10, quota.MaxConnections)
+	// each app has its own usage.
+	quota, tenant, ok = conf.quotaOf(&Principal{Subject: "app-1"}, "")
+	assert.True(t, ok)
+	assert.Equal(t, "app-1", tenant)
+	assert.Equal(t, 100, quota.FramesPerSecond)
+	_, _, ok = conf.quotaOf(&Principal{Subject: "ops"}, "")
+	assert.False(t, ok)
+}
+
+func TestQuotaAccount(t *testing.T) {
+	account := &quotaAccount{}
+	quota := Quota{MaxConnections: 1, FramesPerSecond: 2, BytesPerDay: 10}
+
+	assert.True(t, account.connect(quota))
+	assert.False(t, account.connect(quota))
+	account.disconnect()
+	assert.True(t, account.connect(quota))
+
+	now := time.Date(2022, 3, 1, 23, 59, 58, int(750*time.Millisecond), time.UTC)
+	exceeded, _ := account.use(quota, 4, now)
+	assert.Empty(t, exceeded)
+	exceeded, _ = account.use(quota, 4, now)
+	assert.Empty(t, exceeded)
+	exceeded, retryAfter := account.use(quota, 1, now)
+	assert.Equal(t, QuotaFramesPerSecond, exceeded)
+	assert.Equal(t, 250*time.Millisecond, retryAfter)
+
+	// the frames are limited in each second, the bytes are limited in each day.
+	now = now.Add(500 * time.Millisecond)
+	exceeded, retryAfter = account.use(quota, 4, now)
+	assert.Equal(t, QuotaBytesPerDay, exceeded)
+	assert.Equal(t, 750*time.Millisecond, retryAfter)
+	exceeded, _ = account.use(quota, 2, now)
+	assert.Empty(t, exceeded)
+	exceeded, _ = account.use(quota, 4, now.Add(time.Second))
+	assert.Empty(t, exceeded)
+
+	usage := account.usage("acme", now.Add(time.Second))
+	assert.Equal(t, TenantUsage{
+		Tenant:      "acme",
+		Connections: 1,
+		Frames:      4,
+		Bytes:       14,
+		BytesToday:  4,
+		Exceeded:    map[string]uint64{QuotaMaxConnections: 1, QuotaFramesPerSecond: 1, QuotaBytesPerDay: 1},
+	}, usage)
+}
+
+func TestQuotaEnforcement(t *testing.T) {
+	conf := &WorkflowConfig{
+		Name: "zipper",
+		Host: "localhost",
+		Port: 9032,
+		Auth: &AuthConfig{Tokens: []StaticToken{{Subject: "quota-sensor", Token: "sensor-token"}}},
+		Workflow: Workflow{
+			Sources:   []App{{Name: "sensor"}},
+			Functions: []App{{Name: "noise"}},
+		},
+		Quotas: []Quota{{Subjects: []string{"quota-*"}, MaxConnections: 1, FramesPerSecond: 1}},
+	}
+	assert.NoError(t, Validate(conf))
+	handler := newServerHandler(conf, "")
+	authenticator, err := newAuthenticator(conf.Auth)
+	assert.NoError(t, err)
+	handler.authenticator = authenticator
+	svr := New(conf)
+	go svr.ServeWithHandler(fmt.Sprintf("%s:%d", conf.Host, conf.Port), handler)
+	defer svr.Close()
+
+	exceeded := make(chan source.QuotaExceeded, 10)
+	src, err := source.New("sensor", source.WithToken("sensor-token"), source.WithOnQuotaExceeded(func(q source.QuotaExceeded) {
+		exceeded <- q
+	})).Connect(conf.Host, conf.Port)
+	assert.NoError(t, err)
+	defer src.Close()
+
+	// the max connections of the tenant are reached.
+	_, err = source.New("sensor", source.WithToken("sensor-token")).Connect(conf.Host, conf.Port)
+	assert.ErrorIs(t, err, source.ErrQuotaExceeded)
+
+	// the frames beyond the quota of each second are told to the source.
+	for i := 0; i < 3; i++ {
+		_, err := src.Write([]byte("noise"))
+		assert.NoError(t, err)
+	}
+	select {
+	case q := <-exceeded:
+		assert.Equal(t, QuotaFramesPerSecond, q.Quota)
+		assert.NotEmpty(t, q.TransactionID)
+		assert.True(t, q.RetryAfter > 0 && q.RetryAfter <= time.Second)
+	case <-time.After(3 * time.Second):
+		t.Fatal("the source isn't told the quota is exceeded")
+	}
+
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	pipelineMetrics.write(w, handler)
+	w.Flush()
+	assert.Contains(t, buf.String(), `yomo_zipper_tenant_connections{tenant="quota-sensor"} 1`)
+	assert.Contains(t, buf.String(), `yomo_zipper_quota_exceeded_total{tenant="quota-sensor",quota="max_connections"} 1`)
+	assert.Contains(t, buf.String(), `yomo_zipper_tenant_frames_total{tenant="quota-sensor"}`)
+}
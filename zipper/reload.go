@@ -0,0 +1,194 @@
+package zipper
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/yomorun/yomo/logger"
+)
+
+// reloadedFields are the fields of WorkflowConfig which are applied when the workflow is reloaded, the changes of
+// the other fields require restart, e.g. the listen address.
+var reloadedFields = map[string]bool{
+	"Workflow":     true,
+	"TagRemap":     true,
+	"MaxFrameSize": true,
+	"MaxHops":      true,
+}
+
+// reloadConfig returns the config which the running YoMo-Zipper switches to, it's the current config with the
+// reloaded fields of next, and the names of the other fields which are changed but require restart.
+func reloadConfig(current, next *WorkflowConfig) (*WorkflowConfig, []string, error) {
+	if err := Validate(next); err != nil {
+		return nil, nil, err
+	}
+
+	// the serverless and WASM functions are created when YoMo-Zipper serves.
+	errMsg := ""
+	for _, app := range next.Functions {
+		prev, _ := current.functionOf(app.Name)
+		if !reflect.DeepEqual(prev.Invoke, app.Invoke) || !reflect.DeepEqual(prev.Wasm, app.Wasm) {
+			errMsg += "The serverless or WASM function " + app.Name + " can't be reloaded, it requires restart. "
+		}
+	}
+	if errMsg != "" {
+		return nil, nil, errors.New(errMsg)
+	}
+
+	conf := *current
+	restart := make([]string, 0)
+	cv, nv, v := reflect.ValueOf(current).Elem(), reflect.ValueOf(next).Elem(), reflect.ValueOf(&conf).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		if reloadedFields[field.Name] {
+			v.Field(i).Set(nv.Field(i))
+			continue
+		}
+		if !reflect.DeepEqual(cv.Field(i).Interface(), nv.Field(i).Interface()) {
+			restart = append(restart, strings.Split(field.Tag.Get("yaml"), ",")[0])
+		}
+	}
+	// the weights of sources are scheduled by the fan-in which is created when YoMo-Zipper serves.
+	if !reflect.DeepEqual(weightsOf(current.Sources), weightsOf(next.Sources)) {
+		restart = append(restart, "sources.weight")
+	}
+	return &conf, restart, nil
+}
+
+// functionOf returns the stream function of the name in workflow.
+func (w Workflow) functionOf(name string) (App, bool) {
+	for _, app := range w.Functions {
+		if app.Name == name {
+			return app, true
+		}
+	}
+	return App{}, false
+}
+
+// weightsOf returns the weights of sources by name.
+func weightsOf(sources []App) map[string]int {
+	weights := make(map[string]int)
+	for _, app := range sources {
+		if app.Weight > 0 {
+			weights[app.Name] = app.Weight
+		}
+	}
+	return weights
+}
+
+// reload switches the workflow to the config. The settings of stages, e.g. the tags, the sampling, the shadows and
+// the load balance, are applied to the running pipelines at once, and the stages, e.g. the order, are applied to
+// the pipelines created after the reload, e.g. for the sources connected after it. It returns the names of the
+// changed fields which require restart.
+func (s *quicHandler) reload(next *WorkflowConfig) ([]string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	conf, restart, err := reloadConfig(s.config(), next)
+	if err != nil {
+		return nil, err
+	}
+	// the removed functions keep their settings for the running pipelines which still have them.
+	for _, app := range conf.Functions {
+		storeSampler(app)
+		appCache.Store(app.Name, app)
+		clearStreamFuncCache(app.Name)
+	}
+	s.serverlessConfig.Store(conf)
+	return restart, nil
+}
+
+// ReloadWorkflow switches the running YoMo-Zipper to the workflow of config, the config is validated first, and the
+// current workflow is kept if it's invalid.
+func (r *zipperImpl) ReloadWorkflow(conf *WorkflowConfig) error {
+	if r.handler == nil {
+		return errors.New("[zipper] the zipper is not serving")
+	}
+	restart, err := r.handler.reload(conf)
+	if err != nil {
+		return err
+	}
+	if len(restart) > 0 {
+		logger.Printf("⚠️ The changes of %s in workflow config require restart", strings.Join(restart, ", "))
+	}
+	logger.Printf("✅ The workflow %s is reloaded, stages: %d", conf.Name, len(conf.Functions))
+	return nil
+}
+
+// reloadFile reloads the workflow from the config file.
+func (h *quicHandler) reloadFile() ([]string, error) {
+	path := h.config().path
+	if path == "" {
+		return nil, errors.New("[zipper] the workflow config isn't loaded from a file")
+	}
+	conf, err := ParseConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return h.reload(conf)
+}
+
+// watchConfig reloads the workflow when the config file is modified if the watch is enabled, and on SIGHUP if it's
+// opted in.
+func (r *zipperImpl) watchConfig(ctx context.Context) {
+	path := r.conf.path
+	var tick <-chan time.Time
+	if interval := r.conf.Reload.WatchInterval; interval > 0 && path != "" {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	var sig chan os.Signal
+	if r.conf.Reload.OnSIGHUP {
+		sig = make(chan os.Signal, 1)
+		signal.Notify(sig, syscall.SIGHUP)
+		defer signal.Stop(sig)
+	}
+
+	modTime := modTimeOf(path)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick:
+			// the invalid file is reported once, it's reloaded after it's modified again.
+			if m := modTimeOf(path); m.After(modTime) {
+				modTime = m
+				r.reloadFile("modified")
+			}
+		case <-sig:
+			r.reloadFile("SIGHUP")
+		}
+	}
+}
+
+// reloadFile reloads the workflow from the config file, the failure is logged.
+func (r *zipperImpl) reloadFile(reason string) {
+	restart, err := r.handler.reloadFile()
+	if err != nil {
+		logger.Error("[zipper] reload the workflow config failed.", "reason", reason, "err", err)
+		return
+	}
+	if len(restart) > 0 {
+		logger.Printf("⚠️ The changes of %s in workflow config require restart", strings.Join(restart, ", "))
+	}
+	logger.Printf("✅ The workflow config %s is reloaded by %s", r.conf.path, reason)
+}
+
+// modTimeOf returns the modification time of file, it's zero if the file can't be read.
+func modTimeOf(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
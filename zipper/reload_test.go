@@ -0,0 +1,98 @@
+package zipper
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReloadConfig(t *testing.T) {
+	current := &WorkflowConfig{Name: "zipper", Host: "localhost", Port: 9000, Workflow: Workflow{
+		Functions: []App{{Name: "fn1"}, {Name: "fn2", Invoke: &InvokeConfig{Provider: InvokeHTTP, URL: "http://localhost/fn2"}}},
+	}}
+
+	next := &WorkflowConfig{Name: "zipper", Host: "0.0.0.0", Port: 9000, MaxHops: 4, Workflow: Workflow{
+		Functions: []App{{Name: "fn2", Invoke: &InvokeConfig{Provider: InvokeHTTP, URL: "http://localhost/fn2"}}, {Name: "fn1", Tags: []byte{0x33}}},
+		Sources:   []App{{Name: "source", Weight: 2}},
+	}}
+	conf, restart, err := reloadConfig(current, next)
+	assert.NoError(t, err)
+	assert.Equal(t, next.Workflow, conf.Workflow)
+	assert.Equal(t, 4, conf.MaxHops)
+	// the listen address is kept until restart.
+	assert.Equal(t, "localhost", conf.Host)
+	assert.Equal(t, []string{"host", "sources.weight"}, restart)
+
+	next.Functions[0].Invoke = &InvokeConfig{Provider: InvokeHTTP, URL: "http://localhost/other"}
+	_, _, err = reloadConfig(current, next)
+	assert.EqualError(t, err, "The serverless or WASM function fn2 can't be reloaded, it requires restart. ")
+
+	_, _, err = reloadConfig(current, &WorkflowConfig{Name: "zipper", Host: "localhost"})
+	assert.EqualError(t, err, "Missing name, host or port in workflow config. ")
+}
+
+func TestHandlerReload(t *testing.T) {
+	h := newServerHandler(&WorkflowConfig{Name: "zipper", Host: "localhost", Port: 9000, Workflow: Workflow{
+		Functions: []App{{Name: "reload-fn", Tags: []byte{0x33}}},
+	}}, "")
+	getStreamFuncs(h.config(), &h.connMap)
+	assert.False(t, subscribed("reload-fn", 0x34))
+
+	restart, err := h.reload(&WorkflowConfig{Name: "zipper", Host: "localhost", Port: 9000, Workflow: Workflow{
+		Functions: []App{{Name: "reload-fn", Tags: []byte{0x34}, LoadBalance: LoadBalanceLatency}, {Name: "reload-fn2"}},
+	}})
+	assert.NoError(t, err)
+	assert.Empty(t, restart)
+	assert.Len(t, h.config().Functions, 2)
+	// the settings of stages are applied to the running pipelines.
+	assert.True(t, subscribed("reload-fn", 0x34))
+	assert.Equal(t, LoadBalanceLatency, loadBalanceOf("reload-fn"))
+
+	// the current workflow is kept if the config is invalid.
+	_, err = h.reload(&WorkflowConfig{Name: "zipper", Host: "localhost", Port: 9000, Workflow: Workflow{
+		Functions: []App{{Name: "reload-fn"}, {Name: "reload-fn"}},
+	}})
+	assert.EqualError(t, err, "The function reload-fn is declared more than once. ")
+	assert.Len(t, h.config().Functions, 2)
+}
+
+func TestLoadStrict(t *testing.T) {
+	_, err := load([]byte("name: zipper\nhost: localhost\nport: 9000\nfunctions:\n  - name: fn\n    shadow: [canary]\n"))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "line 6: field shadow not found")
+	}
+}
+
+func TestAdminReloadWorkflow(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "workflow.yaml")
+	assert.NoError(t, ioutil.WriteFile(file, []byte("name: zipper\nhost: localhost\nport: 9000\nadmin_token: secret\nfunctions:\n  - name: admin-fn\n"), 0644))
+	conf, err := ParseConfig(file)
+	assert.NoError(t, err)
+
+	server := httptest.NewServer(newAdminMux(newServerHandler(conf, "")))
+	defer server.Close()
+	reload := func(v interface{}) int {
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/workflow/reload", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		res, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		assert.NoError(t, json.NewDecoder(res.Body).Decode(v))
+		return res.StatusCode
+	}
+
+	assert.NoError(t, ioutil.WriteFile(file, []byte("name: zipper\nhost: localhost\nport: 9001\nadmin_token: secret\nfunctions:\n  - name: admin-fn\n  - name: admin-fn2\n"), 0644))
+	var result ReloadResult
+	assert.Equal(t, http.StatusOK, reload(&result))
+	assert.Equal(t, ReloadResult{Status: "reloaded", Restart: []string{"port"}}, result)
+
+	assert.NoError(t, ioutil.WriteFile(file, []byte("name: zipper\nhost: localhost\nport: 9000\nadmin_token: secret\nfunctions:\n  - nme: admin-fn\n"), 0644))
+	var failure map[string]string
+	assert.Equal(t, http.StatusBadRequest, reload(&failure))
+	assert.Contains(t, failure["error"], "field nme not found")
+}
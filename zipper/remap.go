@@ -0,0 +1,110 @@
+package zipper
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/yomorun/yomo/internal/frame"
+	"github.com/yomorun/yomo/logger"
+)
+
+// maxDataTag is the max data tag, the data tag is the sequence ID of Y3 primitive packet.
+const maxDataTag = 0x3F
+
+// TagRemap is the table of tag renumbering, the key is the original tag and the value is the new tag,
+// the tags which are not in the table are unchanged.
+type TagRemap map[byte]byte
+
+// tag returns the new tag of the original tag.
+func (m TagRemap) tag(tag byte) byte {
+	if t, ok := m[tag]; ok {
+		return t
+	}
+	return tag
+}
+
+// apply renumbers all the tags of the frame in place.
+func (m TagRemap) apply(data *frame.DataFrame) {
+	data.SetDataTagID(m.tag(data.GetDataTagID()))
+	if extra := data.ExtraTags(); len(extra) > 0 {
+		tags := make([]byte, len(extra))
+		for i, tag := range extra {
+			tags[i] = m.tag(tag)
+		}
+		data.SetExtraTags(tags...)
+	}
+}
+
+// changes indicates if any tag of the frame is renumbered.
+func (m TagRemap) changes(data *frame.DataFrame) bool {
+	for _, tag := range data.Tags() {
+		if m.tag(tag) != tag {
+			return true
+		}
+	}
+	return false
+}
+
+// validate the tags in the table.
+func (m TagRemap) validate() error {
+	for from, to := range m {
+		if from > maxDataTag || to > maxDataTag {
+			return fmt.Errorf("the tag must be in the range [0, %#x]", maxDataTag)
+		}
+	}
+	return nil
+}
+
+// TagRemapConfig is the tag remapping of YoMo-Zipper, so the pipelines can be composed across the teams
+// which use different tag numbering without changing the code of sources and functions.
+type TagRemapConfig struct {
+	// Ingress is applied to the frames before they're sent to the stream functions. The priorities of
+	// load shedding refer to the original tags, because the frames are shed before the remapping.
+	Ingress TagRemap `yaml:"ingress,omitempty"`
+	// Egress is applied to the frames after they're processed by all stream functions.
+	Egress TagRemap `yaml:"egress,omitempty"`
+	// Downstreams are applied to the frames which are sent to the downstream YoMo-Zippers by the name,
+	// after the egress remapping.
+	Downstreams map[string]TagRemap `yaml:"downstreams,omitempty"`
+}
+
+// remapTags renumbers the tags of the frames from upstream.
+func remapTags(ctx context.Context, upstream chan *frame.DataFrame, remap TagRemap) chan *frame.DataFrame {
+	next := make(chan *frame.DataFrame, bufferSize)
+
+	go func() {
+		defer close(next)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case item, ok := <-upstream:
+				if !ok {
+					return
+				}
+
+				remap.apply(item)
+				next <- item
+			}
+		}
+	}()
+
+	return next
+}
+
+// remapDownstream returns a copy of the frame with the tags for the downstream YoMo-Zipper,
+// the frame is copied because it's sent to all downstreams concurrently.
+func remapDownstream(data *frame.DataFrame, remap TagRemap) *frame.DataFrame {
+	if !remap.changes(data) {
+		return data
+	}
+
+	copied, err := frame.DecodeToDataFrame(data.Encode())
+	if err != nil {
+		logger.Error("[zipper] copy the frame failed", "err", err)
+		return data
+	}
+	remap.apply(copied)
+	return copied
+}
@@ -0,0 +1,74 @@
+package zipper
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/internal/frame"
+)
+
+func TestTagRemapConfig(t *testing.T) {
+	conf, err := load([]byte(`
+name: test
+host: localhost
+port: 9000
+functions:
+  - name: fn1
+tag_remap:
+  ingress:
+    0x10: 0x20
+  egress:
+    0x20: 0x11
+  downstreams:
+    zipper-2:
+      0x11: 0x30
+`))
+	assert.NoError(t, err)
+	assert.NoError(t, Validate(conf))
+	assert.Equal(t, TagRemap{0x10: 0x20}, conf.TagRemap.Ingress)
+	assert.Equal(t, TagRemap{0x20: 0x11}, conf.TagRemap.Egress)
+	assert.Equal(t, TagRemap{0x11: 0x30}, conf.TagRemap.Downstreams["zipper-2"])
+
+	conf.TagRemap.Egress[0x20] = 0x40
+	assert.Error(t, Validate(conf))
+}
+
+func TestRemapTags(t *testing.T) {
+	upstream := make(chan *frame.DataFrame, 2)
+	f1 := frame.NewDataFrame("1")
+	f1.SetCarriage(0x10, []byte("a"))
+	f2 := frame.NewDataFrame("2")
+	f2.SetCarriage(0x12, []byte("b"))
+	upstream <- f1
+	upstream <- f2
+	close(upstream)
+
+	next := remapTags(context.Background(), upstream, TagRemap{0x10: 0x20})
+	assert.Equal(t, byte(0x20), (<-next).GetDataTagID())
+	assert.Equal(t, byte(0x12), (<-next).GetDataTagID())
+}
+
+func TestRemapDownstream(t *testing.T) {
+	data := frame.NewDataFrame("1")
+	data.SetCarriage(0x11, []byte("a"))
+
+	assert.Same(t, data, remapDownstream(data, nil))
+
+	copied := remapDownstream(data, TagRemap{0x11: 0x30})
+	assert.Equal(t, byte(0x30), copied.GetDataTagID())
+	assert.Equal(t, "1", copied.TransactionID())
+	assert.Equal(t, []byte("a"), copied.GetCarriage())
+	// the original frame is unchanged.
+	assert.Equal(t, byte(0x11), data.GetDataTagID())
+}
+
+func TestRemapExtraTags(t *testing.T) {
+	data := frame.NewDataFrame("1")
+	data.SetCarriage(0x11, []byte("a"))
+	data.SetExtraTags(0x12)
+
+	copied := remapDownstream(data, TagRemap{0x12: 0x32})
+	assert.Equal(t, []byte{0x11, 0x32}, copied.Tags())
+	assert.Equal(t, []byte{0x11, 0x12}, data.Tags())
+}
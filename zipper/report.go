@@ -0,0 +1,139 @@
+package zipper
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/yomorun/yomo/internal/frame"
+	"github.com/yomorun/yomo/logger"
+)
+
+// ShutdownReport is the machine-readable report which is emitted when YoMo-Zipper is closed,
+// it's used to assess the impact of restarts and to tune the drain timeouts.
+type ShutdownReport struct {
+	// Name is the name of workflow.
+	Name string `json:"name"`
+	// StartedAt is the time when YoMo-Zipper started to serve.
+	StartedAt time.Time `json:"started_at"`
+	// StoppedAt is the time when YoMo-Zipper was closed.
+	StoppedAt time.Time `json:"stopped_at"`
+	// InFlightDropped is the count of frames in the queues of pipeline which are dropped at exit.
+	InFlightDropped int `json:"in_flight_dropped"`
+	// Backlog is the count of frames which are waiting for each stream function at exit.
+	Backlog map[string]int `json:"backlog"`
+	// SheddingDropped is the count of frames dropped by load shedding during the lifetime, grouped by data tag.
+	SheddingDropped map[byte]uint64 `json:"shedding_dropped"`
+	// CorruptedFrames is the count of frames dropped because their checksum mismatched during the lifetime.
+	CorruptedFrames uint64 `json:"corrupted_frames"`
+	// ClosedPeers are the connected clients which are forcibly closed.
+	ClosedPeers []ClosedPeer `json:"closed_peers"`
+}
+
+// ClosedPeer is a client which is forcibly closed at exit.
+type ClosedPeer struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Addr string `json:"addr"`
+}
+
+// emit writes the report as a JSON line to w, the report is logged if w is nil.
+func (r ShutdownReport) emit(w io.Writer) {
+	buf, err := json.Marshal(r)
+	if err != nil {
+		logger.Error("[zipper] marshal the shutdown report failed", "err", err)
+		return
+	}
+
+	if w == nil {
+		logger.Printf("Shutdown report: %s", buf)
+		return
+	}
+
+	if _, err := w.Write(append(buf, '\n')); err != nil {
+		logger.Error("[zipper] write the shutdown report failed", "err", err)
+	}
+}
+
+// sampleInterval is the interval of the sampler which prunes the queues of the closed sessions.
+const sampleInterval = time.Second
+
+// trackedQueue is a queue of pipeline and the context of the session which owns it.
+type trackedQueue struct {
+	ctx   context.Context
+	stage string // the stage which consumes the queue, it's empty for the output.
+}
+
+// queueTracker tracks the queues of pipeline, the frames in these queues are dropped when YoMo-Zipper exits.
+// the queues of the closed sessions are pruned by one shared sampler, which runs only while any queue is tracked.
+type queueTracker struct {
+	mutex    sync.Mutex
+	queues   map[chan *frame.DataFrame]trackedQueue
+	sampling bool
+	interval time.Duration
+}
+
+func newQueueTracker() *queueTracker {
+	return &queueTracker{
+		queues:   make(map[chan *frame.DataFrame]trackedQueue),
+		interval: sampleInterval,
+	}
+}
+
+// track the queue consumed by the stage until ctx is done.
+func (t *queueTracker) track(ctx context.Context, stage string, queue chan *frame.DataFrame) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.queues[queue] = trackedQueue{ctx: ctx, stage: stage}
+	if !t.sampling {
+		t.sampling = true
+		go t.sample()
+	}
+}
+
+// sample prunes the queues whose context is done, it stops when no queue is left.
+func (t *queueTracker) sample() {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		t.mutex.Lock()
+		t.prune()
+		if len(t.queues) == 0 {
+			t.sampling = false
+			t.mutex.Unlock()
+			return
+		}
+		t.mutex.Unlock()
+	}
+}
+
+// prune removes the queues whose context is done, the mutex must be held.
+func (t *queueTracker) prune() {
+	for queue, q := range t.queues {
+		if q.ctx.Err() != nil {
+			delete(t.queues, queue)
+		}
+	}
+}
+
+// backlog returns the total count of frames in the queues and the count for each stage.
+func (t *queueTracker) backlog() (int, map[string]int) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.prune()
+	total := 0
+	stages := make(map[string]int)
+	for queue, q := range t.queues {
+		n := len(queue)
+		total += n
+		if q.stage != "" {
+			stages[q.stage] += n
+		}
+	}
+	return total, stages
+}
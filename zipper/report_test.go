@@ -0,0 +1,81 @@
+package zipper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/internal/frame"
+)
+
+func TestQueueTracker(t *testing.T) {
+	tracker := newQueueTracker()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	q1 := make(chan *frame.DataFrame, 10)
+	q2 := make(chan *frame.DataFrame, 10)
+	out := make(chan *frame.DataFrame, 10)
+	tracker.track(ctx, "fn1", q1)
+	tracker.track(ctx, "fn2", q2)
+	tracker.track(ctx, "", out)
+
+	q1 <- frame.NewDataFrame("1")
+	q1 <- frame.NewDataFrame("2")
+	q2 <- frame.NewDataFrame("3")
+	out <- frame.NewDataFrame("4")
+
+	total, backlog := tracker.backlog()
+	assert.Equal(t, 4, total)
+	assert.Equal(t, map[string]int{"fn1": 2, "fn2": 1}, backlog)
+
+	cancel()
+	total, backlog = tracker.backlog()
+	assert.Equal(t, 0, total)
+	assert.Empty(t, backlog)
+}
+
+func TestQueueTrackerSampler(t *testing.T) {
+	tracker := newQueueTracker()
+	tracker.interval = 10 * time.Millisecond
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	for i := 0; i < 100; i++ {
+		tracker.track(ctx1, "fn1", make(chan *frame.DataFrame, 1))
+	}
+	tracker.track(ctx2, "fn2", make(chan *frame.DataFrame, 1))
+
+	cancel1()
+	assert.Eventually(t, func() bool {
+		tracker.mutex.Lock()
+		defer tracker.mutex.Unlock()
+		return len(tracker.queues) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	cancel2()
+	assert.Eventually(t, func() bool {
+		tracker.mutex.Lock()
+		defer tracker.mutex.Unlock()
+		return !tracker.sampling && len(tracker.queues) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestShutdownReportEmit(t *testing.T) {
+	var buf bytes.Buffer
+	report := ShutdownReport{
+		Name:            "test",
+		InFlightDropped: 3,
+		Backlog:         map[string]int{"fn1": 3},
+		ClosedPeers:     []ClosedPeer{{Name: "source", Type: "Source", Addr: "127.0.0.1:1"}},
+	}
+	report.emit(&buf)
+
+	var got ShutdownReport
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	assert.Equal(t, 3, got.InFlightDropped)
+	assert.Equal(t, report.Backlog, got.Backlog)
+	assert.Equal(t, report.ClosedPeers, got.ClosedPeers)
+}
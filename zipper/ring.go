@@ -0,0 +1,110 @@
+package zipper
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/yomorun/yomo/internal/frame"
+)
+
+// ringQueue is the bounded lock-free queue of frames for many producers and one consumer, the producers claim
+// the slots by CAS instead of contending on the lock of channel, e.g. the goroutines of partially reliable streams.
+type ringQueue struct {
+	mask   uint64
+	slots  []ringSlot
+	head   uint64        // head is the next slot to pop, it's only moved by the consumer.
+	tail   uint64        // tail is the next slot to push.
+	notify chan struct{} // notify wakes up the consumer after a frame is pushed.
+}
+
+// ringSlot is a slot of ringQueue, its sequence tells the producers and the consumer if it's free or filled.
+type ringSlot struct {
+	seq uint64
+	f   *frame.DataFrame
+}
+
+// newRingQueue creates a ringQueue, the size is rounded up to the power of 2.
+func newRingQueue(size int) *ringQueue {
+	n := 1
+	for n < size {
+		n <<= 1
+	}
+
+	q := &ringQueue{
+		mask:   uint64(n - 1),
+		slots:  make([]ringSlot, n),
+		notify: make(chan struct{}, 1),
+	}
+	for i := range q.slots {
+		q.slots[i].seq = uint64(i)
+	}
+	return q
+}
+
+// len returns the count of frames in the queue, the slots which are claimed but not filled yet are counted.
+func (q *ringQueue) len() int {
+	head := atomic.LoadUint64(&q.head)
+	return int(atomic.LoadUint64(&q.tail) - head)
+}
+
+// push the frame to the queue, returns false if the queue is full.
+func (q *ringQueue) push(f *frame.DataFrame) bool {
+	for {
+		tail := atomic.LoadUint64(&q.tail)
+		slot := &q.slots[tail&q.mask]
+		seq := atomic.LoadUint64(&slot.seq)
+
+		switch {
+		case seq == tail:
+			if !atomic.CompareAndSwapUint64(&q.tail, tail, tail+1) {
+				continue
+			}
+			slot.f = f
+			atomic.StoreUint64(&slot.seq, tail+1)
+
+			select {
+			case q.notify <- struct{}{}:
+			default:
+			}
+			return true
+		case seq < tail:
+			// the slot has not been popped since the last round.
+			return false
+		}
+		// the tail was moved by another producer.
+	}
+}
+
+// pop the frame from the queue, returns false if the queue is empty. It must be called by one consumer.
+func (q *ringQueue) pop() (*frame.DataFrame, bool) {
+	slot := &q.slots[q.head&q.mask]
+	if atomic.LoadUint64(&slot.seq) != q.head+1 {
+		return nil, false
+	}
+
+	f := slot.f
+	slot.f = nil
+	atomic.StoreUint64(&slot.seq, q.head+q.mask+1)
+	// head is stored atomically for len, it's still read without atomic by the consumer itself.
+	atomic.StoreUint64(&q.head, q.head+1)
+	return f, true
+}
+
+// drain moves the frames to out until ctx is done.
+func (q *ringQueue) drain(ctx context.Context, out chan *frame.DataFrame) {
+	for {
+		for f, ok := q.pop(); ok; f, ok = q.pop() {
+			select {
+			case out <- f:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		select {
+		case <-q.notify:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package zipper
+
+import (
+	"context"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/internal/frame"
+)
+
+func TestRingQueue(t *testing.T) {
+	q := newRingQueue(3)
+	assert.Len(t, q.slots, 4)
+
+	for i := 0; i < 4; i++ {
+		assert.True(t, q.push(frame.NewDataFrame(strconv.Itoa(i))))
+	}
+	// the queue is full.
+	assert.False(t, q.push(frame.NewDataFrame("4")))
+	assert.Equal(t, 4, q.len())
+
+	f, ok := q.pop()
+	assert.True(t, ok)
+	assert.Equal(t, "0", f.TransactionID())
+	assert.Equal(t, 3, q.len())
+	assert.True(t, q.push(frame.NewDataFrame("4")))
+}
+
+func TestRingQueueDrain(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	q := newRingQueue(16)
+	out := make(chan *frame.DataFrame)
+	go q.drain(ctx, out)
+
+	// many producers push concurrently.
+	var wg sync.WaitGroup
+	for p := 0; p < 8; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				for !q.push(frame.NewDataFrame(strconv.Itoa(p*100 + i))) {
+					runtime.Gosched()
+				}
+			}
+		}(p)
+	}
+
+	seen := make(map[string]bool)
+	for len(seen) < 800 {
+		f := <-out
+		assert.False(t, seen[f.TransactionID()])
+		seen[f.TransactionID()] = true
+	}
+	wg.Wait()
+}
@@ -0,0 +1,72 @@
+package zipper
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+)
+
+// Sampling represents the sampling config of a stream function, only the sampled frames will be sent to this function,
+// the rest pass through to the next function untouched.
+type Sampling struct {
+	// Rate is the probability of a frame being sampled, in the range (0, 1].
+	Rate float64 `yaml:"rate,omitempty"`
+	// Every samples one frame in every N frames.
+	Every uint64 `yaml:"every,omitempty"`
+}
+
+// sampler decides whether a frame should be sent to the stream function.
+type sampler struct {
+	rate  float64
+	every uint64
+	count uint64
+}
+
+func newSampler(conf Sampling) *sampler {
+	return &sampler{
+		rate:  conf.Rate,
+		every: conf.Every,
+	}
+}
+
+// sample returns true if the next frame is sampled.
+func (s *sampler) sample() bool {
+	if s.every > 0 {
+		n := atomic.AddUint64(&s.count, 1)
+		return (n-1)%s.every == 0
+	}
+
+	if s.rate > 0 && s.rate < 1 {
+		return rand.Float64() < s.rate
+	}
+
+	return true
+}
+
+var samplerCache = sync.Map{} // the cache for the samplers of stream functions by name.
+
+// storeSampler stores the sampler of the stream function if the sampling is configured.
+func storeSampler(app App) {
+	if app.Sampling == nil {
+		samplerCache.Delete(app.Name)
+		return
+	}
+
+	if s, ok := samplerCache.Load(app.Name); ok {
+		conf := s.(*sampler)
+		if conf.rate == app.Sampling.Rate && conf.every == app.Sampling.Every {
+			// keep the counter of every-Nth sampling.
+			return
+		}
+	}
+	samplerCache.Store(app.Name, newSampler(*app.Sampling))
+}
+
+// sampled indicates if the frame should be sent to the stream function.
+func sampled(name string) bool {
+	s, ok := samplerCache.Load(name)
+	if !ok {
+		return true
+	}
+	return s.(*sampler).sample()
+}
@@ -0,0 +1,45 @@
+package zipper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSamplerEvery(t *testing.T) {
+	s := newSampler(Sampling{Every: 3})
+	results := make([]bool, 0, 6)
+	for i := 0; i < 6; i++ {
+		results = append(results, s.sample())
+	}
+	assert.Equal(t, []bool{true, false, false, true, false, false}, results)
+}
+
+func TestSamplerRate(t *testing.T) {
+	assert.True(t, newSampler(Sampling{}).sample())
+	assert.True(t, newSampler(Sampling{Rate: 1}).sample())
+
+	s := newSampler(Sampling{Rate: 0.5})
+	n := 0
+	for i := 0; i < 1000; i++ {
+		if s.sample() {
+			n++
+		}
+	}
+	assert.InDelta(t, 500, n, 100)
+}
+
+func TestParseSamplingConfig(t *testing.T) {
+	assert.Equal(t, &Sampling{Every: 2}, testConfig.Functions[2].Sampling)
+
+	storeSampler(testConfig.Functions[2])
+	assert.True(t, sampled("func3"))
+	assert.False(t, sampled("func3"))
+	assert.True(t, sampled("func1"))
+}
+
+func TestValidateSampling(t *testing.T) {
+	conf := &WorkflowConfig{Name: "test", Host: "localhost", Port: 9000}
+	conf.Functions = []App{{Name: "func1", Sampling: &Sampling{Rate: 0.5, Every: 2}}}
+	assert.Error(t, Validate(conf))
+}
@@ -23,6 +23,7 @@ type SenderClient interface {
 
 type senderClientImpl struct {
 	*client.Impl
+	chunk int // chunk is the max size of each chunk of the large frames, they're not split if zero.
 }
 
 // NewSender setups the client of Upstream YoMo-Zipper (formerly Zipper-Sender).
@@ -41,17 +42,27 @@ func (c *senderClientImpl) Write(data []byte) (int, error) {
 
 	// wrap data with frame.
 	txid := strconv.FormatInt(time.Now().UnixNano(), 10)
-	frame := frame.NewDataFrame(txid)
+	f := frame.NewDataFrame(txid)
 	// TODO: tag id
-	frame.SetCarriage(0x11, data)
+	f.SetCarriage(0x11, data)
 
-	return c.Stream.WriteFrame(frame)
+	// the large frames are split into chunks, the downstream YoMo-Zipper reassembles them.
+	if c.chunk > 0 && c.Supports(frame.TagOfChunkFrame) {
+		return c.Stream.WriteFrames(core.SplitFrame(f, c.chunk))
+	}
+	return c.Stream.WriteFrame(f)
+}
+
+// SetChunking splits the frames larger than size bytes into chunks.
+func (c *senderClientImpl) SetChunking(size int) {
+	c.chunk = size
 }
 
 // Connect to downstream YoMo-Zipper in edge-mesh.
 func (c *senderClientImpl) Connect(ip string, port int) (SenderClient, error) {
 	cli, err := c.BaseConnect(ip, port)
 	return &senderClientImpl{
-		cli,
+		Impl:  cli,
+		chunk: c.chunk,
 	}, err
 }
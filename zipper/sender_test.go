@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/internal/client"
 )
 
 // TestNewSender setups the client of Upstream YoMo-Zipper (formerly Zipper-Sender).
@@ -32,3 +33,23 @@ func TestSenderConnect(t *testing.T) {
 	server.Close()
 
 }
+
+// TestSenderUnauthenticated is rejected by the downstream YoMo-Zipper which authenticates the clients.
+func TestSenderUnauthenticated(t *testing.T) {
+	conf := *testConfig
+	conf.Auth = &AuthConfig{Tokens: []StaticToken{{Token: "s3cret"}}}
+	server := New(&conf)
+	go func() {
+		server.Serve(fmt.Sprintf("%s:%d", conf.Host, conf.Port+2))
+	}()
+	defer server.Close()
+
+	_, err := NewSender("sender").Connect(conf.Host, conf.Port+2)
+	assert.ErrorIs(t, err, client.ErrUnauthenticated)
+
+	sender := NewSender("sender")
+	sender.(*senderClientImpl).SetToken("s3cret")
+	_, err = sender.Connect(conf.Host, conf.Port+2)
+	assert.NoError(t, err)
+	sender.Close()
+}
@@ -0,0 +1,125 @@
+package zipper
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/yomorun/yomo/internal/frame"
+	"github.com/yomorun/yomo/logger"
+)
+
+const (
+	// SheddingPolicyBlock blocks the reader when the queue is full, it's the default policy.
+	SheddingPolicyBlock = "block"
+	// SheddingPolicyDrop drops the incoming frame when the queue is full.
+	SheddingPolicyDrop = "drop"
+	// SheddingPolicyPriority drops the frames with the lowest priority tags first when the queue is filling up.
+	SheddingPolicyPriority = "priority"
+)
+
+// SheddingConfig represents the config of load shedding when the zipper is overloaded.
+type SheddingConfig struct {
+	// Policy is one of "block", "drop" and "priority", the default is "block".
+	Policy string `yaml:"policy"`
+	// Priorities is the priority of each data tag, the bigger is the more important, the default priority is 0.
+	Priorities map[byte]int `yaml:"priorities"`
+}
+
+// shedder decides whether a frame should be dropped when the queue is full.
+type shedder struct {
+	policy     string
+	priorities map[byte]int
+	levels     []int    // levels are the distinct priorities in ascending order.
+	drops      sync.Map // drops is the counter of dropped frames by tag.
+	onDropped  func(tag byte, total uint64)
+}
+
+func newShedder(conf SheddingConfig) *shedder {
+	s := &shedder{
+		policy:     conf.Policy,
+		priorities: conf.Priorities,
+	}
+	if s.policy == "" {
+		s.policy = SheddingPolicyBlock
+	}
+
+	// the default priority 0 is always a level.
+	set := map[int]bool{0: true}
+	for _, p := range conf.Priorities {
+		set[p] = true
+	}
+	for p := range set {
+		s.levels = append(s.levels, p)
+	}
+	sort.Ints(s.levels)
+
+	return s
+}
+
+// push the frame to the queue, returns false if the frame was dropped.
+func (s *shedder) push(queue chan *frame.DataFrame, f *frame.DataFrame) bool {
+	switch s.policy {
+	case SheddingPolicyDrop:
+		select {
+		case queue <- f:
+			return true
+		default:
+			s.drop(f)
+			return false
+		}
+	case SheddingPolicyPriority:
+		rank := s.rank(f.GetDataTagID())
+		if rank == len(s.levels)-1 {
+			// the most important frames are never dropped.
+			queue <- f
+			return true
+		}
+
+		// the lower priority the frame is, the lower threshold of queue length it will be dropped.
+		threshold := cap(queue) * (rank + 1) / len(s.levels)
+		if len(queue) >= threshold {
+			s.drop(f)
+			return false
+		}
+
+		select {
+		case queue <- f:
+			return true
+		default:
+			s.drop(f)
+			return false
+		}
+	default:
+		queue <- f
+		return true
+	}
+}
+
+// rank returns the index of the tag's priority in levels.
+func (s *shedder) rank(tag byte) int {
+	p := s.priorities[tag]
+	return sort.SearchInts(s.levels, p)
+}
+
+func (s *shedder) drop(f *frame.DataFrame) {
+	tag := f.GetDataTagID()
+	v, _ := s.drops.LoadOrStore(tag, new(uint64))
+	total := atomic.AddUint64(v.(*uint64), 1)
+
+	pipelineTimelines.record(StepDropped, "", f, "the queue is full")
+	logger.Debug("[zipper] the queue is full, drop the frame.", "policy", s.policy, "tag", tag, "TransactionID", f.TransactionID(), "dropped", total)
+	if s.onDropped != nil {
+		s.onDropped(tag, total)
+	}
+}
+
+// dropped returns the count of dropped frames by tag.
+func (s *shedder) dropped() map[byte]uint64 {
+	result := make(map[byte]uint64)
+	s.drops.Range(func(key, value interface{}) bool {
+		result[key.(byte)] = atomic.LoadUint64(value.(*uint64))
+		return true
+	})
+	return result
+}
@@ -0,0 +1,60 @@
+package zipper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/internal/frame"
+)
+
+func TestShedderPriority(t *testing.T) {
+	s := newShedder(SheddingConfig{
+		Policy:     SheddingPolicyPriority,
+		Priorities: map[byte]int{0x10: 1, 0x11: 2},
+	})
+
+	queue := make(chan *frame.DataFrame, 6)
+	low := frame.NewDataFrame("tid")
+	low.SetCarriage(0x12, []byte("low"))
+	mid := frame.NewDataFrame("tid")
+	mid.SetCarriage(0x10, []byte("mid"))
+	high := frame.NewDataFrame("tid")
+	high.SetCarriage(0x11, []byte("high"))
+
+	// the lowest priority frames are dropped when the queue is 1/3 full.
+	assert.True(t, s.push(queue, low))
+	assert.True(t, s.push(queue, low))
+	assert.False(t, s.push(queue, low))
+
+	// the middle priority frames are dropped when the queue is 2/3 full.
+	assert.True(t, s.push(queue, mid))
+	assert.True(t, s.push(queue, mid))
+	assert.False(t, s.push(queue, mid))
+
+	// the highest priority frames are never dropped.
+	assert.True(t, s.push(queue, high))
+	assert.True(t, s.push(queue, high))
+
+	assert.Equal(t, map[byte]uint64{0x12: 1, 0x10: 1}, s.dropped())
+}
+
+func TestShedderDrop(t *testing.T) {
+	var dropped uint64
+	s := newShedder(SheddingConfig{Policy: SheddingPolicyDrop})
+	s.onDropped = func(tag byte, total uint64) {
+		dropped = total
+	}
+
+	queue := make(chan *frame.DataFrame, 1)
+	f := frame.NewDataFrame("tid")
+	f.SetCarriage(0x10, []byte("data"))
+	assert.True(t, s.push(queue, f))
+	assert.False(t, s.push(queue, f))
+	assert.Equal(t, uint64(1), dropped)
+}
+
+func TestValidateSheddingPolicy(t *testing.T) {
+	conf := &WorkflowConfig{Name: "test", Host: "localhost", Port: 9000}
+	conf.Shedding.Policy = "unknown"
+	assert.Error(t, Validate(conf))
+}
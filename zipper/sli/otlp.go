@@ -0,0 +1,205 @@
+package sli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Exporter exports the SLIs.
+type Exporter interface {
+	Export(ctx context.Context, snapshots []Snapshot) error
+}
+
+// otlpExporter exports the SLIs as OTLP metrics by the OTLP/HTTP JSON protocol.
+type otlpExporter struct {
+	endpoint string
+	headers  map[string]string
+	service  string
+	client   *http.Client
+}
+
+// NewOTLPExporter creates an Exporter which sends the SLIs to the OTLP/HTTP endpoint,
+// e.g. "https://otlp-gateway-prod-us-central-0.grafana.net/otlp", the path "/v1/metrics" will be appended.
+// The headers are sent with each request, such as the "Authorization" of Grafana Cloud.
+func NewOTLPExporter(endpoint string, headers map[string]string, service string) Exporter {
+	return &otlpExporter{
+		endpoint: strings.TrimSuffix(endpoint, "/") + "/v1/metrics",
+		headers:  headers,
+		service:  service,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Export the SLIs to the OTLP endpoint.
+func (e *otlpExporter) Export(ctx context.Context, snapshots []Snapshot) error {
+	if len(snapshots) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(e.encode(snapshots))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+
+	res, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("sli: export to %s failed with status %s", e.endpoint, res.Status)
+	}
+	return nil
+}
+
+// the JSON encoding of OTLP metrics, see https://github.com/open-telemetry/opentelemetry-proto.
+type (
+	otlpRequest struct {
+		ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+	}
+
+	otlpResourceMetrics struct {
+		Resource     otlpResource       `json:"resource"`
+		ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+	}
+
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+
+	otlpScopeMetrics struct {
+		Scope   otlpScope    `json:"scope"`
+		Metrics []otlpMetric `json:"metrics"`
+	}
+
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+
+	otlpAttribute struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+
+	otlpValue struct {
+		StringValue string `json:"stringValue"`
+	}
+
+	otlpMetric struct {
+		Name      string         `json:"name"`
+		Unit      string         `json:"unit,omitempty"`
+		Sum       *otlpSum       `json:"sum,omitempty"`
+		Gauge     *otlpGauge     `json:"gauge,omitempty"`
+		Histogram *otlpHistogram `json:"histogram,omitempty"`
+	}
+
+	otlpSum struct {
+		DataPoints             []otlpNumberDataPoint `json:"dataPoints"`
+		AggregationTemporality int                   `json:"aggregationTemporality"`
+		IsMonotonic            bool                  `json:"isMonotonic"`
+	}
+
+	otlpGauge struct {
+		DataPoints []otlpNumberDataPoint `json:"dataPoints"`
+	}
+
+	otlpHistogram struct {
+		DataPoints             []otlpHistogramDataPoint `json:"dataPoints"`
+		AggregationTemporality int                      `json:"aggregationTemporality"`
+	}
+
+	otlpNumberDataPoint struct {
+		Attributes        []otlpAttribute `json:"attributes"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		TimeUnixNano      string          `json:"timeUnixNano"`
+		AsInt             string          `json:"asInt,omitempty"`
+		AsDouble          *float64        `json:"asDouble,omitempty"`
+	}
+
+	otlpHistogramDataPoint struct {
+		Attributes        []otlpAttribute `json:"attributes"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		TimeUnixNano      string          `json:"timeUnixNano"`
+		Count             string          `json:"count"`
+		Sum               float64         `json:"sum"`
+		BucketCounts      []string        `json:"bucketCounts"`
+		ExplicitBounds    []float64       `json:"explicitBounds"`
+	}
+)
+
+// aggregationTemporalityCumulative is AGGREGATION_TEMPORALITY_CUMULATIVE in OTLP.
+const aggregationTemporalityCumulative = 2
+
+func (e *otlpExporter) encode(snapshots []Snapshot) otlpRequest {
+	var (
+		total        = &otlpSum{AggregationTemporality: aggregationTemporalityCumulative, IsMonotonic: true}
+		success      = &otlpSum{AggregationTemporality: aggregationTemporalityCumulative, IsMonotonic: true}
+		availability = &otlpGauge{}
+		latency      = &otlpHistogram{AggregationTemporality: aggregationTemporalityCumulative}
+	)
+
+	for _, s := range snapshots {
+		attrs := []otlpAttribute{{Key: "probe", Value: otlpValue{StringValue: s.Probe}}}
+		start := strconv.FormatInt(s.Start.UnixNano(), 10)
+		now := strconv.FormatInt(s.Time.UnixNano(), 10)
+		ratio := s.Availability()
+
+		total.DataPoints = append(total.DataPoints, otlpNumberDataPoint{
+			Attributes: attrs, StartTimeUnixNano: start, TimeUnixNano: now,
+			AsInt: strconv.FormatUint(s.Total, 10),
+		})
+		success.DataPoints = append(success.DataPoints, otlpNumberDataPoint{
+			Attributes: attrs, StartTimeUnixNano: start, TimeUnixNano: now,
+			AsInt: strconv.FormatUint(s.Success, 10),
+		})
+		availability.DataPoints = append(availability.DataPoints, otlpNumberDataPoint{
+			Attributes: attrs, StartTimeUnixNano: start, TimeUnixNano: now,
+			AsDouble: &ratio,
+		})
+
+		buckets := make([]string, 0, len(s.LatencyBuckets))
+		for _, n := range s.LatencyBuckets {
+			buckets = append(buckets, strconv.FormatUint(n, 10))
+		}
+		latency.DataPoints = append(latency.DataPoints, otlpHistogramDataPoint{
+			Attributes: attrs, StartTimeUnixNano: start, TimeUnixNano: now,
+			Count:          strconv.FormatUint(s.Success, 10),
+			Sum:            s.LatencySum,
+			BucketCounts:   buckets,
+			ExplicitBounds: s.LatencyBounds,
+		})
+	}
+
+	return otlpRequest{
+		ResourceMetrics: []otlpResourceMetrics{{
+			Resource: otlpResource{
+				Attributes: []otlpAttribute{{Key: "service.name", Value: otlpValue{StringValue: e.service}}},
+			},
+			ScopeMetrics: []otlpScopeMetrics{{
+				Scope: otlpScope{Name: "github.com/yomorun/yomo/zipper/sli"},
+				Metrics: []otlpMetric{
+					{Name: "yomo.sli.probes", Sum: total},
+					{Name: "yomo.sli.probes.success", Sum: success},
+					{Name: "yomo.sli.availability", Unit: "1", Gauge: availability},
+					{Name: "yomo.sli.latency", Unit: "ms", Histogram: latency},
+				},
+			}},
+		}},
+	}
+}
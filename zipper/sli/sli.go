@@ -0,0 +1,116 @@
+// Package sli aggregates the results of synthetic probes into availability and latency SLIs,
+// and exports them to an OTLP endpoint such as Grafana Cloud.
+package sli
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultLatencyBounds are the default bucket bounds of the latency histogram, in milliseconds.
+var DefaultLatencyBounds = []float64{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000}
+
+// Result is the result of a probe.
+type Result struct {
+	// Probe is the name of the probe.
+	Probe string
+	// Success indicates if the probe frame went through the whole pipeline before timeout.
+	Success bool
+	// Latency is the end-to-end latency of the probe frame.
+	Latency time.Duration
+}
+
+// Snapshot is the cumulative SLIs of a probe.
+type Snapshot struct {
+	Probe string
+	// Start is the time when the probe started recording.
+	Start time.Time
+	// Time is the time when the snapshot was taken.
+	Time time.Time
+	// Total is the count of probes.
+	Total uint64
+	// Success is the count of successful probes.
+	Success uint64
+	// LatencyBounds are the bucket bounds of the latency histogram, in milliseconds.
+	LatencyBounds []float64
+	// LatencyBuckets are the counts of successful probes in each bucket, it has len(LatencyBounds)+1 elements.
+	LatencyBuckets []uint64
+	// LatencySum is the sum of latency of successful probes, in milliseconds.
+	LatencySum float64
+}
+
+// Availability is the ratio of successful probes.
+func (s Snapshot) Availability() float64 {
+	if s.Total == 0 {
+		return 1
+	}
+	return float64(s.Success) / float64(s.Total)
+}
+
+// Recorder records the results of probes.
+type Recorder struct {
+	mu     sync.Mutex
+	bounds []float64
+	stats  map[string]*Snapshot
+	now    func() time.Time
+}
+
+// NewRecorder creates a new Recorder with the latency bucket bounds in milliseconds.
+func NewRecorder(bounds ...float64) *Recorder {
+	if len(bounds) == 0 {
+		bounds = DefaultLatencyBounds
+	}
+	return &Recorder{
+		bounds: bounds,
+		stats:  make(map[string]*Snapshot),
+		now:    time.Now,
+	}
+}
+
+// Record the result of a probe.
+func (r *Recorder) Record(res Result) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, ok := r.stats[res.Probe]
+	if !ok {
+		s = &Snapshot{
+			Probe:          res.Probe,
+			Start:          r.now(),
+			LatencyBounds:  r.bounds,
+			LatencyBuckets: make([]uint64, len(r.bounds)+1),
+		}
+		r.stats[res.Probe] = s
+	}
+
+	s.Total++
+	if !res.Success {
+		return
+	}
+
+	s.Success++
+	ms := float64(res.Latency) / float64(time.Millisecond)
+	s.LatencySum += ms
+	s.LatencyBuckets[sort.SearchFloat64s(r.bounds, ms)]++
+}
+
+// Snapshots returns the current SLIs of all probes, sorted by name.
+func (r *Recorder) Snapshots() []Snapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	result := make([]Snapshot, 0, len(r.stats))
+	for _, s := range r.stats {
+		snapshot := *s
+		snapshot.Time = now
+		snapshot.LatencyBuckets = append([]uint64(nil), s.LatencyBuckets...)
+		result = append(result, snapshot)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Probe < result[j].Probe
+	})
+
+	return result
+}
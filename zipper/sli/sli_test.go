@@ -0,0 +1,55 @@
+package sli
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder(10, 100)
+	r.Record(Result{Probe: "p1", Success: true, Latency: 5 * time.Millisecond})
+	r.Record(Result{Probe: "p1", Success: true, Latency: 50 * time.Millisecond})
+	r.Record(Result{Probe: "p1"})
+	r.Record(Result{Probe: "p0", Success: true, Latency: time.Second})
+
+	snapshots := r.Snapshots()
+	assert.Equal(t, 2, len(snapshots))
+	assert.Equal(t, "p0", snapshots[0].Probe)
+	assert.Equal(t, []uint64{0, 0, 1}, snapshots[0].LatencyBuckets)
+
+	p1 := snapshots[1]
+	assert.Equal(t, uint64(3), p1.Total)
+	assert.Equal(t, uint64(2), p1.Success)
+	assert.InDelta(t, 2.0/3, p1.Availability(), 0.001)
+	assert.Equal(t, []uint64{1, 1, 0}, p1.LatencyBuckets)
+	assert.InDelta(t, 55, p1.LatencySum, 0.001)
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var req otlpRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/otlp/v1/metrics", r.URL.Path)
+		assert.Equal(t, "Basic token", r.Header.Get("Authorization"))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+	}))
+	defer server.Close()
+
+	r := NewRecorder()
+	r.Record(Result{Probe: "p1", Success: true, Latency: 5 * time.Millisecond})
+
+	exporter := NewOTLPExporter(server.URL+"/otlp/", map[string]string{"Authorization": "Basic token"}, "zipper")
+	assert.NoError(t, exporter.Export(context.Background(), r.Snapshots()))
+
+	metrics := req.ResourceMetrics[0].ScopeMetrics[0].Metrics
+	assert.Equal(t, 4, len(metrics))
+	assert.Equal(t, "1", metrics[0].Sum.DataPoints[0].AsInt)
+	assert.Equal(t, 1.0, *metrics[2].Gauge.DataPoints[0].AsDouble)
+	assert.Equal(t, "p1", metrics[3].Histogram.DataPoints[0].Attributes[0].Value.StringValue)
+}
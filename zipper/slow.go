@@ -0,0 +1,146 @@
+package zipper
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/yomorun/yomo/logger"
+)
+
+const (
+	// DefaultSlowLatency is the default mean latency of a stream function above which it's a slow consumer.
+	DefaultSlowLatency = time.Second
+	// DefaultSlowBacklog is the default count of frames waiting for a stream function above which it's a slow
+	// consumer, unless the backlog is draining.
+	DefaultSlowBacklog = bufferSize * 8 / 10
+	// DefaultSlowInterval is the default interval of checking the stream functions.
+	DefaultSlowInterval = 10 * time.Second
+)
+
+// SlowConsumerConfig represents the thresholds of detecting the slow stream functions, which are reported by
+// the warning logs, the metrics and the admin API.
+type SlowConsumerConfig struct {
+	// Latency is the mean latency in an interval above which the stream function is slow, the default is 1s.
+	Latency time.Duration `yaml:"latency,omitempty"`
+	// Backlog is the count of waiting frames above which the stream function is slow unless the backlog is
+	// draining, the default is 80% of the queue.
+	Backlog int `yaml:"backlog,omitempty"`
+	// Interval is the interval of checking the stream functions, the default is 10s.
+	Interval time.Duration `yaml:"interval,omitempty"`
+}
+
+// slowState is the state of a stream function in the last check.
+type slowState struct {
+	count   uint64  // the count of latency observations at the last check.
+	sum     float64 // the sum of latency observations at the last check.
+	backlog int     // the backlog at the last check.
+	latency float64 // the mean latency in seconds during the last interval.
+	slow    bool
+}
+
+// slowDetector finds the slow stream functions by their latency and the growth of their backlog,
+// the slow stream function with the largest backlog is the bottleneck of workflow.
+type slowDetector struct {
+	conf       SlowConsumerConfig
+	mutex      sync.RWMutex
+	stages     map[string]*slowState
+	bottleneck string
+}
+
+func newSlowDetector(conf SlowConsumerConfig) *slowDetector {
+	if conf.Latency <= 0 {
+		conf.Latency = DefaultSlowLatency
+	}
+	if conf.Backlog <= 0 {
+		conf.Backlog = DefaultSlowBacklog
+	}
+	if conf.Interval <= 0 {
+		conf.Interval = DefaultSlowInterval
+	}
+	return &slowDetector{conf: conf, stages: make(map[string]*slowState)}
+}
+
+// run checks the stream functions periodically until ctx is done.
+func (d *slowDetector) run(ctx context.Context, queues *queueTracker, stages []string) {
+	ticker := time.NewTicker(d.conf.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, backlog := queues.backlog()
+			d.check(stages, backlog, &pipelineMetrics.latency)
+		}
+	}
+}
+
+// check updates the states of stages by their backlog and the histograms of latency.
+func (d *slowDetector) check(stages []string, backlog map[string]int, latency *sync.Map) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	bottleneck, largest := "", -1
+	for _, stage := range stages {
+		state, ok := d.stages[stage]
+		if !ok {
+			state = &slowState{}
+			d.stages[stage] = state
+		}
+
+		state.latency = 0
+		if v, ok := latency.Load(stage); ok {
+			count, sum := v.(*histogram).snapshot()
+			if count > state.count {
+				state.latency = (sum - state.sum) / float64(count-state.count)
+			}
+			state.count, state.sum = count, sum
+		}
+
+		// the full queue which isn't draining is as slow as the growing one.
+		draining := backlog[stage] < state.backlog
+		state.backlog = backlog[stage]
+		slow := state.latency > d.conf.Latency.Seconds() || (state.backlog >= d.conf.Backlog && !draining)
+
+		if slow && !state.slow {
+			logger.Warn("[zipper] the stream function is a slow consumer.", "stream-fn", stage, "latency", state.latency, "backlog", state.backlog)
+		} else if !slow && state.slow {
+			logger.Info("[zipper] the stream function catches up.", "stream-fn", stage, "latency", state.latency, "backlog", state.backlog)
+		}
+		state.slow = slow
+
+		if slow && state.backlog > largest {
+			bottleneck, largest = stage, state.backlog
+		}
+	}
+
+	if bottleneck != "" && bottleneck != d.bottleneck {
+		logger.Warn("[zipper] the stream function is the bottleneck of workflow.", "stream-fn", bottleneck)
+	}
+	d.bottleneck = bottleneck
+}
+
+// state returns if the stage is slow and its mean latency in seconds during the last interval.
+func (d *slowDetector) state(stage string) (slow bool, latency float64) {
+	if d == nil {
+		return false, 0
+	}
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	if state, ok := d.stages[stage]; ok {
+		return state.slow, state.latency
+	}
+	return false, 0
+}
+
+// slowest returns the stage which is the bottleneck of workflow, it's empty if no stage is slow.
+func (d *slowDetector) slowest() string {
+	if d == nil {
+		return ""
+	}
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	return d.bottleneck
+}
@@ -0,0 +1,42 @@
+package zipper
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlowDetector(t *testing.T) {
+	d := newSlowDetector(SlowConsumerConfig{Latency: 100 * time.Millisecond, Backlog: 10})
+	stages := []string{"fast", "laggy", "stuck"}
+	latency := &sync.Map{}
+	fast, laggy := &histogram{}, &histogram{}
+	latency.Store("fast", fast)
+	latency.Store("laggy", laggy)
+
+	fast.observe(0.01)
+	laggy.observe(0.5)
+	d.check(stages, map[string]int{"stuck": 20}, latency)
+
+	slow, mean := d.state("laggy")
+	assert.True(t, slow)
+	assert.Equal(t, 0.5, mean)
+	slow, _ = d.state("fast")
+	assert.False(t, slow)
+	slow, _ = d.state("stuck")
+	assert.True(t, slow)
+	// the slow consumer with the largest backlog.
+	assert.Equal(t, "stuck", d.slowest())
+
+	// the latency is measured in each interval, and the draining backlog catches up.
+	laggy.observe(0.01)
+	d.check(stages, map[string]int{"stuck": 15}, latency)
+	slow, mean = d.state("laggy")
+	assert.False(t, slow)
+	assert.InDelta(t, 0.01, mean, 1e-9)
+	slow, _ = d.state("stuck")
+	assert.False(t, slow)
+	assert.Empty(t, d.slowest())
+}
@@ -0,0 +1,75 @@
+package zipper
+
+import (
+	"sync"
+
+	"github.com/yomorun/yomo/internal/core"
+)
+
+// tagSet is the data tags observed by a stream function, it's changed at runtime by the control frames
+// which the stream function sends.
+type tagSet struct {
+	mutex sync.RWMutex
+	tags  [256]bool
+}
+
+// newTagSet creates the tag set of the tags in the config of app, all tags are observed if none is configured.
+func newTagSet(app App) *tagSet {
+	s := &tagSet{}
+	for i := range s.tags {
+		s.tags[i] = app.accepts(byte(i))
+	}
+	return s
+}
+
+// update adds the subscribed tags and removes the unsubscribed tags.
+func (s *tagSet) update(subscribe []byte, unsubscribe []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, tag := range subscribe {
+		s.tags[tag] = true
+	}
+	for _, tag := range unsubscribe {
+		s.tags[tag] = false
+	}
+}
+
+// contains indicates if the tag is observed.
+func (s *tagSet) contains(tag byte) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.tags[tag]
+}
+
+// list returns the observed tags in ascending order.
+func (s *tagSet) list() []byte {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	tags := make([]byte, 0)
+	for i, ok := range s.tags {
+		if ok {
+			tags = append(tags, byte(i))
+		}
+	}
+	return tags
+}
+
+var subscriptionCache = sync.Map{} // the tags changed at runtime of stream functions by name.
+
+// updateSubscription changes the observed tags of the stream function, the change applies to all instances
+// of the function and lasts until the last instance is disconnected. The observed tags are returned.
+func updateSubscription(app App, subscribe []byte, unsubscribe []byte) []byte {
+	v, _ := subscriptionCache.LoadOrStore(app.Name, newTagSet(app))
+	s := v.(*tagSet)
+	s.update(subscribe, unsubscribe)
+	return s.list()
+}
+
+// clearSubscription forgets the tags changed at runtime when the last instance of the stream function is
+// disconnected, so the instances connected later observe the tags in the config until they change them.
+func clearSubscription(name string, connMap *sync.Map) {
+	if len(findConn(App{Name: name}, connMap, core.ConnTypeStreamFunction)) > 0 {
+		return
+	}
+	subscriptionCache.Delete(name)
+}
@@ -0,0 +1,84 @@
+package zipper
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/core/quic"
+	"github.com/yomorun/yomo/internal/core"
+	"github.com/yomorun/yomo/internal/frame"
+)
+
+func TestUpdateSubscription(t *testing.T) {
+	app := App{Name: "subscription-fn", Tags: []byte{0x10}}
+	appCache.Store(app.Name, app)
+	defer appCache.Delete(app.Name)
+	defer subscriptionCache.Delete(app.Name)
+
+	assert.True(t, subscribed(app.Name, 0x10))
+	assert.False(t, subscribed(app.Name, 0x11))
+
+	// enable the debug data at runtime.
+	assert.Equal(t, []byte{0x10, 0x11}, updateSubscription(app, []byte{0x11}, nil))
+	assert.True(t, subscribed(app.Name, 0x11))
+
+	assert.Equal(t, []byte{0x11}, updateSubscription(app, nil, []byte{0x10}))
+	assert.False(t, subscribed(app.Name, 0x10))
+}
+
+func TestUpdateSubscriptionOfAllTags(t *testing.T) {
+	app := App{Name: "all-tags-fn"}
+	defer subscriptionCache.Delete(app.Name)
+
+	tags := updateSubscription(app, nil, []byte{0x12})
+	assert.Len(t, tags, 255)
+	assert.False(t, subscribed(app.Name, 0x12))
+	assert.True(t, subscribed(app.Name, 0x13))
+}
+
+func TestSubscribedToExtraTags(t *testing.T) {
+	storage := App{Name: "storage-fn", Tags: []byte{0x10}}
+	alerting := App{Name: "alerting-fn", Tags: []byte{0x20}}
+	appCache.Store(storage.Name, storage)
+	appCache.Store(alerting.Name, alerting)
+	defer appCache.Delete(storage.Name)
+	defer appCache.Delete(alerting.Name)
+
+	data := frame.NewDataFrame("1")
+	data.SetCarriage(0x10, []byte("a"))
+	assert.True(t, subscribedTo(storage.Name, data))
+	assert.False(t, subscribedTo(alerting.Name, data))
+
+	// the payload is observed by both stream functions.
+	data.SetExtraTags(0x20)
+	assert.True(t, subscribedTo(storage.Name, data))
+	assert.True(t, subscribedTo(alerting.Name, data))
+}
+
+func TestClearSubscriptionOnReconnect(t *testing.T) {
+	app := App{Name: "reconnect-fn", Tags: []byte{0x10}}
+	appCache.Store(app.Name, app)
+	defer appCache.Delete(app.Name)
+	defer subscriptionCache.Delete(app.Name)
+
+	var connMap sync.Map
+	first := &Conn{Addr: "127.0.0.1:1", Conn: quic.NewConn(app.Name, core.ConnTypeStreamFunction)}
+	second := &Conn{Addr: "127.0.0.1:2", Conn: quic.NewConn(app.Name, core.ConnTypeStreamFunction)}
+	connMap.Store(first.Addr, first)
+	connMap.Store(second.Addr, second)
+
+	updateSubscription(app, []byte{0x11}, []byte{0x10})
+	assert.True(t, subscribed(app.Name, 0x11))
+
+	// the change is kept while any instance is connected.
+	connMap.Delete(first.Addr)
+	clearSubscription(app.Name, &connMap)
+	assert.True(t, subscribed(app.Name, 0x11))
+
+	// the instance reconnected after all instances are gone observes the tags in the config.
+	connMap.Delete(second.Addr)
+	clearSubscription(app.Name, &connMap)
+	assert.True(t, subscribed(app.Name, 0x10))
+	assert.False(t, subscribed(app.Name, 0x11))
+}
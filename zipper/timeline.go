@@ -0,0 +1,199 @@
+package zipper
+
+import (
+	"container/list"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yomorun/yomo/internal/frame"
+)
+
+const (
+	// DefaultTimelineSize is the default count of the recent transactions whose timelines are kept.
+	DefaultTimelineSize = 1000
+	// DefaultTimelineSteps is the default max count of steps kept for each transaction.
+	DefaultTimelineSteps = 64
+)
+
+// the points of pipeline which are recorded in the timeline of transaction.
+const (
+	StepReceived  = "received"
+	StepRouted    = "routed"
+	StepResponded = "responded"
+	StepSent      = "sent"
+	StepDropped   = "dropped"
+	StepFailed    = "failed"
+)
+
+// TimelineConfig represents the config of transaction timelines, the steps of the recent transactions
+// through the pipeline are kept in memory for the admin API.
+type TimelineConfig struct {
+	// Size is the count of the recent transactions which are kept, the least recently updated one is evicted,
+	// the default is 1000.
+	Size int `yaml:"size,omitempty"`
+	// MaxSteps is the max count of steps kept for each transaction, the later steps are discarded, the default is 64.
+	MaxSteps int `yaml:"max_steps,omitempty"`
+}
+
+// TimelineStep is a step of the transaction through the pipeline.
+type TimelineStep struct {
+	Time  time.Time `json:"time"`
+	Point string    `json:"point"`
+	// Stage is the stream function which the frame is routed to, or which responded it.
+	Stage string `json:"stage,omitempty"`
+	Tag   int    `json:"tag"`
+	// Elapsed is the seconds since the first step of the transaction.
+	Elapsed float64 `json:"elapsed_seconds"`
+	// Reason is the reason why the frame is dropped or failed.
+	Reason string `json:"reason,omitempty"`
+}
+
+// Timeline is the recorded steps of a transaction.
+type Timeline struct {
+	TransactionID string         `json:"tid"`
+	Steps         []TimelineStep `json:"steps"`
+	// Truncated indicates the steps beyond the max steps are discarded.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// pipelineTimelines is the timelines of the recent transactions of all pipelines of YoMo-Zipper,
+// nothing is recorded until it's enabled.
+var pipelineTimelines = &timelines{}
+
+// timelines is the LRU of the recent transactions, the front is the most recently updated one.
+type timelines struct {
+	enabled  int32
+	mutex    sync.Mutex
+	size     int
+	maxSteps int
+	lru      *list.List
+	items    map[string]*list.Element
+}
+
+// enable starts recording the timelines by the config, the recorded ones are cleared.
+func (t *timelines) enable(conf *TimelineConfig) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.size, t.maxSteps = conf.Size, conf.MaxSteps
+	if t.size <= 0 {
+		t.size = DefaultTimelineSize
+	}
+	if t.maxSteps <= 0 {
+		t.maxSteps = DefaultTimelineSteps
+	}
+	t.lru = list.New()
+	t.items = make(map[string]*list.Element)
+	atomic.StoreInt32(&t.enabled, 1)
+}
+
+// disable stops recording the timelines and clears the recorded ones.
+func (t *timelines) disable() {
+	atomic.StoreInt32(&t.enabled, 0)
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.lru, t.items = nil, nil
+}
+
+// record appends the step of the frame at the point of pipeline to the timeline of its transaction.
+func (t *timelines) record(point string, stage string, data *frame.DataFrame, reason string) {
+	if atomic.LoadInt32(&t.enabled) == 0 {
+		return
+	}
+	tid := data.TransactionID()
+	if tid == "" {
+		return
+	}
+	step := TimelineStep{Time: time.Now(), Point: point, Stage: stage, Tag: int(data.GetDataTagID()), Reason: reason}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.items == nil {
+		return
+	}
+
+	e, ok := t.items[tid]
+	if !ok {
+		e = t.lru.PushFront(&Timeline{TransactionID: tid})
+		t.items[tid] = e
+		if t.lru.Len() > t.size {
+			oldest := t.lru.Back()
+			t.lru.Remove(oldest)
+			delete(t.items, oldest.Value.(*Timeline).TransactionID)
+		}
+	} else {
+		t.lru.MoveToFront(e)
+	}
+
+	timeline := e.Value.(*Timeline)
+	if len(timeline.Steps) >= t.maxSteps {
+		timeline.Truncated = true
+		return
+	}
+	if len(timeline.Steps) > 0 {
+		step.Elapsed = step.Time.Sub(timeline.Steps[0].Time).Seconds()
+	}
+	timeline.Steps = append(timeline.Steps, step)
+}
+
+// get returns a copy of the timeline of the transaction.
+func (t *timelines) get(tid string) (Timeline, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	e, ok := t.items[tid]
+	if !ok {
+		return Timeline{}, false
+	}
+	timeline := *e.Value.(*Timeline)
+	timeline.Steps = append([]TimelineStep(nil), timeline.Steps...)
+	return timeline, true
+}
+
+// recent returns the IDs of the recent transactions, the most recently updated one is the first.
+func (t *timelines) recent(limit int) []string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	ids := make([]string, 0)
+	if t.lru == nil {
+		return ids
+	}
+	for e := t.lru.Front(); e != nil && (limit <= 0 || len(ids) < limit); e = e.Next() {
+		ids = append(ids, e.Value.(*Timeline).TransactionID)
+	}
+	return ids
+}
+
+// serveTimelines is the admin API of transaction timelines.
+// GET /transactions lists the IDs of the recent transactions, the most recently updated one is the first,
+// they're limited by the query of limit, e.g. "?limit=10".
+// GET /transactions/{tid} returns the timeline of the transaction.
+func serveTimelines(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	tid := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/transactions"), "/")
+	if tid == "" {
+		limit := 0
+		if v := r.URL.Query().Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid limit " + v})
+				return
+			}
+			limit = n
+		}
+		writeJSON(w, http.StatusOK, pipelineTimelines.recent(limit))
+		return
+	}
+	timeline, ok := pipelineTimelines.get(tid)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "the transaction is not found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, timeline)
+}
@@ -0,0 +1,92 @@
+package zipper
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/internal/frame"
+)
+
+func TestTimelines(t *testing.T) {
+	timelines := &timelines{}
+	f1 := frame.NewDataFrame("tid-1")
+	f1.SetCarriage(0x33, []byte("data"))
+
+	// nothing is recorded until it's enabled.
+	timelines.record(StepReceived, "", f1, "")
+	assert.Empty(t, timelines.recent(0))
+
+	timelines.enable(&TimelineConfig{Size: 2, MaxSteps: 3})
+	timelines.record(StepReceived, "", f1, "")
+	timelines.record(StepRouted, "fn", f1, "")
+	timelines.record(StepResponded, "fn", f1, "")
+	timelines.record(StepSent, "", f1, "")
+
+	timeline, ok := timelines.get("tid-1")
+	assert.True(t, ok)
+	assert.True(t, timeline.Truncated)
+	assert.Len(t, timeline.Steps, 3)
+	assert.Equal(t, StepRouted, timeline.Steps[1].Point)
+	assert.Equal(t, "fn", timeline.Steps[1].Stage)
+	assert.Equal(t, 0x33, timeline.Steps[1].Tag)
+	assert.GreaterOrEqual(t, timeline.Steps[2].Elapsed, timeline.Steps[1].Elapsed)
+
+	// the least recently updated transaction is evicted.
+	timelines.record(StepReceived, "", frame.NewDataFrame("tid-2"), "")
+	timelines.record(StepSent, "", f1, "")
+	timelines.record(StepReceived, "", frame.NewDataFrame("tid-3"), "")
+	assert.Equal(t, []string{"tid-3", "tid-1"}, timelines.recent(0))
+	assert.Equal(t, []string{"tid-3"}, timelines.recent(1))
+	_, ok = timelines.get("tid-2")
+	assert.False(t, ok)
+
+	timelines.disable()
+	assert.Empty(t, timelines.recent(0))
+}
+
+func TestServeTimelines(t *testing.T) {
+	pipelineTimelines.enable(&TimelineConfig{})
+	defer pipelineTimelines.disable()
+
+	f := frame.NewDataFrame("tid")
+	f.SetCarriage(0x33, []byte("data"))
+	pipelineTimelines.record(StepReceived, "", f, "")
+	pipelineTimelines.record(StepFailed, "fn", f, "session closed")
+
+	h := newServerHandler(&WorkflowConfig{AdminToken: "secret", Timeline: &TimelineConfig{}}, "")
+	server := httptest.NewServer(newAdminMux(h))
+	defer server.Close()
+
+	get := func(path string) *http.Response {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		res, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		return res
+	}
+
+	res := get("/transactions")
+	var ids []string
+	assert.NoError(t, json.NewDecoder(res.Body).Decode(&ids))
+	res.Body.Close()
+	assert.Equal(t, []string{"tid"}, ids)
+
+	res = get("/transactions/tid")
+	var timeline Timeline
+	assert.NoError(t, json.NewDecoder(res.Body).Decode(&timeline))
+	res.Body.Close()
+	assert.Equal(t, "tid", timeline.TransactionID)
+	assert.Len(t, timeline.Steps, 2)
+	assert.Equal(t, "session closed", timeline.Steps[1].Reason)
+
+	res = get("/transactions/unknown")
+	res.Body.Close()
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+
+	res = get("/transactions?limit=-1")
+	res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
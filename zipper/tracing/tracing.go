@@ -2,15 +2,20 @@ package tracing
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
-	"log"
+	"fmt"
+	"net/url"
 	"os"
 	"strconv"
+	"sync"
 	"time"
 
-	"github.com/tidwall/gjson"
+	"github.com/yomorun/yomo/internal/frame"
+	"github.com/yomorun/yomo/logger"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/exporters/jaeger"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
 	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/resource"
@@ -24,23 +29,68 @@ const (
 	DefaultTracingEnable = false
 	// DefaultTracingEndpoint is the default endpoint for the tracing.
 	DefaultTracingEndpoint = "http://localhost:14268/api/traces"
+	// DefaultOTLPEndpoint is the default endpoint of the OTLP exporter.
+	DefaultOTLPEndpoint = "http://localhost:4318"
+	// DefaultTracingExporter is the default exporter of the spans.
+	DefaultTracingExporter = ExporterJaeger
 )
 
+const (
+	// ExporterJaeger exports the spans to the collector of Jaeger.
+	ExporterJaeger = "jaeger"
+	// ExporterOTLP exports the spans by OTLP over HTTP, e.g. to the OpenTelemetry Collector, Jaeger or Tempo.
+	ExporterOTLP = "otlp"
+	// ExporterStdout prints the spans to stdout.
+	ExporterStdout = "stdout"
+)
+
+var (
+	mutex    sync.Mutex
+	provider *tracesdk.TracerProvider
+)
+
+// spanExporter returns the exporter which sends the spans to the endpoint.
+func spanExporter(exporter string, endpoint string) (tracesdk.SpanExporter, error) {
+	switch exporter {
+	case ExporterJaeger:
+		if endpoint == "" {
+			return stdouttrace.New(stdouttrace.WithPrettyPrint())
+		}
+		// Create the Jaeger exporter
+		return jaeger.New(jaeger.WithCollectorEndpoint(jaeger.WithEndpoint(endpoint)))
+	case ExporterOTLP:
+		return otlpExporter(endpoint)
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithPrettyPrint())
+	}
+	return nil, fmt.Errorf("tracing: unknown exporter %q", exporter)
+}
+
+// otlpExporter returns the OTLP exporter over HTTP, the endpoint is the URL of the collector, e.g.
+// http://localhost:4318. The OTEL_EXPORTER_OTLP_* environment variables, e.g. the headers, are applied as well.
+func otlpExporter(endpoint string) (tracesdk.SpanExporter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(u.Host)}
+	if u.Scheme == "http" {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	if u.Path != "" && u.Path != "/" {
+		opts = append(opts, otlptracehttp.WithURLPath(u.Path))
+	}
+	return otlptracehttp.New(context.Background(), opts...)
+}
+
 // tracerProvider returns an OpenTelemetry TracerProvider configured to use
-// the Jaeger exporter that will send spans to the provided url. The returned
+// the exporter that will send spans to the provided url. The returned
 // TracerProvider will also use a Resource configured with all the information
 // about the application.
-func tracerProvider(service string, collectorEndpoint string) (*tracesdk.TracerProvider, error) {
-	var exp tracesdk.SpanExporter
-	var err error
-	if collectorEndpoint == "" {
-		exp, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
-	} else {
-		// Create the Jaeger exporter
-		exp, err = jaeger.New(jaeger.WithCollectorEndpoint(jaeger.WithEndpoint(collectorEndpoint)))
-		if err != nil {
-			return nil, err
-		}
+func tracerProvider(service string, exporter string, collectorEndpoint string) (*tracesdk.TracerProvider, error) {
+	exp, err := spanExporter(exporter, collectorEndpoint)
+	if err != nil {
+		return nil, err
 	}
 
 	bsp := tracesdk.NewBatchSpanProcessor(exp)
@@ -61,7 +111,11 @@ func tracerProvider(service string, collectorEndpoint string) (*tracesdk.TracerP
 	return tp, nil
 }
 
-// NewTracerProvider creates a new TracerProvider.
+// NewTracerProvider creates a new TracerProvider, which is enabled by the environment variable YOMO_TRACING_ENABLE.
+// The spans are sent by the exporter of YOMO_TRACING_EXPORTER (jaeger, otlp or stdout) to YOMO_TRACING_ENDPOINT.
+// The provider is registered as the global one once per process, and the later calls return it. The sources and
+// the stream functions export their spans by the global provider, so the application calls it and the cleanup at
+// exit to enable their tracing.
 func NewTracerProvider(service string) (trace.TracerProvider, func(context.Context), error) {
 	// tracing enable
 	tracingEnable := DefaultTracingEnable
@@ -74,12 +128,26 @@ func NewTracerProvider(service string) (trace.TracerProvider, func(context.Conte
 	if !tracingEnable {
 		return nil, func(context.Context) {}, errors.New("tracing disabled")
 	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if provider != nil {
+		return provider, func(context.Context) {}, nil
+	}
+
 	// tracer provider
+	exporter := DefaultTracingExporter
+	if envTracingExporter := os.Getenv("YOMO_TRACING_EXPORTER"); envTracingExporter != "" {
+		exporter = envTracingExporter
+	}
 	tracingEndpoint := DefaultTracingEndpoint
+	if exporter == ExporterOTLP {
+		tracingEndpoint = DefaultOTLPEndpoint
+	}
 	if envTracingEndpoint := os.Getenv("YOMO_TRACING_ENDPOINT"); envTracingEndpoint != "" {
 		tracingEndpoint = envTracingEndpoint
 	}
-	tp, err := tracerProvider(service, tracingEndpoint)
+	tp, err := tracerProvider(service, exporter, tracingEndpoint)
 	if err != nil {
 		return nil, func(context.Context) {}, err
 	}
@@ -89,7 +157,7 @@ func NewTracerProvider(service string) (trace.TracerProvider, func(context.Conte
 		ctx, cancel := context.WithTimeout(ctx, time.Second*5)
 		defer cancel()
 		if err := tp.Shutdown(ctx); err != nil {
-			log.Fatal(err)
+			logger.Fatal("[tracing] shutdown the tracer provider failed.", "err", err)
 		}
 	}
 	// Register our TracerProvider as the global so any imported
@@ -97,17 +165,81 @@ func NewTracerProvider(service string) (trace.TracerProvider, func(context.Conte
 	otel.SetTracerProvider(tp)
 	otel.SetTextMapPropagator(propagation.TraceContext{})
 	// otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	provider = tp
 
 	return tp, cleanup, nil
+}
+
+// frameCarrier carries the W3C trace context in the MetaFrame of DataFrame.
+type frameCarrier struct {
+	data *frame.DataFrame
+}
+
+// Get returns the value of the trace context field.
+func (c frameCarrier) Get(key string) string {
+	switch key {
+	case "traceparent":
+		return c.data.TraceParent()
+	case "tracestate":
+		return c.data.TraceState()
+	}
+	return ""
+}
 
+// Set stores the value of the trace context field.
+func (c frameCarrier) Set(key string, value string) {
+	switch key {
+	case "traceparent":
+		c.data.SetTraceParent(value)
+	case "tracestate":
+		c.data.SetTraceState(value)
+	}
+}
+
+// Keys lists the fields of the trace context.
+func (c frameCarrier) Keys() []string {
+	return []string{"traceparent", "tracestate"}
+}
+
+// NewSpanToFrame creates a new span which is the child of the span in ctx, the new span is written to the
+// MetaFrame of data, so the trace is propagated to the next hop.
+func NewSpanToFrame(ctx context.Context, data *frame.DataFrame, tracerName string, spanName string) trace.Span {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, spanName)
+	InjectToFrame(ctx, data)
+	return span
+}
+
+// NewSpanFromFrame creates a new span which is the child of the span carried in the MetaFrame of data, the
+// new span is written back to the frame. A new trace is started if the frame doesn't carry the trace context,
+// e.g. it's sent by a source without tracing.
+func NewSpanFromFrame(data *frame.DataFrame, tracerName string, spanName string) trace.Span {
+	ctx, span := StartSpanFromFrame(data, tracerName, spanName)
+	InjectToFrame(ctx, data)
+	return span
+}
+
+// StartSpanFromFrame creates a new span which is the child of the span carried in the MetaFrame of data, the
+// frame isn't modified, so the caller writes the span by InjectToFrame only if the span is recording.
+func StartSpanFromFrame(data *frame.DataFrame, tracerName string, spanName string) (context.Context, trace.Span) {
+	ctx := propagation.TraceContext{}.Extract(context.Background(), frameCarrier{data: data})
+	return otel.Tracer(tracerName).Start(ctx, spanName)
+}
+
+// InjectToFrame writes the span in ctx to the MetaFrame of data.
+func InjectToFrame(ctx context.Context, data *frame.DataFrame) {
+	propagation.TraceContext{}.Inject(ctx, frameCarrier{data: data})
 }
 
 // NewTraceSpan creates a new span of OpenTelemetry from parent tracing.
+//
+// Deprecated: the trace is propagated by the MetaFrame of DataFrame, use NewSpanFromFrame instead.
 func NewTraceSpan(otelTraceID string, otelSpanID string, tracerName string, spanName string) (trace.Span, error) {
 	return newTraceSpan(otel.GetTracerProvider(), otelTraceID, otelSpanID, tracerName, spanName, false)
 }
 
 // NewRemoteTraceSpan creates a new span of OpenTelemetry from remote parent tracing.
+//
+// Deprecated: the trace is propagated by the MetaFrame of DataFrame, use NewSpanFromFrame instead.
 func NewRemoteTraceSpan(otelTraceID string, otelSpanID string, tracerName string, spanName string) (trace.Span, error) {
 	return newTraceSpan(otel.GetTracerProvider(), otelTraceID, otelSpanID, tracerName, spanName, true)
 }
@@ -137,17 +269,29 @@ func newTraceSpan(tp trace.TracerProvider, otelTraceID string, otelSpanID string
 	return span, nil
 }
 
-// NewSpanFromData gets tje TraceID and SpanID from data and creates a new Span.
+// NewSpanFromData gets the TraceID and SpanID from the metadatas of the JSON data and creates a new Span.
+//
+// Deprecated: the trace is propagated by the MetaFrame of DataFrame, use NewSpanFromFrame instead.
 func NewSpanFromData(data string, tracerName string, spanName string) trace.Span {
+	var payload struct {
+		Metadatas []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"metadatas"`
+	}
+	if err := json.Unmarshal([]byte(data), &payload); err != nil {
+		return nil
+	}
+
 	// tracing
 	var traceID, spanID string
-	traceIDValue := gjson.Get(string(data), `metadatas.#(name=="TraceID").value`)
-	if traceIDValue.Exists() {
-		traceID = traceIDValue.String()
-	}
-	spanIDValue := gjson.Get(string(data), `metadatas.#(name=="SpanID").value`)
-	if spanIDValue.Exists() {
-		spanID = spanIDValue.String()
+	for _, m := range payload.Metadatas {
+		switch m.Name {
+		case "TraceID":
+			traceID = m.Value
+		case "SpanID":
+			spanID = m.Value
+		}
 	}
 
 	var span trace.Span
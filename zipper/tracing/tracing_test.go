@@ -0,0 +1,68 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/internal/frame"
+	"go.opentelemetry.io/otel"
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestFrameTraceContext(t *testing.T) {
+	otel.SetTracerProvider(tracesdk.NewTracerProvider())
+
+	data := frame.NewDataFrame("1234")
+	data.SetCarriage(0x10, []byte("yomo"))
+
+	// a new trace is started if the frame doesn't carry the trace context.
+	root := NewSpanFromFrame(data, "test", "untraced")
+	defer root.End()
+	assert.Contains(t, data.TraceParent(), root.SpanContext().TraceID().String())
+
+	source := NewSpanToFrame(context.Background(), data, "test", "source")
+	defer source.End()
+	assert.NotEmpty(t, data.TraceParent())
+
+	// the span of next hop is the child in the same trace.
+	traceParent := data.TraceParent()
+	span := NewSpanFromFrame(data, "test", "zipper")
+	defer span.End()
+	assert.Equal(t, source.SpanContext().TraceID(), span.SpanContext().TraceID())
+	assert.NotEqual(t, traceParent, data.TraceParent())
+	assert.Contains(t, data.TraceParent(), span.SpanContext().SpanID().String())
+}
+
+func TestStartSpanFromFrame(t *testing.T) {
+	otel.SetTracerProvider(trace.NewNoopTracerProvider())
+
+	// the frame isn't written if the span isn't recording.
+	data := frame.NewDataFrame("1234")
+	ctx, span := StartSpanFromFrame(data, "test", "disabled")
+	defer span.End()
+	assert.False(t, span.IsRecording())
+	assert.Empty(t, data.TraceParent())
+
+	otel.SetTracerProvider(tracesdk.NewTracerProvider())
+	ctx, span = StartSpanFromFrame(data, "test", "enabled")
+	defer span.End()
+	assert.True(t, span.IsRecording())
+	assert.Empty(t, data.TraceParent())
+
+	InjectToFrame(ctx, data)
+	assert.Contains(t, data.TraceParent(), span.SpanContext().SpanID().String())
+}
+
+func TestNewSpanFromData(t *testing.T) {
+	otel.SetTracerProvider(tracesdk.NewTracerProvider())
+
+	span := NewSpanFromData(`{"metadatas":[{"name":"TraceID","value":"4bf92f3577b34da6a3ce929d0e0e4736"},{"name":"SpanID","value":"00f067aa0ba902b7"}]}`, "test", "data")
+	assert.NotNil(t, span)
+	defer span.End()
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+
+	assert.Nil(t, NewSpanFromData(`{"noise":1}`, "test", "data"))
+	assert.Nil(t, NewSpanFromData("yomo", "test", "data"))
+}
@@ -0,0 +1,444 @@
+package zipper
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/yomorun/yomo/internal/frame"
+	"github.com/yomorun/yomo/internal/wasm"
+	"github.com/yomorun/yomo/logger"
+)
+
+const (
+	// DefaultWasmMaxMemory is the default max memory in bytes of each instance of WASM function.
+	DefaultWasmMaxMemory = 16 << 20
+	// DefaultWasmTimeout is the default timeout of the handler of WASM function for each frame.
+	DefaultWasmTimeout = time.Second
+	// maxWasmModule is the max size in bytes of the uploaded module.
+	maxWasmModule = 64 << 20
+)
+
+// the ABI of WASM stream functions, the module exports its memory and the handler, and imports the functions of
+// YoMo from "env".
+const (
+	// wasmInit is the optional export which is called once when the module is instantiated, e.g. to observe the tags.
+	wasmInit = "yomo_init"
+	// wasmHandler is the export which is called with the tag and the length of data of each frame.
+	wasmHandler = "yomo_handler"
+	wasmEnv     = "env"
+	wasi        = "wasi_snapshot_preview1"
+	// wasiENOSYS is the errno of the WASI functions which are not implemented.
+	wasiENOSYS = 52
+	wasiEFAULT = 21
+)
+
+// WasmConfig runs the stream function as the WebAssembly module in the process of YoMo-Zipper, so the simple
+// transforms don't need the network hop, and they can be written in any language which compiles to WebAssembly.
+//
+// The module exports its "memory" and "yomo_handler(tag: i32, length: i32)", which is called for each frame, and the
+// optional "yomo_init()", which is called once when it's instantiated. It imports the functions from "env":
+//
+//	yomo_observe_datatag(tag: i32)                      // processes only the observed tags, the others pass through.
+//	yomo_load_input(ptr: i32)                           // copies the data of frame to the memory at ptr.
+//	yomo_dump_output(tag: i32, ptr: i32, length: i32)   // the output of frame, the frame is dropped without it.
+//
+// The WASI functions of printing, clocks and random are provided, the output is logged.
+type WasmConfig struct {
+	// File is the path of module, it can be replaced by the admin API "PUT /functions/{name}/wasm" at runtime. The
+	// frames are dropped until the module is uploaded if it's empty.
+	File string `yaml:"file,omitempty"`
+	// MaxMemory is the max memory in bytes of each instance, the default is 16MB.
+	MaxMemory int `yaml:"max_memory,omitempty"`
+	// Timeout is the timeout of the handler for each frame, the handler is interrupted when it's timed out, e.g. by
+	// an endless loop. The default is 1s.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// Instances is the count of instances which process the frames concurrently, the order of frames is kept if
+	// it's 1. The default is 1.
+	Instances int `yaml:"instances,omitempty"`
+}
+
+// validate returns the problems of the config of function in the message of Validate.
+func (c *WasmConfig) validate(name string) string {
+	if c.MaxMemory < 0 || c.Timeout < 0 || c.Instances < 0 {
+		return "The max memory, timeout and instances of WASM function " + name + " must not be negative. "
+	}
+	return ""
+}
+
+// wasmFunc runs the WASM module of a stream function in the pool of instances. A trapped instance is closed,
+// since its memory may be inconsistent, and the instances of the replaced module are closed when they're
+// released.
+type wasmFunc struct {
+	name string
+	conf WasmConfig
+
+	mutex      sync.RWMutex
+	module     *wasm.Module
+	generation int
+	// pool holds the idle instances, it's nil for the slot whose instance is not created.
+	pool chan *wasmInstance
+}
+
+// wasmInstance is an instance of module with the state of the frame which is processed.
+type wasmInstance struct {
+	*wasm.Instance
+	generation int
+	observed   map[byte]bool
+
+	input     []byte
+	output    []byte
+	outputTag byte
+}
+
+// newWasmFunc creates the WASM function, the module is loaded from the file.
+func newWasmFunc(name string, conf WasmConfig) (*wasmFunc, error) {
+	if conf.MaxMemory <= 0 {
+		conf.MaxMemory = DefaultWasmMaxMemory
+	}
+	if conf.Timeout <= 0 {
+		conf.Timeout = DefaultWasmTimeout
+	}
+	if conf.Instances <= 0 {
+		conf.Instances = 1
+	}
+	fn := &wasmFunc{name: name, conf: conf, pool: make(chan *wasmInstance, conf.Instances)}
+	for i := 0; i < conf.Instances; i++ {
+		fn.pool <- nil
+	}
+	if conf.File == "" {
+		return fn, nil
+	}
+	buf, err := ioutil.ReadFile(conf.File)
+	if err != nil {
+		return nil, err
+	}
+	if err := fn.load(buf); err != nil {
+		return nil, fmt.Errorf("the WASM function %s: %w", name, err)
+	}
+	return fn, nil
+}
+
+// newWasmFuncs creates the WASM functions of the workflow.
+func newWasmFuncs(apps []App) (map[string]*wasmFunc, error) {
+	funcs := make(map[string]*wasmFunc)
+	for _, app := range apps {
+		if app.Wasm == nil {
+			continue
+		}
+		fn, err := newWasmFunc(app.Name, *app.Wasm)
+		if err != nil {
+			return nil, err
+		}
+		funcs[app.Name] = fn
+	}
+	return funcs, nil
+}
+
+// load replaces the module, it's instantiated once to be checked, the current module is kept if it fails.
+func (fn *wasmFunc) load(buf []byte) error {
+	module, err := wasm.Decode(buf)
+	if err != nil {
+		return err
+	}
+	if t, ok := module.ExportedFunc(wasmHandler); !ok || len(t.Params) != 2 || t.Params[0] != wasm.I32 || t.Params[1] != wasm.I32 || len(t.Results) != 0 {
+		return errors.New("the module must export the function yomo_handler(i32, i32)")
+	}
+	inst, err := fn.instantiate(module, 0)
+	if err != nil {
+		module.Close()
+		return err
+	}
+	inst.Close()
+
+	fn.mutex.Lock()
+	replaced := fn.module
+	fn.module = module
+	fn.generation++
+	fn.mutex.Unlock()
+	// the instances of the replaced module keep working until they're released.
+	if replaced != nil {
+		replaced.Close()
+	}
+	return nil
+}
+
+// loaded indicates if the module is loaded.
+func (fn *wasmFunc) loaded() bool {
+	fn.mutex.RLock()
+	defer fn.mutex.RUnlock()
+	return fn.module != nil
+}
+
+// instantiate creates an instance of the module with the imports of YoMo and WASI.
+func (fn *wasmFunc) instantiate(module *wasm.Module, generation int) (*wasmInstance, error) {
+	inst := &wasmInstance{generation: generation, observed: make(map[byte]bool)}
+	imports := wasm.Imports{wasmEnv: inst.yomoImports(), wasi: fn.wasiImports()}
+	// the other WASI functions are stubbed, so the modules of the common toolchains can be instantiated.
+	for _, imp := range module.Imports() {
+		if _, ok := imports[imp.Module][imp.Name]; !ok && imp.Module == wasi {
+			imports[wasi][imp.Name] = wasiStub(imp.Type)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), fn.conf.Timeout)
+	defer cancel()
+	var err error
+	inst.Instance, err = wasm.Instantiate(ctx, module, imports, fn.config())
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := module.ExportedFunc(wasmInit); ok {
+		if _, err := inst.Call(ctx, wasmInit); err != nil {
+			inst.Close()
+			return nil, fmt.Errorf("yomo_init: %w", err)
+		}
+	}
+	return inst, nil
+}
+
+func (fn *wasmFunc) config() wasm.Config {
+	return wasm.Config{
+		MaxPages: uint32((fn.conf.MaxMemory + 65535) / 65536),
+	}
+}
+
+// acquire waits for a slot of the pool, the instance of slot is nil if it's not created or it's discarded. The slot
+// must be released by release.
+func (fn *wasmFunc) acquire(ctx context.Context) (*wasmInstance, bool) {
+	select {
+	case <-ctx.Done():
+		return nil, false
+	case inst := <-fn.pool:
+		return inst, true
+	}
+}
+
+// release returns the instance to the pool, it's nil if the instance is discarded.
+func (fn *wasmFunc) release(inst *wasmInstance) {
+	fn.pool <- inst
+}
+
+// process runs the handler with the frame in the instance, it returns the new instance of the slot, and the output
+// of handler, which is nil if the frame is dropped. The frames of the tags which are not observed pass through.
+func (fn *wasmFunc) process(ctx context.Context, inst *wasmInstance, tag byte, data []byte) (*wasmInstance, []byte, byte, error) {
+	fn.mutex.RLock()
+	module, generation := fn.module, fn.generation
+	fn.mutex.RUnlock()
+	if module == nil {
+		return inst, nil, tag, errors.New("the module is not uploaded")
+	}
+	if inst == nil || inst.generation != generation {
+		if inst != nil {
+			inst.Close()
+		}
+		var err error
+		if inst, err = fn.instantiate(module, generation); err != nil {
+			return nil, nil, tag, err
+		}
+	}
+	if len(inst.observed) > 0 && !inst.observed[tag] {
+		return inst, data, tag, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, fn.conf.Timeout)
+	defer cancel()
+	inst.input, inst.output, inst.outputTag = data, nil, tag
+	defer func() {
+		inst.input = nil
+	}()
+	if _, err := inst.Call(ctx, wasmHandler, uint64(tag), uint64(len(data))); err != nil {
+		// the trapped instance is closed.
+		inst.Close()
+		return nil, nil, tag, err
+	}
+	return inst, inst.output, inst.outputTag, nil
+}
+
+// yomoImports are the functions of YoMo ABI, they're bound to the instance.
+func (inst *wasmInstance) yomoImports() map[string]wasm.HostFunc {
+	return map[string]wasm.HostFunc{
+		"yomo_observe_datatag": {
+			Type: wasm.FuncType{Params: []wasm.ValueType{wasm.I32}},
+			Fn: func(_ *wasm.Instance, args []uint64) ([]uint64, error) {
+				inst.observed[byte(args[0])] = true
+				return nil, nil
+			},
+		},
+		"yomo_load_input": {
+			Type: wasm.FuncType{Params: []wasm.ValueType{wasm.I32}},
+			Fn: func(m *wasm.Instance, args []uint64) ([]uint64, error) {
+				if !m.Write(uint32(args[0]), inst.input) {
+					return nil, fmt.Errorf("yomo_load_input: %w", wasm.ErrOutOfBounds)
+				}
+				return nil, nil
+			},
+		},
+		"yomo_dump_output": {
+			Type: wasm.FuncType{Params: []wasm.ValueType{wasm.I32, wasm.I32, wasm.I32}},
+			Fn: func(m *wasm.Instance, args []uint64) ([]uint64, error) {
+				buf, ok := m.Read(uint32(args[1]), uint32(args[2]))
+				if !ok {
+					return nil, fmt.Errorf("yomo_dump_output: %w", wasm.ErrOutOfBounds)
+				}
+				inst.output, inst.outputTag = buf, byte(args[0])
+				return nil, nil
+			},
+		},
+	}
+}
+
+// wasiImports are the WASI functions of printing, clocks and random.
+func (fn *wasmFunc) wasiImports() map[string]wasm.HostFunc {
+	i32 := wasm.I32
+	return map[string]wasm.HostFunc{
+		"fd_write": {
+			Type: wasm.FuncType{Params: []wasm.ValueType{i32, i32, i32, i32}, Results: []wasm.ValueType{i32}},
+			Fn: func(m *wasm.Instance, args []uint64) ([]uint64, error) {
+				fd, iovs, n := uint32(args[0]), uint32(args[1]), uint32(args[2])
+				var out []byte
+				for i := uint32(0); i < n; i++ {
+					iov, ok := m.Read(iovs+i*8, 8)
+					if !ok {
+						return []uint64{wasiEFAULT}, nil
+					}
+					buf, ok := m.Read(binary.LittleEndian.Uint32(iov), binary.LittleEndian.Uint32(iov[4:]))
+					if !ok {
+						return []uint64{wasiEFAULT}, nil
+					}
+					out = append(out, buf...)
+				}
+				written := make([]byte, 4)
+				binary.LittleEndian.PutUint32(written, uint32(len(out)))
+				if !m.Write(uint32(args[3]), written) {
+					return []uint64{wasiEFAULT}, nil
+				}
+				logger.Info("[zipper] the WASM function prints.", "stream-fn", fn.name, "fd", fd, "output", string(out))
+				return []uint64{0}, nil
+			},
+		},
+		"proc_exit": {
+			Type: wasm.FuncType{Params: []wasm.ValueType{i32}},
+			Fn: func(_ *wasm.Instance, args []uint64) ([]uint64, error) {
+				return nil, fmt.Errorf("the WASM function exits with code %d", uint32(args[0]))
+			},
+		},
+		"random_get": {
+			Type: wasm.FuncType{Params: []wasm.ValueType{i32, i32}, Results: []wasm.ValueType{i32}},
+			Fn: func(m *wasm.Instance, args []uint64) ([]uint64, error) {
+				buf := make([]byte, uint32(args[1]))
+				rand.Read(buf)
+				if !m.Write(uint32(args[0]), buf) {
+					return []uint64{wasiEFAULT}, nil
+				}
+				return []uint64{0}, nil
+			},
+		},
+		"clock_time_get": {
+			Type: wasm.FuncType{Params: []wasm.ValueType{i32, wasm.I64, i32}, Results: []wasm.ValueType{i32}},
+			Fn: func(m *wasm.Instance, args []uint64) ([]uint64, error) {
+				buf := make([]byte, 8)
+				binary.LittleEndian.PutUint64(buf, uint64(time.Now().UnixNano()))
+				if !m.Write(uint32(args[2]), buf) {
+					return []uint64{wasiEFAULT}, nil
+				}
+				return []uint64{0}, nil
+			},
+		},
+	}
+}
+
+// wasiStub is the WASI function which is not implemented, it returns ENOSYS.
+func wasiStub(t wasm.FuncType) wasm.HostFunc {
+	return wasm.HostFunc{
+		Type: t,
+		Fn: func(_ *wasm.Instance, _ []uint64) ([]uint64, error) {
+			results := make([]uint64, len(t.Results))
+			if len(results) == 1 && t.Results[0] == wasm.I32 {
+				results[0] = wasiENOSYS
+			}
+			return results, nil
+		},
+	}
+}
+
+// pipeWasm runs the WASM function with the frames of upstream in its instances, the frames which are not
+// subscribed, sampled or readable pass through it. The order of frames is kept only if it has one instance.
+func pipeWasm(ctx context.Context, upstream chan *frame.DataFrame, fn *wasmFunc, maxSize int) chan *frame.DataFrame {
+	next := make(chan *frame.DataFrame, bufferSize)
+
+	go func() {
+		var wg sync.WaitGroup
+		defer func() {
+			wg.Wait()
+			close(next)
+		}()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case item, ok := <-upstream:
+				if !ok {
+					return
+				}
+
+				// the encrypted frames can't be read in YoMo-Zipper, they pass through the function.
+				if !subscribedTo(fn.name, item) || !sampled(fn.name) || item.KeyID() != "" {
+					next <- item
+					continue
+				}
+
+				inst, ok := fn.acquire(ctx)
+				if !ok {
+					return
+				}
+				wg.Add(1)
+				go func(inst *wasmInstance, data *frame.DataFrame) {
+					defer wg.Done()
+					inst, data, ok := runWasm(ctx, fn, inst, data, maxSize)
+					if ok {
+						select {
+						case next <- data:
+						case <-ctx.Done():
+						}
+					}
+					// the instance is released after the frame is sent, so the order is kept by one instance.
+					fn.release(inst)
+				}(inst, item)
+			}
+		}
+	}()
+
+	return next
+}
+
+// runWasm runs the WASM function with the frame, returns false if the data was dropped.
+func runWasm(ctx context.Context, fn *wasmFunc, inst *wasmInstance, data *frame.DataFrame, maxSize int) (*wasmInstance, *frame.DataFrame, bool) {
+	if err := materialize(data, maxSize); err != nil {
+		logger.Error("[zipper] the streamed carriage can't be read by the WASM function.", "stream-fn", fn.name, "TransactionID", data.TransactionID(), "err", err)
+		return inst, nil, false
+	}
+
+	pipelineTimelines.record(StepRouted, fn.name, data, "")
+	inst, buf, tag, err := fn.process(ctx, inst, data.GetDataTagID(), data.GetCarriage())
+	if err != nil {
+		logger.Error("[zipper] the WASM function failed.", "stream-fn", fn.name, "TransactionID", data.TransactionID(), "err", err)
+		pipelineTimelines.record(StepFailed, fn.name, data, err.Error())
+		return inst, nil, false
+	}
+	if buf == nil {
+		logger.Debug("[zipper] the WASM function doesn't dump the output.", "stream-fn", fn.name, "TransactionID", data.TransactionID())
+		pipelineTimelines.record(StepDropped, fn.name, data, "no output")
+		return inst, nil, false
+	}
+
+	data.SetCarriage(tag, buf)
+	pipelineTimelines.record(StepResponded, fn.name, data, "")
+	return inst, data, true
+}
@@ -0,0 +1,180 @@
+package zipper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/internal/frame"
+	"github.com/yomorun/yomo/internal/wasm"
+	"github.com/yomorun/yomo/internal/wasm/wasmtest"
+)
+
+// upperModule assembles the WASM function which observes the tag 0x10 and upper-cases the data. It drops the data
+// of 4 bytes, traps with the data of 5 bytes, and spins with the data of 6 bytes.
+func upperModule() []byte {
+	i32 := wasm.I32
+	code := wasmtest.Code
+	return wasmtest.Module{
+		Imports: []wasmtest.Import{
+			{Module: "env", Name: "yomo_observe_datatag", Type: wasm.FuncType{Params: []wasm.ValueType{i32}}},
+			{Module: "env", Name: "yomo_load_input", Type: wasm.FuncType{Params: []wasm.ValueType{i32}}},
+			{Module: "env", Name: "yomo_dump_output", Type: wasm.FuncType{Params: []wasm.ValueType{i32, i32, i32}}},
+			{Module: "wasi_snapshot_preview1", Name: "fd_close", Type: wasm.FuncType{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}},
+		},
+		Memory: 1,
+		Funcs: []wasmtest.Func{
+			{
+				Export: "yomo_init",
+				Code:   code(wasmtest.I32(0x10), 0x10, 0),
+			},
+			{
+				Type:   wasm.FuncType{Params: []wasm.ValueType{i32, i32}},
+				Locals: []wasm.ValueType{i32},
+				Export: "yomo_handler",
+				Code: code(
+					0x20, 1, wasmtest.I32(4), 0x46, 0x04, 0x40, 0x0F, 0x0B, // return if length == 4
+					0x20, 1, wasmtest.I32(5), 0x46, 0x04, 0x40, 0x00, 0x0B, // unreachable if length == 5
+					0x20, 1, wasmtest.I32(6), 0x46, 0x04, 0x40, 0x03, 0x40, 0x0C, 0, 0x0B, 0x0B, // loop if length == 6
+					wasmtest.I32(1024), 0x10, 1, // yomo_load_input(1024)
+					0x02, 0x40, 0x03, 0x40,
+					0x20, 2, 0x20, 1, 0x4F, 0x0D, 1, // break if i >= length
+					0x20, 2, 0x20, 2, 0x2D, 0, 0x80, 0x08, wasmtest.I32(32), 0x6B, 0x3A, 0, 0x80, 0x08, // memory[1024+i] -= 32
+					0x20, 2, wasmtest.I32(1), 0x6A, 0x21, 2, // i++
+					0x0C, 0,
+					0x0B, 0x0B,
+					0x20, 0, wasmtest.I32(1024), 0x20, 1, 0x10, 2, // yomo_dump_output(tag, 1024, length)
+				),
+			},
+		},
+	}.Bytes()
+}
+
+func TestPipeWasm(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "upper.wasm")
+	assert.NoError(t, ioutil.WriteFile(file, upperModule(), 0644))
+	fn, err := newWasmFunc("wasm-1", WasmConfig{File: file})
+	assert.NoError(t, err)
+	assert.True(t, fn.loaded())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	upstream := make(chan *frame.DataFrame, 8)
+	for _, s := range []string{"abc", "raw", "drop", "crash", "xyz"} {
+		f := frame.NewDataFrame(s)
+		if s == "raw" {
+			// the tag which is not observed passes through.
+			f.SetCarriage(0x11, []byte(s))
+		} else {
+			f.SetCarriage(0x10, []byte(s))
+		}
+		upstream <- f
+	}
+	close(upstream)
+
+	results := make([]string, 0)
+	for f := range pipeWasm(ctx, upstream, fn, 0) {
+		results = append(results, string(f.GetCarriage()))
+	}
+	// the trapped instance is replaced, and the order is kept by one instance.
+	assert.Equal(t, []string{"ABC", "raw", "XYZ"}, results)
+}
+
+func TestWasmLimits(t *testing.T) {
+	fn, err := newWasmFunc("wasm-1", WasmConfig{Timeout: 100 * time.Millisecond})
+	assert.NoError(t, err)
+	assert.False(t, fn.loaded())
+	_, _, _, err = fn.process(context.Background(), nil, 0x10, []byte("abc"))
+	assert.EqualError(t, err, "the module is not uploaded")
+
+	assert.NoError(t, fn.load(upperModule()))
+	inst, out, tag, err := fn.process(context.Background(), nil, 0x10, []byte("abc"))
+	assert.NoError(t, err)
+	assert.Equal(t, "ABC", string(out))
+	assert.Equal(t, byte(0x10), tag)
+
+	_, _, _, err = fn.process(context.Background(), inst, 0x10, []byte("spin!!"))
+	assert.ErrorIs(t, err, wasm.ErrInterrupted)
+	_, _, _, err = fn.process(context.Background(), nil, 0x10, bytes.Repeat([]byte("a"), 64<<10))
+	assert.ErrorIs(t, err, wasm.ErrOutOfBounds)
+
+	// the module which can't be instantiated in the memory limit is rejected.
+	fn, err = newWasmFunc("wasm-1", WasmConfig{MaxMemory: 1})
+	assert.NoError(t, err)
+	handler := wasmtest.Func{Type: wasm.FuncType{Params: []wasm.ValueType{wasm.I32, wasm.I32}}, Export: "yomo_handler"}
+	err = fn.load(wasmtest.Module{Memory: 2, Funcs: []wasmtest.Func{handler}}.Bytes())
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "over limit of 1 pages")
+	}
+	assert.EqualError(t, fn.load(wasmtest.Module{Memory: 1}.Bytes()), "the module must export the function yomo_handler(i32, i32)")
+	assert.Error(t, fn.load(upperModule()[:8]))
+	assert.False(t, fn.loaded())
+}
+
+func TestUploadWasm(t *testing.T) {
+	conf := &WorkflowConfig{Name: "zipper", AdminToken: "secret", Workflow: Workflow{Functions: []App{{Name: "wasm-1", Wasm: &WasmConfig{}}}}}
+	h := newServerHandler(conf, "")
+	var err error
+	h.wasmFuncs, err = newWasmFuncs(conf.Functions)
+	assert.NoError(t, err)
+	server := httptest.NewServer(newAdminMux(h))
+	defer server.Close()
+
+	upload := func(name string, body []byte) (int, map[string]string) {
+		req, _ := http.NewRequest(http.MethodPut, server.URL+"/functions/"+name+"/wasm", bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		res, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		var msg map[string]string
+		json.NewDecoder(res.Body).Decode(&msg)
+		return res.StatusCode, msg
+	}
+
+	stages := func() []StageInfo {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/stages", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		res, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		var stages []StageInfo
+		assert.NoError(t, json.NewDecoder(res.Body).Decode(&stages))
+		return stages
+	}
+	assert.True(t, stages()[0].Wasm)
+	assert.Equal(t, 0, stages()[0].Instances)
+
+	status, msg := upload("wasm-1", []byte("not wasm"))
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, msg["error"], "wasm: invalid magic number")
+	status, _ = upload("none", upperModule())
+	assert.Equal(t, http.StatusNotFound, status)
+
+	status, msg = upload("wasm-1", upperModule())
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "loaded", msg["status"])
+	assert.Equal(t, 1, stages()[0].Instances)
+
+	_, out, _, err := h.wasmFuncs["wasm-1"].process(context.Background(), nil, 0x10, []byte("abc"))
+	assert.NoError(t, err)
+	assert.Equal(t, "ABC", string(out))
+}
+
+func TestValidateWasm(t *testing.T) {
+	conf := &WorkflowConfig{Name: "zipper", Host: "localhost", Port: 9000, Workflow: Workflow{Functions: []App{
+		{Name: "fn-1", Wasm: &WasmConfig{Instances: -1}},
+		{Name: "fn-2", Wasm: &WasmConfig{}, Invoke: &InvokeConfig{URL: "http://fn"}},
+	}}}
+	err := Validate(conf)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "The max memory, timeout and instances of WASM function fn-1 must not be negative.")
+		assert.Contains(t, err.Error(), "The function fn-2 can't be both invoked and run as WASM.")
+	}
+}
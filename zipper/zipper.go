@@ -2,9 +2,18 @@ package zipper
 
 import (
 	"context"
-	"log"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/yomorun/yomo/core/quic"
+	"github.com/yomorun/yomo/internal/core"
+	"github.com/yomorun/yomo/logger"
 	"github.com/yomorun/yomo/zipper/tracing"
 )
 
@@ -19,6 +28,19 @@ type Zipper interface {
 	// CurrentConnections gets the current connections in zipper.
 	CurrentConnections() []Conn
 
+	// DroppedFrames gets the count of frames dropped by load shedding, grouped by data tag.
+	DroppedFrames() map[byte]uint64
+
+	// Features gets the flags of experimental features, which can be changed at runtime.
+	Features() *Features
+
+	// ReloadCertificates reloads the certificate of YoMo-Zipper, the established sessions are not dropped.
+	ReloadCertificates() error
+
+	// ReloadWorkflow switches the running YoMo-Zipper to the workflow of config, e.g. the order and the tags of
+	// stages, the current workflow is kept if the config is invalid.
+	ReloadWorkflow(conf *WorkflowConfig) error
+
 	// Close the server. All active sessions will be closed.
 	Close() error
 }
@@ -29,14 +51,44 @@ func New(conf *WorkflowConfig, opts ...Option) Zipper {
 	return &zipperImpl{
 		conf:        conf,
 		meshConfURL: options.meshConfURL,
+		onDropped:   options.onDropped,
+		localFuncs:  options.localFuncs,
+		handlers:    options.handlers,
+		zeroRTT:     options.zeroRTT,
+		report:      options.report,
+		tcp:         options.tcp,
+		auth:        options.auth,
+		authz:       options.authz,
+		features:    NewFeatures(conf.Features),
 	}
 }
 
 type zipperImpl struct {
-	conf        *WorkflowConfig
-	meshConfURL string
-	quicServer  quic.Server
-	handler     *quicHandler
+	conf         *WorkflowConfig
+	meshConfURL  string
+	onDropped    func(tag byte, total uint64)
+	localFuncs   map[string]LocalStreamFunc
+	handlers     map[string]LocalHandler
+	zeroRTT      bool
+	quicServer   quic.Server
+	handler      *quicHandler
+	stopProbes   context.CancelFunc
+	adminServer  *http.Server
+	metrics      *http.Server
+	health       *http.Server
+	webTransport quic.Server
+	features     *Features
+	report       io.Writer
+	tcp          bool
+	auth         Authenticator
+	authz        Authorizer
+	startedAt    time.Time
+	certs        *quic.CertReloader
+	stopCerts    context.CancelFunc
+	stopReload   context.CancelFunc
+	stopSlow     context.CancelFunc
+	stopJournal  context.CancelFunc
+	stopKeys     context.CancelFunc
 }
 
 // Serve a YoMo Zipper.
@@ -44,13 +96,148 @@ func (r *zipperImpl) Serve(endpoint string) error {
 	// tracing
 	_, _, err := tracing.NewTracerProvider("zipper")
 	if err != nil {
-		log.Println(err)
+		logger.Error("[zipper] init the tracer provider failed.", "err", err)
+	}
+
+	// log sampling
+	if r.conf.LogSampling != nil {
+		logger.SetSampling(*r.conf.LogSampling)
 	}
 
 	handler := newServerHandler(r.conf, r.meshConfURL)
-	server := quic.NewServer(handler)
+	handler.shedder.onDropped = r.onDropped
+	handler.localFuncs = r.localFuncs
+	handler.localHandlers = r.handlers
+	handler.features = r.features
+
+	// serverless functions
+	handler.invokers, err = newInvokers(r.conf.Functions)
+	if err != nil {
+		return err
+	}
+	handler.wasmFuncs, err = newWasmFuncs(r.conf.Functions)
+	if err != nil {
+		return err
+	}
+
+	// token authentication
+	handler.authenticator = r.auth
+	if handler.authenticator == nil && r.conf.Auth != nil {
+		handler.authenticator, err = newAuthenticator(r.conf.Auth)
+		if err != nil {
+			return err
+		}
+		r.stopKeys = watchKeys(handler.authenticator, r.conf.Auth)
+	}
+
+	// authorization
+	handler.authorizer = r.authz
+	if handler.authorizer == nil && r.conf.Authz != nil {
+		handler.authorizer, err = newAuthorizer(r.conf.Authz)
+		if err != nil {
+			return err
+		}
+	}
+
+	// audit log
+	if r.conf.Audit != nil {
+		handler.auditor, err = newAuditor(r.conf.Audit)
+		if err != nil {
+			return err
+		}
+	}
+
+	// transaction timelines
+	if r.conf.Timeline != nil {
+		pipelineTimelines.enable(r.conf.Timeline)
+	}
+
+	// durable buffering in JetStream
+	if r.conf.JetStream != nil {
+		handler.journal = newJournal(r.conf.JetStream, r.conf.Name, handler.shedder)
+		ctx, cancel := context.WithCancel(context.Background())
+		r.stopJournal = cancel
+		go handler.journal.run(ctx)
+	}
+
+	// payload capture
+	if r.conf.Capture != nil {
+		handler.capturer, err = newCapturer(r.conf.Capture)
+		if err != nil {
+			return err
+		}
+	}
+
+	opts, err := r.quicOptions(handler, endpoint)
+	if err != nil {
+		return err
+	}
+	server := quic.NewServer(handler, opts...)
 	r.quicServer = server
 	r.handler = handler
+	r.startedAt = time.Now()
+
+	// admin API
+	if r.conf.Admin != "" {
+		r.adminServer = serveAdmin(r.conf.Admin, handler)
+	}
+
+	// Prometheus metrics
+	if r.conf.Metrics != "" {
+		r.metrics = serveMetrics(r.conf.Metrics, handler)
+	}
+
+	// health checks
+	if r.conf.Health != "" {
+		r.health = serveHealth(r.conf.Health, handler)
+	}
+
+	// WebTransport endpoint for browsers, it shares the handler with the QUIC server.
+	if r.conf.WebTransport != "" {
+		wtOpts := append([]quic.Option{
+			quic.WithWebTransportPath(r.conf.WebTransportPath),
+			quic.WithWebTransportOrigins(r.conf.WebTransportOrigins...),
+		}, opts...)
+		r.webTransport = quic.NewWebTransportServer(handler, wtOpts...)
+		go func() {
+			if err := r.webTransport.ListenAndServe(context.Background(), r.conf.WebTransport); err != nil {
+				logger.Error("[zipper] WebTransport endpoint stopped", "err", err)
+			}
+		}()
+	}
+
+	// synthetic SLIs
+	if r.conf.SLI != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		r.stopProbes = cancel
+		handler.prober = newProber(r.conf.SLI, r.conf.Name)
+		handler.prober.tlsConfig = probeTLSConfig(handler.clientTLS)
+		go handler.prober.run(ctx, endpoint)
+	}
+
+	// slow consumers
+	apps := r.conf.allFunctions()
+	stages := make([]string, 0, len(apps))
+	for _, app := range apps {
+		stages = append(stages, app.Name)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r.stopSlow = cancel
+	go handler.slow.run(ctx, handler.queues, stages)
+
+	// certificate rotation
+	if r.certs != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		r.stopCerts = cancel
+		go r.watchCertificates(ctx)
+	}
+
+	// workflow reload
+	if r.conf.Reload != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		r.stopReload = cancel
+		go r.watchConfig(ctx)
+	}
 
 	// return server.ListenAndServe(context.Background(), endpoint)
 	return r.quicServer.ListenAndServe(context.Background(), endpoint)
@@ -58,14 +245,68 @@ func (r *zipperImpl) Serve(endpoint string) error {
 
 // ServeWithHandler serves a YoMo Zipper with handler.
 func (r *zipperImpl) ServeWithHandler(endpoint string, handler quic.ServerHandler) error {
-	server := quic.NewServer(handler)
+	h, _ := handler.(*quicHandler)
+	if h != nil {
+		h.features = r.features
+	}
+	opts, err := r.quicOptions(h, endpoint)
+	if err != nil {
+		return err
+	}
+
+	server := quic.NewServer(handler, opts...)
 	r.quicServer = server
+	r.handler = h
+	r.startedAt = time.Now()
+
+	return r.quicServer.ListenAndServe(context.Background(), endpoint)
+}
+
+// quicOptions returns the options of QUIC server by config, the handler is nil if it's not the default handler.
+func (r *zipperImpl) quicOptions(handler *quicHandler, endpoint string) ([]quic.Option, error) {
+	opts := make([]quic.Option, 0)
+	if r.zeroRTT {
+		opts = append(opts, quic.With0RTT())
+	}
+	if r.tcp {
+		opts = append(opts, quic.WithTCPFallback())
+	}
+	if r.conf.Qlog != "" {
+		opts = append(opts, quic.WithQlog(r.conf.Qlog))
+	}
+	if len(r.conf.Listen) > 0 {
+		opts = append(opts, quic.WithListenAddrs(r.conf.Listen...))
+	}
+	if r.conf.FlowControl != (FlowControl{}) {
+		opts = append(opts, quic.WithFlowControl(quic.FlowControl(r.conf.FlowControl)))
+	}
 
-	if h, ok := handler.(*quicHandler); ok {
-		r.handler = h
+	if r.conf.TLS == nil {
+		return opts, nil
 	}
 
-	return r.quicServer.ListenAndServe(context.Background(), endpoint)
+	serverTLS, err := quic.LoadServerTLSConfig(r.conf.TLS.CertFile, r.conf.TLS.KeyFile, r.conf.TLS.CAFile)
+	if err != nil {
+		return nil, err
+	}
+	r.certs, err = quic.NewCertReloader(r.conf.TLS.CertFile, r.conf.TLS.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	serverTLS = r.certs.TLSConfig(serverTLS)
+
+	if handler != nil {
+		handler.certs = r.certs
+		// the probes and edge-mesh senders connect to YoMo-Zippers with the same certificate, the server name
+		// is empty so it's set to the host of each dial target.
+		clientTLS, err := quic.LoadClientTLSConfig(r.conf.TLS.CertFile, r.conf.TLS.KeyFile, r.conf.TLS.CAFile, "")
+		if err != nil {
+			return nil, err
+		}
+		handler.clientTLS = r.certs.ClientTLSConfig(clientTLS)
+	}
+
+	return append(opts, quic.WithTLSConfig(serverTLS)), nil
 }
 
 // CurrentConnections gets the current connections in zipper.
@@ -77,10 +318,133 @@ func (r *zipperImpl) CurrentConnections() []Conn {
 	return r.handler.currentConnections()
 }
 
+// DroppedFrames gets the count of frames dropped by load shedding, grouped by data tag.
+func (r *zipperImpl) DroppedFrames() map[byte]uint64 {
+	if r.handler == nil {
+		return make(map[byte]uint64)
+	}
+
+	return r.handler.shedder.dropped()
+}
+
+// Features gets the flags of experimental features, which can be changed at runtime.
+func (r *zipperImpl) Features() *Features {
+	return r.features
+}
+
+// ReloadCertificates reloads the certificate of YoMo-Zipper, the established sessions are not dropped.
+func (r *zipperImpl) ReloadCertificates() error {
+	if r.certs == nil {
+		return errors.New("[zipper] the TLS is not configured")
+	}
+	return r.certs.Reload()
+}
+
+// watchCertificates reloads the certificate when the files are modified if the watch is enabled, and on SIGHUP
+// if it's opted in.
+func (r *zipperImpl) watchCertificates(ctx context.Context) {
+	if interval := r.conf.TLS.WatchInterval; interval > 0 {
+		go r.certs.Watch(ctx, interval)
+	}
+	if !r.conf.TLS.ReloadOnSIGHUP {
+		return
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP)
+	defer signal.Stop(sig)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sig:
+			if err := r.certs.Reload(); err != nil {
+				logger.Error("[zipper] reload the certificate failed.", "err", err)
+				continue
+			}
+			logger.Printf("✅ The certificate %s is reloaded by SIGHUP", r.conf.TLS.CertFile)
+		}
+	}
+}
+
 // Close the server. All active sessions will be closed.
 func (r *zipperImpl) Close() error {
-	if r.quicServer != nil {
-		return r.quicServer.Close()
+	if r.stopProbes != nil {
+		r.stopProbes()
+	}
+	if r.stopCerts != nil {
+		r.stopCerts()
+	}
+	if r.stopReload != nil {
+		r.stopReload()
+	}
+	if r.stopSlow != nil {
+		r.stopSlow()
+	}
+	if r.stopKeys != nil {
+		r.stopKeys()
+	}
+	if r.stopJournal != nil {
+		r.stopJournal()
+		r.handler.journal.close()
+	}
+	if r.adminServer != nil {
+		r.adminServer.Close()
+	}
+	if r.metrics != nil {
+		r.metrics.Close()
+	}
+	if r.handler != nil {
+		atomic.StoreInt32(&r.handler.listening, 0)
+	}
+	if r.health != nil {
+		r.health.Close()
+	}
+	if r.webTransport != nil {
+		r.webTransport.Close()
+	}
+	if r.quicServer == nil {
+		return nil
+	}
+
+	report := r.shutdownReport()
+	err := r.quicServer.Close()
+	report.emit(r.report)
+	// the audit log is closed after the clients are disconnected.
+	if r.handler != nil {
+		r.handler.auditor.Close()
+		r.handler.capturer.Close()
+	}
+	if r.conf.Timeline != nil {
+		pipelineTimelines.disable()
+	}
+	return err
+}
+
+// shutdownReport reports the frames and the clients which are affected by the shutdown.
+func (r *zipperImpl) shutdownReport() ShutdownReport {
+	report := ShutdownReport{
+		Name:            r.conf.Name,
+		StartedAt:       r.startedAt,
+		StoppedAt:       time.Now(),
+		Backlog:         make(map[string]int),
+		SheddingDropped: r.DroppedFrames(),
+		CorruptedFrames: core.CorruptedFrames(),
+		ClosedPeers:     make([]ClosedPeer, 0),
+	}
+
+	if r.handler == nil {
+		return report
+	}
+
+	report.InFlightDropped, report.Backlog = r.handler.queues.backlog()
+	for _, c := range r.handler.currentConnections() {
+		report.ClosedPeers = append(report.ClosedPeers, ClosedPeer{
+			Name: c.Conn.Name,
+			Type: c.Conn.Type.String(),
+			Addr: c.RemoteAddr,
+		})
 	}
-	return nil
+	return report
 }